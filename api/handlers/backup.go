@@ -18,7 +18,9 @@ import (
 	"go53/security"
 	"go53/storage"
 	"go53/wal"
+	"go53/zone"
 	"go53/zone/rtypes"
+	"go53/zonemeta"
 
 	"github.com/miekg/dns"
 )
@@ -341,6 +343,25 @@ func applyWALEvent(event wal.Event) error {
 			}
 			return dnsutils.ImportRecords("", event.Zone, records)
 		}
+	case wal.KindViewRecord, wal.KindViewZone:
+		zones, ok, err := zone.ForConfiguredView(event.Key)
+		if err != nil || !ok {
+			return err
+		}
+		switch {
+		case event.Kind == wal.KindViewZone && event.Op == wal.OpDelete:
+			return zones.DeleteZone(event.Zone)
+		case event.Op == wal.OpUpsert:
+			var value any
+			if len(event.Value) > 0 {
+				if err := json.Unmarshal(event.Value, &value); err != nil {
+					return err
+				}
+			}
+			return zones.Store().PutRecordRaw(event.Zone, event.RRType, event.Name, value)
+		case event.Op == wal.OpDelete:
+			return zones.Store().DeleteRecordRaw(event.Zone, event.RRType, event.Name)
+		}
 	case wal.KindConfig:
 		if event.Op == wal.OpUpsert {
 			return config.AppConfig.MergeUpdateLiveJSON(event.Value)
@@ -359,6 +380,13 @@ func applyWALEvent(event wal.Event) error {
 			security.DeleteTSIGKey(event.Key)
 			return nil
 		}
+	case wal.KindZoneMeta:
+		if event.Op == wal.OpUpsert {
+			if err := storage.Backend.SaveTable(event.Table, event.Key, event.Value); err != nil {
+				return err
+			}
+			return zonemeta.Reload()
+		}
 	case wal.KindDNSSECKey:
 		switch event.Op {
 		case wal.OpUpsert:
@@ -382,6 +410,7 @@ func reloadRuntimeState() error {
 		return err
 	}
 	rtypes.InitMemoryStore(store)
+	zone.ResetViews()
 	config.AppConfig.InitLiveConfig()
 	if err := security.LoadTSIGKeysFromStorage(); err != nil {
 		return err
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"go53/config"
 	"go53/distributed"
 	"go53/wal"
@@ -19,6 +20,10 @@ func UpdateLiveConfigHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "x_auth_key must be managed through the local admin auth-key endpoint", http.StatusForbidden)
 		return
 	}
+	if patchContainsCookieSecret(body) {
+		http.Error(w, "cookie secrets must be managed through the cookie secret rotation endpoint", http.StatusForbidden)
+		return
+	}
 
 	// Validate the patch parses as a LiveConfig, but apply it as a raw JSON overlay so
 	// that present false bools / empty strings are honored (a struct-merge would drop
@@ -28,6 +33,12 @@ func UpdateLiveConfigHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid JSON body", http.StatusBadRequest)
 		return
 	}
+	for _, v := range validate.Views {
+		if !config.ValidViewName(v.Name) {
+			http.Error(w, fmt.Sprintf("invalid view name %q", v.Name), http.StatusBadRequest)
+			return
+		}
+	}
 
 	if err := config.AppConfig.MergeUpdateLiveJSON(body); err != nil {
 		http.Error(w, "failed to apply config: "+err.Error(), http.StatusInternalServerError)
@@ -50,6 +61,8 @@ func UpdateLiveConfigHandler(w http.ResponseWriter, r *http.Request) {
 func GetLiveConfigHandler(w http.ResponseWriter, r *http.Request) {
 	live := config.AppConfig.GetLive()
 	live.Auth.XAuthKey = ""
+	live.Cookies.Secret = ""
+	live.Cookies.PreviousSecret = ""
 	err := json.NewEncoder(w).Encode(live)
 	if err != nil {
 		return
@@ -110,18 +123,32 @@ func SetXAuthKeyHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func patchContainsXAuthKey(body []byte) bool {
+	return patchContainsNestedKey(body, "auth", "x_auth_key")
+}
+
+func patchContainsCookieSecret(body []byte) bool {
+	return patchContainsNestedKey(body, "cookies", "secret", "previous_secret")
+}
+
+// patchContainsNestedKey reports whether the config patch sets any of keys
+// inside the top-level section object.
+func patchContainsNestedKey(body []byte, section string, keys ...string) bool {
 	var root map[string]json.RawMessage
 	if err := json.Unmarshal(body, &root); err != nil {
 		return false
 	}
-	authRaw, ok := root["auth"]
+	sectionRaw, ok := root[section]
 	if !ok {
 		return false
 	}
-	var auth map[string]json.RawMessage
-	if err := json.Unmarshal(authRaw, &auth); err != nil {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(sectionRaw, &fields); err != nil {
 		return false
 	}
-	_, ok = auth["x_auth_key"]
-	return ok
+	for _, key := range keys {
+		if _, ok := fields[key]; ok {
+			return true
+		}
+	}
+	return false
 }
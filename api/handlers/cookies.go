@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"go53/config"
+	"go53/distributed"
+	"go53/dns/dnsutils"
+	"go53/wal"
+)
+
+type cookieSecretRequest struct {
+	Secret string `json:"secret"`
+}
+
+type cookieSecretResponse struct {
+	Rotated        bool `json:"rotated"`
+	PreviousActive bool `json:"previous_active"`
+}
+
+// RotateCookieSecretHandler installs a new DNS Cookie server secret, keeping the
+// current one as previous_secret so cookies already handed out stay valid for
+// their lifetime. The request may carry the new secret (to share it with other
+// servers behind the same address); otherwise a random one is generated. The
+// change is replicated to every node in a distributed cluster.
+func RotateCookieSecretHandler(w http.ResponseWriter, r *http.Request) {
+	var req cookieSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	secret := req.Secret
+	if secret == "" {
+		generated, err := dnsutils.NewCookieSecret()
+		if err != nil {
+			http.Error(w, "failed to generate secret: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		secret = generated
+	} else if _, err := dnsutils.ParseCookieSecret(secret); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	previous := config.AppConfig.GetLive().Cookies.Secret
+	body, err := json.Marshal(map[string]any{
+		"cookies": map[string]string{"secret": secret, "previous_secret": previous},
+	})
+	if err != nil {
+		http.Error(w, "failed to build config patch", http.StatusInternalServerError)
+		return
+	}
+	if err := config.AppConfig.MergeUpdateLiveJSON(body); err != nil {
+		http.Error(w, "failed to apply config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := wal.Append(wal.KindConfig, wal.OpUpsert, "", "", "", "config", "live", body); err != nil {
+		http.Error(w, "cookie secret rotated but WAL append failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if distributed.Default != nil {
+		if err := distributed.Default.PublishConfig(body); err != nil {
+			http.Error(w, "cookie secret rotated but distributed event failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, cookieSecretResponse{Rotated: true, PreviousActive: previous != ""})
+}
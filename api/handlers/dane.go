@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/miekg/dns"
+
+	"go53/internal"
+	"go53/types"
+)
+
+// daneRequest is the body of POST /api/zones/{zone}/dane. Usage, selector and
+// matching type take a number or an RFC 7218 mnemonic.
+type daneRequest struct {
+	Type         string  `json:"type"`
+	Name         string  `json:"name"`
+	Email        string  `json:"email"`
+	Usage        any     `json:"usage"`
+	Selector     any     `json:"selector"`
+	MatchingType any     `json:"matching_type"`
+	PEM          string  `json:"pem"`
+	ChainIndex   int     `json:"chain_index"`
+	TTL          *uint32 `json:"ttl"`
+	Publish      bool    `json:"publish"`
+}
+
+type daneResponse struct {
+	Zone         string `json:"zone"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	TTL          uint32 `json:"ttl"`
+	Usage        uint8  `json:"usage"`
+	Selector     uint8  `json:"selector"`
+	MatchingType uint8  `json:"matching_type"`
+	Certificate  string `json:"certificate"`
+	Record       string `json:"record"`
+	Published    bool   `json:"published"`
+}
+
+// POST /api/zones/{zone}/dane
+//
+// GenerateDANERecordHandler builds a TLSA or SMIMEA record from a PEM
+// certificate or public key and, with "publish": true, adds it to the zone
+// through the normal record path. Generating without publishing lets scripts
+// pre-compute the record for a certificate that is not deployed yet.
+func GenerateDANERecordHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName := mux.Vars(r)["zone"]
+	zoneFQDN, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+
+	var req daneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	rrtypeStr := strings.ToUpper(strings.TrimSpace(req.Type))
+	if rrtypeStr == "" {
+		rrtypeStr = string(types.TypeTLSA)
+	}
+	if rrtypeStr != string(types.TypeTLSA) && rrtypeStr != string(types.TypeSMIMEA) {
+		http.Error(w, "type must be TLSA or SMIMEA", http.StatusBadRequest)
+		return
+	}
+
+	owner, err := daneOwner(req, rrtypeStr, zoneFQDN)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var params [3]uint8
+	for i, f := range []struct {
+		name  string
+		raw   any
+		codes map[string]uint8
+	}{
+		{"usage", req.Usage, internal.DANEUsages},
+		{"selector", req.Selector, internal.DANESelectors},
+		{"matching_type", req.MatchingType, internal.DANEMatchingTypes},
+	} {
+		if f.raw == nil {
+			http.Error(w, "Missing field: "+f.name, http.StatusBadRequest)
+			return
+		}
+		if params[i], err = internal.DANECode(f.codes, f.raw); err != nil {
+			http.Error(w, fmt.Sprintf("Field '%s': %v", f.name, err), http.StatusBadRequest)
+			return
+		}
+	}
+	if strings.TrimSpace(req.PEM) == "" {
+		http.Error(w, "Missing field: pem", http.StatusBadRequest)
+		return
+	}
+	data, err := internal.DANEAssociation([]byte(req.PEM), req.ChainIndex, params[1], params[2])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ttl := uint32(3600)
+	if req.TTL != nil {
+		ttl = *req.TTL
+	}
+	rec := types.TLSARecord{Usage: params[0], Selector: params[1], MatchingType: params[2], Certificate: data, TTL: ttl}
+	var built []dns.RR
+	if rrtypeStr == string(types.TypeSMIMEA) {
+		built = internal.RRBuilders[rrtypeStr](owner, []types.SMIMEARecord{types.SMIMEARecord(rec)})
+	} else {
+		built = internal.RRBuilders[rrtypeStr](owner, []types.TLSARecord{rec})
+	}
+
+	resp := daneResponse{
+		Zone:         zoneFQDN,
+		Name:         owner,
+		Type:         rrtypeStr,
+		TTL:          ttl,
+		Usage:        rec.Usage,
+		Selector:     rec.Selector,
+		MatchingType: rec.MatchingType,
+		Certificate:  rec.Certificate,
+		Record:       built[0].String(),
+	}
+
+	status := http.StatusOK
+	if req.Publish {
+		if rejectReadOnlyZone(w, zoneName) {
+			return
+		}
+		value := map[string]interface{}{
+			"usage":         float64(rec.Usage),
+			"selector":      float64(rec.Selector),
+			"matching_type": float64(rec.MatchingType),
+			"certificate":   rec.Certificate,
+		}
+		rrtype := dns.StringToType[rrtypeStr]
+		if !storeRecord(w, zoneName, rrtypeStr, rrtype, addRecordRequest{name: owner, value: value, ttlPtr: &ttl}) {
+			return
+		}
+		resp.Published = true
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// daneOwner resolves the owner name of the generated record. SMIMEA records
+// may give an e-mail address instead of a name (RFC 8162 section 3). The
+// owner must lie inside the zone.
+func daneOwner(req daneRequest, rrtypeStr, zoneFQDN string) (string, error) {
+	var owner string
+	switch {
+	case req.Email != "" && rrtypeStr == string(types.TypeSMIMEA):
+		if req.Name != "" {
+			return "", fmt.Errorf("give either name or email, not both")
+		}
+		name, err := internal.SMIMEAOwner(req.Email)
+		if err != nil {
+			return "", err
+		}
+		owner = name
+	case req.Email != "":
+		return "", fmt.Errorf("email is only supported for SMIMEA")
+	case strings.TrimSpace(req.Name) == "":
+		return "", fmt.Errorf("Missing field: name")
+	case req.Name == "@":
+		owner = zoneFQDN
+	case strings.HasSuffix(req.Name, "."):
+		owner = req.Name
+	default:
+		owner = req.Name + "." + zoneFQDN
+	}
+	if _, ok := dns.IsDomainName(owner); !ok {
+		return "", fmt.Errorf("invalid owner name %q", owner)
+	}
+	if !dns.IsSubDomain(zoneFQDN, owner) {
+		return "", fmt.Errorf("owner %s is outside zone %s", owner, zoneFQDN)
+	}
+	return owner, nil
+}
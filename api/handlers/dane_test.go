@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/miekg/dns"
+
+	"go53/distributed"
+	"go53/zone/rtypes"
+)
+
+func testDANECertificate(t *testing.T) (*x509.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail.dane.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func postDANE(t *testing.T, zoneName string, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	raw, _ := json.Marshal(body)
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/zones/"+zoneName+"/dane", strings.NewReader(string(raw))), map[string]string{"zone": zoneName})
+	rec := httptest.NewRecorder()
+	GenerateDANERecordHandler(rec, req)
+	return rec
+}
+
+func TestGenerateDANERecordHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+	distributed.Default = nil
+	t.Cleanup(func() { distributed.Default = nil })
+	addTestRecord(t, "dane.test.", "SOA", `{"ttl":300,"ns":"ns1.dane.test.","mbox":"hostmaster.dane.test.","refresh":3600,"retry":600,"expire":86400,"minimum":300}`)
+
+	cert, certPEM := testDANECertificate(t)
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	wantData := strings.ToUpper(hex.EncodeToString(spki[:]))
+
+	rec := postDANE(t, "dane.test.", map[string]interface{}{
+		"name": "_25._tcp.mail", "usage": "DANE-EE", "selector": "SPKI", "matching_type": 1, "pem": certPEM,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("generate status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	var got daneResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "_25._tcp.mail.dane.test." || got.Type != "TLSA" || got.Certificate != wantData || got.Published {
+		t.Fatalf("unexpected response %+v", got)
+	}
+	tlsa, err := dns.NewRR(got.Record)
+	if err != nil {
+		t.Fatalf("record %q does not parse: %v", got.Record, err)
+	}
+	// miekg compares lowercase hex; go53 stores association data uppercase.
+	tlsa.(*dns.TLSA).Certificate = strings.ToLower(tlsa.(*dns.TLSA).Certificate)
+	if err := tlsa.(*dns.TLSA).Verify(cert); err != nil {
+		t.Fatalf("generated TLSA does not match certificate: %v", err)
+	}
+	handler, _ := rtypes.Get(dns.TypeTLSA)
+	if rrs, _ := handler.Lookup("_25._tcp.mail.dane.test."); len(rrs) != 0 {
+		t.Fatalf("generate-only request stored a record: %v", rrs)
+	}
+
+	rec = postDANE(t, "dane.test.", map[string]interface{}{
+		"name": "_25._tcp.mail", "usage": 3, "selector": 1, "matching_type": 1, "pem": certPEM, "ttl": 300, "publish": true,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("publish status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	rrs, ok := handler.Lookup("_25._tcp.mail.dane.test.")
+	if !ok || len(rrs) != 1 || rrs[0].Header().Ttl != 300 || !strings.EqualFold(rrs[0].(*dns.TLSA).Certificate, wantData) {
+		t.Fatalf("published TLSA = %v", rrs)
+	}
+
+	rec = postDANE(t, "dane.test.", map[string]interface{}{
+		"type": "SMIMEA", "email": "hugh@dane.test", "usage": 3, "selector": 0, "matching_type": 2, "pem": certPEM,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("SMIMEA status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got.Name, "._smimecert.dane.test.") || len(strings.Split(got.Name, ".")[0]) != 56 {
+		t.Fatalf("unexpected SMIMEA owner %q", got.Name)
+	}
+
+	pubDER, _ := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	pubPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	for name, body := range map[string]map[string]interface{}{
+		"public key with cert selector": {"name": "_443._tcp.www", "usage": 3, "selector": 0, "matching_type": 1, "pem": pubPEM},
+		"owner outside zone":            {"name": "_443._tcp.www.other.test.", "usage": 3, "selector": 1, "matching_type": 1, "pem": certPEM},
+		"bad usage":                     {"name": "_443._tcp.www", "usage": 7, "selector": 1, "matching_type": 1, "pem": certPEM},
+		"no pem blocks":                 {"name": "_443._tcp.www", "usage": 3, "selector": 1, "matching_type": 1, "pem": "nope"},
+		"email for TLSA":                {"email": "a@dane.test", "usage": 3, "selector": 1, "matching_type": 1, "pem": certPEM},
+	} {
+		if rec := postDANE(t, "dane.test.", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d body=%q", name, rec.Code, rec.Body.String())
+		}
+	}
+	rec = postDANE(t, "dane.test.", map[string]interface{}{"name": "_443._tcp.www", "usage": 3, "selector": 1, "matching_type": 1, "pem": pubPEM})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("public key SPKI status = %d body=%q", rec.Code, rec.Body.String())
+	}
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"go53/distributed"
+	"go53/dns/dnsutils"
+	"go53/internal"
 	"go53/security"
 	"go53/types"
 	zonepkg "go53/zone"
+	"go53/zone/rtypes"
 	"io"
 	"net/http"
 	"strconv"
@@ -256,6 +260,69 @@ func DeleteDNSKeyHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetDNSSECTimelineHandler returns the signing policy of a zone, its keys
+// without private material and the key events the scheduler has planned.
+func GetDNSSECTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	timeline, err := dnsutils.ZoneDNSSECTimeline(zoneName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if store := rtypes.GetMemStore(); len(timeline.Keys) == 0 && (store == nil || !store.HasZone(zoneName)) {
+		http.Error(w, fmt.Sprintf("zone %s not found", zoneName), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, timeline)
+}
+
+// StartAlgorithmRolloverHandler moves the zone to the algorithm in the body
+// with the conservative algorithm rollover of RFC 6781 section 4.1.4.
+func StartAlgorithmRolloverHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Algorithm string `json:"algorithm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Algorithm == "" {
+		http.Error(w, "Invalid JSON: algorithm is required", http.StatusBadRequest)
+		return
+	}
+	status, err := dnsutils.StartAlgorithmRollover(zoneName, req.Algorithm)
+	if err != nil {
+		http.Error(w, "Algorithm rollover failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+// GetAlgorithmRolloverHandler reports the phase of the last algorithm
+// rollover of the zone.
+func GetAlgorithmRolloverHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	status, err := dnsutils.AlgorithmRolloverStatusOf(zoneName)
+	switch {
+	case errors.Is(err, dnsutils.ErrNoAlgorithmRollover):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, status)
+}
+
 func removeAfter(r *http.Request) time.Duration {
 	days, err := strconv.Atoi(r.URL.Query().Get("remove_after_days"))
 	if err != nil || days <= 0 {
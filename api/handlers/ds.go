@@ -3,10 +3,12 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"go53/dns/dnsutils"
 	"go53/security"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/miekg/dns"
@@ -27,23 +29,27 @@ func GetDSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-
-	jsonList := make([]map[string]interface{}, 0, len(dsList))
+	rrs := make([]dns.RR, 0, len(dsList))
 	for _, ds := range dsList {
-		jsonList = append(jsonList, map[string]interface{}{
-			"name":       ds.Hdr.Name,
-			"type":       dns.TypeToString[ds.Hdr.Rrtype],
-			"class":      dns.ClassToString[ds.Hdr.Class],
-			"ttl":        ds.Hdr.Ttl,
-			"keytag":     ds.KeyTag,
-			"algorithm":  ds.Algorithm,
-			"digestType": ds.DigestType,
-			"digest":     ds.Digest,
-		})
+		rrs = append(rrs, ds)
+	}
+	if !queryBool(r, "status") {
+		writeRRList(w, rrs)
+		return
 	}
 
-	_ = json.NewEncoder(w).Encode(jsonList)
+	// With status=true the DS come with the last check of the parent.
+	parent, err := dnsutils.ParentDSStatusOf(zone, queryBool(r, "refresh"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to check parent DS: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"zone":   parent.Zone,
+		"ds":     rrJSONList(rrs),
+		"parent": parent,
+	})
 }
 
 func GetCDSHandler(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +63,10 @@ func GetCDSHandler(w http.ResponseWriter, r *http.Request) {
 		writeRRList(w, []dns.RR{security.DeleteDSCDS(zone, ttlFromQuery(r))})
 		return
 	}
+	if security.CDSWithdrawn(zone, time.Now().Unix()) {
+		writeRRList(w, nil)
+		return
+	}
 	dsList, err := security.GetDSWithDigestTypes(zone, digestTypesFromQuery(r))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to get CDS: %v", err), http.StatusInternalServerError)
@@ -94,6 +104,10 @@ func GetCDNSKEYHandler(w http.ResponseWriter, r *http.Request) {
 
 func writeRRList(w http.ResponseWriter, rrs []dns.RR) {
 	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rrJSONList(rrs))
+}
+
+func rrJSONList(rrs []dns.RR) []map[string]interface{} {
 	jsonList := make([]map[string]interface{}, 0, len(rrs))
 	for _, rr := range rrs {
 		h := rr.Header()
@@ -122,7 +136,7 @@ func writeRRList(w http.ResponseWriter, rrs []dns.RR) {
 		}
 		jsonList = append(jsonList, item)
 	}
-	_ = json.NewEncoder(w).Encode(jsonList)
+	return jsonList
 }
 
 func digestTypesFromQuery(r *http.Request) []uint8 {
@@ -148,7 +162,11 @@ func digestTypesFromQuery(r *http.Request) []uint8 {
 }
 
 func deleteSignal(r *http.Request) bool {
-	v := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("delete")))
+	return queryBool(r, "delete")
+}
+
+func queryBool(r *http.Request, name string) bool {
+	v := strings.ToLower(strings.TrimSpace(r.URL.Query().Get(name)))
 	return v == "1" || v == "true" || v == "yes"
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/miekg/dns"
 
 	"go53/distributed"
+	"go53/dns/dnsutils"
 	"go53/security"
 )
 
@@ -159,3 +160,105 @@ func TestDNSKeyLifecycleHandlers(t *testing.T) {
 		t.Fatalf("DeleteDNSKeyHandler status = %d body=%q", deleteRec.Code, deleteRec.Body.String())
 	}
 }
+
+func TestGetDNSSECTimelineHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+	if err := security.InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	now := time.Now().Unix()
+	if _, _, err := security.GenerateRolloverKey("timeline.test.", "zsk", "ECDSAP256SHA256", now-10, now+3600); err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+
+	get := func(zoneName string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/zones/"+zoneName+"/dnssec/timeline", nil), map[string]string{"zone": zoneName})
+		rec := httptest.NewRecorder()
+		GetDNSSECTimelineHandler(rec, req)
+		return rec
+	}
+
+	rec := get("timeline.test")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Zone   string               `json:"zone"`
+		Keys   []security.KeyStatus `json:"keys"`
+		Events []security.KeyEvent  `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Zone != "timeline.test." || len(resp.Keys) != 1 || resp.Keys[0].State != security.KeyStatePublished {
+		t.Fatalf("timeline = %+v", resp)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].Event != security.KeyEventActivate {
+		t.Fatalf("events = %+v", resp.Events)
+	}
+
+	if rec := get("missing.test"); rec.Code != http.StatusNotFound {
+		t.Fatalf("timeline of unknown zone = %d, want 404", rec.Code)
+	}
+}
+
+func TestAlgorithmRolloverHandlers(t *testing.T) {
+	setupHandlerTestStore(t)
+	if err := security.InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	call := func(method, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/zones/roll.test/dnssec/algorithm-rollover", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"zone": "roll.test"})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := call(http.MethodGet, "", GetAlgorithmRolloverHandler); rec.Code != http.StatusNotFound {
+		t.Fatalf("status of a zone without rollover = %d body=%q", rec.Code, rec.Body.String())
+	}
+	if rec := call(http.MethodPost, `{}`, StartAlgorithmRolloverHandler); rec.Code != http.StatusBadRequest {
+		t.Fatalf("start without algorithm = %d", rec.Code)
+	}
+	rec := call(http.MethodPost, `{"algorithm":"ED25519"}`, StartAlgorithmRolloverHandler)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "policy") {
+		t.Fatalf("start without a DNSSEC policy = %d body=%q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGetDSHandlerWithParentStatus(t *testing.T) {
+	setupHandlerTestStore(t)
+	if err := security.InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	now := time.Now().Unix()
+	if _, _, err := security.GenerateRolloverKey("status.test.", "ksk", "ECDSAP256SHA256", now-10, now-10); err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/ds/status.test?status=true", nil), map[string]string{"zone": "status.test"})
+	rec := httptest.NewRecorder()
+	GetDSHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Zone   string                   `json:"zone"`
+		DS     []map[string]interface{} `json:"ds"`
+		Parent dnsutils.ParentDSStatus  `json:"parent"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Zone != "status.test." || len(resp.DS) != 1 || resp.Parent.Enabled || len(resp.Parent.Keys) != 1 || !resp.Parent.CDSPublished {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	plain := httptest.NewRecorder()
+	GetDSHandler(plain, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/ds/status.test", nil), map[string]string{"zone": "status.test"}))
+	var list []map[string]interface{}
+	if err := json.Unmarshal(plain.Body.Bytes(), &list); err != nil || len(list) != 1 {
+		t.Fatalf("plain DS list = %q, %v", plain.Body.String(), err)
+	}
+}
@@ -4,18 +4,23 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/miekg/dns"
 
 	"go53/config"
 	"go53/distributed"
+	"go53/dns/dnsutils"
 	"go53/memory"
 	"go53/security"
 	"go53/storage"
 	"go53/types"
+	"go53/wal"
+	"go53/zone"
 	"go53/zone/rtypes"
 	"go53/zonemeta"
 )
@@ -150,6 +155,45 @@ www.preserve.test. 300 IN A 192.0.2.10
 	}
 }
 
+func TestImportExportUnknownRecordTypes(t *testing.T) {
+	setupHandlerTestStore(t)
+	zoneText := `opaque.test. 300 IN SOA ns1.opaque.test. hostmaster.opaque.test. 1 3600 600 86400 300
+opaque.test. 300 IN NS ns1.opaque.test.
+ns1.opaque.test. 300 IN A 192.0.2.53
+www.opaque.test. 300 IN TYPE65534 \# 4 0a000001
+host.opaque.test. 300 IN HINFO "AMD" "Free"
+`
+	req := httptest.NewRequest(http.MethodPost, "/api/zones/opaque.test./import", strings.NewReader(zoneText))
+	req = mux.SetURLVars(req, map[string]string{"zone": "opaque.test."})
+	rec := httptest.NewRecorder()
+	ImportZoneHandler(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("ImportZoneHandler status = %d body=%q", rec.Code, rec.Body.String())
+	}
+
+	addReq := httptest.NewRequest(http.MethodPost, "/api/zones/opaque.test./records/TYPE65280", strings.NewReader(`{"name":"www","rdata":"\\# 2 beef"}`))
+	addReq = mux.SetURLVars(addReq, map[string]string{"zone": "opaque.test.", "rrtype": "TYPE65280"})
+	addRec := httptest.NewRecorder()
+	AddRecordHandler(addRec, addReq)
+	if addRec.Code != http.StatusCreated {
+		t.Fatalf("AddRecordHandler TYPE65280 status = %d body=%q", addRec.Code, addRec.Body.String())
+	}
+
+	exportReq := httptest.NewRequest(http.MethodGet, "/api/zones/opaque.test./export", nil)
+	exportReq = mux.SetURLVars(exportReq, map[string]string{"zone": "opaque.test."})
+	exportRec := httptest.NewRecorder()
+	ExportZoneHandler(exportRec, exportReq)
+	if exportRec.Code != http.StatusOK {
+		t.Fatalf("ExportZoneHandler status = %d body=%q", exportRec.Code, exportRec.Body.String())
+	}
+	body := exportRec.Body.String()
+	for _, want := range []string{"TYPE65534\t\\# 4 0a000001", "TYPE65280\t\\# 2 beef", "HINFO\t\"AMD\" \"Free\""} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("export lacks %q:\n%s", want, body)
+		}
+	}
+}
+
 func TestTSIGHandlersLifecycle(t *testing.T) {
 	setupHandlerTestStore(t)
 	distributed.Default = nil
@@ -340,3 +384,392 @@ func setupHandlerTestStore(t *testing.T) *storage.MockStorage {
 	rtypes.InitMemoryStore(mem)
 	return backend
 }
+
+func TestGetRRLStatsHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+	config.AppConfig.LiveForTest().RRL.Enabled = true
+
+	rec := httptest.NewRecorder()
+	GetRRLStatsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/rrl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetRRLStatsHandler status = %d", rec.Code)
+	}
+	var stats map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode RRL stats: %v", err)
+	}
+	for _, key := range []string{"enabled", "responses", "dropped", "slipped", "logged", "classes"} {
+		if _, ok := stats[key]; !ok {
+			t.Fatalf("RRL stats missing %q: %v", key, stats)
+		}
+	}
+	if stats["enabled"] != true {
+		t.Fatalf("enabled = %v, want true", stats["enabled"])
+	}
+}
+
+func TestRotateCookieSecretHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+
+	rotate := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		RotateCookieSecretHandler(rec, httptest.NewRequest(http.MethodPost, "/api/cookies/rotate", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := rotate(""); rec.Code != http.StatusOK {
+		t.Fatalf("first rotation status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	first := config.AppConfig.GetLive().Cookies
+	if len(first.Secret) != 32 || first.PreviousSecret != "" {
+		t.Fatalf("cookies after first rotation = %+v", first)
+	}
+
+	shared := "e5e973e5a6b2a43f48e7dc849e37bfcf"
+	rec := rotate(`{"secret":"` + shared + `"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"previous_active":true`) {
+		t.Fatalf("second rotation status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	second := config.AppConfig.GetLive().Cookies
+	if second.Secret != shared || second.PreviousSecret != first.Secret {
+		t.Fatalf("cookies after second rotation = %+v", second)
+	}
+
+	if rec := rotate(`{"secret":"abcd"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("short secret status = %d, want 400", rec.Code)
+	}
+
+	patchRec := httptest.NewRecorder()
+	UpdateLiveConfigHandler(patchRec, httptest.NewRequest(http.MethodPatch, "/api/config", strings.NewReader(`{"cookies":{"secret":"`+shared+`"}}`)))
+	if patchRec.Code != http.StatusForbidden {
+		t.Fatalf("cookie secret config patch status = %d, want 403", patchRec.Code)
+	}
+
+	getRec := httptest.NewRecorder()
+	GetLiveConfigHandler(getRec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if strings.Contains(getRec.Body.String(), shared) || strings.Contains(getRec.Body.String(), first.Secret) {
+		t.Fatalf("GetLiveConfigHandler exposed a cookie secret: %s", getRec.Body.String())
+	}
+}
+
+func TestViewZoneHandlers(t *testing.T) {
+	setupHandlerTestStore(t)
+	zone.ResetViews()
+	t.Cleanup(zone.ResetViews)
+	config.AppConfig.LiveForTest().Views = []config.ViewConfig{{Name: "internal", MatchClients: []string{"10.0.0.0/8"}}}
+	addTestRecord(t, "view.test.", "SOA", `{"ttl":300,"ns":"ns1.view.test.","mbox":"hostmaster.view.test.","refresh":3600,"retry":600,"expire":86400,"minimum":300}`)
+	addTestRecord(t, "view.test.", "A", `{"name":"www","ip":"192.0.2.5","ttl":120}`)
+
+	viewRequest := func(method, path string, vars map[string]string, body string) *http.Request {
+		vars["view"] = "internal"
+		return mux.SetURLVars(httptest.NewRequest(method, path, strings.NewReader(body)), vars)
+	}
+
+	importRec := httptest.NewRecorder()
+	ImportViewZoneHandler(importRec, viewRequest(http.MethodPost, "/api/views/internal/zones/view.test./import", map[string]string{"zone": "view.test."},
+		"view.test. 300 IN SOA ns1.view.test. hostmaster.view.test. 1 3600 600 86400 300\nwww.view.test. 120 IN A 10.0.0.5\n"))
+	if importRec.Code != http.StatusCreated {
+		t.Fatalf("ImportViewZoneHandler status = %d body=%q", importRec.Code, importRec.Body.String())
+	}
+
+	addRec := httptest.NewRecorder()
+	AddViewRecordHandler(addRec, viewRequest(http.MethodPost, "/api/views/internal/zones/view.test./records/A", map[string]string{"zone": "view.test.", "rrtype": "A"}, `{"name":"intranet","ip":"10.0.0.80","ttl":120}`))
+	if addRec.Code != http.StatusCreated {
+		t.Fatalf("AddViewRecordHandler status = %d body=%q", addRec.Code, addRec.Body.String())
+	}
+
+	getRec := httptest.NewRecorder()
+	GetViewRecordHandler(getRec, viewRequest(http.MethodGet, "/api/views/internal/zones/view.test./records/A/www.view.test.", map[string]string{"zone": "view.test.", "rrtype": "A", "name": "www.view.test."}, ""))
+	if getRec.Code != http.StatusOK || !strings.Contains(getRec.Body.String(), "10.0.0.5") {
+		t.Fatalf("GetViewRecordHandler status = %d body=%q", getRec.Code, getRec.Body.String())
+	}
+	if rrs, ok := zone.LookupRecord(dns.TypeA, "intranet.view.test."); ok {
+		t.Fatalf("view record leaked into the default zone: %v", rrs)
+	}
+
+	exportRec := httptest.NewRecorder()
+	ExportViewZoneHandler(exportRec, viewRequest(http.MethodGet, "/api/views/internal/zones/view.test./export", map[string]string{"zone": "view.test."}, ""))
+	if exportRec.Code != http.StatusOK || !strings.Contains(exportRec.Body.String(), "10.0.0.80") || strings.Contains(exportRec.Body.String(), "192.0.2.5") {
+		t.Fatalf("ExportViewZoneHandler status = %d body=%q", exportRec.Code, exportRec.Body.String())
+	}
+
+	// View writes are journaled in the WAL under the view's name and replay
+	// into the view.
+	events, err := wal.EventsAfter(0)
+	if err != nil {
+		t.Fatalf("EventsAfter: %v", err)
+	}
+	var viewEvents []wal.Event
+	for _, event := range events {
+		if event.Kind == wal.KindViewRecord || event.Kind == wal.KindViewZone {
+			if event.Key != "internal" {
+				t.Fatalf("view event key = %q, want internal", event.Key)
+			}
+			viewEvents = append(viewEvents, event)
+		}
+	}
+	if len(viewEvents) == 0 {
+		t.Fatalf("view changes were not written to the WAL: %+v", events)
+	}
+
+	listRec := httptest.NewRecorder()
+	ListViewsHandler(listRec, httptest.NewRequest(http.MethodGet, "/api/views", nil))
+	if !strings.Contains(listRec.Body.String(), `"zones":["view.test."]`) {
+		t.Fatalf("ListViewsHandler body=%q", listRec.Body.String())
+	}
+
+	missingRec := httptest.NewRecorder()
+	GetViewZonesHandler(missingRec, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/views/nope/zones", nil), map[string]string{"view": "nope"}))
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("unknown view status = %d, want 404", missingRec.Code)
+	}
+
+	deleteRec := httptest.NewRecorder()
+	DeleteViewZoneHandler(deleteRec, viewRequest(http.MethodDelete, "/api/views/internal/zones/view.test.", map[string]string{"zone": "view.test."}, ""))
+	if deleteRec.Code != http.StatusNoContent {
+		t.Fatalf("DeleteViewZoneHandler status = %d body=%q", deleteRec.Code, deleteRec.Body.String())
+	}
+	if _, ok := zone.LookupRecord(dns.TypeA, "www.view.test."); !ok {
+		t.Fatalf("deleting the view zone removed the default zone")
+	}
+
+	for _, event := range viewEvents {
+		if err := applyWALEvent(event); err != nil {
+			t.Fatalf("applyWALEvent(%+v): %v", event, err)
+		}
+	}
+	internal, _, err := zone.ForConfiguredView("internal")
+	if err != nil {
+		t.Fatalf("ForConfiguredView: %v", err)
+	}
+	if rrs, ok := internal.LookupRecord(dns.TypeA, "intranet.view.test."); !ok || len(rrs) != 1 {
+		t.Fatalf("replayed view zone lookup = %v ok=%v", rrs, ok)
+	}
+}
+
+func TestUpdateLiveConfigRejectsInvalidViewName(t *testing.T) {
+	setupHandlerTestStore(t)
+	for _, name := range []string{"", "../zones", "a/b", "-lead"} {
+		rec := httptest.NewRecorder()
+		body := `{"views":[{"name":` + strconv.Quote(name) + `}]}`
+		UpdateLiveConfigHandler(rec, httptest.NewRequest(http.MethodPatch, "/api/config", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("view name %q status = %d, want 400", name, rec.Code)
+		}
+	}
+	if _, err := zone.ForView("../zones", false); err == nil {
+		t.Fatalf("ForView accepted a name escaping the view storage prefix")
+	}
+}
+
+func TestZoneSettingsHandlers(t *testing.T) {
+	setupHandlerTestStore(t)
+	security.SetTSIGKey("cust-a.", security.TSIGKey{Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"})
+	t.Cleanup(func() { security.DeleteTSIGKey("cust-a.") })
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/zones/settings.test/settings", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"zone": "settings.test"})
+		rec := httptest.NewRecorder()
+		PutZoneSettingsHandler(rec, req)
+		return rec
+	}
+
+	for _, bad := range []string{
+		`{"primaries":[{"ip":"not-an-ip"}]}`,
+		`{"primaries":[{"ip":"192.0.2.1","port":70000}]}`,
+		`{"allow_transfer":[{"address":"192.0.2.0/33"}]}`,
+		`{"also_notify":[{"ip":"192.0.2.9","tsig_key":"missing-key"}]}`,
+		`{"notify_source":"localhost"}`,
+		`{"primary":"192.0.2.1"}`,
+	} {
+		if rec := put(bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+
+	rec := put(`{"primaries":[{"ip":"2001:DB8::1","port":5353,"tsig_key":"Cust-A"}],"allow_transfer":[{"address":"198.51.100.7/24","tsig_key":"cust-a."}],"notify_source":"192.0.2.200"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d body=%q", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/zones/settings.test/settings", nil)
+	req = mux.SetURLVars(req, map[string]string{"zone": "settings.test"})
+	getRec := httptest.NewRecorder()
+	GetZoneSettingsHandler(getRec, req)
+	var got zoneSettingsResponse
+	if err := json.Unmarshal(getRec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode settings: %v", err)
+	}
+	if got.Zone != "settings.test." || len(got.Primaries) != 1 || got.Primaries[0].IP != "2001:db8::1" || got.Primaries[0].TSIGKey != "cust-a." {
+		t.Fatalf("settings = %+v", got)
+	}
+	if len(got.AllowTransfer) != 1 || got.AllowTransfer[0].Address != "198.51.100.0/24" || got.NotifySource != "192.0.2.200" {
+		t.Fatalf("settings = %+v", got)
+	}
+
+	if err := zonemeta.SetPreserveReadOnly("settings.test.", 1); err != nil {
+		t.Fatalf("SetPreserveReadOnly: %v", err)
+	}
+	meta, _ := zonemeta.Load("settings.test.")
+	if !meta.ReadOnly || len(meta.Primaries) != 1 {
+		t.Fatalf("preserve import dropped the zone settings: %+v", meta)
+	}
+
+	if rec := put(`{}`); rec.Code != http.StatusOK {
+		t.Fatalf("clearing PUT status = %d", rec.Code)
+	}
+	if meta, _ := zonemeta.Load("settings.test."); len(meta.Primaries) != 0 || !meta.ReadOnly {
+		t.Fatalf("settings after clearing = %+v", meta)
+	}
+}
+
+func TestPutZoneSettingsRefusesOfflineKSKWithStoredKSK(t *testing.T) {
+	setupHandlerTestStore(t)
+	if err := security.InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	now := time.Now().Unix()
+	kskID, _, err := security.GenerateRolloverKey("offline.test.", security.KeyRoleKSK, "ECDSAP256SHA256", now-10, now-10)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+
+	put := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/zones/offline.test/settings", strings.NewReader(`{"offline_ksk":true}`))
+		req = mux.SetURLVars(req, map[string]string{"zone": "offline.test"})
+		rec := httptest.NewRecorder()
+		PutZoneSettingsHandler(rec, req)
+		return rec
+	}
+	if rec := put(); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), kskID) {
+		t.Fatalf("PUT with a stored KSK = %d %q", rec.Code, rec.Body.String())
+	}
+	if security.OfflineKSK("offline.test.") {
+		t.Fatal("offline_ksk saved despite the stored KSK")
+	}
+
+	if err := security.DeleteStoredKey(kskID); err != nil {
+		t.Fatalf("DeleteStoredKey: %v", err)
+	}
+	if rec := put(); rec.Code != http.StatusOK || !security.OfflineKSK("offline.test.") {
+		t.Fatalf("PUT without a stored KSK = %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPromoteAndDemoteZoneHandlers(t *testing.T) {
+	setupHandlerTestStore(t)
+	config.AppConfig.LiveForTest().Primary.Ip = ""
+	mem := rtypes.GetMemStore()
+	if err := mem.PutRecordRaw("partner.test.", "SOA", "@", types.SOARecord{Ns: "ns1.partner.test.", Mbox: "hostmaster.partner.test.", Serial: 7, TTL: 300}); err != nil {
+		t.Fatalf("put SOA: %v", err)
+	}
+
+	call := func(handler http.HandlerFunc, action, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/zones/partner.test/"+action, strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"zone": "partner.test"})
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := call(DemoteZoneHandler, "demote", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("demoting a secondary zone = %d, want 409", rec.Code)
+	}
+	if rec := call(PromoteZoneHandler, "promote", ""); rec.Code != http.StatusOK {
+		t.Fatalf("promote = %d body=%q", rec.Code, rec.Body.String())
+	}
+	if zonemeta.IsSecondary("partner.test.") {
+		t.Fatalf("promoted zone is still a secondary")
+	}
+	if rec := call(PromoteZoneHandler, "promote", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("promoting a primary zone = %d, want 409", rec.Code)
+	}
+
+	if rec := call(DemoteZoneHandler, "demote", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("demote without a primary = %d, want 400", rec.Code)
+	}
+	rec := call(DemoteZoneHandler, "demote", `{"primaries":[{"ip":"192.0.2.1","port":5353}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("demote = %d body=%q", rec.Code, rec.Body.String())
+	}
+	meta, _ := zonemeta.Load("partner.test.")
+	if meta.Role != zonemeta.RoleSecondary || len(meta.Primaries) != 1 || meta.Primaries[0].Addr() != "192.0.2.1:5353" {
+		t.Fatalf("meta after demote = %+v", meta)
+	}
+	if _, _, raw, ok := mem.GetRecord("partner.test.", "SOA", "@"); !ok || raw.(types.SOARecord).Serial <= 7 {
+		t.Fatalf("SOA after promote and demote = %+v", raw)
+	}
+}
+
+func TestGetZoneStatusHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+	mem := rtypes.GetMemStore()
+	if err := mem.PutRecordRaw("status.test.", "SOA", "@", types.SOARecord{Ns: "ns1.status.test.", Mbox: "hostmaster.status.test.", Serial: 7, TTL: 300}); err != nil {
+		t.Fatalf("put SOA: %v", err)
+	}
+	state, _ := json.Marshal(map[string]any{"zone": "status.test.", "serial": 7, "last_transfer_at": 1700000000, "next_check_at": 1700003600})
+	if err := storage.Backend.SaveTable(dnsutils.SecondaryTimersTable, "status.test.", state); err != nil {
+		t.Fatalf("save timers: %v", err)
+	}
+
+	get := func(zoneName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/zones/"+zoneName+"/status", nil)
+		req = mux.SetURLVars(req, map[string]string{"zone": zoneName})
+		rec := httptest.NewRecorder()
+		GetZoneStatusHandler(rec, req)
+		return rec
+	}
+
+	rec := get("status.test")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	var resp zoneStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Role != zonemeta.RoleSecondary || !resp.Loaded || resp.Serial != 7 {
+		t.Fatalf("status = %+v", resp)
+	}
+	if resp.Secondary == nil || resp.Secondary.LastTransfer != 1700000000 || resp.Secondary.NextCheck != 1700003600 {
+		t.Fatalf("secondary timers = %+v", resp.Secondary)
+	}
+
+	config.AppConfig.LiveForTest().Mode = "primary"
+	if rec := get("missing.test"); rec.Code != http.StatusNotFound {
+		t.Fatalf("status of unknown zone = %d, want 404", rec.Code)
+	}
+}
+
+func TestGetZonePropagationHandler(t *testing.T) {
+	setupHandlerTestStore(t)
+	mem := rtypes.GetMemStore()
+	if err := mem.PutRecordRaw("prop.test.", "SOA", "@", types.SOARecord{Ns: "ns1.prop.test.", Mbox: "hostmaster.prop.test.", Serial: 9, TTL: 300}); err != nil {
+		t.Fatalf("put SOA: %v", err)
+	}
+
+	get := func(zoneName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/zones/"+zoneName+"/propagation", nil)
+		req = mux.SetURLVars(req, map[string]string{"zone": zoneName})
+		rec := httptest.NewRecorder()
+		GetZonePropagationHandler(rec, req)
+		return rec
+	}
+
+	rec := get("prop.test")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	var resp dnsutils.ZonePropagation
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Zone != "prop.test." || resp.Serial != 9 || resp.Complete {
+		t.Fatalf("propagation = %+v", resp)
+	}
+
+	if rec := get("missing.test"); rec.Code != http.StatusNotFound {
+		t.Fatalf("propagation of unknown zone = %d, want 404", rec.Code)
+	}
+}
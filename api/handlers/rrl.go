@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go53/dns/dnsutils"
+)
+
+// GetRRLStatsHandler reports the Response Rate Limiting counters of this node.
+func GetRRLStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, dnsutils.RRLStatsSnapshot())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go53/dns/dnsutils"
+	"go53/internal"
+)
+
+// defaultKSRDays is the period a key signing request covers unless asked
+// otherwise.
+const defaultKSRDays = 90
+
+// ExportKSRHandler returns the key signing request of an offline-KSK zone
+// for the period from ?from (default now) until ?to, or ?days after from.
+func ExportKSRHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	from := time.Now().Unix()
+	if v := query.Get("from"); v != "" {
+		if from, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "from must be a Unix time", http.StatusBadRequest)
+			return
+		}
+	}
+	days := defaultKSRDays
+	if v := query.Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil || days <= 0 {
+			http.Error(w, "days must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	to := from + int64(days)*24*3600
+	if v := query.Get("to"); v != "" {
+		if to, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "to must be a Unix time", http.StatusBadRequest)
+			return
+		}
+	}
+	ksr, err := dnsutils.ExportKSR(zoneName, from, to)
+	if err != nil {
+		http.Error(w, "KSR export failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, ksr)
+}
+
+// ImportSKRHandler stores the signed key response in the body for the zone.
+func ImportSKRHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		http.Error(w, "failed to read SKR body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	status, err := dnsutils.ImportSKR(zoneName, data)
+	if err != nil {
+		http.Error(w, "SKR import failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, status)
+}
+
+// GetSKRStatusHandler reports the imported SKR of the zone.
+func GetSKRStatusHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	status, err := dnsutils.SKRStatusOf(zoneName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, status)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/miekg/dns"
+
+	"go53/config"
+	"go53/dns/dnsutils"
+	"go53/internal"
+	"go53/zone"
+)
+
+type viewListItem struct {
+	config.ViewConfig
+	Zones []string `json:"zones"`
+}
+
+// viewScope resolves the {view} path variable to the zones of a configured
+// view.
+func viewScope(w http.ResponseWriter, r *http.Request) (zone.Scope, bool) {
+	zones, ok, err := zone.ForConfiguredView(mux.Vars(r)["view"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return zone.Scope{}, false
+	}
+	if !ok {
+		http.Error(w, "view not found", http.StatusNotFound)
+		return zone.Scope{}, false
+	}
+	return zones, true
+}
+
+// GET /api/views
+func ListViewsHandler(w http.ResponseWriter, r *http.Request) {
+	items := []viewListItem{}
+	for _, v := range config.AppConfig.GetLive().Views {
+		item := viewListItem{ViewConfig: v, Zones: []string{}}
+		if zones, err := zone.ForView(v.Name, v.Overlay); err == nil {
+			item.Zones = zones.Store().ZoneNamesSnapshot()
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, items)
+}
+
+// GET /api/views/{view}/zones
+func GetViewZonesHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	names := zones.Store().ZoneNamesSnapshot()
+	limit, offset := pageParams(r)
+	writeJSON(w, pageResult{
+		Items:  pageSlice(names, limit, offset),
+		Limit:  limit,
+		Offset: offset,
+		Total:  len(names),
+	})
+}
+
+// DELETE /api/views/{view}/zones/{zone}
+func DeleteViewZoneHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone", http.StatusBadRequest)
+		return
+	}
+	if err := zones.DeleteZone(zoneName); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := dnsutils.RecordViewZoneDelete(zones, zoneName); err != nil {
+		http.Error(w, "zone deleted but change log failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/views/{view}/zones/{zone}/records lists the records stored in the
+// view. For an overlay view these are only the RRsets it overrides.
+func ListViewZoneRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone", http.StatusBadRequest)
+		return
+	}
+	store := zones.Store()
+	if !zoneExists(store.ZoneNamesSnapshot(), zoneName) {
+		http.Error(w, "zone not found", http.StatusNotFound)
+		return
+	}
+	items := flattenZoneRecords(zoneName, store.ZoneRecordsSnapshot(zoneName), "")
+	limit, offset := pageParams(r)
+	writeJSON(w, pageResult{
+		Items:  pageSlice(items, limit, offset),
+		Limit:  limit,
+		Offset: offset,
+		Total:  len(items),
+	})
+}
+
+// POST /api/views/{view}/zones/{zone}/records/{rrtype}
+func AddViewRecordHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	zoneName := vars["zone"]
+	rrtype, err := internal.RRTypeStringToUint16(vars["rrtype"])
+	if err != nil {
+		http.Error(w, "Unknown RR type", http.StatusBadRequest)
+		return
+	}
+	req, reqErr := newAddRecordRequest(r.Body, zoneName, rrtype)
+	if reqErr != nil {
+		http.Error(w, reqErr.message, reqErr.status)
+		return
+	}
+	if err := zones.AddRecord(rrtype, zoneName, req.name, req.value, req.ttlPtr); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := afterViewRecordChange(zones, zoneName, vars["rrtype"], rrtype, req.name); err != nil {
+		http.Error(w, "record stored but change log failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GET /api/views/{view}/zones/{zone}/records/{rrtype}/{name} returns the
+// RRset as clients of the view see it.
+func GetViewRecordHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	rrtype, err := internal.RRTypeStringToUint16(vars["rrtype"])
+	if err != nil {
+		http.Error(w, "Unknown RR type", http.StatusBadRequest)
+		return
+	}
+	rec, found := zones.LookupRecord(rrtype, vars["name"])
+	if !found {
+		http.Error(w, "Record not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, rec)
+}
+
+// DELETE /api/views/{view}/zones/{zone}/records/{rrtype}/{name}
+func DeleteViewRecordHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	rrtype, err := internal.RRTypeStringToUint16(vars["rrtype"])
+	if err != nil {
+		http.Error(w, "Unknown RR type", http.StatusBadRequest)
+		return
+	}
+	var value interface{}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&value); err != nil && err != io.EOF {
+			http.Error(w, "Invalid JSON in request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := zones.DeleteRecord(rrtype, vars["name"], value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := afterViewRecordChange(zones, vars["zone"], vars["rrtype"], rrtype, vars["name"]); err != nil {
+		http.Error(w, "record deleted but change log failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/views/{view}/zones/{zone}/export writes the zone as the view
+// serves it, so an overlay view exports the merged zone.
+func ExportViewZoneHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone", http.StatusBadRequest)
+		return
+	}
+	rrs, ok := zones.LookupRecord(dns.TypeAXFR, zoneName)
+	if !ok {
+		http.Error(w, "zone not found", http.StatusNotFound)
+		return
+	}
+	if len(rrs) > 1 && rrs[0].String() == rrs[len(rrs)-1].String() {
+		rrs = rrs[:len(rrs)-1]
+	}
+	w.Header().Set("Content-Type", "text/dns; charset=utf-8")
+	for _, rr := range rrs {
+		_, _ = io.WriteString(w, rr.String()+"\n")
+	}
+}
+
+// POST /api/views/{view}/zones/{zone}/import replaces the view's copy of the
+// zone with a zone file. An overlay view may import a partial zone holding
+// only the RRsets it overrides.
+func ImportViewZoneHandler(w http.ResponseWriter, r *http.Request) {
+	zones, ok := viewScope(w, r)
+	if !ok {
+		return
+	}
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 10<<20))
+	if err != nil {
+		http.Error(w, "failed to read zone file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	parser := dns.NewZoneParser(strings.NewReader(string(body)), zoneName, "")
+	records := []dns.RR{}
+	hasSOA := false
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			hasSOA = true
+		}
+		records = append(records, rr)
+	}
+	if err := parser.Err(); err != nil {
+		http.Error(w, "invalid zone file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !hasSOA && !zones.Overlay() {
+		http.Error(w, "zone file must contain an SOA record", http.StatusBadRequest)
+		return
+	}
+	if err := dnsutils.ImportViewRecords(zones, zoneName, records); err != nil {
+		http.Error(w, "zone import failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := dnsutils.RecordViewZone(zones, zoneName); err != nil {
+		http.Error(w, "zone imported but change log failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{"view": zones.View(), "zone": zoneName, "records": len(records)})
+}
+
+// afterViewRecordChange bumps the SOA serial of the view's zone and records
+// the changed RRset and the SOA in the WAL and with distributed peers.
+func afterViewRecordChange(zones zone.Scope, zoneName, rrtypeStr string, rrtype uint16, name string) error {
+	if rrtype != dns.TypeSOA {
+		if err := dnsutils.UpdateViewSOASerial(zones, zoneName); err != nil {
+			log.Printf("warning: failed to update SOA serial in view %s: %v", zones.View(), err)
+		}
+	}
+	if err := dnsutils.RecordViewRRset(zones, zoneName, rrtypeStr, name); err != nil {
+		return err
+	}
+	if rrtype == dns.TypeSOA {
+		return nil
+	}
+	return dnsutils.RecordViewRRset(zones, zoneName, "SOA", "@")
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"go53/config"
+	"go53/dns/dnsutils"
+	"go53/internal"
+	"go53/security"
+	"go53/types"
+	zonepkg "go53/zone"
+	"go53/zone/rtypes"
+	"go53/zonemeta"
+)
+
+type zoneRoleResponse struct {
+	Zone      string            `json:"zone"`
+	Role      string            `json:"role"`
+	Primaries []zonemeta.Server `json:"primaries,omitempty"`
+}
+
+type demoteZoneRequest struct {
+	Primaries []zonemeta.Server `json:"primaries"`
+}
+
+// PromoteZoneHandler turns a secondary zone into one edited on this server.
+// The transferred data and any DNSSEC keys stay; with DNSSEC enabled the zone
+// is re-signed with the local keys, so a signed zone needs its private keys
+// imported first. On a distributed node the zone joins replication.
+func PromoteZoneHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		http.Error(w, "failed to load zone metadata: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if meta.EffectiveRole() != zonemeta.RoleSecondary {
+		http.Error(w, "zone is not a secondary", http.StatusConflict)
+		return
+	}
+	store := rtypes.GetMemStore()
+	if store == nil || !store.HasZone(zoneName) {
+		http.Error(w, "zone has not been transferred yet", http.StatusConflict)
+		return
+	}
+	live := config.AppConfig.GetLive()
+	if live.DNSSECEnabled {
+		_, _, _, signed := store.GetRecord(zoneName, string(types.TypeDNSKEY), "@")
+		keys, err := security.LoadPublishedKeysForZone(zoneName, time.Now().Unix())
+		if err != nil {
+			http.Error(w, "failed to load DNSSEC keys: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if signed && len(keys) == 0 {
+			http.Error(w, "zone is signed but has no local DNSSEC keys; import its private keys before promoting", http.StatusConflict)
+			return
+		}
+	}
+
+	role := zonemeta.RolePrimary
+	if live.Mode == zonemeta.RoleDistributed {
+		role = zonemeta.RoleDistributed
+	}
+	if err := saveZoneRole(zoneName, role, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	dnsutils.ForgetSecondaryTimers(zoneName)
+	if err := zonepkg.RefreshDNSSECKeyMaterial(zoneName); err != nil {
+		log.Printf("promote %s: re-signing failed: %v", zoneName, err)
+	}
+	if err := dnsutils.UpdateSOASerial(zoneName); err != nil {
+		log.Printf("promote %s: failed to update SOA serial: %v", zoneName, err)
+	}
+	if err := dnsutils.EnsureCatalogMember(zoneName); err != nil {
+		log.Printf("promote %s: catalog update failed: %v", zoneName, err)
+	}
+	go dnsutils.ScheduleNotify(zoneName)
+	writeJSON(w, zoneRoleResponse{Zone: zoneName, Role: role})
+}
+
+// DemoteZoneHandler makes a zone a secondary of another server, for example
+// the new primary during a migration. The body may name the primaries to
+// transfer from; without them the zone needs primaries from its settings, the
+// catalog or the global configuration. Local data and DNSSEC keys are kept
+// until the first transfer replaces the data. The zone does not have to exist
+// yet, which adds a partner zone to a primary server.
+func DemoteZoneHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	var req demoteZoneRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	settings := zonemeta.Settings{Primaries: req.Primaries}
+	if err := settings.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, key := range settings.TSIGKeys() {
+		if _, ok := security.GetTSIGKey(key); !ok {
+			http.Error(w, "unknown TSIG key "+key, http.StatusBadRequest)
+			return
+		}
+	}
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		http.Error(w, "failed to load zone metadata: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if meta.EffectiveRole() == zonemeta.RoleSecondary && len(settings.Primaries) == 0 {
+		http.Error(w, "zone is already a secondary", http.StatusConflict)
+		return
+	}
+	if len(settings.Primaries) == 0 && !dnsutils.HasTransferPrimary(zoneName) {
+		http.Error(w, "no primary to transfer the zone from", http.StatusBadRequest)
+		return
+	}
+
+	if err := saveZoneRole(zoneName, zonemeta.RoleSecondary, settings.Primaries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	dnsutils.EnqueueZoneFetch(zoneName)
+	meta, _ = zonemeta.Load(zoneName)
+	writeJSON(w, zoneRoleResponse{Zone: zoneName, Role: zonemeta.RoleSecondary, Primaries: meta.Primaries})
+}
+
+// saveZoneRole stores the role of zoneName and, when given, its primaries,
+// and records the change in the WAL.
+func saveZoneRole(zoneName, role string, primaries []zonemeta.Server) error {
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		return err
+	}
+	meta.Role = role
+	if len(primaries) > 0 {
+		meta.Primaries = primaries
+	}
+	if err := zonemeta.Save(meta); err != nil {
+		return err
+	}
+	return appendZoneMetaWAL(zoneName)
+}
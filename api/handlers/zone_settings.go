@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"go53/config"
+	"go53/internal"
+	"go53/security"
+	"go53/storage"
+	"go53/wal"
+	"go53/zonemeta"
+)
+
+type zoneSettingsResponse struct {
+	Zone string `json:"zone"`
+	Role string `json:"role"`
+	zonemeta.Settings
+}
+
+// GetZoneSettingsHandler returns the role, per-zone primaries, transfer ACL,
+// notify targets, notify source and DNSSEC policy of a zone.
+func GetZoneSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		http.Error(w, "failed to load zone settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, zoneSettingsResponse{Zone: zoneName, Role: meta.EffectiveRole(), Settings: meta.Settings})
+}
+
+// PutZoneSettingsHandler replaces the settings of a zone. The zone does not
+// have to exist yet, so a secondary can be told where to fetch it from. TSIG
+// keys must already be loaded. offline_ksk is refused while private KSK keys
+// are stored for the zone. An empty object clears the settings and the zone
+// falls back to the global configuration.
+func PutZoneSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	var settings zonemeta.Settings
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := settings.Normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, key := range settings.TSIGKeys() {
+		if _, ok := security.GetTSIGKey(key); !ok {
+			http.Error(w, "unknown TSIG key "+key, http.StatusBadRequest)
+			return
+		}
+	}
+	if name := settings.DNSSECPolicy; name != "" && name != zonemeta.PolicyNone {
+		if _, ok := config.AppConfig.GetLive().DNSSEC.Policies[name]; !ok {
+			http.Error(w, "unknown DNSSEC policy "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if settings.OfflineKSK && !security.OfflineKSK(zoneName) {
+		ids, err := security.StoredKSKPrivateKeys(zoneName)
+		if err != nil {
+			http.Error(w, "failed to load DNSSEC keys: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(ids) > 0 {
+			http.Error(w, "zone stores the private keys of KSKs "+strings.Join(ids, ", ")+"; move them to the offline signer and delete them before enabling offline_ksk", http.StatusConflict)
+			return
+		}
+	}
+
+	if err := zonemeta.SaveSettings(zoneName, settings); err != nil {
+		http.Error(w, "failed to save zone settings: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := appendZoneMetaWAL(zoneName); err != nil {
+		http.Error(w, "zone settings saved but WAL append failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, zoneSettingsResponse{Zone: zoneName, Role: zonemeta.RoleOf(zoneName), Settings: settings})
+}
+
+// appendZoneMetaWAL records the stored metadata of zoneName so a point-in-time
+// restore brings it back.
+func appendZoneMetaWAL(zoneName string) error {
+	key := strings.TrimSuffix(zoneName, ".")
+	table, err := storage.Backend.LoadTable(zonemeta.TableName)
+	if err != nil {
+		return err
+	}
+	_, err = wal.Append(wal.KindZoneMeta, wal.OpUpsert, zoneName, "", "", zonemeta.TableName, key, table[key])
+	return err
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/miekg/dns"
+
+	"go53/dns/dnsutils"
+	"go53/internal"
+	zonepkg "go53/zone"
+	"go53/zonemeta"
+)
+
+type zoneStatusResponse struct {
+	Zone      string                        `json:"zone"`
+	Role      string                        `json:"role"`
+	Loaded    bool                          `json:"loaded"`
+	Serial    uint32                        `json:"serial,omitempty"`
+	Secondary *dnsutils.SecondaryZoneStatus `json:"secondary,omitempty"`
+}
+
+// GetZoneStatusHandler returns the role and serial of a zone and, for a
+// secondary, its SOA timers: last check, last transfer, next check and when it
+// expires.
+func GetZoneStatusHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		http.Error(w, "failed to load zone metadata: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := zoneStatusResponse{Zone: zoneName, Role: meta.EffectiveRole()}
+	if rrs, ok := zonepkg.LookupRecord(dns.TypeSOA, zoneName); ok && len(rrs) > 0 {
+		if soa, ok := rrs[0].(*dns.SOA); ok {
+			resp.Loaded = true
+			resp.Serial = soa.Serial
+		}
+	}
+	if resp.Role == zonemeta.RoleSecondary {
+		if st, ok := dnsutils.SecondaryStatus(zoneName); ok {
+			resp.Secondary = &st
+		}
+	}
+	if !resp.Loaded && resp.Secondary == nil && resp.Role != zonemeta.RoleSecondary {
+		http.Error(w, "zone not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// GetZonePropagationHandler reports NOTIFY delivery for the latest serial of a
+// zone and which of its secondaries and name servers serve the current
+// serial. With ?refresh=true they are queried before answering, so a
+// deployment can poll until complete is true.
+func GetZonePropagationHandler(w http.ResponseWriter, r *http.Request) {
+	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
+	if err != nil {
+		http.Error(w, "invalid zone name", http.StatusBadRequest)
+		return
+	}
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	status, err := dnsutils.PropagationStatus(zoneName, refresh)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, status)
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/miekg/dns"
 
-	"go53/config"
 	"go53/distributed"
 	"go53/dns/dnsutils"
 	"go53/internal"
@@ -71,25 +70,34 @@ func AddRecordHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("record add request accepted: rrtype=%s zone_status=present", rrtypeStr)
 
+	if !storeRecord(w, zoneName, rrtypeStr, rrtype, req) {
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// storeRecord adds one record value and runs the WAL, distributed and catalog
+// steps of an API write. On failure it writes the error response itself and
+// returns false.
+func storeRecord(w http.ResponseWriter, zoneName, rrtypeStr string, rrtype uint16, req addRecordRequest) bool {
 	if err := zone.AddRecord(rrtype, zoneName, req.name, req.value, req.ttlPtr); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return false
 	}
 	if err := appendWALRecord(wal.OpUpsert, zoneName, rrtypeStr, req.name, req.value); err != nil {
 		http.Error(w, "record stored but WAL append failed: "+err.Error(), http.StatusInternalServerError)
-		return
+		return false
 	}
 
 	if err := afterRecordUpsert(zoneName, rrtypeStr, rrtype, req.name, req.value); err != nil {
 		http.Error(w, "record stored but distributed event failed: "+err.Error(), http.StatusInternalServerError)
-		return
+		return false
 	}
 	if err := dnsutils.EnsureCatalogMember(zoneName); err != nil {
 		http.Error(w, "record stored but catalog update failed: "+err.Error(), http.StatusInternalServerError)
-		return
+		return false
 	}
-
-	w.WriteHeader(http.StatusCreated)
+	return true
 }
 
 func UpdateRecordHandler(w http.ResponseWriter, r *http.Request) {
@@ -184,7 +192,7 @@ func dnskeyRecordRequest(zoneName string, payload map[string]interface{}) (addRe
 		return addRecordRequest{}, err
 	}
 
-	_, storedKey, loadErr := security.LoadPrivateKeyFromStorage(keyid)
+	storedKey, loadErr := security.LoadStoredKey(keyid)
 	if loadErr != nil {
 		return addRecordRequest{}, &addRecordError{
 			message: fmt.Sprintf("Key not found: %v", loadErr),
@@ -284,7 +292,7 @@ func updateSOAAfterRecordChange(zoneName string) {
 		log.Printf("warning: failed to update SOA serial: %v", err)
 		return
 	}
-	if config.AppConfig.GetLive().Mode != "secondary" {
+	if !zonemeta.IsSecondary(zoneName) {
 		go dnsutils.ScheduleNotify(zoneName)
 	}
 }
@@ -295,11 +303,12 @@ func ListZoneRecordsHandler(w http.ResponseWriter, r *http.Request) {
 
 func ListZoneRecordsByTypeHandler(w http.ResponseWriter, r *http.Request) {
 	rrtypeStr := mux.Vars(r)["rrtype"]
-	if _, err := internal.RRTypeStringToUint16(rrtypeStr); err != nil {
+	rrtype, err := internal.RRTypeStringToUint16(rrtypeStr)
+	if err != nil {
 		http.Error(w, "Unknown RR type", http.StatusBadRequest)
 		return
 	}
-	writeZoneRecords(w, r, strings.ToUpper(rrtypeStr))
+	writeZoneRecords(w, r, internal.TypeName(rrtype))
 }
 
 func writeZoneRecords(w http.ResponseWriter, r *http.Request, onlyType string) {
@@ -391,7 +400,7 @@ func DeleteRecordHandler(w http.ResponseWriter, r *http.Request) {
 	if rrtype != dns.TypeSOA {
 		if err := dnsutils.UpdateSOASerial(zoneName); err != nil {
 			log.Printf("warning: failed to update SOA serial: %v", err)
-		} else if config.AppConfig.GetLive().Mode != "secondary" {
+		} else if !zonemeta.IsSecondary(zoneName) {
 			go dnsutils.ScheduleNotify(zoneName)
 		}
 	}
@@ -448,6 +457,7 @@ func DeleteZoneHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	dnsutils.DropIXFRJournal(zoneName)
 	if _, err := wal.Append(wal.KindZone, wal.OpDelete, zoneName, "", "", "", "", nil); err != nil {
 		http.Error(w, "zone deleted but WAL append failed: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -480,15 +490,15 @@ func publishDistributedZoneDelete(zoneName string, snapshot map[string]map[strin
 }
 
 func TriggerSecondaryFetchHandler(w http.ResponseWriter, r *http.Request) {
-	if config.AppConfig.GetLive().Mode != "secondary" {
-		http.Error(w, "secondary fetch is only available in secondary mode", http.StatusConflict)
-		return
-	}
 	zoneName, err := internal.SanitizeFQDN(mux.Vars(r)["zone"])
 	if err != nil {
 		http.Error(w, "invalid zone", http.StatusBadRequest)
 		return
 	}
+	if !zonemeta.IsSecondary(zoneName) {
+		http.Error(w, "secondary fetch is only available for secondary zones", http.StatusConflict)
+		return
+	}
 	if !dnsutils.EnqueueZoneFetch(zoneName) {
 		http.Error(w, "fetch already pending, rate-limited, or queue full", http.StatusTooManyRequests)
 		return
@@ -600,7 +610,7 @@ func ImportZoneHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "zone imported but catalog update failed: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if config.AppConfig.GetLive().Mode != "secondary" {
+	if !zonemeta.IsSecondary(zoneName) {
 		go dnsutils.ScheduleNotify(zoneName)
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -22,6 +22,7 @@ import (
 	"github.com/gorilla/mux"
 	"go53/api/handlers"
 	"go53/config"
+	"go53/zonemeta"
 	"log"
 	"net"
 	"net/http"
@@ -62,25 +63,49 @@ func NewRouter(cfg config.BaseConfig) http.Handler {
 	r.HandleFunc("/api/zones/{zone}/records/{rrtype}/{name}", disableSecondary(handlers.DeleteRecordHandler)).Methods("DELETE")
 	r.HandleFunc("/api/zones/{zone}/export", handlers.ExportZoneHandler).Methods("GET")
 	r.HandleFunc("/api/zones/{zone}/import", disableSecondary(handlers.ImportZoneHandler)).Methods("POST")
+	r.HandleFunc("/api/zones/{zone}/settings", handlers.GetZoneSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/settings", handlers.PutZoneSettingsHandler).Methods("PUT")
+	r.HandleFunc("/api/zones/{zone}/status", handlers.GetZoneStatusHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/propagation", handlers.GetZonePropagationHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/dnssec/timeline", handlers.GetDNSSECTimelineHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/dnssec/algorithm-rollover", handlers.GetAlgorithmRolloverHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/dnssec/algorithm-rollover", disableSecondary(handlers.StartAlgorithmRolloverHandler)).Methods("POST")
+	r.HandleFunc("/api/zones/{zone}/dnssec/ksr", disableSecondary(handlers.ExportKSRHandler)).Methods("POST")
+	r.HandleFunc("/api/zones/{zone}/dnssec/skr", handlers.GetSKRStatusHandler).Methods("GET")
+	r.HandleFunc("/api/zones/{zone}/dnssec/skr", disableSecondary(handlers.ImportSKRHandler)).Methods("PUT")
+	r.HandleFunc("/api/zones/{zone}/promote", handlers.PromoteZoneHandler).Methods("POST")
+	r.HandleFunc("/api/zones/{zone}/demote", handlers.DemoteZoneHandler).Methods("POST")
+	r.HandleFunc("/api/zones/{zone}/dane", disableSecondary(handlers.GenerateDANERecordHandler)).Methods("POST")
 
 	r.HandleFunc("/api/secondary/fetch/{zone}", handlers.TriggerSecondaryFetchHandler).Methods("POST")
 	r.HandleFunc("/api/notify/{zone}", disableSecondary(handlers.TriggerNotifyHandler)).Methods("POST")
 	r.HandleFunc("/api/catalog", handlers.GetCatalogStatusHandler).Methods("GET")
 	r.HandleFunc("/api/catalog/members", handlers.GetCatalogMembersHandler).Methods("GET")
+	r.HandleFunc("/api/rrl", handlers.GetRRLStatsHandler).Methods("GET")
+	r.HandleFunc("/api/cookies/rotate", handlers.RotateCookieSecretHandler).Methods("POST")
+	r.HandleFunc("/api/views", handlers.ListViewsHandler).Methods("GET")
+	r.HandleFunc("/api/views/{view}/zones", handlers.GetViewZonesHandler).Methods("GET")
+	r.HandleFunc("/api/views/{view}/zones/{zone}", handlers.DeleteViewZoneHandler).Methods("DELETE")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/records", handlers.ListViewZoneRecordsHandler).Methods("GET")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/records/{rrtype}", handlers.AddViewRecordHandler).Methods("POST")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/records/{rrtype}/{name}", handlers.GetViewRecordHandler).Methods("GET")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/records/{rrtype}/{name}", handlers.DeleteViewRecordHandler).Methods("DELETE")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/export", handlers.ExportViewZoneHandler).Methods("GET")
+	r.HandleFunc("/api/views/{view}/zones/{zone}/import", handlers.ImportViewZoneHandler).Methods("POST")
 
 	r.HandleFunc("/api/tsig", handlers.ListTSIGKeysHandler).Methods("GET")
 	r.HandleFunc("/api/tsig/{name}", handlers.AddTSIGKeyHandler).Methods("POST")
 	r.HandleFunc("/api/tsig/{name}", handlers.DeleteTSIGKeyHandler).Methods("DELETE")
 
-	r.HandleFunc("/api/dnskeys", disableSecondary(handlers.ListDNSKeysHandler)).Methods("GET")
-	r.HandleFunc("/api/dnskeys/{keyid}", disableSecondary(handlers.GetDNSKeyHandler)).Methods("GET")
-	r.HandleFunc("/api/dnskeys", disableSecondary(handlers.CreateDNSKeyHandler)).Methods("POST")
-	r.HandleFunc("/api/dnskeys/import-private", disableSecondary(handlers.ImportPrivateDNSKeysHandler)).Methods("POST")
-	r.HandleFunc("/api/dnskeys/rollover", disableSecondary(handlers.CreateRolloverDNSKeyHandler)).Methods("POST")
-	r.HandleFunc("/api/dnskeys/{keyid}/lifecycle", disableSecondary(handlers.UpdateDNSKeyLifecycleHandler)).Methods("PATCH")
-	r.HandleFunc("/api/dnskeys/{keyid}/retire", disableSecondary(handlers.RetireDNSKeyHandler)).Methods("POST")
-	r.HandleFunc("/api/dnskeys/{keyid}/revoke", disableSecondary(handlers.RevokeDNSKeyHandler)).Methods("POST")
-	r.HandleFunc("/api/dnskeys/{keyid}", disableSecondary(handlers.DeleteDNSKeyHandler)).Methods("DELETE")
+	r.HandleFunc("/api/dnskeys", handlers.ListDNSKeysHandler).Methods("GET")
+	r.HandleFunc("/api/dnskeys/{keyid}", handlers.GetDNSKeyHandler).Methods("GET")
+	r.HandleFunc("/api/dnskeys", handlers.CreateDNSKeyHandler).Methods("POST")
+	r.HandleFunc("/api/dnskeys/import-private", handlers.ImportPrivateDNSKeysHandler).Methods("POST")
+	r.HandleFunc("/api/dnskeys/rollover", handlers.CreateRolloverDNSKeyHandler).Methods("POST")
+	r.HandleFunc("/api/dnskeys/{keyid}/lifecycle", handlers.UpdateDNSKeyLifecycleHandler).Methods("PATCH")
+	r.HandleFunc("/api/dnskeys/{keyid}/retire", handlers.RetireDNSKeyHandler).Methods("POST")
+	r.HandleFunc("/api/dnskeys/{keyid}/revoke", handlers.RevokeDNSKeyHandler).Methods("POST")
+	r.HandleFunc("/api/dnskeys/{keyid}", handlers.DeleteDNSKeyHandler).Methods("DELETE")
 
 	r.HandleFunc("/api/ds/{zone}", disableSecondary(handlers.GetDSHandler)).Methods("GET")
 	r.HandleFunc("/api/cds/{zone}", disableSecondary(handlers.GetCDSHandler)).Methods("GET")
@@ -117,10 +142,12 @@ func Start(cfg config.BaseConfig) error {
 	return http.ListenAndServe(addr, handler)
 }
 
+// disableSecondary refuses changes to zones with the secondary role; their
+// content comes from the primary.
 func disableSecondary(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if config.AppConfig.GetLive().Mode == "secondary" {
-			http.Error(w, "Zone/record management is disabled in secondary mode", http.StatusServiceUnavailable)
+		if zonemeta.IsSecondary(mux.Vars(r)["zone"]) {
+			http.Error(w, "Zone/record management is disabled for secondary zones", http.StatusServiceUnavailable)
 			return
 		}
 		handler.ServeHTTP(w, r)
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"go53/distributed"
+	"go53/security"
 
 	"github.com/dgraph-io/badger/v4"
 )
@@ -298,14 +299,15 @@ Commands:
   backup              Export backup WAL data over the local admin socket
   restore             Restore backup or WAL data over the local admin socket
   config               Read or patch live runtime config
-  zones                List, delete, import, or export zones
+  zones                List, delete, import, or export zones; per-zone settings, role and status
   records              List, add, get, patch, or delete records
   catalog              Inspect catalog-zone status and members
   secondary            Trigger secondary transfer fetches
-  notify               Schedule DNS NOTIFY
+  notify               Schedule DNS NOTIFY or show propagation
   tsig                 Manage TSIG keys
   dnskeys              Manage DNSSEC keys
   ds|cds|cdnskey       Generate parent-signaling records
+  skr                  Offline KSK: key signing requests and signed key responses
   distributed          Inspect and repair distributed state
   docs                 Fetch OpenAPI docs or Swagger HTML
   cluster invite       Create a JWT invite token for a new distributed node
@@ -610,6 +612,8 @@ func handleAdminCommand(command string, args []string) bool {
 		handleAdminDNSKeys(args)
 	case "ds", "cds", "cdnskey":
 		handleAdminParentSignal(command, args)
+	case "skr":
+		handleSKR(args)
 	case "distributed":
 		handleAdminDistributed(args)
 	case "docs":
@@ -1176,6 +1180,46 @@ func handleAdminZones(args []string) {
 			path += "?dnssec=" + dnssecMode
 		}
 		mustAdminRequest(*opts, http.MethodPost, path, string(data), "text/dns")
+	case "settings":
+		requireArgs(rest, 1, printZonesUsage)
+		if len(rest) > 1 {
+			mustAdminRequest(*opts, http.MethodPut, "/api/zones/"+rest[0]+"/settings", rest[1], "application/json")
+			return
+		}
+		mustAdminRequest(*opts, http.MethodGet, "/api/zones/"+rest[0]+"/settings", "", "")
+	case "status":
+		requireArgs(rest, 1, printZonesUsage)
+		mustAdminRequest(*opts, http.MethodGet, "/api/zones/"+rest[0]+"/status", "", "")
+	case "promote":
+		requireArgs(rest, 1, printZonesUsage)
+		mustAdminRequest(*opts, http.MethodPost, "/api/zones/"+rest[0]+"/promote", "", "")
+	case "demote":
+		requireArgs(rest, 1, printZonesUsage)
+		body := ""
+		if len(rest) > 1 {
+			primaries := make([]map[string]any, 0, len(rest)-1)
+			for _, addr := range rest[1:] {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					host, port = addr, ""
+				}
+				primary := map[string]any{"ip": host}
+				if port != "" {
+					n, err := strconv.Atoi(port)
+					if err != nil {
+						log.Fatalf("invalid primary %q", addr)
+					}
+					primary["port"] = n
+				}
+				primaries = append(primaries, primary)
+			}
+			data, err := json.Marshal(map[string]any{"primaries": primaries})
+			if err != nil {
+				log.Fatal(err)
+			}
+			body = string(data)
+		}
+		mustAdminRequest(*opts, http.MethodPost, "/api/zones/"+rest[0]+"/demote", body, "application/json")
 	default:
 		printZonesUsage()
 		os.Exit(1)
@@ -1188,12 +1232,21 @@ func printZonesUsage() {
   go53ctl zones delete ZONE [--socket PATH|--api URL]
   go53ctl zones export ZONE [--socket PATH|--api URL]
   go53ctl zones import ZONE FILE [--dnssec preserve] [--socket PATH|--api URL]
+  go53ctl zones settings ZONE [JSON] [--socket PATH|--api URL]
+  go53ctl zones status ZONE [--socket PATH|--api URL]
+  go53ctl zones promote ZONE [--socket PATH|--api URL]
+  go53ctl zones demote ZONE [PRIMARY[:PORT]...] [--socket PATH|--api URL]
 
 Examples:
   go53ctl zones list --limit 50
   go53ctl zones export example.com. > example.com.zone
   go53ctl zones import example.com. example.com.zone
-  go53ctl zones import example.com. signed.zone --dnssec preserve`)
+  go53ctl zones import example.com. signed.zone --dnssec preserve
+  go53ctl zones settings example.com.
+  go53ctl zones settings example.com. '{"primaries":[{"ip":"192.0.2.1","tsig_key":"example-xfr."}],"allow_transfer":[{"address":"198.51.100.0/24","tsig_key":"example-xfr."}]}'
+  go53ctl zones status example.com.
+  go53ctl zones promote partner.example.
+  go53ctl zones demote example.com. 192.0.2.1 198.51.100.7:5353`)
 }
 
 func handleAdminRecords(args []string) {
@@ -1292,6 +1345,10 @@ func printSecondaryUsage() {
 }
 
 func handleAdminNotify(args []string) {
+	if len(args) > 0 && args[0] == "status" {
+		handleNotifyStatus(args[1:])
+		return
+	}
 	fs, opts := newAdminFlagSet("notify", false)
 	_ = fs.Parse(args)
 	rest := fs.Args()
@@ -1299,9 +1356,48 @@ func handleAdminNotify(args []string) {
 	mustAdminRequest(*opts, http.MethodPost, "/api/notify/"+rest[0], "", "")
 }
 
+// handleNotifyStatus prints the NOTIFY and propagation state of a zone. With
+// --wait it re-checks every two seconds until every target serves the current
+// serial, and exits non-zero if that does not happen in time.
+func handleNotifyStatus(args []string) {
+	fs, opts := newAdminFlagSet("notify status", false)
+	refresh := fs.Bool("refresh", false, "Query the targets before reporting")
+	wait := fs.Int("wait", 0, "Seconds to wait for all targets to catch up")
+	_ = fs.Parse(args)
+	rest := fs.Args()
+	requireArgs(rest, 1, printNotifyUsage)
+
+	path := "/api/zones/" + rest[0] + "/propagation"
+	if *refresh || *wait > 0 {
+		path += "?refresh=true"
+	}
+	deadline := time.Now().Add(time.Duration(*wait) * time.Second)
+	for {
+		data, err := adminRequest(*opts, http.MethodGet, path, "", "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		var status struct {
+			Complete bool `json:"complete"`
+		}
+		_ = json.Unmarshal(data, &status)
+		if status.Complete || *wait <= 0 {
+			printResponse(data)
+			return
+		}
+		if time.Now().After(deadline) {
+			printResponse(data)
+			fmt.Fprintf(os.Stderr, "zone %s did not propagate within %ds\n", rest[0], *wait)
+			os.Exit(1)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
 func printNotifyUsage() {
 	fmt.Println(`Usage:
-  go53ctl notify ZONE [--socket PATH|--api URL]`)
+  go53ctl notify ZONE [--socket PATH|--api URL]
+  go53ctl notify status ZONE [--refresh] [--wait SECONDS] [--socket PATH|--api URL]`)
 }
 
 func handleAdminTSIG(args []string) {
@@ -1396,6 +1492,17 @@ func handleAdminDNSKeys(args []string) {
 	case "delete":
 		requireArgs(rest, 1, printDNSKeysUsage)
 		mustAdminRequest(*opts, http.MethodDelete, "/api/dnskeys/"+rest[0], "", "")
+	case "timeline":
+		requireArgs(rest, 1, printDNSKeysUsage)
+		mustAdminRequest(*opts, http.MethodGet, "/api/zones/"+rest[0]+"/dnssec/timeline", "", "")
+	case "algorithm-rollover":
+		requireArgs(rest, 1, printDNSKeysUsage)
+		path := "/api/zones/" + rest[0] + "/dnssec/algorithm-rollover"
+		if len(rest) > 1 {
+			mustAdminRequest(*opts, http.MethodPost, path, fmt.Sprintf(`{"algorithm":%q}`, rest[1]), "application/json")
+			return
+		}
+		mustAdminRequest(*opts, http.MethodGet, path, "", "")
 	default:
 		printDNSKeysUsage()
 		os.Exit(1)
@@ -1413,7 +1520,9 @@ func printDNSKeysUsage() {
   go53ctl dnskeys lifecycle KEYID JSON
   go53ctl dnskeys retire KEYID [REMOVE_AFTER_DAYS]
   go53ctl dnskeys revoke KEYID [REMOVE_AFTER_DAYS]
-  go53ctl dnskeys delete KEYID`)
+  go53ctl dnskeys delete KEYID
+  go53ctl dnskeys timeline ZONE
+  go53ctl dnskeys algorithm-rollover ZONE [ALGORITHM]`)
 }
 
 func handleAdminParentSignal(kind string, args []string) {
@@ -1421,6 +1530,8 @@ func handleAdminParentSignal(kind string, args []string) {
 	digest := fs.String("digest", "", "Comma-separated DNSSEC digest type numbers")
 	ttl := fs.Int("ttl", 0, "TTL for CDS/CDNSKEY delete signaling")
 	deleteSignal := fs.Bool("delete", false, "Return parent delete signaling records")
+	status := fs.Bool("status", false, "ds: include the last check of the DS at the parent")
+	refresh := fs.Bool("refresh", false, "ds: check the DS at the parent now; implies --status")
 	_ = fs.Parse(args)
 	rest := fs.Args()
 	requireArgs(rest, 1, func() { printParentSignalUsage(kind) })
@@ -1435,6 +1546,12 @@ func handleAdminParentSignal(kind string, args []string) {
 	if *ttl > 0 {
 		query = append(query, fmt.Sprintf("ttl=%d", *ttl))
 	}
+	if kind == "ds" && (*status || *refresh) {
+		query = append(query, "status=true")
+		if *refresh {
+			query = append(query, "refresh=true")
+		}
+	}
 	if len(query) > 0 {
 		path += "?" + strings.Join(query, "&")
 	}
@@ -1442,11 +1559,161 @@ func handleAdminParentSignal(kind string, args []string) {
 }
 
 func printParentSignalUsage(kind string) {
+	if kind == "ds" {
+		fmt.Print(`Usage:
+  go53ctl ds ZONE [--digest LIST] [--status] [--refresh] [--socket PATH|--api URL]
+`)
+		return
+	}
 	fmt.Printf(`Usage:
   go53ctl %s ZONE [--digest LIST] [--delete] [--ttl N] [--socket PATH|--api URL]
 `, kind)
 }
 
+// handleSKR runs the offline KSK workflow. keygen and sign run on the
+// offline signer and never contact a server; request, import and status talk
+// to the server holding the ZSKs.
+func handleSKR(args []string) {
+	if len(args) == 0 {
+		printSKRUsage()
+		os.Exit(1)
+	}
+	fs, opts := newAdminFlagSet("skr "+args[0], false)
+	out := fs.String("out", "", "Write the result to FILE instead of stdout")
+	var algorithm, ksrFile *string
+	var from, to *int64
+	var days *int
+	var noCDS *bool
+	var keyFiles repeatedFlag
+	switch args[0] {
+	case "keygen":
+		algorithm = fs.String("algorithm", "ECDSAP256SHA256", "KSK algorithm: ECDSAP256SHA256, ECDSAP384SHA384 or ED25519")
+	case "sign":
+		ksrFile = fs.String("ksr", "", "Key signing request file")
+		fs.Var(&keyFiles, "key", "KSK private-key file; repeat for several KSKs")
+		noCDS = fs.Bool("no-cds", false, "Leave CDS and CDNSKEY out of the bundles")
+	case "request":
+		from = fs.Int64("from", 0, "Start of the period, Unix time (default now)")
+		to = fs.Int64("to", 0, "End of the period, Unix time")
+		days = fs.Int("days", 0, "Length of the period in days (default 90)")
+	}
+	rest := parseInterspersedFlags(fs, args[1:])
+
+	switch args[0] {
+	case "keygen":
+		requireArgs(rest, 1, printSKRUsage)
+		if *out == "" {
+			log.Fatal("skr keygen needs --out FILE for the private key")
+		}
+		file, err := security.GenerateOfflineKSK(rest[0], *algorithm)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("KSK %d of %s written to %s\n", file.Keys[0].KeyTag, file.Zone, *out)
+	case "sign":
+		if *ksrFile == "" || len(keyFiles) == 0 {
+			printSKRUsage()
+			os.Exit(1)
+		}
+		raw, err := os.ReadFile(*ksrFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var ksr security.KeySigningRequest
+		if err := json.Unmarshal(raw, &ksr); err != nil {
+			log.Fatalf("invalid KSR: %v", err)
+		}
+		var ksks []security.OfflineKey
+		for _, path := range keyFiles {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			keys, err := security.LoadOfflineKeys(data)
+			if err != nil {
+				log.Fatalf("%s: %v", path, err)
+			}
+			ksks = append(ksks, keys...)
+		}
+		skr, err := security.SignKSR(ksr, ksks, !*noCDS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := json.Marshal(skr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeSKROutput(*out, data)
+	case "request":
+		requireArgs(rest, 1, printSKRUsage)
+		query := []string{}
+		if *from > 0 {
+			query = append(query, fmt.Sprintf("from=%d", *from))
+		}
+		if *to > 0 {
+			query = append(query, fmt.Sprintf("to=%d", *to))
+		}
+		if *days > 0 {
+			query = append(query, fmt.Sprintf("days=%d", *days))
+		}
+		path := "/api/zones/" + rest[0] + "/dnssec/ksr"
+		if len(query) > 0 {
+			path += "?" + strings.Join(query, "&")
+		}
+		data, err := adminRequest(*opts, http.MethodPost, path, "", "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeSKROutput(*out, data)
+	case "import":
+		requireArgs(rest, 2, printSKRUsage)
+		data, err := os.ReadFile(rest[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		mustAdminRequest(*opts, http.MethodPut, "/api/zones/"+rest[0]+"/dnssec/skr", string(data), "application/json")
+	case "status":
+		requireArgs(rest, 1, printSKRUsage)
+		mustAdminRequest(*opts, http.MethodGet, "/api/zones/"+rest[0]+"/dnssec/skr", "", "")
+	default:
+		printSKRUsage()
+		os.Exit(1)
+	}
+}
+
+func writeSKROutput(path string, data []byte) {
+	if path == "" {
+		printResponse(data)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printSKRUsage() {
+	fmt.Println(`Usage:
+  go53ctl skr keygen ZONE --out FILE [--algorithm ALGORITHM]          (offline)
+  go53ctl skr sign --ksr FILE --key FILE [--key FILE] [--no-cds] [--out FILE]   (offline)
+  go53ctl skr request ZONE [--from UNIX] [--days N|--to UNIX] [--out FILE]
+  go53ctl skr import ZONE FILE
+  go53ctl skr status ZONE`)
+}
+
 func handleAdminDistributed(args []string) {
 	if len(args) == 0 {
 		printDistributedUsage()
@@ -11,6 +11,7 @@ import (
 	"go53/dns/dnsutils"
 	"go53/memory"
 	"go53/security"
+	"go53/security/signer"
 	"go53/storage"
 	"go53/zone/rtypes"
 	"log"
@@ -34,6 +35,22 @@ func main() {
 		log.Fatalf("Failed to load DNSSEC key cache: %v", err)
 	}
 	base := config.AppConfig.GetBase()
+	provider, err := signer.New(signer.Config{
+		Provider:         base.DNSSECSigner,
+		PKCS11Module:     base.PKCS11Module,
+		PKCS11TokenLabel: base.PKCS11TokenLabel,
+		PKCS11PIN:        base.PKCS11PIN,
+		RemoteURL:        base.RemoteSignerURL,
+		RemoteToken:      base.RemoteSignerToken,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up the DNSSEC signer: %v", err)
+	}
+	if provider != nil {
+		defer provider.Close()
+		security.SetSignerProvider(provider)
+		log.Printf("New DNSSEC keys are generated in the %s signer", provider.Name())
+	}
 	slog.Crazy("Live Config DNSSEC ENABLE is: %b", config.AppConfig.GetLive().DNSSECEnabled)
 
 	const table = "tsig-keys"
@@ -70,7 +87,7 @@ func main() {
 		log.Println("TSIG key generation skipped (generateTSIG flag not set).")
 	}
 
-	err := security.LoadTSIGKeysFromStorage()
+	err = security.LoadTSIGKeysFromStorage()
 	if err != nil {
 		return
 	}
@@ -81,11 +98,16 @@ func main() {
 	}
 	rtypes.InitMemoryStore(store)
 	distributed.Init(store)
+	dnsutils.LoadIXFRJournal()
 
 	ctx := context.Background()
 	go dnsutils.ProcessFetchQueue()
-	// Secondary-mode startup + periodic AXFR refresh. No-op in primary/distributed mode.
+	// Startup + periodic AXFR refresh of secondary zones.
 	dnsutils.StartSecondaryRefresh(ctx)
+	// Scheduled DNSSEC key rollovers of zones with a signing policy.
+	dnsutils.StartKASP(ctx)
+	// Parent DS checks that complete KSK rollovers and withdraw CDS/CDNSKEY.
+	dnsutils.StartParentDSMonitor(ctx)
 	distributed.Start(ctx)
 
 	go func() {
@@ -36,6 +36,10 @@ func ApplyLogLevel(level string) {
 
 var xAuthKeyRe = regexp.MustCompile(`^[A-Za-z0-9]{48,}$`)
 
+// viewNameRe keeps view names safe to use as an API path segment and as part of
+// the "view/<name>/" storage prefix.
+var viewNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
 type BaseConfig struct {
 	BindHost       string
 	DNSPort        string
@@ -50,12 +54,45 @@ type BaseConfig struct {
 	// AdminSocketGroup is the OS group granted access to the admin socket (mode 0660).
 	// When the group does not exist the socket falls back to owner-only access.
 	AdminSocketGroup string
+	// DoTPort is the port or host-port suffix of the DNS-over-TLS (RFC 7858)
+	// listener. The listener only starts when TLSCertFile and TLSKeyFile are set.
+	DoTPort string
+	// TLSCertFile and TLSKeyFile are the PEM certificate chain and private key
+	// served on the DNS-over-TLS listener. Both files are re-read when they
+	// change on disk, so certificates can be renewed without a restart.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile is a PEM bundle of CAs whose client certificates are
+	// accepted on the DNS-over-TLS listener. Empty means client certificates
+	// are not requested, so mutual-TLS transfer authorization is unavailable.
+	TLSClientCAFile string
+	// DoHPort is the port or host-port suffix of the DNS-over-HTTPS (RFC 8484)
+	// listener, which uses TLSCertFile/TLSKeyFile. Empty disables it.
+	DoHPort string
+	// DNSSECSigner selects where new DNSSEC keys are generated and kept:
+	// "local" stores the private key, "pkcs11" keeps it in the PKCS #11 token
+	// below and "remote" in the signing service at RemoteSignerURL. Keys made
+	// earlier stay with the signer that made them.
+	DNSSECSigner     string
+	PKCS11Module     string
+	PKCS11TokenLabel string
+	PKCS11PIN        string
+	// RemoteSignerURL is the base URL of the remote signer; RemoteSignerToken
+	// is sent to it as bearer token when set.
+	RemoteSignerURL   string
+	RemoteSignerToken string
 }
 
 type PrimaryConfig struct {
-	NotifyDebounceMs int    `json:"notify_debounce_ms"` // delay before sending NOTIFY
-	Ip               string `json:"ip"`                 //ip of primary DNS
-	Port             int    `json:"port"`               //port of primary DNS
+	NotifyDebounceMs      int    `json:"notify_debounce_ms"`      // delay before sending NOTIFY
+	Ip                    string `json:"ip"`                      //ip of primary DNS
+	Port                  int    `json:"port"`                    //port of primary DNS
+	ZONEMD                bool   `json:"zonemd"`                  // publish an RFC 8976 ZONEMD on every serial change
+	NotifyRetries         int    `json:"notify_retries"`          // NOTIFY resends to a target that does not answer
+	NotifyRetryBaseMs     int    `json:"notify_retry_base_ms"`    // first retry interval; doubles per retry
+	NotifyRetryMaxMs      int    `json:"notify_retry_max_ms"`     // cap on the retry interval; 0 = none
+	PropagationPollSec    int    `json:"propagation_poll_sec"`    // SOA poll of targets after NOTIFY; 0 = on request only
+	PropagationTimeoutSec int    `json:"propagation_timeout_sec"` // stop polling lagging targets after this; 0 = never
 }
 
 type SecondaryConfig struct {
@@ -67,6 +104,11 @@ type SecondaryConfig struct {
 	RefreshJitterSec    int      `json:"refresh_jitter_sec"`     // max random per-zone delay each sweep
 	CatalogEnabled      bool     `json:"catalog_enabled"`        // maintain/follow RFC 9432 catalog zone
 	CatalogZone         string   `json:"catalog_zone"`           // bootstrap catalog zone name
+	ZONEMDEnforce       bool     `json:"zonemd_enforce"`         // refuse transferred zones whose ZONEMD does not verify
+	MinRefreshSec       int      `json:"min_refresh_sec"`        // lower bound on the SOA refresh timer; 0 = none
+	MaxRefreshSec       int      `json:"max_refresh_sec"`        // upper bound on the SOA refresh timer; 0 = none
+	MinRetrySec         int      `json:"min_retry_sec"`          // lower bound on the SOA retry timer; 0 = none
+	MaxRetrySec         int      `json:"max_retry_sec"`          // upper bound on the SOA retry timer; 0 = none
 }
 
 type DNSSECSignaturePolicy struct {
@@ -75,6 +117,42 @@ type DNSSECSignaturePolicy struct {
 	RefreshBeforeSeconds  int `json:"refresh_before_seconds"`
 	JitterSeconds         int `json:"jitter_seconds"`
 	InceptionSkewSeconds  int `json:"inception_skew_seconds"`
+
+	Policies        map[string]KASPPolicy `json:"policies"`          // named key and signing policies
+	DefaultPolicy   string                `json:"default_policy"`    // policy of zones without their own; "" = none
+	KASPIntervalSec int                   `json:"kasp_interval_sec"` // how often the key scheduler runs
+	KASPTakeoverSec int                   `json:"kasp_takeover_sec"` // distributed: overdue key events another node takes over
+	SKRWarnSec      int                   `json:"skr_warn_sec"`      // offline KSK: alert when the imported SKR runs out sooner
+
+	ParentDS ParentDSConfig `json:"parent_ds"`
+}
+
+// ParentDSConfig controls the check of the DS RRset the parent publishes for
+// each signed zone. Without resolvers the parent is not checked and KSK
+// rollovers follow the policy's timing alone.
+type ParentDSConfig struct {
+	Resolvers   []string `json:"resolvers"`    // recursive resolvers, host:port
+	IntervalSec int      `json:"interval_sec"` // between checks of a zone
+	TimeoutMs   int      `json:"timeout_ms"`   // per resolver query
+}
+
+// KASPPolicy is a key and signing policy. The scheduler keeps a KSK and a ZSK
+// of Algorithm for every zone using it and rolls each after its lifetime. The
+// TTL and delay fields set how long a key is published before it is used and
+// kept after it stops signing (RFC 6781 section 4.1, RFC 7583).
+type KASPPolicy struct {
+	Algorithm                 string `json:"algorithm"`
+	KSKLifetimeSec            int    `json:"ksk_lifetime_sec"` // 0 = never rolled
+	ZSKLifetimeSec            int    `json:"zsk_lifetime_sec"` // 0 = never rolled
+	KSKRollover               string `json:"ksk_rollover"`     // double-signature
+	ZSKRollover               string `json:"zsk_rollover"`     // pre-publish/double-signature
+	DNSKEYTTLSec              int    `json:"dnskey_ttl_sec"`
+	MaxZoneTTLSec             int    `json:"max_zone_ttl_sec"`      // largest TTL of signed data
+	PropagationDelaySec       int    `json:"propagation_delay_sec"` // until every secondary serves a change
+	PublishSafetySec          int    `json:"publish_safety_sec"`
+	RetireSafetySec           int    `json:"retire_safety_sec"`
+	ParentDSTTLSec            int    `json:"parent_ds_ttl_sec"`
+	ParentPropagationDelaySec int    `json:"parent_propagation_delay_sec"`
 }
 
 type DistributedConfig struct {
@@ -105,6 +183,106 @@ type AuthConfig struct {
 	OIDCJWKSURL  string `json:"oidc_jwks_url"` // future JWKS endpoint override
 }
 
+// UpdatePolicyRule grants one TSIG key the right to change records through RFC
+// 2136 dynamic UPDATE. A rule matches when the request was signed with Key, the
+// zone section names Zone ("*" for every zone), the owner name relative to the
+// zone ("@" for the apex) matches the Name glob, and the record type is listed in
+// Types. An empty Name matches every owner; empty Types allows every type except
+// SOA, and "ANY" must be listed (or Types left empty) to delete all RRsets at a
+// name in one go.
+type UpdatePolicyRule struct {
+	Key   string   `json:"key"`   // TSIG key name, e.g. dhcp-key.
+	Zone  string   `json:"zone"`  // zone apex or "*"
+	Name  string   `json:"name"`  // owner glob relative to the zone, e.g. "*.dhcp", "_acme-challenge"
+	Types []string `json:"types"` // permitted RR types; empty = all but SOA
+}
+
+type UpdateConfig struct {
+	Rules []UpdatePolicyRule `json:"rules"` // no rules = every UPDATE is refused
+}
+
+// IXFRConfig controls the per-zone difference journal used to answer RFC 1995
+// incremental transfers. A client whose serial has fallen out of the journal,
+// or whose difference would be larger than the zone itself, gets a full AXFR.
+type IXFRConfig struct {
+	JournalDepth int  `json:"journal_depth"` // differences kept per zone; 0 disables the journal
+	Condense     bool `json:"condense"`      // merge consecutive differences into one deletion/addition pair
+}
+
+// XoTConfig controls zone transfers over TLS (RFC 9103). Transfers are served
+// on the DNS-over-TLS listener; the fetch settings apply to every primary a
+// secondary pulls from. A client certificate listed in AllowClients authorizes
+// a transfer on its own, otherwise allow_transfer and TSIG apply as usual.
+type XoTConfig struct {
+	RequireTLS          bool     `json:"require_tls"`            // refuse AXFR/IXFR that did not arrive over TLS
+	RequireClientCert   bool     `json:"require_client_cert"`    // over TLS, only clients listed in allow_clients may transfer
+	AllowClients        []string `json:"allow_clients"`          // verified client certificate names (CN or DNS SAN)
+	FetchOverTLS        bool     `json:"fetch_over_tls"`         // secondaries pull zones from primaries over TLS
+	FetchPort           int      `json:"fetch_port"`             // primary XoT port; 0 = 853
+	FetchServerName     string   `json:"fetch_server_name"`      // name verified in the primary certificate; empty = primary IP
+	FetchCAFile         string   `json:"fetch_ca_file"`          // PEM trust anchors for primaries; empty = system roots
+	FetchSPKIPins       []string `json:"fetch_spki_pins"`        // base64 SHA-256 SPKI pins (RFC 7858 section 4.2)
+	FetchClientCertFile string   `json:"fetch_client_cert_file"` // client certificate presented to primaries
+	FetchClientKeyFile  string   `json:"fetch_client_key_file"`
+}
+
+// RRLConfig is BIND-style Response Rate Limiting for UDP responses. Responses
+// are counted per client netblock and response identity (the query name and
+// type for answers, the zone for NXDOMAIN, the delegation for referrals, and
+// all errors together), so a reflection attack against one victim is damped
+// without throttling other clients behind the same resolver. A rate of 0
+// leaves that response class unlimited.
+type RRLConfig struct {
+	Enabled            bool     `json:"enabled"`
+	ResponsesPerSecond int      `json:"responses_per_second"` // positive answers and NODATA
+	NXDomainsPerSecond int      `json:"nxdomains_per_second"`
+	ReferralsPerSecond int      `json:"referrals_per_second"`
+	ErrorsPerSecond    int      `json:"errors_per_second"`   // REFUSED, SERVFAIL, FORMERR, ...
+	Window             int      `json:"window"`              // seconds a flooded bucket stays limited after the flood stops
+	Slip               int      `json:"slip"`                // every Nth limited response is sent truncated; 0 = drop all
+	IPv4PrefixLength   int      `json:"ipv4_prefix_length"`  // client netblock size, e.g. 24
+	IPv6PrefixLength   int      `json:"ipv6_prefix_length"`  // client netblock size, e.g. 56
+	Exempt             []string `json:"exempt"`              // client IPs or CIDRs never limited
+	LogOnly            bool     `json:"log_only"`            // count and log, but send every response
+	ValidCookie        string   `json:"valid_cookie"`        // exempt/relax/none for queries with a valid server cookie
+	ValidCookieFactor  int      `json:"valid_cookie_factor"` // rate multiplier when valid_cookie is relax
+}
+
+// CookieConfig controls DNS Cookies (RFC 7873). Server cookies use the
+// interoperable SipHash-2-4 format of RFC 9018, so every node answering on the
+// same address must share Secret to accept each other's cookies. A rotation
+// moves the old value to PreviousSecret, which is still accepted but never used
+// for new cookies.
+type CookieConfig struct {
+	Enabled             bool   `json:"enabled"`
+	RequireServerCookie bool   `json:"require_server_cookie"` // answer UDP queries lacking a valid server cookie with BADCOOKIE
+	Secret              string `json:"secret"`                // 128-bit SipHash key, hex; empty = random and node-local
+	PreviousSecret      string `json:"previous_secret"`       // accepted for validation during a rotation
+}
+
+// ViewConfig is one split-horizon view. A query is answered from the first
+// view whose match lists all accept it; an empty list matches everything and
+// any entry of a list is enough. Queries matching no view see the default
+// zones. An overlay view stores only the RRsets it changes and answers the
+// rest of those zones, and every zone it does not hold, from the default zones.
+type ViewConfig struct {
+	Name              string   `json:"name"`
+	MatchClients      []string `json:"match_clients"`      // client IPs or CIDRs
+	MatchKeys         []string `json:"match_keys"`         // TSIG key names; the query must carry a valid signature
+	MatchDestinations []string `json:"match_destinations"` // local listener IPs or CIDRs the query arrived on
+	Overlay           bool     `json:"overlay"`
+}
+
+// ALIASConfig controls how ALIAS records pointing outside the local zones are
+// resolved. Upstream answers are cached for their TTL, so a busy ALIAS costs
+// one upstream query per TTL and address family. Without resolvers such
+// ALIAS records answer SERVFAIL.
+type ALIASConfig struct {
+	Resolvers []string `json:"resolvers"`  // upstream resolvers, host:port
+	TimeoutMs int      `json:"timeout_ms"` // per upstream query
+	CacheSize int      `json:"cache_size"` // cached upstream answers; 0 disables the cache
+}
+
 type LiveConfig struct {
 	LogLevel          string `json:"log_level"`       // debug/info/warn
 	Mode              string `json:"mode"`            // primary/secondary/distributed
@@ -116,6 +294,7 @@ type LiveConfig struct {
 	MaxUDPSize        int    `json:"max_udp_size"`    // e.g. 1232
 	EnableEDNS        bool   `json:"enable_edns"`     // "true"/"false"
 	NSID              string `json:"nsid"`            // EDNS0 NSID (RFC 5001); empty = disabled
+	EDEExtraText      bool   `json:"ede_extra_text"`  // EXTRA-TEXT on Extended DNS Errors (RFC 8914)
 	RateLimitQPS      int    `json:"rate_limit_qps"`  // queries per second
 	WALRetentionDays  int    `json:"wal_retention_days"`
 	MaxRestoreBytes   int64  `json:"max_restore_bytes"` // restore upload cap in bytes; 0 = unlimited
@@ -123,6 +302,7 @@ type LiveConfig struct {
 	DefaultNS         string `json:"default_ns"`        // e.g. ns1.example.com
 	EnforceTSIG       bool   `json:"enforce_tsig"`
 	AnyQueryPolicy    string `json:"any_query_policy"`    // hinfo/refuse
+	MinimalResponses  bool   `json:"minimal_responses"`   // no additional section data in answers
 	UnknownZonePolicy string `json:"unknown_zone_policy"` // refused
 
 	Primary     PrimaryConfig         `json:"primary"`
@@ -130,6 +310,13 @@ type LiveConfig struct {
 	DNSSEC      DNSSECSignaturePolicy `json:"dnssec"`
 	Distributed DistributedConfig     `json:"distributed"`
 	Auth        AuthConfig            `json:"auth"`
+	Update      UpdateConfig          `json:"update"`
+	IXFR        IXFRConfig            `json:"ixfr"`
+	XoT         XoTConfig             `json:"xot"`
+	RRL         RRLConfig             `json:"rrl"`
+	Cookies     CookieConfig          `json:"cookies"`
+	Views       []ViewConfig          `json:"views"`
+	ALIAS       ALIASConfig           `json:"alias"`
 }
 
 // ConfigManager hold the live config behind an atmic pointer
@@ -161,6 +348,18 @@ func (cm *ConfigManager) Init() {
 		PostgresDSN:      MustEnv("POSTGRES_DSN", DefaultBaseConfig.PostgresDSN),
 		AdminSocket:      MustEnv("ADMIN_SOCKET", DefaultBaseConfig.AdminSocket),
 		AdminSocketGroup: MustEnv("ADMIN_SOCKET_GROUP", DefaultBaseConfig.AdminSocketGroup),
+		DoTPort:          MustEnv("DOT_PORT", DefaultBaseConfig.DoTPort),
+		TLSCertFile:      MustEnv("TLS_CERT_FILE", DefaultBaseConfig.TLSCertFile),
+		TLSKeyFile:       MustEnv("TLS_KEY_FILE", DefaultBaseConfig.TLSKeyFile),
+		TLSClientCAFile:  MustEnv("TLS_CLIENT_CA_FILE", DefaultBaseConfig.TLSClientCAFile),
+		DoHPort:          MustEnv("DOH_PORT", DefaultBaseConfig.DoHPort),
+
+		DNSSECSigner:      MustEnv("DNSSEC_SIGNER", DefaultBaseConfig.DNSSECSigner),
+		PKCS11Module:      MustEnv("PKCS11_MODULE", DefaultBaseConfig.PKCS11Module),
+		PKCS11TokenLabel:  MustEnv("PKCS11_TOKEN_LABEL", DefaultBaseConfig.PKCS11TokenLabel),
+		PKCS11PIN:         MustEnv("PKCS11_PIN", DefaultBaseConfig.PKCS11PIN),
+		RemoteSignerURL:   MustEnv("REMOTE_SIGNER_URL", DefaultBaseConfig.RemoteSignerURL),
+		RemoteSignerToken: MustEnv("REMOTE_SIGNER_TOKEN", DefaultBaseConfig.RemoteSignerToken),
 	}
 
 	if err := storage.Init(cm.Base.StorageBackend); err != nil {
@@ -181,6 +380,7 @@ func (cm *ConfigManager) InitLiveConfig() {
 	}
 
 	cfg := DefaultLiveConfig
+	cfg.DNSSEC.Policies = cloneKASPPolicies(cfg.DNSSEC.Policies)
 	changed := false
 	val := reflect.ValueOf(&cfg).Elem()
 	typ := val.Type()
@@ -446,6 +646,14 @@ func (cm *ConfigManager) MergeUpdateLiveJSON(raw []byte) error {
 	// Clone nested maps so an in-place unmarshal cannot mutate the published
 	// snapshot before commit (copy-on-write).
 	merged.Distributed.PeerPublicKeys = clonePeerPublicKeys(merged.Distributed.PeerPublicKeys)
+	merged.Update.Rules = append([]UpdatePolicyRule(nil), merged.Update.Rules...)
+	merged.XoT.AllowClients = append([]string(nil), merged.XoT.AllowClients...)
+	merged.XoT.FetchSPKIPins = append([]string(nil), merged.XoT.FetchSPKIPins...)
+	merged.RRL.Exempt = append([]string(nil), merged.RRL.Exempt...)
+	merged.ALIAS.Resolvers = append([]string(nil), merged.ALIAS.Resolvers...)
+	merged.Views = cloneViews(merged.Views)
+	merged.DNSSEC.Policies = cloneKASPPolicies(merged.DNSSEC.Policies)
+	merged.DNSSEC.ParentDS.Resolvers = append([]string(nil), merged.DNSSEC.ParentDS.Resolvers...)
 	prepareReplaceOnlyMapFields(raw, &merged)
 	if err := json.Unmarshal(raw, &merged); err != nil {
 		cm.writeMu.Unlock()
@@ -481,6 +689,31 @@ func prepareReplaceOnlyMapFields(raw []byte, cfg *LiveConfig) {
 	}
 }
 
+func cloneViews(in []ViewConfig) []ViewConfig {
+	if in == nil {
+		return nil
+	}
+	out := make([]ViewConfig, len(in))
+	for i, v := range in {
+		v.MatchClients = append([]string(nil), v.MatchClients...)
+		v.MatchKeys = append([]string(nil), v.MatchKeys...)
+		v.MatchDestinations = append([]string(nil), v.MatchDestinations...)
+		out[i] = v
+	}
+	return out
+}
+
+func cloneKASPPolicies(in map[string]KASPPolicy) map[string]KASPPolicy {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]KASPPolicy, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
 func clonePeerPublicKeys(in map[string]string) map[string]string {
 	if in == nil {
 		return nil
@@ -496,6 +729,12 @@ func ValidXAuthKey(key string) bool {
 	return xAuthKeyRe.MatchString(key)
 }
 
+// ValidViewName reports whether name may name a split-horizon view: 1 to 63
+// letters, digits, '-' or '_', starting with a letter or digit.
+func ValidViewName(name string) bool {
+	return viewNameRe.MatchString(name)
+}
+
 func MustEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -9,8 +9,9 @@ var DefaultLiveConfig = LiveConfig{
 	Version:           "go53 v0.79.1",
 	MaxUDPSize:        1232,
 	EnableEDNS:        true,
-	NSID:              "", // empty = NSID disabled, avoids leaking node identity by default
-	RateLimitQPS:      0,  // 0 = no rate limiting
+	NSID:              "",    // empty = NSID disabled, avoids leaking node identity by default
+	EDEExtraText:      false, // EDE codes only; EXTRA-TEXT may name clients, keys and zones
+	RateLimitQPS:      0,     // 0 = no rate limiting
 	WALRetentionDays:  14,
 	MaxRestoreBytes:   1 << 30, // 1 GiB; raise via config for larger backups, 0 = unlimited
 	AllowAXFR:         false,
@@ -18,12 +19,19 @@ var DefaultLiveConfig = LiveConfig{
 	EnforceTSIG:       false,
 	DNSSECEnabled:     true,
 	AnyQueryPolicy:    "hinfo",
+	MinimalResponses:  false,
 	UnknownZonePolicy: "refused",
 
 	Primary: PrimaryConfig{
-		NotifyDebounceMs: 2000,
-		Ip:               "127.0.0.1",
-		Port:             53,
+		NotifyDebounceMs:      2000,
+		Ip:                    "127.0.0.1",
+		Port:                  53,
+		ZONEMD:                false,
+		NotifyRetries:         5,
+		NotifyRetryBaseMs:     1000,
+		NotifyRetryMaxMs:      60000,
+		PropagationPollSec:    10,
+		PropagationTimeoutSec: 3600,
 	},
 
 	Secondary: SecondaryConfig{
@@ -35,6 +43,11 @@ var DefaultLiveConfig = LiveConfig{
 		RefreshJitterSec:    60,
 		CatalogEnabled:      false,
 		CatalogZone:         "_catalog.go53.",
+		ZONEMDEnforce:       false,
+		MinRefreshSec:       300, // BIND-like timer bounds
+		MaxRefreshSec:       2419200,
+		MinRetrySec:         500,
+		MaxRetrySec:         1209600,
 	},
 
 	DNSSEC: DNSSECSignaturePolicy{
@@ -43,6 +56,31 @@ var DefaultLiveConfig = LiveConfig{
 		RefreshBeforeSeconds:  24 * 3600,
 		JitterSeconds:         3600,
 		InceptionSkewSeconds:  3600,
+		Policies: map[string]KASPPolicy{
+			"default": {
+				Algorithm:                 "ECDSAP256SHA256",
+				KSKLifetimeSec:            0,
+				ZSKLifetimeSec:            90 * 24 * 3600,
+				KSKRollover:               "double-signature",
+				ZSKRollover:               "pre-publish",
+				DNSKEYTTLSec:              3600,
+				MaxZoneTTLSec:             86400,
+				PropagationDelaySec:       300,
+				PublishSafetySec:          3600,
+				RetireSafetySec:           3600,
+				ParentDSTTLSec:            86400,
+				ParentPropagationDelaySec: 3600,
+			},
+		},
+		DefaultPolicy:   "",
+		KASPIntervalSec: 60,
+		KASPTakeoverSec: 3600,
+		SKRWarnSec:      14 * 24 * 3600,
+		ParentDS: ParentDSConfig{
+			Resolvers:   []string{},
+			IntervalSec: 300,
+			TimeoutMs:   2000,
+		},
 	},
 
 	Distributed: DistributedConfig{
@@ -64,6 +102,48 @@ var DefaultLiveConfig = LiveConfig{
 		OIDCAudience: "",
 		OIDCJWKSURL:  "",
 	},
+
+	Update: UpdateConfig{
+		Rules: []UpdatePolicyRule{},
+	},
+
+	IXFR: IXFRConfig{
+		JournalDepth: 100,
+		Condense:     true,
+	},
+
+	XoT: XoTConfig{
+		AllowClients:  []string{},
+		FetchPort:     853,
+		FetchSPKIPins: []string{},
+	},
+
+	RRL: RRLConfig{
+		Enabled:            false,
+		ResponsesPerSecond: 5,
+		NXDomainsPerSecond: 5,
+		ReferralsPerSecond: 5,
+		ErrorsPerSecond:    5,
+		Window:             15,
+		Slip:               2,
+		IPv4PrefixLength:   24,
+		IPv6PrefixLength:   56,
+		Exempt:             []string{},
+		ValidCookie:        "exempt",
+		ValidCookieFactor:  4,
+	},
+
+	Cookies: CookieConfig{
+		Enabled: true,
+	},
+
+	Views: []ViewConfig{},
+
+	ALIAS: ALIASConfig{
+		Resolvers: []string{},
+		TimeoutMs: 2000,
+		CacheSize: 10000,
+	},
 }
 
 var DefaultBaseConfig = BaseConfig{
@@ -74,4 +154,7 @@ var DefaultBaseConfig = BaseConfig{
 	PostgresDSN:      "host=localhost port=5432 user=postgres password=postgres dbname=go53 sslmode=disable",
 	AdminSocket:      "/run/go53/admin.sock",
 	AdminSocketGroup: "go53_admin",
+	DoTPort:          ":853",
+	DoHPort:          ":443",
+	DNSSECSigner:     "local",
 }
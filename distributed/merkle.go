@@ -57,6 +57,9 @@ func (s *Service) merkleZoneRoots() (map[string]MerkleZoneRoot, error) {
 	}
 	roots := map[string]MerkleZoneRoot{}
 	for _, zone := range s.store.ZoneNamesSnapshot() {
+		if !replicated(zone) {
+			continue
+		}
 		tree, err := s.merkleZoneTree(zone)
 		if err != nil {
 			return nil, err
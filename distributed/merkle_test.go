@@ -6,6 +6,7 @@ import (
 	"go53/config"
 	"go53/memory"
 	"go53/storage"
+	"go53/zonemeta"
 )
 
 func TestMerkleTreeEmptyAndFilteredLeaves(t *testing.T) {
@@ -61,6 +62,29 @@ func TestMerkleTreeEmptyAndFilteredLeaves(t *testing.T) {
 	}
 }
 
+func TestMerkleRootsSkipLocalRoleZones(t *testing.T) {
+	svc := newMerkleOnlyService(t)
+	for _, zone := range []string{"shared.test.", "local.test."} {
+		if err := svc.store.PutRecordRaw(zone, "A", "www", map[string]any{"ip": "192.0.2.1"}); err != nil {
+			t.Fatalf("PutRecordRaw %s: %v", zone, err)
+		}
+	}
+	if err := zonemeta.SetRole("local.test.", zonemeta.RolePrimary); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+
+	roots, err := svc.MerkleZoneRoots()
+	if err != nil {
+		t.Fatalf("MerkleZoneRoots: %v", err)
+	}
+	if _, ok := roots["shared.test."]; !ok {
+		t.Fatalf("replicated zone missing from roots: %#v", roots)
+	}
+	if _, ok := roots["local.test."]; ok {
+		t.Fatalf("zone with a local role is replicated: %#v", roots)
+	}
+}
+
 func TestMerkleDifferingHelpersAreSorted(t *testing.T) {
 	branches := merkleDifferingBranches(
 		map[string]MerkleBranch{"b": {Hash: "same", LeafCount: 1}, "a": {Hash: "left", LeafCount: 1}},
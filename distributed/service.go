@@ -25,17 +25,22 @@ import (
 	"go53/storage"
 	"go53/types"
 	zonepkg "go53/zone"
+	"go53/zonemeta"
 )
 
 const (
 	OperationUpsert = "UPSERT"
 	OperationDelete = "DELETE"
 
-	EntityZoneRecord = "zone_record"
-	EntityConfig     = "config"
-	EntityTSIGKey    = "tsig_key"
-	EntityDNSSECKey  = "dnssec_key"
-	EntityZone       = "zone"
+	EntityZoneRecord        = "zone_record"
+	EntityConfig            = "config"
+	EntityTSIGKey           = "tsig_key"
+	EntityDNSSECKey         = "dnssec_key"
+	EntitySKR               = "dnssec_skr"
+	EntityAlgorithmRollover = "dnssec_algorithm_rollover"
+	EntityZone              = "zone"
+	EntityViewRecord        = "view_record"
+	EntityViewZone          = "view_zone"
 
 	eventsTable       = "distributed-events"
 	vectorTable       = "distributed-vector"
@@ -50,6 +55,7 @@ type Event struct {
 	Seq        uint64            `json:"seq"`
 	Entity     string            `json:"entity"`
 	EntityType string            `json:"entity_type,omitempty"`
+	View       string            `json:"view,omitempty"`
 	Zone       string            `json:"zone"`
 	RRType     string            `json:"rrtype"`
 	Name       string            `json:"name"`
@@ -168,7 +174,7 @@ func TLSTransportEnabled() bool {
 }
 
 func (s *Service) PublishUpsert(zone, rrtype, name string, value any) error {
-	if s == nil || !enabled() {
+	if s == nil || !enabled() || !replicated(zone) {
 		return nil
 	}
 	raw, err := json.Marshal(value)
@@ -186,7 +192,7 @@ func (s *Service) PublishUpsert(zone, rrtype, name string, value any) error {
 }
 
 func (s *Service) PublishDelete(zone, rrtype, name string) error {
-	if s == nil || !enabled() {
+	if s == nil || !enabled() || !replicated(zone) {
 		return nil
 	}
 	return s.publish(Event{
@@ -204,7 +210,7 @@ func (s *Service) PublishDelete(zone, rrtype, name string) error {
 // anti-entropy from resurrecting individual records, while this zone-level event
 // removes the now-empty zone shell on peers via store.DeleteZone.
 func (s *Service) PublishZoneDelete(zone string) error {
-	if s == nil || !enabled() {
+	if s == nil || !enabled() || !replicated(zone) {
 		return nil
 	}
 	return s.publish(Event{
@@ -214,6 +220,55 @@ func (s *Service) PublishZoneDelete(zone string) error {
 	})
 }
 
+// PublishViewUpsert replicates the RRset stored for name in a zone of a
+// split-horizon view.
+func (s *Service) PublishViewUpsert(view, zone, rrtype, name string, value any) error {
+	if s == nil || !enabled() {
+		return nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.publish(Event{
+		EntityType: EntityViewRecord,
+		View:       view,
+		Zone:       zone,
+		RRType:     rrtype,
+		Name:       name,
+		Operation:  OperationUpsert,
+		Value:      raw,
+	})
+}
+
+// PublishViewDelete replicates the removal of an RRset from a zone of a view.
+func (s *Service) PublishViewDelete(view, zone, rrtype, name string) error {
+	if s == nil || !enabled() {
+		return nil
+	}
+	return s.publish(Event{
+		EntityType: EntityViewRecord,
+		View:       view,
+		Zone:       zone,
+		RRType:     rrtype,
+		Name:       name,
+		Operation:  OperationDelete,
+	})
+}
+
+// PublishViewZoneDelete replicates the removal of a whole zone from a view.
+func (s *Service) PublishViewZoneDelete(view, zone string) error {
+	if s == nil || !enabled() {
+		return nil
+	}
+	return s.publish(Event{
+		EntityType: EntityViewZone,
+		View:       view,
+		Zone:       zone,
+		Operation:  OperationDelete,
+	})
+}
+
 // PublishConfig replicates a live-config change to peers. It takes the raw JSON patch
 // (only the keys the admin actually set), so presence is preserved end-to-end and
 // false/empty values propagate. Node-local distributed keys are stripped so peers
@@ -329,6 +384,41 @@ func (s *Service) PublishDNSSECKeyDelete(keyID string) error {
 	})
 }
 
+// PublishSKR replicates the signed key response stored for an offline-KSK
+// zone.
+func (s *Service) PublishSKR(zone string, skr any) error {
+	if s == nil || !readyToPublish() {
+		return nil
+	}
+	raw, err := json.Marshal(skr)
+	if err != nil {
+		return err
+	}
+	return s.publish(Event{
+		EntityType: EntitySKR,
+		Zone:       zone,
+		Operation:  OperationUpsert,
+		Value:      raw,
+	})
+}
+
+// PublishAlgorithmRollover replicates the algorithm rollover of a zone.
+func (s *Service) PublishAlgorithmRollover(zone string, rollover any) error {
+	if s == nil || !readyToPublish() {
+		return nil
+	}
+	raw, err := json.Marshal(rollover)
+	if err != nil {
+		return err
+	}
+	return s.publish(Event{
+		EntityType: EntityAlgorithmRollover,
+		Zone:       zone,
+		Operation:  OperationUpsert,
+		Value:      raw,
+	})
+}
+
 func (s *Service) publish(event Event) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -893,6 +983,9 @@ func (s *Service) repairPeerZones(ctx context.Context, peer string) error {
 		zones[zone] = true
 	}
 	for zone := range zones {
+		if !replicated(zone) {
+			continue
+		}
 		local := localRoots[zone]
 		remote := peerRoots[zone]
 		if local.Root == remote.Root && local.LeafCount == remote.LeafCount {
@@ -1045,8 +1138,21 @@ func (s *Service) applyEventLocked(ctx context.Context, event Event) error {
 		return s.applyTSIGEvent(event)
 	case EntityDNSSECKey:
 		return s.applyDNSSECKeyEvent(event)
+	case EntitySKR:
+		return s.applySKREvent(event)
+	case EntityAlgorithmRollover:
+		return s.applyAlgorithmRolloverEvent(event)
 	case EntityZone:
+		if !replicated(event.Zone) {
+			return nil
+		}
 		return s.applyZoneEvent(event)
+	case EntityViewRecord, EntityViewZone:
+		return s.applyViewEvent(event)
+	}
+	if !replicated(event.Zone) {
+		// The zone has a role of its own on this node.
+		return nil
 	}
 	switch event.Operation {
 	case OperationUpsert:
@@ -1077,6 +1183,40 @@ func (s *Service) applyZoneEvent(event Event) error {
 	return s.store.DeleteZone(event.Zone)
 }
 
+// applyViewEvent applies a record or zone event of a split-horizon view. Events
+// for a view this node has no config for are dropped, as its zones would not
+// be answered from.
+func (s *Service) applyViewEvent(event Event) error {
+	if strings.TrimSpace(event.Zone) == "" {
+		return fmt.Errorf("view event missing zone")
+	}
+	scope, ok, err := zonepkg.ForConfiguredView(event.View)
+	if err != nil || !ok {
+		return err
+	}
+	mem := scope.Store()
+	if eventType(event) == EntityViewZone {
+		if event.Operation != OperationDelete {
+			return fmt.Errorf("unsupported view zone operation %q", event.Operation)
+		}
+		return scope.DeleteZone(event.Zone)
+	}
+	switch event.Operation {
+	case OperationUpsert:
+		var value any
+		if len(event.Value) > 0 {
+			if err := json.Unmarshal(event.Value, &value); err != nil {
+				return err
+			}
+		}
+		return mem.PutRecordRaw(event.Zone, event.RRType, event.Name, value)
+	case OperationDelete:
+		return mem.DeleteRecordRaw(event.Zone, event.RRType, event.Name)
+	default:
+		return fmt.Errorf("unknown distributed operation %q", event.Operation)
+	}
+}
+
 func (s *Service) applyConfigEvent(event Event) error {
 	if event.Operation != OperationUpsert {
 		return fmt.Errorf("unsupported config operation %q", event.Operation)
@@ -1238,6 +1378,32 @@ func (s *Service) applyDNSSECKeyEvent(event Event) error {
 	}
 }
 
+func (s *Service) applySKREvent(event Event) error {
+	if event.Operation != OperationUpsert {
+		return fmt.Errorf("unsupported SKR operation %q", event.Operation)
+	}
+	if strings.TrimSpace(event.Zone) == "" {
+		return errors.New("missing SKR zone")
+	}
+	if err := security.SaveReplicatedSKR(event.Zone, event.Value); err != nil {
+		return err
+	}
+	return zonepkg.RefreshDNSSECKeyMaterial(event.Zone)
+}
+
+func (s *Service) applyAlgorithmRolloverEvent(event Event) error {
+	if event.Operation != OperationUpsert {
+		return fmt.Errorf("unsupported algorithm rollover operation %q", event.Operation)
+	}
+	if strings.TrimSpace(event.Zone) == "" {
+		return errors.New("missing algorithm rollover zone")
+	}
+	if err := security.SaveReplicatedAlgorithmRollover(event.Zone, event.Value); err != nil {
+		return err
+	}
+	return zonepkg.RefreshDNSSECKeyMaterial(event.Zone)
+}
+
 func (s *Service) applyRepairEvent(ctx context.Context, event Event) error {
 	if eventType(event) != EntityZoneRecord {
 		return nil
@@ -1907,8 +2073,16 @@ func eventEntityKey(event Event) string {
 		return EntityTSIGKey + "/" + strings.ToLower(strings.TrimSpace(event.Name))
 	case EntityDNSSECKey:
 		return EntityDNSSECKey + "/" + strings.TrimSpace(event.Name)
+	case EntitySKR:
+		return EntitySKR + "/" + strings.ToLower(strings.TrimSpace(event.Zone))
+	case EntityAlgorithmRollover:
+		return EntityAlgorithmRollover + "/" + strings.ToLower(strings.TrimSpace(event.Zone))
 	case EntityZone:
 		return EntityZone + "/" + strings.ToLower(strings.TrimSpace(event.Zone))
+	case EntityViewRecord:
+		return EntityViewRecord + "/" + strings.TrimSpace(event.View) + "/" + entityKey(event.Zone, event.RRType, event.Name)
+	case EntityViewZone:
+		return EntityViewZone + "/" + strings.TrimSpace(event.View) + "/" + strings.ToLower(strings.TrimSpace(event.Zone))
 	default:
 		return entityKey(event.Zone, event.RRType, event.Name)
 	}
@@ -1930,6 +2104,49 @@ func enabled() bool {
 	return liveConfig().Mode == "distributed"
 }
 
+// replicated reports whether zone is shared with the peers. Zones given the
+// primary or secondary role of their own are kept local.
+func replicated(zone string) bool {
+	meta, err := zonemeta.Load(zone)
+	return err != nil || meta.Role == "" || meta.Role == zonemeta.RoleDistributed
+}
+
+// ZoneOwner returns the node that does the scheduled work of zone, such as
+// key rollovers, so two nodes never act on it at once. Members are this node
+// and the nodes in peer_public_keys; the owner is the one with the highest
+// hash of its node ID and the zone, so few zones move when members change.
+// It is empty outside distributed mode and for zones kept local.
+func ZoneOwner(zone string) string {
+	if !enabled() || !replicated(zone) {
+		return ""
+	}
+	live := liveConfig()
+	members := []string{strings.TrimSpace(live.Distributed.NodeID)}
+	for nodeID := range live.Distributed.PeerPublicKeys {
+		members = append(members, strings.TrimSpace(nodeID))
+	}
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	owner := ""
+	var best []byte
+	for _, member := range members {
+		if member == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(member + "|" + zone))
+		if best == nil || bytes.Compare(sum[:], best) > 0 || (bytes.Equal(sum[:], best) && member < owner) {
+			best = sum[:]
+			owner = member
+		}
+	}
+	return owner
+}
+
+// OwnsZone reports whether this node does the scheduled work of zone.
+func OwnsZone(zone string) bool {
+	owner := ZoneOwner(zone)
+	return owner == "" || owner == strings.TrimSpace(liveConfig().Distributed.NodeID)
+}
+
 func readyToPublish() bool {
 	live := liveConfig()
 	return live.Mode == "distributed" &&
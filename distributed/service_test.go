@@ -1136,3 +1136,37 @@ func TestZoneDeleteReplication(t *testing.T) {
 		t.Fatalf("zone shell should be removed on peer after zone-delete event")
 	}
 }
+
+func TestZoneOwnerAgreesAcrossMembers(t *testing.T) {
+	storage.Backend = &storage.MockStorage{Zones: map[string][]byte{}, Tables: map[string]map[string][]byte{}}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	t.Cleanup(func() { config.AppConfig.SetLive(config.DefaultLiveConfig) })
+
+	if ZoneOwner("owner.test.") != "" || !OwnsZone("owner.test.") {
+		t.Fatalf("standalone node does not own its zones")
+	}
+
+	setNode := func(self, other string) {
+		live := config.AppConfig.LiveForTest()
+		live.Mode = "distributed"
+		live.Distributed.NodeID = self
+		live.Distributed.PeerPublicKeys = map[string]string{other: "key"}
+	}
+	owned := map[string]int{}
+	for _, zone := range []string{"a.test.", "b.test.", "c.test.", "d.test.", "e.test.", "f.test."} {
+		setNode("node-a", "node-b")
+		ownerA, ownsA := ZoneOwner(zone), OwnsZone(zone)
+		setNode("node-b", "node-a")
+		ownerB, ownsB := ZoneOwner(zone), OwnsZone(zone)
+		if ownerA == "" || ownerA != ownerB || ownsA == ownsB {
+			t.Fatalf("%s: owner %q/%q, owns %v/%v", zone, ownerA, ownerB, ownsA, ownsB)
+		}
+		if ZoneOwner(zone[:len(zone)-1]) != ownerB {
+			t.Fatalf("%s: owner depends on the trailing dot", zone)
+		}
+		owned[ownerA]++
+	}
+	if len(owned) != 2 {
+		t.Fatalf("zones not spread over members: %v", owned)
+	}
+}
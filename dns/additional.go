@@ -0,0 +1,136 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: additional.go is part of the go53 authoritative DNS server.
+package dns
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/zone"
+)
+
+// additionalRecords returns the A and AAAA RRsets of the names the MX, SRV, NS,
+// SVCB and HTTPS records in answer point at (RFC 1034 section 3.7, RFC 9460
+// section 4.1), limited to targets in the zone the answer came from. Records
+// already in the response are skipped. With DNSSEC each RRset is followed by
+// its RRSIGs, except glue below a delegation, which is not signed.
+func additionalRecords(zones zone.Scope, answer, present []dns.RR, wantsDNSSEC bool) []dns.RR {
+	seen := make(map[string]bool)
+	for _, rr := range present {
+		seen[rrsetKey(rr)] = true
+	}
+
+	var out []dns.RR
+	for _, rr := range answer {
+		target, ok := additionalTarget(rr)
+		if !ok {
+			continue
+		}
+		zoneName, ok := zones.AuthoritativeZoneForName(rr.Header().Name)
+		if !ok || !inBailiwickGlue(target, zoneName) {
+			continue
+		}
+		_, _, delegated := zones.DelegationFor(target)
+		for _, rrtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			records, ok := zones.LookupRecord(rrtype, target)
+			if !ok || len(records) == 0 || seen[rrsetKey(records[0])] {
+				continue
+			}
+			seen[rrsetKey(records[0])] = true
+			if wantsDNSSEC && !delegated {
+				records, _ = appendRRSIGs(zones, records)
+			}
+			out = append(out, records...)
+		}
+	}
+	return out
+}
+
+// additionalTarget returns the name whose addresses belong in the additional
+// section for rr.
+func additionalTarget(rr dns.RR) (string, bool) {
+	var target string
+	switch v := rr.(type) {
+	case *dns.MX:
+		target = v.Mx
+	case *dns.SRV:
+		target = v.Target
+	case *dns.NS:
+		target = v.Ns
+	case *dns.SVCB:
+		target = svcbTarget(v.Hdr.Name, v.Priority, v.Target)
+	case *dns.HTTPS:
+		target = svcbTarget(v.Hdr.Name, v.Priority, v.Target)
+	}
+	if target == "" || target == "." {
+		return "", false
+	}
+	return dns.Fqdn(target), true
+}
+
+// svcbTarget resolves the "." target of a ServiceMode record to its owner. An
+// AliasMode "." means the service does not exist.
+func svcbTarget(owner string, priority uint16, target string) string {
+	if target != "." {
+		return target
+	}
+	if priority == 0 {
+		return ""
+	}
+	return owner
+}
+
+func rrsetKey(rr dns.RR) string {
+	hdr := rr.Header()
+	return strings.ToLower(hdr.Name) + "|" + internal.TypeName(hdr.Rrtype)
+}
+
+// trimAdditional drops additional section RRsets, last first, until resp fits
+// in size bytes. Additional data is optional, so unlike Truncate this does
+// not set TC (RFC 2181 section 9). Referral glue is left to Truncate.
+func trimAdditional(resp *dns.Msg, size int) {
+	if !resp.Authoritative || len(resp.Answer) == 0 {
+		return
+	}
+	resp.Compress = true
+	for resp.Len() > size {
+		end := -1
+		for i := len(resp.Extra) - 1; i >= 0; i-- {
+			if resp.Extra[i].Header().Rrtype != dns.TypeOPT {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return
+		}
+		key := additionalGroupKey(resp.Extra[end])
+		start := end
+		for start > 0 && additionalGroupKey(resp.Extra[start-1]) == key {
+			start--
+		}
+		resp.Extra = append(resp.Extra[:start], resp.Extra[end+1:]...)
+	}
+}
+
+// additionalGroupKey keeps an RRset and its RRSIGs together.
+func additionalGroupKey(rr dns.RR) string {
+	if sig, ok := rr.(*dns.RRSIG); ok {
+		return strings.ToLower(sig.Hdr.Name) + "|" + internal.TypeName(sig.TypeCovered)
+	}
+	return rrsetKey(rr)
+}
@@ -0,0 +1,71 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: algorithm_rollover.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"errors"
+	"fmt"
+
+	"go53/internal"
+	"go53/security"
+)
+
+// ErrNoAlgorithmRollover is returned for a zone that never had an algorithm
+// rollover.
+var ErrNoAlgorithmRollover = errors.New("no algorithm rollover")
+
+// StartAlgorithmRollover starts moving zoneName to algorithm with the timing
+// of its DNSSEC policy, re-signs it with the new keys and replicates them.
+// The key scheduler takes the rollover on from there.
+func StartAlgorithmRollover(zoneName, algorithm string) (security.AlgorithmRolloverStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return security.AlgorithmRolloverStatus{}, err
+	}
+	name, policy, ok := ZoneKASPPolicy(fqdn)
+	if !ok {
+		if name == "" {
+			return security.AlgorithmRolloverStatus{}, fmt.Errorf("zone %s has no DNSSEC policy to time an algorithm rollover with", fqdn)
+		}
+		return security.AlgorithmRolloverStatus{}, fmt.Errorf("DNSSEC policy %s of zone %s is not configured", name, fqdn)
+	}
+	now := kaspNow().Unix()
+	rollover, res, err := security.StartAlgorithmRollover(fqdn, policy, algorithm, now)
+	if applyErr := applyKASPResult(fqdn, res, true); applyErr != nil && err == nil {
+		err = applyErr
+	}
+	if err != nil {
+		return security.AlgorithmRolloverStatus{}, err
+	}
+	return rollover.StatusAt(now), nil
+}
+
+// AlgorithmRolloverStatusOf reports the last algorithm rollover of zoneName
+// and the phase it is in.
+func AlgorithmRolloverStatusOf(zoneName string) (security.AlgorithmRolloverStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return security.AlgorithmRolloverStatus{}, err
+	}
+	rollover, ok, err := security.ZoneAlgorithmRollover(fqdn)
+	if err != nil {
+		return security.AlgorithmRolloverStatus{}, err
+	}
+	if !ok {
+		return security.AlgorithmRolloverStatus{}, fmt.Errorf("zone %s: %w", fqdn, ErrNoAlgorithmRollover)
+	}
+	return rollover.StatusAt(kaspNow().Unix()), nil
+}
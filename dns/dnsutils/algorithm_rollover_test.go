@@ -0,0 +1,64 @@
+package dnsutils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go53/config"
+	"go53/security"
+	"go53/zonemeta"
+)
+
+func TestStartAlgorithmRolloverNeedsPolicy(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "nopolicy.test.")
+	if err := zonemeta.SaveSettings("nopolicy.test.", zonemeta.Settings{DNSSECPolicy: zonemeta.PolicyNone}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	if _, err := StartAlgorithmRollover("nopolicy.test.", "ED25519"); err == nil {
+		t.Fatal("started an algorithm rollover without a policy")
+	}
+	if _, err := AlgorithmRolloverStatusOf("nopolicy.test."); !errors.Is(err, ErrNoAlgorithmRollover) {
+		t.Fatalf("AlgorithmRolloverStatusOf = %v, want ErrNoAlgorithmRollover", err)
+	}
+}
+
+func TestAlgorithmRolloverThroughTheScheduler(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.Policies["default"] = config.KASPPolicy{
+		Algorithm: "ECDSAP256SHA256", DNSKEYTTLSec: 300, MaxZoneTTLSec: 600, PropagationDelaySec: 60,
+		ParentDSTTLSec: 3600, ParentPropagationDelaySec: 600,
+	}
+	addTestSOA(t, "algo.test.")
+	if err := runKASPZone("algo.test."); err != nil {
+		t.Fatalf("runKASPZone: %v", err)
+	}
+
+	status, err := StartAlgorithmRollover("algo.test.", "ED25519")
+	if err != nil {
+		t.Fatalf("StartAlgorithmRollover: %v", err)
+	}
+	if status.Phase != security.AlgorithmRolloverNewSignatures || status.From != "ECDSAP256SHA256" || status.To != "ED25519" {
+		t.Fatalf("status = %+v", status)
+	}
+	timeline, err := ZoneDNSSECTimeline("algo.test.")
+	if err != nil || timeline.Error != "" || timeline.AlgorithmRollover == nil || len(timeline.Keys) != 4 {
+		t.Fatalf("timeline = %+v, %v", timeline, err)
+	}
+
+	start := kaspNow()
+	kaspNow = func() time.Time { return start.Add(time.Duration(status.DSAt-start.Unix()) * time.Second) }
+	t.Cleanup(func() { kaspNow = time.Now })
+	before, _ := localZoneSOA("algo.test.")
+	if err := runKASPZone("algo.test."); err != nil {
+		t.Fatalf("runKASPZone: %v", err)
+	}
+	status, err = AlgorithmRolloverStatusOf("algo.test.")
+	if err != nil || status.Phase != security.AlgorithmRolloverNewDS || status.NextAt != status.RemoveAt {
+		t.Fatalf("status at the DS swap = %+v, %v", status, err)
+	}
+	if after, _ := localZoneSOA("algo.test."); after.Serial == before.Serial {
+		t.Fatalf("serial stayed %d across the phase change", after.Serial)
+	}
+}
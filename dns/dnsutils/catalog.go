@@ -9,6 +9,7 @@ import (
 	"go53/security"
 	"go53/zone"
 	"go53/zone/rtypes"
+	"go53/zonemeta"
 	"log"
 	"net"
 	"sort"
@@ -49,13 +50,16 @@ func catalogMemberOwner(member, catalog string) string {
 // common primary mutation path does not scan large catalogs.
 func EnsureCatalogMember(zoneName string) error {
 	catalog, ok := catalogZoneName()
-	if !ok || config.AppConfig.GetLive().Mode == "secondary" {
+	if !ok {
 		return nil
 	}
 	member, err := internal.SanitizeFQDN(zoneName)
 	if err != nil {
 		return err
 	}
+	if zonemeta.IsSecondary(member) || zonemeta.IsSecondary(catalog) {
+		return nil
+	}
 	if member == catalog {
 		return nil
 	}
@@ -481,6 +485,7 @@ func pruneRemovedCatalogMembers(oldMembers, newMembers []string) {
 			log.Printf("[catalog] failed to delete removed member %s: %v", member, err)
 			continue
 		}
+		ForgetSecondaryTimers(member)
 		log.Printf("[catalog] deleted removed member %s", member)
 	}
 }
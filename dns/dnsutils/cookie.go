@@ -0,0 +1,278 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: cookie.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TenforwardAB/slog"
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+// RFC 9018 server cookie layout: Version | Reserved (3) | Timestamp (4) |
+// Hash (8), following the 8-octet client cookie.
+const (
+	cookieVersion      = 1
+	clientCookieLen    = 8
+	serverCookieLen    = 16
+	cookieSecretLen    = 16
+	cookieMaxAge       = 3600 // seconds a server cookie stays valid
+	cookieMaxClockSkew = 300  // seconds a timestamp may lie in the future
+	cookieReissueAge   = 1800 // older valid cookies are replaced by a fresh one
+)
+
+// CookieResult is the outcome of checking the COOKIE option of a query.
+type CookieResult struct {
+	Present bool // the query carried a COOKIE option and cookies are enabled
+	Valid   bool // it included a server cookie this server issued recently
+
+	client []byte
+	server []byte // valid server cookie to echo back; nil when a new one is due
+}
+
+// cookieSecrets caches the decoded secrets keyed by their configured values.
+// The node-local secret is generated once and used while none is configured.
+type cookieSecrets struct {
+	mu       sync.Mutex
+	key      string
+	current  []byte
+	previous []byte
+	local    []byte
+}
+
+var cookieKeys cookieSecrets
+
+// CheckCookie validates the COOKIE option of req (RFC 7873 section 5.2)
+// against the client address. A malformed option is rejected earlier, so a
+// cookie that cannot be parsed here is simply treated as absent.
+func CheckCookie(client net.IP, req *dns.Msg, now time.Time) CookieResult {
+	live := config.AppConfig.GetLive()
+	if !live.EnableEDNS || !live.Cookies.Enabled || req == nil {
+		return CookieResult{}
+	}
+	opt := req.IsEdns0()
+	if opt == nil {
+		return CookieResult{}
+	}
+	var raw []byte
+	for _, o := range opt.Option {
+		if c, ok := o.(*dns.EDNS0_COOKIE); ok {
+			decoded, err := hex.DecodeString(c.Cookie)
+			if err != nil || (len(decoded) != clientCookieLen && (len(decoded) < 16 || len(decoded) > 40)) {
+				return CookieResult{}
+			}
+			raw = decoded
+			break
+		}
+	}
+	if raw == nil {
+		return CookieResult{}
+	}
+
+	res := CookieResult{Present: true, client: raw[:clientCookieLen]}
+	server := raw[clientCookieLen:]
+	if len(server) != serverCookieLen || server[0] != cookieVersion || client == nil {
+		return res
+	}
+	age := int32(uint32(now.Unix()) - binary.BigEndian.Uint32(server[4:8]))
+	if age < -cookieMaxClockSkew || age > cookieMaxAge {
+		return res
+	}
+	current, previous := cookieKeys.secrets(live.Cookies)
+	for _, secret := range [][]byte{current, previous} {
+		if secret == nil {
+			continue
+		}
+		if string(serverCookieHash(secret, res.client, server[:8], client)) == string(server[8:]) {
+			res.Valid = true
+			if age <= cookieReissueAge && string(secret) == string(current) {
+				res.server = server
+			}
+			return res
+		}
+	}
+	return res
+}
+
+// ApplyCookie adds the COOKIE option to the response of a query that carried
+// one: the client cookie followed by the still-fresh server cookie it sent, or
+// a newly minted one.
+func ApplyCookie(resp *dns.Msg, req *dns.Msg, res CookieResult, client net.IP, now time.Time) {
+	if resp == nil || req == nil || !res.Present {
+		return
+	}
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil {
+		return
+	}
+	server := res.server
+	if server == nil {
+		if client == nil {
+			return
+		}
+		current, _ := cookieKeys.secrets(config.AppConfig.GetLive().Cookies)
+		server = newServerCookie(current, res.client, client, now)
+	}
+
+	opt := responseOPT(resp, reqOpt)
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_COOKIE); !ok {
+			options = append(options, o)
+		}
+	}
+	opt.Option = append(options, &dns.EDNS0_COOKIE{
+		Code:   dns.EDNS0COOKIE,
+		Cookie: hex.EncodeToString(res.client) + hex.EncodeToString(server),
+	})
+}
+
+// NewCookieSecret returns a random server secret in the configuration format.
+func NewCookieSecret() (string, error) {
+	secret := make([]byte, cookieSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// ParseCookieSecret decodes a configured secret, which must be 128 bits of hex.
+func ParseCookieSecret(s string) ([]byte, error) {
+	secret, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(secret) != cookieSecretLen {
+		return nil, fmt.Errorf("cookie secret must be %d hex-encoded bytes", cookieSecretLen)
+	}
+	return secret, nil
+}
+
+// secrets returns the secret used for new cookies and the previous one still
+// accepted, falling back to a random node-local secret when none is usable.
+func (s *cookieSecrets) secrets(cfg config.CookieConfig) ([]byte, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cfg.Secret + "|" + cfg.PreviousSecret
+	if s.current == nil || key != s.key {
+		s.key = key
+		s.current, s.previous = nil, nil
+		if cfg.Secret != "" {
+			secret, err := ParseCookieSecret(cfg.Secret)
+			if err != nil {
+				slog.Warn("DNS cookies: ignoring configured secret: %v", err)
+			}
+			s.current = secret
+		}
+		if cfg.PreviousSecret != "" {
+			secret, err := ParseCookieSecret(cfg.PreviousSecret)
+			if err != nil {
+				slog.Warn("DNS cookies: ignoring configured previous secret: %v", err)
+			}
+			s.previous = secret
+		}
+		if s.current == nil {
+			if s.local == nil {
+				s.local = make([]byte, cookieSecretLen)
+				_, _ = rand.Read(s.local)
+				slog.Info("DNS cookies: no shared secret configured, using a node-local one")
+			}
+			s.current = s.local
+		}
+	}
+	return s.current, s.previous
+}
+
+func newServerCookie(secret, clientCookie []byte, client net.IP, now time.Time) []byte {
+	server := make([]byte, 8, serverCookieLen)
+	server[0] = cookieVersion
+	binary.BigEndian.PutUint32(server[4:8], uint32(now.Unix()))
+	return append(server, serverCookieHash(secret, clientCookie, server, client)...)
+}
+
+// serverCookieHash is the RFC 9018 section 4.4 hash: SipHash-2-4 keyed with
+// the server secret over the client cookie, the version, reserved and
+// timestamp fields, and the client address.
+func serverCookieHash(secret, clientCookie, header []byte, client net.IP) []byte {
+	if v4 := client.To4(); v4 != nil {
+		client = v4
+	}
+	msg := make([]byte, 0, len(clientCookie)+len(header)+len(client))
+	msg = append(msg, clientCookie...)
+	msg = append(msg, header...)
+	msg = append(msg, client...)
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint64(out, sipHash24(secret, msg))
+	return out
+}
+
+// sipHash24 is SipHash-2-4 with a 128-bit key, as used by RFC 9018.
+func sipHash24(key, msg []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(msg)
+	for len(msg) >= 8 {
+		m := binary.LittleEndian.Uint64(msg)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+		msg = msg[8:]
+	}
+	var last [8]byte
+	copy(last[:], msg)
+	last[7] = byte(n)
+	m := binary.LittleEndian.Uint64(last[:])
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
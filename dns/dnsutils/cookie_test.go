@@ -0,0 +1,146 @@
+package dnsutils
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+// RFC 9018 appendix A.1: the first response to a resolver at 198.51.100.100.
+const (
+	cookieVectorSecret = "e5e973e5a6b2a43f48e7dc849e37bfcf"
+	cookieVectorClient = "2464c4abcf10c957"
+	cookieVectorServer = "010000005cf79f111f8130c3eee29480"
+	cookieVectorTime   = 1559731985
+)
+
+func setLiveCookies(t *testing.T, cookies config.CookieConfig) {
+	t.Helper()
+	prev := config.AppConfig.GetLive()
+	t.Cleanup(func() {
+		config.AppConfig.SetLive(prev)
+	})
+	live := prev
+	live.EnableEDNS = true
+	live.Cookies = cookies
+	config.AppConfig.SetLive(live)
+}
+
+func cookieQuery(cookie string) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(1232, false)
+	req.IsEdns0().Option = append(req.IsEdns0().Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+	return req
+}
+
+func responseCookie(t *testing.T, resp *dns.Msg) string {
+	t.Helper()
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if c, ok := o.(*dns.EDNS0_COOKIE); ok {
+				return c.Cookie
+			}
+		}
+	}
+	t.Fatalf("response carries no COOKIE option: %v", resp)
+	return ""
+}
+
+func TestSipHash24ReferenceVector(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	msg, _ := hex.DecodeString("000102030405060708090a0b0c0d0e")
+	if got := sipHash24(key, msg); got != 0xa129ca6149be45e5 {
+		t.Fatalf("SipHash-2-4 = %#x, want 0xa129ca6149be45e5", got)
+	}
+}
+
+func TestApplyCookieIssuesRFC9018ServerCookie(t *testing.T) {
+	setLiveCookies(t, config.CookieConfig{Enabled: true, Secret: cookieVectorSecret})
+	client := net.ParseIP("198.51.100.100")
+	now := time.Unix(cookieVectorTime, 0)
+
+	req := cookieQuery(cookieVectorClient)
+	res := CheckCookie(client, req, now)
+	if !res.Present || res.Valid {
+		t.Fatalf("client-only cookie = %+v", res)
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	ApplyCookie(resp, req, res, client, now)
+	if got := responseCookie(t, resp); got != cookieVectorClient+cookieVectorServer {
+		t.Fatalf("cookie = %s, want %s", got, cookieVectorClient+cookieVectorServer)
+	}
+}
+
+func TestCheckCookieValidatesAndRotates(t *testing.T) {
+	setLiveCookies(t, config.CookieConfig{Enabled: true, Secret: cookieVectorSecret})
+	client := net.ParseIP("198.51.100.100")
+	issued := time.Unix(cookieVectorTime, 0)
+	req := cookieQuery(cookieVectorClient + cookieVectorServer)
+
+	cases := []struct {
+		name    string
+		client  string
+		now     time.Time
+		valid   bool
+		reissue bool
+	}{
+		{"fresh", "198.51.100.100", issued.Add(time.Minute), true, false},
+		{"due for renewal", "198.51.100.100", issued.Add(40 * time.Minute), true, true},
+		{"expired", "198.51.100.100", issued.Add(2 * time.Hour), false, true},
+		{"from the future", "198.51.100.100", issued.Add(-10 * time.Minute), false, true},
+		{"other client", "198.51.100.101", issued, false, true},
+	}
+	for _, tc := range cases {
+		res := CheckCookie(net.ParseIP(tc.client), req, tc.now)
+		if !res.Present || res.Valid != tc.valid || (res.server == nil) != tc.reissue {
+			t.Fatalf("%s: result = %+v", tc.name, res)
+		}
+	}
+
+	// After a rotation the old cookie is still accepted but replaced.
+	newSecret, err := NewCookieSecret()
+	if err != nil {
+		t.Fatalf("NewCookieSecret: %v", err)
+	}
+	setLiveCookies(t, config.CookieConfig{Enabled: true, Secret: newSecret, PreviousSecret: cookieVectorSecret})
+	res := CheckCookie(client, req, issued)
+	if !res.Valid || res.server != nil {
+		t.Fatalf("cookie under previous secret = %+v, want valid and reissued", res)
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	ApplyCookie(resp, req, res, client, issued)
+	renewed := responseCookie(t, resp)
+	if renewed == cookieVectorClient+cookieVectorServer {
+		t.Fatalf("rotated secret reissued the old cookie")
+	}
+	if res := CheckCookie(client, cookieQuery(renewed), issued); !res.Valid || res.server == nil {
+		t.Fatalf("renewed cookie = %+v, want valid", res)
+	}
+
+	setLiveCookies(t, config.CookieConfig{Enabled: true, Secret: newSecret})
+	if res := CheckCookie(client, req, issued); res.Valid {
+		t.Fatalf("cookie under a retired secret was accepted")
+	}
+}
+
+func TestCheckCookieIgnoredWhenDisabled(t *testing.T) {
+	setLiveCookies(t, config.CookieConfig{Enabled: false, Secret: cookieVectorSecret})
+	req := cookieQuery(cookieVectorClient)
+	res := CheckCookie(net.ParseIP("198.51.100.100"), req, time.Unix(cookieVectorTime, 0))
+	if res.Present {
+		t.Fatalf("disabled cookies reported %+v", res)
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	ApplyCookie(resp, req, res, net.ParseIP("198.51.100.100"), time.Now())
+	if resp.IsEdns0() != nil {
+		t.Fatalf("disabled cookies added an OPT record")
+	}
+}
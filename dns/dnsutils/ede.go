@@ -0,0 +1,61 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: ede.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+// ApplyEDE attaches an Extended DNS Error option (RFC 8914) to the response so
+// a resolver can tell why it got REFUSED, SERVFAIL or NOTIMP.
+//
+// Like every EDNS option it is only sent when EDNS is enabled and the request
+// carried an OPT record (RFC 6891 section 7). The EXTRA-TEXT field is filled
+// in only when ede_extra_text is enabled, since the text may name clients,
+// keys and zones. A response carries at most one EDE per info-code.
+//
+// Parameters:
+//   - resp:      The response *dns.Msg that will be sent to the client.
+//   - req:       The incoming *dns.Msg from the client.
+//   - code:      The EDE INFO-CODE, e.g. dns.ExtendedErrorCodeProhibited.
+//   - extraText: Human-readable detail, sent only when enabled.
+func ApplyEDE(resp *dns.Msg, req *dns.Msg, code uint16, extraText string) {
+	if resp == nil || req == nil {
+		return
+	}
+
+	live := config.AppConfig.GetLive()
+	if !live.EnableEDNS {
+		return
+	}
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil {
+		return
+	}
+
+	opt := responseOPT(resp, reqOpt)
+	for _, o := range opt.Option {
+		if ede, ok := o.(*dns.EDNS0_EDE); ok && ede.InfoCode == code {
+			return
+		}
+	}
+	ede := &dns.EDNS0_EDE{InfoCode: code}
+	if live.EDEExtraText {
+		ede.ExtraText = extraText
+	}
+	opt.Option = append(opt.Option, ede)
+}
@@ -0,0 +1,61 @@
+package dnsutils
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+func responseEDEs(resp *dns.Msg) []*dns.EDNS0_EDE {
+	opt := resp.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	var out []*dns.EDNS0_EDE
+	for _, o := range opt.Option {
+		if ede, ok := o.(*dns.EDNS0_EDE); ok {
+			out = append(out, ede)
+		}
+	}
+	return out
+}
+
+func TestApplyEDE(t *testing.T) {
+	setLiveNSID(t, true, "")
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	resp := new(dns.Msg)
+	resp.SetRcode(req, dns.RcodeRefused)
+	ApplyEDE(resp, req, dns.ExtendedErrorCodeProhibited, "policy")
+	if resp.IsEdns0() != nil {
+		t.Fatalf("EDE added to a response for a query without EDNS")
+	}
+
+	req.SetEdns0(1232, true)
+	ApplyEDE(resp, req, dns.ExtendedErrorCodeProhibited, "policy")
+	ApplyEDE(resp, req, dns.ExtendedErrorCodeProhibited, "again")
+	edes := responseEDEs(resp)
+	if len(edes) != 1 || edes[0].InfoCode != dns.ExtendedErrorCodeProhibited || edes[0].ExtraText != "" {
+		t.Fatalf("EDE options = %v, want one Prohibited without EXTRA-TEXT", edes)
+	}
+	if !resp.IsEdns0().Do() {
+		t.Fatalf("response OPT does not mirror the DO bit")
+	}
+
+	config.AppConfig.LiveForTest().EDEExtraText = true
+	ApplyEDE(resp, req, dns.ExtendedErrorCodeNotReady, "zone not loaded")
+	edes = responseEDEs(resp)
+	if len(edes) != 2 || edes[1].InfoCode != dns.ExtendedErrorCodeNotReady || edes[1].ExtraText != "zone not loaded" {
+		t.Fatalf("EDE options = %v, want Not Ready with EXTRA-TEXT", edes)
+	}
+
+	config.AppConfig.LiveForTest().EnableEDNS = false
+	other := new(dns.Msg)
+	other.SetReply(req)
+	ApplyEDE(other, req, dns.ExtendedErrorCodeProhibited, "policy")
+	if other.IsEdns0() != nil {
+		t.Fatalf("EDE added while EDNS is disabled")
+	}
+}
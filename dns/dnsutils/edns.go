@@ -99,3 +99,119 @@ func responseOPT(resp *dns.Msg, reqOpt *dns.OPT) *dns.OPT {
 	resp.Extra = append(resp.Extra, opt)
 	return opt
 }
+
+// paddingBlockSize is the response block length recommended by RFC 8467
+// section 4.1 for block-length padding.
+const paddingBlockSize = 468
+
+// ApplyPadding adds an EDNS0 Padding option (RFC 7830) to the response so its
+// wire length becomes a multiple of 468 octets, following the block-length
+// padding strategy of RFC 8467.
+//
+// RFC 8467 section 4 requires a server to pad only responses to queries that
+// were padded themselves, and padding is only meaningful on an encrypted
+// transport, so callers apply it on the TLS listeners only. Any padding option
+// already on the response is replaced. When padding would push the message past
+// the 65535-octet limit of a stream transport the response is left unpadded.
+//
+// Parameters:
+//   - resp: The response *dns.Msg that is about to be written.
+//   - req:  The incoming *dns.Msg from the client.
+func ApplyPadding(resp *dns.Msg, req *dns.Msg) {
+	if resp == nil || req == nil {
+		return
+	}
+	if !config.AppConfig.GetLive().EnableEDNS {
+		return
+	}
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil || !requestPadded(reqOpt) {
+		return
+	}
+
+	opt := responseOPT(resp, reqOpt)
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_PADDING); !ok {
+			options = append(options, o)
+		}
+	}
+	padding := &dns.EDNS0_PADDING{}
+	opt.Option = append(options, padding)
+
+	length := resp.Len()
+	pad := 0
+	if r := length % paddingBlockSize; r != 0 {
+		pad = paddingBlockSize - r
+	}
+	if length+pad > dns.MaxMsgSize {
+		opt.Option = opt.Option[:len(opt.Option)-1]
+		return
+	}
+	padding.Padding = make([]byte, pad)
+}
+
+// requestPadded reports whether the client's OPT record carried a Padding
+// option, which is how it signals that padded responses are wanted.
+func requestPadded(opt *dns.OPT) bool {
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_PADDING); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyExpire adds an EDNS0 EXPIRE option (RFC 7314) to a response carrying
+// the SOA of one of our zones when the client asked for it. Section 3: a
+// primary sends the SOA expire value, a secondary the time left before its copy
+// expires, so a secondary fed by another secondary does not outlive the data.
+//
+// Parameters:
+//   - resp: The response *dns.Msg that will be sent to the client.
+//   - req:  The incoming *dns.Msg from the client.
+//   - soa:  The apex SOA of the zone the response is for.
+func ApplyExpire(resp *dns.Msg, req *dns.Msg, soa *dns.SOA) {
+	if resp == nil || req == nil || soa == nil {
+		return
+	}
+	if !config.AppConfig.GetLive().EnableEDNS {
+		return
+	}
+	reqOpt := req.IsEdns0()
+	if reqOpt == nil || !requestWantsExpire(reqOpt) {
+		return
+	}
+	expire, ok := secondaryExpireRemaining(soa.Hdr.Name)
+	if !ok {
+		expire = soa.Expire
+	}
+	opt := responseOPT(resp, reqOpt)
+	opt.Option = append(opt.Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Expire: expire})
+}
+
+// requestWantsExpire reports whether the client's OPT record carried an
+// EXPIRE option.
+func requestWantsExpire(opt *dns.OPT) bool {
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_EXPIRE); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// responseExpire returns the EXPIRE value a primary sent back, if any. An
+// empty option means the primary has no value to offer.
+func responseExpire(resp *dns.Msg) (uint32, bool) {
+	opt := resp.IsEdns0()
+	if opt == nil {
+		return 0, false
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*dns.EDNS0_EXPIRE); ok && !e.Empty {
+			return e.Expire, true
+		}
+	}
+	return 0, false
+}
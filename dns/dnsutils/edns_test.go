@@ -174,3 +174,62 @@ func TestApplyNSID_ReusesExistingResponseOPT(t *testing.T) {
 		t.Error("expected NSID to be added to the existing OPT record")
 	}
 }
+
+func paddedRequest(padded bool) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(1232, false)
+	if padded {
+		opt := req.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_PADDING{Padding: make([]byte, 64)})
+	}
+	return req
+}
+
+func TestApplyPadding_PadsToBlockLength(t *testing.T) {
+	setLiveNSID(t, true, "")
+
+	req := paddedRequest(true)
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Answer = append(resp.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   []byte{192, 0, 2, 1},
+	})
+
+	ApplyPadding(resp, req)
+
+	packed, err := resp.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if len(packed)%paddingBlockSize != 0 {
+		t.Fatalf("padded length = %d, want a multiple of %d", len(packed), paddingBlockSize)
+	}
+
+	// Applying padding twice must not stack a second option.
+	ApplyPadding(resp, req)
+	count := 0
+	for _, o := range resp.IsEdns0().Option {
+		if _, ok := o.(*dns.EDNS0_PADDING); ok {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("padding options = %d, want 1", count)
+	}
+}
+
+func TestApplyPadding_SilentWhenClientDidNotPad(t *testing.T) {
+	setLiveNSID(t, true, "")
+
+	req := paddedRequest(false)
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+
+	ApplyPadding(resp, req)
+
+	if resp.IsEdns0() != nil {
+		t.Fatalf("unpadded query must not get a padded response: %v", resp.Extra)
+	}
+}
@@ -3,10 +3,10 @@ package dnsutils
 import (
 	"encoding/json"
 	"fmt"
-	"go53/config"
 	"go53/internal"
 	"go53/types"
 	"go53/zone"
+	"go53/zonemeta"
 	"log"
 	"reflect"
 	"strings"
@@ -16,6 +16,15 @@ import (
 )
 
 func ImportRecords(rrtype string, zoneName string, data interface{}) error {
+	return importRecords(zone.Default, rrtype, zoneName, data)
+}
+
+// ImportViewRecords replaces zoneName in the zones of a view with records.
+func ImportViewRecords(zones zone.Scope, zoneName string, records []dns.RR) error {
+	return importRecords(zones, "", zoneName, records)
+}
+
+func importRecords(zones zone.Scope, rrtype string, zoneName string, data interface{}) error {
 	var zoneData types.ZoneData
 	var fromAPI bool
 	slog.Crazy("[fetch.go:ImportRecords] data is: ", data)
@@ -24,7 +33,7 @@ func ImportRecords(rrtype string, zoneName string, data interface{}) error {
 	case []dns.RR:
 		fromAPI = false
 		santitizedZone, _ := internal.SanitizeFQDN(zoneName)
-		err := zone.DeleteZone(santitizedZone)
+		err := zones.DeleteZone(santitizedZone)
 		if err != nil {
 			return err
 		}
@@ -50,10 +59,10 @@ func ImportRecords(rrtype string, zoneName string, data interface{}) error {
 		return fmt.Errorf("unsupported data type for import")
 	}
 
-	return importFromZoneData(zoneName, zoneData, fromAPI)
+	return importFromZoneData(zones, zoneName, zoneData, fromAPI)
 }
 
-func importFromZoneData(zoneName string, zd types.ZoneData, fromAPI bool) error {
+func importFromZoneData(zones zone.Scope, zoneName string, zd types.ZoneData, fromAPI bool) error {
 	add := func(rrtype uint16, name string, rec interface{}, ttl uint32) error {
 		b, _ := json.Marshal(rec)
 		var out map[string]interface{}
@@ -61,7 +70,7 @@ func importFromZoneData(zoneName string, zd types.ZoneData, fromAPI bool) error
 			return err
 		}
 		log.Println("Adding", name, "to", out)
-		return zone.AddRecord(rrtype, zoneName, name, out, &ttl)
+		return zones.AddRecord(rrtype, zoneName, name, out, &ttl)
 	}
 
 	val := reflect.ValueOf(zd)
@@ -159,10 +168,26 @@ func importFromZoneData(zoneName string, zd types.ZoneData, fromAPI bool) error
 		}
 	}
 
+	// Records in RFC 3597 opaque form are keyed by type name rather than by
+	// a field of their own, so the loop above does not reach them.
+	for typeName, owners := range zd.Unknown {
+		rrType, ok := internal.TypeCode(typeName)
+		if !ok {
+			continue
+		}
+		for name, recs := range owners {
+			for _, rec := range recs {
+				if err := add(rrType, name, rec, rec.TTL); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	if fromAPI {
 		if err := UpdateSOASerial(zoneName); err != nil {
 			log.Printf("warning: failed to update SOA serial: %v", err)
-		} else if config.AppConfig.GetLive().Mode != "secondary" {
+		} else if !zonemeta.IsSecondary(zoneName) {
 			go ScheduleNotify(zoneName)
 		}
 	}
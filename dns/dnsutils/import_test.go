@@ -27,7 +27,7 @@ func TestImportFromZoneDataAddsCommonRecords(t *testing.T) {
 		},
 		SOA: &types.SOARecord{Ns: "ns1.import.test.", Mbox: "hostmaster.import.test.", Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, Minimum: 300, TTL: 300},
 	}
-	if err := importFromZoneData("import.test.", zd, false); err != nil {
+	if err := importFromZoneData(zone.Default, "import.test.", zd, false); err != nil {
 		t.Fatalf("importFromZoneData: %v", err)
 	}
 	if rrs, ok := zone.LookupRecord(dns.TypeA, "www.import.test."); !ok || len(rrs) != 1 {
@@ -0,0 +1,313 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: ixfr_client.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/memory"
+	"go53/types"
+	"go53/zone"
+	"go53/zone/rtypes"
+)
+
+type ixfrFetchResult int
+
+const (
+	// ixfrFailed means the incremental attempt gave nothing usable and the
+	// caller should run a plain AXFR.
+	ixfrFailed ixfrFetchResult = iota
+	// ixfrApplied means the zone is current, either because the primary had
+	// nothing newer or because its differences were applied.
+	ixfrApplied
+	// ixfrFullZone means the primary answered with a full zone, which the
+	// caller imports like an AXFR.
+	ixfrFullZone
+)
+
+var errIXFRChain = errors.New("IXFR difference chain does not start at the local serial")
+
+// ixfrSequence is one deletion/addition pair from an incremental IXFR answer.
+type ixfrSequence struct {
+	from    *dns.SOA
+	to      *dns.SOA
+	deleted []dns.RR
+	added   []dns.RR
+}
+
+// ixfrSetKey identifies an RRset touched by an IXFR. covered is only set for
+// RRSIGs, which are stored per covered type.
+type ixfrSetKey struct {
+	owner   string
+	rrtype  uint16
+	covered uint16
+}
+
+// fetchZoneIncremental asks primary for the changes since the local SOA. It
+// returns the transferred records when the primary answered with a full zone.
+func fetchZoneIncremental(fqdn string, local *dns.SOA, primary catalogPrimary) ([]dns.RR, ixfrFetchResult) {
+	tran, addr, err := newZoneTransfer(primary)
+	if err != nil {
+		log.Printf("[fetchZone] cannot prepare IXFR of %s from %s: %v", fqdn, primary.addr(), err)
+		return nil, ixfrFailed
+	}
+	serial := local.Serial
+	req := new(dns.Msg)
+	req.SetQuestion(fqdn, dns.TypeIXFR)
+	req.Ns = []dns.RR{dns.Copy(local)}
+
+	if !applyTransferTSIG(req, tran, primary, "[fetchZone]") {
+		return nil, ixfrFailed
+	}
+
+	log.Printf("[fetchZone] starting IXFR of %s from %s at serial %d", fqdn, addr, serial)
+	envCh, err := tran.In(req, addr)
+	if err != nil {
+		log.Printf("[fetchZone] error initiating IXFR: %v", err)
+		return nil, ixfrFailed
+	}
+	var records []dns.RR
+	for env := range envCh {
+		if env.Error != nil {
+			log.Printf("[fetchZone] IXFR error for %s: %v", fqdn, env.Error)
+			return nil, ixfrFailed
+		}
+		records = append(records, env.RR...)
+	}
+
+	seqs, full, err := parseIXFRResponse(records, serial)
+	if err != nil {
+		log.Printf("[fetchZone] unusable IXFR answer for %s: %v", fqdn, err)
+		return nil, ixfrFailed
+	}
+	if full {
+		log.Printf("[fetchZone] primary answered IXFR for %s with a full zone (%d records)", fqdn, len(records))
+		return records, ixfrFullZone
+	}
+	if len(seqs) == 0 {
+		log.Printf("[fetchZone] %s is up to date at serial %d", fqdn, serial)
+		return nil, ixfrApplied
+	}
+	if err := applyIXFRSequences(fqdn, seqs); err != nil {
+		log.Printf("[fetchZone] applying IXFR for %s failed: %v", fqdn, err)
+		return nil, ixfrFailed
+	}
+	log.Printf("[fetchZone] applied %d IXFR difference sequences to %s, now at serial %d", len(seqs), fqdn, seqs[len(seqs)-1].to.Serial)
+	return nil, ixfrApplied
+}
+
+// parseIXFRResponse splits an IXFR answer (RFC 1995 section 4) into its
+// difference sequences. full is true when the primary sent the whole zone in
+// AXFR form instead; an answer holding only an SOA no newer than serial yields
+// no sequences.
+func parseIXFRResponse(rrs []dns.RR, serial uint32) ([]ixfrSequence, bool, error) {
+	if len(rrs) == 0 {
+		return nil, false, errors.New("empty IXFR answer")
+	}
+	current, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return nil, false, errors.New("IXFR answer does not start with SOA")
+	}
+	if len(rrs) == 1 {
+		if serialNewer(current.Serial, serial) {
+			return nil, false, errors.New("primary sent only the SOA for a newer serial")
+		}
+		return nil, false, nil
+	}
+	if last, ok := rrs[len(rrs)-1].(*dns.SOA); !ok || last.Serial != current.Serial {
+		return nil, false, errors.New("IXFR answer does not end with the current SOA")
+	}
+	if _, ok := rrs[1].(*dns.SOA); !ok || len(rrs) == 2 {
+		return nil, true, nil
+	}
+
+	var seqs []ixfrSequence
+	at := serial
+	i := 1
+	for i < len(rrs)-1 {
+		from, ok := rrs[i].(*dns.SOA)
+		if !ok || from.Serial != at {
+			return nil, false, errIXFRChain
+		}
+		seq := ixfrSequence{from: from}
+		i++
+		for ; i < len(rrs)-1; i++ {
+			if soa, ok := rrs[i].(*dns.SOA); ok {
+				seq.to = soa
+				break
+			}
+			seq.deleted = append(seq.deleted, rrs[i])
+		}
+		if seq.to == nil {
+			return nil, false, errors.New("IXFR deletion list is not closed by an SOA")
+		}
+		i++
+		for ; i < len(rrs)-1; i++ {
+			if _, ok := rrs[i].(*dns.SOA); ok {
+				break
+			}
+			seq.added = append(seq.added, rrs[i])
+		}
+		seqs = append(seqs, seq)
+		at = seq.to.Serial
+	}
+	if at != current.Serial {
+		return nil, false, fmt.Errorf("IXFR differences end at serial %d, want %d", at, current.Serial)
+	}
+	return seqs, false, nil
+}
+
+// applyIXFRSequences folds the difference sequences into the RRsets they
+// touch, builds the resulting RRsets in a staging zone and swaps them into the
+// live zone in a single step, so queries never see a partially applied
+// transfer.
+func applyIXFRSequences(fqdn string, seqs []ixfrSequence) error {
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+
+	sets := map[ixfrSetKey][]dns.RR{}
+	var order []ixfrSetKey
+	rrset := func(rr dns.RR) ixfrSetKey {
+		key := ixfrSetKey{owner: dns.CanonicalName(rr.Header().Name), rrtype: rr.Header().Rrtype}
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			key.covered = sig.TypeCovered
+		}
+		if _, ok := sets[key]; !ok {
+			sets[key] = loadIXFRRRset(key)
+			order = append(order, key)
+		}
+		return key
+	}
+
+	for _, seq := range seqs {
+		for _, rr := range seq.deleted {
+			if !nameInZone(rr.Header().Name, fqdn) {
+				return fmt.Errorf("IXFR deletes out-of-zone record %s", rr.Header().Name)
+			}
+			key := rrset(rr)
+			sets[key] = removeRdata(sets[key], rr)
+		}
+		for _, rr := range seq.added {
+			if !nameInZone(rr.Header().Name, fqdn) {
+				return fmt.Errorf("IXFR adds out-of-zone record %s", rr.Header().Name)
+			}
+			key := rrset(rr)
+			sets[key] = append(removeRdata(sets[key], rr), dns.Copy(rr))
+		}
+	}
+
+	soa := seqs[len(seqs)-1].to
+	if err := checkTransferredZONEMD(fqdn, ixfrResultingZone(mem, fqdn, sets, soa)); err != nil {
+		return err
+	}
+
+	staging := mem.BeginStaging()
+	keys := make([]memory.RRsetKey, 0, len(order)+1)
+	for _, key := range order {
+		rrs := sets[key]
+		if len(rrs) > 0 {
+			if err := importFromZoneData(zone.Default, staging, internal.RRToZoneDataForZone(fqdn, rrs), false); err != nil {
+				mem.DiscardStaging(staging)
+				return err
+			}
+		}
+		stored := memory.RRsetKey{Type: internal.TypeName(key.rrtype), Name: ixfrRelativeName(fqdn, key.owner)}
+		if key.rrtype == dns.TypeRRSIG {
+			stored.Covered = internal.TypeName(key.covered)
+		}
+		keys = append(keys, stored)
+	}
+
+	if err := mem.AddRecord(staging, string(types.TypeSOA), "@", types.SOARecord{
+		Ns:      soa.Ns,
+		Mbox:    soa.Mbox,
+		Serial:  soa.Serial,
+		Refresh: soa.Refresh,
+		Retry:   soa.Retry,
+		Expire:  soa.Expire,
+		Minimum: soa.Minttl,
+		TTL:     soa.Hdr.Ttl,
+	}); err != nil {
+		mem.DiscardStaging(staging)
+		return err
+	}
+	keys = append(keys, memory.RRsetKey{Type: string(types.TypeSOA), Name: "@"})
+
+	return mem.CommitStaging(staging, fqdn, keys)
+}
+
+// ixfrResultingZone returns the zone as it will look once the folded RRsets
+// and the new SOA are swapped in, so its ZONEMD can be checked first. Zones
+// that neither hold nor receive a ZONEMD are not assembled.
+func ixfrResultingZone(mem *memory.InMemoryZoneStore, fqdn string, sets map[ixfrSetKey][]dns.RR, soa *dns.SOA) []dns.RR {
+	apex := dns.CanonicalName(fqdn)
+	_, _, _, held := mem.GetRecord(fqdn, string(types.TypeZONEMD), "@")
+	if _, received := sets[ixfrSetKey{owner: apex, rrtype: dns.TypeZONEMD}]; !held && !received {
+		return []dns.RR{soa}
+	}
+
+	current, _ := mem.GetZone(fqdn)
+	out := make([]dns.RR, 0, len(current)+1)
+	for _, rr := range current {
+		key := ixfrSetKey{owner: dns.CanonicalName(rr.Header().Name), rrtype: rr.Header().Rrtype}
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			key.covered = sig.TypeCovered
+		}
+		if _, changed := sets[key]; changed || (key.rrtype == dns.TypeSOA && key.owner == apex) {
+			continue
+		}
+		out = append(out, rr)
+	}
+	for _, rrs := range sets {
+		out = append(out, rrs...)
+	}
+	return append(out, soa)
+}
+
+func loadIXFRRRset(key ixfrSetKey) []dns.RR {
+	if key.rrtype == dns.TypeRRSIG {
+		rrs, _ := zone.LookupRecord(dns.TypeRRSIG, key.owner+"___"+internal.TypeName(key.covered))
+		return rrs
+	}
+	rrs, _ := zone.LookupRecord(key.rrtype, key.owner)
+	return rrs
+}
+
+func removeRdata(set []dns.RR, rr dns.RR) []dns.RR {
+	out := set[:0:0]
+	for _, existing := range set {
+		if !sameRdata(existing, rr) {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
+
+func ixfrRelativeName(fqdn, owner string) string {
+	zoneName := strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	name := strings.ToLower(strings.TrimSuffix(owner, "."))
+	if name == zoneName {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zoneName)
+}
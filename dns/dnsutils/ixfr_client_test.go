@@ -0,0 +1,184 @@
+package dnsutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/memory"
+	"go53/storage"
+	"go53/zone"
+	"go53/zone/rtypes"
+)
+
+const ixfrClientZone = "ixfr-client.test."
+
+func TestParseIXFRResponse(t *testing.T) {
+	soa := func(serial uint32) dns.RR { return ixfrClientSOA(t, serial) }
+	a := mustUpdateRR(t, "www.ixfr-client.test. 300 IN A 192.0.2.1")
+
+	seqs, full, err := parseIXFRResponse([]dns.RR{soa(3), soa(1), a, soa(2), soa(2), soa(3), a, soa(3)}, 1)
+	if err != nil || full || len(seqs) != 2 {
+		t.Fatalf("incremental: seqs=%d full=%v err=%v", len(seqs), full, err)
+	}
+	if len(seqs[0].deleted) != 1 || len(seqs[0].added) != 0 || len(seqs[1].added) != 1 {
+		t.Fatalf("incremental sequences = %+v", seqs)
+	}
+
+	if _, full, err := parseIXFRResponse([]dns.RR{soa(3), a, soa(3)}, 1); err != nil || !full {
+		t.Fatalf("AXFR-style answer: full=%v err=%v", full, err)
+	}
+	if seqs, full, err := parseIXFRResponse([]dns.RR{soa(1)}, 1); err != nil || full || len(seqs) != 0 {
+		t.Fatalf("up-to-date answer: seqs=%d full=%v err=%v", len(seqs), full, err)
+	}
+	if _, _, err := parseIXFRResponse([]dns.RR{soa(3), soa(2), soa(3), soa(3)}, 1); err == nil {
+		t.Fatalf("chain starting at another serial must be rejected")
+	}
+	if _, _, err := parseIXFRResponse([]dns.RR{soa(3), soa(1), a, soa(2), soa(2)}, 1); err == nil {
+		t.Fatalf("answer without closing current SOA must be rejected")
+	}
+}
+
+func TestFetchZoneFromPrimaryAppliesIXFR(t *testing.T) {
+	setupIXFRClientZone(t)
+	var axfrs atomic.Int32
+	primary := startIXFRTestPrimary(t, 15363, &axfrs, func(t *testing.T) []dns.RR {
+		return []dns.RR{
+			ixfrClientSOA(t, 102),
+			ixfrClientSOA(t, 100),
+			mustUpdateRR(t, "old.ixfr-client.test. 300 IN A 192.0.2.2"),
+			ixfrClientSOA(t, 101),
+			mustUpdateRR(t, "new.ixfr-client.test. 300 IN A 192.0.2.3"),
+			ixfrClientSOA(t, 101),
+			mustUpdateRR(t, "new.ixfr-client.test. 300 IN A 192.0.2.3"),
+			ixfrClientSOA(t, 102),
+			mustUpdateRR(t, "www.ixfr-client.test. 300 IN A 192.0.2.4"),
+			ixfrClientSOA(t, 102),
+		}
+	})
+
+	if !fetchZoneFromPrimary(ixfrClientZone, primary) {
+		t.Fatalf("fetchZoneFromPrimary failed")
+	}
+	if axfrs.Load() != 0 {
+		t.Fatalf("AXFR requests = %d, want 0", axfrs.Load())
+	}
+	if serial, _ := localZoneSerial(ixfrClientZone); serial != 102 {
+		t.Fatalf("serial = %d, want 102", serial)
+	}
+	if _, ok := zone.LookupRecord(dns.TypeA, "old.ixfr-client.test."); ok {
+		t.Fatalf("deleted record survived IXFR")
+	}
+	if _, ok := zone.LookupRecord(dns.TypeA, "new.ixfr-client.test."); ok {
+		t.Fatalf("record added and deleted within the transfer survived IXFR")
+	}
+	rrs, ok := zone.LookupRecord(dns.TypeA, "www.ixfr-client.test.")
+	if !ok || len(rrs) != 2 {
+		t.Fatalf("www A = %v ok=%v, want old and added address", rrs, ok)
+	}
+	for _, name := range rtypes.GetMemStore().ZoneNamesSnapshot() {
+		if strings.Contains(name, "invalid") {
+			t.Fatalf("staging zone %s left behind", name)
+		}
+	}
+	for name := range storage.Backend.(*storage.MockStorage).Zones {
+		if strings.Contains(name, "invalid") {
+			t.Fatalf("staging zone %s persisted", name)
+		}
+	}
+}
+
+func TestFetchZoneFromPrimaryFallsBackToAXFROnBrokenChain(t *testing.T) {
+	setupIXFRClientZone(t)
+	var axfrs atomic.Int32
+	primary := startIXFRTestPrimary(t, 15364, &axfrs, func(t *testing.T) []dns.RR {
+		return []dns.RR{
+			ixfrClientSOA(t, 102),
+			ixfrClientSOA(t, 99),
+			ixfrClientSOA(t, 102),
+			ixfrClientSOA(t, 102),
+		}
+	})
+
+	if !fetchZoneFromPrimary(ixfrClientZone, primary) {
+		t.Fatalf("fetchZoneFromPrimary failed")
+	}
+	if axfrs.Load() != 1 {
+		t.Fatalf("AXFR requests = %d, want fallback to exactly one AXFR", axfrs.Load())
+	}
+	if serial, _ := localZoneSerial(ixfrClientZone); serial != 102 {
+		t.Fatalf("serial = %d, want 102 after AXFR", serial)
+	}
+}
+
+func setupIXFRClientZone(t *testing.T) {
+	t.Helper()
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	config.AppConfig.LiveForTest().DNSSECEnabled = false
+	config.AppConfig.LiveForTest().Mode = "secondary"
+	storage.Backend = &storage.MockStorage{Zones: map[string][]byte{}, Tables: map[string]map[string][]byte{}}
+	store, err := memory.NewZoneStore(storage.Backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	rtypes.InitMemoryStore(store)
+	t.Cleanup(func() {
+		rtypes.InitMemoryStore(nil)
+	})
+
+	if err := zone.AddRecord(dns.TypeSOA, ixfrClientZone, "@", map[string]interface{}{
+		"ns":      "ns1.ixfr-client.test.",
+		"mbox":    "hostmaster.ixfr-client.test.",
+		"serial":  float64(100),
+		"refresh": float64(3600),
+		"retry":   float64(900),
+		"expire":  float64(1209600),
+		"minimum": float64(300),
+	}, ptrUint32(3600)); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	for name, ip := range map[string]string{"www": "192.0.2.1", "old": "192.0.2.2"} {
+		if err := zone.AddRecord(dns.TypeA, ixfrClientZone, name, map[string]interface{}{"ip": ip}, ptrUint32(300)); err != nil {
+			t.Fatalf("add A: %v", err)
+		}
+	}
+}
+
+// startIXFRTestPrimary serves ixfr() for IXFR and the equivalent full zone for
+// AXFR, counting AXFR requests.
+func startIXFRTestPrimary(t *testing.T, port int, axfrs *atomic.Int32, ixfr func(*testing.T) []dns.RR) catalogPrimary {
+	t.Helper()
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		var rrs []dns.RR
+		if r.Question[0].Qtype == dns.TypeAXFR {
+			axfrs.Add(1)
+			rrs = []dns.RR{
+				ixfrClientSOA(t, 102),
+				mustUpdateRR(t, "www.ixfr-client.test. 300 IN A 192.0.2.1"),
+				ixfrClientSOA(t, 102),
+			}
+		} else {
+			rrs = ixfr(t)
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = rrs
+		_ = w.WriteMsg(m)
+	})
+	srv := &dns.Server{Addr: "127.0.0.1:" + strconv.Itoa(port), Net: "tcp", Handler: handler}
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	time.Sleep(100 * time.Millisecond)
+	return catalogPrimary{IP: "127.0.0.1", Port: port}
+}
+
+func ixfrClientSOA(t *testing.T, serial uint32) dns.RR {
+	t.Helper()
+	return mustUpdateRR(t, fmt.Sprintf("%s 3600 IN SOA ns1.ixfr-client.test. hostmaster.ixfr-client.test. %d 3600 900 1209600 300", ixfrClientZone, serial))
+}
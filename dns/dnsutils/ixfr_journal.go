@@ -0,0 +1,551 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: ixfr_journal.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/memory"
+	"go53/storage"
+	"go53/types"
+	"go53/zone/rtypes"
+)
+
+// IXFRJournalTable holds one JSON-encoded difference list per zone.
+const IXFRJournalTable = "ixfr-journal"
+
+// ixfrDiff is one RFC 1995 difference sequence: the RRs removed and added when
+// the zone moved from serial From to serial To. SOA records are kept apart
+// because they delimit the sequence on the wire.
+type ixfrDiff struct {
+	From    uint32   `json:"from"`
+	To      uint32   `json:"to"`
+	OldSOA  string   `json:"old_soa"`
+	NewSOA  string   `json:"new_soa"`
+	Deleted []string `json:"deleted"`
+	Added   []string `json:"added"`
+}
+
+// ixfrZoneJournal is the in-memory state for one zone. snapshot is the zone's
+// transfer content at serial soa.Serial, grouped by RRset, so the next serial
+// change can be diffed against it. pending holds RRsets written since then that
+// were not yet journaled because the serial had not moved. Each zone has its
+// own lock so journaling one zone does not hold up transfers of another.
+type ixfrZoneJournal struct {
+	mu         sync.Mutex
+	soa        *dns.SOA
+	snapshot   map[ixfrJournalKey]map[string]dns.RR
+	aliases    map[string]bool
+	pending    map[memory.RRsetKey]bool
+	pendingAll bool
+	diffs      []ixfrDiff
+	dropped    bool
+}
+
+// ixfrJournalKey names one RRset of the transfer content. RRSIGs are grouped with
+// the RRset they cover.
+type ixfrJournalKey struct {
+	owner string
+	rtype string
+}
+
+var (
+	ixfrMu       sync.Mutex
+	ixfrJournals = map[string]*ixfrZoneJournal{}
+)
+
+// LoadIXFRJournal restores persisted differences and snapshots every loaded
+// zone so the first change after startup can already be served incrementally.
+func LoadIXFRJournal() {
+	mem := rtypes.GetMemStore()
+	if mem == nil || storage.Backend == nil {
+		return
+	}
+	raw, err := storage.Backend.LoadTable(IXFRJournalTable)
+	if err != nil {
+		log.Printf("[ixfr] failed to load journal: %v", err)
+		raw = nil
+	}
+
+	journals := map[string]*ixfrZoneJournal{}
+	if config.AppConfig.GetLive().IXFR.JournalDepth > 0 {
+		for _, zoneName := range mem.ZoneNamesSnapshot() {
+			mem.TakeChangedRRsets(zoneName)
+			j := &ixfrZoneJournal{}
+			if !j.resnapshot(zoneName) {
+				continue
+			}
+			if data, ok := raw[zoneName]; ok {
+				if err := json.Unmarshal(data, &j.diffs); err != nil {
+					log.Printf("[ixfr] dropping unreadable journal for %s: %v", zoneName, err)
+					j.diffs = nil
+				}
+			}
+			journals[zoneName] = j
+		}
+	}
+	ixfrMu.Lock()
+	ixfrJournals = journals
+	ixfrMu.Unlock()
+}
+
+// RecordIXFRJournal appends the difference since the previous serial to the
+// journal if the serial has advanced. Only the RRsets the memory store reports
+// as written since the last call are re-read and compared, so the cost follows
+// the size of the change rather than of the zone. It is cheap to call when
+// nothing changed: an unchanged serial is a no-op, and content changes made
+// without a serial bump are folded into the next difference.
+func RecordIXFRJournal(zoneName string) {
+	depth := config.AppConfig.GetLive().IXFR.JournalDepth
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return
+	}
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return
+	}
+
+	ixfrMu.Lock()
+	if depth <= 0 {
+		delete(ixfrJournals, fqdn)
+		ixfrMu.Unlock()
+		mem.TakeChangedRRsets(fqdn)
+		return
+	}
+	j, ok := ixfrJournals[fqdn]
+	if !ok {
+		j = &ixfrZoneJournal{}
+		ixfrJournals[fqdn] = j
+	}
+	ixfrMu.Unlock()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.dropped {
+		return
+	}
+	keys, all := mem.TakeChangedRRsets(fqdn)
+	if j.soa == nil {
+		j.resnapshot(fqdn)
+		return
+	}
+	j.pendingAll = j.pendingAll || all
+	if j.pending == nil {
+		j.pending = map[memory.RRsetKey]bool{}
+	}
+	for _, key := range keys {
+		j.pending[key] = true
+	}
+
+	soa, ok := ixfrZoneSOA(fqdn)
+	if !ok || soa.Serial == j.soa.Serial {
+		return
+	}
+	if !serialNewer(soa.Serial, j.soa.Serial) {
+		log.Printf("[ixfr] serial for %s went from %d to %d; discarding journal", fqdn, j.soa.Serial, soa.Serial)
+		j.diffs = nil
+		j.resnapshot(fqdn)
+		persistIXFRJournal(fqdn, nil)
+		return
+	}
+
+	oldSOA, oldSnapshot := j.soa, j.snapshot
+	var affected map[ixfrJournalKey]bool
+	if j.pendingAll {
+		if !j.resnapshot(fqdn) {
+			return
+		}
+	} else {
+		affected = j.refresh(fqdn)
+	}
+	j.pending, j.pendingAll = nil, false
+
+	diff := ixfrDiff{From: oldSOA.Serial, To: j.soa.Serial, OldSOA: oldSOA.String(), NewSOA: j.soa.String()}
+	diff.Deleted, diff.Added = ixfrSnapshotDiff(oldSnapshot, j.snapshot, affected)
+
+	if n := len(j.diffs); n > 0 && j.diffs[n-1].To != diff.From {
+		j.diffs = nil
+	}
+	j.diffs = append(j.diffs, diff)
+	if len(j.diffs) > depth {
+		j.diffs = append([]ixfrDiff(nil), j.diffs[len(j.diffs)-depth:]...)
+	}
+	persistIXFRJournal(fqdn, j.diffs)
+}
+
+// resnapshot replaces the journal's snapshot with the full transfer content of
+// the zone. The previous snapshot map is left intact for the caller to diff.
+func (j *ixfrZoneJournal) resnapshot(zoneName string) bool {
+	soa, snapshot, aliases, ok := ixfrZoneSnapshot(zoneName)
+	if !ok {
+		return false
+	}
+	j.soa, j.snapshot, j.aliases = soa, snapshot, aliases
+	return true
+}
+
+// refresh re-reads the pending RRsets, the SOA and the ALIAS addresses into a
+// copy of the snapshot and returns the RRsets it replaced. The previous
+// snapshot map is left intact for the caller to diff.
+func (j *ixfrZoneJournal) refresh(zoneName string) map[ixfrJournalKey]bool {
+	mem := rtypes.GetMemStore()
+	keys := []memory.RRsetKey{{Type: string(types.TypeSOA), Name: "@"}}
+	affected := map[ixfrJournalKey]bool{{owner: zoneName, rtype: string(types.TypeSOA)}: true}
+	typeWide := map[string]bool{}
+	aliases := make(map[string]bool, len(j.aliases))
+	for owner := range j.aliases {
+		aliases[owner] = true
+	}
+	for key := range j.pending {
+		keys = append(keys, key)
+		if key.Name == "" {
+			typeWide[key.Type] = true
+			continue
+		}
+		owner := ixfrOwnerName(zoneName, key.Name)
+		if key.Type != string(types.TypeALIAS) {
+			affected[ixfrJournalKey{owner: owner, rtype: key.Type}] = true
+			continue
+		}
+		delete(aliases, owner)
+		if _, _, _, ok := mem.GetRecord(zoneName, key.Type, key.Name); ok {
+			aliases[owner] = true
+		}
+		affected[ixfrJournalKey{owner: owner, rtype: "A"}] = true
+		affected[ixfrJournalKey{owner: owner, rtype: "AAAA"}] = true
+	}
+
+	rrs := mem.GetRRsets(zoneName, keys)
+	rrs = append(rrs, ixfrALIASAddresses(mem, zoneName, aliases)...)
+	rrs = mem.SignZoneTransferRRsets(rrs)
+	soa, fresh := ixfrGroupRRs(rrs)
+	if soa != nil {
+		j.soa = soa
+	}
+
+	for owner := range j.aliases {
+		affected[ixfrJournalKey{owner: owner, rtype: "A"}] = true
+		affected[ixfrJournalKey{owner: owner, rtype: "AAAA"}] = true
+	}
+	for key := range j.snapshot {
+		if typeWide[key.rtype] {
+			affected[key] = true
+		}
+	}
+	for key := range fresh {
+		affected[key] = true
+	}
+
+	snapshot := make(map[ixfrJournalKey]map[string]dns.RR, len(j.snapshot))
+	for key, set := range j.snapshot {
+		snapshot[key] = set
+	}
+	for key := range affected {
+		if set, ok := fresh[key]; ok {
+			snapshot[key] = set
+		} else {
+			delete(snapshot, key)
+		}
+	}
+	j.snapshot, j.aliases = snapshot, aliases
+	return affected
+}
+
+// ixfrSnapshotDiff returns the RRs of the RRsets named by keys that are only
+// in before or only in after, sorted. With keys nil every RRset is compared.
+func ixfrSnapshotDiff(before, after map[ixfrJournalKey]map[string]dns.RR, keys map[ixfrJournalKey]bool) (deleted, added []string) {
+	if keys == nil {
+		keys = make(map[ixfrJournalKey]bool, len(after))
+		for key := range before {
+			keys[key] = true
+		}
+		for key := range after {
+			keys[key] = true
+		}
+	}
+	for key := range keys {
+		prev, next := before[key], after[key]
+		for rrKey, rr := range prev {
+			if _, ok := next[rrKey]; !ok {
+				deleted = append(deleted, rr.String())
+			}
+		}
+		for rrKey, rr := range next {
+			if _, ok := prev[rrKey]; !ok {
+				added = append(added, rr.String())
+			}
+		}
+	}
+	sort.Strings(deleted)
+	sort.Strings(added)
+	return deleted, added
+}
+
+// DropIXFRJournal forgets everything journaled for a deleted zone.
+func DropIXFRJournal(zoneName string) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return
+	}
+	ixfrMu.Lock()
+	j, ok := ixfrJournals[fqdn]
+	delete(ixfrJournals, fqdn)
+	ixfrMu.Unlock()
+	if ok {
+		j.mu.Lock()
+		j.dropped = true
+		j.mu.Unlock()
+	}
+	if storage.Backend != nil {
+		if err := storage.Backend.DeleteFromTable(IXFRJournalTable, fqdn); err != nil {
+			log.Printf("[ixfr] failed to delete journal for %s: %v", fqdn, err)
+		}
+	}
+}
+
+// ixfrDifferences builds the IXFR answer that brings a client at serial client
+// up to current, or reports false when the journal cannot bridge the gap and a
+// full transfer has to be sent instead.
+func ixfrDifferences(zoneName string, client uint32, current *dns.SOA, condense bool) ([]dns.RR, bool) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return nil, false
+	}
+
+	ixfrMu.Lock()
+	j, ok := ixfrJournals[fqdn]
+	ixfrMu.Unlock()
+	var chain []ixfrDiff
+	if ok {
+		j.mu.Lock()
+		chain = ixfrChain(j.diffs, client, current.Serial)
+		j.mu.Unlock()
+	}
+	if chain == nil {
+		return nil, false
+	}
+	if condense && len(chain) > 1 {
+		chain = []ixfrDiff{condenseIXFRDiffs(chain)}
+	}
+
+	out := []dns.RR{current}
+	for _, d := range chain {
+		seq, err := parseIXFRDiff(d)
+		if err != nil {
+			log.Printf("[ixfr] unreadable journal entry %d->%d for %s: %v", d.From, d.To, fqdn, err)
+			return nil, false
+		}
+		out = append(out, seq...)
+	}
+	return append(out, current), true
+}
+
+// ixfrChain returns the consecutive differences leading from serial from to
+// serial to, or nil if the journal does not cover that range.
+func ixfrChain(diffs []ixfrDiff, from, to uint32) []ixfrDiff {
+	for i, d := range diffs {
+		if d.From != from {
+			continue
+		}
+		for k := i; k < len(diffs); k++ {
+			if k > i && diffs[k].From != diffs[k-1].To {
+				return nil
+			}
+			if diffs[k].To == to {
+				return diffs[i : k+1]
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// condenseIXFRDiffs merges consecutive differences into one, cancelling RRs
+// that were added and later removed (or removed and later re-added).
+func condenseIXFRDiffs(chain []ixfrDiff) ixfrDiff {
+	deleted := map[string]bool{}
+	added := map[string]bool{}
+	for _, d := range chain {
+		for _, rr := range d.Deleted {
+			if added[rr] {
+				delete(added, rr)
+			} else {
+				deleted[rr] = true
+			}
+		}
+		for _, rr := range d.Added {
+			if deleted[rr] {
+				delete(deleted, rr)
+			} else {
+				added[rr] = true
+			}
+		}
+	}
+	out := ixfrDiff{
+		From:   chain[0].From,
+		To:     chain[len(chain)-1].To,
+		OldSOA: chain[0].OldSOA,
+		NewSOA: chain[len(chain)-1].NewSOA,
+	}
+	for rr := range deleted {
+		out.Deleted = append(out.Deleted, rr)
+	}
+	for rr := range added {
+		out.Added = append(out.Added, rr)
+	}
+	sort.Strings(out.Deleted)
+	sort.Strings(out.Added)
+	return out
+}
+
+func parseIXFRDiff(d ixfrDiff) ([]dns.RR, error) {
+	texts := make([]string, 0, len(d.Deleted)+len(d.Added)+2)
+	texts = append(texts, d.OldSOA)
+	texts = append(texts, d.Deleted...)
+	texts = append(texts, d.NewSOA)
+	texts = append(texts, d.Added...)
+	out := make([]dns.RR, 0, len(texts))
+	for _, text := range texts {
+		rr, err := dns.NewRR(text)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rr)
+	}
+	return out, nil
+}
+
+// ixfrZoneSnapshot returns the zone's SOA, the rest of its transfer content
+// grouped by RRset and keyed by ixfrRRKey within each, and the owners of its
+// ALIAS records. RRSIGs are included because secondaries receive them in AXFR
+// too.
+func ixfrZoneSnapshot(zoneName string) (*dns.SOA, map[ixfrJournalKey]map[string]dns.RR, map[string]bool, bool) {
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return nil, nil, nil, false
+	}
+	rrs, err := rtypes.TransferZone(mem, zoneName)
+	if err != nil || len(rrs) == 0 {
+		return nil, nil, nil, false
+	}
+	soa, snapshot := ixfrGroupRRs(mem.SignZoneTransferRRsets(rrs))
+	if soa == nil {
+		return nil, nil, nil, false
+	}
+	aliases := map[string]bool{}
+	for name := range mem.ZoneRecordsSnapshot(zoneName)[string(types.TypeALIAS)] {
+		aliases[ixfrOwnerName(zoneName, name)] = true
+	}
+	return soa, snapshot, aliases, true
+}
+
+// ixfrZoneSOA returns the SOA record stored for zoneName.
+func ixfrZoneSOA(zoneName string) (*dns.SOA, bool) {
+	mem := rtypes.GetMemStore()
+	for _, rr := range mem.GetRRsets(zoneName, []memory.RRsetKey{{Type: string(types.TypeSOA), Name: "@"}}) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa, true
+		}
+	}
+	return nil, false
+}
+
+// ixfrGroupRRs splits transfer content into the SOA and the remaining RRs
+// grouped by RRset.
+func ixfrGroupRRs(rrs []dns.RR) (*dns.SOA, map[ixfrJournalKey]map[string]dns.RR) {
+	var soa *dns.SOA
+	grouped := map[ixfrJournalKey]map[string]dns.RR{}
+	for _, rr := range rrs {
+		if s, ok := rr.(*dns.SOA); ok {
+			if soa == nil {
+				soa = dns.Copy(s).(*dns.SOA)
+			}
+			continue
+		}
+		rtype := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			rtype = sig.TypeCovered
+		}
+		key := ixfrJournalKey{owner: strings.ToLower(dns.Fqdn(rr.Header().Name)), rtype: internal.TypeName(rtype)}
+		if grouped[key] == nil {
+			grouped[key] = map[string]dns.RR{}
+		}
+		grouped[key][ixfrRRKey(rr)] = dns.Copy(rr)
+	}
+	return soa, grouped
+}
+
+// ixfrALIASAddresses resolves the ALIAS records at owners the way a transfer
+// carries them.
+func ixfrALIASAddresses(mem *memory.InMemoryZoneStore, zoneName string, owners map[string]bool) []dns.RR {
+	var out []dns.RR
+	for owner := range owners {
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			addrs, _, err := rtypes.ResolveALIAS(mem, owner, qtype)
+			if err != nil {
+				log.Printf("[ixfr] ALIAS %s left out of the journal of %s: %v", owner, zoneName, err)
+				continue
+			}
+			out = append(out, addrs...)
+		}
+	}
+	return out
+}
+
+// ixfrOwnerName turns a storage key of zoneName into a lower-case owner name.
+func ixfrOwnerName(zoneName, name string) string {
+	if name == "@" {
+		return zoneName
+	}
+	if dns.IsFqdn(name) {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name + "." + zoneName)
+}
+
+func ixfrRRKey(rr dns.RR) string {
+	cp := dns.Copy(rr)
+	cp.Header().Name = strings.ToLower(cp.Header().Name)
+	return cp.String()
+}
+
+func persistIXFRJournal(zoneName string, diffs []ixfrDiff) {
+	if storage.Backend == nil {
+		return
+	}
+	if len(diffs) == 0 {
+		if err := storage.Backend.DeleteFromTable(IXFRJournalTable, zoneName); err != nil {
+			log.Printf("[ixfr] failed to clear journal for %s: %v", zoneName, err)
+		}
+		return
+	}
+	data, err := json.Marshal(diffs)
+	if err != nil {
+		log.Printf("[ixfr] failed to encode journal for %s: %v", zoneName, err)
+		return
+	}
+	if err := storage.Backend.SaveTable(IXFRJournalTable, zoneName, data); err != nil {
+		log.Printf("[ixfr] failed to persist journal for %s: %v", zoneName, err)
+	}
+}
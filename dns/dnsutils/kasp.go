@@ -0,0 +1,278 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: kasp.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/distributed"
+	"go53/internal"
+	"go53/security"
+	"go53/zone"
+	"go53/zone/rtypes"
+	"go53/zonemeta"
+)
+
+// kaspNow is the clock of the key scheduler; tests replace it.
+var kaspNow = time.Now
+
+// publishedKeySets remembers, per zone, the DNSKEY, CDS and CDNSKEY RRsets
+// last served at the apex, so a scheduler step can tell whether they moved.
+var publishedKeySets = struct {
+	sync.Mutex
+	byZone map[string]string
+}{byZone: map[string]string{}}
+
+// DNSSECTimeline is the key and signing policy state of a zone: the policy it
+// follows, the node that rolls its keys in distributed mode, its keys, the
+// key events ahead and its algorithm rollover until that has completed.
+type DNSSECTimeline struct {
+	Zone              string                            `json:"zone"`
+	Policy            string                            `json:"policy,omitempty"`
+	Owner             string                            `json:"owner,omitempty"`
+	Error             string                            `json:"error,omitempty"`
+	AlgorithmRollover *security.AlgorithmRolloverStatus `json:"algorithm_rollover,omitempty"`
+	security.KASPTimeline
+}
+
+// ZoneKASPPolicy returns the name and policy of zoneName: its own
+// dnssec_policy or dnssec.default_policy. ok is false when neither names a
+// configured policy or the zone opted out.
+func ZoneKASPPolicy(zoneName string) (string, config.KASPPolicy, bool) {
+	dnssecCfg := config.AppConfig.GetLive().DNSSEC
+	name := zoneSettings(zoneName).DNSSECPolicy
+	if name == "" {
+		name = dnssecCfg.DefaultPolicy
+	}
+	if name == "" || name == zonemeta.PolicyNone {
+		return "", config.KASPPolicy{}, false
+	}
+	policy, ok := dnssecCfg.Policies[name]
+	if !ok {
+		return name, config.KASPPolicy{}, false
+	}
+	return name, policy, true
+}
+
+// signedZones returns the zones this node signs. Imports that keep their own
+// signatures are left alone.
+func signedZones() []string {
+	mem := rtypes.GetMemStore()
+	if mem == nil || !config.AppConfig.GetLive().DNSSECEnabled {
+		return nil
+	}
+	var out []string
+	for _, name := range mem.ZoneNamesSnapshot() {
+		meta, err := zonemeta.Load(name)
+		if err != nil || meta.EffectiveRole() == zonemeta.RoleSecondary || meta.DNSSECMode == "preserve" {
+			continue
+		}
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// kaspZones returns the signed zones that have a policy.
+func kaspZones() []string {
+	var out []string
+	for _, name := range signedZones() {
+		if _, _, ok := ZoneKASPPolicy(name); ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// StartKASP runs the key scheduler every dnssec.kasp_interval_sec until ctx
+// is done. It also checks the SKRs of offline-KSK zones and renews the ZONEMD
+// of zones whose signatures changed since it was computed.
+func StartKASP(ctx context.Context) {
+	go func() {
+		for {
+			interval := time.Duration(config.AppConfig.GetLive().DNSSEC.KASPIntervalSec) * time.Second
+			if interval <= 0 {
+				interval = time.Minute
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+				runKASPOnce()
+				runSKROnce()
+				runZONEMDOnce()
+			}
+		}
+	}()
+}
+
+func runKASPOnce() {
+	for _, name := range kaspZones() {
+		if err := runKASPZone(name); err != nil {
+			log.Printf("[kasp] %s: %v", name, err)
+		}
+	}
+}
+
+// runKASPZone takes one scheduler step for zoneName. In distributed mode the
+// owner of the zone acts on time and the other nodes only after
+// dnssec.kasp_takeover_sec, so an unreachable owner does not stall a
+// rollover. Every decision is replicated as a key event.
+func runKASPZone(zoneName string) error {
+	_, policy, ok := ZoneKASPPolicy(zoneName)
+	if !ok {
+		return nil
+	}
+	var delay int64
+	if !distributed.OwnsZone(zoneName) {
+		delay = int64(config.AppConfig.GetLive().DNSSEC.KASPTakeoverSec)
+		if delay <= 0 {
+			return nil
+		}
+	}
+	res, err := security.KASPStep(zoneName, policy, kaspNow().Unix(), delay)
+	if applyErr := applyKASPResult(zoneName, res, delay == 0 || len(res.Decisions) > 0); applyErr != nil && err == nil {
+		err = applyErr
+	}
+	return err
+}
+
+// applyKASPResult logs the decisions of a scheduler step, re-signs the zone
+// when keys or its algorithm rollover changed and, with publish set,
+// replicates them. A change of the key sets the zone serves or of the phase of
+// its algorithm rollover bumps its serial.
+func applyKASPResult(zoneName string, res security.KASPResult, publish bool) error {
+	for _, decision := range res.Decisions {
+		log.Printf("[kasp] %s: %s", zoneName, decision)
+	}
+	if len(res.Changed) == 0 && res.Rollover == nil {
+		// Keys also enter and leave the apex as their times pass.
+		return bumpOnKeySetChange(zoneName, false, false)
+	}
+	if r := res.Rollover; r != nil {
+		log.Printf("[kasp] %s: algorithm rollover from %s to %s in phase %s", zoneName, r.From, r.To, r.Phase)
+	}
+	err := zone.RefreshDNSSECKeyMaterial(zoneName)
+	if publish && res.Rollover != nil {
+		if pubErr := distributed.Default.PublishAlgorithmRollover(zoneName, *res.Rollover); pubErr != nil && err == nil {
+			err = pubErr
+		}
+	}
+	if publish {
+		ids := make([]string, 0, len(res.Changed))
+		for id := range res.Changed {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if pubErr := distributed.Default.PublishDNSSECKey(id, res.Changed[id]); pubErr != nil && err == nil {
+				err = pubErr
+			}
+		}
+	}
+	if bumpErr := bumpOnKeySetChange(zoneName, len(res.Changed) > 0, res.Rollover != nil); bumpErr != nil && err == nil {
+		err = bumpErr
+	}
+	return err
+}
+
+// bumpOnKeySetChange bumps the SOA serial of zoneName and notifies its
+// secondaries when the DNSKEY, CDS or CDNSKEY RRset it serves differs from
+// the one seen at the previous step. A zone not seen before counts as changed
+// only when keys did, so a restart does not move every serial. A phase change
+// of an algorithm rollover always bumps it, as it changes the signatures the
+// zone carries even when the key sets stay.
+func bumpOnKeySetChange(zoneName string, keysChanged, phaseChanged bool) error {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return err
+	}
+	current := keySetFingerprint(fqdn)
+	publishedKeySets.Lock()
+	previous, seen := publishedKeySets.byZone[fqdn]
+	publishedKeySets.byZone[fqdn] = current
+	publishedKeySets.Unlock()
+	if !phaseChanged && (seen && previous == current || !seen && !keysChanged) {
+		return nil
+	}
+	if err := UpdateSOASerial(fqdn); err != nil {
+		return err
+	}
+	go ScheduleNotify(fqdn)
+	return nil
+}
+
+func keySetFingerprint(fqdn string) string {
+	var out []string
+	for _, qtype := range []uint16{dns.TypeDNSKEY, dns.TypeCDS, dns.TypeCDNSKEY} {
+		rrs, _ := zone.LookupRecord(qtype, fqdn)
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == qtype {
+				out = append(out, rr.String())
+			}
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, "\n")
+}
+
+// ZoneDNSSECTimeline reports the policy, keys and coming key events of
+// zoneName.
+func ZoneDNSSECTimeline(zoneName string) (DNSSECTimeline, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return DNSSECTimeline{}, err
+	}
+	name, policy, ok := ZoneKASPPolicy(fqdn)
+	out := DNSSECTimeline{Zone: fqdn, Policy: name, Owner: distributed.ZoneOwner(fqdn)}
+	now := kaspNow().Unix()
+	timeline, err := security.ZoneKASPTimeline(fqdn, policy, now)
+	out.KASPTimeline = timeline
+	if rollover, found, rerr := security.ZoneAlgorithmRollover(fqdn); rerr == nil && found {
+		if status := rollover.StatusAt(now); status.Phase != security.AlgorithmRolloverCompleted {
+			out.AlgorithmRollover = &status
+		}
+	}
+	switch {
+	case !ok:
+		// Without a policy nothing is planned; the stored times still apply.
+		out.Events = storedEventsOnly(out.Events)
+		if name != "" && name != zonemeta.PolicyNone {
+			out.Error = fmt.Sprintf("DNSSEC policy %s is not configured", name)
+		}
+	case err != nil:
+		out.Error = err.Error()
+	}
+	return out, nil
+}
+
+func storedEventsOnly(events []security.KeyEvent) []security.KeyEvent {
+	out := []security.KeyEvent{}
+	for _, ev := range events {
+		if !ev.Planned {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
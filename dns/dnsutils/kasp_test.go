@@ -0,0 +1,155 @@
+package dnsutils
+
+import (
+	"sync"
+	"testing"
+
+	"go53/config"
+	"go53/security"
+	"go53/storage"
+	"go53/zonemeta"
+)
+
+// metaCountingStorage counts the reads of the zone_meta table.
+type metaCountingStorage struct {
+	storage.Storage
+	mu    sync.Mutex
+	loads int
+}
+
+func (c *metaCountingStorage) LoadTable(table string) (map[string][]byte, error) {
+	if table == zonemeta.TableName {
+		c.mu.Lock()
+		c.loads++
+		c.mu.Unlock()
+	}
+	return c.Storage.LoadTable(table)
+}
+
+func setupKASPTest(t *testing.T) {
+	t.Helper()
+	setupCatalogTestStore(t, "primary")
+	if err := security.InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	live := config.AppConfig.LiveForTest()
+	live.Secondary.CatalogEnabled = false
+	live.DNSSECEnabled = true
+	live.DNSSEC.DefaultPolicy = "default"
+	live.DNSSEC.Policies = map[string]config.KASPPolicy{
+		"default": {Algorithm: "ECDSAP256SHA256", ZSKLifetimeSec: 86400, DNSKEYTTLSec: 300, PropagationDelaySec: 60},
+	}
+}
+
+func TestKASPZonesFollowPolicies(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "signed.test.")
+	addTestSOA(t, "optout.test.")
+	if err := zonemeta.SaveSettings("optout.test.", zonemeta.Settings{DNSSECPolicy: zonemeta.PolicyNone}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+
+	zones := kaspZones()
+	if len(zones) != 1 || zones[0] != "signed.test." {
+		t.Fatalf("kaspZones = %v", zones)
+	}
+
+	config.AppConfig.LiveForTest().DNSSEC.DefaultPolicy = ""
+	if zones := kaspZones(); len(zones) != 0 {
+		t.Fatalf("kaspZones without a default policy = %v", zones)
+	}
+}
+
+func TestSignedZonesDoesNotScanZoneMetaPerZone(t *testing.T) {
+	setupKASPTest(t)
+	for _, name := range []string{"a.test.", "b.test.", "c.test."} {
+		addTestSOA(t, name)
+	}
+	if err := zonemeta.SetRole("b.test.", zonemeta.RoleSecondary); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	counting := &metaCountingStorage{Storage: storage.Backend}
+	storage.Backend = counting
+
+	for i := 0; i < 2; i++ {
+		if zones := signedZones(); len(zones) != 2 || zones[0] != "a.test." || zones[1] != "c.test." {
+			t.Fatalf("signedZones = %v", zones)
+		}
+	}
+	counting.mu.Lock()
+	defer counting.mu.Unlock()
+	if counting.loads > 1 {
+		t.Fatalf("zone_meta read %d times for two passes over three zones", counting.loads)
+	}
+}
+
+func TestRunKASPZoneGeneratesKeys(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "keys.test.")
+
+	if err := runKASPZone("keys.test."); err != nil {
+		t.Fatalf("runKASPZone: %v", err)
+	}
+	keys, err := security.LoadAllKeysForZone("keys.test")
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("keys = %d, err %v", len(keys), err)
+	}
+
+	timeline, err := ZoneDNSSECTimeline("keys.test.")
+	if err != nil {
+		t.Fatalf("ZoneDNSSECTimeline: %v", err)
+	}
+	if timeline.Policy != "default" || timeline.Error != "" || len(timeline.Keys) != 2 {
+		t.Fatalf("timeline = %+v", timeline)
+	}
+	planned := 0
+	for _, ev := range timeline.Events {
+		if ev.Planned {
+			planned++
+		}
+	}
+	if planned == 0 {
+		t.Fatalf("no planned ZSK rollover in %+v", timeline.Events)
+	}
+}
+
+func TestRunKASPZoneBumpsSerialWhenKeySetsChange(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "serial.test.")
+	before, ok := localZoneSOA("serial.test.")
+	if !ok {
+		t.Fatal("no SOA")
+	}
+
+	if err := runKASPZone("serial.test."); err != nil {
+		t.Fatalf("runKASPZone: %v", err)
+	}
+	after, _ := localZoneSOA("serial.test.")
+	if after.Serial == before.Serial {
+		t.Fatalf("serial stayed %d after the first keys were published", after.Serial)
+	}
+
+	if err := runKASPZone("serial.test."); err != nil {
+		t.Fatalf("second runKASPZone: %v", err)
+	}
+	again, _ := localZoneSOA("serial.test.")
+	if again.Serial != after.Serial {
+		t.Fatalf("serial moved from %d to %d without a key set change", after.Serial, again.Serial)
+	}
+}
+
+func TestZoneDNSSECTimelineReportsUnknownPolicy(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "unknown.test.")
+	if err := zonemeta.SaveSettings("unknown.test.", zonemeta.Settings{DNSSECPolicy: "gone"}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+
+	timeline, err := ZoneDNSSECTimeline("unknown.test.")
+	if err != nil {
+		t.Fatalf("ZoneDNSSECTimeline: %v", err)
+	}
+	if timeline.Policy != "gone" || timeline.Error == "" || len(timeline.Events) != 0 {
+		t.Fatalf("timeline = %+v", timeline)
+	}
+}
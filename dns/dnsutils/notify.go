@@ -8,9 +8,9 @@ import (
 	"go53/security"
 	"go53/zone"
 	"go53/zone/rtypes"
+	"go53/zonemeta"
 	"log"
 	"math/rand"
-	"net"
 	"strings"
 	"sync"
 	"time"
@@ -78,9 +78,15 @@ func ScheduleNotify(zone string) {
 	})
 }
 
-// SendNotify sends a DNS NOTIFY message for the given zone to all
-// configured transfer targets. It tries UDP first and falls back to TCP
-// if the UDP attempt fails. Each target is notified asynchronously.
+// transferTSIGKeyName is the TSIG key used for NOTIFY and transfers of zones
+// without per-zone keys when enforce_tsig is set.
+const transferTSIGKeyName = "xxfr-key"
+
+// SendNotify sends a DNS NOTIFY message for the given zone to its notify
+// targets (see notifyTargets). Each target is notified asynchronously, over
+// UDP with a TCP fallback, and retried with exponential backoff until it
+// answers (see deliverNotify). Delivery and the serial each target then
+// serves are tracked for PropagationStatus.
 //
 // Parameters:
 //   - inzone: The raw zone name (which will be sanitized before use).
@@ -89,75 +95,63 @@ func SendNotify(inzone string) {
 	if err != nil {
 		log.Printf("warning: failed to sanitize FQDN: %v", err)
 	}
-	targets := strings.Split(config.AppConfig.GetLive().AllowTransfer, ",")
-	enforceTSIG := config.AppConfig.GetLive().EnforceTSIG
-
-	const tsigKeyName = "xxfr-key" //TODO: We have this set in too many locations need a central place for all constants
-	fqdnKeyName, _ := internal.SanitizeFQDN(tsigKeyName)
-	tsigKey, tsigExists := security.GetTSIGKey(fqdnKeyName)
+	targets, source := notifyTargets(szone, config.AppConfig.GetLive())
+	serial, _ := localZoneSerial(szone)
+	generation := startNotifyTracking(szone, serial, targets)
 
 	for _, target := range targets {
-		target = strings.TrimSpace(target)
-		if target == "" {
-			continue
-		}
-
-		addr := target
-		if !strings.Contains(addr, ":") {
-			addr += ":53"
-		}
-
-		host, _, err := net.SplitHostPort(addr)
-		if err != nil {
-			log.Printf("SendNotify: invalid address format '%s': %v", addr, err)
-			continue
-		}
-		if ip := net.ParseIP(host); ip == nil {
-			log.Printf("SendNotify: invalid IP address '%s'", host)
-			continue
-		}
+		go deliverNotify(szone, generation, target, source)
+	}
+	watchPropagation(szone)
+}
 
-		go func(addr string) {
-			m := new(dns.Msg)
-			m.SetNotify(szone)
-			m.RecursionDesired = false
+// sendNotifyOnce sends one NOTIFY for zoneName to target, over UDP and then
+// TCP if UDP gets no answer. The target must answer NOERROR.
+func sendNotifyOnce(zoneName string, target notifyTarget, source string) error {
+	m := new(dns.Msg)
+	m.SetNotify(zoneName)
+	m.RecursionDesired = false
 
-			// Optional: sign with TSIG
-			var tsigSecrets map[string]string
-			if enforceTSIG && tsigExists {
-				m.SetTsig(fqdnKeyName, dns.HmacSHA256, 300, time.Now().Unix())
-				tsigSecrets = map[string]string{fqdnKeyName: tsigKey.Secret}
-			}
+	udpClient := &dns.Client{
+		Net:     "udp",
+		Timeout: 3 * time.Second,
+		Dialer:  notifyDialer(source, "udp", 3*time.Second),
+	}
+	if target.TSIGKey != "" && !applyTransferTSIG(m, udpClient, catalogPrimary{TSIGKeyName: target.TSIGKey}, "SendNotify:") {
+		return fmt.Errorf("TSIG key %s is not loaded", target.TSIGKey)
+	}
 
-			udpClient := &dns.Client{
-				Net:        "udp",
-				Timeout:    3 * time.Second,
-				TsigSecret: tsigSecrets,
-			}
+	resp, _, err := udpClient.Exchange(m, target.Addr)
+	if err == nil {
+		log.Printf("SendNotify: notified %s for zone %s over UDP", target.Addr, zoneName)
+		return notifyResponseError(resp)
+	}
 
-			_, _, err := udpClient.Exchange(m, addr)
-			log.Println("Zone is: ", szone)
-			if err == nil {
-				log.Printf("SendNotify: successfully notified %s for zone %s over UDP", addr, szone)
-				return
-			}
+	log.Printf("SendNotify: UDP notify to %s failed: %v — retrying over TCP", target.Addr, err)
 
-			log.Printf("SendNotify: UDP notify to %s failed: %v — retrying over TCP", addr, err)
+	tcpClient := &dns.Client{
+		Net:        "tcp",
+		Timeout:    5 * time.Second,
+		TsigSecret: udpClient.TsigSecret,
+		Dialer:     notifyDialer(source, "tcp", 5*time.Second),
+	}
 
-			tcpClient := &dns.Client{
-				Net:        "tcp",
-				Timeout:    5 * time.Second,
-				TsigSecret: tsigSecrets,
-			}
+	resp, _, err = tcpClient.Exchange(m, target.Addr)
+	if err != nil {
+		log.Printf("SendNotify: TCP notify to %s for zone %s also failed: %v", target.Addr, zoneName, err)
+		return err
+	}
+	log.Printf("SendNotify: notified %s for zone %s over TCP", target.Addr, zoneName)
+	return notifyResponseError(resp)
+}
 
-			_, _, err = tcpClient.Exchange(m, addr)
-			if err != nil {
-				log.Printf("SendNotify: TCP notify to %s for zone %s also failed: %v", addr, szone, err)
-			} else {
-				log.Printf("SendNotify: successfully notified %s for zone %s over TCP", addr, szone)
-			}
-		}(addr)
+// notifyResponseError turns a NOTIFY answer other than NOERROR into an error,
+// so a secondary that refuses the NOTIFY is retried.
+func notifyResponseError(resp *dns.Msg) error {
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("NOTIFY answered %s", dns.RcodeToString[resp.Rcode])
 	}
+	return nil
 }
 
 // HandleNotify processes an incoming DNS NOTIFY message.
@@ -224,20 +218,31 @@ func localZoneSerial(lzone string) (uint32, error) {
 // with the local zone's SOA serial. It determines if the primary has a newer version.
 //
 // Returns true if the primary serial is higher (indicating an update is available),
-// or false if the serials are equal, missing, or if an error occurs.
+// or false if the serials are equal, missing, or if an error occurs. Every answer
+// is recorded in the zone's refresh timers; when no primary answers the zone is
+// retried on its SOA retry timer and moves towards expiry.
 //
 // Parameters:
 //   - zone: The zone name to check.
 func checkSOA(zone string) bool {
+	var lastErr error
 	for _, primary := range transferPrimariesForZone(zone) {
-		if checkSOAFromPrimary(zone, primary) {
-			return true
+		newer, err := checkSOAFromPrimary(zone, primary)
+		if err == nil {
+			return newer
 		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		recordCheckFailure(zone, lastErr.Error())
 	}
 	return false
 }
 
-func checkSOAFromPrimary(zone string, primary catalogPrimary) bool {
+// checkSOAFromPrimary asks primary for the SOA of zone with an EDNS EXPIRE
+// option (RFC 7314) and records the answer. It reports whether the primary has
+// a newer serial; an error means the primary gave no usable answer.
+func checkSOAFromPrimary(zone string, primary catalogPrimary) (bool, error) {
 	addr := primary.addr()
 
 	// 2) build the query
@@ -245,40 +250,46 @@ func checkSOAFromPrimary(zone string, primary catalogPrimary) bool {
 	fqdn, _ := internal.SanitizeFQDN(zone)
 	m.SetQuestion(fqdn, dns.TypeSOA)
 	m.RecursionDesired = false
+	m.SetEdns0(dns.DefaultMsgSize, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Empty: true})
 
 	c := &dns.Client{
 		Timeout: 3 * time.Second,
 	}
 	if !applyTransferTSIG(m, c, primary, "[checkSOA]") {
-		return false
+		return false, fmt.Errorf("TSIG key for %s is not loaded", addr)
 	}
 
 	resp, _, err := c.Exchange(m, addr)
 	if err != nil {
 		log.Printf("[checkSOA] lookup %s SOA on %s: %v", zone, addr, err)
-		return false
+		return false, fmt.Errorf("SOA query to %s: %w", addr, err)
 	}
 
-	var primarySerial uint32
+	var primarySOA *dns.SOA
 	for _, ans := range resp.Answer {
 		if soa, ok := ans.(*dns.SOA); ok {
-			primarySerial = soa.Serial
+			primarySOA = soa
 			break
 		}
 	}
-	if primarySerial == 0 {
+	if primarySOA == nil || primarySOA.Serial == 0 {
 		log.Printf("[checkSOA] no SOA in answer for %s from %s", zone, addr)
-		return false
+		return false, fmt.Errorf("no SOA for %s from %s (rcode %s)", zone, addr, dns.RcodeToString[resp.Rcode])
 	}
 
 	localSerial, err := localZoneSerial(zone)
 	if err != nil {
 		log.Printf("[checkSOA] cannot read local serial for %s: %v", zone, err)
-		return false
+		return false, err
 	}
 
-	log.Printf("[checkSOA] %s primary=%d local=%d", zone, primarySerial, localSerial)
-	return primarySerial > localSerial
+	expire, hasExpire := responseExpire(resp)
+	newer := primarySOA.Serial > localSerial
+	log.Printf("[checkSOA] %s primary=%d local=%d", zone, primarySOA.Serial, localSerial)
+	recordSOACheck(zone, primarySOA, expire, hasExpire, newer)
+	return newer, nil
 }
 
 // enqueueFetch applies the per-zone pending guard and secondary.min_fetch_interval_sec
@@ -349,45 +360,39 @@ func handleNotify(zone string) {
 func fetchZone(zoneName string) bool {
 	for _, primary := range transferPrimariesForZone(zoneName) {
 		if fetchZoneFromPrimary(zoneName, primary) {
+			recordTransfer(zoneName)
 			return true
 		}
 	}
+	recordTransferFailure(zoneName)
 	return false
 }
 
 func fetchZoneFromPrimary(zoneName string, primary catalogPrimary) bool {
-	addr := primary.addr()
-	req := new(dns.Msg)
 	fqdn, _ := internal.SanitizeFQDN(zoneName)
-	req.SetAxfr(fqdn)
-
-	tran := &dns.Transfer{
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
-	}
-	if !applyTransferTSIG(req, tran, primary, "[fetchZone]") {
-		return false
-	}
-
-	log.Printf("[fetchZone] starting AXFR of %s from %s", zoneName, addr)
-	envCh, err := tran.In(req, addr)
-	if err != nil {
-		log.Printf("[fetchZone] error initiating AXFR: %v", err)
-		return false
-	}
 
 	var records []dns.RR
-	for env := range envCh {
-		if env.Error != nil {
-			log.Printf("[fetchZone] AXFR error for %s: %v", zoneName, env.Error)
+	if local, ok := localZoneSOA(fqdn); ok {
+		var result ixfrFetchResult
+		records, result = fetchZoneIncremental(fqdn, local, primary)
+		switch result {
+		case ixfrApplied:
+			RecordIXFRJournal(zoneName)
+			return true
+		case ixfrFailed:
+			records = nil
+		}
+	}
+	if records == nil {
+		var ok bool
+		if records, ok = transferFullZone(fqdn, primary); !ok {
 			return false
 		}
-		records = append(records, env.RR...)
 	}
 
-	log.Printf("[fetchZone] AXFR returned %d records", len(records))
-	log.Printf("[fetchZone] AXFR returned the records: %+v", records)
+	if err := checkTransferredZONEMD(fqdn, records); err != nil {
+		return false
+	}
 
 	var oldCatalogMembers, newCatalogMembers []string
 	if catalog, ok := catalogZoneName(); ok && fqdn == catalog {
@@ -395,7 +400,7 @@ func fetchZoneFromPrimary(zoneName string, primary catalogPrimary) bool {
 		newCatalogMembers = catalogMembersFromRecords(records, catalog)
 	}
 
-	err = ImportRecords("", zoneName, records)
+	err := ImportRecords("", zoneName, records)
 	if err != nil {
 		log.Println("[fetchZone] error importing AXFR records: ", err)
 		return false
@@ -403,15 +408,61 @@ func fetchZoneFromPrimary(zoneName string, primary catalogPrimary) bool {
 	if oldCatalogMembers != nil {
 		pruneRemovedCatalogMembers(oldCatalogMembers, newCatalogMembers)
 	}
+	RecordIXFRJournal(zoneName)
 
 	log.Printf("[fetchZone] got %d records for %s", len(records), zoneName)
 	return true
 }
 
+// localZoneSOA returns the SOA of a locally held copy of fqdn, if any.
+func localZoneSOA(fqdn string) (*dns.SOA, bool) {
+	rrs, ok := zone.LookupRecord(dns.TypeSOA, fqdn)
+	if !ok || len(rrs) == 0 {
+		return nil, false
+	}
+	soa, ok := rrs[0].(*dns.SOA)
+	return soa, ok
+}
+
+// transferFullZone runs an AXFR of fqdn from primary.
+func transferFullZone(fqdn string, primary catalogPrimary) ([]dns.RR, bool) {
+	tran, addr, err := newZoneTransfer(primary)
+	if err != nil {
+		log.Printf("[fetchZone] cannot prepare transfer of %s from %s: %v", fqdn, primary.addr(), err)
+		return nil, false
+	}
+	req := new(dns.Msg)
+	req.SetAxfr(fqdn)
+
+	if !applyTransferTSIG(req, tran, primary, "[fetchZone]") {
+		return nil, false
+	}
+
+	log.Printf("[fetchZone] starting AXFR of %s from %s", fqdn, addr)
+	envCh, err := tran.In(req, addr)
+	if err != nil {
+		log.Printf("[fetchZone] error initiating AXFR: %v", err)
+		return nil, false
+	}
+
+	var records []dns.RR
+	for env := range envCh {
+		if env.Error != nil {
+			log.Printf("[fetchZone] AXFR error for %s: %v", fqdn, env.Error)
+			return nil, false
+		}
+		records = append(records, env.RR...)
+	}
+
+	log.Printf("[fetchZone] AXFR returned %d records", len(records))
+	log.Printf("[fetchZone] AXFR returned the records: %+v", records)
+	return records, true
+}
+
 func applyTransferTSIG(msg *dns.Msg, target any, primary catalogPrimary, logPrefix string) bool {
 	tsigKeyName := primary.TSIGKeyName
 	if tsigKeyName == "" && config.AppConfig.GetLive().EnforceTSIG {
-		tsigKeyName, _ = internal.SanitizeFQDN("xxfr-key")
+		tsigKeyName, _ = internal.SanitizeFQDN(transferTSIGKeyName)
 	}
 	if tsigKeyName == "" {
 		return true
@@ -439,6 +490,9 @@ func applyTransferTSIG(msg *dns.Msg, target any, primary catalogPrimary, logPref
 }
 
 func transferPrimariesForZone(zoneName string) []catalogPrimary {
+	if primaries := zonePrimaries(zoneName); len(primaries) > 0 {
+		return primaries
+	}
 	if primaries, found := catalogPrimariesForZoneWithPresence(zoneName); found {
 		return primaries
 	}
@@ -454,6 +508,9 @@ func transferPrimariesForZone(zoneName string) []catalogPrimary {
 }
 
 func hasTransferPrimaryForZone(zoneName string, live config.LiveConfig) bool {
+	if len(zonePrimaries(zoneName)) > 0 {
+		return true
+	}
 	if primaries, found := catalogPrimariesForZoneWithPresence(zoneName); found {
 		return len(primaries) > 0
 	}
@@ -506,16 +563,18 @@ func ProcessFetchQueue() {
 }
 
 // secondaryEnabled reports whether secondary refresh logic should run for the given
-// live config. It mirrors the NOTIFY gating used in dns/handler.go.
+// live config: the server is a secondary or some zone has the secondary role.
 func secondaryEnabled(live config.LiveConfig) bool {
-	return live.Mode == "secondary"
+	return live.Mode == zonemeta.RoleSecondary || len(zonemeta.ZonesWithRole(zonemeta.RoleSecondary)) > 0
 }
 
 // refreshZoneUnion returns the deduped union of the configured bootstrap zones
 // (Secondary.Zones) and the locally stored zones (ZoneNamesSnapshot), as sanitized
-// FQDNs. The configured list bootstraps a fresh/empty secondary; the local snapshot
-// self-heals already-imported zones after downtime.
+// FQDNs, limited to zones with the secondary role. The configured list bootstraps a
+// fresh/empty secondary; the local snapshot self-heals already-imported zones after
+// downtime.
 func refreshZoneUnion() []string {
+	isSecondary := secondaryZoneFilter()
 	set := make(map[string]struct{})
 	for _, z := range config.AppConfig.GetLive().Secondary.Zones {
 		if f, err := internal.SanitizeFQDN(z); err == nil && f != "" {
@@ -528,6 +587,12 @@ func refreshZoneUnion() []string {
 	for _, z := range catalogMembers() {
 		set[z] = struct{}{}
 	}
+	for _, z := range settingsSecondaryZones() {
+		set[z] = struct{}{}
+	}
+	for _, z := range zonemeta.ZonesWithRole(zonemeta.RoleSecondary) {
+		set[z] = struct{}{}
+	}
 	if store := rtypes.GetMemStore(); store != nil {
 		for _, z := range store.ZoneNamesSnapshot() {
 			if f, err := internal.SanitizeFQDN(z); err == nil && f != "" {
@@ -537,26 +602,71 @@ func refreshZoneUnion() []string {
 	}
 	out := make([]string, 0, len(set))
 	for z := range set {
-		out = append(out, z)
+		if isSecondary(z) {
+			out = append(out, z)
+		}
 	}
 	return out
 }
 
+// PendingSecondaryZone returns the configured or catalog-listed secondary zone
+// covering name when that zone has never been transferred, so queries for it
+// can be answered with SERVFAIL and EDE "Not Ready" instead of REFUSED.
+func PendingSecondaryZone(name string) (string, bool) {
+	live := config.AppConfig.GetLive()
+	if !secondaryEnabled(live) {
+		return "", false
+	}
+	qname := strings.ToLower(dns.Fqdn(name))
+	candidates := append([]string{}, live.Secondary.Zones...)
+	if catalog, ok := catalogZoneName(); ok {
+		candidates = append(candidates, catalog)
+	}
+	candidates = append(candidates, catalogMembers()...)
+	candidates = append(candidates, settingsSecondaryZones()...)
+	candidates = append(candidates, zonemeta.ZonesWithRole(zonemeta.RoleSecondary)...)
+
+	isSecondary := secondaryZoneFilter()
+	best := ""
+	for _, z := range candidates {
+		f, err := internal.SanitizeFQDN(z)
+		if err != nil || f == "" || !isSecondary(f) {
+			continue
+		}
+		f = strings.ToLower(f)
+		if (qname == f || strings.HasSuffix(qname, "."+f)) && len(f) > len(best) {
+			best = f
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	if store := rtypes.GetMemStore(); store != nil && store.HasZone(best) {
+		return "", false
+	}
+	return best, true
+}
+
 // StartSecondaryRefresh runs a one-shot startup sweep and then the periodic refresh
-// ticker. It is a no-op unless secondary mode is active and at least one transfer
-// primary is configured or discoverable from the catalog.
+// ticker, next to the per-zone SOA timers that schedule each zone once it has been
+// checked. The sweep is skipped unless a zone is a secondary and at least one transfer
+// primary is configured or discoverable from the catalog; the ticker keeps checking,
+// so zones demoted later are refreshed too.
 // The startup sweep enqueues the zone union once
 // through the guarded enqueueFetch path, so ProcessFetchQueue's SOA-gate decides whether
 // an AXFR is actually needed. NOTIFY remains the fast-path signal on top of this.
 //
 // The provided context cancels the periodic ticker for graceful shutdown.
 func StartSecondaryRefresh(ctx context.Context) {
+	go runZoneTimers(ctx)
 	live := config.AppConfig.GetLive()
 	if !secondaryEnabled(live) {
+		go runRefreshTicker(ctx)
 		return
 	}
 	if !hasAnyTransferPrimary(live) {
-		log.Printf("[secondary-refresh] disabled: no primary is configured")
+		log.Printf("[secondary-refresh] startup sweep skipped: no primary is configured")
+		go runRefreshTicker(ctx)
 		return
 	}
 
@@ -573,7 +683,8 @@ func StartSecondaryRefresh(ctx context.Context) {
 }
 
 // runRefreshTicker periodically re-enqueues the zone union on the configured
-// Secondary.RefreshIntervalSec cadence. A value <= 0 disables periodic refresh (the
+// Secondary.RefreshIntervalSec cadence, as a safety net for zones whose SOA timers
+// are not known yet. A value <= 0 disables periodic refresh (the
 // startup sweep still ran, and NOTIFY remains active). It mirrors the distributed
 // resync ticker idiom: read the interval once, run on the ticker, exit on ctx.Done().
 func runRefreshTicker(ctx context.Context) {
@@ -601,9 +712,16 @@ func runRefreshTicker(ctx context.Context) {
 // sweepOnce recomputes the zone union and enqueues each zone, spreading the enqueues
 // across [0, RefreshJitterSec] so a many-zone secondary does not hammer the primary at
 // a single instant. The union is recomputed each call so newly imported local zones and
-// edits to Secondary.Zones are picked up automatically.
+// edits to Secondary.Zones are picked up automatically. Zones whose SOA refresh timer
+// has not run out are left to runZoneTimers.
 func sweepOnce(ctx context.Context, live config.LiveConfig) {
-	zones := refreshZoneUnion()
+	var zones []string
+	now := timerNow()
+	for _, z := range refreshZoneUnion() {
+		if secondaryCheckDue(z, now) {
+			zones = append(zones, z)
+		}
+	}
 	jitterMax := time.Duration(live.Secondary.RefreshJitterSec) * time.Second
 	log.Printf("[secondary-refresh] periodic sweep: %d zones", len(zones))
 	for _, z := range zones {
@@ -627,7 +745,7 @@ func hasAnyTransferPrimary(live config.LiveConfig) bool {
 		return true
 	}
 	for _, z := range refreshZoneUnion() {
-		if len(catalogPrimariesForZone(z)) > 0 {
+		if len(zonePrimaries(z)) > 0 || len(catalogPrimariesForZone(z)) > 0 {
 			return true
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"go53/config"
 	"go53/security"
+	"go53/zone"
 	"sync"
 	"testing"
 	"time"
@@ -316,6 +317,7 @@ func TestCheckSOA_MissingPrimary(t *testing.T) {
 		},
 	})
 
+	t.Cleanup(func() { ForgetSecondaryTimers("nonexistent.com.") })
 	ok := checkSOA("nonexistent.com.")
 	if ok {
 		t.Errorf("checkSOA should return false on connection failure")
@@ -365,10 +367,21 @@ func TestSendNotify_InvalidTarget(t *testing.T) {
 
 // collectQueued drains up to want zone names from fetchQueue within the timeout and
 // returns them as a set. It does not require ProcessFetchQueue to be running.
-func collectQueued(want int, timeout time.Duration) map[string]bool {
+// collectQueued drains fetchQueue until every zone in want was seen or timeout
+// passes. Zones queued by goroutines of earlier tests are collected too, but
+// do not end the wait.
+func collectQueued(want []string, timeout time.Duration) map[string]bool {
 	got := make(map[string]bool)
 	deadline := time.After(timeout)
-	for len(got) < want {
+	seen := func() bool {
+		for _, z := range want {
+			if !got[z] {
+				return false
+			}
+		}
+		return true
+	}
+	for !seen() {
 		select {
 		case z := <-fetchQueue:
 			got[z] = true
@@ -393,9 +406,11 @@ func TestStartSecondaryRefresh_StartupSweepEnqueuesConfiguredZones(t *testing.T)
 	config.AppConfig.LiveForTest().Secondary.RefreshIntervalSec = 0 // disable ticker; test the one-shot sweep
 	config.AppConfig.LiveForTest().Secondary.Zones = []string{"a.test.", "b.test."}
 
-	StartSecondaryRefresh(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartSecondaryRefresh(ctx)
 
-	got := collectQueued(2, time.Second)
+	got := collectQueued([]string{"a.test.", "b.test."}, time.Second)
 	if !got["a.test."] || !got["b.test."] {
 		t.Fatalf("startup sweep enqueued %v, want a.test. and b.test.", got)
 	}
@@ -413,7 +428,9 @@ func TestStartSecondaryRefresh_DisabledInPrimaryMode(t *testing.T) {
 	config.AppConfig.LiveForTest().Primary.Ip = "127.0.0.1"
 	config.AppConfig.LiveForTest().Secondary.Zones = []string{"a.test."}
 
-	StartSecondaryRefresh(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartSecondaryRefresh(ctx)
 
 	select {
 	case z := <-fetchQueue:
@@ -434,7 +451,9 @@ func TestStartSecondaryRefresh_DisabledWhenPrimaryIpEmpty(t *testing.T) {
 	config.AppConfig.LiveForTest().Primary.Ip = "" // no upstream configured
 	config.AppConfig.LiveForTest().Secondary.Zones = []string{"a.test."}
 
-	StartSecondaryRefresh(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartSecondaryRefresh(ctx)
 
 	select {
 	case z := <-fetchQueue:
@@ -451,9 +470,11 @@ func TestStartSecondaryRefreshUsesCatalogPrimaryWhenPrimaryIpEmpty(t *testing.T)
 	addCatalogPTR(t, "m1.zones", "member.test.")
 	addCatalogA(t, "ns1.primaries.ext", "192.0.2.53")
 
-	StartSecondaryRefresh(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	StartSecondaryRefresh(ctx)
 
-	got := collectQueued(1, time.Second)
+	got := collectQueued([]string{"member.test."}, time.Second)
 	if !got["member.test."] {
 		t.Fatalf("startup sweep enqueued %v, want member.test.", got)
 	}
@@ -643,3 +664,26 @@ func TestProcessFetchQueue_Trigger(t *testing.T) {
 		t.Error("expected fetchZone to be called")
 	}
 }
+
+func TestPendingSecondaryZone(t *testing.T) {
+	setupCatalogTestStore(t, "secondary")
+	config.AppConfig.LiveForTest().Secondary.Zones = []string{"pending.test", "sub.loaded.test."}
+	if err := zone.AddRecord(dns.TypeSOA, "sub.loaded.test.", "sub.loaded.test.", map[string]interface{}{"ns": "ns1.loaded.test.", "mbox": "hostmaster.loaded.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)}, nil); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+
+	if got, ok := PendingSecondaryZone("www.Pending.test."); !ok || got != "pending.test." {
+		t.Fatalf("PendingSecondaryZone(www.pending.test.) = %q, %v", got, ok)
+	}
+	if got, ok := PendingSecondaryZone("www.sub.loaded.test."); ok {
+		t.Fatalf("loaded zone reported pending: %q", got)
+	}
+	if got, ok := PendingSecondaryZone("www.other.test."); ok {
+		t.Fatalf("unconfigured zone reported pending: %q", got)
+	}
+
+	config.AppConfig.LiveForTest().Mode = "primary"
+	if got, ok := PendingSecondaryZone("www.pending.test."); ok {
+		t.Fatalf("primary reported pending zone %q", got)
+	}
+}
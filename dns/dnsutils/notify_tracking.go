@@ -0,0 +1,436 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: notify_tracking.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/zone"
+)
+
+// NOTIFY delivery states of a target.
+const (
+	NotifyPending = "pending"
+	NotifyAcked   = "acked"
+	NotifyFailed  = "failed"
+)
+
+// Sources of a propagation target.
+const (
+	PropagationSourceNotify = "notify"
+	PropagationSourceNS     = "ns"
+)
+
+// NotifyDelivery is the NOTIFY state of one target for the latest notified
+// serial of a zone. Times are Unix seconds.
+type NotifyDelivery struct {
+	Address       string `json:"address"`
+	State         string `json:"state"`
+	Attempts      int    `json:"attempts"`
+	LastAttemptAt int64  `json:"last_attempt_at,omitempty"`
+	AckedAt       int64  `json:"acked_at,omitempty"`
+	NextRetryAt   int64  `json:"next_retry_at,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// PropagationTarget is a server expected to serve a zone, a NOTIFY target, a
+// name server of the apex NS RRset or both, with the serial it answered.
+type PropagationTarget struct {
+	Address   string          `json:"address"`
+	Sources   []string        `json:"sources"`
+	NSName    string          `json:"ns_name,omitempty"`
+	Serial    uint32          `json:"serial,omitempty"`
+	InSync    bool            `json:"in_sync"`
+	CheckedAt int64           `json:"checked_at,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Notify    *NotifyDelivery `json:"notify,omitempty"`
+
+	tsigKey string
+}
+
+// ZonePropagation reports whether the current serial of a zone has reached
+// every target. Lagging lists the targets that have not answered with it.
+type ZonePropagation struct {
+	Zone           string              `json:"zone"`
+	Serial         uint32              `json:"serial"`
+	NotifiedSerial uint32              `json:"notified_serial,omitempty"`
+	NotifiedAt     int64               `json:"notified_at,omitempty"`
+	CheckedAt      int64               `json:"checked_at,omitempty"`
+	Complete       bool                `json:"complete"`
+	Lagging        []string            `json:"lagging"`
+	Targets        []PropagationTarget `json:"targets"`
+}
+
+// zoneNotifyState is the NOTIFY round for the latest serial of a zone and the
+// result of the last propagation check. generation changes with every
+// SendNotify so retries of an older round stop.
+type zoneNotifyState struct {
+	serial     uint32
+	generation uint64
+	notifiedAt int64
+	deliveries map[string]*NotifyDelivery
+
+	checked    []PropagationTarget
+	checkedAt  int64
+	watching   bool
+	watchUntil time.Time
+}
+
+var (
+	trackingMu       sync.Mutex
+	notifyTracking   = map[string]*zoneNotifyState{}
+	notifyGeneration uint64
+)
+
+func notifyStateLocked(zoneName string) *zoneNotifyState {
+	st, ok := notifyTracking[zoneName]
+	if !ok {
+		st = &zoneNotifyState{deliveries: map[string]*NotifyDelivery{}}
+		notifyTracking[zoneName] = st
+	}
+	return st
+}
+
+// startNotifyTracking begins a NOTIFY round for serial of zoneName and returns
+// its generation.
+func startNotifyTracking(zoneName string, serial uint32, targets []notifyTarget) uint64 {
+	trackingMu.Lock()
+	defer trackingMu.Unlock()
+	notifyGeneration++
+	st := notifyStateLocked(zoneName)
+	st.serial = serial
+	st.generation = notifyGeneration
+	st.notifiedAt = time.Now().Unix()
+	st.deliveries = make(map[string]*NotifyDelivery, len(targets))
+	for _, target := range targets {
+		st.deliveries[target.Addr] = &NotifyDelivery{Address: target.Addr, State: NotifyPending}
+	}
+	return st.generation
+}
+
+// notifyCurrent reports whether generation is still the latest NOTIFY round
+// of zoneName.
+func notifyCurrent(zoneName string, generation uint64) bool {
+	trackingMu.Lock()
+	defer trackingMu.Unlock()
+	st, ok := notifyTracking[zoneName]
+	return ok && st.generation == generation
+}
+
+// recordNotifyAttempt stores the outcome of one NOTIFY to addr. A zero next
+// means no retry follows.
+func recordNotifyAttempt(zoneName string, generation uint64, addr string, err error, next time.Time) {
+	trackingMu.Lock()
+	defer trackingMu.Unlock()
+	st, ok := notifyTracking[zoneName]
+	if !ok || st.generation != generation {
+		return
+	}
+	d, ok := st.deliveries[addr]
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	d.Attempts++
+	d.LastAttemptAt = now
+	d.NextRetryAt = 0
+	switch {
+	case err == nil:
+		d.State = NotifyAcked
+		d.AckedAt = now
+		d.LastError = ""
+	case next.IsZero():
+		d.State = NotifyFailed
+		d.LastError = err.Error()
+	default:
+		d.State = NotifyPending
+		d.NextRetryAt = next.Unix()
+		d.LastError = err.Error()
+	}
+}
+
+// deliverNotify sends NOTIFY to target until it answers, retrying up to
+// primary.notify_retries times. RFC 1996 section 3.6: the interval starts at
+// primary.notify_retry_base_ms and doubles up to primary.notify_retry_max_ms.
+// A newer NOTIFY round for the zone ends the retries.
+func deliverNotify(zoneName string, generation uint64, target notifyTarget, source string) {
+	live := config.AppConfig.GetLive()
+	backoff := time.Duration(live.Primary.NotifyRetryBaseMs) * time.Millisecond
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := time.Duration(live.Primary.NotifyRetryMaxMs) * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		if !notifyCurrent(zoneName, generation) {
+			return
+		}
+		err := sendNotifyOnce(zoneName, target, source)
+		if err == nil || attempt >= live.Primary.NotifyRetries {
+			recordNotifyAttempt(zoneName, generation, target.Addr, err, time.Time{})
+			if err != nil {
+				log.Printf("SendNotify: giving up on %s for zone %s after %d attempts: %v", target.Addr, zoneName, attempt+1, err)
+			}
+			return
+		}
+		recordNotifyAttempt(zoneName, generation, target.Addr, err, time.Now().Add(backoff))
+		time.Sleep(backoff)
+		backoff *= 2
+		if maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// propagationTargets returns the servers that should serve zoneName: its
+// NOTIFY targets and the addresses of its apex NS names.
+func propagationTargets(zoneName string) []PropagationTarget {
+	var out []PropagationTarget
+	index := make(map[string]int)
+	add := func(addr, src, nsName, tsigKey string) {
+		if i, ok := index[addr]; ok {
+			t := &out[i]
+			if !slices.Contains(t.Sources, src) {
+				t.Sources = append(t.Sources, src)
+			}
+			if t.NSName == "" {
+				t.NSName = nsName
+			}
+			if t.tsigKey == "" {
+				t.tsigKey = tsigKey
+			}
+			return
+		}
+		index[addr] = len(out)
+		out = append(out, PropagationTarget{Address: addr, Sources: []string{src}, NSName: nsName, tsigKey: tsigKey})
+	}
+
+	targets, _ := notifyTargets(zoneName, config.AppConfig.GetLive())
+	for _, target := range targets {
+		add(target.Addr, PropagationSourceNotify, "", target.TSIGKey)
+	}
+	rrs, _ := zone.LookupRecord(dns.TypeNS, zoneName)
+	for _, rr := range rrs {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		for _, ip := range nameServerAddresses(ns.Ns) {
+			add(net.JoinHostPort(ip, "53"), PropagationSourceNS, ns.Ns, "")
+		}
+	}
+	return out
+}
+
+// nameServerAddresses returns the addresses of a name server, from our own
+// zones when we have them and from the system resolver otherwise.
+func nameServerAddresses(host string) []string {
+	var out []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		rrs, _ := zone.LookupRecord(qtype, host)
+		for _, rr := range rrs {
+			switch a := rr.(type) {
+			case *dns.A:
+				out = append(out, a.A.String())
+			case *dns.AAAA:
+				out = append(out, a.AAAA.String())
+			}
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		log.Printf("[propagation] cannot resolve name server %s: %v", host, err)
+		return nil
+	}
+	for _, addr := range addrs {
+		out = append(out, addr.IP.String())
+	}
+	return out
+}
+
+// querySOASerial asks addr for the SOA serial of zoneName, signed with
+// tsigKey when set.
+func querySOASerial(zoneName, addr, tsigKey string) (uint32, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(zoneName, dns.TypeSOA)
+	m.RecursionDesired = false
+	c := &dns.Client{Timeout: 2 * time.Second}
+	if tsigKey != "" && !applyTransferTSIG(m, c, catalogPrimary{TSIGKeyName: tsigKey}, "[propagation]") {
+		return 0, fmt.Errorf("TSIG key %s is not loaded", tsigKey)
+	}
+	resp, _, err := c.Exchange(m, addr)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return 0, fmt.Errorf("answered %s", dns.RcodeToString[resp.Rcode])
+	}
+	if soa, ok := firstSOA(resp.Answer); ok {
+		return soa.Serial, nil
+	}
+	return 0, fmt.Errorf("no SOA in answer")
+}
+
+// checkPropagation queries every target of zoneName for its serial and
+// stores the result.
+func checkPropagation(zoneName string) {
+	serial, err := localZoneSerial(zoneName)
+	if err != nil {
+		return
+	}
+	targets := propagationTargets(zoneName)
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(t *PropagationTarget) {
+			defer wg.Done()
+			got, err := querySOASerial(zoneName, t.Address, t.tsigKey)
+			t.CheckedAt = time.Now().Unix()
+			if err != nil {
+				t.Error = err.Error()
+				return
+			}
+			t.Serial = got
+			t.InSync = got == serial || serialNewer(got, serial)
+		}(&targets[i])
+	}
+	wg.Wait()
+
+	trackingMu.Lock()
+	st := notifyStateLocked(zoneName)
+	st.checked = targets
+	st.checkedAt = time.Now().Unix()
+	trackingMu.Unlock()
+}
+
+// watchPropagation polls the targets of zoneName every
+// primary.propagation_poll_sec after a NOTIFY until all serve the current
+// serial or primary.propagation_timeout_sec passes. One watcher runs per
+// zone; a later NOTIFY extends its deadline.
+func watchPropagation(zoneName string) {
+	live := config.AppConfig.GetLive()
+	poll := time.Duration(live.Primary.PropagationPollSec) * time.Second
+	if poll <= 0 {
+		return
+	}
+	trackingMu.Lock()
+	st := notifyStateLocked(zoneName)
+	st.watchUntil = time.Time{}
+	if live.Primary.PropagationTimeoutSec > 0 {
+		st.watchUntil = time.Now().Add(time.Duration(live.Primary.PropagationTimeoutSec) * time.Second)
+	}
+	if st.watching {
+		trackingMu.Unlock()
+		return
+	}
+	st.watching = true
+	trackingMu.Unlock()
+
+	go func() {
+		for {
+			time.Sleep(poll)
+			checkPropagation(zoneName)
+			report, err := PropagationStatus(zoneName, false)
+			trackingMu.Lock()
+			st := notifyStateLocked(zoneName)
+			done := err != nil || (report.Complete && report.Serial == st.serial) ||
+				(!st.watchUntil.IsZero() && time.Now().After(st.watchUntil))
+			if done {
+				st.watching = false
+			}
+			trackingMu.Unlock()
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+// PropagationStatus reports which targets of zoneName serve its current
+// serial, from the last check or, with refresh, from querying them now. It is
+// only complete once a check has run and found no target lagging.
+func PropagationStatus(zoneName string, refresh bool) (ZonePropagation, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return ZonePropagation{}, err
+	}
+	soa, ok := localZoneSOA(fqdn)
+	if !ok {
+		return ZonePropagation{}, fmt.Errorf("zone %s not found", fqdn)
+	}
+	serial := soa.Serial
+	if refresh {
+		checkPropagation(fqdn)
+	}
+
+	trackingMu.Lock()
+	defer trackingMu.Unlock()
+	out := ZonePropagation{Zone: fqdn, Serial: serial, Lagging: []string{}}
+	st, ok := notifyTracking[fqdn]
+	var targets []PropagationTarget
+	deliveries := map[string]*NotifyDelivery{}
+	if ok {
+		out.NotifiedSerial = st.serial
+		out.NotifiedAt = st.notifiedAt
+		out.CheckedAt = st.checkedAt
+		targets = append(targets, st.checked...)
+		deliveries = st.deliveries
+	}
+	seen := make(map[string]bool)
+	for i := range targets {
+		t := &targets[i]
+		seen[t.Address] = true
+		// Compare with the serial now; it may have moved since the check.
+		t.InSync = t.Error == "" && (t.Serial == serial || serialNewer(t.Serial, serial))
+	}
+	for addr := range deliveries {
+		if !seen[addr] {
+			targets = append(targets, PropagationTarget{Address: addr, Sources: []string{PropagationSourceNotify}})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Address < targets[j].Address })
+	for i := range targets {
+		t := &targets[i]
+		if d, ok := deliveries[t.Address]; ok {
+			copied := *d
+			t.Notify = &copied
+		}
+		if !t.InSync {
+			out.Lagging = append(out.Lagging, t.Address)
+		}
+	}
+	out.Targets = targets
+	if out.Targets == nil {
+		out.Targets = []PropagationTarget{}
+	}
+	out.Complete = out.CheckedAt > 0 && len(out.Lagging) == 0
+	return out, nil
+}
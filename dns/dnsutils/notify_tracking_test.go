@@ -0,0 +1,169 @@
+package dnsutils
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"go53/config"
+	"go53/zone"
+	"go53/zonemeta"
+)
+
+func setupNotifyTrackingTest(t *testing.T, zoneName string, port int) {
+	t.Helper()
+	setupCatalogTestStore(t, "primary")
+	live := config.AppConfig.LiveForTest()
+	live.Secondary.CatalogEnabled = false
+	live.Primary.NotifyRetries = 3
+	live.Primary.NotifyRetryBaseMs = 10
+	live.Primary.NotifyRetryMaxMs = 20
+	live.Primary.PropagationPollSec = 0
+	trackingMu.Lock()
+	notifyTracking = map[string]*zoneNotifyState{}
+	trackingMu.Unlock()
+
+	addTestSOA(t, zoneName)
+	settings := zonemeta.Settings{AlsoNotify: []zonemeta.Server{{IP: "127.0.0.1", Port: port}}}
+	if err := zonemeta.SaveSettings(zoneName, settings); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+}
+
+func startTrackingTestServer(t *testing.T, addr string, handler dns.HandlerFunc) {
+	t.Helper()
+	started := make(chan struct{})
+	srv := &dns.Server{Addr: addr, Net: "udp", Handler: handler}
+	srv.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	<-started
+}
+
+func TestSendNotifyRetriesUntilAcked(t *testing.T) {
+	setupNotifyTrackingTest(t, "retry.test.", 15368)
+
+	var notifies atomic.Int32
+	startTrackingTestServer(t, "127.0.0.1:15368", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Opcode == dns.OpcodeNotify && notifies.Add(1) < 3 {
+			m.Rcode = dns.RcodeRefused
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	SendNotify("retry.test.")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, err := PropagationStatus("retry.test.", false)
+		if err != nil {
+			t.Fatalf("PropagationStatus: %v", err)
+		}
+		if len(status.Targets) != 1 || status.Targets[0].Notify == nil {
+			t.Fatalf("targets = %+v", status.Targets)
+		}
+		d := status.Targets[0].Notify
+		if d.State == NotifyAcked {
+			if d.Attempts != 3 || d.LastError != "" {
+				t.Fatalf("delivery = %+v, want acked on the third attempt", d)
+			}
+			break
+		}
+		if d.State == NotifyFailed || time.Now().After(deadline) {
+			t.Fatalf("delivery not acked: %+v", d)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSendNotifyGivesUpAfterRetries(t *testing.T) {
+	setupNotifyTrackingTest(t, "giveup.test.", 15369)
+	config.AppConfig.LiveForTest().Primary.NotifyRetries = 1
+
+	startTrackingTestServer(t, "127.0.0.1:15369", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		_ = w.WriteMsg(m)
+	})
+
+	serial, _ := localZoneSerial("giveup.test.")
+	generation := startNotifyTracking("giveup.test.", serial, []notifyTarget{{Addr: "127.0.0.1:15369"}})
+	deliverNotify("giveup.test.", generation, notifyTarget{Addr: "127.0.0.1:15369"}, "")
+
+	status, _ := PropagationStatus("giveup.test.", false)
+	d := status.Targets[0].Notify
+	if d.State != NotifyFailed || d.Attempts != 2 || d.LastError == "" {
+		t.Fatalf("delivery = %+v, want failed after two attempts", d)
+	}
+}
+
+func TestPropagationStatusReportsLaggingTargets(t *testing.T) {
+	setupNotifyTrackingTest(t, "prop.test.", 15370)
+	serial, err := localZoneSerial("prop.test.")
+	if err != nil {
+		t.Fatalf("local serial: %v", err)
+	}
+
+	var mu sync.Mutex
+	served := serial - 1
+	startTrackingTestServer(t, "127.0.0.1:15370", func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		s := served
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{timerTestSOA(r.Question[0].Name, s)}
+		_ = w.WriteMsg(m)
+	})
+
+	status, err := PropagationStatus("prop.test.", false)
+	if err != nil || status.Complete || status.CheckedAt != 0 {
+		t.Fatalf("status before any check = %+v, %v", status, err)
+	}
+
+	status, _ = PropagationStatus("prop.test.", true)
+	if status.Complete || len(status.Lagging) != 1 || status.Lagging[0] != "127.0.0.1:15370" {
+		t.Fatalf("status with an old serial = %+v", status)
+	}
+	if got := status.Targets[0]; got.Serial != serial-1 || got.InSync {
+		t.Fatalf("target = %+v", got)
+	}
+
+	mu.Lock()
+	served = serial
+	mu.Unlock()
+	status, _ = PropagationStatus("prop.test.", true)
+	if !status.Complete || len(status.Lagging) != 0 || !status.Targets[0].InSync {
+		t.Fatalf("status after catching up = %+v", status)
+	}
+
+	if _, err := PropagationStatus("missing.test.", false); err == nil {
+		t.Fatalf("PropagationStatus of an unknown zone succeeded")
+	}
+}
+
+func TestPropagationTargetsIncludeNameServers(t *testing.T) {
+	setupNotifyTrackingTest(t, "ns.test.", 15371)
+	if err := zone.AddRecord(dns.TypeNS, "ns.test.", "@", map[string]interface{}{"ns": "ns1.ns.test."}, ptrUint32(3600)); err != nil {
+		t.Fatalf("add NS: %v", err)
+	}
+	if err := zone.AddRecord(dns.TypeA, "ns.test.", "ns1", map[string]interface{}{"ip": "127.0.0.1"}, ptrUint32(300)); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+
+	targets := propagationTargets("ns.test.")
+	if len(targets) != 2 {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if targets[0].Address != "127.0.0.1:15371" || targets[0].Sources[0] != PropagationSourceNotify {
+		t.Fatalf("notify target = %+v", targets[0])
+	}
+	if targets[1].Address != "127.0.0.1:53" || targets[1].NSName != "ns1.ns.test." || targets[1].Sources[0] != PropagationSourceNS {
+		t.Fatalf("NS target = %+v", targets[1])
+	}
+}
@@ -0,0 +1,381 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: parent_ds.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/distributed"
+	"go53/internal"
+	"go53/security"
+	"go53/types"
+	"go53/zone"
+)
+
+// ParentDSRecord is a DS record, as the parent publishes it or as the zone
+// expects it.
+type ParentDSRecord struct {
+	KeyTag     uint16 `json:"keytag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digestType"`
+	Digest     string `json:"digest"`
+}
+
+// ParentDSKeyStatus is a published KSK and whether the parent holds its DS.
+// SeenAt is when the parent was first seen with it and SettledAt when every
+// validator holds a DS RRset with it.
+type ParentDSKeyStatus struct {
+	KeyID     string `json:"key_id"`
+	KeyTag    uint16 `json:"keytag"`
+	Algorithm uint8  `json:"algorithm"`
+	State     string `json:"state"`
+	AtParent  bool   `json:"at_parent"`
+	SeenAt    int64  `json:"seen_at,omitempty"`
+	SettledAt int64  `json:"settled_at,omitempty"`
+}
+
+// ParentDSStatus is the last check of the DS RRset the parent publishes for
+// a zone against the DS of its signing KSKs. InSync is true when they are the
+// same keys; CDSPublished is false once the CDS and CDNSKEY RRsets have been
+// withdrawn.
+type ParentDSStatus struct {
+	Zone         string              `json:"zone"`
+	Enabled      bool                `json:"enabled"`
+	CheckedAt    int64               `json:"checked_at,omitempty"`
+	Resolver     string              `json:"resolver,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	TTL          uint32              `json:"ttl,omitempty"`
+	Parent       []ParentDSRecord    `json:"parent"`
+	Missing      []ParentDSRecord    `json:"missing"`
+	Unexpected   []ParentDSRecord    `json:"unexpected"`
+	Keys         []ParentDSKeyStatus `json:"keys"`
+	InSync       bool                `json:"in_sync"`
+	CDSPublished bool                `json:"cds_published"`
+}
+
+// parentDSObservation is what this node last saw of a key's DS at the
+// parent and since when.
+type parentDSObservation struct {
+	present bool
+	since   int64
+}
+
+type parentDSZoneState struct {
+	status   ParentDSStatus
+	observed map[string]parentDSObservation // by key ID
+}
+
+var (
+	parentDSMu    sync.Mutex
+	parentDSZones = map[string]*parentDSZoneState{}
+)
+
+// parentDSExchange sends one DS query to a resolver.
+var parentDSExchange = func(m *dns.Msg, server string, timeout time.Duration) (*dns.Msg, error) {
+	c := &dns.Client{Net: "udp", Timeout: timeout}
+	resp, _, err := c.Exchange(m, server)
+	if err == nil && resp.Truncated {
+		c.Net = "tcp"
+		resp, _, err = c.Exchange(m, server)
+	}
+	return resp, err
+}
+
+// StartParentDSMonitor checks the parent DS of every signed zone each
+// dnssec.parent_ds.interval_sec until ctx is done. Nothing is queried while
+// dnssec.parent_ds.resolvers is empty.
+func StartParentDSMonitor(ctx context.Context) {
+	go func() {
+		for {
+			interval := time.Duration(config.AppConfig.GetLive().DNSSEC.ParentDS.IntervalSec) * time.Second
+			if interval <= 0 {
+				interval = 5 * time.Minute
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+				runParentDSOnce()
+			}
+		}
+	}()
+}
+
+func runParentDSOnce() {
+	if !security.ParentDSChecked() {
+		return
+	}
+	for _, name := range signedZones() {
+		if _, err := CheckParentDS(name); err != nil {
+			log.Printf("[parent-ds] %s: %v", name, err)
+		}
+	}
+}
+
+// queryParentDS asks the resolvers for the DS RRset of zoneName, trying them
+// in order. A name the parent does not delegate has no DS. Only answers the
+// resolver validated, with the AD bit set, are taken: an unvalidated answer
+// could complete a KSK rollover on a forged DS.
+func queryParentDS(zoneName string) ([]*dns.DS, uint32, string, error) {
+	cfg := config.AppConfig.GetLive().DNSSEC.ParentDS
+	if len(cfg.Resolvers) == 0 {
+		return nil, 0, "", fmt.Errorf("dnssec.parent_ds.resolvers is empty")
+	}
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(zoneName, dns.TypeDS)
+	m.RecursionDesired = true
+	m.AuthenticatedData = true
+	m.SetEdns0(1232, true)
+
+	var lastErr error
+	for _, server := range cfg.Resolvers {
+		resp, err := parentDSExchange(m, server, timeout)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", server, err)
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+			lastErr = fmt.Errorf("%s answered %s", server, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		if !resp.AuthenticatedData {
+			lastErr = fmt.Errorf("%s did not validate the answer", server)
+			continue
+		}
+		var out []*dns.DS
+		ttl := uint32(math.MaxUint32)
+		for _, rr := range resp.Answer {
+			if ds, ok := rr.(*dns.DS); ok && strings.EqualFold(ds.Hdr.Name, zoneName) {
+				out = append(out, ds)
+				ttl = min(ttl, ds.Hdr.Ttl)
+			}
+		}
+		if len(out) == 0 {
+			ttl = 0
+		}
+		return out, ttl, server, nil
+	}
+	return nil, 0, "", fmt.Errorf("query DS %s: %w", zoneName, lastErr)
+}
+
+// CheckParentDS queries the parent DS of zoneName and records on each KSK
+// whether the parent publishes its DS. In distributed mode the owner of the
+// zone records at once; other nodes only what they have seen unchanged for
+// dnssec.kasp_takeover_sec. A change re-signs the zone, as the CDS and
+// CDNSKEY RRsets follow it.
+func CheckParentDS(zoneName string) (ParentDSStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return ParentDSStatus{}, err
+	}
+	now := kaspNow().Unix()
+	keys, err := security.ParentDSKeys(fqdn, now)
+	if err != nil {
+		return ParentDSStatus{}, err
+	}
+	parent, ttl, resolver, queryErr := queryParentDS(fqdn)
+
+	parentDSMu.Lock()
+	state := parentDSZones[fqdn]
+	if state == nil {
+		state = &parentDSZoneState{observed: map[string]parentDSObservation{}}
+		parentDSZones[fqdn] = state
+	}
+	parentDSMu.Unlock()
+
+	changed := map[string]types.StoredKey{}
+	if queryErr == nil {
+		owner := distributed.OwnsZone(fqdn)
+		delay := int64(config.AppConfig.GetLive().DNSSEC.KASPTakeoverSec)
+		for i := range keys {
+			k := &keys[i]
+			present := parentHasDS(parent, k.DNSKEY)
+			parentDSMu.Lock()
+			obs, ok := state.observed[k.KeyID]
+			if !ok || obs.present != present {
+				obs = parentDSObservation{present: present, since: now}
+				state.observed[k.KeyID] = obs
+			}
+			parentDSMu.Unlock()
+			if !owner && (delay <= 0 || now < obs.since+delay) {
+				continue
+			}
+			at := int64(0)
+			if present {
+				at = obs.since
+			}
+			updated, didChange, err := security.SetParentDSSeen(k.KeyID, at, ttl)
+			if err != nil {
+				return ParentDSStatus{}, err
+			}
+			if didChange {
+				k.Key = updated
+				changed[k.KeyID] = updated
+			}
+		}
+	}
+	if len(changed) > 0 {
+		err = publishParentDSChanges(fqdn, changed)
+	}
+
+	status := parentDSStatus(fqdn, keys, parent, now)
+	status.CheckedAt = now
+	status.Resolver = resolver
+	if queryErr != nil {
+		status.Error = queryErr.Error()
+		status.InSync = false
+	} else {
+		status.TTL = ttl
+	}
+	parentDSMu.Lock()
+	state.status = status
+	parentDSMu.Unlock()
+	return status, err
+}
+
+func publishParentDSChanges(zoneName string, changed map[string]types.StoredKey) error {
+	var err error
+	if refreshErr := zone.RefreshDNSSECKeyMaterial(zoneName); refreshErr != nil {
+		err = refreshErr
+	}
+	ids := make([]string, 0, len(changed))
+	for id := range changed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if at := changed[id].ParentDSAt; at != 0 {
+			log.Printf("[parent-ds] %s: parent publishes the DS of %s since %d", zoneName, id, at)
+		} else {
+			log.Printf("[parent-ds] %s: parent no longer publishes the DS of %s", zoneName, id)
+		}
+		if pubErr := distributed.Default.PublishDNSSECKey(id, changed[id]); pubErr != nil && err == nil {
+			err = pubErr
+		}
+	}
+	return err
+}
+
+func parentHasDS(parent []*dns.DS, key *dns.DNSKEY) bool {
+	for _, ds := range parent {
+		if security.DSMatchesDNSKEY(ds, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// parentDSStatus compares parent with the DS the zone expects. parent is nil
+// when it was not queried.
+func parentDSStatus(fqdn string, keys []security.ParentDSKey, parent []*dns.DS, now int64) ParentDSStatus {
+	status := ParentDSStatus{
+		Zone:         fqdn,
+		Enabled:      security.ParentDSChecked(),
+		Parent:       []ParentDSRecord{},
+		Missing:      []ParentDSRecord{},
+		Unexpected:   []ParentDSRecord{},
+		Keys:         []ParentDSKeyStatus{},
+		CDSPublished: !security.CDSWithdrawn(fqdn, now),
+	}
+	_, policy, _ := ZoneKASPPolicy(fqdn)
+	for i := range keys {
+		k := &keys[i]
+		status.Keys = append(status.Keys, ParentDSKeyStatus{
+			KeyID:     k.KeyID,
+			KeyTag:    k.DNSKEY.KeyTag(),
+			Algorithm: k.DNSKEY.Algorithm,
+			State:     k.Key.State,
+			AtParent:  parentHasDS(parent, k.DNSKEY),
+			SeenAt:    k.Key.ParentDSAt,
+			SettledAt: security.ParentDSSettledAt(&k.Key, int64(policy.ParentDSTTLSec)),
+		})
+	}
+	for _, ds := range parent {
+		status.Parent = append(status.Parent, parentDSRecord(ds))
+		known := false
+		for i := range keys {
+			if security.DSMatchesDNSKEY(ds, keys[i].DNSKEY) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			status.Unexpected = append(status.Unexpected, parentDSRecord(ds))
+		}
+	}
+	expected, _ := security.GetDS(fqdn)
+	for _, ds := range expected {
+		for _, k := range status.Keys {
+			if k.KeyTag == ds.KeyTag && k.Algorithm == ds.Algorithm && !k.AtParent {
+				status.Missing = append(status.Missing, parentDSRecord(ds))
+			}
+		}
+	}
+	status.InSync = len(expected) > 0 && len(status.Missing) == 0 && len(status.Unexpected) == 0
+	return status
+}
+
+func parentDSRecord(ds *dns.DS) ParentDSRecord {
+	return ParentDSRecord{KeyTag: ds.KeyTag, Algorithm: ds.Algorithm, DigestType: ds.DigestType, Digest: strings.ToUpper(ds.Digest)}
+}
+
+// ParentDSStatusOf returns the last parent DS check of zoneName, or checks
+// now when refresh is set. Before the first check only the recorded state of
+// the keys is known.
+func ParentDSStatusOf(zoneName string, refresh bool) (ParentDSStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return ParentDSStatus{}, err
+	}
+	if refresh {
+		return CheckParentDS(fqdn)
+	}
+	parentDSMu.Lock()
+	state := parentDSZones[fqdn]
+	var last ParentDSStatus
+	if state != nil {
+		last = state.status
+	}
+	parentDSMu.Unlock()
+	if state != nil {
+		return last, nil
+	}
+	now := kaspNow().Unix()
+	keys, err := security.ParentDSKeys(fqdn, now)
+	if err != nil {
+		return ParentDSStatus{}, err
+	}
+	status := parentDSStatus(fqdn, keys, nil, now)
+	status.InSync = false
+	status.Missing = []ParentDSRecord{}
+	for i := range status.Keys {
+		status.Keys[i].AtParent = status.Keys[i].SeenAt != 0
+	}
+	return status, nil
+}
@@ -0,0 +1,137 @@
+package dnsutils
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"go53/config"
+	"go53/security"
+)
+
+func TestCheckParentDSRecordsDSAndWithdrawsCDS(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.Resolvers = []string{"127.0.0.1:15372"}
+	addTestSOA(t, "child.test.")
+	now := time.Now().Unix()
+	kskID, _, err := security.GenerateRolloverKey("child.test", security.KeyRoleKSK, "ECDSAP256SHA256", now-10, now-10)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	expected, err := security.GetDS("child.test.")
+	if err != nil || len(expected) != 1 {
+		t.Fatalf("GetDS = %v, %v", expected, err)
+	}
+
+	var mu sync.Mutex
+	var served []dns.RR
+	startTrackingTestServer(t, "127.0.0.1:15372", func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		answer := served
+		mu.Unlock()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.AuthenticatedData = true
+		m.Answer = answer
+		_ = w.WriteMsg(m)
+	})
+	serve := func(rrs ...dns.RR) {
+		mu.Lock()
+		served = rrs
+		mu.Unlock()
+	}
+
+	status, err := CheckParentDS("child.test.")
+	if err != nil {
+		t.Fatalf("CheckParentDS: %v", err)
+	}
+	if status.Error != "" || status.InSync || len(status.Missing) != 1 || !status.CDSPublished || status.Keys[0].AtParent {
+		t.Fatalf("status without DS at the parent = %+v", status)
+	}
+
+	ds := dns.Copy(expected[0]).(*dns.DS)
+	ds.Hdr.Ttl = 600
+	serve(ds)
+	status, err = CheckParentDS("child.test.")
+	if err != nil {
+		t.Fatalf("CheckParentDS: %v", err)
+	}
+	if !status.InSync || status.TTL != 600 || status.CDSPublished || !status.Keys[0].AtParent || status.Keys[0].SeenAt == 0 {
+		t.Fatalf("status with the DS at the parent = %+v", status)
+	}
+	if key, _ := security.LoadStoredKey(kskID); key.ParentDSAt == 0 || key.ParentDSTTL != 600 {
+		t.Fatalf("stored key = %+v", key)
+	}
+
+	stale := &dns.DS{Hdr: dns.RR_Header{Name: "child.test.", Rrtype: dns.TypeDS, Class: dns.ClassINET, Ttl: 600}, KeyTag: 1, Algorithm: 13, DigestType: 2, Digest: "AB"}
+	serve(ds, stale)
+	if status, _ = CheckParentDS("child.test."); status.InSync || len(status.Unexpected) != 1 || status.Unexpected[0].KeyTag != 1 {
+		t.Fatalf("status with a stale DS = %+v", status)
+	}
+	if last, _ := ParentDSStatusOf("child.test.", false); last.CheckedAt != status.CheckedAt || len(last.Unexpected) != 1 {
+		t.Fatalf("ParentDSStatusOf = %+v", last)
+	}
+
+	serve()
+	if status, _ = CheckParentDS("child.test."); !status.CDSPublished || status.Keys[0].SeenAt != 0 {
+		t.Fatalf("status after the DS left the parent = %+v", status)
+	}
+}
+
+func TestCheckParentDSReportsResolverErrors(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.Resolvers = []string{"127.0.0.1:15373"}
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.TimeoutMs = 200
+	addTestSOA(t, "broken.test.")
+	now := time.Now().Unix()
+	if _, _, err := security.GenerateRolloverKey("broken.test", security.KeyRoleKSK, "ECDSAP256SHA256", now-10, now-10); err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	startTrackingTestServer(t, "127.0.0.1:15373", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		_ = w.WriteMsg(m)
+	})
+
+	status, err := CheckParentDS("broken.test.")
+	if err != nil {
+		t.Fatalf("CheckParentDS: %v", err)
+	}
+	if status.Error == "" || status.InSync || !status.CDSPublished {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestCheckParentDSIgnoresUnvalidatedAnswers(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.Resolvers = []string{"127.0.0.1:15374"}
+	addTestSOA(t, "forged.test.")
+	now := time.Now().Unix()
+	kskID, _, err := security.GenerateRolloverKey("forged.test", security.KeyRoleKSK, "ECDSAP256SHA256", now-10, now-10)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	expected, err := security.GetDS("forged.test.")
+	if err != nil || len(expected) != 1 {
+		t.Fatalf("GetDS = %v, %v", expected, err)
+	}
+	startTrackingTestServer(t, "127.0.0.1:15374", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{expected[0]}
+		_ = w.WriteMsg(m)
+	})
+
+	status, err := CheckParentDS("forged.test.")
+	if err != nil {
+		t.Fatalf("CheckParentDS: %v", err)
+	}
+	if status.Error == "" || status.InSync || !status.CDSPublished {
+		t.Fatalf("status = %+v", status)
+	}
+	if key, _ := security.LoadStoredKey(kskID); key.ParentDSAt != 0 {
+		t.Fatalf("stored key = %+v", key)
+	}
+}
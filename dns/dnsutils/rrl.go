@@ -0,0 +1,356 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: rrl.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TenforwardAB/slog"
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+// RRLAction is what Response Rate Limiting decided to do with a response.
+type RRLAction int
+
+const (
+	// RRLSend sends the response unchanged.
+	RRLSend RRLAction = iota
+	// RRLDrop sends nothing.
+	RRLDrop
+	// RRLSlip sends an empty truncated (TC=1) response instead, so a genuine
+	// client retries over TCP while a reflection victim gets nothing larger
+	// than the query.
+	RRLSlip
+)
+
+// Response classes counted in separate buckets.
+const (
+	rrlClassAnswer   = "answer"
+	rrlClassNXDomain = "nxdomain"
+	rrlClassReferral = "referral"
+	rrlClassError    = "error"
+)
+
+// maxRRLBuckets bounds the bucket table. When it is full, idle buckets are
+// reclaimed first; responses that still find no room are sent unlimited and
+// counted as table_full.
+const maxRRLBuckets = 200_000
+
+type rrlBucket struct {
+	tokens  float64
+	last    time.Time
+	limited uint64 // responses limited since the bucket last had credit
+}
+
+// RRLClassStats counts responses of one class.
+type RRLClassStats struct {
+	Responses uint64 `json:"responses"`
+	Limited   uint64 `json:"limited"`
+}
+
+// RRLStats is the counter snapshot exposed through the API.
+type RRLStats struct {
+	Enabled   bool                     `json:"enabled"`
+	LogOnly   bool                     `json:"log_only"`
+	Responses uint64                   `json:"responses"`
+	Exempt    uint64                   `json:"exempt"`
+	Cookie    uint64                   `json:"cookie"` // responses to queries with a valid server cookie
+	Dropped   uint64                   `json:"dropped"`
+	Slipped   uint64                   `json:"slipped"`
+	Logged    uint64                   `json:"logged"` // would have been limited in log-only mode
+	TableFull uint64                   `json:"table_full"`
+	Buckets   int                      `json:"buckets"`
+	Classes   map[string]RRLClassStats `json:"classes"`
+}
+
+type responseRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rrlBucket
+	stats   RRLStats
+
+	exemptKey string
+	exempt    []*net.IPNet
+}
+
+var rrl = newResponseRateLimiter()
+
+func newResponseRateLimiter() *responseRateLimiter {
+	return &responseRateLimiter{
+		buckets: make(map[string]*rrlBucket),
+		stats:   RRLStats{Classes: map[string]RRLClassStats{}},
+	}
+}
+
+// RateLimitResponse applies Response Rate Limiting to a UDP response about to
+// be sent to client. Callers skip it for TCP and other stream transports, where
+// the client address cannot be spoofed. A query with a valid server cookie
+// proves the address the same way, so rrl.valid_cookie can exempt such clients
+// or give them separate, more generous buckets.
+func RateLimitResponse(client net.IP, req, resp *dns.Msg, validCookie bool, now time.Time) RRLAction {
+	cfg := config.AppConfig.GetLive().RRL
+	if !cfg.Enabled || client == nil || req == nil || resp == nil {
+		return RRLSend
+	}
+	return rrl.check(cfg, client, req, resp, validCookie, now)
+}
+
+// RRLStatsSnapshot returns the current Response Rate Limiting counters.
+func RRLStatsSnapshot() RRLStats {
+	cfg := config.AppConfig.GetLive().RRL
+	rrl.mu.Lock()
+	defer rrl.mu.Unlock()
+	out := rrl.stats
+	out.Enabled = cfg.Enabled
+	out.LogOnly = cfg.LogOnly
+	out.Buckets = len(rrl.buckets)
+	out.Classes = make(map[string]RRLClassStats, len(rrl.stats.Classes))
+	for class, stats := range rrl.stats.Classes {
+		out.Classes[class] = stats
+	}
+	return out
+}
+
+// SweepRRL drops buckets that have been idle long enough to be back at full
+// credit, keeping the table small between floods.
+func SweepRRL(now time.Time) {
+	rrl.sweep(now, rrlIdle(config.AppConfig.GetLive().RRL))
+}
+
+func (l *responseRateLimiter) check(cfg config.RRLConfig, client net.IP, req, resp *dns.Msg, validCookie bool, now time.Time) RRLAction {
+	class, identity := rrlResponseIdentity(req, resp)
+	rate := rrlRate(cfg, class)
+	policy := strings.ToLower(cfg.ValidCookie)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stats.Responses++
+	classStats := l.stats.Classes[class]
+	classStats.Responses++
+	l.stats.Classes[class] = classStats
+
+	if rate <= 0 {
+		return RRLSend
+	}
+	if l.isExempt(cfg.Exempt, client) {
+		l.stats.Exempt++
+		return RRLSend
+	}
+
+	block := rrlNetblock(client, cfg)
+	key := block + "|" + class + "|" + identity
+	if validCookie {
+		switch policy {
+		case "exempt", "":
+			l.stats.Cookie++
+			return RRLSend
+		case "relax":
+			l.stats.Cookie++
+			if cfg.ValidCookieFactor > 1 {
+				rate *= cfg.ValidCookieFactor
+			}
+			// Cookie clients get their own buckets so spoofed traffic in the
+			// same netblock cannot exhaust their credit.
+			key += "|cookie"
+		}
+	}
+	b := l.buckets[key]
+	if b == nil {
+		if len(l.buckets) >= maxRRLBuckets {
+			l.sweepLocked(now, rrlIdle(cfg))
+		}
+		if len(l.buckets) >= maxRRLBuckets {
+			l.stats.TableFull++
+			return RRLSend
+		}
+		b = &rrlBucket{tokens: float64(rate), last: now}
+		l.buckets[key] = b
+	}
+
+	// Credit refills at rate per second up to one second of burst. A flooded
+	// bucket may go into debt for up to window seconds of traffic, so it stays
+	// limited for a while after the flood stops instead of flapping.
+	window := cfg.Window
+	if window <= 0 {
+		window = 1
+	}
+	b.tokens += now.Sub(b.last).Seconds() * float64(rate)
+	if b.tokens > float64(rate) {
+		b.tokens = float64(rate)
+	}
+	b.last = now
+	b.tokens--
+	if floor := -float64(rate * window); b.tokens < floor {
+		b.tokens = floor
+	}
+	if b.tokens >= 0 {
+		b.limited = 0
+		return RRLSend
+	}
+
+	b.limited++
+	classStats.Limited++
+	l.stats.Classes[class] = classStats
+	if b.limited == 1 {
+		slog.Info("RRL: limiting %s responses to %s for %q (%d/s)", class, block, identity, rate)
+	}
+	if cfg.LogOnly {
+		l.stats.Logged++
+		return RRLSend
+	}
+	if cfg.Slip > 0 && b.limited%uint64(cfg.Slip) == 0 {
+		l.stats.Slipped++
+		return RRLSlip
+	}
+	l.stats.Dropped++
+	return RRLDrop
+}
+
+func (l *responseRateLimiter) sweep(now time.Time, idle time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweepLocked(now, idle)
+}
+
+func (l *responseRateLimiter) sweepLocked(now time.Time, idle time.Duration) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// isExempt reports whether client is on the exempt list. The parsed list is
+// cached until the configured entries change.
+func (l *responseRateLimiter) isExempt(entries []string, client net.IP) bool {
+	if len(entries) == 0 {
+		return false
+	}
+	key := strings.Join(entries, ",")
+	if key != l.exemptKey {
+		l.exemptKey = key
+		l.exempt = parseRRLExempt(entries)
+	}
+	for _, n := range l.exempt {
+		if n.Contains(client) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRRLExempt(entries []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, n)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		slog.Warn("RRL: ignoring invalid exempt entry %q", entry)
+	}
+	return nets
+}
+
+// rrlResponseIdentity classifies resp and names what it is about. Errors from
+// one netblock share a single bucket, as in BIND, so varying the query name
+// cannot dodge the limit.
+func rrlResponseIdentity(req, resp *dns.Msg) (string, string) {
+	qname := ""
+	qtype := ""
+	if len(req.Question) > 0 {
+		qname = strings.ToLower(req.Question[0].Name)
+		qtype = dns.TypeToString[req.Question[0].Qtype]
+	}
+
+	switch resp.Rcode {
+	case dns.RcodeSuccess:
+		if len(resp.Answer) == 0 {
+			for _, rr := range resp.Ns {
+				if rr.Header().Rrtype == dns.TypeNS {
+					return rrlClassReferral, strings.ToLower(rr.Header().Name)
+				}
+			}
+		}
+		return rrlClassAnswer, qname + "/" + qtype
+	case dns.RcodeNameError:
+		for _, rr := range resp.Ns {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				return rrlClassNXDomain, strings.ToLower(rr.Header().Name)
+			}
+		}
+		return rrlClassNXDomain, qname
+	default:
+		return rrlClassError, ""
+	}
+}
+
+func rrlRate(cfg config.RRLConfig, class string) int {
+	switch class {
+	case rrlClassNXDomain:
+		return cfg.NXDomainsPerSecond
+	case rrlClassReferral:
+		return cfg.ReferralsPerSecond
+	case rrlClassError:
+		return cfg.ErrorsPerSecond
+	default:
+		return cfg.ResponsesPerSecond
+	}
+}
+
+// rrlNetblock masks client to the configured prefix length so every address
+// in a block shares buckets.
+func rrlNetblock(client net.IP, cfg config.RRLConfig) string {
+	if v4 := client.To4(); v4 != nil {
+		bits := clampPrefix(cfg.IPv4PrefixLength, 32)
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(bits, 32)), Mask: net.CIDRMask(bits, 32)}).String()
+	}
+	bits := clampPrefix(cfg.IPv6PrefixLength, 128)
+	return (&net.IPNet{IP: client.Mask(net.CIDRMask(bits, 128)), Mask: net.CIDRMask(bits, 128)}).String()
+}
+
+func clampPrefix(bits, max int) int {
+	if bits <= 0 || bits > max {
+		return max
+	}
+	return bits
+}
+
+// rrlIdle is how long an untouched bucket needs to pay off the deepest debt
+// and refill, after which it can be forgotten.
+func rrlIdle(cfg config.RRLConfig) time.Duration {
+	window := cfg.Window
+	if window <= 0 {
+		window = 1
+	}
+	return time.Duration(window+1) * time.Second
+}
@@ -0,0 +1,170 @@
+package dnsutils
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+func rrlTestConfig() config.RRLConfig {
+	cfg := config.DefaultLiveConfig.RRL
+	cfg.Enabled = true
+	cfg.ResponsesPerSecond = 2
+	cfg.NXDomainsPerSecond = 2
+	cfg.ErrorsPerSecond = 2
+	cfg.Window = 2
+	cfg.Slip = 2
+	return cfg
+}
+
+func rrlTestExchange(t *testing.T, qname string, rcode int) (*dns.Msg, *dns.Msg) {
+	t.Helper()
+	req := new(dns.Msg)
+	req.SetQuestion(qname, dns.TypeA)
+	resp := new(dns.Msg)
+	resp.SetRcode(req, rcode)
+	if rcode == dns.RcodeNameError {
+		soa, err := dns.NewRR("rrl.test. 300 IN SOA ns1.rrl.test. hostmaster.rrl.test. 1 3600 900 86400 300")
+		if err != nil {
+			t.Fatalf("SOA: %v", err)
+		}
+		resp.Ns = []dns.RR{soa}
+	}
+	return req, resp
+}
+
+func TestRRLLimitsAndSlips(t *testing.T) {
+	l := newResponseRateLimiter()
+	cfg := rrlTestConfig()
+	now := time.Unix(1000, 0)
+	client := net.ParseIP("192.0.2.10")
+	req, resp := rrlTestExchange(t, "www.rrl.test.", dns.RcodeSuccess)
+
+	var got []RRLAction
+	for i := 0; i < 6; i++ {
+		got = append(got, l.check(cfg, client, req, resp, false, now))
+	}
+	want := []RRLAction{RRLSend, RRLSend, RRLDrop, RRLSlip, RRLDrop, RRLSlip}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("actions = %v, want %v", got, want)
+		}
+	}
+
+	// Another address in the same /24 shares the bucket; another /24 does not.
+	if a := l.check(cfg, net.ParseIP("192.0.2.99"), req, resp, false, now); a == RRLSend {
+		t.Fatalf("same netblock was not limited")
+	}
+	if a := l.check(cfg, net.ParseIP("198.51.100.1"), req, resp, false, now); a != RRLSend {
+		t.Fatalf("other netblock was limited: %v", a)
+	}
+
+	// The debt is capped at window seconds, so the bucket recovers after that.
+	if a := l.check(cfg, client, req, resp, false, now.Add(3*time.Second)); a != RRLSend {
+		t.Fatalf("bucket still limited after the window: %v", a)
+	}
+
+	stats := l.stats
+	if stats.Dropped == 0 || stats.Slipped == 0 || stats.Classes[rrlClassAnswer].Limited == 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRRLGroupsNXDOMAINByZoneAndErrorsByClient(t *testing.T) {
+	l := newResponseRateLimiter()
+	cfg := rrlTestConfig()
+	cfg.Slip = 0
+	now := time.Unix(1000, 0)
+	client := net.ParseIP("2001:db8:1:2::53")
+
+	// Random subdomains of one zone share the zone's NXDOMAIN bucket.
+	for i, name := range []string{"a.rrl.test.", "b.rrl.test.", "c.rrl.test."} {
+		req, resp := rrlTestExchange(t, name, dns.RcodeNameError)
+		a := l.check(cfg, client, req, resp, false, now)
+		if (i < 2 && a != RRLSend) || (i == 2 && a != RRLDrop) {
+			t.Fatalf("NXDOMAIN %d for %s = %v", i, name, a)
+		}
+	}
+
+	// Errors for unrelated names share one bucket per client netblock, and a
+	// client in the same /56 counts against it too.
+	for i, name := range []string{"x.example.", "y.example."} {
+		req, resp := rrlTestExchange(t, name, dns.RcodeRefused)
+		if a := l.check(cfg, client, req, resp, false, now); a != RRLSend {
+			t.Fatalf("error %d = %v", i, a)
+		}
+	}
+	req, resp := rrlTestExchange(t, "z.example.", dns.RcodeRefused)
+	if a := l.check(cfg, net.ParseIP("2001:db8:1:2a::1"), req, resp, false, now); a != RRLDrop {
+		t.Fatalf("third error from the same /56 = %v, want drop", a)
+	}
+}
+
+func TestRRLExemptAndLogOnly(t *testing.T) {
+	l := newResponseRateLimiter()
+	cfg := rrlTestConfig()
+	cfg.Exempt = []string{"192.0.2.0/28", "203.0.113.7"}
+	now := time.Unix(1000, 0)
+	req, resp := rrlTestExchange(t, "www.rrl.test.", dns.RcodeSuccess)
+
+	for _, ip := range []string{"192.0.2.5", "203.0.113.7"} {
+		for i := 0; i < 5; i++ {
+			if a := l.check(cfg, net.ParseIP(ip), req, resp, false, now); a != RRLSend {
+				t.Fatalf("exempt client %s limited: %v", ip, a)
+			}
+		}
+	}
+
+	cfg.LogOnly = true
+	client := net.ParseIP("198.51.100.8")
+	for i := 0; i < 5; i++ {
+		if a := l.check(cfg, client, req, resp, false, now); a != RRLSend {
+			t.Fatalf("log-only mode limited a response: %v", a)
+		}
+	}
+	if l.stats.Exempt != 10 || l.stats.Logged != 3 || l.stats.Dropped != 0 {
+		t.Fatalf("stats = %+v", l.stats)
+	}
+}
+
+func TestRRLValidCookiePolicy(t *testing.T) {
+	l := newResponseRateLimiter()
+	cfg := rrlTestConfig()
+	cfg.Slip = 0
+	now := time.Unix(1000, 0)
+	client := net.ParseIP("192.0.2.20")
+	req, resp := rrlTestExchange(t, "www.rrl.test.", dns.RcodeSuccess)
+
+	for i := 0; i < 5; i++ {
+		if a := l.check(cfg, client, req, resp, true, now); a != RRLSend {
+			t.Fatalf("exempt cookie client limited: %v", a)
+		}
+	}
+
+	// Relaxed: cookie clients get factor times the rate in their own buckets,
+	// untouched by spoofed traffic that has drained the netblock's bucket.
+	cfg.ValidCookie = "relax"
+	cfg.ValidCookieFactor = 3
+	for i := 0; i < 3; i++ {
+		l.check(cfg, client, req, resp, false, now)
+	}
+	for i := 0; i < 6; i++ {
+		if a := l.check(cfg, client, req, resp, true, now); a != RRLSend {
+			t.Fatalf("relaxed cookie response %d limited: %v", i, a)
+		}
+	}
+	if a := l.check(cfg, client, req, resp, true, now); a != RRLDrop {
+		t.Fatalf("relaxed cookie response beyond factor*rate = %v, want drop", a)
+	}
+
+	cfg.ValidCookie = "none"
+	if a := l.check(cfg, net.ParseIP("192.0.2.21"), req, resp, true, now); a != RRLDrop {
+		t.Fatalf("cookie client with policy none = %v, want drop", a)
+	}
+	if l.stats.Cookie != 12 {
+		t.Fatalf("cookie responses = %d, want 12", l.stats.Cookie)
+	}
+}
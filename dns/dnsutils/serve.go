@@ -22,11 +22,13 @@ import (
 	"go53/config"
 	"go53/zone"
 	"log"
+	"net"
 	"time"
 )
 
-// ServeDNS handles incoming DNS requests and responds with AXFR (full zone transfer) data
-// if the request is valid. It ensures the query is for a zone transfer (AXFR or IXFR),
+// ServeDNS handles incoming DNS requests and responds with AXFR (full zone transfer) data,
+// or with RFC 1995 incremental differences from the zone journal for IXFR, if the request
+// is valid. It ensures the query is for a zone transfer (AXFR or IXFR),
 // retrieves the corresponding zone data from memory, and streams the response in chunks
 // that do not exceed the DNS message size limit.
 //
@@ -34,6 +36,8 @@ import (
 //   - Validates that the DNS request is non-nil and contains exactly one question.
 //   - Verifies that the question type is either AXFR or IXFR.
 //   - Retrieves the corresponding zone records from in-memory storage using `zone.LookupRecord`.
+//   - For IXFR, answers from the journal when it covers the client's serial and the
+//     differences are smaller than the zone; otherwise falls back to AXFR.
 //   - Packs and sends records in DNS messages that do not exceed 61 KiB in size.
 //   - Sends the final message if any records remain after the last chunk.
 //   - Responds with SERVFAIL if the request is invalid or an error occurs.
@@ -44,9 +48,16 @@ import (
 //
 // This function is intended for use in a DNS server supporting zone transfers.
 func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ServeDNSFrom(w, req, zone.Default)
+}
+
+// ServeDNSFrom is ServeDNS for the zones of a split-horizon view. The IXFR
+// journal only records the default zones, so IXFR of a view zone is always
+// answered with the full zone.
+func ServeDNSFrom(w dns.ResponseWriter, req *dns.Msg, zones zone.Scope) {
 	if req == nil || len(req.Question) != 1 {
 		log.Println("req.Question error:", req)
-		respondWithFailure(w, req)
+		respondWithFailure(w, req, dns.ExtendedErrorCodeOther, "zone transfer needs exactly one question")
 		return
 	}
 
@@ -54,26 +65,24 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	//TODO: Do we need this extra check?
 	if !(q.Qtype == dns.TypeAXFR || q.Qtype == dns.TypeIXFR) {
 		log.Println("Q.type is not AXFR or IXFR:", q.Qtype)
-		respondWithFailure(w, req)
+		respondWithFailure(w, req, dns.ExtendedErrorCodeNotSupported, "not a zone transfer")
 		return
 	}
 
 	log.Println("Fetching zone from memory:", q.Name)
-	rrs, ok := zone.LookupRecord(dns.TypeAXFR, q.Name)
+	rrs, ok := zones.LookupRecord(dns.TypeAXFR, q.Name)
 	if !ok || len(rrs) < 2 {
 		log.Println("zone not found or too few records")
-		respondWithFailure(w, req)
+		respondWithFailure(w, req, dns.ExtendedErrorCodeNotAuthoritative, "no zone "+q.Name+" to transfer")
 		return
 	}
 	currentSOA, ok := firstSOA(rrs)
 	if !ok {
 		log.Println("zone transfer data missing SOA")
-		respondWithFailure(w, req)
+		respondWithFailure(w, req, dns.ExtendedErrorCodeNotReady, "zone "+q.Name+" has no SOA")
 		return
 	}
 
-	const maxSize = 61 * 1024
-
 	tsigKey := ""
 	if req.IsTsig() != nil {
 		if w.TsigStatus() == nil {
@@ -84,7 +93,7 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 
 			if config.AppConfig.GetLive().EnforceTSIG {
 				log.Printf("TSIG validation failed and EnforceTSIG is enabled: %v", w.TsigStatus())
-				respondWithFailure(w, req)
+				respondWithFailure(w, req, dns.ExtendedErrorCodeProhibited, "TSIG validation failed")
 				return
 			} else {
 				log.Printf("TSIG validation failed but EnforceTSIG is disabled: ignoring error")
@@ -92,7 +101,7 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 		}
 	} else if config.AppConfig.GetLive().EnforceTSIG {
 		log.Println("TSIG required but not present")
-		respondWithFailure(w, req)
+		respondWithFailure(w, req, dns.ExtendedErrorCodeProhibited, "TSIG required")
 		return
 	}
 
@@ -108,12 +117,41 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 			writeTransferMessage(w, req, []dns.RR{currentSOA}, tsigKey)
 			return
 		}
-		log.Printf("IXFR journal unavailable for %s: client=%d current=%d; falling back to full zone transfer", q.Name, clientSerial, currentSOA.Serial)
+		if zones.View() == "" {
+			if ixfr, ok := ixfrDifferences(q.Name, clientSerial, currentSOA, config.AppConfig.GetLive().IXFR.Condense); ok && len(ixfr) < len(rrs) {
+				log.Printf("IXFR for %s: client=%d current=%d, sending %d RRs from journal", q.Name, clientSerial, currentSOA.Serial, len(ixfr))
+				if isUDPTransfer(w) {
+					writeUDPIXFR(w, req, ixfr, currentSOA, tsigKey)
+					return
+				}
+				writeTransferRRs(w, req, ixfr, tsigKey)
+				return
+			}
+		}
+		log.Printf("IXFR journal does not cover %s: client=%d current=%d; falling back to full zone transfer", q.Name, clientSerial, currentSOA.Serial)
+		if isUDPTransfer(w) {
+			// RFC 1995 section 2: a UDP client that cannot be answered
+			// incrementally gets the current SOA and retries over TCP.
+			writeTransferMessage(w, req, []dns.RR{currentSOA}, tsigKey)
+			return
+		}
 	}
 
+	writeTransferRRs(w, req, rrs, tsigKey)
+}
+
+// writeTransferRRs streams rrs to the client in messages that stay below the
+// 64 KiB TCP message limit.
+func writeTransferRRs(w dns.ResponseWriter, req *dns.Msg, rrs []dns.RR, tsigKey string) {
+	const maxSize = 61 * 1024
+
 	msg := new(dns.Msg)
 	msg.SetReply(req)
 	msg.Answer = make([]dns.RR, 0, 10)
+	if soa, ok := firstSOA(rrs); ok {
+		// RFC 7314 section 3: the EXPIRE option rides on the first message.
+		ApplyExpire(msg, req, soa)
+	}
 
 	for _, rr := range rrs {
 		msg.Answer = append(msg.Answer, rr)
@@ -121,7 +159,7 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 		packed, err := msg.Pack()
 		if err != nil {
 			log.Printf("Pack error: %v", err)
-			respondWithFailure(w, req)
+			respondWithFailure(w, req, dns.ExtendedErrorCodeOther, "zone transfer message could not be packed")
 			return
 		}
 
@@ -133,7 +171,7 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 			}
 
 			if err := w.WriteMsg(msg); err != nil {
-				log.Printf("Failed to send AXFR chunk: %v", err)
+				log.Printf("Failed to send transfer chunk: %v", err)
 				return
 			}
 
@@ -149,11 +187,32 @@ func ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 		}
 
 		if err := w.WriteMsg(msg); err != nil {
-			log.Printf("Failed to send final AXFR packet: %v", err)
+			log.Printf("Failed to send final transfer packet: %v", err)
 		}
 	}
 }
 
+// writeUDPIXFR sends an incremental answer in a single datagram, or only the
+// current SOA when the differences do not fit so the client retries over TCP.
+func writeUDPIXFR(w dns.ResponseWriter, req *dns.Msg, ixfr []dns.RR, currentSOA *dns.SOA, tsigKey string) {
+	size := dns.MinMsgSize
+	if opt := req.IsEdns0(); opt != nil && int(opt.UDPSize()) > size {
+		size = int(opt.UDPSize())
+	}
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Answer = ixfr
+	if msg.Len() > size {
+		ixfr = []dns.RR{currentSOA}
+	}
+	writeTransferMessage(w, req, ixfr, tsigKey)
+}
+
+func isUDPTransfer(w dns.ResponseWriter) bool {
+	_, ok := w.RemoteAddr().(*net.UDPAddr)
+	return ok
+}
+
 func firstSOA(rrs []dns.RR) (*dns.SOA, bool) {
 	for _, rr := range rrs {
 		soa, ok := rr.(*dns.SOA)
@@ -182,6 +241,9 @@ func writeTransferMessage(w dns.ResponseWriter, req *dns.Msg, answer []dns.RR, t
 	msg := new(dns.Msg)
 	msg.SetReply(req)
 	msg.Answer = answer
+	if soa, ok := firstSOA(answer); ok {
+		ApplyExpire(msg, req, soa)
+	}
 	if config.AppConfig.GetLive().EnforceTSIG && tsigKey != "" {
 		msg.SetTsig(tsigKey, dns.HmacSHA256, 300, time.Now().Unix())
 	}
@@ -192,13 +254,25 @@ func writeTransferMessage(w dns.ResponseWriter, req *dns.Msg, answer []dns.RR, t
 }
 
 // respondWithFailure sends a DNS response with RcodeServerFailure (SERVFAIL) to the client.
-// It sets the Rcode in the response message appropriately, based on the incoming request.
+// It sets the Rcode in the response message appropriately, based on the incoming request,
+// and explains the failure with an Extended DNS Error when the client uses EDNS.
 //
 // Parameters:
 //   - w: The dns.ResponseWriter used to send the response.
 //   - req: The original *dns.Msg request, may be nil.
-func respondWithFailure(w dns.ResponseWriter, req *dns.Msg) {
-	respondWithRcode(w, req, dns.RcodeServerFailure)
+//   - ede: The EDE INFO-CODE describing the failure.
+//   - extraText: The EDE EXTRA-TEXT, sent only when ede_extra_text is enabled.
+func respondWithFailure(w dns.ResponseWriter, req *dns.Msg, ede uint16, extraText string) {
+	m := new(dns.Msg)
+	if req == nil {
+		m.MsgHdr.Rcode = dns.RcodeServerFailure
+		_ = w.WriteMsg(m)
+		return
+	}
+	m.SetRcode(req, dns.RcodeServerFailure)
+	ApplyNSID(m, req)
+	ApplyEDE(m, req, ede, extraText)
+	_ = w.WriteMsg(m)
 }
 
 func respondWithRcode(w dns.ResponseWriter, req *dns.Msg, rcode int) {
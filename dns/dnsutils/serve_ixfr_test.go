@@ -1,6 +1,7 @@
 package dnsutils
 
 import (
+	"fmt"
 	"net"
 	"testing"
 
@@ -180,3 +181,163 @@ func countTransferType(rrs []dns.RR, rrtype uint16) int {
 	}
 	return count
 }
+
+func TestServeDNSIXFRAnswersFromJournal(t *testing.T) {
+	zoneName := "ixfr-journal.test"
+	start := setupIXFRJournalZone(t, zoneName)
+
+	addIXFRTestA(t, zoneName, "new", "192.0.2.99")
+	mustBumpIXFRSerial(t, zoneName)
+	if err := mustTransferRR(t, dns.TypeA).Delete("host1."+zoneName+".", nil); err != nil {
+		t.Fatalf("delete A: %v", err)
+	}
+	mustBumpIXFRSerial(t, zoneName)
+
+	answers := serveIXFRAnswers(t, ixfrRequest(zoneName, start.Serial))
+	if countTransferType(answers, dns.TypeSOA) != 4 {
+		t.Fatalf("SOA count = %d, want 4 for one condensed difference: %v", countTransferType(answers, dns.TypeSOA), answers)
+	}
+	if got := answers[1].(*dns.SOA).Serial; got != start.Serial {
+		t.Fatalf("deletion SOA serial = %d, want %d", got, start.Serial)
+	}
+	if a, ok := answers[2].(*dns.A); !ok || a.Hdr.Name != "host1."+zoneName+"." {
+		t.Fatalf("deleted RR = %v, want host1 A", answers[2])
+	}
+	if a, ok := answers[4].(*dns.A); !ok || a.A.String() != "192.0.2.99" {
+		t.Fatalf("added RR = %v, want new A", answers[4])
+	}
+	if countTransferType(answers, dns.TypeA) != 2 {
+		t.Fatalf("A count = %d, want only the changed records", countTransferType(answers, dns.TypeA))
+	}
+}
+
+func TestServeDNSIXFRUncondensedAndCancelledChanges(t *testing.T) {
+	zoneName := "ixfr-uncondensed.test"
+	start := setupIXFRJournalZone(t, zoneName)
+
+	addIXFRTestA(t, zoneName, "tmp", "192.0.2.98")
+	mustBumpIXFRSerial(t, zoneName)
+	if err := mustTransferRR(t, dns.TypeA).Delete("tmp."+zoneName+".", nil); err != nil {
+		t.Fatalf("delete A: %v", err)
+	}
+	mustBumpIXFRSerial(t, zoneName)
+
+	answers := serveIXFRAnswers(t, ixfrRequest(zoneName, start.Serial))
+	if countTransferType(answers, dns.TypeSOA) != 4 || countTransferType(answers, dns.TypeA) != 0 {
+		t.Fatalf("condensed add+delete should cancel out, got %v", answers)
+	}
+
+	config.AppConfig.LiveForTest().IXFR.Condense = false
+	answers = serveIXFRAnswers(t, ixfrRequest(zoneName, start.Serial))
+	if countTransferType(answers, dns.TypeSOA) != 6 || countTransferType(answers, dns.TypeA) != 2 {
+		t.Fatalf("uncondensed answer should carry both differences, got %v", answers)
+	}
+}
+
+func TestRecordIXFRJournalReadsOnlyChangedRRsets(t *testing.T) {
+	zoneName := "ixfr-incremental.test"
+	setupIXFRJournalZone(t, zoneName)
+	fqdn := dns.Fqdn(zoneName)
+
+	addIXFRTestA(t, zoneName, "new", "192.0.2.99")
+	if err := mustTransferRR(t, dns.TypeA).Delete("host2."+fqdn, nil); err != nil {
+		t.Fatalf("delete A: %v", err)
+	}
+	mustBumpIXFRSerial(t, zoneName)
+	addIXFRTestA(t, zoneName, "later", "192.0.2.100")
+	mustBumpIXFRSerial(t, zoneName)
+
+	ixfrMu.Lock()
+	j := ixfrJournals[fqdn]
+	ixfrMu.Unlock()
+	if j == nil || len(j.diffs) != 2 {
+		t.Fatalf("journal = %+v, want two differences", j)
+	}
+	if d := j.diffs[0]; len(d.Deleted) != 1 || len(d.Added) != 1 {
+		t.Fatalf("first difference = %+v, want host2 out and new in", d)
+	}
+	if d := j.diffs[1]; len(d.Deleted) != 0 || len(d.Added) != 1 {
+		t.Fatalf("second difference = %+v, want later in", d)
+	}
+	soa, full, _, ok := ixfrZoneSnapshot(fqdn)
+	if !ok || soa.Serial != j.soa.Serial {
+		t.Fatalf("journal SOA %v, zone SOA %v", j.soa, soa)
+	}
+	if deleted, added := ixfrSnapshotDiff(j.snapshot, full, nil); len(deleted) != 0 || len(added) != 0 {
+		t.Fatalf("incremental snapshot drifted from the zone: -%v +%v", deleted, added)
+	}
+}
+
+func TestServeDNSIXFRBeyondJournalDepthFallsBack(t *testing.T) {
+	zoneName := "ixfr-depth.test"
+	start := setupIXFRJournalZone(t, zoneName)
+	config.AppConfig.LiveForTest().IXFR.JournalDepth = 1
+
+	addIXFRTestA(t, zoneName, "one", "192.0.2.91")
+	mustBumpIXFRSerial(t, zoneName)
+	addIXFRTestA(t, zoneName, "two", "192.0.2.92")
+	mustBumpIXFRSerial(t, zoneName)
+
+	answers := serveIXFRAnswers(t, ixfrRequest(zoneName, start.Serial))
+	if countTransferType(answers, dns.TypeSOA) != 2 {
+		t.Fatalf("SOA count = %d, want AXFR fallback", countTransferType(answers, dns.TypeSOA))
+	}
+
+	w := &udpTransferResponseWriter{}
+	ServeDNS(w, ixfrRequest(zoneName, start.Serial))
+	if len(w.messages) != 1 || len(w.messages[0].Answer) != 1 || w.messages[0].Answer[0].Header().Rrtype != dns.TypeSOA {
+		t.Fatalf("UDP fallback should be the current SOA only, got %v", w.messages)
+	}
+}
+
+type udpTransferResponseWriter struct {
+	transferResponseWriter
+}
+
+func (w *udpTransferResponseWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}
+}
+
+func setupIXFRJournalZone(t *testing.T, zoneName string) *dns.SOA {
+	t.Helper()
+	soa := setupIXFRTestZone(t, zoneName)
+	for i := 1; i <= 8; i++ {
+		addIXFRTestA(t, zoneName, fmt.Sprintf("host%d", i), fmt.Sprintf("192.0.2.%d", i))
+	}
+	LoadIXFRJournal()
+	t.Cleanup(func() {
+		ixfrMu.Lock()
+		ixfrJournals = map[string]*ixfrZoneJournal{}
+		ixfrMu.Unlock()
+	})
+	return soa
+}
+
+func addIXFRTestA(t *testing.T, zoneName, name, ip string) {
+	t.Helper()
+	ttl := uint32(3600)
+	if err := mustTransferRR(t, dns.TypeA).Add(zoneName, name, map[string]interface{}{"ip": ip}, &ttl); err != nil {
+		t.Fatalf("add A %s: %v", name, err)
+	}
+}
+
+func mustBumpIXFRSerial(t *testing.T, zoneName string) {
+	t.Helper()
+	if err := UpdateSOASerial(zoneName); err != nil {
+		t.Fatalf("UpdateSOASerial: %v", err)
+	}
+}
+
+func serveIXFRAnswers(t *testing.T, req *dns.Msg) []dns.RR {
+	t.Helper()
+	w := &transferResponseWriter{}
+	ServeDNS(w, req)
+	var answers []dns.RR
+	for _, msg := range w.messages {
+		if msg.Rcode != dns.RcodeSuccess {
+			t.Fatalf("rcode = %s, want NOERROR", dns.RcodeToString[msg.Rcode])
+		}
+		answers = append(answers, msg.Answer...)
+	}
+	return answers
+}
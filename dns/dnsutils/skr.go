@@ -0,0 +1,131 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: skr.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/TenforwardAB/slog"
+	"go53/config"
+	"go53/distributed"
+	"go53/internal"
+	"go53/security"
+	"go53/zone"
+)
+
+// skrWindows holds the inception of the SKR bundle each offline-KSK zone
+// served at the last check, 0 for none.
+var skrWindows = struct {
+	sync.Mutex
+	current map[string]int64
+}{current: map[string]int64{}}
+
+// ExportKSR returns the key signing request of an offline-KSK zone for the
+// period from until to. A zone with a policy first gets the ZSKs it rolls to
+// in that period, which are replicated like any other key change.
+func ExportKSR(zoneName string, from, to int64) (security.KeySigningRequest, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return security.KeySigningRequest{}, err
+	}
+	if !security.OfflineKSK(fqdn) {
+		return security.KeySigningRequest{}, fmt.Errorf("zone %s does not keep its KSK offline; set offline_ksk in its settings", fqdn)
+	}
+	ttl := uint32(3600)
+	if _, policy, ok := ZoneKASPPolicy(fqdn); ok {
+		res, err := security.PregenerateZSKs(fqdn, policy, kaspNow().Unix(), to)
+		if applyErr := applyKASPResult(fqdn, res, true); applyErr != nil && err == nil {
+			err = applyErr
+		}
+		if err != nil {
+			return security.KeySigningRequest{}, err
+		}
+		if policy.DNSKEYTTLSec > 0 {
+			ttl = uint32(policy.DNSKEYTTLSec)
+		}
+	}
+	return security.BuildKSR(fqdn, from, to, ttl)
+}
+
+// ImportSKR stores the signed key response in data for zoneName, re-signs
+// the zone with it and replicates it.
+func ImportSKR(zoneName string, data []byte) (security.SKRStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return security.SKRStatus{}, err
+	}
+	skr, err := security.ImportSKR(fqdn, data, kaspNow().Unix())
+	if err != nil {
+		return security.SKRStatus{}, err
+	}
+	if err := zone.RefreshDNSSECKeyMaterial(fqdn); err != nil {
+		return security.SKRStatus{}, err
+	}
+	if err := distributed.Default.PublishSKR(fqdn, skr); err != nil {
+		return security.SKRStatus{}, err
+	}
+	return SKRStatusOf(fqdn)
+}
+
+// SKRStatusOf reports the imported SKR of zoneName, with warnings from
+// dnssec.skr_warn_sec on.
+func SKRStatusOf(zoneName string) (security.SKRStatus, error) {
+	warn := int64(config.AppConfig.GetLive().DNSSEC.SKRWarnSec)
+	return security.SKRStatusOf(zoneName, kaspNow().Unix(), warn)
+}
+
+func runSKROnce() {
+	for _, name := range signedZones() {
+		if !security.OfflineKSK(name) {
+			continue
+		}
+		if _, err := checkSKR(name); err != nil {
+			log.Printf("[skr] %s: %v", name, err)
+		}
+	}
+}
+
+// checkSKR re-signs zoneName when another SKR bundle took over since the
+// last check, so the NSEC chain follows its CDS and CDNSKEY RRsets, and
+// raises an alert for every warning of its status.
+func checkSKR(zoneName string) (security.SKRStatus, error) {
+	status, err := SKRStatusOf(zoneName)
+	if err != nil {
+		return status, err
+	}
+	var current int64
+	for _, b := range status.Bundles {
+		if b.Current {
+			current = b.Inception
+		}
+	}
+	skrWindows.Lock()
+	last, seen := skrWindows.current[zoneName]
+	skrWindows.current[zoneName] = current
+	skrWindows.Unlock()
+	if seen && last != current {
+		log.Printf("[skr] %s: serving the SKR bundle from %d", zoneName, current)
+		if err := zone.RefreshDNSSECKeyMaterial(zoneName); err != nil {
+			return status, err
+		}
+	}
+	for _, warning := range status.Warnings {
+		slog.Alert("[skr] %s: %s", zoneName, warning)
+	}
+	return status, nil
+}
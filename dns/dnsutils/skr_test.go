@@ -0,0 +1,75 @@
+package dnsutils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go53/config"
+	"go53/security"
+	"go53/zonemeta"
+)
+
+func TestExportKSRRequiresOfflineKSK(t *testing.T) {
+	setupKASPTest(t)
+	addTestSOA(t, "online.test.")
+	now := time.Now().Unix()
+
+	if _, err := ExportKSR("online.test.", now, now+86400); err == nil {
+		t.Fatal("ExportKSR succeeded for a zone that keeps its KSK online")
+	}
+}
+
+func TestOfflineKSKZoneServesImportedSKR(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.SKRWarnSec = 3600
+	addTestSOA(t, "offline.test.")
+	if err := zonemeta.SaveSettings("offline.test.", zonemeta.Settings{OfflineKSK: true}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	if err := runKASPZone("offline.test."); err != nil {
+		t.Fatalf("runKASPZone: %v", err)
+	}
+	if keys, _ := security.LoadAllKeysForZone("offline.test"); len(keys) != 0 {
+		t.Fatalf("key scheduler generated %d keys for an offline-KSK zone", len(keys))
+	}
+
+	now := time.Now().Unix()
+	ksr, err := ExportKSR("offline.test.", now, now+3*86400)
+	if err != nil {
+		t.Fatalf("ExportKSR: %v", err)
+	}
+	if len(ksr.Slots) < 4 || ksr.TTL != 300 {
+		t.Fatalf("KSR = %d slots with TTL %d, want a slot per daily ZSK set with the policy TTL", len(ksr.Slots), ksr.TTL)
+	}
+
+	file, err := security.GenerateOfflineKSK("offline.test.", "ED25519")
+	if err != nil {
+		t.Fatalf("GenerateOfflineKSK: %v", err)
+	}
+	keyFile, _ := json.Marshal(file)
+	ksks, err := security.LoadOfflineKeys(keyFile)
+	if err != nil {
+		t.Fatalf("LoadOfflineKeys: %v", err)
+	}
+	skr, err := security.SignKSR(ksr, ksks, true)
+	if err != nil {
+		t.Fatalf("SignKSR: %v", err)
+	}
+	data, _ := json.Marshal(skr)
+	status, err := ImportSKR("offline.test.", data)
+	if err != nil {
+		t.Fatalf("ImportSKR: %v", err)
+	}
+	if status.CoveredUntil != now+3*86400 || len(status.Warnings) != 0 {
+		t.Fatalf("status = %+v, want covered for three days without warnings", status)
+	}
+
+	parent, err := security.ParentDSDNSKEYs("offline.test.", now)
+	if err != nil || len(parent) != 1 || parent[0].KeyTag() != file.Keys[0].KeyTag {
+		t.Fatalf("ParentDSDNSKEYs = %v, %v, want the offline KSK", parent, err)
+	}
+	if status, err = checkSKR("offline.test."); err != nil || len(status.Warnings) != 0 {
+		t.Fatalf("checkSKR = %+v, %v", status, err)
+	}
+}
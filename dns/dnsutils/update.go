@@ -0,0 +1,605 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: update.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/distributed"
+	"go53/internal"
+	"go53/memory"
+	"go53/security"
+	"go53/wal"
+	"go53/zone"
+	"go53/zone/rtypes"
+	"go53/zonemeta"
+)
+
+// updateMu serializes dynamic UPDATE transactions so the prerequisite check and
+// the RRset rewrites of one request cannot interleave with those of another
+// (RFC 2136 §3.7).
+var updateMu sync.Mutex
+
+// HandleUpdate processes an RFC 2136 dynamic UPDATE message and writes the
+// response.
+//
+// Every UPDATE must be TSIG-signed with a key that an update-policy rule
+// (config.UpdateConfig) binds to the zone, owner name and record type being
+// changed; unsigned requests are refused. A request whose signature does not
+// verify is answered NOTAUTH without a TSIG record (RFC 8945 §5.3.2).
+//
+// Processing follows RFC 2136 §3: zone section, prerequisites, permission
+// check, update pre-scan, then the update itself. All RRsets are computed
+// before anything is written, so a rejected request leaves the zone untouched,
+// and they are swapped in together, so a failed write does too.
+// When records changed the SOA serial is bumped, each changed RRset is
+// appended to the WAL and replicated to distributed peers, and NOTIFY is
+// scheduled for the zone.
+func HandleUpdate(w dns.ResponseWriter, r *dns.Msg) {
+	tsig := r.IsTsig()
+	if tsig != nil {
+		if _, ok := security.GetTSIGKey(tsig.Hdr.Name); !ok || w.TsigStatus() != nil {
+			log.Printf("[update] TSIG verification failed for key %s: %v", tsig.Hdr.Name, w.TsigStatus())
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeNotAuth)
+			ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "TSIG verification failed for key "+tsig.Hdr.Name)
+			if err := w.WriteMsg(m); err != nil {
+				log.Printf("[update] failed to write UPDATE response: %v", err)
+			}
+			return
+		}
+	}
+
+	keyName := ""
+	if tsig != nil {
+		keyName = tsig.Hdr.Name
+	}
+	rcode := processUpdate(r, keyName, config.AppConfig.GetLive())
+	writeUpdateResponse(w, r, rcode, tsig)
+}
+
+func processUpdate(r *dns.Msg, keyName string, live config.LiveConfig) int {
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError
+	}
+	zq := r.Question[0]
+	zoneName := strings.ToLower(dns.Fqdn(zq.Name))
+	if zq.Qclass != dns.ClassINET {
+		return dns.RcodeNotAuth
+	}
+	if apex, ok := zone.AuthoritativeZoneForName(zoneName); !ok || apex != zoneName {
+		log.Printf("[update] not authoritative for zone %s", zoneName)
+		return dns.RcodeNotAuth
+	}
+	if zonemeta.IsSecondary(zoneName) {
+		// RFC 2136 §6 lets a secondary forward UPDATEs to its primary; go53
+		// does not, so the client must talk to the primary directly.
+		log.Printf("[update] refusing UPDATE for %s: zone is a secondary", zoneName)
+		return dns.RcodeRefused
+	}
+	if meta, readOnly := zonemeta.ReadOnly(zoneName); readOnly {
+		log.Printf("[update] refusing UPDATE for read-only zone %s: %s", zoneName, meta.ReadOnlyReason)
+		return dns.RcodeRefused
+	}
+	if keyName == "" {
+		log.Printf("[update] refusing unsigned UPDATE for %s", zoneName)
+		return dns.RcodeRefused
+	}
+
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	txn, err := newUpdateTxn(zoneName)
+	if err != nil {
+		log.Printf("[update] %v", err)
+		return dns.RcodeServerFailure
+	}
+	if rcode := txn.checkPrerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+	if rcode := prescanUpdates(zoneName, r.Ns); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+	for _, rr := range r.Ns {
+		hdr := rr.Header()
+		if !updatePermitted(live.Update.Rules, keyName, zoneName, hdr.Name, hdr.Rrtype) {
+			log.Printf("[update] key %s may not update %s %s in %s", keyName, hdr.Name, dns.TypeToString[hdr.Rrtype], zoneName)
+			return dns.RcodeRefused
+		}
+	}
+	for _, rr := range r.Ns {
+		txn.apply(rr)
+	}
+	if err := txn.commit(); err != nil {
+		log.Printf("[update] applying UPDATE to %s failed: %v", zoneName, err)
+		return dns.RcodeServerFailure
+	}
+	return dns.RcodeSuccess
+}
+
+func writeUpdateResponse(w dns.ResponseWriter, r *dns.Msg, rcode int, tsig *dns.TSIG) {
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	if tsig != nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+	if err := w.WriteMsg(m); err != nil {
+		log.Printf("[update] failed to write UPDATE response: %v", err)
+	}
+}
+
+// prescanUpdates validates the update section before anything is applied
+// (RFC 2136 §3.4.1). Records the server maintains itself (DNSSEC material) and
+// types that cannot be stored (meta types, OPT) are refused.
+func prescanUpdates(zoneName string, updates []dns.RR) int {
+	for _, rr := range updates {
+		hdr := rr.Header()
+		if !nameInZone(hdr.Name, zoneName) {
+			return dns.RcodeNotZone
+		}
+		switch hdr.Rrtype {
+		case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB:
+			return dns.RcodeFormatError
+		}
+		switch hdr.Class {
+		case dns.ClassINET:
+			if hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassANY:
+			if hdr.Ttl != 0 || !rdataEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if hdr.Ttl != 0 || hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+		if hdr.Rrtype == dns.TypeANY {
+			continue
+		}
+		if serverMaintainedType(hdr.Rrtype) {
+			log.Printf("[update] refusing UPDATE of server-maintained %s at %s", dns.TypeToString[hdr.Rrtype], hdr.Name)
+			return dns.RcodeRefused
+		}
+		if _, ok := rtypes.Get(hdr.Rrtype); !ok {
+			log.Printf("[update] refusing UPDATE of unsupported type %d at %s", hdr.Rrtype, hdr.Name)
+			return dns.RcodeRefused
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// serverMaintainedType reports whether rrtype is produced by go53's own DNSSEC
+// or ZONEMD machinery and therefore must not be changed by UPDATE.
+func serverMaintainedType(rrtype uint16) bool {
+	switch rrtype {
+	case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM,
+		dns.TypeDNSKEY, dns.TypeCDS, dns.TypeCDNSKEY, dns.TypeZONEMD:
+		return true
+	}
+	return false
+}
+
+// singleValuedType reports whether the store keeps at most one record of
+// rrtype per owner, so an UPDATE add replaces rather than extends the RRset.
+func singleValuedType(rrtype uint16) bool {
+	switch rrtype {
+	case dns.TypeCNAME, dns.TypeDNAME, dns.TypeSPF, dns.TypeSOA:
+		return true
+	}
+	return false
+}
+
+// rdataEmpty reports whether rr carries no RDATA, which is how prerequisites
+// and class ANY deletions are encoded on the wire. Messages built in-process
+// never set Rdlength, so the packed length is compared instead.
+func rdataEmpty(rr dns.RR) bool {
+	if _, ok := rr.(*dns.ANY); ok {
+		return true
+	}
+	if rr.Header().Rdlength > 0 {
+		return false
+	}
+	return dns.Len(rr) <= dns.Len(&dns.ANY{Hdr: *rr.Header()})
+}
+
+func nameInZone(name, zoneName string) bool {
+	name = strings.ToLower(dns.Fqdn(name))
+	return name == zoneName || strings.HasSuffix(name, "."+zoneName)
+}
+
+type updateRRsetKey struct {
+	owner  string
+	rrtype uint16
+}
+
+// updateTxn is the working copy of the RRsets touched by one UPDATE. RRsets are
+// loaded lazily from the zone store, changed in memory, and written back by
+// commit only when their content differs from what was loaded.
+type updateTxn struct {
+	zone     string
+	zoneKey  string
+	sets     map[updateRRsetKey][]dns.RR
+	original map[updateRRsetKey][]dns.RR
+	order    []updateRRsetKey
+}
+
+func newUpdateTxn(zoneName string) (*updateTxn, error) {
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return nil, fmt.Errorf("memory store is not initialized")
+	}
+	zoneKey, _, _, ok := mem.GetRecord(zoneName, "SOA", "@")
+	if !ok {
+		return nil, fmt.Errorf("zone %s has no SOA", zoneName)
+	}
+	return &updateTxn{
+		zone:     zoneName,
+		zoneKey:  zoneKey,
+		sets:     map[updateRRsetKey][]dns.RR{},
+		original: map[updateRRsetKey][]dns.RR{},
+	}, nil
+}
+
+func (t *updateTxn) rrset(owner string, rrtype uint16) []dns.RR {
+	key := updateRRsetKey{owner: strings.ToLower(dns.Fqdn(owner)), rrtype: rrtype}
+	if rrs, ok := t.sets[key]; ok {
+		return rrs
+	}
+	var rrs []dns.RR
+	if found, ok := zone.LookupRecord(rrtype, key.owner); ok {
+		for _, rr := range found {
+			if rr.Header().Rrtype == rrtype {
+				rrs = append(rrs, dns.Copy(rr))
+			}
+		}
+	}
+	t.sets[key] = rrs
+	t.original[key] = rrs
+	return rrs
+}
+
+func (t *updateTxn) set(owner string, rrtype uint16, rrs []dns.RR) {
+	t.rrset(owner, rrtype)
+	key := updateRRsetKey{owner: strings.ToLower(dns.Fqdn(owner)), rrtype: rrtype}
+	t.sets[key] = rrs
+	for _, existing := range t.order {
+		if existing == key {
+			return
+		}
+	}
+	t.order = append(t.order, key)
+}
+
+// ownerTypes lists the RR types currently present at owner, including RRsets
+// added earlier in this transaction.
+func (t *updateTxn) ownerTypes(owner string) []uint16 {
+	owner = strings.ToLower(dns.Fqdn(owner))
+	rel := updateRelativeName(t.zone, owner)
+	seen := map[uint16]bool{}
+	if mem := rtypes.GetMemStore(); mem != nil {
+		for typeName, names := range mem.ZoneRecordsSnapshot(t.zoneKey) {
+			rrtype, ok := internal.TypeCode(typeName)
+			if !ok {
+				continue
+			}
+			for name := range names {
+				if strings.EqualFold(name, rel) {
+					seen[rrtype] = true
+				}
+			}
+		}
+	}
+	for key := range t.sets {
+		if key.owner == owner {
+			seen[key.rrtype] = true
+		}
+	}
+	var out []uint16
+	for rrtype := range seen {
+		if len(t.rrset(owner, rrtype)) > 0 {
+			out = append(out, rrtype)
+		}
+	}
+	return out
+}
+
+func (t *updateTxn) nameInUse(owner string) bool {
+	for _, rrtype := range t.ownerTypes(owner) {
+		if !serverMaintainedType(rrtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPrerequisites evaluates the prerequisite section (RFC 2136 §3.2).
+func (t *updateTxn) checkPrerequisites(prereqs []dns.RR) int {
+	valueSets := map[updateRRsetKey][]dns.RR{}
+	var valueOrder []updateRRsetKey
+	for _, rr := range prereqs {
+		hdr := rr.Header()
+		if hdr.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !nameInZone(hdr.Name, t.zone) {
+			return dns.RcodeNotZone
+		}
+		switch hdr.Class {
+		case dns.ClassANY:
+			if !rdataEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if !t.nameInUse(hdr.Name) {
+					return dns.RcodeNameError
+				}
+			} else if len(t.rrset(hdr.Name, hdr.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if !rdataEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if t.nameInUse(hdr.Name) {
+					return dns.RcodeYXDomain
+				}
+			} else if len(t.rrset(hdr.Name, hdr.Rrtype)) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			key := updateRRsetKey{owner: strings.ToLower(dns.Fqdn(hdr.Name)), rrtype: hdr.Rrtype}
+			if _, ok := valueSets[key]; !ok {
+				valueOrder = append(valueOrder, key)
+			}
+			valueSets[key] = append(valueSets[key], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	for _, key := range valueOrder {
+		if !sameRdataSet(valueSets[key], t.rrset(key.owner, key.rrtype)) {
+			return dns.RcodeNXRrset
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// apply performs one update RR against the working copy (RFC 2136 §3.4.2).
+// Updates the RFC says to silently ignore are dropped here.
+func (t *updateTxn) apply(rr dns.RR) {
+	hdr := rr.Header()
+	owner := strings.ToLower(dns.Fqdn(hdr.Name))
+	apex := owner == t.zone
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		add := dns.Copy(rr)
+		add.Header().Name = owner
+		switch hdr.Rrtype {
+		case dns.TypeSOA:
+			current := t.rrset(owner, dns.TypeSOA)
+			if !apex || len(current) == 0 {
+				return
+			}
+			if !serialNewer(add.(*dns.SOA).Serial, current[0].(*dns.SOA).Serial) {
+				return
+			}
+		case dns.TypeCNAME:
+			for _, rrtype := range t.ownerTypes(owner) {
+				if rrtype != dns.TypeCNAME && !serverMaintainedType(rrtype) {
+					return
+				}
+			}
+		default:
+			if len(t.rrset(owner, dns.TypeCNAME)) > 0 {
+				return
+			}
+		}
+		if singleValuedType(hdr.Rrtype) {
+			t.set(owner, hdr.Rrtype, []dns.RR{add})
+			return
+		}
+		next := []dns.RR{add}
+		for _, existing := range t.rrset(owner, hdr.Rrtype) {
+			if sameRdata(existing, add) {
+				continue
+			}
+			kept := dns.Copy(existing)
+			// RFC 2181 §5.2: all records of an RRset share one TTL; the
+			// store enforces it, so the added record's TTL wins.
+			kept.Header().Ttl = hdr.Ttl
+			next = append(next, kept)
+		}
+		t.set(owner, hdr.Rrtype, next)
+
+	case dns.ClassANY:
+		if hdr.Rrtype == dns.TypeANY {
+			for _, rrtype := range t.ownerTypes(owner) {
+				if serverMaintainedType(rrtype) || (apex && (rrtype == dns.TypeSOA || rrtype == dns.TypeNS)) {
+					continue
+				}
+				t.set(owner, rrtype, nil)
+			}
+			return
+		}
+		if apex && (hdr.Rrtype == dns.TypeSOA || hdr.Rrtype == dns.TypeNS) {
+			return
+		}
+		t.set(owner, hdr.Rrtype, nil)
+
+	case dns.ClassNONE:
+		if hdr.Rrtype == dns.TypeSOA {
+			return
+		}
+		var next []dns.RR
+		for _, existing := range t.rrset(owner, hdr.Rrtype) {
+			if !sameRdata(existing, rr) {
+				next = append(next, existing)
+			}
+		}
+		if apex && hdr.Rrtype == dns.TypeNS && len(next) == 0 {
+			return
+		}
+		t.set(owner, hdr.Rrtype, next)
+	}
+}
+
+// commit builds every changed RRset in a staging zone and swaps them into the
+// live zone in one step, so a failed write leaves the zone as it was. It then
+// bumps the SOA serial and records the changes in the WAL and the distributed
+// event log.
+func (t *updateTxn) commit() error {
+	var changed []updateRRsetKey
+	for _, key := range t.order {
+		if !sameRRset(t.original[key], t.sets[key]) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+
+	staging := mem.BeginStaging()
+	keys := make([]memory.RRsetKey, 0, len(changed))
+	soaUpdated := false
+	for _, key := range changed {
+		if key.rrtype == dns.TypeSOA {
+			soaUpdated = true
+		}
+		if rrs := t.sets[key]; len(rrs) > 0 {
+			if err := importFromZoneData(zone.Default, staging, internal.RRToZoneDataForZone(t.zone, rrs), false); err != nil {
+				mem.DiscardStaging(staging)
+				return err
+			}
+		}
+		keys = append(keys, memory.RRsetKey{Type: internal.TypeName(key.rrtype), Name: updateRelativeName(t.zone, key.owner)})
+	}
+	if err := mem.CommitStaging(staging, t.zoneKey, keys); err != nil {
+		return err
+	}
+	if !soaUpdated {
+		if err := UpdateSOASerial(t.zone); err != nil {
+			return err
+		}
+		changed = append(changed, updateRRsetKey{owner: t.zone, rrtype: dns.TypeSOA})
+	} else {
+		RecordIXFRJournal(t.zone)
+	}
+
+	for _, key := range changed {
+		if err := t.recordChange(key); err != nil {
+			return err
+		}
+	}
+	go ScheduleNotify(t.zone)
+	return nil
+}
+
+// recordChange appends the stored state of one RRset to the WAL and publishes
+// it to distributed peers. The full stored value is used so WAL replay and peer
+// apply replace the RRset exactly as it exists here.
+func (t *updateTxn) recordChange(key updateRRsetKey) error {
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	typeName := internal.TypeName(key.rrtype)
+	name := updateRelativeName(t.zone, key.owner)
+	zoneKey, typeKey, value, ok := mem.GetRecord(t.zoneKey, typeName, name)
+	if !ok {
+		if _, err := wal.Append(wal.KindZoneRecord, wal.OpDelete, t.zoneKey, typeName, name, "", "", nil); err != nil {
+			return err
+		}
+		if distributed.Default == nil || !distributed.Enabled() {
+			return nil
+		}
+		return distributed.Default.PublishDelete(t.zoneKey, typeName, name)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := wal.Append(wal.KindZoneRecord, wal.OpUpsert, zoneKey, typeKey, name, "", "", raw); err != nil {
+		return err
+	}
+	if distributed.Default == nil || !distributed.Enabled() {
+		return nil
+	}
+	return distributed.Default.PublishUpsert(zoneKey, typeKey, name, value)
+}
+
+func updateRelativeName(zoneName, owner string) string {
+	owner = strings.ToLower(dns.Fqdn(owner))
+	if owner == zoneName {
+		return "@"
+	}
+	return strings.TrimSuffix(owner, "."+zoneName)
+}
+
+// sameRdata compares two RRs by owner, type and RDATA, ignoring class and TTL
+// so class NONE deletions and prerequisites match stored IN records.
+func sameRdata(a, b dns.RR) bool {
+	ac, bc := dns.Copy(a), dns.Copy(b)
+	ac.Header().Class = dns.ClassINET
+	bc.Header().Class = dns.ClassINET
+	return dns.IsDuplicate(ac, bc)
+}
+
+func sameRdataSet(a, b []dns.RR) bool {
+	contains := func(set []dns.RR, rr dns.RR) bool {
+		for _, candidate := range set {
+			if sameRdata(candidate, rr) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rr := range a {
+		if !contains(b, rr) {
+			return false
+		}
+	}
+	for _, rr := range b {
+		if !contains(a, rr) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameRRset(a, b []dns.RR) bool {
+	if len(a) != len(b) || !sameRdataSet(a, b) {
+		return false
+	}
+	return len(a) == 0 || a[0].Header().Ttl == b[0].Header().Ttl
+}
@@ -0,0 +1,82 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: update_policy.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"path"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/security"
+)
+
+// updatePermitted reports whether a request signed with keyName may change the
+// rrtype RRset at owner in zoneName. rrtype is dns.TypeANY for a "delete all
+// RRsets at a name" update.
+//
+// Rules only grant access while their key still exists in the TSIG key store,
+// so deleting a key through the API revokes its update rights immediately
+// without touching the policy.
+func updatePermitted(rules []config.UpdatePolicyRule, keyName, zoneName, owner string, rrtype uint16) bool {
+	keyName = dns.CanonicalName(keyName)
+	if _, ok := security.ListTSIGKeys()[keyName]; !ok {
+		return false
+	}
+	zoneName = dns.CanonicalName(zoneName)
+	rel := updateRelativeName(zoneName, owner)
+	for _, rule := range rules {
+		if dns.CanonicalName(strings.TrimSpace(rule.Key)) != keyName {
+			continue
+		}
+		if rule.Zone != "*" && dns.CanonicalName(strings.TrimSpace(rule.Zone)) != zoneName {
+			continue
+		}
+		if !updateNameMatches(rule.Name, rel) {
+			continue
+		}
+		if updateTypeMatches(rule.Types, rrtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateNameMatches matches a rule's owner glob against an owner name relative
+// to the zone. "*" in the glob spans labels, so "*.dhcp" covers every name below
+// dhcp; an empty pattern matches every owner.
+func updateNameMatches(pattern, rel string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, strings.ToLower(rel))
+	return err == nil && ok
+}
+
+func updateTypeMatches(allowed []string, rrtype uint16) bool {
+	if len(allowed) == 0 {
+		return rrtype != dns.TypeSOA
+	}
+	want := internal.TypeName(rrtype)
+	for _, t := range allowed {
+		if strings.EqualFold(strings.TrimSpace(t), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package dnsutils
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/memory"
+	"go53/security"
+	"go53/storage"
+	"go53/zone"
+	"go53/zone/rtypes"
+)
+
+const updateTestKey = "dhcp-key."
+
+type updateResponseWriter struct {
+	msg     *dns.Msg
+	tsigErr error
+}
+
+func (w *updateResponseWriter) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 15353}
+}
+
+func (w *updateResponseWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}
+}
+
+func (w *updateResponseWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+func (w *updateResponseWriter) Write([]byte) (int, error) { return 0, nil }
+func (w *updateResponseWriter) Close() error              { return nil }
+func (w *updateResponseWriter) TsigStatus() error         { return w.tsigErr }
+func (w *updateResponseWriter) TsigTimersOnly(bool)       {}
+func (w *updateResponseWriter) Hijack()                   {}
+
+func TestHandleUpdateAddsRecordAndBumpsSerial(t *testing.T) {
+	before := setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "update.test", Name: "*.dhcp", Types: []string{"A"}}})
+
+	req := updateRequest("update.test.")
+	req.Insert([]dns.RR{mustUpdateRR(t, "host1.dhcp.update.test. 300 IN A 192.0.2.50")})
+
+	rcode := runUpdate(t, req, nil)
+	if rcode != dns.RcodeSuccess {
+		t.Fatalf("rcode = %s, want NOERROR", dns.RcodeToString[rcode])
+	}
+	rrs, ok := zone.LookupRecord(dns.TypeA, "host1.dhcp.update.test.")
+	if !ok || len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "192.0.2.50" {
+		t.Fatalf("A lookup = %v ok=%v", rrs, ok)
+	}
+	if after := currentUpdateSerial(t); !serialNewer(after, before) {
+		t.Fatalf("serial %d not newer than %d", after, before)
+	}
+}
+
+func TestHandleUpdatePrerequisiteFailureLeavesZoneUntouched(t *testing.T) {
+	before := setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "*"}})
+
+	req := updateRequest("update.test.")
+	req.RRsetNotUsed([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 0.0.0.0")})
+	req.Insert([]dns.RR{mustUpdateRR(t, "www.update.test. 300 IN A 192.0.2.99")})
+
+	if rcode := runUpdate(t, req, nil); rcode != dns.RcodeYXRrset {
+		t.Fatalf("rcode = %s, want YXRRSET", dns.RcodeToString[rcode])
+	}
+	rrs, _ := zone.LookupRecord(dns.TypeA, "www.update.test.")
+	if len(rrs) != 1 {
+		t.Fatalf("www A records = %d, want 1", len(rrs))
+	}
+	if after := currentUpdateSerial(t); after != before {
+		t.Fatalf("serial changed from %d to %d on failed prerequisite", before, after)
+	}
+}
+
+func TestHandleUpdateValueDependentPrerequisite(t *testing.T) {
+	setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "update.test"}})
+
+	req := updateRequest("update.test.")
+	req.Used([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 192.0.2.10")})
+	req.Remove([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 192.0.2.10")})
+	req.Insert([]dns.RR{mustUpdateRR(t, "www.update.test. 300 IN A 192.0.2.11")})
+
+	if rcode := runUpdate(t, req, nil); rcode != dns.RcodeSuccess {
+		t.Fatalf("rcode = %s, want NOERROR", dns.RcodeToString[rcode])
+	}
+	rrs, ok := zone.LookupRecord(dns.TypeA, "www.update.test.")
+	if !ok || len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "192.0.2.11" {
+		t.Fatalf("A lookup = %v ok=%v", rrs, ok)
+	}
+
+	stale := updateRequest("update.test.")
+	stale.Used([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 192.0.2.10")})
+	if rcode := runUpdate(t, stale, nil); rcode != dns.RcodeNXRrset {
+		t.Fatalf("stale prerequisite rcode = %s, want NXRRSET", dns.RcodeToString[rcode])
+	}
+}
+
+func TestHandleUpdateDeletesRRsetAndName(t *testing.T) {
+	setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "update.test"}})
+	if err := zone.AddRecord(dns.TypeTXT, "update.test.", "www", map[string]interface{}{"text": "hello"}, ptrUint32(300)); err != nil {
+		t.Fatalf("add TXT: %v", err)
+	}
+
+	req := updateRequest("update.test.")
+	req.RemoveName([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 0.0.0.0")})
+	req.RemoveRRset([]dns.RR{mustUpdateRR(t, "update.test. 0 IN NS ns1.update.test.")})
+
+	if rcode := runUpdate(t, req, nil); rcode != dns.RcodeSuccess {
+		t.Fatalf("rcode = %s, want NOERROR", dns.RcodeToString[rcode])
+	}
+	if _, ok := zone.LookupRecord(dns.TypeA, "www.update.test."); ok {
+		t.Fatalf("www A survived delete-name")
+	}
+	if _, ok := zone.LookupRecord(dns.TypeTXT, "www.update.test."); ok {
+		t.Fatalf("www TXT survived delete-name")
+	}
+	if rrs, ok := zone.LookupRecord(dns.TypeNS, "update.test."); !ok || len(rrs) != 1 {
+		t.Fatalf("apex NS must survive RRset delete, got %v ok=%v", rrs, ok)
+	}
+}
+
+func TestHandleUpdateRefusedOutsidePolicy(t *testing.T) {
+	setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "update.test", Name: "*.dhcp", Types: []string{"A"}}})
+
+	cases := map[string]dns.RR{
+		"name": mustUpdateRR(t, "mail.update.test. 300 IN A 192.0.2.60"),
+		"type": mustUpdateRR(t, "host1.dhcp.update.test. 300 IN TXT \"x\""),
+	}
+	for name, rr := range cases {
+		req := updateRequest("update.test.")
+		req.Insert([]dns.RR{rr})
+		if rcode := runUpdate(t, req, nil); rcode != dns.RcodeRefused {
+			t.Fatalf("%s: rcode = %s, want REFUSED", name, dns.RcodeToString[rcode])
+		}
+	}
+}
+
+func TestHandleUpdateRejectsUnsignedAndBadTSIG(t *testing.T) {
+	setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "*"}})
+
+	unsigned := new(dns.Msg)
+	unsigned.SetUpdate("update.test.")
+	unsigned.Insert([]dns.RR{mustUpdateRR(t, "www.update.test. 300 IN A 192.0.2.70")})
+	w := &updateResponseWriter{}
+	HandleUpdate(w, unsigned)
+	if w.msg == nil || w.msg.Rcode != dns.RcodeRefused {
+		t.Fatalf("unsigned UPDATE response = %v, want REFUSED", w.msg)
+	}
+
+	if rcode := runUpdate(t, updateRequest("update.test."), dns.ErrSig); rcode != dns.RcodeNotAuth {
+		t.Fatalf("bad TSIG rcode = %s, want NOTAUTH", dns.RcodeToString[rcode])
+	}
+}
+
+func TestHandleUpdateNotAuthAndNotZone(t *testing.T) {
+	setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "*"}})
+
+	if rcode := runUpdate(t, updateRequest("other.test."), nil); rcode != dns.RcodeNotAuth {
+		t.Fatalf("unknown zone rcode = %s, want NOTAUTH", dns.RcodeToString[rcode])
+	}
+
+	req := updateRequest("update.test.")
+	req.Insert([]dns.RR{mustUpdateRR(t, "www.other.test. 300 IN A 192.0.2.1")})
+	if rcode := runUpdate(t, req, nil); rcode != dns.RcodeNotZone {
+		t.Fatalf("out-of-zone rcode = %s, want NOTZONE", dns.RcodeToString[rcode])
+	}
+}
+
+func TestUpdatePermittedRequiresExistingKey(t *testing.T) {
+	setupUpdateTestZone(t, nil)
+	rules := []config.UpdatePolicyRule{
+		{Key: "gone-key", Zone: "*"},
+		{Key: updateTestKey, Zone: "update.test", Name: "_acme-challenge*", Types: []string{"TXT"}},
+	}
+
+	if updatePermitted(rules, "gone-key.", "update.test.", "www.update.test.", dns.TypeA) {
+		t.Fatalf("rule for a key missing from the TSIG store must not grant access")
+	}
+	if !updatePermitted(rules, updateTestKey, "update.test.", "_acme-challenge.www.update.test.", dns.TypeTXT) {
+		t.Fatalf("expected TXT update under _acme-challenge to be permitted")
+	}
+	if updatePermitted(rules, updateTestKey, "update.test.", "_acme-challenge.update.test.", dns.TypeANY) {
+		t.Fatalf("delete-name must require ANY in the rule types")
+	}
+	if updatePermitted([]config.UpdatePolicyRule{{Key: updateTestKey, Zone: "*"}}, updateTestKey, "update.test.", "update.test.", dns.TypeSOA) {
+		t.Fatalf("SOA must be listed explicitly")
+	}
+}
+
+func setupUpdateTestZone(t *testing.T, rules []config.UpdatePolicyRule) uint32 {
+	t.Helper()
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	config.AppConfig.LiveForTest().DNSSECEnabled = false
+	config.AppConfig.LiveForTest().Mode = "primary"
+	config.AppConfig.LiveForTest().Update.Rules = rules
+	backend := &storage.MockStorage{Zones: map[string][]byte{}, Tables: map[string]map[string][]byte{}}
+	storage.Backend = backend
+	store, err := memory.NewZoneStore(backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	rtypes.InitMemoryStore(store)
+	security.SetTSIGKey(updateTestKey, security.TSIGKey{Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"})
+	t.Cleanup(func() {
+		rtypes.InitMemoryStore(nil)
+		security.DeleteTSIGKey(updateTestKey)
+	})
+
+	if err := zone.AddRecord(dns.TypeSOA, "update.test.", "@", map[string]interface{}{
+		"ns":      "ns1.update.test.",
+		"mbox":    "hostmaster.update.test.",
+		"refresh": float64(3600),
+		"retry":   float64(900),
+		"expire":  float64(1209600),
+		"minimum": float64(300),
+	}, ptrUint32(3600)); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	if err := zone.AddRecord(dns.TypeNS, "update.test.", "@", map[string]interface{}{"ns": "ns1.update.test."}, ptrUint32(3600)); err != nil {
+		t.Fatalf("add NS: %v", err)
+	}
+	if err := zone.AddRecord(dns.TypeA, "update.test.", "www", map[string]interface{}{"ip": "192.0.2.10"}, ptrUint32(300)); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	return currentUpdateSerial(t)
+}
+
+func updateRequest(zoneName string) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetUpdate(zoneName)
+	req.SetTsig(updateTestKey, dns.HmacSHA256, 300, time.Now().Unix())
+	return req
+}
+
+func runUpdate(t *testing.T, req *dns.Msg, tsigErr error) int {
+	t.Helper()
+	w := &updateResponseWriter{tsigErr: tsigErr}
+	HandleUpdate(w, req)
+	if w.msg == nil {
+		t.Fatalf("expected UPDATE response")
+	}
+	if w.msg.Opcode != dns.OpcodeUpdate {
+		t.Fatalf("response opcode = %d, want UPDATE", w.msg.Opcode)
+	}
+	if tsigErr == nil && w.msg.IsTsig() == nil {
+		t.Fatalf("signed UPDATE response must carry TSIG")
+	}
+	return w.msg.Rcode
+}
+
+func currentUpdateSerial(t *testing.T) uint32 {
+	t.Helper()
+	rrs, ok := zone.LookupRecord(dns.TypeSOA, "update.test.")
+	if !ok || len(rrs) != 1 {
+		t.Fatalf("SOA lookup failed")
+	}
+	return rrs[0].(*dns.SOA).Serial
+}
+
+func mustUpdateRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return rr
+}
+
+func ptrUint32(v uint32) *uint32 {
+	return &v
+}
+
+func TestHandleUpdateFailedWriteLeavesZoneUntouched(t *testing.T) {
+	before := setupUpdateTestZone(t, []config.UpdatePolicyRule{{Key: updateTestKey, Zone: "update.test"}})
+
+	req := updateRequest("update.test.")
+	req.RemoveRRset([]dns.RR{mustUpdateRR(t, "www.update.test. 0 IN A 0.0.0.0")})
+	req.Insert([]dns.RR{&dns.CAA{Hdr: dns.RR_Header{Name: "update.test.", Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 300}, Value: "ca.example"}})
+
+	if rcode := runUpdate(t, req, nil); rcode != dns.RcodeServerFailure {
+		t.Fatalf("rcode = %s, want SERVFAIL", dns.RcodeToString[rcode])
+	}
+	if rrs, ok := zone.LookupRecord(dns.TypeA, "www.update.test."); !ok || len(rrs) != 1 {
+		t.Fatalf("www A = %v ok=%v after a failed UPDATE", rrs, ok)
+	}
+	if after := currentUpdateSerial(t); after != before {
+		t.Fatalf("serial changed from %d to %d on a failed UPDATE", before, after)
+	}
+}
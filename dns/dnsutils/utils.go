@@ -7,10 +7,18 @@ import (
 	"go53/types"
 	"go53/zone"
 	"go53/zone/rtypes"
+	"log"
 )
 
 func UpdateSOASerial(zoneName string) error {
-	store := rtypes.GetMemStore()
+	return UpdateViewSOASerial(zone.Default, zoneName)
+}
+
+// UpdateViewSOASerial bumps the SOA serial of zoneName in the zones of a view.
+// An overlay view that does not override the SOA yet starts from the serial
+// of the default zone, so the merged zone it serves always moves forward.
+func UpdateViewSOASerial(zones zone.Scope, zoneName string) error {
+	store := zones.Store()
 	if store == nil {
 		return fmt.Errorf("memstore is not initialized")
 	}
@@ -21,8 +29,13 @@ func UpdateSOASerial(zoneName string) error {
 	}
 
 	_, _, raw, found := store.GetRecord(sanitizedZone, string(types.TypeSOA), "@")
+	if !found && zones.Overlay() {
+		if base := rtypes.GetMemStore(); base != nil {
+			_, _, raw, found = base.GetRecord(sanitizedZone, string(types.TypeSOA), "@")
+		}
+	}
 	if !found {
-		err := zone.AddRecord(dns.TypeSOA, sanitizedZone, "@", map[string]interface{}{}, nil)
+		err := zones.AddRecord(dns.TypeSOA, sanitizedZone, "@", map[string]interface{}{}, nil)
 		if err != nil {
 			return err
 		}
@@ -49,5 +62,14 @@ func UpdateSOASerial(zoneName string) error {
 	}
 
 	existing.Serial = internal.NextSerial(existing.Serial)
-	return store.AddRecord(sanitizedZone, string(types.TypeSOA), "@", existing) //TODO: why not use zone.AddRecord?
+	if err := store.AddRecord(sanitizedZone, string(types.TypeSOA), "@", existing); err != nil { //TODO: why not use zone.AddRecord?
+		return err
+	}
+	if zones.View() == "" {
+		if err := RefreshZONEMD(sanitizedZone); err != nil {
+			log.Printf("[zonemd] %s: cannot publish ZONEMD: %v", sanitizedZone, err)
+		}
+		RecordIXFRJournal(sanitizedZone)
+	}
+	return nil
 }
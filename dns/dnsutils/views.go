@@ -0,0 +1,122 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: views.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/distributed"
+	"go53/internal"
+	"go53/types"
+	"go53/wal"
+	"go53/zone"
+)
+
+// RecordViewRRset appends the RRset of rrtype at name, as now stored in the
+// view, to the WAL and publishes it to distributed peers, or records its
+// removal when the view no longer holds it. name may be relative to zoneName
+// or fully qualified.
+func RecordViewRRset(zones zone.Scope, zoneName, rrtype, name string) error {
+	mem := zones.Store()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	zoneKey, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return err
+	}
+	rrtype = strings.ToUpper(rrtype)
+	name = viewRecordName(zoneKey, rrtype, name)
+
+	_, _, value, ok := mem.GetRecord(zoneKey, rrtype, name)
+	if !ok {
+		if _, err := wal.Append(wal.KindViewRecord, wal.OpDelete, zoneKey, rrtype, name, "", zones.View(), nil); err != nil {
+			return err
+		}
+		if distributed.Default == nil || !distributed.Enabled() {
+			return nil
+		}
+		return distributed.Default.PublishViewDelete(zones.View(), zoneKey, rrtype, name)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := wal.Append(wal.KindViewRecord, wal.OpUpsert, zoneKey, rrtype, name, "", zones.View(), raw); err != nil {
+		return err
+	}
+	if distributed.Default == nil || !distributed.Enabled() {
+		return nil
+	}
+	return distributed.Default.PublishViewUpsert(zones.View(), zoneKey, rrtype, name, value)
+}
+
+// RecordViewZoneDelete records the removal of zoneName from the view.
+func RecordViewZoneDelete(zones zone.Scope, zoneName string) error {
+	zoneKey, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return err
+	}
+	if _, err := wal.Append(wal.KindViewZone, wal.OpDelete, zoneKey, "", "", "", zones.View(), nil); err != nil {
+		return err
+	}
+	if distributed.Default == nil || !distributed.Enabled() {
+		return nil
+	}
+	return distributed.Default.PublishViewZoneDelete(zones.View(), zoneKey)
+}
+
+// RecordViewZone records zoneName after it was replaced in the view: the old
+// copy is removed and every RRset now stored is written again. Signatures and
+// denial chains are left out, as each node signs its views itself.
+func RecordViewZone(zones zone.Scope, zoneName string) error {
+	mem := zones.Store()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	zoneKey, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return err
+	}
+	if err := RecordViewZoneDelete(zones, zoneKey); err != nil {
+		return err
+	}
+	for rrtype, names := range mem.ZoneRecordsSnapshot(zoneKey) {
+		switch rrtype {
+		case string(types.TypeRRSIG), string(types.TypeNSEC), string(types.TypeNSEC3):
+			continue
+		}
+		for name := range names {
+			if err := RecordViewRRset(zones, zoneKey, rrtype, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func viewRecordName(zoneName, rrtype, name string) string {
+	switch {
+	case rrtype == string(types.TypeSOA), strings.TrimSpace(name) == "":
+		return "@"
+	case dns.IsFqdn(name):
+		return updateRelativeName(zoneName, name)
+	}
+	return name
+}
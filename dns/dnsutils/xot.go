@@ -0,0 +1,161 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: xot.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+// xotDefaultPort is the DNS-over-TLS port XoT primaries listen on.
+const xotDefaultPort = 853
+
+var errSPKIPinMismatch = errors.New("primary certificate does not match any pinned SPKI")
+
+// newZoneTransfer prepares a transfer from primary and returns the address to
+// dial. With xot.fetch_over_tls the transfer runs over TLS (RFC 9103) on the
+// configured XoT port instead of the primary's cleartext port.
+func newZoneTransfer(primary catalogPrimary) (*dns.Transfer, string, error) {
+	tran := &dns.Transfer{
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	xot := config.AppConfig.GetLive().XoT
+	if !xot.FetchOverTLS {
+		return tran, primary.addr(), nil
+	}
+
+	tlsConfig, err := xotClientTLSConfig(primary, xot)
+	if err != nil {
+		return nil, "", err
+	}
+	tran.TLS = tlsConfig
+	port := xot.FetchPort
+	if port == 0 {
+		port = xotDefaultPort
+	}
+	return tran, net.JoinHostPort(primary.IP, strconv.Itoa(port)), nil
+}
+
+// xotClientTLSConfig builds the TLS client configuration for pulling from
+// primary. The primary is authenticated against FetchCAFile (or the system
+// roots) and FetchServerName. When SPKI pins are configured the certificate
+// must also match one of them; pins without a CA file authenticate the primary
+// on their own, which suits self-signed primaries.
+func xotClientTLSConfig(primary catalogPrimary, xot config.XoTConfig) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"dot"},
+		ServerName: strings.TrimSuffix(strings.TrimSpace(xot.FetchServerName), "."),
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = primary.IP
+	}
+
+	if xot.FetchCAFile != "" {
+		pem, err := os.ReadFile(xot.FetchCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read XoT trust anchors: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", xot.FetchCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if xot.FetchClientCertFile != "" || xot.FetchClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(xot.FetchClientCertFile, xot.FetchClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load XoT client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	pins, err := parseSPKIPins(xot.FetchSPKIPins)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) > 0 {
+		if xot.FetchCAFile == "" {
+			// The pin is the trust anchor; chain and name checks are skipped,
+			// so only the certificate whose key the handshake proved counts.
+			cfg.InsecureSkipVerify = true
+			cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 {
+					return errSPKIPinMismatch
+				}
+				return verifySPKIPins(cs.PeerCertificates[:1], pins)
+			}
+		} else {
+			cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+				for _, chain := range cs.VerifiedChains {
+					if verifySPKIPins(chain, pins) == nil {
+						return nil
+					}
+				}
+				return errSPKIPinMismatch
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func parseSPKIPins(raw []string) ([][]byte, error) {
+	var pins [][]byte
+	for _, pin := range raw {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid SPKI pin %q: want base64 of a SHA-256 digest", pin)
+		}
+		pins = append(pins, decoded)
+	}
+	return pins, nil
+}
+
+// verifySPKIPins accepts the chain when any of its certificates'
+// SubjectPublicKeyInfo hashes to one of pins. With a CA the chains the CA
+// verified are passed, so the primary's own key or an intermediate can be
+// pinned; without one only the leaf is, since the other certificates the
+// primary sends are unauthenticated.
+func verifySPKIPins(chain []*x509.Certificate, pins [][]byte) error {
+	for _, cert := range chain {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if string(sum[:]) == string(pin) {
+				return nil
+			}
+		}
+	}
+	return errSPKIPinMismatch
+}
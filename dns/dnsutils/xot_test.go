@@ -0,0 +1,143 @@
+package dnsutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+)
+
+func TestFetchZoneFromPrimaryOverTLSWithPinnedSPKI(t *testing.T) {
+	setupIXFRClientZone(t)
+	cert := xotTestCertificate(t)
+	startXoTTestPrimary(t, 15365, cert)
+
+	sum := sha256.Sum256(cert.Leaf.RawSubjectPublicKeyInfo)
+	live := config.AppConfig.LiveForTest()
+	live.XoT.FetchOverTLS = true
+	live.XoT.FetchPort = 15365
+	live.XoT.FetchSPKIPins = []string{base64.StdEncoding.EncodeToString(sum[:])}
+
+	// The cleartext port points nowhere, so success proves the TLS path was used.
+	primary := catalogPrimary{IP: "127.0.0.1", Port: 1}
+	if !fetchZoneFromPrimary(ixfrClientZone, primary) {
+		t.Fatalf("fetchZoneFromPrimary over TLS failed")
+	}
+	if serial, _ := localZoneSerial(ixfrClientZone); serial != 102 {
+		t.Fatalf("serial = %d, want 102", serial)
+	}
+}
+
+func TestFetchZoneFromPrimaryOverTLSRejectsWrongPin(t *testing.T) {
+	setupIXFRClientZone(t)
+	startXoTTestPrimary(t, 15366, xotTestCertificate(t))
+
+	wrong := sha256.Sum256([]byte("some other key"))
+	live := config.AppConfig.LiveForTest()
+	live.XoT.FetchOverTLS = true
+	live.XoT.FetchPort = 15366
+	live.XoT.FetchSPKIPins = []string{base64.StdEncoding.EncodeToString(wrong[:])}
+
+	if fetchZoneFromPrimary(ixfrClientZone, catalogPrimary{IP: "127.0.0.1"}) {
+		t.Fatalf("fetch from a primary with an unpinned key must fail")
+	}
+	if serial, _ := localZoneSerial(ixfrClientZone); serial != 100 {
+		t.Fatalf("serial = %d, want unchanged 100", serial)
+	}
+}
+
+func TestFetchZoneFromPrimaryOverTLSPinsOnlyTheLeafWithoutCA(t *testing.T) {
+	setupIXFRClientZone(t)
+	foreign := xotTestCertificate(t)
+	pinned := xotTestCertificate(t)
+	// The primary sends someone else's leaf followed by the pinned certificate,
+	// which it holds no key for.
+	foreign.Certificate = append(foreign.Certificate, pinned.Certificate...)
+	startXoTTestPrimary(t, 15380, foreign)
+
+	sum := sha256.Sum256(pinned.Leaf.RawSubjectPublicKeyInfo)
+	live := config.AppConfig.LiveForTest()
+	live.XoT.FetchOverTLS = true
+	live.XoT.FetchPort = 15380
+	live.XoT.FetchSPKIPins = []string{base64.StdEncoding.EncodeToString(sum[:])}
+
+	if fetchZoneFromPrimary(ixfrClientZone, catalogPrimary{IP: "127.0.0.1"}) {
+		t.Fatalf("fetch must fail when the pin matches only a certificate after the leaf")
+	}
+	if serial, _ := localZoneSerial(ixfrClientZone); serial != 100 {
+		t.Fatalf("serial = %d, want unchanged 100", serial)
+	}
+}
+
+func TestXoTClientTLSConfigRejectsMalformedPins(t *testing.T) {
+	xot := config.XoTConfig{FetchOverTLS: true, FetchSPKIPins: []string{"c2hvcnQ="}}
+	if _, err := xotClientTLSConfig(catalogPrimary{IP: "192.0.2.1"}, xot); err == nil {
+		t.Fatalf("pin that is not a SHA-256 digest must be rejected")
+	}
+}
+
+// startXoTTestPrimary serves the full ixfr-client.test zone at serial 102 over
+// TLS with ALPN "dot", answering IXFR in AXFR form.
+func startXoTTestPrimary(t *testing.T, port int, cert tls.Certificate) {
+	t.Helper()
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{
+			ixfrClientSOA(t, 102),
+			mustUpdateRR(t, "www.ixfr-client.test. 300 IN A 192.0.2.1"),
+			ixfrClientSOA(t, 102),
+		}
+		_ = w.WriteMsg(m)
+	})
+	srv := &dns.Server{
+		Addr:      "127.0.0.1:" + strconv.Itoa(port),
+		Net:       "tcp-tls",
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"dot"}},
+		Handler:   handler,
+	}
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("XoT test primary did not start")
+	}
+}
+
+func xotTestCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "primary.ixfr-client.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
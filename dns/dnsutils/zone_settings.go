@@ -0,0 +1,203 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: zone_settings.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go53/config"
+	"go53/internal"
+	"go53/security"
+	"go53/zonemeta"
+)
+
+// zoneSettings returns the per-zone settings of zoneName, empty when it has
+// none or they cannot be read.
+func zoneSettings(zoneName string) zonemeta.Settings {
+	meta, err := zonemeta.Load(zoneName)
+	if err != nil {
+		log.Printf("[zone-settings] cannot load settings of %s: %v", zoneName, err)
+		return zonemeta.Settings{}
+	}
+	return meta.Settings
+}
+
+// zonePrimaries returns the primaries configured for zoneName itself.
+func zonePrimaries(zoneName string) []catalogPrimary {
+	var out []catalogPrimary
+	for _, p := range zoneSettings(zoneName).Primaries {
+		out = append(out, catalogPrimary{IP: p.IP, Port: p.Port, TSIGKeyName: p.TSIGKey})
+	}
+	return out
+}
+
+// settingsSecondaryZones returns the zones that have primaries of their own,
+// so a secondary fetches them before they exist locally.
+func settingsSecondaryZones() []string {
+	metas, err := zonemeta.List()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, meta := range metas {
+		if len(meta.Primaries) == 0 {
+			continue
+		}
+		if f, err := internal.SanitizeFQDN(meta.Zone); err == nil && f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// secondaryZoneFilter returns a test for whether a zone has the secondary
+// role, reading the zone metadata once for a whole sweep.
+func secondaryZoneFilter() func(string) bool {
+	metas, err := zonemeta.List()
+	if err != nil {
+		return zonemeta.IsSecondary
+	}
+	roles := make(map[string]string, len(metas))
+	for _, meta := range metas {
+		if f, err := internal.SanitizeFQDN(meta.Zone); err == nil {
+			roles[strings.ToLower(f)] = meta.EffectiveRole()
+		}
+	}
+	mode := config.AppConfig.GetLive().Mode
+	return func(zoneName string) bool {
+		role, ok := roles[strings.ToLower(zoneName)]
+		if !ok {
+			role = mode
+		}
+		return role == zonemeta.RoleSecondary
+	}
+}
+
+// HasTransferPrimary reports whether zoneName has a primary to transfer from:
+// its own, one from the catalog or the global primary.
+func HasTransferPrimary(zoneName string) bool {
+	return hasTransferPrimaryForZone(zoneName, config.AppConfig.GetLive())
+}
+
+// NotifyAllowedFromPrimary reports whether remoteIP is a primary of zoneName:
+// one of its configured primaries or, without those, a catalog primary.
+func NotifyAllowedFromPrimary(zoneName, remoteIP string) bool {
+	primaries := zonePrimaries(zoneName)
+	if len(primaries) == 0 {
+		return catalogNotifyAllowed(zoneName, remoteIP)
+	}
+	ip := net.ParseIP(remoteIP)
+	for _, p := range primaries {
+		if ip != nil && ip.Equal(net.ParseIP(p.IP)) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyTarget is a server NOTIFY is sent to and the TSIG key to sign with.
+type notifyTarget struct {
+	Addr    string
+	TSIGKey string
+}
+
+// notifyTargets returns where NOTIFY for zoneName goes and the local address
+// to send from. A zone with also_notify or allow_transfer settings notifies
+// its also_notify servers and the single hosts of its ACL, each with its own
+// key. Other zones notify the global allow_transfer list, signed with the
+// transfer key when enforce_tsig is set.
+func notifyTargets(zoneName string, live config.LiveConfig) ([]notifyTarget, string) {
+	settings := zoneSettings(zoneName)
+	var targets []notifyTarget
+	seen := make(map[string]bool)
+	add := func(addr, key string) {
+		if seen[addr] {
+			return
+		}
+		seen[addr] = true
+		targets = append(targets, notifyTarget{Addr: addr, TSIGKey: key})
+	}
+
+	if len(settings.AlsoNotify) > 0 || len(settings.AllowTransfer) > 0 {
+		for _, server := range settings.AlsoNotify {
+			add(server.Addr(), server.TSIGKey)
+		}
+		for _, entry := range settings.AllowTransfer {
+			if ip, ok := entry.Host(); ok {
+				add(net.JoinHostPort(ip.String(), "53"), entry.TSIGKey)
+			}
+		}
+		return targets, settings.NotifySource
+	}
+
+	key := ""
+	if live.EnforceTSIG {
+		fqdnKeyName, _ := internal.SanitizeFQDN(transferTSIGKeyName)
+		if _, ok := security.GetTSIGKey(fqdnKeyName); ok {
+			key = fqdnKeyName
+		}
+	}
+	for _, target := range strings.Split(live.AllowTransfer, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		addr := target
+		if !strings.Contains(addr, ":") {
+			addr += ":53"
+		}
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			log.Printf("SendNotify: invalid address format '%s': %v", addr, err)
+			continue
+		}
+		if ip := net.ParseIP(host); ip == nil {
+			log.Printf("SendNotify: invalid IP address '%s'", host)
+			continue
+		}
+		add(addr, key)
+	}
+	return targets, settings.NotifySource
+}
+
+// notifyDialer binds outgoing NOTIFY to source (IP or IP:port; the port only
+// applies to UDP), or returns nil to let the system choose.
+func notifyDialer(source, network string, timeout time.Duration) *net.Dialer {
+	if source == "" {
+		return nil
+	}
+	host, portStr, err := net.SplitHostPort(source)
+	if err != nil {
+		host, portStr = source, "0"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	port, _ := strconv.Atoi(portStr)
+	d := &net.Dialer{Timeout: timeout}
+	if network == "tcp" {
+		// A fixed TCP source port would collide with its own TIME_WAIT.
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	} else {
+		d.LocalAddr = &net.UDPAddr{IP: ip, Port: port}
+	}
+	return d
+}
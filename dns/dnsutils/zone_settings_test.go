@@ -0,0 +1,120 @@
+package dnsutils
+
+import (
+	"testing"
+
+	"go53/config"
+	"go53/zonemeta"
+)
+
+func TestZoneSettingsOverrideGlobalPrimaryAndNotify(t *testing.T) {
+	setupCatalogTestStore(t, "secondary")
+	live := config.AppConfig.LiveForTest()
+	live.Secondary.CatalogEnabled = false
+	live.Primary.Ip = "192.0.2.1"
+	live.AllowTransfer = "192.0.2.50"
+
+	if got := transferPrimariesForZone("plain.test."); len(got) != 1 || got[0].IP != "192.0.2.1" {
+		t.Fatalf("global primaries = %+v", got)
+	}
+	if targets, source := notifyTargets("plain.test.", *live); len(targets) != 1 || targets[0].Addr != "192.0.2.50:53" || source != "" {
+		t.Fatalf("global notify targets = %+v, %q", targets, source)
+	}
+
+	settings := zonemeta.Settings{
+		Primaries: []zonemeta.Server{{IP: "198.51.100.1", Port: 5353, TSIGKey: "cust-a."}, {IP: "198.51.100.2"}},
+		AllowTransfer: []zonemeta.TransferACLEntry{
+			{Address: "203.0.113.10", TSIGKey: "cust-a."},
+			{Address: "203.0.113.0/24"},
+		},
+		AlsoNotify:   []zonemeta.Server{{IP: "203.0.113.99", Port: 5300}, {IP: "203.0.113.10"}},
+		NotifySource: "192.0.2.200",
+	}
+	if err := zonemeta.SaveSettings("customer.test", settings); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+
+	got := transferPrimariesForZone("customer.test.")
+	if len(got) != 2 || got[0].addr() != "198.51.100.1:5353" || got[0].TSIGKeyName != "cust-a." || got[1].addr() != "198.51.100.2:53" {
+		t.Fatalf("zone primaries = %+v", got)
+	}
+	if !NotifyAllowedFromPrimary("customer.test.", "198.51.100.2") || NotifyAllowedFromPrimary("customer.test.", "192.0.2.1") {
+		t.Fatalf("NOTIFY acceptance does not follow the zone primaries")
+	}
+
+	targets, source := notifyTargets("customer.test.", *live)
+	want := []notifyTarget{{Addr: "203.0.113.99:5300"}, {Addr: "203.0.113.10:53"}}
+	if len(targets) != len(want) || source != "192.0.2.200" {
+		t.Fatalf("zone notify targets = %+v, %q", targets, source)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Fatalf("target %d = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+
+	found := false
+	for _, z := range refreshZoneUnion() {
+		found = found || z == "customer.test."
+	}
+	if !found {
+		t.Fatalf("zone with its own primaries missing from the refresh sweep")
+	}
+	if zone, ok := PendingSecondaryZone("www.customer.test."); !ok || zone != "customer.test." {
+		t.Fatalf("PendingSecondaryZone = %q, %v", zone, ok)
+	}
+}
+
+func TestNotifyDialerBindsSource(t *testing.T) {
+	if d := notifyDialer("", "udp", 0); d != nil {
+		t.Fatalf("dialer without source = %+v", d)
+	}
+	if d := notifyDialer("192.0.2.200:5300", "udp", 0); d == nil || d.LocalAddr.String() != "192.0.2.200:5300" {
+		t.Fatalf("UDP dialer = %+v", d)
+	}
+	if d := notifyDialer("192.0.2.200:5300", "tcp", 0); d == nil || d.LocalAddr.String() != "192.0.2.200:0" {
+		t.Fatalf("TCP dialer = %+v", d)
+	}
+}
+
+func TestZoneRoleSelectsSecondaryZones(t *testing.T) {
+	setupCatalogTestStore(t, "primary")
+	live := config.AppConfig.LiveForTest()
+	live.Secondary.CatalogEnabled = false
+	live.Primary.Ip = ""
+
+	if secondaryEnabled(*live) || len(refreshZoneUnion()) != 0 {
+		t.Fatalf("primary server without secondary zones runs the refresh: %v", refreshZoneUnion())
+	}
+	if err := zonemeta.SetRole("partner.test.", zonemeta.RoleSecondary); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	if err := zonemeta.SaveSettings("partner.test.", zonemeta.Settings{Primaries: []zonemeta.Server{{IP: "198.51.100.1"}}}); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+	if !secondaryEnabled(*live) {
+		t.Fatalf("secondary zone does not enable the refresh")
+	}
+	if got := refreshZoneUnion(); len(got) != 1 || got[0] != "partner.test." {
+		t.Fatalf("refresh union = %v", got)
+	}
+	if zone, ok := PendingSecondaryZone("www.partner.test."); !ok || zone != "partner.test." {
+		t.Fatalf("PendingSecondaryZone = %q, %v", zone, ok)
+	}
+	if !NotifyAllowedFromPrimary("partner.test.", "198.51.100.1") {
+		t.Fatalf("NOTIFY from the zone primary refused")
+	}
+
+	live.Mode = "secondary"
+	if err := zonemeta.SetRole("own.test.", zonemeta.RolePrimary); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	live.Secondary.Zones = []string{"own.test.", "other.test."}
+	got := map[string]bool{}
+	for _, z := range refreshZoneUnion() {
+		got[z] = true
+	}
+	if got["own.test."] || !got["other.test."] || !got["partner.test."] {
+		t.Fatalf("refresh union on a secondary server = %v", got)
+	}
+}
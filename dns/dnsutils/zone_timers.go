@@ -0,0 +1,371 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: zone_timers.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/storage"
+	"go53/zonemeta"
+)
+
+// SecondaryTimersTable holds the refresh state of every secondary zone, so an
+// expired zone stays expired across a restart.
+const SecondaryTimersTable = "secondary-timers"
+
+// SecondaryZoneStatus is the refresh state of a secondary zone. RFC 1035
+// section 4.3.5: the zone is checked every SOA refresh seconds, every retry
+// seconds after a failure, and stops being served once expire seconds pass
+// without a successful check. Times are Unix seconds, zero when unknown.
+type SecondaryZoneStatus struct {
+	Zone         string `json:"zone"`
+	Serial       uint32 `json:"serial"`
+	Refresh      uint32 `json:"refresh"`
+	Retry        uint32 `json:"retry"`
+	Expire       uint32 `json:"expire"`
+	LastCheck    int64  `json:"last_check_at,omitempty"`
+	LastSuccess  int64  `json:"last_success_at,omitempty"`
+	LastTransfer int64  `json:"last_transfer_at,omitempty"`
+	NextCheck    int64  `json:"next_check_at,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+	Expired      bool   `json:"expired"`
+
+	// expireOption is the EDNS EXPIRE value of the check that found a newer
+	// serial, applied once the transfer it triggered succeeds.
+	expireOption uint32
+}
+
+var (
+	timersMu        sync.Mutex
+	secondaryTimers = map[string]*SecondaryZoneStatus{}
+	// timersBackend is the storage secondaryTimers was loaded from, so a
+	// replaced backend is read again.
+	timersBackend storage.Storage
+
+	timerNow = time.Now
+)
+
+// loadSecondaryTimersLocked reads the persisted timers on first use of a
+// storage backend. Callers hold timersMu.
+func loadSecondaryTimersLocked() {
+	if storage.Backend == nil || timersBackend == storage.Backend {
+		return
+	}
+	timersBackend = storage.Backend
+	secondaryTimers = map[string]*SecondaryZoneStatus{}
+	raw, err := storage.Backend.LoadTable(SecondaryTimersTable)
+	if err != nil {
+		log.Printf("[zone-timers] failed to load timers: %v", err)
+		return
+	}
+	for zoneName, data := range raw {
+		var st SecondaryZoneStatus
+		if err := json.Unmarshal(data, &st); err != nil {
+			log.Printf("[zone-timers] dropping unreadable timers of %s: %v", zoneName, err)
+			continue
+		}
+		secondaryTimers[zoneName] = &st
+	}
+}
+
+// timerStateLocked returns the state of fqdn, creating it. Callers hold
+// timersMu.
+func timerStateLocked(fqdn string) *SecondaryZoneStatus {
+	loadSecondaryTimersLocked()
+	st, ok := secondaryTimers[fqdn]
+	if !ok {
+		st = &SecondaryZoneStatus{Zone: fqdn}
+		secondaryTimers[fqdn] = st
+	}
+	return st
+}
+
+func persistSecondaryTimers(st SecondaryZoneStatus) {
+	if storage.Backend == nil {
+		return
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		log.Printf("[zone-timers] failed to encode timers of %s: %v", st.Zone, err)
+		return
+	}
+	if err := storage.Backend.SaveTable(SecondaryTimersTable, st.Zone, data); err != nil {
+		log.Printf("[zone-timers] failed to persist timers of %s: %v", st.Zone, err)
+	}
+}
+
+// updateSecondaryTimers applies fn to the state of zoneName and persists the
+// result.
+func updateSecondaryTimers(zoneName string, fn func(st *SecondaryZoneStatus, now time.Time)) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil || fqdn == "" {
+		return
+	}
+	timersMu.Lock()
+	st := timerStateLocked(fqdn)
+	fn(st, timerNow())
+	snapshot := *st
+	timersMu.Unlock()
+	persistSecondaryTimers(snapshot)
+}
+
+// localTimerSOA returns the SOA of the local copy of zoneName, read before
+// timersMu is taken.
+func localTimerSOA(zoneName string) (*dns.SOA, bool) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return nil, false
+	}
+	return localZoneSOA(fqdn)
+}
+
+// setSOATimers copies the timer fields of soa into st.
+func (st *SecondaryZoneStatus) setSOATimers(soa *dns.SOA) {
+	st.Serial = soa.Serial
+	st.Refresh = soa.Refresh
+	st.Retry = soa.Retry
+	st.Expire = soa.Expire
+}
+
+// refreshInterval and retryInterval return the SOA timers of st within the
+// secondary.min/max_refresh_sec and min/max_retry_sec bounds.
+func (st *SecondaryZoneStatus) refreshInterval(live config.LiveConfig) time.Duration {
+	return clampTimer(st.Refresh, live.Secondary.MinRefreshSec, live.Secondary.MaxRefreshSec)
+}
+
+func (st *SecondaryZoneStatus) retryInterval(live config.LiveConfig) time.Duration {
+	return clampTimer(st.Retry, live.Secondary.MinRetrySec, live.Secondary.MaxRetrySec)
+}
+
+// clampTimer bounds seconds by minSec and maxSec, where 0 leaves that side
+// open. It never returns less than a second so a zone with zero timers does
+// not spin.
+func clampTimer(seconds uint32, minSec, maxSec int) time.Duration {
+	v := int64(seconds)
+	if minSec > 0 && v < int64(minSec) {
+		v = int64(minSec)
+	}
+	if maxSec > 0 && v > int64(maxSec) {
+		v = int64(maxSec)
+	}
+	if v < 1 {
+		v = 1
+	}
+	return time.Duration(v) * time.Second
+}
+
+// recordSOACheck records an answered SOA query for zoneName. When the primary
+// has no newer serial the zone is current: the expire timer restarts, from
+// the EDNS EXPIRE value when the primary sent one (RFC 7314 section 4), and
+// the next check is a refresh interval away. A newer serial leads to a
+// transfer; until that succeeds the zone is checked again after retry.
+func recordSOACheck(zoneName string, soa *dns.SOA, expireOption uint32, hasExpireOption, newer bool) {
+	live := config.AppConfig.GetLive()
+	local, haveLocal := localTimerSOA(zoneName)
+	updateSecondaryTimers(zoneName, func(st *SecondaryZoneStatus, now time.Time) {
+		st.LastCheck = now.Unix()
+		if newer {
+			if st.Serial == 0 {
+				st.setSOATimers(soa)
+			}
+			st.expireOption = 0
+			if hasExpireOption {
+				st.expireOption = expireOption
+			}
+			st.NextCheck = now.Add(st.retryInterval(live)).Unix()
+			return
+		}
+		if haveLocal {
+			st.setSOATimers(local)
+		} else {
+			st.setSOATimers(soa)
+		}
+		st.LastSuccess = now.Unix()
+		st.LastError = ""
+		st.NextCheck = now.Add(st.refreshInterval(live)).Unix()
+		expire := int64(st.Expire)
+		if hasExpireOption {
+			expire = int64(expireOption)
+		}
+		st.ExpiresAt = now.Unix() + expire
+	})
+}
+
+// recordCheckFailure records that no primary answered the SOA query for
+// zoneName. The zone is checked again after retry; a zone seen for the first
+// time starts its expire timer from the local SOA.
+func recordCheckFailure(zoneName, reason string) {
+	live := config.AppConfig.GetLive()
+	local, haveLocal := localTimerSOA(zoneName)
+	updateSecondaryTimers(zoneName, func(st *SecondaryZoneStatus, now time.Time) {
+		if haveLocal && st.Serial == 0 {
+			st.setSOATimers(local)
+		}
+		st.LastCheck = now.Unix()
+		st.LastError = reason
+		st.NextCheck = now.Add(st.retryInterval(live)).Unix()
+		if st.ExpiresAt == 0 && st.Expire > 0 {
+			st.ExpiresAt = now.Unix() + int64(st.Expire)
+		}
+	})
+}
+
+// recordTransfer records a successful transfer of zoneName and restarts its
+// timers from the new SOA.
+func recordTransfer(zoneName string) {
+	live := config.AppConfig.GetLive()
+	local, haveLocal := localTimerSOA(zoneName)
+	updateSecondaryTimers(zoneName, func(st *SecondaryZoneStatus, now time.Time) {
+		if haveLocal {
+			st.setSOATimers(local)
+		}
+		expire := int64(st.Expire)
+		if st.expireOption > 0 {
+			expire = int64(st.expireOption)
+			st.expireOption = 0
+		}
+		st.LastTransfer = now.Unix()
+		st.LastSuccess = now.Unix()
+		st.LastError = ""
+		st.NextCheck = now.Add(st.refreshInterval(live)).Unix()
+		st.ExpiresAt = now.Unix() + expire
+	})
+}
+
+// recordTransferFailure notes that the transfer after a newer serial failed
+// from every primary. The retry set by the SOA check stands.
+func recordTransferFailure(zoneName string) {
+	updateSecondaryTimers(zoneName, func(st *SecondaryZoneStatus, _ time.Time) {
+		st.LastError = "transfer failed from every primary"
+	})
+}
+
+// SecondaryStatus returns the refresh state of zoneName, if it has been
+// checked since it became a secondary.
+func SecondaryStatus(zoneName string) (SecondaryZoneStatus, bool) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return SecondaryZoneStatus{}, false
+	}
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	loadSecondaryTimersLocked()
+	st, ok := secondaryTimers[fqdn]
+	if !ok {
+		return SecondaryZoneStatus{}, false
+	}
+	out := *st
+	out.Expired = out.ExpiresAt > 0 && timerNow().Unix() >= out.ExpiresAt
+	return out, true
+}
+
+// ZoneExpired reports whether the secondary zone zoneName went past its SOA
+// expire time without reaching a primary. It is no longer authoritative data
+// and must not be served (RFC 1035 section 4.3.5).
+func ZoneExpired(zoneName string) bool {
+	st, ok := SecondaryStatus(zoneName)
+	return ok && st.Expired && zonemeta.IsSecondary(zoneName)
+}
+
+// secondaryExpireRemaining returns the seconds left before the secondary zone
+// zoneName expires, for the EDNS EXPIRE option (RFC 7314 section 3).
+func secondaryExpireRemaining(zoneName string) (uint32, bool) {
+	st, ok := SecondaryStatus(zoneName)
+	if !ok || st.ExpiresAt == 0 || !zonemeta.IsSecondary(zoneName) {
+		return 0, false
+	}
+	left := st.ExpiresAt - timerNow().Unix()
+	if left < 0 {
+		left = 0
+	}
+	return uint32(left), true
+}
+
+// ForgetSecondaryTimers drops the refresh state of zoneName, for a zone that
+// is no longer a secondary or no longer exists.
+func ForgetSecondaryTimers(zoneName string) {
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return
+	}
+	timersMu.Lock()
+	loadSecondaryTimersLocked()
+	delete(secondaryTimers, fqdn)
+	timersMu.Unlock()
+	if storage.Backend == nil {
+		return
+	}
+	if err := storage.Backend.DeleteFromTable(SecondaryTimersTable, fqdn); err != nil {
+		log.Printf("[zone-timers] failed to drop timers of %s: %v", fqdn, err)
+	}
+}
+
+// secondaryCheckDue reports whether zoneName should be checked now: its timers
+// say so, or it has none yet.
+func secondaryCheckDue(zoneName string, now time.Time) bool {
+	st, ok := SecondaryStatus(zoneName)
+	return !ok || st.NextCheck <= now.Unix()
+}
+
+// dueSecondaryZones returns the zones with timers whose next check has come.
+func dueSecondaryZones(now time.Time) []string {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	loadSecondaryTimersLocked()
+	var out []string
+	for zoneName, st := range secondaryTimers {
+		if st.NextCheck > 0 && st.NextCheck <= now.Unix() {
+			out = append(out, zoneName)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// runZoneTimers enqueues every secondary zone whose SOA refresh or retry
+// timer ran out. enqueueFetch keeps its pending guard, so a zone whose check
+// is still running is simply picked up on a later tick.
+func runZoneTimers(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			zones := dueSecondaryZones(timerNow())
+			if len(zones) == 0 {
+				continue
+			}
+			live := config.AppConfig.GetLive()
+			isSecondary := secondaryZoneFilter()
+			for _, z := range zones {
+				if isSecondary(z) && hasTransferPrimaryForZone(z, live) {
+					enqueueFetch(z)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package dnsutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"go53/config"
+)
+
+func setupZoneTimersTest(t *testing.T) *time.Time {
+	t.Helper()
+	setupCatalogTestStore(t, "secondary")
+	config.AppConfig.LiveForTest().Secondary.CatalogEnabled = false
+	now := time.Unix(1_700_000_000, 0)
+	timerNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		timerNow = time.Now
+	})
+	return &now
+}
+
+func timerTestSOA(name string, serial uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+		Ns:      "ns1." + name,
+		Mbox:    "hostmaster." + name,
+		Serial:  serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  7200,
+		Minttl:  300,
+	}
+}
+
+func TestSecondaryTimersFollowSOA(t *testing.T) {
+	now := setupZoneTimersTest(t)
+	start := now.Unix()
+
+	recordSOACheck("timers.test.", timerTestSOA("timers.test.", 5), 0, false, false)
+	st, ok := SecondaryStatus("timers.test.")
+	if !ok {
+		t.Fatalf("no status after SOA check")
+	}
+	if st.NextCheck != start+3600 || st.ExpiresAt != start+7200 || st.LastSuccess != start {
+		t.Fatalf("status after check = %+v", st)
+	}
+	if got := dueSecondaryZones(*now); len(got) != 0 {
+		t.Fatalf("zone due right after a check: %v", got)
+	}
+
+	*now = now.Add(3601 * time.Second)
+	if got := dueSecondaryZones(*now); len(got) != 1 || got[0] != "timers.test." {
+		t.Fatalf("due zones after refresh = %v", got)
+	}
+
+	recordCheckFailure("timers.test.", "timeout")
+	st, _ = SecondaryStatus("timers.test.")
+	if st.NextCheck != now.Unix()+600 || st.ExpiresAt != start+7200 || st.LastError != "timeout" {
+		t.Fatalf("status after failure = %+v", st)
+	}
+	if ZoneExpired("timers.test.") {
+		t.Fatalf("zone expired before its expire time")
+	}
+
+	*now = time.Unix(start+7200, 0)
+	if !ZoneExpired("timers.test.") {
+		t.Fatalf("zone not expired after its expire time")
+	}
+
+	recordSOACheck("timers.test.", timerTestSOA("timers.test.", 5), 60, true, false)
+	st, _ = SecondaryStatus("timers.test.")
+	if st.Expired || st.ExpiresAt != now.Unix()+60 {
+		t.Fatalf("EDNS EXPIRE not applied: %+v", st)
+	}
+}
+
+func TestSecondaryTimersClampAndPersist(t *testing.T) {
+	now := setupZoneTimersTest(t)
+	live := config.AppConfig.LiveForTest()
+	live.Secondary.MinRefreshSec = 7200
+	live.Secondary.MaxRetrySec = 60
+
+	recordSOACheck("clamp.test.", timerTestSOA("clamp.test.", 1), 0, false, false)
+	recordCheckFailure("clamp.test.", "timeout")
+	st, _ := SecondaryStatus("clamp.test.")
+	if st.NextCheck != now.Unix()+60 {
+		t.Fatalf("retry not clamped: next check %d, want %d", st.NextCheck, now.Unix()+60)
+	}
+	recordSOACheck("clamp.test.", timerTestSOA("clamp.test.", 1), 0, false, false)
+	st, _ = SecondaryStatus("clamp.test.")
+	if st.NextCheck != now.Unix()+7200 {
+		t.Fatalf("refresh not clamped: next check %d, want %d", st.NextCheck, now.Unix()+7200)
+	}
+
+	timersMu.Lock()
+	secondaryTimers = map[string]*SecondaryZoneStatus{}
+	timersBackend = nil
+	timersMu.Unlock()
+	if reloaded, ok := SecondaryStatus("clamp.test."); !ok || reloaded.ExpiresAt != st.ExpiresAt {
+		t.Fatalf("timers not restored from storage: %+v", reloaded)
+	}
+
+	ForgetSecondaryTimers("clamp.test.")
+	if _, ok := SecondaryStatus("clamp.test."); ok {
+		t.Fatalf("timers kept after ForgetSecondaryTimers")
+	}
+}
+
+func TestCheckSOAHonorsEDNSExpire(t *testing.T) {
+	setupZoneTimersTest(t)
+	config.AppConfig.LiveForTest().Secondary.MinRefreshSec = 0
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{timerTestSOA(r.Question[0].Name, 42)}
+		if opt := r.IsEdns0(); opt != nil {
+			for _, o := range opt.Option {
+				if _, ok := o.(*dns.EDNS0_EXPIRE); ok {
+					m.SetEdns0(dns.DefaultMsgSize, false)
+					resp := m.IsEdns0()
+					resp.Option = append(resp.Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Expire: 1234})
+				}
+			}
+		}
+		_ = w.WriteMsg(m)
+	})
+	started := make(chan struct{})
+	srv := &dns.Server{Addr: "127.0.0.1:15367", Net: "udp", Handler: handler}
+	srv.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	<-started
+
+	newer, err := checkSOAFromPrimary("expire.test.", catalogPrimary{IP: "127.0.0.1", Port: 15367})
+	if err != nil || !newer {
+		t.Fatalf("checkSOAFromPrimary = %v, %v; want newer serial", newer, err)
+	}
+	st, _ := SecondaryStatus("expire.test.")
+	if st.ExpiresAt != 0 || st.NextCheck != timerNow().Unix()+600 {
+		t.Fatalf("status before transfer = %+v", st)
+	}
+
+	addTestSOA(t, "expire.test.")
+	recordTransfer("expire.test.")
+	st, _ = SecondaryStatus("expire.test.")
+	if st.ExpiresAt != timerNow().Unix()+1234 || st.LastTransfer != timerNow().Unix() {
+		t.Fatalf("status after transfer = %+v", st)
+	}
+}
+
+func TestApplyExpireReportsRemainingTime(t *testing.T) {
+	now := setupZoneTimersTest(t)
+	config.AppConfig.LiveForTest().EnableEDNS = true
+
+	req := new(dns.Msg)
+	req.SetQuestion("remaining.test.", dns.TypeSOA)
+	req.SetEdns0(dns.DefaultMsgSize, false)
+	req.IsEdns0().Option = append(req.IsEdns0().Option, &dns.EDNS0_EXPIRE{Code: dns.EDNS0EXPIRE, Empty: true})
+
+	soa := timerTestSOA("remaining.test.", 1)
+	recordSOACheck("remaining.test.", soa, 0, false, false)
+	*now = now.Add(200 * time.Second)
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	ApplyExpire(resp, req, soa)
+	opt := resp.IsEdns0()
+	if opt == nil || len(opt.Option) != 1 {
+		t.Fatalf("no EXPIRE option in response: %v", resp)
+	}
+	if got := opt.Option[0].(*dns.EDNS0_EXPIRE).Expire; got != 7000 {
+		t.Fatalf("EXPIRE = %d, want 7000", got)
+	}
+
+	config.AppConfig.LiveForTest().Mode = "primary"
+	resp = new(dns.Msg)
+	resp.SetReply(req)
+	ApplyExpire(resp, req, soa)
+	if got := resp.IsEdns0().Option[0].(*dns.EDNS0_EXPIRE).Expire; got != soa.Expire {
+		t.Fatalf("primary EXPIRE = %d, want %d", got, soa.Expire)
+	}
+}
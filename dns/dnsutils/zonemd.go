@@ -0,0 +1,168 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: zonemd.go is part of the go53 authoritative DNS server.
+package dnsutils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/memory"
+	"go53/security"
+	"go53/types"
+	"go53/zone/rtypes"
+	"go53/zonemeta"
+)
+
+// RefreshZONEMD recomputes and publishes the apex ZONEMD of zoneName (RFC 8976,
+// SIMPLE scheme with SHA-384) when primary.zonemd is enabled. It runs after
+// every serial change. The first run adds a placeholder so the apex NSEC
+// lists ZONEMD before the digest is taken. The signatures transfers serve,
+// including the key set RRSIGs made on first use, are created before the
+// digest so it covers the zone as a secondary receives it. Signatures renewed
+// later without a serial change are caught by RefreshStaleZONEMD.
+func RefreshZONEMD(zoneName string) error {
+	if !zonemdPublished(zoneName) {
+		return nil
+	}
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return fmt.Errorf("memstore is not initialized")
+	}
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return err
+	}
+	soa, ok := localZoneSOA(fqdn)
+	if !ok {
+		return fmt.Errorf("SOA not found for zone %s", fqdn)
+	}
+
+	rec := types.ZONEMDRecord{
+		Serial: soa.Serial,
+		Scheme: dns.ZoneMDSchemeSimple,
+		Hash:   dns.ZoneMDHashAlgSHA384,
+		Digest: strings.Repeat("00", 48),
+		TTL:    soa.Hdr.Ttl,
+	}
+	if _, _, _, found := mem.GetRecord(fqdn, string(types.TypeZONEMD), "@"); !found {
+		if err := mem.AddRecord(fqdn, string(types.TypeZONEMD), "@", []types.ZONEMDRecord{rec}); err != nil {
+			return err
+		}
+	}
+	if rec.Digest, err = servedZONEMDDigest(mem, fqdn, rec.Hash); err != nil {
+		return err
+	}
+	return mem.PublishZONEMD(fqdn, []types.ZONEMDRecord{rec})
+}
+
+// RefreshStaleZONEMD bumps the serial of zoneName, which publishes a new
+// ZONEMD, when the published digest no longer matches the zone as it is
+// transferred. The digest covers the RRSIGs, so this happens when signatures
+// were renewed without a serial change: on expiry, or when the keys changed.
+// It reports whether the serial was bumped.
+func RefreshStaleZONEMD(zoneName string) (bool, error) {
+	if !zonemdPublished(zoneName) {
+		return false, nil
+	}
+	mem := rtypes.GetMemStore()
+	if mem == nil {
+		return false, fmt.Errorf("memstore is not initialized")
+	}
+	fqdn, err := internal.SanitizeFQDN(zoneName)
+	if err != nil {
+		return false, err
+	}
+	_, _, raw, found := mem.GetRecord(fqdn, string(types.TypeZONEMD), "@")
+	if !found {
+		return false, nil
+	}
+	published := internal.DecodeRecords[types.ZONEMDRecord](raw)
+	if len(published) == 0 {
+		return false, nil
+	}
+	digest, err := servedZONEMDDigest(mem, fqdn, published[0].Hash)
+	if err != nil {
+		return false, err
+	}
+	if strings.EqualFold(digest, published[0].Digest) {
+		return false, nil
+	}
+	if err := UpdateSOASerial(fqdn); err != nil {
+		return false, err
+	}
+	go ScheduleNotify(fqdn)
+	return true, nil
+}
+
+func runZONEMDOnce() {
+	if !config.AppConfig.GetLive().Primary.ZONEMD {
+		return
+	}
+	for _, name := range signedZones() {
+		if _, err := RefreshStaleZONEMD(name); err != nil {
+			log.Printf("[zonemd] %s: %v", name, err)
+		}
+	}
+}
+
+func zonemdPublished(zoneName string) bool {
+	return config.AppConfig.GetLive().Primary.ZONEMD && !zonemeta.IsSecondary(zoneName)
+}
+
+// servedZONEMDDigest digests fqdn as transfers serve it, signing what a
+// transfer would sign first.
+func servedZONEMDDigest(mem *memory.InMemoryZoneStore, fqdn string, hash uint8) (string, error) {
+	mem.WaitForSigning()
+	rrs, err := rtypes.TransferZone(mem, fqdn)
+	if err != nil {
+		return "", err
+	}
+	mem.SignZoneTransferRRsets(rrs)
+	mem.WaitForSigning()
+	if rrs, err = rtypes.TransferZone(mem, fqdn); err != nil {
+		return "", err
+	}
+	return security.ZONEMDDigest(fqdn, rrs, hash)
+}
+
+// checkTransferredZONEMD verifies the ZONEMD of a zone received from a primary
+// before it is swapped in. Zones without ZONEMD, or with only unsupported
+// ones, pass. A digest that does not match is logged, and refused when
+// secondary.zonemd_enforce is set.
+func checkTransferredZONEMD(fqdn string, rrs []dns.RR) error {
+	err := security.VerifyZONEMD(fqdn, rrs)
+	switch {
+	case err == nil:
+		log.Printf("[zonemd] %s: ZONEMD verified", fqdn)
+		return nil
+	case errors.Is(err, security.ErrZONEMDMissing):
+		return nil
+	case errors.Is(err, security.ErrZONEMDUnsupported):
+		log.Printf("[zonemd] %s: %v, zone not verified", fqdn, err)
+		return nil
+	}
+	if config.AppConfig.GetLive().Secondary.ZONEMDEnforce {
+		log.Printf("[zonemd] %s: %v, refusing transfer", fqdn, err)
+		return fmt.Errorf("%s: %w", fqdn, err)
+	}
+	log.Printf("[zonemd] %s: %v, accepting because secondary.zonemd_enforce is off", fqdn, err)
+	return nil
+}
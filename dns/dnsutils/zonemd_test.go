@@ -0,0 +1,229 @@
+package dnsutils
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/memory"
+	"go53/security"
+	"go53/storage"
+	"go53/zone"
+	"go53/zone/rtypes"
+)
+
+const zonemdTestZone = "zonemd.test."
+
+func setupZONEMDPrimary(t *testing.T, dnssec bool) *memory.InMemoryZoneStore {
+	t.Helper()
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	config.AppConfig.LiveForTest().DNSSECEnabled = dnssec
+	config.AppConfig.LiveForTest().Mode = "primary"
+	config.AppConfig.LiveForTest().Primary.ZONEMD = true
+	storage.Backend = &storage.MockStorage{Zones: map[string][]byte{}, Tables: map[string]map[string][]byte{}}
+	store, err := memory.NewZoneStore(storage.Backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	rtypes.InitMemoryStore(store)
+	t.Cleanup(func() {
+		store.WaitForSigning()
+		rtypes.InitMemoryStore(nil)
+	})
+
+	if err := zone.AddRecord(dns.TypeSOA, zonemdTestZone, "@", map[string]interface{}{
+		"ns":      "ns1.zonemd.test.",
+		"mbox":    "hostmaster.zonemd.test.",
+		"serial":  float64(100),
+		"refresh": float64(3600),
+		"retry":   float64(900),
+		"expire":  float64(1209600),
+		"minimum": float64(300),
+	}, ptrUint32(3600)); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	if dnssec {
+		now := time.Now().Unix()
+		for _, role := range []string{"ksk", "zsk"} {
+			if _, _, err := security.GenerateRolloverKey(zonemdTestZone, role, "ED25519", now-10, now-10); err != nil {
+				t.Fatalf("generate %s: %v", role, err)
+			}
+		}
+		if err := store.RefreshDNSSECKeyMaterial(zonemdTestZone); err != nil {
+			t.Fatalf("refresh DNSSEC key material: %v", err)
+		}
+	}
+	if err := zone.AddRecord(dns.TypeA, zonemdTestZone, "www", map[string]interface{}{"ip": "192.0.2.1"}, ptrUint32(300)); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	return store
+}
+
+func TestUpdateSOASerialPublishesZONEMD(t *testing.T) {
+	for _, dnssec := range []bool{false, true} {
+		t.Run("dnssec="+strconv.FormatBool(dnssec), func(t *testing.T) {
+			store := setupZONEMDPrimary(t, dnssec)
+
+			for i := 0; i < 2; i++ {
+				if err := UpdateSOASerial(zonemdTestZone); err != nil {
+					t.Fatalf("UpdateSOASerial: %v", err)
+				}
+				store.WaitForSigning()
+				rrs, err := store.GetZone(zonemdTestZone)
+				if err != nil {
+					t.Fatalf("GetZone: %v", err)
+				}
+				if err := security.VerifyZONEMD(zonemdTestZone, rrs); err != nil {
+					t.Fatalf("round %d: published ZONEMD does not verify: %v", i, err)
+				}
+				if !dnssec {
+					continue
+				}
+				signed := false
+				for _, rr := range rrs {
+					if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == dns.TypeZONEMD {
+						signed = true
+					}
+				}
+				if !signed {
+					t.Fatalf("round %d: ZONEMD RRset is not signed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshStaleZONEMDAfterResigning(t *testing.T) {
+	store := setupZONEMDPrimary(t, true)
+	if err := UpdateSOASerial(zonemdTestZone); err != nil {
+		t.Fatalf("UpdateSOASerial: %v", err)
+	}
+	store.WaitForSigning()
+	if bumped, err := RefreshStaleZONEMD(zonemdTestZone); err != nil || bumped {
+		t.Fatalf("RefreshStaleZONEMD on a current digest = %v, %v", bumped, err)
+	}
+	before, _ := localZoneSOA(zonemdTestZone)
+
+	// New signatures without a serial change leave the digest stale.
+	config.AppConfig.LiveForTest().DNSSEC.InceptionSkewSeconds = 7200
+	if err := store.RefreshDNSSECKeyMaterial(zonemdTestZone); err != nil {
+		t.Fatalf("RefreshDNSSECKeyMaterial: %v", err)
+	}
+	store.WaitForSigning()
+	if rrs, err := store.GetZone(zonemdTestZone); err != nil || security.VerifyZONEMD(zonemdTestZone, rrs) == nil {
+		t.Fatalf("ZONEMD still verifies after re-signing, err = %v", err)
+	}
+
+	if bumped, err := RefreshStaleZONEMD(zonemdTestZone); err != nil || !bumped {
+		t.Fatalf("RefreshStaleZONEMD = %v, %v, want a serial bump", bumped, err)
+	}
+	store.WaitForSigning()
+	after, _ := localZoneSOA(zonemdTestZone)
+	if after.Serial == before.Serial {
+		t.Fatalf("serial not bumped: %d", after.Serial)
+	}
+	rrs, err := store.GetZone(zonemdTestZone)
+	if err != nil {
+		t.Fatalf("GetZone: %v", err)
+	}
+	if err := security.VerifyZONEMD(zonemdTestZone, rrs); err != nil {
+		t.Fatalf("renewed ZONEMD does not verify: %v", err)
+	}
+}
+
+func TestUpdateSOASerialSkipsZONEMDWhenDisabled(t *testing.T) {
+	store := setupZONEMDPrimary(t, false)
+	config.AppConfig.LiveForTest().Primary.ZONEMD = false
+
+	if err := UpdateSOASerial(zonemdTestZone); err != nil {
+		t.Fatalf("UpdateSOASerial: %v", err)
+	}
+	if _, _, _, found := store.GetRecord(zonemdTestZone, "ZONEMD", "@"); found {
+		t.Fatalf("ZONEMD published with primary.zonemd off")
+	}
+}
+
+func TestFetchZoneFromPrimaryChecksZONEMD(t *testing.T) {
+	good := []dns.RR{
+		mustUpdateRR(t, "zonemd.test. 3600 IN SOA ns1.zonemd.test. hostmaster.zonemd.test. 200 3600 900 1209600 300"),
+		mustUpdateRR(t, "www.zonemd.test. 300 IN A 192.0.2.9"),
+	}
+	digest, err := security.ZONEMDDigest(zonemdTestZone, good, dns.ZoneMDHashAlgSHA384)
+	if err != nil {
+		t.Fatalf("ZONEMDDigest: %v", err)
+	}
+	good = append(good, mustUpdateRR(t, "zonemd.test. 3600 IN ZONEMD 200 1 1 "+digest))
+	bad := append([]dns.RR{}, good...)
+	bad[1] = mustUpdateRR(t, "www.zonemd.test. 300 IN A 192.0.2.66")
+
+	cases := []struct {
+		name    string
+		zone    []dns.RR
+		enforce bool
+		want    bool
+		wantIP  string
+	}{
+		{"verified", good, true, true, "192.0.2.9"},
+		{"mismatch enforced", bad, true, false, "192.0.2.1"},
+		{"mismatch logged", bad, false, true, "192.0.2.66"},
+	}
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			setupZONEMDPrimary(t, false)
+			config.AppConfig.LiveForTest().Mode = "secondary"
+			config.AppConfig.LiveForTest().Secondary.ZONEMDEnforce = c.enforce
+			primary := startZONEMDTestPrimary(t, 15371+i, c.zone)
+
+			if got := fetchZoneFromPrimary(zonemdTestZone, primary); got != c.want {
+				t.Fatalf("fetchZoneFromPrimary = %v, want %v", got, c.want)
+			}
+			rrs, ok := zone.LookupRecord(dns.TypeA, "www.zonemd.test.")
+			if !ok || len(rrs) == 0 || rrs[0].(*dns.A).A.String() != c.wantIP {
+				t.Fatalf("www A = %v, want %s", rrs, c.wantIP)
+			}
+		})
+	}
+}
+
+func TestCheckTransferredZONEMDIgnoresUnsignedZones(t *testing.T) {
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	config.AppConfig.LiveForTest().Secondary.ZONEMDEnforce = true
+
+	plain := []dns.RR{mustUpdateRR(t, "zonemd.test. 3600 IN SOA ns1.zonemd.test. hostmaster.zonemd.test. 1 3600 900 1209600 300")}
+	if err := checkTransferredZONEMD(zonemdTestZone, plain); err != nil {
+		t.Fatalf("zone without ZONEMD refused: %v", err)
+	}
+	mismatch := append(plain, mustUpdateRR(t, "zonemd.test. 3600 IN ZONEMD 1 1 1 "+zeroDigest()))
+	if err := checkTransferredZONEMD(zonemdTestZone, mismatch); !errors.Is(err, security.ErrZONEMDMismatch) {
+		t.Fatalf("mismatching ZONEMD: err = %v", err)
+	}
+}
+
+func zeroDigest() string {
+	b := make([]byte, 96)
+	for i := range b {
+		b[i] = '0'
+	}
+	return string(b)
+}
+
+// startZONEMDTestPrimary answers IXFR and AXFR alike with the full zone rrs.
+func startZONEMDTestPrimary(t *testing.T, port int, rrs []dns.RR) catalogPrimary {
+	t.Helper()
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(append([]dns.RR{}, rrs...), rrs[0])
+		_ = w.WriteMsg(m)
+	})
+	srv := &dns.Server{Addr: "127.0.0.1:" + strconv.Itoa(port), Net: "tcp", Handler: handler}
+	go func() { _ = srv.ListenAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	time.Sleep(100 * time.Millisecond)
+	return catalogPrimary{IP: "127.0.0.1", Port: port}
+}
@@ -0,0 +1,261 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: doh.go is part of the go53 authoritative DNS server.
+package dns
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TenforwardAB/slog"
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/dns/dnsutils"
+	"go53/security"
+)
+
+const (
+	// dohPath is the URI template path of the DoH endpoint (RFC 8484 section 3).
+	dohPath = "/dns-query"
+	// dohMediaType is the RFC 8484 media type for DNS wire-format messages.
+	dohMediaType = "application/dns-message"
+)
+
+// dohHandler serves RFC 8484 DNS-over-HTTPS requests. Queries are answered by
+// handleRequest exactly as on UDP/TCP/TLS; zone transfers are refused because
+// a multi-message answer cannot be carried in one HTTP response.
+func dohHandler(rw http.ResponseWriter, r *http.Request) {
+	raw, status := dohReadQuery(r)
+	if status != http.StatusOK {
+		if status == http.StatusMethodNotAllowed {
+			rw.Header().Set("Allow", "GET, POST")
+		}
+		http.Error(rw, http.StatusText(status), status)
+		return
+	}
+
+	req := new(dns.Msg)
+	if err := req.Unpack(raw); err != nil {
+		http.Error(rw, "malformed DNS message", http.StatusBadRequest)
+		return
+	}
+
+	w := &dohResponseWriter{local: dohLocalAddr(r.Context().Value(http.LocalAddrContextKey)), remote: dohRemoteAddr(r.RemoteAddr), req: req}
+	if req.IsTsig() != nil {
+		w.tsigErr = dns.TsigVerifyWithProvider(raw, security.DynamicTSIGProvider{}, "", false)
+	}
+
+	if len(req.Question) == 1 && (req.Question[0].Qtype == dns.TypeAXFR || req.Question[0].Qtype == dns.TypeIXFR) {
+		m := new(dns.Msg)
+		m.SetRcode(req, dns.RcodeRefused)
+		_ = w.WriteMsg(m)
+	} else {
+		handleRequest(w, req)
+	}
+	if w.msg == nil {
+		// The request was dropped (e.g. an unauthorized NOTIFY); tell the client
+		// there is no answer rather than sending an empty message.
+		http.Error(rw, "no response", http.StatusBadGateway)
+		return
+	}
+
+	dnsutils.ApplyPadding(w.msg, req)
+	packed, err := w.pack()
+	if err != nil {
+		slog.Warn("DoH: failed to pack response: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", dohMediaType)
+	rw.Header().Set("Content-Length", strconv.Itoa(len(packed)))
+	if maxAge, ok := dohMaxAge(w.msg); ok {
+		rw.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+	} else {
+		rw.Header().Set("Cache-Control", "no-store")
+	}
+	_, _ = rw.Write(packed)
+}
+
+// dohReadQuery extracts the wire-format query from a GET (base64url "dns"
+// parameter) or POST (application/dns-message body) request and returns the
+// HTTP status to answer with when the request is unusable.
+func dohReadQuery(r *http.Request) ([]byte, int) {
+	switch r.Method {
+	case http.MethodGet:
+		param := r.URL.Query().Get("dns")
+		if param == "" {
+			return nil, http.StatusBadRequest
+		}
+		// RFC 8484 section 6 omits padding, but tolerate clients that send it.
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(param, "="))
+		if err != nil || len(raw) > dns.MaxMsgSize {
+			return nil, http.StatusBadRequest
+		}
+		return raw, http.StatusOK
+	case http.MethodPost:
+		mediaType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+		if !strings.EqualFold(mediaType, dohMediaType) {
+			return nil, http.StatusUnsupportedMediaType
+		}
+		raw, err := io.ReadAll(io.LimitReader(r.Body, dns.MaxMsgSize+1))
+		if err != nil {
+			return nil, http.StatusBadRequest
+		}
+		if len(raw) > dns.MaxMsgSize {
+			return nil, http.StatusRequestEntityTooLarge
+		}
+		return raw, http.StatusOK
+	default:
+		return nil, http.StatusMethodNotAllowed
+	}
+}
+
+// dohMaxAge returns the freshness lifetime of a response (RFC 8484 section
+// 5.1): the smallest TTL of any record in it, where an SOA in the authority
+// section is capped by its MINIMUM field as negative caching does (RFC 2308
+// section 5). Responses without records are not cacheable.
+func dohMaxAge(m *dns.Msg) (uint32, bool) {
+	var maxAge uint32
+	found := false
+	consider := func(ttl uint32) {
+		if !found || ttl < maxAge {
+			maxAge = ttl
+			found = true
+		}
+	}
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			switch rr.Header().Rrtype {
+			case dns.TypeOPT, dns.TypeTSIG:
+				continue
+			}
+			consider(rr.Header().Ttl)
+		}
+	}
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			consider(soa.Minttl)
+		}
+	}
+	return maxAge, found
+}
+
+// dohResponseWriter collects the answer handleRequest writes so it can be
+// returned as the HTTP response body.
+type dohResponseWriter struct {
+	local   net.Addr
+	remote  net.Addr
+	req     *dns.Msg
+	tsigErr error
+	msg     *dns.Msg
+}
+
+func (w *dohResponseWriter) LocalAddr() net.Addr  { return w.local }
+func (w *dohResponseWriter) RemoteAddr() net.Addr { return w.remote }
+
+func (w *dohResponseWriter) WriteMsg(m *dns.Msg) error {
+	if w.msg == nil {
+		w.msg = m
+	}
+	return nil
+}
+
+func (w *dohResponseWriter) Write(b []byte) (int, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(b); err != nil {
+		return 0, err
+	}
+	return len(b), w.WriteMsg(m)
+}
+
+func (w *dohResponseWriter) Close() error        { return nil }
+func (w *dohResponseWriter) TsigStatus() error   { return w.tsigErr }
+func (w *dohResponseWriter) TsigTimersOnly(bool) {}
+func (w *dohResponseWriter) Hijack()             {}
+
+// pack serializes the collected answer, signing it when the handler attached
+// a TSIG record, as the miekg/dns server does for stream transports.
+func (w *dohResponseWriter) pack() ([]byte, error) {
+	if w.msg.IsTsig() == nil {
+		return w.msg.Pack()
+	}
+	requestMAC := ""
+	if t := w.req.IsTsig(); t != nil {
+		requestMAC = t.MAC
+	}
+	packed, _, err := dns.TsigGenerateWithProvider(w.msg, security.DynamicTSIGProvider{}, requestMAC, false)
+	return packed, err
+}
+
+// dohRemoteAddr reports the HTTP client as a TCP peer so UDP-only behaviour
+// such as truncation and per-client rate limiting is not applied.
+func dohRemoteAddr(remote string) net.Addr {
+	host, port, err := net.SplitHostPort(remote)
+	if err != nil {
+		return &net.TCPAddr{}
+	}
+	p, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(host), Port: p}
+}
+
+func dohLocalAddr(v any) net.Addr {
+	if addr, ok := v.(net.Addr); ok {
+		return addr
+	}
+	return &net.TCPAddr{}
+}
+
+// dohEnabled reports whether the DNS-over-HTTPS listener is configured.
+func dohEnabled(cfg config.BaseConfig) bool {
+	return cfg.DoHPort != "" && cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
+}
+
+// buildDoHServer returns the DNS-over-HTTPS listener, or nil when it is not
+// configured. It shares the DNS-over-TLS certificate and reloads it the same
+// way, but is a separate listener from the admin API.
+func buildDoHServer(cfg config.BaseConfig) (string, *http.Server, error) {
+	if !dohEnabled(cfg) {
+		return "", nil, nil
+	}
+	reloader, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return "", nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(dohPath, dohHandler)
+	addr := fmt.Sprintf("%s%s", cfg.BindHost, cfg.DoHPort)
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		},
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       dotIdleTimeout,
+	}
+	return addr, server, nil
+}
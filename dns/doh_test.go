@@ -0,0 +1,169 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mdns "github.com/miekg/dns"
+	"go53/config"
+	"go53/zone"
+)
+
+func TestDoHGetAnswersWithCacheControl(t *testing.T) {
+	setupDoHTestZone(t)
+	config.AppConfig.LiveForTest().NSID = "doh-node"
+
+	req := new(mdns.Msg)
+	req.SetQuestion("www.doh.test.", mdns.TypeA)
+	req.Id = 0
+	req.SetEdns0(1232, false)
+	req.IsEdns0().Option = append(req.IsEdns0().Option, &mdns.EDNS0_NSID{Code: mdns.EDNS0NSID})
+	packed, err := req.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	dohHandler(rec, httptest.NewRequest(http.MethodGet, dohPath+"?dns="+base64.RawURLEncoding.EncodeToString(packed), nil))
+
+	resp := dohTestResponse(t, rec)
+	if resp.Rcode != mdns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Fatalf("DoH answer = %v", resp)
+	}
+	if got, want := rec.Header().Get("Cache-Control"), fmt.Sprintf("max-age=%d", resp.Answer[0].Header().Ttl); got != want {
+		t.Fatalf("Cache-Control = %q, want %q", got, want)
+	}
+	if _, ok := responseNSIDOption(resp); !ok {
+		t.Fatalf("NSID requested over DoH was not returned: %v", resp.Extra)
+	}
+}
+
+func TestDoHPostNegativeAnswerUsesSOAMinimum(t *testing.T) {
+	setupDoHTestZone(t)
+
+	req := new(mdns.Msg)
+	req.SetQuestion("missing.doh.test.", mdns.TypeA)
+	packed, err := req.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	httpReq := httptest.NewRequest(http.MethodPost, dohPath, bytes.NewReader(packed))
+	httpReq.Header.Set("Content-Type", dohMediaType)
+	rec := httptest.NewRecorder()
+	dohHandler(rec, httpReq)
+
+	resp := dohTestResponse(t, rec)
+	if resp.Rcode != mdns.RcodeNameError || resp.Id != req.Id {
+		t.Fatalf("DoH NXDOMAIN = %v", resp)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "max-age=60" {
+		t.Fatalf("Cache-Control = %q, want SOA minimum max-age=60", got)
+	}
+}
+
+func TestDoHRejectsUnusableRequests(t *testing.T) {
+	setupDoHTestZone(t)
+
+	axfr := new(mdns.Msg)
+	axfr.SetAxfr("doh.test.")
+	packed, _ := axfr.Pack()
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        []byte
+		want        int
+	}{
+		{name: "missing dns parameter", method: http.MethodGet, target: dohPath, want: http.StatusBadRequest},
+		{name: "invalid base64url", method: http.MethodGet, target: dohPath + "?dns=***", want: http.StatusBadRequest},
+		{name: "wrong media type", method: http.MethodPost, target: dohPath, contentType: "application/json", body: packed, want: http.StatusUnsupportedMediaType},
+		{name: "malformed message", method: http.MethodPost, target: dohPath, contentType: dohMediaType, body: []byte{1, 2, 3}, want: http.StatusBadRequest},
+		{name: "unsupported method", method: http.MethodPut, target: dohPath, want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpReq := httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				httpReq.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			dohHandler(rec, httpReq)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	dohHandler(rec, httptest.NewRequest(http.MethodGet, dohPath+"?dns="+base64.RawURLEncoding.EncodeToString(packed), nil))
+	if resp := dohTestResponse(t, rec); resp.Rcode != mdns.RcodeRefused {
+		t.Fatalf("AXFR over DoH rcode = %s, want REFUSED", mdns.RcodeToString[resp.Rcode])
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control for a record-less answer = %q, want no-store", got)
+	}
+}
+
+func setupDoHTestZone(t *testing.T) {
+	t.Helper()
+	setupDNSHandlerTestStore(t)
+	config.AppConfig.LiveForTest().AllowAXFR = true
+	soaTTL := uint32(3600)
+	if err := zone.AddRecord(mdns.TypeSOA, "doh.test.", "doh.test.", map[string]interface{}{"ns": "ns1.doh.test.", "mbox": "hostmaster.doh.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(60)}, &soaTTL); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	if err := zone.AddRecord(mdns.TypeA, "doh.test.", "www", map[string]interface{}{"ip": "192.0.2.53"}, &soaTTL); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+}
+
+func dohTestResponse(t *testing.T, rec *httptest.ResponseRecorder) *mdns.Msg {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != dohMediaType {
+		t.Fatalf("Content-Type = %q, want %s", ct, dohMediaType)
+	}
+	resp := new(mdns.Msg)
+	if err := resp.Unpack(rec.Body.Bytes()); err != nil {
+		t.Fatalf("unpack DoH response: %v", err)
+	}
+	return resp
+}
+
+func responseNSIDOption(m *mdns.Msg) (string, bool) {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return "", false
+	}
+	for _, o := range opt.Option {
+		if nsid, ok := o.(*mdns.EDNS0_NSID); ok {
+			return nsid.Nsid, true
+		}
+	}
+	return "", false
+}
+
+func TestDoHMaxAgeUsesSmallestTTL(t *testing.T) {
+	m := new(mdns.Msg)
+	m.Answer = []mdns.RR{
+		&mdns.A{Hdr: mdns.RR_Header{Name: "a.doh.test.", Rrtype: mdns.TypeA, Class: mdns.ClassINET, Ttl: 300}},
+		&mdns.CNAME{Hdr: mdns.RR_Header{Name: "b.doh.test.", Rrtype: mdns.TypeCNAME, Class: mdns.ClassINET, Ttl: 900}, Target: "a.doh.test."},
+	}
+	m.Extra = []mdns.RR{&mdns.AAAA{Hdr: mdns.RR_Header{Name: "ns.doh.test.", Rrtype: mdns.TypeAAAA, Class: mdns.ClassINET, Ttl: 45}}}
+	m.SetEdns0(1232, false)
+
+	if maxAge, ok := dohMaxAge(m); !ok || maxAge != 45 {
+		t.Fatalf("dohMaxAge = %d, %v; want 45", maxAge, ok)
+	}
+	if _, ok := dohMaxAge(new(mdns.Msg)); ok {
+		t.Fatalf("empty response must not be cacheable")
+	}
+}
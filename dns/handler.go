@@ -4,13 +4,16 @@ import (
 	"go53/config"
 	"go53/dns/dnsutils"
 	"go53/internal"
+	"go53/memory"
 	"go53/security"
 	"go53/zone"
+	"go53/zonemeta"
 
 	"github.com/TenforwardAB/slog"
 	"github.com/miekg/dns"
 
 	"encoding/hex"
+	"errors"
 	"net"
 	"strconv"
 	"strings"
@@ -32,7 +35,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		}
 	}
 
-	if r.Opcode == dns.OpcodeNotify && live.Mode == "secondary" {
+	if r.Opcode == dns.OpcodeNotify && notifyForSecondaryZone(r, live) {
 		remoteIP, _, err := net.SplitHostPort(w.RemoteAddr().String())
 		if err != nil {
 			slog.Warn("invalid remote address: %v", w.RemoteAddr())
@@ -54,6 +57,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 				slog.Warn("TSIG key not recognized: %s — rejecting", tsig.Hdr.Name)
 				m := new(dns.Msg)
 				m.SetRcode(r, dns.RcodeRefused)
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "unknown TSIG key "+tsig.Hdr.Name)
 				_ = w.WriteMsg(m)
 				return
 			}
@@ -61,6 +65,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 				slog.Warn("TSIG validation failed: %v", w.TsigStatus())
 				m := new(dns.Msg)
 				m.SetRcode(r, dns.RcodeRefused)
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "TSIG validation failed")
 				_ = w.WriteMsg(m)
 				return
 			}
@@ -71,6 +76,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			slog.Warn("TSIG required but not present — rejecting")
 			m := new(dns.Msg)
 			m.SetRcode(r, dns.RcodeRefused)
+			dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "TSIG required")
 			_ = w.WriteMsg(m)
 			return
 
@@ -83,6 +89,11 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		dnsutils.HandleUpdate(w, r)
+		return
+	}
+
 	m := new(dns.Msg)
 	m.SetReply(r)
 	m.RecursionAvailable = false
@@ -90,6 +101,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	if r.Opcode != dns.OpcodeQuery {
 		m.SetRcode(r, dns.RcodeNotImplemented)
 		m.Authoritative = false
+		dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeNotSupported, "opcode "+dns.OpcodeToString[r.Opcode]+" not supported")
 		writeResponse(w, r, m)
 		return
 	}
@@ -116,6 +128,18 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
+	// RFC 7873 section 5.2.3/5.2.4: when policy demands a server cookie, a UDP
+	// query that only has a client cookie (or a stale/forged server cookie) gets
+	// BADCOOKIE with a fresh cookie to retry with. TCP already proves the address.
+	if live.Cookies.RequireServerCookie && !responseIsTCP(w) {
+		if cookie := dnsutils.CheckCookie(remoteIP(w), r, time.Now()); cookie.Present && !cookie.Valid {
+			m.SetRcode(r, dns.RcodeBadCookie)
+			m.Authoritative = false
+			writeResponse(w, r, m)
+			return
+		}
+	}
+
 	if len(r.Question) != 1 {
 		slog.Debug("refusing DNS request with QDCOUNT=%d; only one question is supported", len(r.Question))
 		m.SetRcode(r, dns.RcodeFormatError)
@@ -125,6 +149,16 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
+	zones, err := queryScope(w, r, live)
+	if err != nil {
+		slog.Error("failed to select view: %v", err)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		m.Authoritative = false
+		dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeOther, "view unavailable")
+		writeResponse(w, r, m)
+		return
+	}
+
 	m.Authoritative = true
 
 	for _, q := range r.Question {
@@ -153,6 +187,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		if q.Qclass != dns.ClassINET {
 			m.SetRcode(r, dns.RcodeNotImplemented)
 			m.Authoritative = false
+			dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeNotSupported, "class "+dns.ClassToString[q.Qclass]+" not supported")
 			answered = true
 		}
 
@@ -160,8 +195,22 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			continue
 		}
 
-		if _, ok := zone.AuthoritativeZoneForName(q.Name); !ok {
-			applyUnknownZonePolicy(m, r, live)
+		if zoneName, ok := zones.AuthoritativeZoneForName(q.Name); !ok {
+			if pending, ok := dnsutils.PendingSecondaryZone(q.Name); ok {
+				// RFC 8914 §4.15: the zone is ours but has not been loaded yet.
+				m.SetRcode(r, dns.RcodeServerFailure)
+				m.Authoritative = false
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeNotReady, "zone "+pending+" has not been transferred yet")
+			} else {
+				applyUnknownZonePolicy(m, r, live)
+			}
+			answered = true
+		} else if dnsutils.ZoneExpired(zoneName) {
+			// RFC 1035 §4.3.5: past its SOA expire time the zone data is no
+			// longer authoritative, including for transfers.
+			m.SetRcode(r, dns.RcodeServerFailure)
+			m.Authoritative = false
+			dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeInvalidData, "zone "+zoneName+" expired")
 			answered = true
 		}
 
@@ -169,30 +218,29 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			continue
 		}
 
-		if delegation, ns, ok := zone.DelegationFor(q.Name); ok && shouldReturnReferral(q, delegation) {
+		if delegation, ns, ok := zones.DelegationFor(q.Name); ok && shouldReturnReferral(q, delegation) {
 			m.Authoritative = false
 			m.Ns = append(m.Ns, ns...)
-			m.Extra = append(m.Extra, glueRecords(ns)...)
+			m.Extra = append(m.Extra, glueRecords(zones, ns)...)
 			answered = true
 		}
 
 		if answered {
 			if wantsDNSSEC {
 				slog.Crazy("Using DNSSEC")
-				m.Answer = appendRRSIGs(m.Answer)
-				m.Ns = appendRRSIGs(m.Ns)
+				signResponse(zones, m, r)
 			}
 			continue
 		}
 
 		switch q.Qtype {
 		case dns.TypeANY:
-			applyANYPolicy(m, q, live)
+			applyANYPolicy(m, r, q, live)
 			answered = true
 
 		case dns.TypeDNSKEY:
 			zoneApex, _ := internal.SanitizeFQDN(q.Name) //TODO: manage error
-			if rec, ok := zone.LookupRecord(dns.TypeDNSKEY, zoneApex); ok {
+			if rec, ok := zones.LookupRecord(dns.TypeDNSKEY, zoneApex); ok {
 				slog.Debug("DNSKEY record found: %v", rec)
 				m.Answer = append(m.Answer, rec...)
 				answered = true
@@ -201,7 +249,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			}
 
 		case dns.TypeCNAME, dns.TypeDNAME, dns.TypeNS:
-			if rec, ok := zone.LookupRecord(q.Qtype, q.Name); ok {
+			if rec, ok := zones.LookupRecord(q.Qtype, q.Name); ok {
 				m.Answer = append(m.Answer, rec...)
 				answered = true
 			}
@@ -210,13 +258,15 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			if !live.AllowAXFR {
 				slog.Debug("AXFR/IXFR disabled by configuration")
 				m.SetRcode(r, dns.RcodeRefused)
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "zone transfers are disabled")
 				writeResponse(w, r, m)
 				return
 			}
 
-			if !transferClientAllowed(w.RemoteAddr().String(), live.AllowTransfer) {
+			if !transferRequestAllowed(w, r, live) {
 				slog.Warn("AXFR/IXFR refused for unauthorized client %s", w.RemoteAddr().String())
 				m.SetRcode(r, dns.RcodeRefused)
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "zone transfer not allowed for "+w.RemoteAddr().String())
 				writeResponse(w, r, m)
 				return
 			}
@@ -231,12 +281,14 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 				if _, ok := security.GetTSIGKey(tsig.Hdr.Name); !ok {
 					slog.Warn("TSIG key not recognized: %s — rejecting", tsig.Hdr.Name)
 					m.SetRcode(r, dns.RcodeRefused)
+					dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "unknown TSIG key "+tsig.Hdr.Name)
 					writeResponse(w, r, m)
 					return
 				}
 				if w.TsigStatus() != nil {
 					slog.Warn("TSIG validation failed: %v", w.TsigStatus())
 					m.SetRcode(r, dns.RcodeRefused)
+					dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "TSIG validation failed")
 					writeResponse(w, r, m)
 					return
 				}
@@ -246,6 +298,7 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 				// TSIG required but missing
 				slog.Warn("AXFR/IXFR request is not TSIG-signed — rejecting due to EnforceTSIG")
 				m.SetRcode(r, dns.RcodeRefused)
+				dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeProhibited, "TSIG required")
 				writeResponse(w, r, m)
 				return
 
@@ -255,23 +308,26 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 			}
 
 			// Delegate to ServeDNS
-			dnsutils.ServeDNS(w, r)
+			dnsutils.ServeDNSFrom(w, r, zones)
 			return
 
 		default:
-			result := resolveAnswerChain(q.Name, q.Qtype, wantsDNSSEC)
-			if len(result.Answer) > 0 {
+			result := resolveAnswerChain(zones, q.Name, q.Qtype, wantsDNSSEC)
+			if len(result.Answer) > 0 || result.ALIASFailed {
 				m.Answer = append(m.Answer, result.Answer...)
 				m.Ns = append(m.Ns, result.Authority...)
 				if result.Rcode != dns.RcodeSuccess {
 					m.Rcode = result.Rcode
 				}
+				if result.ALIASFailed {
+					dnsutils.ApplyEDE(m, r, dns.ExtendedErrorCodeNetworkError, "ALIAS target could not be resolved")
+				}
 				answered = true
 			}
 		}
 
 		if !answered {
-			if rec, authority, ok := lookupWildcard(q.Qtype, q.Name, wantsDNSSEC); ok {
+			if rec, authority, ok := lookupWildcard(zones, q.Qtype, q.Name, wantsDNSSEC); ok {
 				m.Answer = append(m.Answer, rec...)
 				m.Ns = append(m.Ns, authority...)
 				answered = true
@@ -279,30 +335,50 @@ func handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 		}
 
 		if !answered {
-			nameFound := nameExists(q.Name)
-			nxdomain := !nameFound && !zone.WildcardExists(q.Name)
+			nameFound := nameExists(zones, q.Name)
+			nxdomain := !nameFound && !zones.WildcardExists(q.Name)
 			if nxdomain {
 				m.Rcode = dns.RcodeNameError
 			}
-			if soaRec, ok := lookupApexSOA(q.Name); ok {
+			if soaRec, ok := lookupApexSOA(zones, q.Name); ok {
 				m.Ns = append(m.Ns, soaRec...)
 			}
 			if wantsDNSSEC {
-				m.Ns = append(m.Ns, denialRecords(q.Name, q.Qtype, nxdomain)...)
+				m.Ns = append(m.Ns, denialRecords(zones, q.Name, q.Qtype, nxdomain)...)
 			}
 		}
 
+		if !live.MinimalResponses && len(m.Answer) > 0 && m.Rcode == dns.RcodeSuccess {
+			present := append(append([]dns.RR{}, m.Answer...), m.Extra...)
+			m.Extra = append(m.Extra, additionalRecords(zones, m.Answer, present, wantsDNSSEC)...)
+		}
+
 		if wantsDNSSEC {
 			slog.Crazy("Using DNSSEC")
-			m.Answer = appendRRSIGs(m.Answer)
-			m.Ns = appendRRSIGs(m.Ns)
+			signResponse(zones, m, r)
 		}
 	}
 
+	if soa, ok := apexSOAAnswer(r, m); ok {
+		dnsutils.ApplyExpire(m, r, soa)
+	}
 	dnsutils.ApplyNSID(m, r)
 	writeResponse(w, r, m)
 }
 
+// apexSOAAnswer returns the SOA answering a query for the SOA of a zone apex,
+// the response RFC 7314 attaches EXPIRE to.
+func apexSOAAnswer(req *dns.Msg, resp *dns.Msg) (*dns.SOA, bool) {
+	if req.Question[0].Qtype != dns.TypeSOA || len(resp.Answer) == 0 {
+		return nil, false
+	}
+	soa, ok := resp.Answer[0].(*dns.SOA)
+	if !ok || !strings.EqualFold(soa.Hdr.Name, req.Question[0].Name) {
+		return nil, false
+	}
+	return soa, true
+}
+
 func multipleOPTRecords(r *dns.Msg) bool {
 	count := 0
 	for _, rr := range r.Extra {
@@ -337,10 +413,33 @@ func malformedEDNSCookie(opt *dns.OPT) bool {
 }
 
 func writeResponse(w dns.ResponseWriter, req *dns.Msg, resp *dns.Msg) {
-	finalizeResponse(req, resp, responseIsTCP(w))
+	tcp := responseIsTCP(w)
+	now := time.Now()
+	client := remoteIP(w)
+	cookie := dnsutils.CheckCookie(client, req, now)
+	if !tcp && req.Opcode == dns.OpcodeQuery && client != nil {
+		switch dnsutils.RateLimitResponse(client, req, resp, cookie.Valid, now) {
+		case dnsutils.RRLDrop:
+			return
+		case dnsutils.RRLSlip:
+			resp = slipResponse(req)
+		}
+	}
+	dnsutils.ApplyCookie(resp, req, cookie, client, now)
+	finalizeResponse(req, resp, tcp)
 	_ = w.WriteMsg(resp)
 }
 
+// slipResponse is the empty truncated answer RRL sends in place of a limited
+// response, prompting a genuine client to retry over TCP.
+func slipResponse(req *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Authoritative = false
+	m.Truncated = true
+	return m
+}
+
 func finalizeResponse(req *dns.Msg, resp *dns.Msg, tcp bool) {
 	if req == nil || resp == nil {
 		return
@@ -379,10 +478,20 @@ func finalizeResponse(req *dns.Msg, resp *dns.Msg, tcp bool) {
 		maxSize = live.MaxUDPSize
 	}
 	if maxSize > 0 {
+		trimAdditional(resp, max(maxSize, dns.MinMsgSize))
 		resp.Truncate(maxSize)
 	}
 }
 
+// notifyForSecondaryZone reports whether a NOTIFY concerns a zone this server
+// transfers from a primary.
+func notifyForSecondaryZone(r *dns.Msg, live config.LiveConfig) bool {
+	if len(r.Question) == 0 {
+		return live.Mode == zonemeta.RoleSecondary
+	}
+	return zonemeta.IsSecondary(r.Question[0].Name)
+}
+
 func notifySourceAllowed(r *dns.Msg, remoteIP string, live config.LiveConfig) bool {
 	if live.Primary.Ip != "" && strings.HasPrefix(remoteIP, live.Primary.Ip) {
 		return true
@@ -390,7 +499,19 @@ func notifySourceAllowed(r *dns.Msg, remoteIP string, live config.LiveConfig) bo
 	if len(r.Question) == 0 {
 		return false
 	}
-	return dnsutils.NotifyAllowedFromCatalogPrimary(r.Question[0].Name, remoteIP)
+	return dnsutils.NotifyAllowedFromPrimary(r.Question[0].Name, remoteIP)
+}
+
+// remoteIP returns the client address of a UDP or TCP writer.
+func remoteIP(w dns.ResponseWriter) net.IP {
+	switch addr := w.RemoteAddr().(type) {
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.TCPAddr:
+		return addr.IP
+	default:
+		return nil
+	}
 }
 
 func responseIsTCP(w dns.ResponseWriter) bool {
@@ -405,17 +526,22 @@ func responseIsTCP(w dns.ResponseWriter) bool {
 func applyUnknownZonePolicy(resp *dns.Msg, req *dns.Msg, live config.LiveConfig) {
 	if strings.EqualFold(live.UnknownZonePolicy, "nxdomain") {
 		resp.SetRcode(req, dns.RcodeNameError)
-		resp.Authoritative = false
-		return
+	} else {
+		resp.SetRcode(req, dns.RcodeRefused)
 	}
-	resp.SetRcode(req, dns.RcodeRefused)
 	resp.Authoritative = false
+	qname := ""
+	if len(req.Question) > 0 {
+		qname = req.Question[0].Name
+	}
+	dnsutils.ApplyEDE(resp, req, dns.ExtendedErrorCodeNotAuthoritative, "not authoritative for "+qname)
 }
 
-func applyANYPolicy(resp *dns.Msg, q dns.Question, live config.LiveConfig) {
+func applyANYPolicy(resp *dns.Msg, req *dns.Msg, q dns.Question, live config.LiveConfig) {
 	if strings.EqualFold(live.AnyQueryPolicy, "refuse") {
 		resp.Rcode = dns.RcodeRefused
 		resp.Authoritative = false
+		dnsutils.ApplyEDE(resp, req, dns.ExtendedErrorCodeProhibited, "ANY queries are refused by policy")
 		return
 	}
 	resp.Answer = append(resp.Answer, &dns.HINFO{
@@ -430,7 +556,22 @@ func applyANYPolicy(resp *dns.Msg, q dns.Question, live config.LiveConfig) {
 	})
 }
 
-func appendRRSIGs(section []dns.RR) []dns.RR {
+// signResponse adds RRSIGs to the answer and authority sections. When a cached
+// signature has lapsed and could not be refreshed the RRset goes out unsigned
+// and the response says so with EDE "Signature Expired".
+func signResponse(zones zone.Scope, resp *dns.Msg, req *dns.Msg) {
+	var answerExpired, nsExpired bool
+	resp.Answer, answerExpired = appendRRSIGs(zones, resp.Answer)
+	resp.Ns, nsExpired = appendRRSIGs(zones, resp.Ns)
+	if answerExpired || nsExpired {
+		dnsutils.ApplyEDE(resp, req, dns.ExtendedErrorCodeSignatureExpired, "RRSIG expired and could not be refreshed")
+	}
+}
+
+// appendRRSIGs adds the RRSIGs of every RRset in section that lacks them. It
+// also reports whether any RRset was left unsigned because its signature
+// expired.
+func appendRRSIGs(zones zone.Scope, section []dns.RR) ([]dns.RR, bool) {
 	seen := make(map[string]bool)
 	synthesizedDNAMECNAME := synthesizedDNAMECNAMEs(section)
 	for _, rr := range section {
@@ -445,7 +586,7 @@ func appendRRSIGs(section []dns.RR) []dns.RR {
 		if !ok {
 			continue
 		}
-		key := strings.ToLower(rrsig.Hdr.Name) + "|" + dns.TypeToString[rrsig.TypeCovered]
+		key := strings.ToLower(rrsig.Hdr.Name) + "|" + internal.TypeName(rrsig.TypeCovered)
 		covered[key] = true
 	}
 
@@ -458,17 +599,21 @@ func appendRRSIGs(section []dns.RR) []dns.RR {
 		if hdr.Rrtype == dns.TypeCNAME && synthesizedDNAMECNAME[strings.ToLower(hdr.Name)] {
 			continue
 		}
-		key := strings.ToLower(hdr.Name) + "|" + dns.TypeToString[hdr.Rrtype]
+		key := strings.ToLower(hdr.Name) + "|" + internal.TypeName(hdr.Rrtype)
 		if covered[key] {
 			continue
 		}
 		rrsets[key] = append(rrsets[key], rr)
 	}
 
+	expired := false
 	for _, rrset := range rrsets {
-		rrsigRecords, err := zone.EnsureSignedRRSet(rrset)
+		rrsigRecords, err := zones.EnsureSignedRRSet(rrset)
 		if err != nil {
 			slog.Warn("DNSSEC query-time signing failed: %v", err)
+			if errors.Is(err, memory.ErrSignatureExpired) {
+				expired = true
+			}
 			continue
 		}
 		for _, sig := range rrsigRecords {
@@ -485,16 +630,18 @@ func appendRRSIGs(section []dns.RR) []dns.RR {
 		}
 	}
 
-	return section
+	return section, expired
 }
 
 type answerChainResult struct {
 	Answer    []dns.RR
 	Authority []dns.RR
 	Rcode     int
+	// ALIASFailed is set when an ALIAS target could not be resolved.
+	ALIASFailed bool
 }
 
-func resolveAnswerChain(qname string, qtype uint16, wantsDNSSEC bool) answerChainResult {
+func resolveAnswerChain(zones zone.Scope, qname string, qtype uint16, wantsDNSSEC bool) answerChainResult {
 	const maxAliasDepth = 8
 	result := answerChainResult{Rcode: dns.RcodeSuccess}
 	current := dns.Fqdn(qname)
@@ -508,18 +655,34 @@ func resolveAnswerChain(qname string, qtype uint16, wantsDNSSEC bool) answerChai
 		}
 		seen[key] = true
 
-		if rec, ok := zone.LookupRecord(qtype, current); ok {
+		if rec, ok := zones.LookupRecord(qtype, current); ok {
 			result.Answer = append(result.Answer, rec...)
 			return result
 		}
 
-		if rec, authority, ok := lookupWildcard(qtype, current, wantsDNSSEC); ok {
+		if rec, authority, ok := lookupWildcard(zones, qtype, current, wantsDNSSEC); ok {
 			result.Answer = append(result.Answer, rec...)
 			result.Authority = append(result.Authority, authority...)
 			return result
 		}
 
-		if cnameRec, ok := zone.LookupRecord(dns.TypeCNAME, current); ok {
+		if qtype == dns.TypeA || qtype == dns.TypeAAAA {
+			rec, _, err := zones.ResolveALIAS(current, qtype)
+			if err != nil {
+				slog.Warn("ALIAS %s %s: %v", current, dns.TypeToString[qtype], err)
+				result.Rcode = dns.RcodeServerFailure
+				result.ALIASFailed = true
+				return result
+			}
+			if len(rec) > 0 {
+				result.Answer = append(result.Answer, rec...)
+				return result
+			}
+			// An ALIAS whose target has no such addresses falls through to
+			// NODATA; CNAME cannot coexist with it.
+		}
+
+		if cnameRec, ok := zones.LookupRecord(dns.TypeCNAME, current); ok {
 			result.Answer = append(result.Answer, cnameRec...)
 			cname, ok := firstCNAME(cnameRec)
 			if !ok {
@@ -529,23 +692,23 @@ func resolveAnswerChain(qname string, qtype uint16, wantsDNSSEC bool) answerChai
 			continue
 		}
 
-		if dnameRec, synthesized, ok := lookupDNAMERewrite(current); ok {
+		if dnameRec, synthesized, ok := lookupDNAMERewrite(zones, current); ok {
 			result.Answer = append(result.Answer, dnameRec...)
 			result.Answer = append(result.Answer, synthesized)
 			current = dns.Fqdn(synthesized.Target)
 			continue
 		}
 
-		if len(result.Answer) > 0 && authoritativeForName(current) {
-			nxdomain := !nameExists(current) && !zone.WildcardExists(current)
+		if len(result.Answer) > 0 && authoritativeForName(zones, current) {
+			nxdomain := !nameExists(zones, current) && !zones.WildcardExists(current)
 			if nxdomain {
 				result.Rcode = dns.RcodeNameError
 			}
-			if soaRec, ok := lookupApexSOA(current); ok {
+			if soaRec, ok := lookupApexSOA(zones, current); ok {
 				result.Authority = append(result.Authority, soaRec...)
 			}
 			if wantsDNSSEC {
-				result.Authority = append(result.Authority, denialRecords(current, qtype, nxdomain)...)
+				result.Authority = append(result.Authority, denialRecords(zones, current, qtype, nxdomain)...)
 			}
 		}
 		return result
@@ -565,8 +728,8 @@ func firstCNAME(rrs []dns.RR) (*dns.CNAME, bool) {
 	return nil, false
 }
 
-func lookupDNAMERewrite(qname string) ([]dns.RR, *dns.CNAME, bool) {
-	owner, dnameRec, ok := closestDNAME(qname)
+func lookupDNAMERewrite(zones zone.Scope, qname string) ([]dns.RR, *dns.CNAME, bool) {
+	owner, dnameRec, ok := closestDNAME(zones, qname)
 	if !ok {
 		return nil, nil, false
 	}
@@ -589,12 +752,12 @@ func lookupDNAMERewrite(qname string) ([]dns.RR, *dns.CNAME, bool) {
 	}, true
 }
 
-func closestDNAME(qname string) (string, []dns.RR, bool) {
+func closestDNAME(zones zone.Scope, qname string) (string, []dns.RR, bool) {
 	trimmed := strings.TrimSuffix(strings.ToLower(qname), ".")
 	labels := strings.Split(trimmed, ".")
 	for i := 1; i < len(labels)-1; i++ {
 		candidate := dns.Fqdn(strings.Join(labels[i:], "."))
-		if rec, ok := zone.LookupRecord(dns.TypeDNAME, candidate); ok {
+		if rec, ok := zones.LookupRecord(dns.TypeDNAME, candidate); ok {
 			return candidate, rec, true
 		}
 	}
@@ -653,8 +816,8 @@ func synthesizedDNAMECNAMEs(section []dns.RR) map[string]bool {
 	return out
 }
 
-func authoritativeForName(name string) bool {
-	_, ok := lookupApexSOA(name)
+func authoritativeForName(zones zone.Scope, name string) bool {
+	_, ok := lookupApexSOA(zones, name)
 	return ok
 }
 
@@ -665,7 +828,7 @@ func shouldReturnReferral(q dns.Question, delegation string) bool {
 	return true
 }
 
-func glueRecords(nsRecords []dns.RR) []dns.RR {
+func glueRecords(zones zone.Scope, nsRecords []dns.RR) []dns.RR {
 	var out []dns.RR
 	seen := make(map[string]bool)
 	for _, rr := range nsRecords {
@@ -677,7 +840,7 @@ func glueRecords(nsRecords []dns.RR) []dns.RR {
 			continue
 		}
 		for _, rrtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
-			records, ok := zone.LookupRecord(rrtype, ns.Ns)
+			records, ok := zones.LookupRecord(rrtype, ns.Ns)
 			if !ok {
 				continue
 			}
@@ -700,34 +863,34 @@ func inBailiwickGlue(target, delegation string) bool {
 	return target == delegation || strings.HasSuffix(target, "."+delegation)
 }
 
-func lookupWildcard(rrtype uint16, qname string, wantsDNSSEC bool) ([]dns.RR, []dns.RR, bool) {
-	if nameExists(qname) {
+func lookupWildcard(zones zone.Scope, rrtype uint16, qname string, wantsDNSSEC bool) ([]dns.RR, []dns.RR, bool) {
+	if nameExists(zones, qname) {
 		return nil, nil, false
 	}
-	wildcard, ok := zone.WildcardName(qname)
+	wildcard, ok := zones.WildcardName(qname)
 	if !ok {
 		return nil, nil, false
 	}
 
-	if rec, ok := zone.LookupRecord(rrtype, wildcard); ok {
-		return synthesizeWildcard(qname, rec, wantsDNSSEC), wildcardDenialAuthority(qname, rrtype, wantsDNSSEC), true
+	if rec, ok := zones.LookupRecord(rrtype, wildcard); ok {
+		return synthesizeWildcard(zones, qname, rec, wantsDNSSEC), wildcardDenialAuthority(zones, qname, rrtype, wantsDNSSEC), true
 	}
 	if rrtype != dns.TypeCNAME {
-		if rec, ok := zone.LookupRecord(dns.TypeCNAME, wildcard); ok {
-			return synthesizeWildcard(qname, rec, wantsDNSSEC), wildcardDenialAuthority(qname, rrtype, wantsDNSSEC), true
+		if rec, ok := zones.LookupRecord(dns.TypeCNAME, wildcard); ok {
+			return synthesizeWildcard(zones, qname, rec, wantsDNSSEC), wildcardDenialAuthority(zones, qname, rrtype, wantsDNSSEC), true
 		}
 	}
 	return nil, nil, false
 }
 
-func wildcardDenialAuthority(qname string, rrtype uint16, wantsDNSSEC bool) []dns.RR {
+func wildcardDenialAuthority(zones zone.Scope, qname string, rrtype uint16, wantsDNSSEC bool) []dns.RR {
 	if !wantsDNSSEC {
 		return nil
 	}
-	return denialRecords(qname, rrtype, false)
+	return denialRecords(zones, qname, rrtype, false)
 }
 
-func synthesizeWildcard(qname string, wildcardRRSet []dns.RR, wantsDNSSEC bool) []dns.RR {
+func synthesizeWildcard(zones zone.Scope, qname string, wildcardRRSet []dns.RR, wantsDNSSEC bool) []dns.RR {
 	var out []dns.RR
 	for _, rr := range wildcardRRSet {
 		copied := dns.Copy(rr)
@@ -738,7 +901,7 @@ func synthesizeWildcard(qname string, wildcardRRSet []dns.RR, wantsDNSSEC bool)
 	if !wantsDNSSEC {
 		return out
 	}
-	rrsigRecords, err := zone.EnsureSignedRRSet(wildcardRRSet)
+	rrsigRecords, err := zones.EnsureSignedRRSet(wildcardRRSet)
 	if err != nil {
 		slog.Warn("DNSSEC wildcard signing failed: %v", err)
 		return out
@@ -754,20 +917,20 @@ func synthesizeWildcard(qname string, wildcardRRSet []dns.RR, wantsDNSSEC bool)
 	return out
 }
 
-func lookupApexSOA(name string) ([]dns.RR, bool) {
-	apex, ok := zone.AuthoritativeZoneForName(name)
+func lookupApexSOA(zones zone.Scope, name string) ([]dns.RR, bool) {
+	apex, ok := zones.AuthoritativeZoneForName(name)
 	if !ok {
 		return nil, false
 	}
-	return zone.LookupRecord(dns.TypeSOA, apex)
+	return zones.LookupRecord(dns.TypeSOA, apex)
 }
 
-func denialRecords(name string, qtype uint16, nxdomain bool) []dns.RR {
-	return zone.DenialProofs(name, qtype, nxdomain)
+func denialRecords(zones zone.Scope, name string, qtype uint16, nxdomain bool) []dns.RR {
+	return zones.DenialProofs(name, qtype, nxdomain)
 }
 
-func nameExists(name string) bool {
-	return zone.NameExists(name)
+func nameExists(zones zone.Scope, name string) bool {
+	return zones.NameExists(name)
 }
 
 func transferClientAllowed(remoteAddr string, allowTransfer string) bool {
@@ -1,11 +1,16 @@
 package dns
 
 import (
+	"encoding/json"
 	"net"
+	"strconv"
 	"testing"
+	"time"
 
 	mdns "github.com/miekg/dns"
 	"go53/config"
+	"go53/dns/dnsutils"
+	"go53/internal"
 	"go53/memory"
 	"go53/storage"
 	"go53/types"
@@ -178,6 +183,52 @@ func TestHandleRequestPositiveAAndNXDOMAIN(t *testing.T) {
 	}
 }
 
+func TestHandleRequestAnswersALIAS(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	ttl := uint32(300)
+	if err := zone.AddRecord(mdns.TypeSOA, "alias.test.", "alias.test.", map[string]interface{}{"ns": "ns1.alias.test.", "mbox": "hostmaster.alias.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)}, &ttl); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	if err := zone.AddRecord(mdns.TypeA, "alias.test.", "web", map[string]interface{}{"ip": "192.0.2.80"}, &ttl); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	if err := zone.AddRecord(internal.TypeALIAS, "alias.test.", "@", map[string]interface{}{"target": "web.alias.test."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	if err := zone.AddRecord(internal.TypeALIAS, "alias.test.", "ext", map[string]interface{}{"target": "lb.example.net."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+
+	query := func(name string, qtype uint16) *mdns.Msg {
+		t.Helper()
+		req := new(mdns.Msg)
+		req.SetQuestion(name, qtype)
+		req.SetEdns0(1232, false)
+		w := &captureResponseWriter{}
+		handleRequest(w, req)
+		if w.msg == nil {
+			t.Fatalf("no response for %s", name)
+		}
+		return w.msg
+	}
+
+	resp := query("alias.test.", mdns.TypeA)
+	if resp.Rcode != mdns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Fatalf("apex ALIAS response = %v", resp)
+	}
+	if a, ok := resp.Answer[0].(*mdns.A); !ok || a.Hdr.Name != "alias.test." || a.A.String() != "192.0.2.80" {
+		t.Fatalf("apex ALIAS answer = %v", resp.Answer[0])
+	}
+	if resp := query("alias.test.", mdns.TypeAAAA); resp.Rcode != mdns.RcodeSuccess || len(resp.Answer) != 0 {
+		t.Fatalf("apex ALIAS AAAA = %v, want NODATA", resp)
+	}
+
+	resp = query("ext.alias.test.", mdns.TypeA)
+	if ede := responseEDE(resp); resp.Rcode != mdns.RcodeServerFailure || ede == nil || ede.InfoCode != mdns.ExtendedErrorCodeNetworkError {
+		t.Fatalf("unresolvable ALIAS: rcode=%s ede=%v", mdns.RcodeToString[resp.Rcode], ede)
+	}
+}
+
 func TestHandleRequestUnknownZoneRefused(t *testing.T) {
 	setupDNSHandlerTestStore(t)
 
@@ -197,6 +248,134 @@ func TestHandleRequestUnknownZoneRefused(t *testing.T) {
 	}
 }
 
+func TestHandleRequestAttachesExtendedDNSErrors(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+
+	query := func(name string, qtype uint16, edns bool) *mdns.Msg {
+		t.Helper()
+		req := new(mdns.Msg)
+		req.SetQuestion(name, qtype)
+		if edns {
+			req.SetEdns0(1232, false)
+		}
+		w := &captureResponseWriter{}
+		handleRequest(w, req)
+		if w.msg == nil {
+			t.Fatalf("no response for %s", name)
+		}
+		return w.msg
+	}
+
+	resp := query("www.unknown.test.", mdns.TypeA, true)
+	if ede := responseEDE(resp); resp.Rcode != mdns.RcodeRefused || ede == nil || ede.InfoCode != mdns.ExtendedErrorCodeNotAuthoritative {
+		t.Fatalf("unknown zone: rcode=%s ede=%v", mdns.RcodeToString[resp.Rcode], ede)
+	}
+	if ede := responseEDE(resp); ede.ExtraText != "" {
+		t.Fatalf("EXTRA-TEXT sent while ede_extra_text is off: %q", ede.ExtraText)
+	}
+	if resp := query("www.unknown.test.", mdns.TypeA, false); resp.IsEdns0() != nil {
+		t.Fatalf("EDE added to a query without EDNS: %v", resp.Extra)
+	}
+
+	addViewTestZone(t, zone.Default, "example.test.", nil)
+	config.AppConfig.LiveForTest().EDEExtraText = true
+	resp = query("example.test.", mdns.TypeAXFR, true)
+	if ede := responseEDE(resp); resp.Rcode != mdns.RcodeRefused || ede == nil || ede.InfoCode != mdns.ExtendedErrorCodeProhibited || ede.ExtraText != "zone transfers are disabled" {
+		t.Fatalf("disabled AXFR: rcode=%s ede=%v", mdns.RcodeToString[resp.Rcode], ede)
+	}
+
+	live := config.AppConfig.LiveForTest()
+	live.Mode = "secondary"
+	live.Secondary.Zones = []string{"pending.test"}
+	resp = query("www.pending.test.", mdns.TypeA, true)
+	if ede := responseEDE(resp); resp.Rcode != mdns.RcodeServerFailure || ede == nil || ede.InfoCode != mdns.ExtendedErrorCodeNotReady {
+		t.Fatalf("pending secondary zone: rcode=%s ede=%v", mdns.RcodeToString[resp.Rcode], ede)
+	}
+}
+
+func TestHandleRequestExpiredSecondaryZone(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	config.AppConfig.LiveForTest().EnableEDNS = true
+	ttl := uint32(300)
+	if err := zone.AddRecord(mdns.TypeSOA, "expiry.test.", "expiry.test.", map[string]interface{}{"ns": "ns1.expiry.test.", "mbox": "hostmaster.expiry.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)}, &ttl); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	// Timers left behind by a secondary copy that expired a minute ago.
+	state, _ := json.Marshal(map[string]any{"zone": "expiry.test.", "expire": 86400, "expires_at": time.Now().Add(-time.Minute).Unix()})
+	if err := storage.Backend.SaveTable(dnsutils.SecondaryTimersTable, "expiry.test.", state); err != nil {
+		t.Fatalf("save timers: %v", err)
+	}
+	query := func(qtype uint16) *mdns.Msg {
+		req := new(mdns.Msg)
+		req.SetQuestion("expiry.test.", qtype)
+		req.SetEdns0(1232, false)
+		opt := req.IsEdns0()
+		opt.Option = append(opt.Option, &mdns.EDNS0_EXPIRE{Code: mdns.EDNS0EXPIRE, Empty: true})
+		w := &captureResponseWriter{}
+		handleRequest(w, req)
+		if w.msg == nil {
+			t.Fatalf("no response for %s", mdns.TypeToString[qtype])
+		}
+		return w.msg
+	}
+
+	resp := query(mdns.TypeSOA)
+	var expire *mdns.EDNS0_EXPIRE
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if e, ok := o.(*mdns.EDNS0_EXPIRE); ok {
+				expire = e
+			}
+		}
+	}
+	if expire == nil || expire.Expire != 86400 {
+		t.Fatalf("primary SOA answer EXPIRE = %v, want 86400", expire)
+	}
+
+	config.AppConfig.LiveForTest().Mode = "secondary"
+	resp = query(mdns.TypeSOA)
+	if ede := responseEDE(resp); resp.Rcode != mdns.RcodeServerFailure || ede == nil || ede.InfoCode != mdns.ExtendedErrorCodeInvalidData {
+		t.Fatalf("expired zone: rcode=%s ede=%v", mdns.RcodeToString[resp.Rcode], ede)
+	}
+}
+
+func responseEDE(m *mdns.Msg) *mdns.EDNS0_EDE {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if ede, ok := o.(*mdns.EDNS0_EDE); ok {
+			return ede
+		}
+	}
+	return nil
+}
+
+func TestHandleRequestRoutesUnsignedUpdate(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	ttl := uint32(300)
+	if err := zone.AddRecord(mdns.TypeSOA, "update.test.", "update.test.", map[string]interface{}{"ns": "ns1.update.test.", "mbox": "hostmaster.update.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)}, &ttl); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+
+	req := new(mdns.Msg)
+	req.SetUpdate("update.test.")
+	rr, _ := mdns.NewRR("www.update.test. 300 IN A 192.0.2.1")
+	req.Insert([]mdns.RR{rr})
+	w := &captureResponseWriter{}
+	handleRequest(w, req)
+	if w.msg == nil {
+		t.Fatalf("no response written")
+	}
+	if w.msg.Opcode != mdns.OpcodeUpdate || w.msg.Rcode != mdns.RcodeRefused {
+		t.Fatalf("opcode=%d rcode=%s, want UPDATE REFUSED", w.msg.Opcode, mdns.RcodeToString[w.msg.Rcode])
+	}
+	if _, ok := zone.LookupRecord(mdns.TypeA, "www.update.test."); ok {
+		t.Fatalf("unsigned UPDATE must not change the zone")
+	}
+}
+
 func TestHandleRequestAcceptsNotifyFromCatalogPrimary(t *testing.T) {
 	setupDNSHandlerTestStore(t)
 	config.AppConfig.LiveForTest().Mode = "secondary"
@@ -311,6 +490,63 @@ func TestHandleRequestRejectsMalformedEDNSCookie(t *testing.T) {
 	}
 }
 
+func TestHandleRequestRequiresServerCookieOverUDP(t *testing.T) {
+	resetDNSHandlerTestConfig()
+	cookies := &config.AppConfig.LiveForTest().Cookies
+	cookies.RequireServerCookie = true
+	cookies.Secret = "e5e973e5a6b2a43f48e7dc849e37bfcf"
+
+	query := func(remote net.Addr, cookie string) *mdns.Msg {
+		req := new(mdns.Msg)
+		req.SetQuestion("example.test.", mdns.TypeA)
+		req.SetEdns0(1232, false)
+		req.IsEdns0().Option = append(req.IsEdns0().Option, &mdns.EDNS0_COOKIE{Code: mdns.EDNS0COOKIE, Cookie: cookie})
+		w := &captureResponseWriter{remoteAddr: remote}
+		handleRequest(w, req)
+		if w.msg == nil {
+			t.Fatalf("expected response")
+		}
+		packed, err := w.msg.Pack()
+		if err != nil {
+			t.Fatalf("pack response: %v", err)
+		}
+		resp := new(mdns.Msg)
+		if err := resp.Unpack(packed); err != nil {
+			t.Fatalf("unpack response: %v", err)
+		}
+		return resp
+	}
+	cookieOf := func(m *mdns.Msg) string {
+		for _, o := range m.IsEdns0().Option {
+			if c, ok := o.(*mdns.EDNS0_COOKIE); ok {
+				return c.Cookie
+			}
+		}
+		t.Fatalf("response carries no COOKIE option")
+		return ""
+	}
+
+	udp := &net.UDPAddr{IP: net.ParseIP("198.51.100.100"), Port: 5353}
+	bad := query(udp, "2464c4abcf10c957")
+	if bad.Rcode != mdns.RcodeBadCookie {
+		t.Fatalf("client-only cookie rcode = %s, want BADCOOKIE", mdns.RcodeToString[bad.Rcode])
+	}
+	cookie := cookieOf(bad)
+	if len(cookie) != 48 || cookie[:16] != "2464c4abcf10c957" {
+		t.Fatalf("BADCOOKIE carried cookie %q", cookie)
+	}
+
+	retry := query(udp, cookie)
+	if retry.Rcode != mdns.RcodeRefused || cookieOf(retry) != cookie {
+		t.Fatalf("retry with server cookie = %v", retry)
+	}
+
+	tcp := &net.TCPAddr{IP: net.ParseIP("198.51.100.100"), Port: 5353}
+	if resp := query(tcp, "2464c4abcf10c957"); resp.Rcode != mdns.RcodeRefused || len(cookieOf(resp)) != 48 {
+		t.Fatalf("TCP client-only cookie = %v, want normal answer with a server cookie", resp)
+	}
+}
+
 func TestHandleRequestRejectsMultipleOPTRecords(t *testing.T) {
 	resetDNSHandlerTestConfig()
 	req := new(mdns.Msg)
@@ -363,6 +599,115 @@ func TestFinalizeResponseCapsUDPSizeAndTruncates(t *testing.T) {
 	}
 }
 
+func TestHandleRequestAddsAdditionalAddresses(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	ttl := uint32(300)
+	add := func(rrtype uint16, name string, value map[string]interface{}) {
+		t.Helper()
+		if err := zone.AddRecord(rrtype, "extra.test.", name, value, &ttl); err != nil {
+			t.Fatalf("add %s %s: %v", mdns.TypeToString[rrtype], name, err)
+		}
+	}
+	add(mdns.TypeSOA, "extra.test.", map[string]interface{}{"ns": "ns1.extra.test.", "mbox": "hostmaster.extra.test.", "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)})
+	add(mdns.TypeNS, "@", map[string]interface{}{"ns": "ns1.extra.test."})
+	add(mdns.TypeA, "ns1", map[string]interface{}{"ip": "192.0.2.53"})
+	add(mdns.TypeMX, "@", map[string]interface{}{"host": "mail.extra.test.", "priority": float64(10)})
+	add(mdns.TypeMX, "@", map[string]interface{}{"host": "mx.example.net.", "priority": float64(20)})
+	add(mdns.TypeA, "mail", map[string]interface{}{"ip": "192.0.2.25"})
+	add(mdns.TypeAAAA, "mail", map[string]interface{}{"ip": "2001:db8::25"})
+	add(mdns.TypeSRV, "_sip._tcp", map[string]interface{}{"priority": float64(10), "weight": float64(5), "port": float64(5060), "target": "mail.extra.test."})
+
+	query := func(name string, qtype uint16) *mdns.Msg {
+		t.Helper()
+		req := new(mdns.Msg)
+		req.SetQuestion(name, qtype)
+		w := &captureResponseWriter{}
+		handleRequest(w, req)
+		if w.msg == nil {
+			t.Fatalf("no response for %s", name)
+		}
+		return w.msg
+	}
+	extra := func(m *mdns.Msg) map[string]bool {
+		out := map[string]bool{}
+		for _, rr := range m.Extra {
+			out[rr.Header().Name+" "+mdns.TypeToString[rr.Header().Rrtype]] = true
+		}
+		return out
+	}
+
+	got := extra(query("extra.test.", mdns.TypeMX))
+	if !got["mail.extra.test. A"] || !got["mail.extra.test. AAAA"] || len(got) != 2 {
+		t.Fatalf("MX additional section = %v, want the in-zone A and AAAA only", got)
+	}
+	if got := extra(query("_sip._tcp.extra.test.", mdns.TypeSRV)); !got["mail.extra.test. A"] || len(got) != 2 {
+		t.Fatalf("SRV additional section = %v", got)
+	}
+	if got := extra(query("extra.test.", mdns.TypeNS)); !got["ns1.extra.test. A"] || len(got) != 1 {
+		t.Fatalf("NS additional section = %v", got)
+	}
+
+	config.AppConfig.LiveForTest().MinimalResponses = true
+	if resp := query("extra.test.", mdns.TypeMX); len(resp.Extra) != 0 || len(resp.Answer) != 2 {
+		t.Fatalf("minimal response = %v", resp)
+	}
+}
+
+func TestAdditionalTargetSVCB(t *testing.T) {
+	for _, tc := range []struct {
+		rr   string
+		want string
+	}{
+		{"svc.test. 300 IN SVCB 1 . alpn=h2", "svc.test."},
+		{"svc.test. 300 IN HTTPS 1 pool.svc.test. alpn=h2", "pool.svc.test."},
+		{"svc.test. 300 IN HTTPS 0 .", ""},
+		{"svc.test. 300 IN HTTPS 0 cdn.svc.test.", "cdn.svc.test."},
+	} {
+		rr, err := mdns.NewRR(tc.rr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.rr, err)
+		}
+		if got, _ := additionalTarget(rr); got != tc.want {
+			t.Errorf("%s: target = %q, want %q", tc.rr, got, tc.want)
+		}
+	}
+}
+
+func TestFinalizeResponseDropsAdditionalWithoutTC(t *testing.T) {
+	resetDNSHandlerTestConfig()
+	config.AppConfig.LiveForTest().MaxUDPSize = 512
+
+	req := new(mdns.Msg)
+	req.SetQuestion("big.test.", mdns.TypeMX)
+	req.SetEdns0(4096, false)
+	resp := new(mdns.Msg)
+	resp.SetReply(req)
+	resp.Authoritative = true
+	for i := 0; i < 10; i++ {
+		host := "mail" + strconv.Itoa(i) + ".big.test."
+		mx, _ := mdns.NewRR("big.test. 300 IN MX 10 " + host)
+		a, _ := mdns.NewRR(host + " 300 IN A 192.0.2.1")
+		aaaa, _ := mdns.NewRR(host + " 300 IN AAAA 2001:db8::1")
+		resp.Answer = append(resp.Answer, mx)
+		resp.Extra = append(resp.Extra, a, aaaa)
+	}
+
+	finalizeResponse(req, resp, false)
+
+	if resp.Truncated {
+		t.Fatalf("TC set although only additional data was dropped")
+	}
+	if len(resp.Answer) != 10 || resp.IsEdns0() == nil {
+		t.Fatalf("answer or OPT lost: %d answers, OPT %v", len(resp.Answer), resp.IsEdns0())
+	}
+	if len(resp.Extra) == 1 || len(resp.Extra) == 21 {
+		t.Fatalf("additional section has %d records, want it trimmed to fit", len(resp.Extra))
+	}
+	if resp.Len() > 512 {
+		t.Fatalf("response is %d bytes", resp.Len())
+	}
+}
+
 func TestHandleRequestDNSKEYAndReferralWithGlue(t *testing.T) {
 	setupDNSHandlerTestStore(t)
 	ttl := uint32(300)
@@ -452,7 +797,7 @@ func TestGlueRecordsReturnsOnlyInBailiwickAddressRecords(t *testing.T) {
 		&mdns.NS{Hdr: mdns.RR_Header{Name: "example.test.", Rrtype: mdns.TypeNS, Class: mdns.ClassINET, Ttl: ttl}, Ns: "ns.other.test."},
 	}
 
-	glue := glueRecords(nsRecords)
+	glue := glueRecords(zone.Default, nsRecords)
 	if len(glue) != 1 {
 		t.Fatalf("glue records = %d, want 1: %v", len(glue), glue)
 	}
@@ -522,7 +867,7 @@ func TestAppendRRSIGsSkipsAlreadyCoveredAndSynthesizedCNAME(t *testing.T) {
 	dname := &mdns.DNAME{Hdr: mdns.RR_Header{Name: "old.sig.test.", Rrtype: mdns.TypeDNAME, Class: mdns.ClassINET, Ttl: 300}, Target: "new.sig.test."}
 	synth := &mdns.CNAME{Hdr: mdns.RR_Header{Name: "www.old.sig.test.", Rrtype: mdns.TypeCNAME, Class: mdns.ClassINET, Ttl: 300}, Target: "www.new.sig.test."}
 
-	out := appendRRSIGs([]mdns.RR{a, existing, dname, synth})
+	out, _ := appendRRSIGs(zone.Default, []mdns.RR{a, existing, dname, synth})
 	if len(out) != 4 {
 		t.Fatalf("appendRRSIGs added unexpected records: %#v", out)
 	}
@@ -544,7 +889,7 @@ func TestWildcardSynthesisAndApexSOAHelpers(t *testing.T) {
 		t.Fatalf("Add SOA: %v", err)
 	}
 
-	wildcardRRSet, authority, ok := lookupWildcard(mdns.TypeA, "missing.wild.example.test.", false)
+	wildcardRRSet, authority, ok := lookupWildcard(zone.Default, mdns.TypeA, "missing.wild.example.test.", false)
 	if !ok || len(wildcardRRSet) != 1 || wildcardRRSet[0].Header().Name != "missing.wild.example.test." {
 		wildcardName, wildcardOK := zone.WildcardName("missing.wild.example.test.")
 		lookup, lookupOK := zone.LookupRecord(mdns.TypeA, wildcardName)
@@ -553,14 +898,14 @@ func TestWildcardSynthesisAndApexSOAHelpers(t *testing.T) {
 	if len(authority) != 0 {
 		t.Fatalf("lookupWildcard without DNSSEC returned authority = %#v", authority)
 	}
-	synthesized := synthesizeWildcard("other.wild.example.test.", wildcardRRSet, false)
+	synthesized := synthesizeWildcard(zone.Default, "other.wild.example.test.", wildcardRRSet, false)
 	if len(synthesized) != 1 || synthesized[0].Header().Name != "other.wild.example.test." {
 		t.Fatalf("synthesizeWildcard = %#v", synthesized)
 	}
-	if soa, ok := lookupApexSOA("www.example.test."); !ok || len(soa) != 1 || soa[0].Header().Rrtype != mdns.TypeSOA {
+	if soa, ok := lookupApexSOA(zone.Default, "www.example.test."); !ok || len(soa) != 1 || soa[0].Header().Rrtype != mdns.TypeSOA {
 		t.Fatalf("lookupApexSOA = %#v ok=%v", soa, ok)
 	}
-	if !authoritativeForName("www.example.test.") {
+	if !authoritativeForName(zone.Default, "www.example.test.") {
 		t.Fatalf("authoritativeForName returned false")
 	}
 }
@@ -574,7 +919,7 @@ func TestResolveAnswerChainCNAMEAndDNAME(t *testing.T) {
 	if err := zone.AddRecord(mdns.TypeCNAME, "chain.test.", "alias", map[string]interface{}{"target": "target.chain.test."}, &ttl); err != nil {
 		t.Fatalf("Add CNAME: %v", err)
 	}
-	result := resolveAnswerChain("alias.chain.test.", mdns.TypeA, false)
+	result := resolveAnswerChain(zone.Default, "alias.chain.test.", mdns.TypeA, false)
 	if result.Rcode != mdns.RcodeSuccess || len(result.Answer) != 2 {
 		t.Fatalf("CNAME resolve result = %#v", result)
 	}
@@ -591,7 +936,7 @@ func TestResolveAnswerChainCNAMEAndDNAME(t *testing.T) {
 	if err := zone.AddRecord(mdns.TypeDNAME, "other.test.", "old", map[string]interface{}{"target": "new.other.test."}, &ttl); err != nil {
 		t.Fatalf("Add DNAME: %v", err)
 	}
-	dnameResult := resolveAnswerChain("www.old.other.test.", mdns.TypeA, false)
+	dnameResult := resolveAnswerChain(zone.Default, "www.old.other.test.", mdns.TypeA, false)
 	if len(dnameResult.Answer) != 3 {
 		t.Fatalf("DNAME resolve result = %#v", dnameResult)
 	}
@@ -19,6 +19,8 @@ package dns
 import (
 	"sync"
 	"time"
+
+	"go53/dns/dnsutils"
 )
 
 // clientLimiter is a tiny per-source-IP token bucket used to cap query rate.
@@ -93,11 +95,13 @@ func (l *clientLimiter) sweep(now time.Time, idle time.Duration) {
 	}
 }
 
-// startLimiterCleanup periodically reclaims idle client buckets. It runs for
-// the lifetime of the process and is started once from the DNS server setup.
+// startLimiterCleanup periodically reclaims idle client and RRL buckets. It
+// runs for the lifetime of the process and is started once from the DNS
+// server setup.
 func startLimiterCleanup() {
 	t := time.NewTicker(time.Minute)
 	for range t.C {
 		limiter.sweep(time.Now(), 10*time.Minute)
+		dnsutils.SweepRRL(time.Now())
 	}
 }
@@ -17,9 +17,13 @@
 package dns
 
 import (
+	"net"
 	"strconv"
 	"testing"
 	"time"
+
+	mdns "github.com/miekg/dns"
+	"go53/config"
 )
 
 func TestClientLimiter(t *testing.T) {
@@ -101,3 +105,35 @@ func TestClientLimiter(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleRequestRRLSlipsOverUDPOnly(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	rrl := &config.AppConfig.LiveForTest().RRL
+	rrl.Enabled = true
+	rrl.ErrorsPerSecond = 1
+	rrl.Slip = 1
+
+	query := func(remote net.Addr) *mdns.Msg {
+		req := new(mdns.Msg)
+		req.SetQuestion("www.unknown-rrl.test.", mdns.TypeA)
+		w := &captureResponseWriter{remoteAddr: remote}
+		handleRequest(w, req)
+		return w.msg
+	}
+
+	udp := &net.UDPAddr{IP: net.ParseIP("198.51.100.77"), Port: 5353}
+	if first := query(udp); first == nil || first.Truncated || first.Rcode != mdns.RcodeRefused {
+		t.Fatalf("first response = %v, want untouched REFUSED", first)
+	}
+	slipped := query(udp)
+	if slipped == nil || !slipped.Truncated || len(slipped.Answer)+len(slipped.Ns) != 0 {
+		t.Fatalf("limited response = %v, want empty TC=1 slip", slipped)
+	}
+
+	tcp := &net.TCPAddr{IP: net.ParseIP("198.51.100.77"), Port: 5353}
+	for i := 0; i < 3; i++ {
+		if resp := query(tcp); resp == nil || resp.Truncated {
+			t.Fatalf("TCP response %d was rate limited: %v", i, resp)
+		}
+	}
+}
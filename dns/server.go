@@ -11,6 +11,30 @@ import (
 
 func Start(cfg config.BaseConfig) error {
 	addr, udpServer, tcpServer := buildDNSServers(cfg)
+	dotAddr, dotServer, err := buildDoTServer(cfg)
+	if err != nil {
+		return err
+	}
+	dohAddr, dohServer, err := buildDoHServer(cfg)
+	if err != nil {
+		return err
+	}
+	if dotServer != nil {
+		go func() {
+			log.Printf("Starting DNS-over-TLS server on %s", dotAddr)
+			if err := dotServer.ListenAndServe(); err != nil {
+				log.Printf("DNS-over-TLS server error: %v", err)
+			}
+		}()
+	}
+	if dohServer != nil {
+		go func() {
+			log.Printf("Starting DNS-over-HTTPS server on %s%s", dohAddr, dohPath)
+			if err := dohServer.ListenAndServeTLS("", ""); err != nil {
+				log.Printf("DNS-over-HTTPS server error: %v", err)
+			}
+		}()
+	}
 
 	go func() {
 		log.Printf("Starting TCP DNS server on %s", addr)
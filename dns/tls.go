@@ -0,0 +1,194 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: tls.go is part of the go53 authoritative DNS server.
+package dns
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/dns/dnsutils"
+	"go53/security"
+)
+
+// dotALPN is the ALPN protocol identifier for DNS-over-TLS (RFC 7858).
+const dotALPN = "dot"
+
+// DNS-over-TLS connection limits. Recursive resolvers talking to
+// authoritatives over TLS (ADoT) keep connections open and pipeline many
+// queries over them, so the idle timeout and the per-connection query budget
+// are far more generous than on the plain TCP listener, while a connection
+// that stalls mid-message is still dropped quickly.
+const (
+	dotIdleTimeout    = 30 * time.Second
+	dotReadTimeout    = 5 * time.Second
+	dotWriteTimeout   = 5 * time.Second
+	dotMaxConnQueries = 10000
+)
+
+// certReloader serves the configured certificate and key, re-reading both
+// files when their modification time changes. A failed reload keeps the last
+// good certificate so a half-written renewal never takes the listener down.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.changed() {
+		if err := r.reloadLocked(); err != nil {
+			log.Printf("DoT certificate reload failed, keeping previous certificate: %v", err)
+		} else {
+			log.Printf("DoT certificate reloaded from %s", r.certFile)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *certReloader) reloadLocked() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load DoT certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	return nil
+}
+
+func (r *certReloader) changed() bool {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return false
+	}
+	return !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)
+}
+
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// paddingResponseWriter pads every response written on an encrypted
+// transport according to RFC 8467 when the query was padded.
+type paddingResponseWriter struct {
+	dns.ResponseWriter
+	req *dns.Msg
+}
+
+func (w *paddingResponseWriter) WriteMsg(m *dns.Msg) error {
+	dnsutils.ApplyPadding(m, w.req)
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// ConnectionState exposes the TLS state of the wrapped writer so handlers can
+// still inspect the client certificate.
+func (w *paddingResponseWriter) ConnectionState() *tls.ConnectionState {
+	if cs, ok := w.ResponseWriter.(dns.ConnectionStater); ok {
+		return cs.ConnectionState()
+	}
+	return nil
+}
+
+// servePadded runs the normal request path with a padding response writer.
+func servePadded(w dns.ResponseWriter, r *dns.Msg) {
+	dns.DefaultServeMux.ServeDNS(&paddingResponseWriter{ResponseWriter: w, req: r}, r)
+}
+
+// dotEnabled reports whether the DNS-over-TLS listener is configured.
+func dotEnabled(cfg config.BaseConfig) bool {
+	return cfg.DoTPort != "" && cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
+}
+
+// buildDoTServer returns the DNS-over-TLS listener, or nil when it is not
+// configured. It fails when the configured certificate cannot be loaded.
+func buildDoTServer(cfg config.BaseConfig) (string, *dns.Server, error) {
+	if !dotEnabled(cfg) {
+		return "", nil, nil
+	}
+	reloader, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{dotALPN},
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.TLSClientCAFile != "" {
+		// Client certificates are optional: ordinary resolvers connect without
+		// one, while XoT secondaries may present one to authorize transfers.
+		pool, err := loadClientCAs(cfg.TLSClientCAFile)
+		if err != nil {
+			return "", nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	addr := fmt.Sprintf("%s%s", cfg.BindHost, cfg.DoTPort)
+	server := &dns.Server{
+		Addr:          addr,
+		Net:           "tcp-tls",
+		TLSConfig:     tlsConfig,
+		TsigProvider:  security.DynamicTSIGProvider{},
+		Handler:       dns.HandlerFunc(servePadded),
+		ReadTimeout:   dotReadTimeout,
+		WriteTimeout:  dotWriteTimeout,
+		IdleTimeout:   func() time.Duration { return dotIdleTimeout },
+		MaxTCPQueries: dotMaxConnQueries,
+	}
+	return addr, server, nil
+}
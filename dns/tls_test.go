@@ -0,0 +1,191 @@
+package dns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"go53/config"
+)
+
+// dotResponseBlock is the RFC 8467 response padding block length.
+const dotResponseBlock = 468
+
+func TestBuildDoTServerDisabledWithoutCertificate(t *testing.T) {
+	cfg := config.DefaultBaseConfig
+	_, server, err := buildDoTServer(cfg)
+	if err != nil || server != nil {
+		t.Fatalf("buildDoTServer without certificate = %v, %v; want disabled", server, err)
+	}
+
+	cfg.TLSCertFile = filepath.Join(t.TempDir(), "missing.pem")
+	cfg.TLSKeyFile = cfg.TLSCertFile
+	if _, _, err := buildDoTServer(cfg); err == nil {
+		t.Fatalf("buildDoTServer with unreadable certificate must fail")
+	}
+}
+
+func TestDoTServesPaddedResponsesWithALPN(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	mdns.HandleFunc(".", handleRequest)
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "dot.test")
+
+	cfg := config.DefaultBaseConfig
+	cfg.BindHost = "127.0.0.1"
+	cfg.DoTPort = ":15853"
+	cfg.TLSCertFile = certFile
+	cfg.TLSKeyFile = keyFile
+	addr, server, err := buildDoTServer(cfg)
+	if err != nil || server == nil {
+		t.Fatalf("buildDoTServer: %v", err)
+	}
+	if server.Net != "tcp-tls" || server.MaxTCPQueries != dotMaxConnQueries || server.IdleTimeout() != dotIdleTimeout {
+		t.Fatalf("DoT server limits = net %q max %d idle %s", server.Net, server.MaxTCPQueries, server.IdleTimeout())
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ListenAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("DoT server did not start")
+	}
+
+	co, err := mdns.DialWithTLS("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{dotALPN}})
+	if err != nil {
+		t.Fatalf("dial DoT: %v", err)
+	}
+	defer co.Close()
+	if proto := co.Conn.(*tls.Conn).ConnectionState().NegotiatedProtocol; proto != dotALPN {
+		t.Fatalf("ALPN = %q, want %q", proto, dotALPN)
+	}
+
+	// Two pipelined queries on the same connection, only the first padded.
+	padded := new(mdns.Msg)
+	padded.SetQuestion("www.unknown.test.", mdns.TypeA)
+	padded.SetEdns0(1232, false)
+	padded.IsEdns0().Option = append(padded.IsEdns0().Option, &mdns.EDNS0_PADDING{Padding: make([]byte, 16)})
+	plain := new(mdns.Msg)
+	plain.SetQuestion("www.unknown.test.", mdns.TypeA)
+	plain.SetEdns0(1232, false)
+	for _, q := range []*mdns.Msg{padded, plain} {
+		if err := co.WriteMsg(q); err != nil {
+			t.Fatalf("write query: %v", err)
+		}
+	}
+
+	raw, err := co.ReadMsgHeader(nil)
+	if err != nil {
+		t.Fatalf("read padded response: %v", err)
+	}
+	if len(raw)%dotResponseBlock != 0 {
+		t.Fatalf("padded response length = %d, want a multiple of %d", len(raw), dotResponseBlock)
+	}
+	resp := new(mdns.Msg)
+	if err := resp.Unpack(raw); err != nil || resp.Rcode != mdns.RcodeRefused {
+		t.Fatalf("padded response = %v, err %v", resp, err)
+	}
+
+	raw, err = co.ReadMsgHeader(nil)
+	if err != nil {
+		t.Fatalf("read unpadded response: %v", err)
+	}
+	if len(raw)%dotResponseBlock == 0 {
+		t.Fatalf("response to an unpadded query was padded to %d octets", len(raw))
+	}
+}
+
+func TestCertReloaderPicksUpRenewedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "first.test")
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	if cn := servedCommonName(t, reloader); cn != "first.test" {
+		t.Fatalf("served CN = %q, want first.test", cn)
+	}
+
+	writeTestCertificate(t, dir, "second.test")
+	touchFiles(t, time.Now().Add(time.Minute), certFile, keyFile)
+	if cn := servedCommonName(t, reloader); cn != "second.test" {
+		t.Fatalf("served CN after renewal = %q, want second.test", cn)
+	}
+
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write broken certificate: %v", err)
+	}
+	touchFiles(t, time.Now().Add(2*time.Minute), certFile)
+	if cn := servedCommonName(t, reloader); cn != "second.test" {
+		t.Fatalf("served CN after broken renewal = %q, want previous second.test", cn)
+	}
+}
+
+func servedCommonName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func touchFiles(t *testing.T, when time.Time, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		if err := os.Chtimes(f, when, when); err != nil {
+			t.Fatalf("chtimes %s: %v", f, err)
+		}
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and key for cn into
+// dir as cert.pem and key.pem, replacing any previous pair.
+func writeTestCertificate(t *testing.T, dir, cn string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
@@ -0,0 +1,107 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: views.go is part of the go53 authoritative DNS server.
+package dns
+
+import (
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/zone"
+)
+
+// queryScope selects the zones r is answered from: those of the first
+// configured view matching the client address, the TSIG key the query was
+// validly signed with and the local address it arrived on, or the default
+// zones when no view matches.
+func queryScope(w dns.ResponseWriter, r *dns.Msg, live config.LiveConfig) (zone.Scope, error) {
+	if len(live.Views) == 0 {
+		return zone.Default, nil
+	}
+	client := remoteIP(w)
+	local := localIP(w)
+	key := validTSIGKey(w, r)
+	for _, v := range live.Views {
+		if viewMatches(v, client, local, key) {
+			return zone.ForView(v.Name, v.Overlay)
+		}
+	}
+	return zone.Default, nil
+}
+
+func viewMatches(v config.ViewConfig, client, local net.IP, key string) bool {
+	if len(v.MatchClients) > 0 && !addressListContains(v.MatchClients, client) {
+		return false
+	}
+	if len(v.MatchDestinations) > 0 && !addressListContains(v.MatchDestinations, local) {
+		return false
+	}
+	if len(v.MatchKeys) > 0 {
+		if key == "" {
+			return false
+		}
+		for _, name := range v.MatchKeys {
+			if strings.EqualFold(dns.Fqdn(strings.TrimSpace(name)), key) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// addressListContains reports whether ip is one of the IPs or inside one of
+// the CIDRs in entries.
+func addressListContains(entries []string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			if n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(entry); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validTSIGKey returns the name of the key r was signed with when the
+// signature verified, and "" otherwise.
+func validTSIGKey(w dns.ResponseWriter, r *dns.Msg) string {
+	tsig := r.IsTsig()
+	if tsig == nil || w.TsigStatus() != nil {
+		return ""
+	}
+	return dns.Fqdn(tsig.Hdr.Name)
+}
+
+func localIP(w dns.ResponseWriter) net.IP {
+	switch addr := w.LocalAddr().(type) {
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.TCPAddr:
+		return addr.IP
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,212 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"go53/config"
+	"go53/security"
+	"go53/zone"
+)
+
+type viewResponseWriter struct {
+	captureResponseWriter
+	localAddr net.Addr
+	msgs      []*mdns.Msg
+}
+
+func (w *viewResponseWriter) LocalAddr() net.Addr {
+	if w.localAddr != nil {
+		return w.localAddr
+	}
+	return w.captureResponseWriter.LocalAddr()
+}
+
+func (w *viewResponseWriter) WriteMsg(m *mdns.Msg) error {
+	w.msgs = append(w.msgs, m)
+	return w.captureResponseWriter.WriteMsg(m)
+}
+
+func TestViewMatches(t *testing.T) {
+	v := config.ViewConfig{
+		Name:              "internal",
+		MatchClients:      []string{"10.0.0.0/8", "192.0.2.7"},
+		MatchKeys:         []string{"Internal-Key"},
+		MatchDestinations: []string{"198.51.100.53"},
+	}
+	client := net.ParseIP("10.1.2.3")
+	local := net.ParseIP("198.51.100.53")
+	cases := []struct {
+		name   string
+		client net.IP
+		local  net.IP
+		key    string
+		want   bool
+	}{
+		{"all criteria", client, local, "internal-key.", true},
+		{"single client address", net.ParseIP("192.0.2.7"), local, "internal-key.", true},
+		{"other client", net.ParseIP("192.0.2.8"), local, "internal-key.", false},
+		{"other listener", client, net.ParseIP("198.51.100.54"), "internal-key.", false},
+		{"unsigned", client, local, "", false},
+		{"other key", client, local, "other.", false},
+	}
+	for _, tc := range cases {
+		if got := viewMatches(v, tc.client, tc.local, tc.key); got != tc.want {
+			t.Fatalf("%s: viewMatches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if !viewMatches(config.ViewConfig{Name: "any"}, nil, nil, "") {
+		t.Fatalf("view without match lists did not match everything")
+	}
+}
+
+func TestHandleRequestAnswersFromMatchingView(t *testing.T) {
+	setupViewTestStore(t)
+	addViewTestZone(t, zone.Default, "split.test.", map[string]string{"www": "192.0.2.1", "mail": "192.0.2.25"})
+	internal, err := zone.ForView("internal", false)
+	if err != nil {
+		t.Fatalf("ForView internal: %v", err)
+	}
+	addViewTestZone(t, internal, "split.test.", map[string]string{"www": "10.0.0.1"})
+	vpn, err := zone.ForView("vpn", true)
+	if err != nil {
+		t.Fatalf("ForView vpn: %v", err)
+	}
+	if err := vpn.AddRecord(mdns.TypeA, "split.test.", "www", map[string]interface{}{"ip": "172.16.0.1"}, nil); err != nil {
+		t.Fatalf("add overlay A: %v", err)
+	}
+	config.AppConfig.LiveForTest().Views = []config.ViewConfig{
+		{Name: "internal", MatchClients: []string{"10.0.0.0/8"}},
+		{Name: "vpn", MatchDestinations: []string{"198.51.100.53"}, Overlay: true},
+	}
+
+	cases := []struct {
+		name   string
+		client string
+		local  string
+		qname  string
+		rcode  int
+		want   string
+	}{
+		{"default www", "192.0.2.100", "", "www.split.test.", mdns.RcodeSuccess, "192.0.2.1"},
+		{"internal www", "10.1.2.3", "", "www.split.test.", mdns.RcodeSuccess, "10.0.0.1"},
+		{"internal copy has no mail", "10.1.2.3", "", "mail.split.test.", mdns.RcodeNameError, ""},
+		{"overlay www", "192.0.2.100", "198.51.100.53", "www.split.test.", mdns.RcodeSuccess, "172.16.0.1"},
+		{"overlay falls back to base", "192.0.2.100", "198.51.100.53", "mail.split.test.", mdns.RcodeSuccess, "192.0.2.25"},
+	}
+	for _, tc := range cases {
+		w := &viewResponseWriter{captureResponseWriter: captureResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP(tc.client), Port: 5353}}}
+		if tc.local != "" {
+			w.localAddr = &net.UDPAddr{IP: net.ParseIP(tc.local), Port: 53}
+		}
+		req := new(mdns.Msg)
+		req.SetQuestion(tc.qname, mdns.TypeA)
+		handleRequest(w, req)
+		if w.msg == nil || w.msg.Rcode != tc.rcode {
+			t.Fatalf("%s: response = %v", tc.name, w.msg)
+		}
+		if tc.want == "" {
+			continue
+		}
+		if len(w.msg.Answer) != 1 {
+			t.Fatalf("%s: answer = %v", tc.name, w.msg.Answer)
+		}
+		if a, ok := w.msg.Answer[0].(*mdns.A); !ok || a.A.String() != tc.want {
+			t.Fatalf("%s: answer = %v, want %s", tc.name, w.msg.Answer[0], tc.want)
+		}
+	}
+
+	// The overlay view answers from the merged zone and follows later
+	// changes to the default zone once the merge is rebuilt in the
+	// background.
+	if err := zone.AddRecord(mdns.TypeA, "split.test.", "ftp", map[string]interface{}{"ip": "192.0.2.21"}, nil); err != nil {
+		t.Fatalf("add base A: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rrs, ok := vpn.LookupRecord(mdns.TypeA, "ftp.split.test.")
+		if ok && len(rrs) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("overlay did not pick up new base record: %v ok=%v", rrs, ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Writes to the overlay itself are visible as soon as they return.
+	if err := vpn.AddRecord(mdns.TypeA, "split.test.", "mail", map[string]interface{}{"ip": "172.16.0.25"}, nil); err != nil {
+		t.Fatalf("add overlay mail A: %v", err)
+	}
+	if rrs, ok := vpn.LookupRecord(mdns.TypeA, "mail.split.test."); !ok || len(rrs) != 1 || rrs[0].(*mdns.A).A.String() != "172.16.0.25" {
+		t.Fatalf("overlay write not visible: %v ok=%v", rrs, ok)
+	}
+}
+
+func TestHandleRequestAXFRUsesViewOfTSIGKey(t *testing.T) {
+	setupViewTestStore(t)
+	live := config.AppConfig.LiveForTest()
+	live.AllowAXFR = true
+	live.Views = []config.ViewConfig{{Name: "partner", MatchKeys: []string{"xfr-partner."}}}
+	security.SetTSIGKey("xfr-partner.", security.TSIGKey{Algorithm: mdns.HmacSHA256, Secret: "c2VjcmV0c2VjcmV0c2VjcmV0"})
+	t.Cleanup(func() { security.DeleteTSIGKey("xfr-partner.") })
+
+	addViewTestZone(t, zone.Default, "xfr.test.", map[string]string{"www": "192.0.2.1"})
+	partner, err := zone.ForView("partner", false)
+	if err != nil {
+		t.Fatalf("ForView partner: %v", err)
+	}
+	addViewTestZone(t, partner, "xfr.test.", map[string]string{"www": "203.0.113.1"})
+
+	transfer := func(signed bool) string {
+		t.Helper()
+		req := new(mdns.Msg)
+		req.SetQuestion("xfr.test.", mdns.TypeAXFR)
+		if signed {
+			req.SetTsig("xfr-partner.", mdns.HmacSHA256, 300, time.Now().Unix())
+		}
+		w := &viewResponseWriter{captureResponseWriter: captureResponseWriter{remoteAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5353}}}
+		handleRequest(w, req)
+		for _, m := range w.msgs {
+			for _, rr := range m.Answer {
+				if a, ok := rr.(*mdns.A); ok {
+					return a.A.String()
+				}
+			}
+		}
+		t.Fatalf("AXFR signed=%v returned no A record: %v", signed, w.msgs)
+		return ""
+	}
+	if got := transfer(true); got != "203.0.113.1" {
+		t.Fatalf("AXFR with the view key = %s, want the view's copy", got)
+	}
+	if got := transfer(false); got != "192.0.2.1" {
+		t.Fatalf("unsigned AXFR = %s, want the default zone", got)
+	}
+}
+
+func setupViewTestStore(t *testing.T) {
+	t.Helper()
+	setupDNSHandlerTestStore(t)
+	zone.ResetViews()
+	t.Cleanup(zone.ResetViews)
+}
+
+func addViewTestZone(t *testing.T, zones zone.Scope, zoneName string, hosts map[string]string) {
+	t.Helper()
+	ttl := uint32(300)
+	soa := map[string]interface{}{"ns": "ns1." + zoneName, "mbox": "hostmaster." + zoneName, "serial": float64(1), "refresh": float64(3600), "retry": float64(600), "expire": float64(86400), "minimum": float64(300)}
+	if err := zones.AddRecord(mdns.TypeSOA, zoneName, zoneName, soa, &ttl); err != nil {
+		t.Fatalf("add SOA to %q: %v", zones.View(), err)
+	}
+	if err := zones.AddRecord(mdns.TypeNS, zoneName, "@", map[string]interface{}{"ns": "ns1." + zoneName}, &ttl); err != nil {
+		t.Fatalf("add NS to %q: %v", zones.View(), err)
+	}
+	for host, ip := range hosts {
+		if err := zones.AddRecord(mdns.TypeA, zoneName, host, map[string]interface{}{"ip": ip}, &ttl); err != nil {
+			t.Fatalf("add A %s to %q: %v", host, zones.View(), err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: xot.go is part of the go53 authoritative DNS server.
+package dns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/zonemeta"
+)
+
+// transferRequestAllowed applies the transfer ACLs to an AXFR/IXFR request.
+// Over TLS (XoT, RFC 9103) a verified client certificate listed in
+// xot.allow_clients authorizes the transfer by itself; any other client falls
+// back to the address list (see transferAddressAllowed) unless
+// xot.require_client_cert is set. Cleartext transfers are refused outright
+// when xot.require_tls is set. TSIG is checked separately by the caller in
+// every case.
+func transferRequestAllowed(w dns.ResponseWriter, r *dns.Msg, live config.LiveConfig) bool {
+	state := tlsConnectionState(w)
+	if state == nil {
+		if live.XoT.RequireTLS {
+			return false
+		}
+		return transferAddressAllowed(w, r, live)
+	}
+	if clientCertificateAllowed(state, live.XoT.AllowClients) {
+		return true
+	}
+	if live.XoT.RequireClientCert {
+		return false
+	}
+	return transferAddressAllowed(w, r, live)
+}
+
+// transferAddressAllowed checks the client against the allow_transfer setting
+// of the zone asked for, or the global allow_transfer list when the zone has
+// none. Zone entries bound to a TSIG key only match requests signed with it.
+func transferAddressAllowed(w dns.ResponseWriter, r *dns.Msg, live config.LiveConfig) bool {
+	acl := zoneTransferACL(r)
+	if len(acl) == 0 {
+		return transferClientAllowed(w.RemoteAddr().String(), live.AllowTransfer)
+	}
+	keyName := ""
+	if tsig := r.IsTsig(); tsig != nil {
+		keyName = tsig.Hdr.Name
+	}
+	client := remoteIP(w)
+	for _, entry := range acl {
+		if entry.Matches(client) && (entry.TSIGKey == "" || strings.EqualFold(entry.TSIGKey, keyName)) {
+			return true
+		}
+	}
+	return false
+}
+
+func zoneTransferACL(r *dns.Msg) []zonemeta.TransferACLEntry {
+	if r == nil || len(r.Question) == 0 {
+		return nil
+	}
+	meta, err := zonemeta.Load(r.Question[0].Name)
+	if err != nil {
+		return nil
+	}
+	return meta.AllowTransfer
+}
+
+// tlsConnectionState returns the TLS state of the connection w answers on, or
+// nil for cleartext UDP and TCP.
+func tlsConnectionState(w dns.ResponseWriter) *tls.ConnectionState {
+	if cs, ok := w.(dns.ConnectionStater); ok {
+		return cs.ConnectionState()
+	}
+	return nil
+}
+
+// clientCertificateAllowed reports whether the client presented a certificate
+// that chained to a configured client CA and whose common name or one of whose
+// DNS names is listed in allowed.
+func clientCertificateAllowed(state *tls.ConnectionState, allowed []string) bool {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return false
+	}
+	leaf := state.VerifiedChains[0][0]
+	names := append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+	for _, entry := range allowed {
+		entry = strings.TrimSuffix(strings.TrimSpace(entry), ".")
+		if entry == "" {
+			continue
+		}
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSuffix(name, "."), entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadClientCAs reads the PEM bundle of CAs trusted for client certificates.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read DoT client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
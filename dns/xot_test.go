@@ -0,0 +1,102 @@
+package dns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	mdns "github.com/miekg/dns"
+	"go53/config"
+	"go53/zonemeta"
+)
+
+// tlsCaptureResponseWriter is a captureResponseWriter on a TLS connection.
+type tlsCaptureResponseWriter struct {
+	captureResponseWriter
+	state tls.ConnectionState
+}
+
+func (w *tlsCaptureResponseWriter) ConnectionState() *tls.ConnectionState {
+	return &w.state
+}
+
+func TestTransferRequestAllowedOverTLS(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.20"), Port: 40000}
+	secondary := &x509.Certificate{Subject: pkix.Name{CommonName: "other"}, DNSNames: []string{"ns2.example."}}
+	verified := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{secondary}}}
+	unverified := tls.ConnectionState{PeerCertificates: []*x509.Certificate{secondary}}
+
+	tests := []struct {
+		name  string
+		w     mdns.ResponseWriter
+		xot   config.XoTConfig
+		allow string
+		want  bool
+	}{
+		{name: "cleartext in acl", w: &captureResponseWriter{remoteAddr: remote}, allow: "192.0.2.20", want: true},
+		{name: "cleartext refused when tls required", w: &captureResponseWriter{remoteAddr: remote}, allow: "192.0.2.20", xot: config.XoTConfig{RequireTLS: true}, want: false},
+		{name: "listed certificate bypasses address acl", w: &tlsCaptureResponseWriter{captureResponseWriter{remoteAddr: remote}, verified}, allow: "127.0.0.1", xot: config.XoTConfig{RequireTLS: true, AllowClients: []string{"NS2.example"}}, want: true},
+		{name: "unverified certificate is ignored", w: &tlsCaptureResponseWriter{captureResponseWriter{remoteAddr: remote}, unverified}, allow: "127.0.0.1", xot: config.XoTConfig{AllowClients: []string{"ns2.example"}}, want: false},
+		{name: "tls without certificate uses address acl", w: &tlsCaptureResponseWriter{captureResponseWriter{remoteAddr: remote}, tls.ConnectionState{}}, allow: "192.0.2.20", want: true},
+		{name: "client certificate required", w: &tlsCaptureResponseWriter{captureResponseWriter{remoteAddr: remote}, tls.ConnectionState{}}, allow: "192.0.2.20", xot: config.XoTConfig{RequireClientCert: true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := config.DefaultLiveConfig
+			live.AllowTransfer = tt.allow
+			live.XoT = tt.xot
+			if got := transferRequestAllowed(tt.w, nil, live); got != tt.want {
+				t.Fatalf("transferRequestAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferRequestAllowedUsesZoneACL(t *testing.T) {
+	setupDNSHandlerTestStore(t)
+	if err := zonemeta.SaveSettings("acl.test.", zonemeta.Settings{AllowTransfer: []zonemeta.TransferACLEntry{
+		{Address: "198.51.100.0/24"},
+		{Address: "203.0.113.5", TSIGKey: "cust-b."},
+	}}); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	live := config.DefaultLiveConfig
+	live.AllowTransfer = "192.0.2.20"
+
+	request := func(zoneName, key string) *mdns.Msg {
+		r := new(mdns.Msg)
+		r.SetAxfr(zoneName)
+		if key != "" {
+			r.SetTsig(key, mdns.HmacSHA256, 300, 0)
+		}
+		return r
+	}
+	writer := func(ip string) mdns.ResponseWriter {
+		return &captureResponseWriter{remoteAddr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}}
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		zone string
+		key  string
+		want bool
+	}{
+		{name: "global list still applies to other zones", ip: "192.0.2.20", zone: "other.test.", want: true},
+		{name: "zone acl replaces the global list", ip: "192.0.2.20", zone: "acl.test.", want: false},
+		{name: "prefix match", ip: "198.51.100.77", zone: "acl.test.", want: true},
+		{name: "key-bound entry without TSIG", ip: "203.0.113.5", zone: "acl.test.", want: false},
+		{name: "key-bound entry with another key", ip: "203.0.113.5", zone: "acl.test.", key: "other.", want: false},
+		{name: "key-bound entry with its key", ip: "203.0.113.5", zone: "acl.test.", key: "cust-b.", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transferRequestAllowed(writer(tt.ip), request(tt.zone, tt.key), live); got != tt.want {
+				t.Fatalf("transferRequestAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/miekg/dns v1.1.66
+	github.com/miekg/pkcs11 v1.1.2
 )
 
 require (
@@ -0,0 +1,164 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: dane.go is part of the go53 authoritative DNS server.
+
+package internal
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DANE parameter mnemonics from RFC 7218, shared by TLSA (RFC 6698) and
+// SMIMEA (RFC 8162). 255 is reserved for private use in each registry.
+var (
+	DANEUsages = map[string]uint8{
+		"PKIX-TA": 0, "PKIX-EE": 1, "DANE-TA": 2, "DANE-EE": 3, "PRIVCERT": 255,
+	}
+	DANESelectors = map[string]uint8{
+		"CERT": 0, "SPKI": 1, "PRIVSEL": 255,
+	}
+	DANEMatchingTypes = map[string]uint8{
+		"FULL": 0, "SHA2-256": 1, "SHA2-512": 2, "PRIVMATCH": 255,
+	}
+)
+
+// DANECode resolves a DANE parameter given as a JSON number or an RFC 7218
+// mnemonic against one of the registries above.
+func DANECode(codes map[string]uint8, raw any) (uint8, error) {
+	switch v := raw.(type) {
+	case string:
+		code, ok := codes[strings.ToUpper(strings.TrimSpace(v))]
+		if !ok {
+			return 0, fmt.Errorf("unknown mnemonic %q", v)
+		}
+		return code, nil
+	case float64:
+		if v == math.Trunc(v) && v >= 0 && v <= 255 {
+			for _, code := range codes {
+				if float64(code) == v {
+					return code, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unsupported value %v", v)
+	default:
+		return 0, fmt.Errorf("must be a number or mnemonic, got %T", raw)
+	}
+}
+
+// ValidateDANEData checks certificate association data against its matching
+// type and returns it as uppercase hex. SHA2-256 and SHA2-512 digests must
+// have their exact length; full data and private matching types only need
+// to be non-empty.
+func ValidateDANEData(matchingType uint8, data string) (string, error) {
+	data = strings.ToUpper(strings.Join(strings.Fields(data), ""))
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return "", errors.New("certificate association data must be hex")
+	}
+	want := map[uint8]int{1: sha256.Size, 2: sha512.Size}[matchingType]
+	switch {
+	case len(raw) == 0:
+		return "", errors.New("certificate association data must not be empty")
+	case want != 0 && len(raw) != want:
+		return "", fmt.Errorf("matching type %d expects %d bytes of data, got %d", matchingType, want, len(raw))
+	}
+	return data, nil
+}
+
+// DANEAssociation computes TLSA/SMIMEA certificate association data from PEM
+// input. index picks the CERTIFICATE or PUBLIC KEY block to use, so DANE-TA
+// records can be built from the issuer in a full chain. A bare public key
+// only supports the SPKI selector. Other PEM blocks, such as a private key in
+// a combined file, are skipped.
+func DANEAssociation(pemData []byte, index int, selector, matchingType uint8) (string, error) {
+	var blocks []*pem.Block
+	for rest := pemData; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" || block.Type == "PUBLIC KEY" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		return "", errors.New("no CERTIFICATE or PUBLIC KEY block in PEM input")
+	}
+	if index < 0 || index >= len(blocks) {
+		return "", fmt.Errorf("chain index %d out of range, PEM input has %d usable blocks", index, len(blocks))
+	}
+
+	var data []byte
+	block := blocks[index]
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("parse certificate: %w", err)
+		}
+		switch selector {
+		case 0:
+			data = cert.Raw
+		case 1:
+			data = cert.RawSubjectPublicKeyInfo
+		}
+	case "PUBLIC KEY":
+		if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return "", fmt.Errorf("parse public key: %w", err)
+		}
+		if selector != 1 {
+			return "", errors.New("a public key only supports selector 1 (SPKI)")
+		}
+		data = block.Bytes
+	}
+	if data == nil {
+		return "", fmt.Errorf("unsupported selector %d", selector)
+	}
+
+	switch matchingType {
+	case 0:
+	case 1:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case 2:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported matching type %d", matchingType)
+	}
+	return strings.ToUpper(hex.EncodeToString(data)), nil
+}
+
+// SMIMEAOwner returns the SMIMEA owner name for an e-mail address (RFC 8162
+// section 3): the SHA2-256 hash of the local part truncated to 28 octets,
+// under _smimecert of the address domain.
+func SMIMEAOwner(email string) (string, error) {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid e-mail address %q", email)
+	}
+	sum := sha256.Sum256([]byte(email[:at]))
+	return hex.EncodeToString(sum[:28]) + "._smimecert." + strings.ToLower(strings.TrimSuffix(email[at+1:], ".")) + ".", nil
+}
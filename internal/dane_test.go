@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSMIMEAOwner(t *testing.T) {
+	// Local-part hash from the RFC 7929 section 3 example, which RFC 8162
+	// reuses under _smimecert.
+	got, err := SMIMEAOwner("hugh@Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._smimecert.example.com."; got != want {
+		t.Fatalf("SMIMEAOwner = %q, want %q", got, want)
+	}
+	for _, bad := range []string{"hugh", "@example.com", "hugh@"} {
+		if _, err := SMIMEAOwner(bad); err == nil {
+			t.Errorf("SMIMEAOwner(%q) accepted invalid address", bad)
+		}
+	}
+}
+
+func TestDANECodeAndData(t *testing.T) {
+	if v, err := DANECode(DANEUsages, "dane-ee"); err != nil || v != 3 {
+		t.Fatalf("DANECode(dane-ee) = %d, %v", v, err)
+	}
+	if v, err := DANECode(DANEMatchingTypes, float64(255)); err != nil || v != 255 {
+		t.Fatalf("DANECode(255) = %d, %v", v, err)
+	}
+	for _, raw := range []any{float64(4), float64(1.5), "SHA1", true} {
+		if _, err := DANECode(DANEUsages, raw); err == nil {
+			t.Errorf("DANECode(%v) accepted invalid usage", raw)
+		}
+	}
+
+	digest := strings.Repeat("ab", 32)
+	if got, err := ValidateDANEData(1, digest); err != nil || got != strings.ToUpper(digest) {
+		t.Fatalf("ValidateDANEData = %q, %v", got, err)
+	}
+	if _, err := ValidateDANEData(2, digest); err == nil {
+		t.Fatalf("ValidateDANEData accepted a SHA2-256 digest for SHA2-512")
+	}
+	if _, err := ValidateDANEData(0, "zz"); err == nil {
+		t.Fatalf("ValidateDANEData accepted non-hex data")
+	}
+}
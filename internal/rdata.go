@@ -0,0 +1,330 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: rdata.go is part of the go53 authoritative DNS server.
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+// DecodeRecords converts a stored RRset value into typed records. Freshly added
+// records are held as typed slices while records reloaded from storage are
+// JSON-decoded []interface{} values; both round-trip through JSON here so the
+// two forms behave identically.
+func DecodeRecords[T any](val any) []T {
+	if recs, ok := val.([]T); ok {
+		return recs
+	}
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+	var recs []T
+	if err := json.Unmarshal(raw, &recs); err != nil {
+		return nil
+	}
+	return recs
+}
+
+// svcParamKeys lists the SvcParamKeys (RFC 9460 section 14.3.2) go53 accepts by
+// name. Other keys can be given in the generic keyNNNNN form.
+var svcParamKeys = map[string]dns.SVCBKey{
+	"mandatory":       dns.SVCB_MANDATORY,
+	"alpn":            dns.SVCB_ALPN,
+	"no-default-alpn": dns.SVCB_NO_DEFAULT_ALPN,
+	"port":            dns.SVCB_PORT,
+	"ipv4hint":        dns.SVCB_IPV4HINT,
+	"ech":             dns.SVCB_ECHCONFIG,
+	"ipv6hint":        dns.SVCB_IPV6HINT,
+	"dohpath":         dns.SVCB_DOHPATH,
+}
+
+// ParseSVCBParams validates the SvcParams of an SVCB or HTTPS record given in
+// presentation form (key -> value) and returns them in wire order.
+//
+// Values follow RFC 9460 section 7: alpn, mandatory, ipv4hint and ipv6hint are
+// comma-separated lists, port is a number, ech is base64 and no-default-alpn
+// takes no value. The cross-key rules of sections 7.1.1 and 8 are enforced:
+// no-default-alpn needs alpn, and every key listed in mandatory must be present
+// and must not be mandatory itself.
+func ParseSVCBParams(params map[string]string) ([]dns.SVCBKeyValue, error) {
+	out := make([]dns.SVCBKeyValue, 0, len(params))
+	seen := map[dns.SVCBKey]bool{}
+	for rawKey, value := range params {
+		key, err := svcParamKey(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("SvcParam %q given twice", rawKey)
+		}
+		seen[key] = true
+		kv, err := svcParamValue(key, strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("SvcParam %s: %w", key, err)
+		}
+		out = append(out, kv)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key() < out[j].Key() })
+
+	if seen[dns.SVCB_NO_DEFAULT_ALPN] && !seen[dns.SVCB_ALPN] {
+		return nil, fmt.Errorf("SvcParam no-default-alpn requires alpn")
+	}
+	for _, kv := range out {
+		mandatory, ok := kv.(*dns.SVCBMandatory)
+		if !ok {
+			continue
+		}
+		for _, key := range mandatory.Code {
+			if key == dns.SVCB_MANDATORY {
+				return nil, fmt.Errorf("SvcParam mandatory must not list itself")
+			}
+			if !seen[key] {
+				return nil, fmt.Errorf("SvcParam mandatory lists %s, which is not present", key)
+			}
+		}
+	}
+	return out, nil
+}
+
+// SVCBParamsToMap returns SvcParams in the presentation form stored in
+// types.SVCBRecord and types.HTTPSRecord.
+func SVCBParamsToMap(kvs []dns.SVCBKeyValue) map[string]string {
+	out := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		out[kv.Key().String()] = kv.String()
+	}
+	return out
+}
+
+func svcParamKey(name string) (dns.SVCBKey, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if key, ok := svcParamKeys[name]; ok {
+		return key, nil
+	}
+	if strings.HasPrefix(name, "key") {
+		n, err := strconv.ParseUint(strings.TrimPrefix(name, "key"), 10, 16)
+		if err == nil && n != 65535 {
+			return dns.SVCBKey(n), nil
+		}
+	}
+	// Keys registered after the ones above are known to miekg/dns by name
+	// only and are carried as opaque values.
+	for code := dns.SVCBKey(0); code < 64; code++ {
+		if code.String() == name {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported SvcParam key %q", name)
+}
+
+func svcParamValue(key dns.SVCBKey, value string) (dns.SVCBKeyValue, error) {
+	switch key {
+	case dns.SVCB_MANDATORY:
+		names := splitSvcList(value)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("needs at least one key")
+		}
+		codes := make([]dns.SVCBKey, 0, len(names))
+		for _, name := range names {
+			code, err := svcParamKey(name)
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		for i := 1; i < len(codes); i++ {
+			if codes[i] == codes[i-1] {
+				return nil, fmt.Errorf("key %s listed twice", codes[i])
+			}
+		}
+		return &dns.SVCBMandatory{Code: codes}, nil
+	case dns.SVCB_ALPN:
+		ids := splitSvcList(value)
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("needs at least one protocol id")
+		}
+		for _, id := range ids {
+			if id == "" || len(id) > 255 {
+				return nil, fmt.Errorf("protocol id %q must be 1 to 255 octets", id)
+			}
+		}
+		return &dns.SVCBAlpn{Alpn: ids}, nil
+	case dns.SVCB_NO_DEFAULT_ALPN:
+		if value != "" {
+			return nil, fmt.Errorf("takes no value")
+		}
+		return &dns.SVCBNoDefaultAlpn{}, nil
+	case dns.SVCB_PORT:
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		return &dns.SVCBPort{Port: uint16(port)}, nil
+	case dns.SVCB_IPV4HINT, dns.SVCB_IPV6HINT:
+		addrs := splitSvcList(value)
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("needs at least one address")
+		}
+		hints := make([]net.IP, 0, len(addrs))
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			isV4 := ip != nil && ip.To4() != nil && !strings.Contains(addr, ":")
+			if ip == nil || isV4 != (key == dns.SVCB_IPV4HINT) {
+				return nil, fmt.Errorf("invalid address %q", addr)
+			}
+			hints = append(hints, ip)
+		}
+		if key == dns.SVCB_IPV4HINT {
+			return &dns.SVCBIPv4Hint{Hint: hints}, nil
+		}
+		return &dns.SVCBIPv6Hint{Hint: hints}, nil
+	case dns.SVCB_ECHCONFIG:
+		ech, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(ech) == 0 {
+			return nil, fmt.Errorf("ech must be a non-empty base64 ECHConfigList")
+		}
+		return &dns.SVCBECHConfig{ECH: ech}, nil
+	case dns.SVCB_DOHPATH:
+		if value == "" {
+			return nil, fmt.Errorf("needs a URI template")
+		}
+		return &dns.SVCBDoHPath{Template: value}, nil
+	default:
+		return &dns.SVCBLocal{KeyCode: key, Data: []byte(value)}, nil
+	}
+}
+
+func splitSvcList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	parts := strings.Split(value, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// LOC precision defaults from RFC 1876 section 3, in meters.
+const (
+	LOCDefaultSize     = 1
+	LOCDefaultHorizPre = 10000
+	LOCDefaultVertPre  = 10
+)
+
+// ValidateLOC checks that a LOC record is representable on the wire
+// (RFC 1876 section 2).
+func ValidateLOC(rec types.LOCRecord) error {
+	switch {
+	case math.IsNaN(rec.Latitude) || rec.Latitude < -90 || rec.Latitude > 90:
+		return fmt.Errorf("latitude must be between -90 and 90 degrees")
+	case math.IsNaN(rec.Longitude) || rec.Longitude < -180 || rec.Longitude > 180:
+		return fmt.Errorf("longitude must be between -180 and 180 degrees")
+	case math.IsNaN(rec.Altitude) || rec.Altitude < -100000 || rec.Altitude > 42849672.95:
+		return fmt.Errorf("altitude must be between -100000 and 42849672.95 meters")
+	}
+	for field, v := range map[string]float64{"size": rec.Size, "horiz_pre": rec.HorizPre, "vert_pre": rec.VertPre} {
+		if math.IsNaN(v) || v < 0 || v > 90000000 {
+			return fmt.Errorf("%s must be between 0 and 90000000 meters", field)
+		}
+	}
+	return nil
+}
+
+// LOCToRR encodes a LOC record: coordinates in thousandths of an arc second
+// offset from the equator and prime meridian, altitude in centimeters above
+// 100000 m below the WGS 84 spheroid, and sizes as mantissa/exponent pairs.
+func LOCToRR(name string, rec types.LOCRecord) *dns.LOC {
+	return &dns.LOC{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeLOC, Class: dns.ClassINET, Ttl: rec.TTL},
+		Version:   0,
+		Size:      locPrecision(rec.Size),
+		HorizPre:  locPrecision(rec.HorizPre),
+		VertPre:   locPrecision(rec.VertPre),
+		Latitude:  uint32(int64(dns.LOC_EQUATOR) + int64(math.Round(rec.Latitude*dns.LOC_DEGREES))),
+		Longitude: uint32(int64(dns.LOC_PRIMEMERIDIAN) + int64(math.Round(rec.Longitude*dns.LOC_DEGREES))),
+		Altitude:  uint32(int64(math.Round(rec.Altitude*100)) + dns.LOC_ALTITUDEBASE*100),
+	}
+}
+
+// LOCFromRR is the inverse of LOCToRR.
+func LOCFromRR(rr *dns.LOC) types.LOCRecord {
+	return types.LOCRecord{
+		Latitude:  float64(int64(rr.Latitude)-int64(dns.LOC_EQUATOR)) / dns.LOC_DEGREES,
+		Longitude: float64(int64(rr.Longitude)-int64(dns.LOC_PRIMEMERIDIAN)) / dns.LOC_DEGREES,
+		Altitude:  float64(int64(rr.Altitude)-dns.LOC_ALTITUDEBASE*100) / 100,
+		Size:      locMeters(rr.Size),
+		HorizPre:  locMeters(rr.HorizPre),
+		VertPre:   locMeters(rr.VertPre),
+		TTL:       rr.Hdr.Ttl,
+	}
+}
+
+// locPrecision encodes meters as the RFC 1876 size format: the high nibble is
+// a mantissa and the low nibble a power of ten, in centimeters.
+func locPrecision(meters float64) uint8 {
+	cm := math.Round(meters * 100)
+	exp := uint8(0)
+	for cm > 9 && exp < 9 {
+		cm = math.Round(cm / 10)
+		exp++
+	}
+	if cm > 9 {
+		cm = 9
+	}
+	return uint8(cm)<<4 | exp
+}
+
+func locMeters(v uint8) float64 {
+	return float64(v>>4) * math.Pow10(int(v&0x0f)) / 100
+}
+
+// APLPrefix parses one APL item (RFC 3123). The address family is derived from
+// the prefix when family is 0 and must match it otherwise.
+func APLPrefix(family uint16, prefix string, negation bool) (dns.APLPrefix, error) {
+	_, network, err := net.ParseCIDR(strings.TrimSpace(prefix))
+	if err != nil {
+		return dns.APLPrefix{}, fmt.Errorf("invalid prefix %q", prefix)
+	}
+	derived := uint16(2)
+	if network.IP.To4() != nil {
+		derived = 1
+		network.IP = network.IP.To4()
+	}
+	if family != 0 && family != derived {
+		return dns.APLPrefix{}, fmt.Errorf("address family %d does not match prefix %s", family, prefix)
+	}
+	return dns.APLPrefix{Negation: negation, Network: *network}, nil
+}
+
+// APLFamily returns the RFC 3123 address family of a parsed APL item.
+func APLFamily(p dns.APLPrefix) uint16 {
+	if p.Network.IP.To4() != nil {
+		return 1
+	}
+	return 2
+}
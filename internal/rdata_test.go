@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"math"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"go53/types"
+)
+
+func TestParseSVCBParams(t *testing.T) {
+	kvs, err := ParseSVCBParams(map[string]string{
+		"port":      "443",
+		"alpn":      "h3,h2",
+		"mandatory": "alpn,port",
+		"key65001":  "x",
+	})
+	if err != nil {
+		t.Fatalf("ParseSVCBParams: %v", err)
+	}
+	var keys []dns.SVCBKey
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key())
+	}
+	if len(keys) != 4 || keys[0] != dns.SVCB_MANDATORY || keys[1] != dns.SVCB_ALPN || keys[2] != dns.SVCB_PORT || keys[3] != 65001 {
+		t.Fatalf("unexpected key order: %v", keys)
+	}
+	back := SVCBParamsToMap(kvs)
+	if back["alpn"] != "h3,h2" || back["port"] != "443" || back["mandatory"] != "alpn,port" || back["key65001"] != "x" {
+		t.Fatalf("SVCBParamsToMap = %v", back)
+	}
+
+	for name, params := range map[string]map[string]string{
+		"duplicate":       {"port": "1", "key3": "2"},
+		"mandatory self":  {"mandatory": "mandatory"},
+		"mandatory miss":  {"mandatory": "ech"},
+		"no-default-alpn": {"no-default-alpn": ""},
+		"empty alpn id":   {"alpn": "h2,"},
+		"port range":      {"port": "65536"},
+		"ipv6hint family": {"ipv6hint": "192.0.2.1"},
+		"unknown":         {"flux": "1"},
+	} {
+		if _, err := ParseSVCBParams(params); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLOCRoundTrip(t *testing.T) {
+	rec := types.LOCRecord{Latitude: -33.8568, Longitude: 151.2153, Altitude: -12.5, Size: 20, HorizPre: 100, VertPre: 2, TTL: 300}
+	if err := ValidateLOC(rec); err != nil {
+		t.Fatalf("ValidateLOC: %v", err)
+	}
+	rr := LOCToRR("loc.example.test.", rec)
+	parsed, err := dns.NewRR(rr.String())
+	if err != nil {
+		t.Fatalf("LOC %q does not reparse: %v", rr.String(), err)
+	}
+	if !dns.IsDuplicate(rr, parsed) {
+		t.Fatalf("LOC text round trip differs: %s vs %s", rr, parsed)
+	}
+	got := LOCFromRR(rr)
+	if math.Abs(got.Latitude-rec.Latitude) > 1e-6 || math.Abs(got.Longitude-rec.Longitude) > 1e-6 || got.Altitude != rec.Altitude {
+		t.Fatalf("LOCFromRR = %+v, want %+v", got, rec)
+	}
+	if got.Size != 20 || got.HorizPre != 100 || got.VertPre != 2 {
+		t.Fatalf("LOC precision round trip = %+v", got)
+	}
+}
+
+func TestAPLPrefix(t *testing.T) {
+	p, err := APLPrefix(0, "192.0.2.77/24", false)
+	if err != nil || APLFamily(p) != 1 || p.Network.String() != "192.0.2.0/24" || len(p.Network.IP) != 4 {
+		t.Fatalf("APLPrefix v4 = %+v err=%v", p, err)
+	}
+	if _, err := APLPrefix(1, "2001:db8::/32", false); err == nil {
+		t.Fatalf("APLPrefix accepted mismatched family")
+	}
+	if _, err := APLPrefix(0, "nope", false); err == nil {
+		t.Fatalf("APLPrefix accepted invalid prefix")
+	}
+}
@@ -0,0 +1,178 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: rfc3597.go is part of the go53 authoritative DNS server.
+
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+// TypeALIAS is the code go53 files the ALIAS pseudo-type under, taken from the
+// private use range (RFC 6895) and shared with other servers that implement
+// ALIAS. ALIAS records never go on the wire.
+const TypeALIAS uint16 = 65401
+
+// TypeName returns the presentation name of rrtype: its mnemonic when known and
+// the generic TYPEnnn form of RFC 3597 section 5 otherwise. Record types are
+// stored under this name.
+func TypeName(rrtype uint16) string {
+	if name, ok := dns.TypeToString[rrtype]; ok {
+		return name
+	}
+	if rrtype == TypeALIAS {
+		return string(types.TypeALIAS)
+	}
+	return "TYPE" + strconv.Itoa(int(rrtype))
+}
+
+// TypeCode is the inverse of TypeName. It accepts mnemonics and the TYPEnnn
+// form, case-insensitively, and ANAME as another name for ALIAS.
+func TypeCode(name string) (uint16, bool) {
+	name = strings.ToUpper(name)
+	if t, ok := dns.StringToType[name]; ok {
+		return t, true
+	}
+	if name == string(types.TypeALIAS) || name == "ANAME" {
+		return TypeALIAS, true
+	}
+	if !strings.HasPrefix(name, "TYPE") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[len("TYPE"):], 10, 16)
+	if err != nil {
+		return 0, false
+	}
+	return uint16(n), true
+}
+
+// OpaqueType reports whether records of rrtype can be stored as opaque rdata.
+// Type 0, OPT and the meta and query types (128-255: TKEY, TSIG, IXFR, AXFR,
+// ANY, ...) never appear in zone data, and the DNSSEC types are maintained
+// by the signer. ALIAS has a handler of its own.
+func OpaqueType(rrtype uint16) bool {
+	switch rrtype {
+	case 0, dns.TypeOPT, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM, TypeALIAS:
+		return false
+	}
+	return rrtype < 128 || rrtype > 255
+}
+
+// ParseOpaqueRdata reads rdata given in the RFC 3597 generic form
+// ("\# 4 0a000001") or as bare hex, and returns it as lowercase hex.
+func ParseOpaqueRdata(s string) (string, error) {
+	fields := strings.Fields(s)
+	want := -1
+	if len(fields) > 0 && fields[0] == `\#` {
+		if len(fields) < 2 {
+			return "", fmt.Errorf(`generic rdata needs a length after \#`)
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("invalid generic rdata length %q", fields[1])
+		}
+		want = int(n)
+		fields = fields[2:]
+	}
+	data, err := hex.DecodeString(strings.Join(fields, ""))
+	if err != nil {
+		return "", fmt.Errorf("rdata must be hex")
+	}
+	if want >= 0 && len(data) != want {
+		return "", fmt.Errorf("generic rdata length %d does not match %d bytes of data", want, len(data))
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// OpaqueRR builds the RR for opaque rdata. Types miekg/dns knows come back
+// as their typed RR, so DNSSEC canonicalization and name handling apply;
+// anything else stays a dns.RFC3597. The rdata must parse as rrtype.
+func OpaqueRR(name string, rrtype uint16, rdata string, ttl uint32) (dns.RR, error) {
+	raw, err := hex.DecodeString(rdata)
+	if err != nil {
+		return nil, fmt.Errorf("rdata must be hex")
+	}
+	generic := &dns.RFC3597{
+		Hdr:   dns.RR_Header{Name: dns.Fqdn(name), Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl, Rdlength: uint16(len(raw))},
+		Rdata: rdata,
+	}
+	buf := make([]byte, dns.Len(generic)+len(raw))
+	off, err := dns.PackRR(generic, buf, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	rr, _, err := dns.UnpackRR(buf[:off], 0)
+	if err != nil {
+		return nil, fmt.Errorf("rdata is not valid for %s: %w", TypeName(rrtype), err)
+	}
+	return rr, nil
+}
+
+// opaqueBuilder builds the records of a type stored in RFC 3597 form.
+func opaqueBuilder(rrtype uint16) RRBuilder {
+	return func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.UnknownRecord](data) {
+			rr, err := OpaqueRR(name, rrtype, rec.Rdata, rec.TTL)
+			if err != nil {
+				continue
+			}
+			rrs = append(rrs, rr)
+		}
+		return rrs
+	}
+}
+
+// BuilderFor returns the RR builder for the stored type name, falling back to
+// the RFC 3597 builder for types without a dedicated one.
+func BuilderFor(typeName string) (RRBuilder, bool) {
+	if builder, ok := RRBuilders[typeName]; ok {
+		return builder, true
+	}
+	rrtype, ok := TypeCode(typeName)
+	if !ok || !OpaqueType(rrtype) {
+		return nil, false
+	}
+	return opaqueBuilder(rrtype), true
+}
+
+// opaqueZoneData records rr in the Unknown section of zd.
+func opaqueZoneData(zd *types.ZoneData, name string, rr dns.RR) {
+	hdr := rr.Header()
+	if !OpaqueType(hdr.Rrtype) {
+		return
+	}
+	generic := new(dns.RFC3597)
+	if err := generic.ToRFC3597(rr); err != nil {
+		return
+	}
+	typeName := TypeName(hdr.Rrtype)
+	if zd.Unknown == nil {
+		zd.Unknown = map[string]map[string][]types.UnknownRecord{}
+	}
+	if zd.Unknown[typeName] == nil {
+		zd.Unknown[typeName] = map[string][]types.UnknownRecord{}
+	}
+	zd.Unknown[typeName][name] = append(zd.Unknown[typeName][name], types.UnknownRecord{
+		Rdata: strings.ToLower(generic.Rdata),
+		TTL:   hdr.Ttl,
+	})
+}
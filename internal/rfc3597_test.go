@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTypeNameAndCode(t *testing.T) {
+	cases := []struct {
+		code uint16
+		name string
+	}{
+		{dns.TypeA, "A"},
+		{dns.TypeHINFO, "HINFO"},
+		{65534, "TYPE65534"},
+	}
+	for _, c := range cases {
+		if got := TypeName(c.code); got != c.name {
+			t.Fatalf("TypeName(%d) = %q, want %q", c.code, got, c.name)
+		}
+		if got, ok := TypeCode(c.name); !ok || got != c.code {
+			t.Fatalf("TypeCode(%q) = %d, %v", c.name, got, ok)
+		}
+	}
+	if got, ok := TypeCode("type1"); !ok || got != dns.TypeA {
+		t.Fatalf("TypeCode(type1) = %d, %v", got, ok)
+	}
+	for _, bad := range []string{"TYPE", "TYPE65536", "TYPEx", "BOGUS"} {
+		if _, ok := TypeCode(bad); ok {
+			t.Fatalf("TypeCode(%q) accepted", bad)
+		}
+	}
+	if _, err := RRTypeStringToUint16("TYPE0"); err == nil {
+		t.Fatalf("RRTypeStringToUint16(TYPE0) accepted")
+	}
+}
+
+func TestOpaqueType(t *testing.T) {
+	for _, rrtype := range []uint16{dns.TypeHINFO, dns.TypeA, 65534, 65280} {
+		if !OpaqueType(rrtype) {
+			t.Fatalf("OpaqueType(%d) = false", rrtype)
+		}
+	}
+	for _, rrtype := range []uint16{0, dns.TypeOPT, dns.TypeRRSIG, dns.TypeNSEC3, dns.TypeTSIG, dns.TypeAXFR, dns.TypeANY, 128} {
+		if OpaqueType(rrtype) {
+			t.Fatalf("OpaqueType(%d) = true", rrtype)
+		}
+	}
+}
+
+func TestParseOpaqueRdata(t *testing.T) {
+	good := map[string]string{
+		`\# 4 0A000001`:  "0a000001",
+		`\# 4 0a00 0001`: "0a000001",
+		"0a000001":       "0a000001",
+		`\# 0`:           "",
+		"DE AD be ef":    "deadbeef",
+	}
+	for in, want := range good {
+		got, err := ParseOpaqueRdata(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseOpaqueRdata(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{`\# 3 0a000001`, `\#`, `\# x 00`, "0g", "abc"} {
+		if _, err := ParseOpaqueRdata(in); err == nil {
+			t.Fatalf("ParseOpaqueRdata(%q) accepted", in)
+		}
+	}
+}
+
+func TestOpaqueRR(t *testing.T) {
+	rr, err := OpaqueRR("x.example.test.", 65534, "0a000001", 300)
+	if err != nil {
+		t.Fatalf("OpaqueRR: %v", err)
+	}
+	if got := rr.String(); got != "x.example.test.\t300\tCLASS1\tTYPE65534\t\\# 4 0a000001" {
+		t.Fatalf("OpaqueRR string = %q", got)
+	}
+
+	rr, err = OpaqueRR("x.example.test.", dns.TypeA, "c0000201", 300)
+	if err != nil {
+		t.Fatalf("OpaqueRR A: %v", err)
+	}
+	if a, ok := rr.(*dns.A); !ok || a.A.String() != "192.0.2.1" {
+		t.Fatalf("OpaqueRR A = %v", rr)
+	}
+	if _, err := OpaqueRR("x.example.test.", dns.TypeA, "c00002", 300); err == nil {
+		t.Fatalf("OpaqueRR accepted a truncated A record")
+	}
+}
+
+func TestRRToZoneDataKeepsUnknownTypes(t *testing.T) {
+	texts := []string{
+		`opaque.example.test. 300 IN TYPE65534 \# 4 0a000001`,
+		`host.example.test. 300 IN HINFO "AMD" "Free"`,
+	}
+	for _, text := range texts {
+		want, err := dns.NewRR(text)
+		if err != nil {
+			t.Fatalf("NewRR(%q): %v", text, err)
+		}
+		typeName := TypeName(want.Header().Rrtype)
+		zd := RRToZoneDataForZone("example.test.", []dns.RR{want})
+		owner := dns.SplitDomainName(want.Header().Name)[0]
+		records, ok := zd.Unknown[typeName][owner]
+		if !ok {
+			t.Fatalf("%s: RRToZoneDataForZone did not populate Unknown[%s][%s]: %+v", typeName, typeName, owner, zd.Unknown)
+		}
+
+		raw, err := json.Marshal(records)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", typeName, err)
+		}
+		var reloaded []interface{}
+		if err := json.Unmarshal(raw, &reloaded); err != nil {
+			t.Fatalf("%s: unmarshal: %v", typeName, err)
+		}
+
+		builder, ok := BuilderFor(typeName)
+		if !ok {
+			t.Fatalf("BuilderFor(%s) missing", typeName)
+		}
+		for _, data := range []any{records, reloaded} {
+			got := builder(want.Header().Name, data)
+			if len(got) != 1 || !dns.IsDuplicate(got[0], want) || got[0].Header().Ttl != 300 {
+				t.Fatalf("%s: built %v from %T, want %s", typeName, got, data, want)
+			}
+		}
+	}
+
+	nsec, _ := dns.NewRR("a.example.test. 300 IN NSEC b.example.test. A RRSIG NSEC")
+	if zd := RRToZoneDataForZone("example.test.", []dns.RR{nsec}); len(zd.Unknown) != 0 {
+		t.Fatalf("NSEC ended up in Unknown: %+v", zd.Unknown)
+	}
+}
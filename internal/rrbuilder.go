@@ -974,10 +974,197 @@ var RRBuilders = map[string]RRBuilder{
 
 		return rrs
 	},
+	"NAPTR": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.NAPTRRecord](data) {
+			rrs = append(rrs, &dns.NAPTR{
+				Hdr:         dns.RR_Header{Name: name, Rrtype: dns.TypeNAPTR, Class: dns.ClassINET, Ttl: rec.TTL},
+				Order:       rec.Order,
+				Preference:  rec.Preference,
+				Flags:       rec.Flags,
+				Service:     rec.Service,
+				Regexp:      rec.Regexp,
+				Replacement: dns.Fqdn(rec.Replacement),
+			})
+		}
+		return rrs
+	},
+	"SVCB": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.SVCBRecord](data) {
+			svcb, err := svcbRR(name, dns.TypeSVCB, rec.Priority, rec.Target, rec.Params, rec.TTL)
+			if err != nil {
+				slog.Warn("[rrbuilder.go:RRBuilder] skipping SVCB at %s: %v", name, err)
+				continue
+			}
+			rrs = append(rrs, &svcb)
+		}
+		return rrs
+	},
+	"HTTPS": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.HTTPSRecord](data) {
+			svcb, err := svcbRR(name, dns.TypeHTTPS, rec.Priority, rec.Target, rec.Params, rec.TTL)
+			if err != nil {
+				slog.Warn("[rrbuilder.go:RRBuilder] skipping HTTPS at %s: %v", name, err)
+				continue
+			}
+			rrs = append(rrs, &dns.HTTPS{SVCB: svcb})
+		}
+		return rrs
+	},
+	"LOC": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.LOCRecord](data) {
+			rrs = append(rrs, LOCToRR(name, rec))
+		}
+		return rrs
+	},
+	"CERT": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.CERTRecord](data) {
+			rrs = append(rrs, &dns.CERT{
+				Hdr:         dns.RR_Header{Name: name, Rrtype: dns.TypeCERT, Class: dns.ClassINET, Ttl: rec.TTL},
+				Type:        rec.Type,
+				KeyTag:      rec.KeyTag,
+				Algorithm:   rec.Algorithm,
+				Certificate: rec.Cert,
+			})
+		}
+		return rrs
+	},
+	"SSHFP": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.SSHFPRecord](data) {
+			rrs = append(rrs, &dns.SSHFP{
+				Hdr:         dns.RR_Header{Name: name, Rrtype: dns.TypeSSHFP, Class: dns.ClassINET, Ttl: rec.TTL},
+				Algorithm:   rec.Algorithm,
+				Type:        rec.FingerprintType,
+				FingerPrint: rec.Fingerprint,
+			})
+		}
+		return rrs
+	},
+	"URI": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.URIRecord](data) {
+			rrs = append(rrs, &dns.URI{
+				Hdr:      dns.RR_Header{Name: name, Rrtype: dns.TypeURI, Class: dns.ClassINET, Ttl: rec.TTL},
+				Priority: rec.Priority,
+				Weight:   rec.Weight,
+				Target:   rec.Target,
+			})
+		}
+		return rrs
+	},
+	// APL items are stored one per record but always served as a single RR,
+	// since RFC 3123 puts every prefix of an owner into one rdata.
+	"APL": func(name string, data any) []dns.RR {
+		recs := DecodeRecords[types.APLRecord](data)
+		if len(recs) == 0 {
+			return nil
+		}
+		apl := &dns.APL{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAPL, Class: dns.ClassINET, Ttl: recs[0].TTL}}
+		for _, rec := range recs {
+			p, err := APLPrefix(rec.AddressFamily, rec.Prefix, rec.Negation)
+			if err != nil {
+				slog.Warn("[rrbuilder.go:RRBuilder] skipping APL item at %s: %v", name, err)
+				continue
+			}
+			apl.Prefixes = append(apl.Prefixes, p)
+		}
+		if len(apl.Prefixes) == 0 {
+			return nil
+		}
+		return []dns.RR{apl}
+	},
+	"TLSA": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.TLSARecord](data) {
+			rrs = append(rrs, &dns.TLSA{
+				Hdr:          dns.RR_Header{Name: name, Rrtype: dns.TypeTLSA, Class: dns.ClassINET, Ttl: rec.TTL},
+				Usage:        rec.Usage,
+				Selector:     rec.Selector,
+				MatchingType: rec.MatchingType,
+				Certificate:  rec.Certificate,
+			})
+		}
+		return rrs
+	},
+	"SMIMEA": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.SMIMEARecord](data) {
+			rrs = append(rrs, &dns.SMIMEA{
+				Hdr:          dns.RR_Header{Name: name, Rrtype: dns.TypeSMIMEA, Class: dns.ClassINET, Ttl: rec.TTL},
+				Usage:        rec.Usage,
+				Selector:     rec.Selector,
+				MatchingType: rec.MatchingType,
+				Certificate:  rec.Certificate,
+			})
+		}
+		return rrs
+	},
+	"OPENPGPKEY": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.OPENPGPKEYRecord](data) {
+			rrs = append(rrs, &dns.OPENPGPKEY{
+				Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeOPENPGPKEY, Class: dns.ClassINET, Ttl: rec.TTL},
+				PublicKey: rec.PublicKey,
+			})
+		}
+		return rrs
+	},
+	"ZONEMD": func(name string, data any) []dns.RR {
+		var rrs []dns.RR
+		for _, rec := range DecodeRecords[types.ZONEMDRecord](data) {
+			rrs = append(rrs, &dns.ZONEMD{
+				Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeZONEMD, Class: dns.ClassINET, Ttl: rec.TTL},
+				Serial: rec.Serial,
+				Scheme: rec.Scheme,
+				Hash:   rec.Hash,
+				Digest: rec.Digest,
+			})
+		}
+		return rrs
+	},
+}
+
+// svcbRR builds the SVCB rdata shared by SVCB and HTTPS records.
+func svcbRR(name string, rrtype uint16, priority uint16, target string, params map[string]string, ttl uint32) (dns.SVCB, error) {
+	kvs, err := ParseSVCBParams(params)
+	if err != nil {
+		return dns.SVCB{}, err
+	}
+	return dns.SVCB{
+		Hdr:      dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl},
+		Priority: priority,
+		Target:   dns.Fqdn(target),
+		Value:    kvs,
+	}, nil
 }
 
+// RRToZoneData converts a transferred or imported RR list into ZoneData. The
+// zone apex is taken from the owner of the first record, which for AXFR and
+// zone-file input is always the SOA.
 func RRToZoneData(rrs []dns.RR) types.ZoneData {
+	var apex string
+	if len(rrs) > 0 {
+		apex = rrs[0].Header().Name
+	}
+	zd := RRToZoneDataForZone(apex, rrs)
+	if zd.SOA == nil {
+		zd.SOA = &types.SOARecord{}
+	}
+	return zd
+}
+
+// RRToZoneDataForZone converts rrs into ZoneData with owner names made relative
+// to zoneName ("@" for the apex). Unlike RRToZoneData it leaves SOA nil unless
+// rrs carries one, so callers holding a partial RR list (dynamic UPDATE, single
+// RRset replacement) do not write a blank SOA.
+func RRToZoneDataForZone(zoneName string, rrs []dns.RR) types.ZoneData {
 	var zd types.ZoneData
+	zone := strings.ToLower(strings.TrimSuffix(zoneName, "."))
 
 	zd.A = map[string][]types.ARecord{}
 	zd.AAAA = map[string][]types.AAAARecord{}
@@ -1005,11 +1192,13 @@ func RRToZoneData(rrs []dns.RR) types.ZoneData {
 	zd.SSHFP = map[string][]types.SSHFPRecord{}
 	zd.URI = map[string][]types.URIRecord{}
 	zd.APL = map[string][]types.APLRecord{}
-	zd.SOA = &types.SOARecord{}
+	zd.TLSA = map[string][]types.TLSARecord{}
+	zd.SMIMEA = map[string][]types.SMIMEARecord{}
+	zd.OPENPGPKEY = map[string][]types.OPENPGPKEYRecord{}
+	zd.ZONEMD = map[string][]types.ZONEMDRecord{}
 
 	for _, rr := range rrs {
-		name := strings.ToLower(strings.TrimSuffix(rr.Header().Name, "."))     // Normalize
-		zone := strings.ToLower(strings.TrimSuffix(rrs[0].Header().Name, ".")) // Or use sanitizedZoneName passed in as arg!
+		name := strings.ToLower(strings.TrimSuffix(rr.Header().Name, ".")) // Normalize
 
 		// Remove the zone suffix from the name
 		if strings.HasSuffix(name, "."+zone) {
@@ -1077,10 +1266,7 @@ func RRToZoneData(rrs []dns.RR) types.ZoneData {
 			})
 		case *dns.RRSIG:
 			// Use .TypeCovered to group RRSIGs for different RRsets
-			covered := dns.TypeToString[v.TypeCovered]
-			if covered == "" {
-				covered = "UNKNOWN"
-			}
+			covered := TypeName(v.TypeCovered)
 			rec := &types.RRSIGRecord{
 				Name:        name,
 				TypeCovered: covered,
@@ -1108,7 +1294,91 @@ func RRToZoneData(rrs []dns.RR) types.ZoneData {
 				TTL:     v.Hdr.Ttl,
 			}
 			slog.Crazy("[rrbuilder.go:RRToZoneData] zd.SOA is: %v", zd.SOA)
-			// TODO: Add remaining record types if needed
+		case *dns.NAPTR:
+			zd.NAPTR[name] = append(zd.NAPTR[name], types.NAPTRRecord{
+				Order:       v.Order,
+				Preference:  v.Preference,
+				Flags:       v.Flags,
+				Service:     v.Service,
+				Regexp:      v.Regexp,
+				Replacement: v.Replacement,
+				TTL:         v.Hdr.Ttl,
+			})
+		case *dns.SVCB:
+			zd.SVCB[name] = append(zd.SVCB[name], types.SVCBRecord{
+				Priority: v.Priority,
+				Target:   v.Target,
+				Params:   SVCBParamsToMap(v.Value),
+				TTL:      v.Hdr.Ttl,
+			})
+		case *dns.HTTPS:
+			zd.HTTPS[name] = append(zd.HTTPS[name], types.HTTPSRecord{
+				Priority: v.Priority,
+				Target:   v.Target,
+				Params:   SVCBParamsToMap(v.Value),
+				TTL:      v.Hdr.Ttl,
+			})
+		case *dns.LOC:
+			zd.LOC[name] = append(zd.LOC[name], LOCFromRR(v))
+		case *dns.CERT:
+			zd.CERT[name] = append(zd.CERT[name], types.CERTRecord{
+				Type:      v.Type,
+				KeyTag:    v.KeyTag,
+				Algorithm: v.Algorithm,
+				Cert:      v.Certificate,
+				TTL:       v.Hdr.Ttl,
+			})
+		case *dns.SSHFP:
+			zd.SSHFP[name] = append(zd.SSHFP[name], types.SSHFPRecord{
+				Algorithm:       v.Algorithm,
+				FingerprintType: v.Type,
+				Fingerprint:     strings.ToUpper(v.FingerPrint),
+				TTL:             v.Hdr.Ttl,
+			})
+		case *dns.URI:
+			zd.URI[name] = append(zd.URI[name], types.URIRecord{
+				Priority: v.Priority,
+				Weight:   v.Weight,
+				Target:   v.Target,
+				TTL:      v.Hdr.Ttl,
+			})
+		case *dns.APL:
+			for _, p := range v.Prefixes {
+				zd.APL[name] = append(zd.APL[name], types.APLRecord{
+					AddressFamily: APLFamily(p),
+					Prefix:        p.Network.String(),
+					Negation:      p.Negation,
+					TTL:           v.Hdr.Ttl,
+				})
+			}
+		case *dns.TLSA:
+			zd.TLSA[name] = append(zd.TLSA[name], types.TLSARecord{
+				Usage:        v.Usage,
+				Selector:     v.Selector,
+				MatchingType: v.MatchingType,
+				Certificate:  strings.ToUpper(v.Certificate),
+				TTL:          v.Hdr.Ttl,
+			})
+		case *dns.SMIMEA:
+			zd.SMIMEA[name] = append(zd.SMIMEA[name], types.SMIMEARecord{
+				Usage:        v.Usage,
+				Selector:     v.Selector,
+				MatchingType: v.MatchingType,
+				Certificate:  strings.ToUpper(v.Certificate),
+				TTL:          v.Hdr.Ttl,
+			})
+		case *dns.OPENPGPKEY:
+			zd.OPENPGPKEY[name] = append(zd.OPENPGPKEY[name], types.OPENPGPKEYRecord{PublicKey: v.PublicKey, TTL: v.Hdr.Ttl})
+		case *dns.ZONEMD:
+			zd.ZONEMD[name] = append(zd.ZONEMD[name], types.ZONEMDRecord{
+				Serial: v.Serial,
+				Scheme: v.Scheme,
+				Hash:   v.Hash,
+				Digest: strings.ToUpper(v.Digest),
+				TTL:    v.Hdr.Ttl,
+			})
+		default:
+			opaqueZoneData(&zd, name, rr)
 		}
 	}
 	slog.Crazy("[rrbuilder.go:RRToZoneData] zoneData: %v", zd)
@@ -1140,7 +1410,7 @@ func decodeRecord(raw map[string]interface{}, out interface{}) error {
 func typeBitmap(types []string) []uint16 {
 	var bitmap []uint16
 	for _, t := range types {
-		if code, ok := dns.StringToType[strings.ToUpper(t)]; ok {
+		if code, ok := TypeCode(t); ok {
 			bitmap = append(bitmap, code)
 		}
 	}
@@ -1179,7 +1449,7 @@ func nsec3SaltLength(value string) int {
 }
 
 func toDNSRRSIG(name string, r *types.RRSIGRecord) (*dns.RRSIG, error) {
-	rrtype, ok := dns.StringToType[r.TypeCovered]
+	rrtype, ok := TypeCode(r.TypeCovered)
 	if !ok {
 		return nil, fmt.Errorf("invalid type_covered: %s", r.TypeCovered)
 	}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"net"
 	"reflect"
 	"strings"
@@ -182,3 +183,51 @@ func TestRRToZoneData(t *testing.T) {
 		t.Fatalf("CNAME not populated: %#v", zd.CNAME)
 	}
 }
+
+func TestRRBuildersRoundTripAdditionalRecordTypes(t *testing.T) {
+	texts := []string{
+		`naptr.example.test. 300 IN NAPTR 100 10 "U" "E2U+sip" "!^.*$!sip:info@example.test!" .`,
+		`_dns.example.test. 300 IN SVCB 1 dns.example.test. alpn="dot,doq" port=853 ipv4hint=192.0.2.53`,
+		`www.example.test. 300 IN HTTPS 1 . alpn="h3,h2" ipv6hint=2001:db8::443 mandatory=alpn`,
+		`alias.example.test. 300 IN HTTPS 0 www.example.test.`,
+		`loc.example.test. 300 IN LOC 59 19 45.480 N 18 4 6.960 E 28.00m 1m 10000m 10m`,
+		`cert.example.test. 300 IN CERT PKIX 0 RSASHA256 MIIBCgKCAQEA`,
+		`host.example.test. 300 IN SSHFP 4 2 A87F1B687AC0E57D2A081A2F282672334D90ED316D2B818CA9580EA384D92401`,
+		`_http._tcp.example.test. 300 IN URI 10 1 "https://www.example.test/"`,
+		`apl.example.test. 300 IN APL 1:192.0.2.0/24 !2:2001:db8::/32`,
+		`_25._tcp.mail.example.test. 300 IN TLSA 3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6`,
+		`hash._smimecert.example.test. 300 IN SMIMEA 3 0 0 3082010A02820101`,
+		`hash._openpgpkey.example.test. 300 IN OPENPGPKEY mQENBFVHm5sBCADR`,
+	}
+	for _, text := range texts {
+		want, err := dns.NewRR(text)
+		if err != nil {
+			t.Fatalf("NewRR(%q): %v", text, err)
+		}
+		rrtype := dns.TypeToString[want.Header().Rrtype]
+		zd := RRToZoneDataForZone("example.test.", []dns.RR{want})
+
+		// Store the records the way a reload from disk hands them back.
+		field := reflect.ValueOf(zd).FieldByName(rrtype)
+		owner := strings.TrimSuffix(want.Header().Name, ".example.test.")
+		records := field.MapIndex(reflect.ValueOf(owner))
+		if !records.IsValid() {
+			t.Fatalf("%s: RRToZoneDataForZone did not populate %q", rrtype, owner)
+		}
+		raw, err := json.Marshal(records.Interface())
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", rrtype, err)
+		}
+		var reloaded []interface{}
+		if err := json.Unmarshal(raw, &reloaded); err != nil {
+			t.Fatalf("%s: unmarshal: %v", rrtype, err)
+		}
+
+		for _, data := range []any{records.Interface(), reloaded} {
+			got := RRBuilders[rrtype](want.Header().Name, data)
+			if len(got) != 1 || !dns.IsDuplicate(got[0], want) || got[0].Header().Ttl != 300 {
+				t.Fatalf("%s: built %v from %T, want %s", rrtype, got, data, want)
+			}
+		}
+	}
+}
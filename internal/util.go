@@ -48,7 +48,7 @@ func SplitName(name string) (zone, host string, ok bool) {
 }
 
 func RRTypeStringToUint16(s string) (uint16, error) {
-	t, ok := dns.StringToType[strings.ToUpper(s)]
+	t, ok := TypeCode(s)
 	if !ok || t == 0 {
 		return 0, fmt.Errorf("unknown RR type: %s", s)
 	}
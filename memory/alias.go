@@ -0,0 +1,196 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: alias.go is part of the go53 authoritative DNS server.
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/security"
+	"go53/types"
+)
+
+// maxSynthesizedSigs bounds the signatures kept for ALIAS answers. The cache
+// is dropped as a whole when full; entries are cheap to recreate.
+const maxSynthesizedSigs = 4096
+
+// validateALIASCoexistence keeps ALIAS and the address RRsets it stands for
+// apart: an owner has either an ALIAS or its own A/AAAA records.
+func validateALIASCoexistence(zoneMap map[string]map[string]any, rtype, name string) error {
+	var others []string
+	switch rtype {
+	case string(types.TypeALIAS):
+		others = []string{string(types.TypeA), string(types.TypeAAAA)}
+	case string(types.TypeA), string(types.TypeAAAA):
+		others = []string{string(types.TypeALIAS)}
+	default:
+		return nil
+	}
+	for _, other := range others {
+		if _, ok := zoneMap[other][name]; ok {
+			return fmt.Errorf("%s cannot coexist with %s at %s", rtype, other, name)
+		}
+	}
+	return nil
+}
+
+// aliasFamilies tells which address RRsets an ALIAS target had when it was
+// last resolved. The zero value means nothing is known yet.
+type aliasFamilies struct {
+	noA, noAAAA bool
+}
+
+// addALIASAddressTypes replaces ALIAS in the NSEC type lists with the A and
+// AAAA RRsets queries at those owners are answered with. A family the target
+// was found not to have is left out, so NODATA for it validates; until the
+// target is resolved both are listed.
+func addALIASAddressTypes(owners map[string]map[string]bool, families map[string]aliasFamilies) {
+	for name, typesForOwner := range owners {
+		if !typesForOwner[string(types.TypeALIAS)] {
+			continue
+		}
+		delete(typesForOwner, string(types.TypeALIAS))
+		f := families[name]
+		if !f.noA {
+			typesForOwner[string(types.TypeA)] = true
+		}
+		if !f.noAAAA {
+			typesForOwner[string(types.TypeAAAA)] = true
+		}
+	}
+}
+
+// SetALIASFamily records whether the ALIAS at name in zone resolved to any
+// records of qtype, A or AAAA. When that changes the NSEC and NSEC3 type
+// bitmaps of a signed zone are rebuilt and signed again.
+func (z *InMemoryZoneStore) SetALIASFamily(zone, name string, qtype uint16, present bool) {
+	if qtype != dns.TypeA && qtype != dns.TypeAAAA {
+		return
+	}
+	dnssecPrimary := signsZone(zone)
+
+	z.mu.Lock()
+	f := z.aliasFamilies[zone][name]
+	prev := f
+	if qtype == dns.TypeA {
+		f.noA = !present
+	} else {
+		f.noAAAA = !present
+	}
+	if f == prev {
+		z.mu.Unlock()
+		return
+	}
+	if z.aliasFamilies == nil {
+		z.aliasFamilies = map[string]map[string]aliasFamilies{}
+	}
+	if z.aliasFamilies[zone] == nil {
+		z.aliasFamilies[zone] = map[string]aliasFamilies{}
+	}
+	z.aliasFamilies[zone][name] = f
+	if !dnssecPrimary {
+		z.mu.Unlock()
+		return
+	}
+	z.gen[zone]++
+	z.rebuildNSECChainLocked(zone)
+	z.rebuildNSEC3ChainLocked(zone)
+	z.mu.Unlock()
+
+	z.spawnSign(func() { z.signNSECChain(zone) })
+	z.spawnSign(func() { z.signNSEC3Chain(zone) })
+}
+
+// synthesizedRRSet reports whether an A or AAAA RRset at owner comes from an
+// ALIAS record rather than from the store.
+func (z *InMemoryZoneStore) synthesizedRRSet(zone string, hdr *dns.RR_Header) bool {
+	if hdr.Rrtype != dns.TypeA && hdr.Rrtype != dns.TypeAAAA {
+		return false
+	}
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.ownerHasTypeLocked(zone, hdr.Name, string(types.TypeALIAS))
+}
+
+// signSynthesizedRRSet signs an RRset resolved from an ALIAS record. The
+// addresses change with the target, so signatures are cached by RRset content
+// and kept out of the zone: they are neither persisted nor part of GetZone.
+func (z *InMemoryZoneStore) signSynthesizedRRSet(zone string, rrs []dns.RR) ([]dns.RR, error) {
+	hdr := rrs[0].Header()
+	key := synthesizedRRSetKey(rrs)
+	now := time.Now()
+
+	z.synthMu.Lock()
+	cached := z.synthSigs[key]
+	z.synthMu.Unlock()
+	if len(cached) > 0 {
+		fresh := true
+		for _, sig := range cached {
+			if !security.RRSIGFresh(hdr.Name, security.RRSIGFromDNS(sig), hdr.Rrtype, now) {
+				fresh = false
+				break
+			}
+		}
+		if fresh {
+			out := make([]dns.RR, 0, len(cached))
+			for _, sig := range cached {
+				out = append(out, sig)
+			}
+			return out, nil
+		}
+	}
+
+	sigs, err := z.signRRSetWithZoneKeys(zone, rrs, false)
+	if err != nil {
+		return nil, err
+	}
+	z.synthMu.Lock()
+	if z.synthSigs == nil || len(z.synthSigs) >= maxSynthesizedSigs {
+		z.synthSigs = make(map[string][]*dns.RRSIG)
+	}
+	z.synthSigs[key] = sigs
+	z.synthMu.Unlock()
+
+	out := make([]dns.RR, 0, len(sigs))
+	for _, sig := range sigs {
+		out = append(out, sig)
+	}
+	return out, nil
+}
+
+// dropSynthesizedSigs forgets the cached ALIAS signatures, e.g. after the
+// zone keys changed.
+func (z *InMemoryZoneStore) dropSynthesizedSigs() {
+	z.synthMu.Lock()
+	z.synthSigs = nil
+	z.synthMu.Unlock()
+}
+
+// synthesizedRRSetKey identifies an RRset by owner, type, TTL and rdata, which
+// is everything its signature covers.
+func synthesizedRRSetKey(rrs []dns.RR) string {
+	lines := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		cp := dns.Copy(rr)
+		cp.Header().Name = strings.ToLower(cp.Header().Name)
+		lines = append(lines, cp.String())
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
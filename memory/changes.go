@@ -0,0 +1,116 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: changes.go is part of the go53 authoritative DNS server.
+package memory
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+// zoneChanges collects the RRsets of one zone written since the last
+// TakeChangedRRsets. A key with an empty Name stands for every owner of Type;
+// all is set by writes that touched the whole zone.
+type zoneChanges struct {
+	keys map[RRsetKey]bool
+	all  bool
+}
+
+// markChangedLocked records that the RRset of rtype at name changed, together
+// with its RRSIGs. RRSIG writes are recorded under the type they cover. An
+// empty name marks every owner of rtype, an empty rtype the whole zone.
+// Staging zones are not tracked; CommitStaging marks what it moves.
+func (z *InMemoryZoneStore) markChangedLocked(zone, rtype, name string) {
+	if z.staging[zone] {
+		return
+	}
+	if z.changed == nil {
+		z.changed = map[string]*zoneChanges{}
+	}
+	c, ok := z.changed[zone]
+	if !ok {
+		c = &zoneChanges{keys: map[RRsetKey]bool{}}
+		z.changed[zone] = c
+	}
+	if rtype == "" {
+		c.all = true
+		c.keys = map[RRsetKey]bool{}
+		return
+	}
+	if !c.all {
+		c.keys[RRsetKey{Type: rtype, Name: name}] = true
+	}
+}
+
+// TakeChangedRRsets returns the RRsets of zone written since the previous call
+// and forgets them. all reports a write that touched the whole zone, such as a
+// key change that dropped every signature; keys is then empty. A key with an
+// empty Name covers every owner of its Type.
+func (z *InMemoryZoneStore) TakeChangedRRsets(zone string) (keys []RRsetKey, all bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	c, ok := z.changed[zone]
+	if !ok {
+		return nil, false
+	}
+	delete(z.changed, zone)
+	for key := range c.keys {
+		keys = append(keys, key)
+	}
+	return keys, c.all
+}
+
+// GetRRsets returns the records of zone named by keys together with the
+// RRSIGs stored for them. A key with an empty Name returns every owner of its
+// Type. ALIAS records are left out, as in GetZone.
+func (z *InMemoryZoneStore) GetRRsets(zone string, keys []RRsetKey) []dns.RR {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	zoneMap, ok := z.cache["zones"][zone]
+	if !ok {
+		return nil
+	}
+
+	var out []dns.RR
+	add := func(rtype string, names map[string]any, name string) {
+		builder, ok := internal.BuilderFor(rtype)
+		if !ok {
+			return
+		}
+		if name == "" {
+			for n, raw := range names {
+				out = append(out, builder(zoneOwnerFQDN(zone, n), raw)...)
+			}
+			return
+		}
+		for n, raw := range names {
+			if strings.EqualFold(n, name) {
+				out = append(out, builder(zoneOwnerFQDN(zone, n), raw)...)
+			}
+		}
+	}
+	for _, key := range keys {
+		if key.Type != string(types.TypeALIAS) {
+			add(key.Type, zoneMap[key.Type], key.Name)
+		}
+		if sigs, ok := zoneMap[string(types.TypeRRSIG)][key.Type]; ok {
+			add(string(types.TypeRRSIG), rrsigNameMap(sigs), key.Name)
+		}
+	}
+	return out
+}
@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -99,6 +101,28 @@ func TestRRSIGCacheHelpers(t *testing.T) {
 	}
 }
 
+func TestEnsureSignedRRSetReportsExpiredSignature(t *testing.T) {
+	store := setupProofZoneStore(t)
+	zone := "sig.test."
+	if err := store.PutRecordRaw(zone, "A", "www", []any{map[string]any{"ip": "192.0.2.1", "ttl": float64(proofTTL)}}); err != nil {
+		t.Fatalf("PutRecordRaw: %v", err)
+	}
+	config.AppConfig.LiveForTest().DNSSECEnabled = true
+	rrset := []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: owner(zone, "www"), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: proofTTL}, A: net.ParseIP("192.0.2.1")}}
+
+	if _, err := store.EnsureSignedRRSet(rrset); err == nil || errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("unsigned RRset without keys: err = %v, want a plain signing error", err)
+	}
+
+	expired := testRRSIG(zone)
+	expired.Inception = uint32(time.Now().Add(-20 * 24 * time.Hour).Unix())
+	expired.Expiration = uint32(time.Now().Add(-time.Hour).Unix())
+	store.storeRRSIG(zone, "A", "www", expired)
+	if _, err := store.EnsureSignedRRSet(rrset); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expired RRSIG without keys: err = %v, want ErrSignatureExpired", err)
+	}
+}
+
 func setupDNSSECProofStore(t *testing.T) (*InMemoryZoneStore, string) {
 	t.Helper()
 	configureProofDefaults()
@@ -1,7 +1,6 @@
 package memory
 
 import (
-	"crypto"
 	"encoding/base32"
 	"encoding/hex"
 	"errors"
@@ -30,7 +29,28 @@ type InMemoryZoneStore struct {
 	// (notably restore) can wait for them to finish persisting before they
 	// replace zone data underneath them.
 	signWG sync.WaitGroup
-}
+	// staging holds the scratch zones created by BeginStaging. They live in
+	// cache so the rtypes Add paths can build values in them, but they are never
+	// persisted or listed.
+	staging map[string]bool
+	// gen counts data changes per zone so an overlay view can tell when the
+	// zones it is merged from have moved on.
+	gen map[string]uint64
+	// changed collects the RRsets written per zone for the IXFR journal, see
+	// TakeChangedRRsets.
+	changed map[string]*zoneChanges
+	// aliasFamilies records per zone and ALIAS owner the address families its
+	// target was last resolved to, see SetALIASFamily.
+	aliasFamilies map[string]map[string]aliasFamilies
+	// synthSigs caches the signatures of A/AAAA RRsets resolved from ALIAS
+	// records, keyed by RRset content.
+	synthMu   sync.Mutex
+	synthSigs map[string][]*dns.RRSIG
+}
+
+// ErrSignatureExpired is returned by EnsureSignedRRSet when the cached RRSIGs
+// of an RRset are no longer fresh and no new signature could be made.
+var ErrSignatureExpired = errors.New("cached RRSIG expired and could not be refreshed")
 
 // spawnSign runs an async signing task while tracking it in signWG so
 // WaitForSigning can drain outstanding work.
@@ -50,12 +70,7 @@ func (z *InMemoryZoneStore) WaitForSigning() {
 }
 
 func NewZoneStore(s storage.Storage) (*InMemoryZoneStore, error) {
-	zs := &InMemoryZoneStore{
-		cache: map[string]map[string]map[string]map[string]any{
-			"zones": {},
-		},
-		storage: s,
-	}
+	zs := newZoneStore(s)
 	if err := zs.loadFromStorage(); err != nil {
 		return nil, err
 	}
@@ -63,15 +78,28 @@ func NewZoneStore(s storage.Storage) (*InMemoryZoneStore, error) {
 	return zs, nil
 }
 
+func newZoneStore(s storage.Storage) *InMemoryZoneStore {
+	return &InMemoryZoneStore{
+		cache: map[string]map[string]map[string]map[string]any{
+			"zones": {},
+		},
+		storage: s,
+		staging: map[string]bool{},
+		gen:     map[string]uint64{},
+	}
+}
+
 func (z *InMemoryZoneStore) loadFromStorage() error {
 	names, err := z.storage.ListZones()
 	if err != nil {
 		return err
 	}
-	dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
 	z.mu.Lock()
 	defer z.mu.Unlock()
 	for _, zone := range names {
+		if IsViewStorageName(zone) {
+			continue
+		}
 		raw, err := z.storage.LoadZone(zone)
 		if err != nil {
 			log.Printf("failed to load zone %s: %v", zone, err)
@@ -83,7 +111,7 @@ func (z *InMemoryZoneStore) loadFromStorage() error {
 			continue
 		}
 		z.cache["zones"][zone] = decoded
-		if dnssecPrimary {
+		if signsZone(zone) {
 			z.rebuildNSECChainLocked(zone)
 			z.rebuildNSEC3ChainLocked(zone)
 			if err := z.persistLocked(zone); err != nil {
@@ -95,8 +123,19 @@ func (z *InMemoryZoneStore) loadFromStorage() error {
 	return nil
 }
 
+// signsZone reports whether go53 signs zone itself. Secondary zones carry the
+// signatures of their primary, and staging zones are signed once committed.
+func signsZone(zone string) bool {
+	return config.AppConfig.GetLive().DNSSECEnabled && !zonemeta.IsSecondary(zone) &&
+		!strings.HasSuffix(zone, stagingZoneSuffix)
+}
+
 func (z *InMemoryZoneStore) persist(zone string) error {
 	z.mu.RLock()
+	if z.staging[zone] {
+		z.mu.RUnlock()
+		return nil
+	}
 	data, err := z.encodeZoneDataLocked(zone)
 	z.mu.RUnlock()
 	if err != nil {
@@ -106,6 +145,9 @@ func (z *InMemoryZoneStore) persist(zone string) error {
 }
 
 func (z *InMemoryZoneStore) persistLocked(zone string) error {
+	if z.staging[zone] {
+		return nil
+	}
 	data, err := z.encodeZoneDataLocked(zone)
 	if err != nil {
 		return err
@@ -122,7 +164,7 @@ func (z *InMemoryZoneStore) encodeZoneDataLocked(zone string) ([]byte, error) {
 }
 
 func (z *InMemoryZoneStore) AddRecord(zone, rtype, name string, record any) error {
-	dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
+	dnssecPrimary := signsZone(zone)
 
 	z.mu.Lock()
 	if err := z.validateRRSetMutationLocked(zone, rtype, name, record); err != nil {
@@ -137,6 +179,8 @@ func (z *InMemoryZoneStore) AddRecord(zone, rtype, name string, record any) erro
 		zones[zone][rtype] = make(map[string]any)
 	}
 	zones[zone][rtype][name] = record
+	z.gen[zone]++
+	z.markRecordChangedLocked(zone, rtype, name)
 	if dnssecPrimary {
 		z.invalidateRRSIGLocked(zone, rtype, name)
 		if shouldMaintainNSEC(rtype) {
@@ -160,7 +204,7 @@ func (z *InMemoryZoneStore) AddRecord(zone, rtype, name string, record any) erro
 }
 
 func (z *InMemoryZoneStore) PutRecordRaw(zone, rtype, name string, record any) error {
-	dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
+	dnssecPrimary := signsZone(zone)
 
 	z.mu.Lock()
 	zones := z.cache["zones"]
@@ -171,6 +215,8 @@ func (z *InMemoryZoneStore) PutRecordRaw(zone, rtype, name string, record any) e
 		zones[zone][rtype] = make(map[string]any)
 	}
 	zones[zone][rtype][name] = record
+	z.gen[zone]++
+	z.markRecordChangedLocked(zone, rtype, name)
 	if dnssecPrimary {
 		z.invalidateRRSIGLocked(zone, rtype, name)
 		if shouldMaintainNSEC(rtype) {
@@ -193,12 +239,46 @@ func (z *InMemoryZoneStore) PutRecordRaw(zone, rtype, name string, record any) e
 	return nil
 }
 
+// PublishZONEMD replaces the apex ZONEMD RRset of zone and signs it before
+// returning. The digest covers the NSEC/NSEC3 chain and every other RRSIG, so
+// unlike AddRecord it leaves the chain and its signatures untouched; the
+// ZONEMD type must already be present at the apex for the chain to be right.
+func (z *InMemoryZoneStore) PublishZONEMD(zone string, records []types.ZONEMDRecord) error {
+	dnssecPrimary := signsZone(zone)
+	rtype := string(types.TypeZONEMD)
+
+	z.mu.Lock()
+	zones := z.cache["zones"]
+	if _, ok := zones[zone]; !ok {
+		z.mu.Unlock()
+		return errors.New("zone not found")
+	}
+	if _, ok := zones[zone][rtype]; !ok {
+		zones[zone][rtype] = make(map[string]any)
+	}
+	zones[zone][rtype]["@"] = records
+	z.gen[zone]++
+	z.markChangedLocked(zone, rtype, "@")
+	if dnssecPrimary {
+		z.invalidateRRSIGLocked(zone, rtype, "@")
+	}
+	z.mu.Unlock()
+
+	if !dnssecPrimary {
+		return z.persist(zone)
+	}
+	z.maybeSignRRSet(zone, rtype, "@")
+	return nil
+}
+
 func (z *InMemoryZoneStore) DeleteRecordRaw(zone, rtype, name string) error {
 	z.mu.Lock()
 	zones := z.cache["zones"]
 	if recType, ok := zones[zone][rtype]; ok {
 		delete(recType, name)
-		dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
+		z.gen[zone]++
+		z.markRecordChangedLocked(zone, rtype, name)
+		dnssecPrimary := signsZone(zone)
 		if dnssecPrimary {
 			z.invalidateRRSIGLocked(zone, rtype, name)
 			if shouldMaintainNSEC(rtype) {
@@ -271,6 +351,9 @@ func (z *InMemoryZoneStore) validateRRSetMutationLocked(zone, rtype, name string
 	if err := validateCNAMECoexistence(zoneMap, rtype, name); err != nil {
 		return err
 	}
+	if err := validateALIASCoexistence(zoneMap, rtype, name); err != nil {
+		return err
+	}
 	if existing, ok := zoneMap[rtype][name]; ok {
 		if existingTTL, ok := firstRecordTTL(existing); ok {
 			if newTTL, ok := firstRecordTTL(record); ok && newTTL != existingTTL {
@@ -359,6 +442,54 @@ func firstRecordTTL(record any) (uint32, bool) {
 		if len(v) > 0 {
 			return v[0].TTL, true
 		}
+	case []types.NAPTRRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.SVCBRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.HTTPSRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.LOCRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.CERTRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.SSHFPRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.URIRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.APLRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.TLSARecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.SMIMEARecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.OPENPGPKEYRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
+	case []types.UnknownRecord:
+		if len(v) > 0 {
+			return v[0].TTL, true
+		}
 	case types.SOARecord:
 		return v.TTL, true
 	case types.CNAMERecord:
@@ -429,12 +560,24 @@ func (z *InMemoryZoneStore) ZoneNamesSnapshot() []string {
 	zones := z.cache["zones"]
 	out := make([]string, 0, len(zones))
 	for zone := range zones {
+		if z.staging[zone] {
+			continue
+		}
 		out = append(out, zone)
 	}
 	sort.Strings(out)
 	return out
 }
 
+// splitName is internal.SplitName resolved against this store's zones first,
+// so a view store finds zones the default store does not hold.
+func (z *InMemoryZoneStore) splitName(name string) (string, string, bool) {
+	if zone, host, ok := z.AuthoritativeNameParts(name); ok {
+		return strings.TrimSuffix(zone, "."), host, true
+	}
+	return internal.SplitName(name)
+}
+
 func (z *InMemoryZoneStore) AuthoritativeNameParts(name string) (string, string, bool) {
 	z.mu.RLock()
 	defer z.mu.RUnlock()
@@ -498,7 +641,11 @@ func (z *InMemoryZoneStore) GetZone(zone string) ([]dns.RR, error) {
 	var allRRs []dns.RR
 
 	for rtype, namesMap := range zoneMap {
-		builder, ok := internal.RRBuilders[rtype]
+		if rtype == string(types.TypeALIAS) {
+			// Never served itself; transfers carry the resolved addresses.
+			continue
+		}
+		builder, ok := internal.BuilderFor(rtype)
 		if !ok {
 			slog.Warn("[GetZone] no builder for rtype %s", rtype)
 			continue
@@ -555,7 +702,11 @@ func zoneOwnerFQDN(zone, name string) string {
 
 func automaticDNSSECKeyFlowRRs(zone string) []dns.RR {
 	var out []dns.RR
-	if dnskeys, err := security.LoadPublishedKeysForZone(zone, time.Now().Unix()); err == nil {
+	if security.OfflineKSK(zone) {
+		if dnskeys, err := security.SKRRRset(zone, dns.TypeDNSKEY, time.Now().Unix()); err == nil {
+			out = append(out, dnskeys...)
+		}
+	} else if dnskeys, err := security.LoadPublishedKeysForZone(zone, time.Now().Unix()); err == nil {
 		for _, key := range dnskeys {
 			out = append(out, &dns.DNSKEY{
 				Hdr: dns.RR_Header{
@@ -589,7 +740,9 @@ func (z *InMemoryZoneStore) DeleteRecord(zone, rtype, name string) error {
 	zones := z.cache["zones"]
 	if recType, ok := zones[zone][rtype]; ok {
 		delete(recType, name)
-		dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
+		z.gen[zone]++
+		z.markRecordChangedLocked(zone, rtype, name)
+		dnssecPrimary := signsZone(zone)
 		if dnssecPrimary {
 			z.invalidateRRSIGLocked(zone, rtype, name)
 			if shouldMaintainNSEC(rtype) {
@@ -617,6 +770,9 @@ func (z *InMemoryZoneStore) DeleteZone(zone string) error {
 	defer z.mu.Unlock()
 
 	zones := z.cache["zones"]
+	z.gen[zone]++
+	z.markChangedLocked(zone, "", "")
+	delete(z.aliasFamilies, zone)
 	if _, exists := zones[zone]; exists {
 		delete(zones, zone)
 		return z.storage.DeleteZone(zone)
@@ -629,7 +785,7 @@ func (z *InMemoryZoneStore) EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
 	if len(rrs) == 0 {
 		return nil, errors.New("cannot sign empty RRSet")
 	}
-	if !config.AppConfig.GetLive().DNSSECEnabled || config.AppConfig.GetLive().Mode == "secondary" {
+	if !config.AppConfig.GetLive().DNSSECEnabled {
 		return nil, nil
 	}
 
@@ -644,7 +800,7 @@ func (z *InMemoryZoneStore) EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
 		}
 	}
 
-	zoneName, shortName, ok := internal.SplitName(hdr.Name)
+	zoneName, shortName, ok := z.splitName(hdr.Name)
 	if !ok {
 		return nil, fmt.Errorf("cannot derive zone from %q", hdr.Name)
 	}
@@ -652,38 +808,70 @@ func (z *InMemoryZoneStore) EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
 	if err != nil {
 		return nil, err
 	}
-	if meta, readOnly := zonemeta.ReadOnly(zoneName); readOnly && meta.DNSSECMode == "preserve" {
+	meta, readOnly := zonemeta.ReadOnly(zoneName)
+	if readOnly && meta.DNSSECMode == "preserve" {
 		return nil, nil
 	}
-	typeName := dns.TypeToString[hdr.Rrtype]
-	if typeName == "" {
-		return nil, fmt.Errorf("unknown RR type %d", hdr.Rrtype)
+	if meta.EffectiveRole() == zonemeta.RoleSecondary {
+		return nil, nil
 	}
-
-	if cached := z.cachedRRSIGs(zoneName, typeName, shortName, hdr.Rrtype, hdr.Name); len(cached) > 0 {
-		return cached, nil
+	if z.synthesizedRRSet(zoneName, hdr) {
+		return z.signSynthesizedRRSet(zoneName, rrs)
 	}
+	typeName := internal.TypeName(hdr.Rrtype)
 
 	// CDS and CDNSKEY must be signed by the KSK (the key the parent DS
 	// references), like the DNSKEY RRset, per RFC 7344. Everything else is
 	// signed by the ZSK.
 	useKSK := hdr.Rrtype == dns.TypeDNSKEY || hdr.Rrtype == dns.TypeCDS || hdr.Rrtype == dns.TypeCDNSKEY
+	if useKSK && security.OfflineKSK(zoneName) {
+		// The KSK is offline: the RRset carries the signatures of the SKR
+		// bundle it was taken from, never cached ones of an earlier bundle.
+		sigs, err := security.SKRSignatures(zoneName, hdr.Rrtype, time.Now().Unix())
+		if err != nil {
+			return nil, err
+		}
+		out := make([]dns.RR, 0, len(sigs))
+		for _, sig := range sigs {
+			out = append(out, sig)
+		}
+		return out, nil
+	}
+
+	if cached := z.cachedRRSIGs(zoneName, typeName, shortName, hdr.Rrtype, hdr.Name); len(cached) > 0 {
+		return cached, nil
+	}
+	sigs, err := z.signRRSetWithZoneKeys(zoneName, rrs, useKSK)
+	if err != nil {
+		return nil, z.signingFailure(zoneName, typeName, shortName, err)
+	}
+
+	signed := make([]dns.RR, 0, len(sigs))
+	for _, rrsig := range sigs {
+		z.storeRRSIG(zoneName, typeName, shortName, security.RRSIGFromDNS(rrsig))
+		signed = append(signed, rrsig)
+	}
+	if err := z.persist(zoneName); err != nil {
+		slog.Warn("Failed to persist query-time RRSIG cache for zone %q: %v", zoneName, err)
+	}
+	return signed, nil
+}
+
+// signRRSetWithZoneKeys signs rrs with every signing key of zone, the KSKs
+// when useKSK is set and the ZSKs otherwise.
+func (z *InMemoryZoneStore) signRRSetWithZoneKeys(zoneName string, rrs []dns.RR, useKSK bool) ([]*dns.RRSIG, error) {
+	hdr := rrs[0].Header()
+	typeName := internal.TypeName(hdr.Rrtype)
 	keyNames, err := security.GetDNSSECKeyNamesForRRSet(zoneName, useKSK)
 	if err != nil {
 		return nil, err
 	}
 
-	var signed []dns.RR
+	var signed []*dns.RRSIG
 	for _, keyName := range keyNames {
-		privKey, storedKey, err := security.LoadPrivateKeyFromStorage(keyName)
+		signer, storedKey, err := security.LoadSigner(keyName)
 		if err != nil {
-			slog.Alert("Failed to load private key %q: %v", keyName, err)
-			continue
-		}
-
-		signer, ok := privKey.(crypto.Signer)
-		if !ok {
-			slog.Alert("Key %q does not implement crypto.Signer", keyName)
+			slog.Alert("Failed to load signer of key %q: %v", keyName, err)
 			continue
 		}
 
@@ -693,22 +881,20 @@ func (z *InMemoryZoneStore) EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
 			slog.Error("Failed to query-time sign RRSet %q/%s with key %q: %v", hdr.Name, typeName, keyName, err)
 			continue
 		}
-
-		z.storeRRSIG(zoneName, typeName, shortName, security.RRSIGFromDNS(rrsig))
 		signed = append(signed, rrsig)
 	}
 
 	if len(signed) == 0 {
 		return nil, fmt.Errorf("no usable DNSSEC key for %s %s", hdr.Name, typeName)
 	}
-	if err := z.persist(zoneName); err != nil {
-		slog.Warn("Failed to persist query-time RRSIG cache for zone %q: %v", zoneName, err)
-	}
 	return signed, nil
 }
 
 func (z *InMemoryZoneStore) SignZoneTransferRRsets(rrs []dns.RR) []dns.RR {
-	if len(rrs) == 0 || !config.AppConfig.GetLive().DNSSECEnabled || config.AppConfig.GetLive().Mode == "secondary" {
+	if len(rrs) == 0 || !config.AppConfig.GetLive().DNSSECEnabled {
+		return uniqueRRs(rrs)
+	}
+	if zoneName, _, ok := z.splitName(rrs[0].Header().Name); ok && zonemeta.IsSecondary(zoneName) {
 		return uniqueRRs(rrs)
 	}
 
@@ -719,7 +905,7 @@ func (z *InMemoryZoneStore) SignZoneTransferRRsets(rrs []dns.RR) []dns.RR {
 			continue
 		}
 		h := rr.Header()
-		key := strings.ToLower(h.Name) + "|" + dns.TypeToString[h.Rrtype] + "|" + dns.ClassToString[h.Class]
+		key := strings.ToLower(h.Name) + "|" + internal.TypeName(h.Rrtype) + "|" + dns.ClassToString[h.Class]
 		rrsets[key] = append(rrsets[key], rr)
 	}
 
@@ -740,8 +926,9 @@ func (z *InMemoryZoneStore) RefreshDNSSECKeyMaterial(zone string) error {
 	if err != nil {
 		return err
 	}
-	dnssecPrimary := config.AppConfig.GetLive().DNSSECEnabled && config.AppConfig.GetLive().Mode != "secondary"
+	dnssecPrimary := signsZone(zone)
 
+	z.dropSynthesizedSigs()
 	z.mu.Lock()
 	if _, ok := z.cache["zones"][zone]; !ok {
 		z.cache["zones"][zone] = make(map[string]map[string]any)
@@ -771,7 +958,7 @@ func (z *InMemoryZoneStore) RefreshDNSSECKeyMaterial(zone string) error {
 }
 
 func (z *InMemoryZoneStore) FindNSECProof(name string) ([]dns.RR, bool) {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return nil, false
 	}
@@ -805,7 +992,7 @@ func (z *InMemoryZoneStore) FindNSECProof(name string) ([]dns.RR, bool) {
 }
 
 func (z *InMemoryZoneStore) FindNSEC3Proof(name string) ([]dns.RR, bool) {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return nil, false
 	}
@@ -851,7 +1038,7 @@ func (z *InMemoryZoneStore) FindNSEC3Proof(name string) ([]dns.RR, bool) {
 }
 
 func (z *InMemoryZoneStore) DenialProofs(name string, qtype uint16, nxdomain bool) []dns.RR {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return nil
 	}
@@ -870,7 +1057,7 @@ func (z *InMemoryZoneStore) DenialProofs(name string, qtype uint16, nxdomain boo
 }
 
 func (z *InMemoryZoneStore) NameExists(name string) bool {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return false
 	}
@@ -890,7 +1077,7 @@ func (z *InMemoryZoneStore) WildcardExists(name string) bool {
 }
 
 func (z *InMemoryZoneStore) WildcardName(name string) (string, bool) {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return "", false
 	}
@@ -906,7 +1093,7 @@ func (z *InMemoryZoneStore) WildcardName(name string) (string, bool) {
 }
 
 func (z *InMemoryZoneStore) DelegationFor(name string) (string, []dns.RR, bool) {
-	zoneName, _, ok := internal.SplitName(name)
+	zoneName, _, ok := z.splitName(name)
 	if !ok {
 		return "", nil, false
 	}
@@ -928,6 +1115,11 @@ func (z *InMemoryZoneStore) DelegationFor(name string) (string, []dns.RR, bool)
 
 func (z *InMemoryZoneStore) maybeSignRRSet(zone, rtype, name string) {
 	slog.Crazy("[maybeSignRRSet]", zone, rtype, name)
+	if rtype == string(types.TypeALIAS) {
+		// Signed per answer once resolved, see signSynthesizedRRSet.
+		_ = z.persist(zone)
+		return
+	}
 
 	//always persist first to make sure the current data is avablee on disc.
 	_ = z.persist(zone)
@@ -935,7 +1127,7 @@ func (z *InMemoryZoneStore) maybeSignRRSet(zone, rtype, name string) {
 	if !config.AppConfig.GetLive().DNSSECEnabled {
 		slog.Debug("[maybeSignRRSet] DNSSEC disabled, skipping signing")
 		return
-	} else if config.AppConfig.GetLive().Mode == "secondary" {
+	} else if zonemeta.IsSecondary(zone) {
 		slog.Warn("[maybeSignRRSet] Is secondary, skipping signing, done in primary only")
 		return
 	}
@@ -966,6 +1158,10 @@ func (z *InMemoryZoneStore) maybeSignRRSet(zone, rtype, name string) {
 	// references), like the DNSKEY RRset, per RFC 7344. Everything else is
 	// signed by the ZSK.
 	useKSK := rtype == string(types.TypeDNSKEY) || rtype == string(types.TypeCDS) || rtype == string(types.TypeCDNSKEY)
+	if useKSK && security.OfflineKSK(zone) {
+		// Served with the signatures of the SKR; nothing to sign here.
+		return
+	}
 	keyNames, err := security.GetDNSSECKeyNamesForRRSet(zone, useKSK)
 	slog.Crazy("[maybeSignRRSet] keyNames", keyNames)
 	if err != nil {
@@ -973,15 +1169,9 @@ func (z *InMemoryZoneStore) maybeSignRRSet(zone, rtype, name string) {
 	}
 
 	for _, keyName := range keyNames {
-		privKey, storedKey, err := security.LoadPrivateKeyFromStorage(keyName)
+		signer, storedKey, err := security.LoadSigner(keyName)
 		if err != nil {
-			slog.Alert("Failed to load private key %q: %v", keyName, err)
-			continue
-		}
-
-		signer, ok := privKey.(crypto.Signer)
-		if !ok {
-			slog.Alert("Key %q does not implement crypto.Signer", keyName)
+			slog.Alert("Failed to load signer of key %q: %v", keyName, err)
 			continue
 		}
 		fqdn, _ := internal.SanitizeFQDN(storedKey.Zone)
@@ -993,7 +1183,7 @@ func (z *InMemoryZoneStore) maybeSignRRSet(zone, rtype, name string) {
 			continue
 		}
 
-		typeName := dns.TypeToString[rrsig.TypeCovered]
+		typeName := internal.TypeName(rrsig.TypeCovered)
 		z.storeRRSIG(zone, typeName, name, security.RRSIGFromDNS(rrsig))
 
 		slog.Debug("Successfully signed RRSet for %q with key %q (keyTag=%d)", name, keyName, storedKey.KeyTag)
@@ -1024,7 +1214,7 @@ func (z *InMemoryZoneStore) cachedRRSIGs(zone, typeName, name string, covered ui
 	var out []dns.RR
 	now := time.Now()
 	for _, sig := range rrsigRecordsFromRaw(rawList) {
-		if sig.TypeCovered != dns.TypeToString[covered] {
+		if sig.TypeCovered != internal.TypeName(covered) {
 			continue
 		}
 		if !security.RRSIGFresh(owner, sig, covered, now) {
@@ -1042,6 +1232,24 @@ func (z *InMemoryZoneStore) cachedRRSIGs(zone, typeName, name string, covered ui
 	return out
 }
 
+// signingFailure wraps err in ErrSignatureExpired when the RRset already had
+// cached signatures, i.e. a signature lapsed and could not be replaced.
+func (z *InMemoryZoneStore) signingFailure(zone, typeName, name string, err error) error {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	typeMap, ok := z.cache["zones"][zone]["RRSIG"][typeName].(map[string]any)
+	if !ok {
+		return err
+	}
+	for _, sig := range rrsigRecordsFromRaw(typeMap[name]) {
+		if sig.TypeCovered == typeName {
+			return fmt.Errorf("%w: %v", ErrSignatureExpired, err)
+		}
+	}
+	return err
+}
+
 func (z *InMemoryZoneStore) storeRRSIG(zone, typeName, name string, rec *types.RRSIGRecord) {
 	if rec == nil {
 		return
@@ -1073,6 +1281,7 @@ func (z *InMemoryZoneStore) storeRRSIG(zone, typeName, name string, rec *types.R
 	}
 	updated = append(updated, rec)
 	typedMap[name] = updated
+	z.markChangedLocked(zone, typeName, name)
 }
 
 func shouldMaintainNSEC(rtype string) bool {
@@ -1112,7 +1321,7 @@ func addAutomaticDNSSECKeyFlowTypes(zone string, owners map[string]map[string]bo
 		}
 		owners["@"][string(types.TypeDNSKEY)] = true
 	}
-	if _, err := security.ParentDSDNSKEYs(zone, now); err == nil {
+	if cds, err := security.GetCDS(zone); err == nil && len(cds) > 0 {
 		if _, ok := owners["@"]; !ok {
 			owners["@"] = make(map[string]bool)
 		}
@@ -1152,6 +1361,7 @@ func (z *InMemoryZoneStore) rebuildNSECChainLocked(zone string) {
 		}
 	}
 	addAutomaticDNSSECKeyFlowTypes(zone, owners)
+	addALIASAddressTypes(owners, z.aliasFamilies[zone])
 
 	if len(owners) == 0 {
 		delete(zoneMap, string(types.TypeNSEC))
@@ -1184,7 +1394,9 @@ func (z *InMemoryZoneStore) rebuildNSECChainLocked(zone string) {
 			typeList = append(typeList, rtype)
 		}
 		sort.Slice(typeList, func(i, j int) bool {
-			return dns.StringToType[typeList[i]] < dns.StringToType[typeList[j]]
+			a, _ := internal.TypeCode(typeList[i])
+			b, _ := internal.TypeCode(typeList[j])
+			return a < b
 		})
 
 		nsecMap[name] = types.NSECRecord{
@@ -1228,6 +1440,7 @@ func (z *InMemoryZoneStore) rebuildNSEC3ChainLocked(zone string) {
 		}
 	}
 	addAutomaticDNSSECKeyFlowTypes(zone, owners)
+	addALIASAddressTypes(owners, z.aliasFamilies[zone])
 
 	if nsec3OptOutEnabled(params) {
 		for name, typesForOwner := range owners {
@@ -1294,7 +1507,9 @@ func (z *InMemoryZoneStore) rebuildNSEC3ChainLocked(zone string) {
 			typeList = append(typeList, rtype)
 		}
 		sort.Slice(typeList, func(i, j int) bool {
-			return dns.StringToType[typeList[i]] < dns.StringToType[typeList[j]]
+			a, _ := internal.TypeCode(typeList[i])
+			b, _ := internal.TypeCode(typeList[j])
+			return a < b
 		})
 
 		flags := uint8(0)
@@ -1365,7 +1580,7 @@ func (z *InMemoryZoneStore) signAllDataRRSets(zone string) {
 	if zoneMap, ok := z.cache["zones"][zone]; ok {
 		for rtype, namesMap := range zoneMap {
 			switch rtype {
-			case string(types.TypeRRSIG), string(types.TypeNSEC), string(types.TypeNSEC3):
+			case string(types.TypeRRSIG), string(types.TypeNSEC), string(types.TypeNSEC3), string(types.TypeALIAS):
 				continue
 			}
 			for name := range namesMap {
@@ -1398,6 +1613,7 @@ func (z *InMemoryZoneStore) invalidateRRSIGLocked(zone, typeName, name string) {
 		return
 	}
 	delete(typedMap, name)
+	z.markChangedLocked(zone, typeName, name)
 }
 
 func (z *InMemoryZoneStore) invalidateAllRRSIGLocked(zone, typeName string) {
@@ -1408,6 +1624,21 @@ func (z *InMemoryZoneStore) invalidateAllRRSIGLocked(zone, typeName string) {
 	if rrsigMap, ok := zoneMap[string(types.TypeRRSIG)]; ok {
 		delete(rrsigMap, typeName)
 	}
+	z.markChangedLocked(zone, typeName, "")
+}
+
+// markRecordChangedLocked marks a write through the generic record paths.
+// RRSIGs written that way are keyed by covered type inside the record, so the
+// whole zone is marked. Writing an ALIAS forgets what its old target resolved
+// to.
+func (z *InMemoryZoneStore) markRecordChangedLocked(zone, rtype, name string) {
+	switch rtype {
+	case string(types.TypeRRSIG):
+		rtype = ""
+	case string(types.TypeALIAS):
+		delete(z.aliasFamilies[zone], name)
+	}
+	z.markChangedLocked(zone, rtype, name)
 }
 
 func (z *InMemoryZoneStore) invalidateAllRRSIGsLocked(zone string) {
@@ -1416,6 +1647,7 @@ func (z *InMemoryZoneStore) invalidateAllRRSIGsLocked(zone string) {
 		return
 	}
 	delete(zoneMap, string(types.TypeRRSIG))
+	z.markChangedLocked(zone, "", "")
 }
 
 func ownerFQDN(zone, name string) string {
@@ -1800,7 +2032,7 @@ func nsecRecordFromRaw(raw any) (types.NSECRecord, bool) {
 func nsecRecordToDNS(owner string, rec types.NSECRecord) *dns.NSEC {
 	var bitmap []uint16
 	for _, t := range rec.Types {
-		if code, ok := dns.StringToType[strings.ToUpper(t)]; ok {
+		if code, ok := internal.TypeCode(t); ok {
 			bitmap = append(bitmap, code)
 		}
 	}
@@ -1909,7 +2141,7 @@ func nsec3RecordFromRaw(raw any) (types.NSEC3Record, bool) {
 func nsec3RecordToDNS(hash, zone string, rec types.NSEC3Record) *dns.NSEC3 {
 	var bitmap []uint16
 	for _, t := range rec.Types {
-		if code, ok := dns.StringToType[strings.ToUpper(t)]; ok {
+		if code, ok := internal.TypeCode(t); ok {
 			bitmap = append(bitmap, code)
 		}
 	}
@@ -2065,7 +2297,7 @@ func rrsigRecordFromMap(raw map[string]interface{}) *types.RRSIGRecord {
 }
 
 func rrsigRecordToDNS(owner string, sig *types.RRSIGRecord) (*dns.RRSIG, error) {
-	covered, ok := dns.StringToType[sig.TypeCovered]
+	covered, ok := internal.TypeCode(sig.TypeCovered)
 	if !ok {
 		return nil, fmt.Errorf("invalid type_covered: %s", sig.TypeCovered)
 	}
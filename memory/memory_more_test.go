@@ -174,3 +174,36 @@ func TestHasOtherRecordsAndDeepSizeEdges(t *testing.T) {
 		t.Fatalf("HasOtherRecords excluded type = found=%v rrtype=%d", found, rrtype)
 	}
 }
+
+func TestAddRecordRejectsRRsetTTLMismatch(t *testing.T) {
+	store, err := NewZoneStore(setupMemoryStoreBackend(t))
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+
+	cases := []struct {
+		rtype         string
+		first, second any
+	}{
+		{"NAPTR", []types.NAPTRRecord{{Order: 10, TTL: 300}}, []types.NAPTRRecord{{Order: 10, TTL: 600}}},
+		{"SVCB", []types.SVCBRecord{{Priority: 1, Target: ".", TTL: 300}}, []types.SVCBRecord{{Priority: 1, Target: ".", TTL: 600}}},
+		{"HTTPS", []types.HTTPSRecord{{Priority: 1, Target: ".", TTL: 300}}, []types.HTTPSRecord{{Priority: 1, Target: ".", TTL: 600}}},
+		{"LOC", []types.LOCRecord{{TTL: 300}}, []types.LOCRecord{{TTL: 600}}},
+		{"CERT", []types.CERTRecord{{Type: 1, TTL: 300}}, []types.CERTRecord{{Type: 1, TTL: 600}}},
+		{"SSHFP", []types.SSHFPRecord{{Algorithm: 4, TTL: 300}}, []types.SSHFPRecord{{Algorithm: 4, TTL: 600}}},
+		{"URI", []types.URIRecord{{Priority: 10, TTL: 300}}, []types.URIRecord{{Priority: 10, TTL: 600}}},
+		{"APL", []types.APLRecord{{AddressFamily: 1, TTL: 300}}, []types.APLRecord{{AddressFamily: 1, TTL: 600}}},
+		{"TLSA", []types.TLSARecord{{Usage: 3, TTL: 300}}, []types.TLSARecord{{Usage: 3, TTL: 600}}},
+		{"SMIMEA", []types.SMIMEARecord{{Usage: 3, TTL: 300}}, []types.SMIMEARecord{{Usage: 3, TTL: 600}}},
+		{"OPENPGPKEY", []types.OPENPGPKEYRecord{{TTL: 300}}, []types.OPENPGPKEYRecord{{TTL: 600}}},
+		{"TYPE65534", []types.UnknownRecord{{Rdata: "0a000001", TTL: 300}}, []types.UnknownRecord{{Rdata: "0a000001", TTL: 600}}},
+	}
+	for _, tc := range cases {
+		if err := store.AddRecord("ttl.test.", tc.rtype, "host", tc.first); err != nil {
+			t.Fatalf("%s: first AddRecord: %v", tc.rtype, err)
+		}
+		if err := store.AddRecord("ttl.test.", tc.rtype, "host", tc.second); err == nil {
+			t.Fatalf("%s: AddRecord accepted an RRset with a different TTL", tc.rtype)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: staging.go is part of the go53 authoritative DNS server.
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// stagingZoneSuffix parents every scratch zone. .invalid (RFC 6761) never
+// resolves, so no query can be answered from a half-built staging zone.
+const stagingZoneSuffix = "staging.go53.invalid."
+
+var stagingSeq atomic.Uint64
+
+// RRsetKey names one stored RRset. Type is the storage type key ("A", "SOA",
+// "RRSIG"), Name the owner relative to the zone ("@" for the apex). RRSIGs are
+// stored per covered type, so Covered must be set when Type is "RRSIG".
+type RRsetKey struct {
+	Type    string
+	Name    string
+	Covered string
+}
+
+// BeginStaging creates an empty scratch zone. RRsets can be built in it with
+// the normal record Add paths and then moved into a live zone in one step by
+// CommitStaging. Staging zones are not persisted and not listed.
+func (z *InMemoryZoneStore) BeginStaging() string {
+	name := fmt.Sprintf("s%d.%s", stagingSeq.Add(1), stagingZoneSuffix)
+	z.mu.Lock()
+	z.staging[name] = true
+	z.cache["zones"][name] = make(map[string]map[string]any)
+	z.mu.Unlock()
+	return name
+}
+
+// DiscardStaging drops a scratch zone without touching any live zone.
+func (z *InMemoryZoneStore) DiscardStaging(staging string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if !z.staging[staging] {
+		return
+	}
+	delete(z.cache["zones"], staging)
+	delete(z.staging, staging)
+}
+
+// CommitStaging replaces every RRset named in keys in zone with its staged
+// value, or deletes it when nothing was staged for it, then drops the staging
+// zone and persists zone once. All replacements happen under a single write
+// lock, so readers see either the old or the new zone content, never a mix.
+// When go53 signs zone, the replaced RRsets and the NSEC/NSEC3 chains are
+// re-signed afterwards, as AddRecord does for a single RRset.
+func (z *InMemoryZoneStore) CommitStaging(staging, zone string, keys []RRsetKey) error {
+	dnssecPrimary := signsZone(zone)
+	var resign []RRsetKey
+	rebuildNSEC := false
+
+	z.mu.Lock()
+	if !z.staging[staging] {
+		z.mu.Unlock()
+		return fmt.Errorf("unknown staging zone %s", staging)
+	}
+	src := z.cache["zones"][staging]
+	zones := z.cache["zones"]
+	if _, ok := zones[zone]; !ok {
+		zones[zone] = make(map[string]map[string]any)
+	}
+	dst := zones[zone]
+
+	for _, key := range keys {
+		if key.Type == "RRSIG" {
+			commitStagedRRSIG(src, dst, key)
+			z.markChangedLocked(zone, key.Covered, key.Name)
+			continue
+		}
+		z.markChangedLocked(zone, key.Type, key.Name)
+		value, found := lookupFold(src[key.Type], key.Name)
+		deleteFold(dst[key.Type], key.Name)
+		if dnssecPrimary {
+			z.invalidateRRSIGLocked(zone, key.Type, key.Name)
+			rebuildNSEC = rebuildNSEC || shouldMaintainNSEC(key.Type)
+		}
+		if !found {
+			continue
+		}
+		if _, ok := dst[key.Type]; !ok {
+			dst[key.Type] = make(map[string]any)
+		}
+		dst[key.Type][key.Name] = value
+		resign = append(resign, key)
+	}
+
+	delete(zones, staging)
+	delete(z.staging, staging)
+	z.gen[zone]++
+	if rebuildNSEC {
+		z.rebuildNSECChainLocked(zone)
+		z.rebuildNSEC3ChainLocked(zone)
+	}
+	err := z.persistLocked(zone)
+	z.mu.Unlock()
+	if err != nil || !dnssecPrimary {
+		return err
+	}
+
+	for _, key := range resign {
+		z.spawnSign(func() { z.maybeSignRRSet(zone, key.Type, key.Name) })
+	}
+	if rebuildNSEC {
+		z.spawnSign(func() { z.signNSECChain(zone) })
+		z.spawnSign(func() { z.signNSEC3Chain(zone) })
+	}
+	return nil
+}
+
+func commitStagedRRSIG(src, dst map[string]map[string]any, key RRsetKey) {
+	staged := rrsigNameMap(src["RRSIG"][key.Covered])
+	live := rrsigNameMap(dst["RRSIG"][key.Covered])
+	deleteFold(live, key.Name)
+	if value, ok := lookupFold(staged, key.Name); ok {
+		live[key.Name] = value
+	}
+	if _, ok := dst["RRSIG"]; !ok {
+		dst["RRSIG"] = make(map[string]any)
+	}
+	if len(live) == 0 {
+		delete(dst["RRSIG"], key.Covered)
+		return
+	}
+	dst["RRSIG"][key.Covered] = live
+}
+
+// rrsigNameMap normalizes the per-covered-type RRSIG map, which is written as
+// map[string][]map[string]interface{} by RRSIGRecord.Add and decoded from
+// storage as map[string]any, into a fresh map[string]any.
+func rrsigNameMap(raw any) map[string]any {
+	out := map[string]any{}
+	switch v := raw.(type) {
+	case map[string]any:
+		for name, sigs := range v {
+			out[name] = sigs
+		}
+	case map[string][]map[string]interface{}:
+		for name, sigs := range v {
+			out[name] = sigs
+		}
+	}
+	return out
+}
+
+func lookupFold(m map[string]any, name string) (any, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	for candidate, v := range m {
+		if strings.EqualFold(candidate, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func deleteFold(m map[string]any, name string) {
+	for candidate := range m {
+		if strings.EqualFold(candidate, name) {
+			delete(m, candidate)
+		}
+	}
+}
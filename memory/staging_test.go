@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestCommitStagingReplacesAndDeletesRRsets(t *testing.T) {
+	backend := setupMemoryStoreBackend(t)
+	store, err := NewZoneStore(backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	if err := store.PutRecordRaw("stage.test.", "A", "www", []any{map[string]any{"ip": "192.0.2.1", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("PutRecordRaw www: %v", err)
+	}
+	if err := store.PutRecordRaw("stage.test.", "A", "old", []any{map[string]any{"ip": "192.0.2.2", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("PutRecordRaw old: %v", err)
+	}
+	if err := store.PutRecordRaw("stage.test.", "RRSIG", "A", map[string]any{"www": []any{}, "old": []any{}}); err != nil {
+		t.Fatalf("PutRecordRaw RRSIG: %v", err)
+	}
+
+	staging := store.BeginStaging()
+	if err := store.AddRecord(staging, "A", "www", []any{map[string]any{"ip": "192.0.2.9", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("stage www: %v", err)
+	}
+	for _, zone := range store.ZoneNamesSnapshot() {
+		if zone == staging {
+			t.Fatalf("staging zone must not be listed")
+		}
+	}
+	if data, _ := backend.LoadZone(staging); data != nil {
+		t.Fatalf("staging zone must not be persisted")
+	}
+
+	err = store.CommitStaging(staging, "stage.test.", []RRsetKey{
+		{Type: "A", Name: "www"},
+		{Type: "A", Name: "old"},
+		{Type: "RRSIG", Name: "old", Covered: "A"},
+	})
+	if err != nil {
+		t.Fatalf("CommitStaging: %v", err)
+	}
+	_, _, raw, ok := store.GetRecord("stage.test.", "A", "www")
+	if !ok || raw.([]any)[0].(map[string]any)["ip"] != "192.0.2.9" {
+		t.Fatalf("www after commit = %#v ok=%v", raw, ok)
+	}
+	if _, _, _, ok := store.GetRecord("stage.test.", "A", "old"); ok {
+		t.Fatalf("unstaged RRset must be deleted on commit")
+	}
+	_, _, sigs, _ := store.GetRecord("stage.test.", "RRSIG", "A")
+	if m := sigs.(map[string]any); len(m) != 1 || m["www"] == nil {
+		t.Fatalf("RRSIG map after commit = %#v, want only www", sigs)
+	}
+	if err := store.CommitStaging(staging, "stage.test.", nil); err == nil {
+		t.Fatalf("committing a staging zone twice must fail")
+	}
+}
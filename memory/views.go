@@ -0,0 +1,171 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: views.go is part of the go53 authoritative DNS server.
+package memory
+
+import (
+	"strings"
+
+	"go53/storage"
+)
+
+// viewStoragePrefix namespaces the zones of a split-horizon view in the zone
+// storage: view "internal" keeps example.com under "view/internal/example.com.".
+const viewStoragePrefix = "view/"
+
+// IsViewStorageName reports whether a stored zone name belongs to a view.
+func IsViewStorageName(name string) bool {
+	return strings.HasPrefix(name, viewStoragePrefix)
+}
+
+// viewStorage exposes the zones of one view as if they were the only zones in
+// the backend. Tables are shared, so DNSSEC keys and TSIG keys stay global.
+type viewStorage struct {
+	storage.Storage
+	prefix string
+}
+
+// NewViewStore opens the zones stored for view on top of backend s.
+func NewViewStore(s storage.Storage, view string) (*InMemoryZoneStore, error) {
+	zs := newZoneStore(&viewStorage{Storage: s, prefix: viewStoragePrefix + view + "/"})
+	if err := zs.loadFromStorage(); err != nil {
+		return nil, err
+	}
+	return zs, nil
+}
+
+func (v *viewStorage) SaveZone(name string, data []byte) error {
+	return v.Storage.SaveZone(v.prefix+name, data)
+}
+
+func (v *viewStorage) LoadZone(name string) ([]byte, error) {
+	return v.Storage.LoadZone(v.prefix + name)
+}
+
+func (v *viewStorage) DeleteZone(name string) error {
+	return v.Storage.DeleteZone(v.prefix + name)
+}
+
+func (v *viewStorage) ListZones() ([]string, error) {
+	names, err := v.Storage.ListZones()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for _, name := range names {
+		if zone, ok := strings.CutPrefix(name, v.prefix); ok {
+			out = append(out, zone)
+		}
+	}
+	return out, nil
+}
+
+func (v *viewStorage) LoadAllZones() (map[string][]byte, error) {
+	all, err := v.Storage.LoadAllZones()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte)
+	for name, data := range all {
+		if zone, ok := strings.CutPrefix(name, v.prefix); ok {
+			out[zone] = data
+		}
+	}
+	return out, nil
+}
+
+// transientStorage backs the merged stores of overlay views, which are
+// rebuilt from their sources and never persisted.
+type transientStorage struct{}
+
+func (transientStorage) Init() error                                 { return nil }
+func (transientStorage) SaveZone(string, []byte) error               { return nil }
+func (transientStorage) LoadZone(string) ([]byte, error)             { return nil, nil }
+func (transientStorage) DeleteZone(string) error                     { return nil }
+func (transientStorage) ListZones() ([]string, error)                { return nil, nil }
+func (transientStorage) LoadAllZones() (map[string][]byte, error)    { return map[string][]byte{}, nil }
+func (transientStorage) LoadTable(string) (map[string][]byte, error) { return map[string][]byte{}, nil }
+func (transientStorage) SaveTable(string, string, []byte) error      { return nil }
+func (transientStorage) DeleteFromTable(string, string) error        { return nil }
+
+// NewOverlayStore builds an unpersisted store holding zone as the RRsets of
+// base with every RRset of overlay replacing the one at the same owner and
+// type. Signatures and denial chains are regenerated for the merged data.
+func NewOverlayStore(base, overlay *InMemoryZoneStore, zone string) (*InMemoryZoneStore, error) {
+	merged, err := base.zoneDataCopy(zone)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := overlay.zoneDataCopy(zone)
+	if err != nil {
+		return nil, err
+	}
+	for rtype, names := range overrides {
+		switch rtype {
+		case "RRSIG", "NSEC", "NSEC3":
+			continue
+		}
+		if merged[rtype] == nil {
+			merged[rtype] = make(map[string]any)
+		}
+		for name, rec := range names {
+			merged[rtype][name] = rec
+		}
+	}
+	for _, rtype := range []string{"RRSIG", "NSEC", "NSEC3"} {
+		delete(merged, rtype)
+	}
+
+	zs := newZoneStore(transientStorage{})
+	zs.mu.Lock()
+	zs.cache["zones"][zone] = merged
+	zs.mu.Unlock()
+	if err := zs.RefreshDNSSECKeyMaterial(zone); err != nil {
+		return nil, err
+	}
+	return zs, nil
+}
+
+// Generation returns a counter that changes whenever the data of zone does.
+func (z *InMemoryZoneStore) Generation(zone string) uint64 {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	return z.gen[zone]
+}
+
+// HasZone reports whether the store holds zone.
+func (z *InMemoryZoneStore) HasZone(zone string) bool {
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+	_, ok := z.cache["zones"][zone]
+	return ok && !z.staging[zone]
+}
+
+// zoneDataCopy returns a deep copy of the stored data of zone, made through
+// the storage encoding so records share nothing with the cache.
+func (z *InMemoryZoneStore) zoneDataCopy(zone string) (map[string]map[string]any, error) {
+	z.mu.RLock()
+	data, ok := z.cache["zones"][zone]
+	if !ok {
+		z.mu.RUnlock()
+		return make(map[string]map[string]any), nil
+	}
+	raw, err := encodeZoneData(data)
+	z.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return decodeZoneData(raw)
+}
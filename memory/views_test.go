@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestViewStoreIsolatedFromDefaultZones(t *testing.T) {
+	backend := setupMemoryStoreBackend(t)
+	view, err := NewViewStore(backend, "internal")
+	if err != nil {
+		t.Fatalf("NewViewStore: %v", err)
+	}
+	if err := view.PutRecordRaw("view.test.", "A", "www", []any{map[string]any{"ip": "10.0.0.1", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("PutRecordRaw: %v", err)
+	}
+	if data, _ := backend.LoadZone("view/internal/view.test."); data == nil {
+		t.Fatalf("view zone was not persisted under the view prefix")
+	}
+
+	store, err := NewZoneStore(backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	if names := store.ZoneNamesSnapshot(); len(names) != 0 {
+		t.Fatalf("default store loaded view zones: %v", names)
+	}
+	reopened, err := NewViewStore(backend, "internal")
+	if err != nil {
+		t.Fatalf("reopen view: %v", err)
+	}
+	if names := reopened.ZoneNamesSnapshot(); len(names) != 1 || names[0] != "view.test." {
+		t.Fatalf("view zones = %v", names)
+	}
+	if other, _ := NewViewStore(backend, "other"); len(other.ZoneNamesSnapshot()) != 0 {
+		t.Fatalf("view zones leaked into another view")
+	}
+}
+
+func TestOverlayStoreReplacesRRsetsAndTracksGenerations(t *testing.T) {
+	backend := setupMemoryStoreBackend(t)
+	base, err := NewZoneStore(backend)
+	if err != nil {
+		t.Fatalf("NewZoneStore: %v", err)
+	}
+	overlay, err := NewViewStore(backend, "lab")
+	if err != nil {
+		t.Fatalf("NewViewStore: %v", err)
+	}
+	if err := base.PutRecordRaw("merge.test.", "A", "www", []any{map[string]any{"ip": "192.0.2.1", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("base www: %v", err)
+	}
+	if err := base.PutRecordRaw("merge.test.", "A", "mail", []any{map[string]any{"ip": "192.0.2.25", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("base mail: %v", err)
+	}
+	gen := base.Generation("merge.test.")
+	if err := overlay.PutRecordRaw("merge.test.", "A", "www", []any{map[string]any{"ip": "10.0.0.1", "ttl": float64(300)}}); err != nil {
+		t.Fatalf("overlay www: %v", err)
+	}
+	if base.Generation("merge.test.") != gen {
+		t.Fatalf("overlay write changed the base generation")
+	}
+
+	merged, err := NewOverlayStore(base, overlay, "merge.test.")
+	if err != nil {
+		t.Fatalf("NewOverlayStore: %v", err)
+	}
+	records := merged.ZoneRecordsSnapshot("merge.test.")["A"]
+	if len(records) != 2 {
+		t.Fatalf("merged A owners = %v", records)
+	}
+	www, _ := records["www"].([]any)
+	if len(www) != 1 || www[0].(map[string]any)["ip"] != "10.0.0.1" {
+		t.Fatalf("merged www = %v, want the overlay RRset", records["www"])
+	}
+}
@@ -0,0 +1,400 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: algorithm_rollover.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/storage"
+	"go53/types"
+	"go53/wal"
+)
+
+const dnssecAlgorithmRolloverTable = "dnssec_algorithm_rollover"
+
+// Phases of an algorithm rollover, the steps of the conservative approach of
+// RFC 6781 section 4.1.4.
+const (
+	// The keys of the new algorithm sign every RRset; their DNSKEYs are not
+	// published until the signatures have reached every cache.
+	AlgorithmRolloverNewSignatures = "new-signatures"
+	// The DNSKEY RRset holds the keys of both algorithms.
+	AlgorithmRolloverNewDNSKEYs = "new-dnskeys"
+	// CDS and CDNSKEY name the new KSK only, asking the parent to swap the DS.
+	AlgorithmRolloverNewDS = "new-ds"
+	// The old DNSKEYs are gone; their signatures stay until the old DNSKEY
+	// RRset has expired from caches.
+	AlgorithmRolloverDNSKEYRemoval = "dnskey-removal"
+	AlgorithmRolloverCompleted     = "completed"
+)
+
+// AlgorithmRollover is the plan of a zone's move from the keys of one
+// algorithm to another. RemoveAt and RetireAt stay 0 while the parent DS is
+// checked and the DS of the new KSK has not settled there.
+type AlgorithmRollover struct {
+	Zone      string   `json:"zone"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Phase     string   `json:"phase"`
+	StartedAt int64    `json:"started_at"`
+	PublishAt int64    `json:"publish_at"`          // new DNSKEYs are published
+	DSAt      int64    `json:"ds_at"`               // CDS and CDNSKEY switch to the new KSK
+	RemoveAt  int64    `json:"remove_at,omitempty"` // old DNSKEYs are removed
+	RetireAt  int64    `json:"retire_at,omitempty"` // old signatures are removed
+	NewKeys   []string `json:"new_keys"`
+	OldKeys   []string `json:"old_keys"`
+}
+
+// AlgorithmRolloverStatus is an algorithm rollover at a point in time: its
+// phase, what it waits for and when the next phase starts, 0 when that
+// depends on the parent.
+type AlgorithmRolloverStatus struct {
+	AlgorithmRollover
+	Phase      string `json:"phase"`
+	WaitingFor string `json:"waiting_for,omitempty"`
+	NextAt     int64  `json:"next_at,omitempty"`
+}
+
+var algorithmRolloverCache = struct {
+	sync.RWMutex
+	backendID string
+	zones     map[string]AlgorithmRollover
+}{}
+
+func algorithmRolloverKey(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(dns.Fqdn(zone), "."))
+}
+
+func resetAlgorithmRolloverCache() {
+	algorithmRolloverCache.Lock()
+	algorithmRolloverCache.zones = nil
+	algorithmRolloverCache.Unlock()
+}
+
+// ZoneAlgorithmRollover returns the last algorithm rollover of zone; ok is
+// false when it never had one.
+func ZoneAlgorithmRollover(zone string) (AlgorithmRollover, bool, error) {
+	backendID := currentStorageBackendID()
+	algorithmRolloverCache.RLock()
+	if algorithmRolloverCache.zones != nil && algorithmRolloverCache.backendID == backendID {
+		r, ok := algorithmRolloverCache.zones[algorithmRolloverKey(zone)]
+		algorithmRolloverCache.RUnlock()
+		return r, ok, nil
+	}
+	algorithmRolloverCache.RUnlock()
+
+	if storage.Backend == nil {
+		return AlgorithmRollover{}, false, fmt.Errorf("storage backend is not initialized")
+	}
+	table, err := storage.Backend.LoadTable(dnssecAlgorithmRolloverTable)
+	if err != nil {
+		return AlgorithmRollover{}, false, fmt.Errorf("load table: %w", err)
+	}
+	zones := make(map[string]AlgorithmRollover, len(table))
+	for key, raw := range table {
+		var r AlgorithmRollover
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return AlgorithmRollover{}, false, fmt.Errorf("unmarshal algorithm rollover of %q: %w", key, err)
+		}
+		zones[key] = r
+	}
+
+	algorithmRolloverCache.Lock()
+	algorithmRolloverCache.zones = zones
+	algorithmRolloverCache.backendID = backendID
+	algorithmRolloverCache.Unlock()
+	r, ok := zones[algorithmRolloverKey(zone)]
+	return r, ok, nil
+}
+
+func saveAlgorithmRollover(r AlgorithmRollover) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	key := algorithmRolloverKey(r.Zone)
+	if err := storage.Backend.SaveTable(dnssecAlgorithmRolloverTable, key, data); err != nil {
+		return err
+	}
+	if _, err := wal.Append(wal.KindDNSSECKey, wal.OpUpsert, "", "", "", dnssecAlgorithmRolloverTable, key, data); err != nil {
+		return err
+	}
+	resetAlgorithmRolloverCache()
+	return nil
+}
+
+// SaveReplicatedAlgorithmRollover stores the algorithm rollover of a zone
+// received from another node.
+func SaveReplicatedAlgorithmRollover(zone string, data []byte) error {
+	if err := storage.Backend.SaveTable(dnssecAlgorithmRolloverTable, algorithmRolloverKey(zone), data); err != nil {
+		return err
+	}
+	resetAlgorithmRolloverCache()
+	return nil
+}
+
+// PhaseAt returns the phase of r at now.
+func (r AlgorithmRollover) PhaseAt(now int64) string {
+	switch {
+	case now < r.PublishAt:
+		return AlgorithmRolloverNewSignatures
+	case now < r.DSAt:
+		return AlgorithmRolloverNewDNSKEYs
+	case r.RemoveAt == 0 || now < r.RemoveAt:
+		return AlgorithmRolloverNewDS
+	case now < r.RetireAt:
+		return AlgorithmRolloverDNSKEYRemoval
+	default:
+		return AlgorithmRolloverCompleted
+	}
+}
+
+// StatusAt describes r at now.
+func (r AlgorithmRollover) StatusAt(now int64) AlgorithmRolloverStatus {
+	out := AlgorithmRolloverStatus{AlgorithmRollover: r, Phase: r.PhaseAt(now)}
+	switch out.Phase {
+	case AlgorithmRolloverNewSignatures:
+		out.WaitingFor = "signatures of " + r.To + " to reach every cache"
+		out.NextAt = r.PublishAt
+	case AlgorithmRolloverNewDNSKEYs:
+		out.WaitingFor = "the DNSKEY RRset with the " + r.To + " keys to reach every cache"
+		out.NextAt = r.DSAt
+	case AlgorithmRolloverNewDS:
+		if r.RemoveAt == 0 {
+			out.WaitingFor = "the parent to publish the DS of the " + r.To + " KSK for the TTL of its DS RRset"
+		} else {
+			out.WaitingFor = "the DS of the " + r.To + " KSK to replace the old one in every cache"
+		}
+		out.NextAt = r.RemoveAt
+	case AlgorithmRolloverDNSKEYRemoval:
+		out.WaitingFor = "the DNSKEY RRset with the " + r.From + " keys to expire from every cache"
+		out.NextAt = r.RetireAt
+	}
+	return out
+}
+
+// inProgressAt reports whether r still has phases ahead at now.
+func (r AlgorithmRollover) inProgressAt(now int64) bool {
+	return r.PhaseAt(now) != AlgorithmRolloverCompleted
+}
+
+// algorithmRolloverTimes returns when the DNSKEYs of an algorithm rollover
+// started at start are published, when the DS is swapped, when the old
+// DNSKEYs go and when their signatures follow. The new signatures must be
+// cached wherever a DNSKEY RRset with the new keys can be, and the new
+// DNSKEYs wherever the new DS can be; the reverse holds for the removals.
+func algorithmRolloverTimes(p config.KASPPolicy, start int64) (publishAt, dsAt, removeAt, retireAt int64) {
+	publishAt = start + int64(p.PropagationDelaySec+max(p.DNSKEYTTLSec, p.MaxZoneTTLSec)+p.PublishSafetySec)
+	dsAt = publishAt + int64(p.PropagationDelaySec+p.DNSKEYTTLSec+p.PublishSafetySec)
+	removeAt = dsAt + int64(p.ParentPropagationDelaySec+p.ParentDSTTLSec+p.RetireSafetySec)
+	return publishAt, dsAt, removeAt, oldSignaturesRetireAt(p, removeAt)
+}
+
+func oldSignaturesRetireAt(p config.KASPPolicy, removeAt int64) int64 {
+	return removeAt + int64(p.PropagationDelaySec+p.DNSKEYTTLSec+p.RetireSafetySec)
+}
+
+// kaspAlgorithm returns the algorithm the scheduler keeps zone in: the target
+// of its algorithm rollover while that runs or while the policy still names
+// the algorithm the zone left, and the policy's otherwise. The rollover is
+// returned until its completion has been recorded.
+func kaspAlgorithm(zone string, p config.KASPPolicy, now int64) (string, *AlgorithmRollover, error) {
+	r, ok, err := ZoneAlgorithmRollover(zone)
+	if err != nil || !ok {
+		return p.Algorithm, nil, err
+	}
+	if r.inProgressAt(now) || r.Phase != AlgorithmRolloverCompleted {
+		return r.To, &r, nil
+	}
+	if p.Algorithm == r.From {
+		return r.To, nil, nil
+	}
+	return p.Algorithm, nil, nil
+}
+
+// StartAlgorithmRollover moves zone from the algorithm of its keys to
+// algorithmName with the timing of policy p. A KSK and a ZSK of the new
+// algorithm are generated and sign at once, but their DNSKEYs are only
+// published once every RRset carries signatures of both algorithms in every
+// cache. The key scheduler takes the rollover through its further phases.
+func StartAlgorithmRollover(zone string, p config.KASPPolicy, algorithmName string, now int64) (AlgorithmRollover, KASPResult, error) {
+	res := KASPResult{Changed: map[string]types.StoredKey{}}
+	p, err := NormalizeKASPPolicy(p)
+	if err != nil {
+		return AlgorithmRollover{}, res, err
+	}
+	zone = algorithmRolloverKey(zone)
+	to := strings.ToUpper(strings.TrimSpace(algorithmName))
+	if !signingAlgorithm(to) {
+		return AlgorithmRollover{}, res, fmt.Errorf("unsupported signing algorithm %q", algorithmName)
+	}
+	if OfflineKSK(zone) {
+		return AlgorithmRollover{}, res, fmt.Errorf("zone %s keeps its KSK offline; roll its algorithm on the offline signer", zone)
+	}
+	if r, ok, err := ZoneAlgorithmRollover(zone); err != nil {
+		return AlgorithmRollover{}, res, err
+	} else if ok && r.inProgressAt(now) {
+		return AlgorithmRollover{}, res, fmt.Errorf("zone %s is already rolling from %s to %s (%s)", zone, r.From, r.To, r.PhaseAt(now))
+	}
+
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return AlgorithmRollover{}, res, err
+	}
+	var old []kaspKey
+	var algorithms []string
+	for _, k := range keys {
+		if k.key.Revoke || keyStateAt(&k.key, now) == KeyStateRemoved {
+			continue
+		}
+		old = append(old, k)
+		if !slices.Contains(algorithms, k.key.Algorithm) {
+			algorithms = append(algorithms, k.key.Algorithm)
+		}
+	}
+	sort.Strings(algorithms)
+	switch {
+	case len(old) == 0:
+		return AlgorithmRollover{}, res, fmt.Errorf("zone %s has no keys; it is signed with the policy algorithm once it gets its first", zone)
+	case len(algorithms) > 1:
+		return AlgorithmRollover{}, res, fmt.Errorf("zone %s has keys of %s; remove all but one algorithm first", zone, strings.Join(algorithms, ", "))
+	case algorithms[0] == to:
+		return AlgorithmRollover{}, res, fmt.Errorf("zone %s is already signed with %s", zone, to)
+	}
+
+	r := AlgorithmRollover{Zone: zone, From: algorithms[0], To: to, StartedAt: now, NewKeys: []string{}, OldKeys: []string{}}
+	var removeAt, retireAt int64
+	r.PublishAt, r.DSAt, removeAt, retireAt = algorithmRolloverTimes(p, now)
+	if !ParentDSChecked() {
+		r.RemoveAt, r.RetireAt = removeAt, retireAt
+	}
+	for _, role := range []string{KeyRoleKSK, KeyRoleZSK} {
+		id, key, err := GenerateRolloverKey(zone, role, to, r.PublishAt, now)
+		if err != nil {
+			return r, res, fmt.Errorf("generate %s %s for %s: %w", to, role, zone, err)
+		}
+		res.Changed[id] = *key
+		r.NewKeys = append(r.NewKeys, id)
+		res.Decisions = append(res.Decisions, fmt.Sprintf("generated %s %s (tag %d) signing at once, publishing at %d", role, id, key.KeyTag, r.PublishAt))
+	}
+	for _, k := range old {
+		r.OldKeys = append(r.OldKeys, k.id)
+	}
+	if err := retireOldAlgorithmKeys(r, old, now, &res); err != nil {
+		return r, res, err
+	}
+	r.Phase = r.PhaseAt(now)
+	if err := saveAlgorithmRollover(r); err != nil {
+		return r, res, err
+	}
+	res.Rollover = &r
+	res.Decisions = append(res.Decisions, fmt.Sprintf("started algorithm rollover from %s to %s", r.From, r.To))
+	return r, res, nil
+}
+
+// retireOldAlgorithmKeys gives the keys of the old algorithm the removal
+// times of r, once those are known. Times a key already has that are
+// earlier are kept.
+func retireOldAlgorithmKeys(r AlgorithmRollover, old []kaspKey, now int64, res *KASPResult) error {
+	if r.RemoveAt == 0 {
+		return nil
+	}
+	for _, k := range old {
+		if k.key.RemoveAt == 0 || k.key.RemoveAt > r.RemoveAt {
+			k.key.RemoveAt = r.RemoveAt
+		}
+		if k.key.RetireAt == 0 || k.key.RetireAt > r.RetireAt {
+			k.key.RetireAt = r.RetireAt
+		}
+		k.key.State = keyStateAt(&k.key, now)
+		if err := saveStoredKey(k.id, &k.key); err != nil {
+			return err
+		}
+		res.Changed[k.id] = k.key
+		res.Decisions = append(res.Decisions, fmt.Sprintf("removing %s %s at %d, its signatures at %d", r.From, k.id, k.key.RemoveAt, k.key.RetireAt))
+	}
+	return nil
+}
+
+// algorithmRolloverStep takes the algorithm rollover r of zone on at now.
+// With the parent DS checked, the old DNSKEYs are only scheduled for removal
+// once the DS of the new KSK has settled at the parent; other nodes than
+// the owner take that decision after delay. A change of phase is recorded
+// so the zone is re-signed with the key set and CDS RRset of the new phase.
+func algorithmRolloverStep(zone string, p config.KASPPolicy, r AlgorithmRollover, now, delay int64, res *KASPResult) error {
+	changed := false
+	if r.RemoveAt == 0 && now >= r.DSAt {
+		var settled int64
+		for _, id := range r.NewKeys {
+			if key, err := LoadStoredKey(id); err == nil && isKSK(key) {
+				settled = ParentDSSettledAt(key, int64(p.ParentDSTTLSec))
+			}
+		}
+		if settled != 0 && now >= settled+delay {
+			r.RemoveAt = max(settled, r.DSAt)
+			r.RetireAt = oldSignaturesRetireAt(p, r.RemoveAt)
+			keys, err := zoneKeys(zone)
+			if err != nil {
+				return err
+			}
+			var old []kaspKey
+			for _, k := range keys {
+				if slices.Contains(r.OldKeys, k.id) {
+					old = append(old, k)
+				}
+			}
+			res.Decisions = append(res.Decisions, fmt.Sprintf("DS of the %s KSK settled at the parent at %d", r.To, settled))
+			if err := retireOldAlgorithmKeys(r, old, now, res); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	if phase := r.PhaseAt(now); phase != r.Phase {
+		r.Phase = phase
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := saveAlgorithmRollover(r); err != nil {
+		return err
+	}
+	res.Rollover = &r
+	return nil
+}
+
+// dsExcludedAlgorithm returns the algorithm whose KSKs CDS and CDNSKEY leave
+// out at now: during an algorithm rollover the new one until the DS swap and
+// the old one from then on; "" otherwise.
+func dsExcludedAlgorithm(zone string, now int64) string {
+	r, ok, err := ZoneAlgorithmRollover(zone)
+	if err != nil || !ok || !r.inProgressAt(now) {
+		return ""
+	}
+	if now < r.DSAt {
+		return r.To
+	}
+	return r.From
+}
@@ -0,0 +1,242 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"go53/config"
+)
+
+func countAlgorithms(algorithms []string) map[string]int {
+	out := map[string]int{}
+	for _, a := range algorithms {
+		out[a]++
+	}
+	return out
+}
+
+func signingAlgorithms(t *testing.T, zone string, isDNSKEY bool, now int64) map[string]int {
+	t.Helper()
+	ids, err := ActiveSigningKeyIDs(zone, isDNSKEY, now)
+	if err != nil {
+		t.Fatalf("ActiveSigningKeyIDs: %v", err)
+	}
+	var out []string
+	for _, id := range ids {
+		key, err := LoadStoredKey(id)
+		if err != nil {
+			t.Fatalf("LoadStoredKey(%s): %v", id, err)
+		}
+		out = append(out, key.Algorithm)
+	}
+	return countAlgorithms(out)
+}
+
+func publishedAlgorithms(t *testing.T, zone string, now int64) map[string]int {
+	t.Helper()
+	keys, err := LoadPublishedKeysForZone(zone, now)
+	if err != nil {
+		t.Fatalf("LoadPublishedKeysForZone: %v", err)
+	}
+	var out []string
+	for _, key := range keys {
+		out = append(out, key.Algorithm)
+	}
+	return countAlgorithms(out)
+}
+
+func dsAlgorithms(t *testing.T, zone string, now int64) []uint8 {
+	t.Helper()
+	keys, err := ParentDSDNSKEYs(zone, now)
+	if err != nil {
+		t.Fatalf("ParentDSDNSKEYs: %v", err)
+	}
+	var out []uint8
+	for _, key := range keys {
+		out = append(out, key.Algorithm)
+	}
+	return out
+}
+
+func startTestAlgorithmRollover(t *testing.T, zone string, p config.KASPPolicy, now int64) AlgorithmRollover {
+	t.Helper()
+	if _, err := KASPStep(zone, p, now-1000, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	r, res, err := StartAlgorithmRollover(zone, p, "ed25519", now)
+	if err != nil {
+		t.Fatalf("StartAlgorithmRollover: %v", err)
+	}
+	if r.From != "ECDSAP256SHA256" || r.To != "ED25519" || len(r.NewKeys) != 2 || len(r.OldKeys) != 2 || res.Rollover == nil {
+		t.Fatalf("rollover = %+v, result %+v", r, res)
+	}
+	return r
+}
+
+func TestAlgorithmRolloverConservativeApproach(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	zone := "algroll.test"
+	now := int64(100000)
+	r := startTestAlgorithmRollover(t, zone, p, now)
+
+	// publish = 10+30+5, DS = +10+20+5, remove = +10+40+5, retire = +10+20+5
+	if r.PublishAt != now+45 || r.DSAt != now+80 || r.RemoveAt != now+135 || r.RetireAt != now+170 {
+		t.Fatalf("rollover times = %+v", r)
+	}
+	if got := r.StatusAt(now + 1); got.Phase != AlgorithmRolloverNewSignatures || got.NextAt != r.PublishAt {
+		t.Fatalf("status at start = %+v", got)
+	}
+
+	both := map[string]int{"ECDSAP256SHA256": 1, "ED25519": 1}
+	for _, tc := range []struct {
+		at        int64
+		phase     string
+		signing   map[string]int
+		published map[string]int
+		ds        uint8
+	}{
+		{now + 1, AlgorithmRolloverNewSignatures, both, map[string]int{"ECDSAP256SHA256": 2}, 13},
+		{now + 50, AlgorithmRolloverNewDNSKEYs, both, map[string]int{"ECDSAP256SHA256": 2, "ED25519": 2}, 13},
+		{now + 90, AlgorithmRolloverNewDS, both, map[string]int{"ECDSAP256SHA256": 2, "ED25519": 2}, 15},
+		{now + 140, AlgorithmRolloverDNSKEYRemoval, both, map[string]int{"ED25519": 2}, 15},
+		{now + 170, AlgorithmRolloverCompleted, map[string]int{"ED25519": 1}, map[string]int{"ED25519": 2}, 15},
+	} {
+		if _, err := KASPStep(zone, p, tc.at, 0); err != nil {
+			t.Fatalf("KASPStep at +%d: %v", tc.at-now, err)
+		}
+		if stored, _, _ := ZoneAlgorithmRollover(zone); stored.Phase != tc.phase {
+			t.Fatalf("at +%d phase %q, want %q", tc.at-now, stored.Phase, tc.phase)
+		}
+		for _, isDNSKEY := range []bool{false, true} {
+			if got := signingAlgorithms(t, zone, isDNSKEY, tc.at); !equalCounts(got, tc.signing) {
+				t.Fatalf("at +%d signing keys (KSK %v) = %v, want %v", tc.at-now, isDNSKEY, got, tc.signing)
+			}
+		}
+		if got := publishedAlgorithms(t, zone, tc.at); !equalCounts(got, tc.published) {
+			t.Fatalf("at +%d published keys = %v, want %v", tc.at-now, got, tc.published)
+		}
+		if got := dsAlgorithms(t, zone, tc.at); len(got) != 1 || got[0] != tc.ds {
+			t.Fatalf("at +%d CDS keys of algorithms %v, want %d", tc.at-now, got, tc.ds)
+		}
+	}
+	for _, id := range r.OldKeys {
+		if key, _ := LoadStoredKey(id); key.State != KeyStateRemoved {
+			t.Fatalf("old key %s is %s after the rollover", id, key.State)
+		}
+	}
+
+	// The policy still names the algorithm the zone left: the scheduler
+	// keeps to the new one instead of refusing the zone.
+	if res, err := KASPStep(zone, p, now+200, 0); err != nil || len(res.Decisions) != 0 {
+		t.Fatalf("step after the rollover = %+v, %v", res, err)
+	}
+	p.Algorithm = "RSASHA256"
+	if _, err := KASPStep(zone, p, now+200, 0); err == nil {
+		t.Fatal("KASPStep accepted a policy of a third algorithm")
+	}
+}
+
+func equalCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAlgorithmRolloverCDSFollowsTheDSSwap(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	zone := "cdsroll.test"
+	now := int64(100000)
+	r := startTestAlgorithmRollover(t, zone, p, now)
+	for _, id := range r.OldKeys {
+		if _, _, err := SetParentDSSeen(id, now-500, 40); err != nil {
+			t.Fatalf("SetParentDSSeen: %v", err)
+		}
+	}
+
+	if !CDSWithdrawn(zone, now+50) {
+		t.Fatal("CDS asks for the new KSK before the DS swap")
+	}
+	if CDSWithdrawn(zone, now+90) {
+		t.Fatal("CDS withdrawn while the parent has no DS for the new KSK")
+	}
+}
+
+func TestAlgorithmRolloverWaitsForParentDS(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.Resolvers = []string{"127.0.0.1:53"}
+	p := testKASPPolicy()
+	zone := "dsroll.test"
+	now := int64(100000)
+	r := startTestAlgorithmRollover(t, zone, p, now)
+	if r.RemoveAt != 0 || r.RetireAt != 0 {
+		t.Fatalf("removal planned before the parent was seen: %+v", r)
+	}
+
+	if _, err := KASPStep(zone, p, now+500, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	stored, _, _ := ZoneAlgorithmRollover(zone)
+	if stored.Phase != AlgorithmRolloverNewDS || stored.RemoveAt != 0 {
+		t.Fatalf("rollover without the parent DS = %+v", stored)
+	}
+	if status := stored.StatusAt(now + 500); status.NextAt != 0 || !strings.Contains(status.WaitingFor, "parent") {
+		t.Fatalf("status = %+v, want to wait for the parent", status)
+	}
+
+	var newKSK string
+	for _, id := range r.NewKeys {
+		if key, _ := LoadStoredKey(id); isKSK(key) {
+			newKSK = id
+		}
+	}
+	if _, _, err := SetParentDSSeen(newKSK, now+500, 60); err != nil {
+		t.Fatalf("SetParentDSSeen: %v", err)
+	}
+	if res, err := KASPStep(zone, p, now+530, 0); err != nil || res.Rollover != nil {
+		t.Fatalf("step before the DS settled = %+v, %v", res, err)
+	}
+	if _, err := KASPStep(zone, p, now+560, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	stored, _, _ = ZoneAlgorithmRollover(zone)
+	if stored.RemoveAt != now+560 || stored.RetireAt != now+595 || stored.Phase != AlgorithmRolloverDNSKEYRemoval {
+		t.Fatalf("rollover after the DS settled = %+v", stored)
+	}
+	for _, id := range r.OldKeys {
+		if key, _ := LoadStoredKey(id); key.RemoveAt != now+560 || key.RetireAt != now+595 {
+			t.Fatalf("old key %s times = %d/%d", id, key.RemoveAt, key.RetireAt)
+		}
+	}
+}
+
+func TestStartAlgorithmRolloverRefuses(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	now := int64(100000)
+	if _, _, err := StartAlgorithmRollover("empty.test", p, "ED25519", now); err == nil {
+		t.Fatal("started a rollover of a zone without keys")
+	}
+	if _, err := KASPStep("same.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	if _, _, err := StartAlgorithmRollover("same.test", p, "ECDSAP256SHA256", now); err == nil {
+		t.Fatal("started a rollover to the algorithm of the zone")
+	}
+	if _, _, err := StartAlgorithmRollover("same.test", p, "DSA", now); err == nil {
+		t.Fatal("started a rollover to an unsupported algorithm")
+	}
+	if _, _, err := StartAlgorithmRollover("same.test", p, "ED25519", now); err != nil {
+		t.Fatalf("StartAlgorithmRollover: %v", err)
+	}
+	if _, _, err := StartAlgorithmRollover("same.test", p, "RSASHA256", now+10); err == nil {
+		t.Fatal("started a second rollover while the first runs")
+	}
+}
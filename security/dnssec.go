@@ -24,7 +24,7 @@ func ToRRSet(name string, rtype string, raw any) ([]dns.RR, error) {
 	default:
 		slog.Crazy("[ToRRSet] raw är INTE []types.DNSKEYRecord utan %T", raw)
 	}
-	builder, ok := internal.RRBuilders[rtype]
+	builder, ok := internal.BuilderFor(rtype)
 	slog.Crazy("[ToRRSet] rtype is: ", rtype)
 	slog.Crazy("[ToRRSet] name is:", name)
 	slog.Crazy("[ToRRSet] raw is:", raw)
@@ -163,7 +163,7 @@ func RRSIGFromDNS(rrsig *dns.RRSIG) *types.RRSIGRecord {
 	}
 
 	return &types.RRSIGRecord{
-		TypeCovered: dns.TypeToString[rrsig.TypeCovered],
+		TypeCovered: internal.TypeName(rrsig.TypeCovered),
 		Algorithm:   rrsig.Algorithm,
 		Labels:      rrsig.Labels,
 		OrigTTL:     rrsig.OrigTtl,
@@ -0,0 +1,483 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: kasp.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/types"
+)
+
+// Rollover methods of a key and signing policy (RFC 6781 section 4.1).
+const (
+	RolloverPrePublish      = "pre-publish"
+	RolloverDoubleSignature = "double-signature"
+)
+
+// Key roles.
+const (
+	KeyRoleKSK = "ksk"
+	KeyRoleZSK = "zsk"
+)
+
+// Events in the life of a key.
+const (
+	KeyEventGenerate = "generate"
+	KeyEventPublish  = "publish"
+	KeyEventActivate = "activate"
+	KeyEventRetire   = "retire"
+	KeyEventRemove   = "remove"
+)
+
+// KeyEvent is a point in the life of a key. Planned events are not stored
+// yet: they belong to a successor the scheduler has still to generate, or to
+// the retirement of the key it replaces.
+type KeyEvent struct {
+	At        int64  `json:"at"`
+	Event     string `json:"event"`
+	Role      string `json:"role"`
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id,omitempty"`
+	KeyTag    uint16 `json:"key_tag,omitempty"`
+	Planned   bool   `json:"planned,omitempty"`
+}
+
+// KeyStatus is the lifecycle of a stored key without its private material.
+type KeyStatus struct {
+	KeyID      string `json:"key_id"`
+	Role       string `json:"role"`
+	Algorithm  string `json:"algorithm"`
+	KeyTag     uint16 `json:"key_tag"`
+	State      string `json:"state"`
+	PublishAt  int64  `json:"publish_at,omitempty"`
+	ActivateAt int64  `json:"activate_at,omitempty"`
+	RetireAt   int64  `json:"retire_at,omitempty"`
+	RemoveAt   int64  `json:"remove_at,omitempty"`
+}
+
+// KASPTimeline is the key set of a zone and the events ahead of it.
+type KASPTimeline struct {
+	Keys   []KeyStatus `json:"keys"`
+	Events []KeyEvent  `json:"events"`
+}
+
+// KASPResult lists the keys a scheduler step changed. Decisions describe the
+// rollover steps it took; the other changes are state updates that follow
+// from timestamps set before. Rollover is the algorithm rollover of the zone
+// when the step changed it.
+type KASPResult struct {
+	Changed   map[string]types.StoredKey
+	Decisions []string
+	Rollover  *AlgorithmRollover
+}
+
+type kaspKey struct {
+	id  string
+	key types.StoredKey
+}
+
+// NormalizeKASPPolicy fills in the defaults of p and checks it.
+func NormalizeKASPPolicy(p config.KASPPolicy) (config.KASPPolicy, error) {
+	if p.Algorithm == "" {
+		p.Algorithm = "ECDSAP256SHA256"
+	}
+	p.Algorithm = strings.ToUpper(strings.TrimSpace(p.Algorithm))
+	if !signingAlgorithm(p.Algorithm) {
+		return p, fmt.Errorf("unsupported signing algorithm %q", p.Algorithm)
+	}
+	if p.KSKRollover == "" {
+		p.KSKRollover = RolloverDoubleSignature
+	}
+	if p.ZSKRollover == "" {
+		p.ZSKRollover = RolloverPrePublish
+	}
+	if p.KSKRollover != RolloverDoubleSignature {
+		return p, fmt.Errorf("unsupported KSK rollover method %q", p.KSKRollover)
+	}
+	if p.ZSKRollover != RolloverPrePublish && p.ZSKRollover != RolloverDoubleSignature {
+		return p, fmt.Errorf("unsupported ZSK rollover method %q", p.ZSKRollover)
+	}
+	for name, v := range map[string]int{
+		"ksk_lifetime_sec":             p.KSKLifetimeSec,
+		"zsk_lifetime_sec":             p.ZSKLifetimeSec,
+		"dnskey_ttl_sec":               p.DNSKEYTTLSec,
+		"max_zone_ttl_sec":             p.MaxZoneTTLSec,
+		"propagation_delay_sec":        p.PropagationDelaySec,
+		"publish_safety_sec":           p.PublishSafetySec,
+		"retire_safety_sec":            p.RetireSafetySec,
+		"parent_ds_ttl_sec":            p.ParentDSTTLSec,
+		"parent_propagation_delay_sec": p.ParentPropagationDelaySec,
+	} {
+		if v < 0 {
+			return p, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for role, lifetime := range map[string]int{KeyRoleKSK: p.KSKLifetimeSec, KeyRoleZSK: p.ZSKLifetimeSec} {
+		if lead, _ := rolloverTiming(p, role); lifetime > 0 && int64(lifetime) <= lead {
+			return p, fmt.Errorf("%s lifetime %ds is shorter than its rollover (%ds)", role, lifetime, lead)
+		}
+	}
+	return p, nil
+}
+
+// signingAlgorithm reports whether keys of the named algorithm can be
+// generated and used to sign.
+func signingAlgorithm(name string) bool {
+	for _, algo := range supportedAlgos {
+		if algo.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloverTiming returns how long before the end of a key's lifetime its
+// successor is published, and how long after publication the successor
+// starts signing.
+//
+// Pre-publish: the successor's DNSKEY reaches every cache before it signs.
+// Double-signature: the successor signs at once and the old key stays until
+// every cached RRset carries a signature of the new one. A KSK also waits
+// for the parent to publish the new DS and for the old DS to expire.
+func rolloverTiming(p config.KASPPolicy, role string) (lead, activateAfter int64) {
+	publish := int64(p.PropagationDelaySec + p.DNSKEYTTLSec + p.PublishSafetySec)
+	switch {
+	case role == KeyRoleKSK:
+		return publish + int64(p.ParentPropagationDelaySec+p.ParentDSTTLSec+p.RetireSafetySec), 0
+	case p.ZSKRollover == RolloverDoubleSignature:
+		return int64(p.PropagationDelaySec + max(p.DNSKEYTTLSec, p.MaxZoneTTLSec) + p.PublishSafetySec), 0
+	default:
+		return publish, publish
+	}
+}
+
+// predecessorTimes returns when the key replaced by a successor that signs
+// from activateAt stops signing and leaves the DNSKEY RRset.
+func predecessorTimes(p config.KASPPolicy, role string, activateAt int64) (retireAt, removeAt int64) {
+	if role == KeyRoleKSK || p.ZSKRollover == RolloverDoubleSignature {
+		lead, _ := rolloverTiming(p, role)
+		return activateAt + lead, activateAt + lead
+	}
+	return activateAt, activateAt + int64(p.PropagationDelaySec+p.MaxZoneTTLSec+p.RetireSafetySec)
+}
+
+func keyRole(key *types.StoredKey) string {
+	if isKSK(key) {
+		return KeyRoleKSK
+	}
+	return KeyRoleZSK
+}
+
+func roleLifetime(p config.KASPPolicy, role string) int64 {
+	if role == KeyRoleKSK {
+		return int64(p.KSKLifetimeSec)
+	}
+	return int64(p.ZSKLifetimeSec)
+}
+
+// zoneKeys returns the stored keys of zone sorted by activation.
+func zoneKeys(zone string) ([]kaspKey, error) {
+	table, err := cachedDNSSECKeyTable()
+	if err != nil {
+		return nil, err
+	}
+	zone = strings.TrimSuffix(dns.Fqdn(zone), ".")
+	var out []kaspKey
+	for id, key := range table {
+		if strings.EqualFold(key.Zone, zone) {
+			out = append(out, kaspKey{id: id, key: key})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].key, out[j].key
+		if a.ActivateAt != b.ActivateAt {
+			return a.ActivateAt < b.ActivateAt
+		}
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return out[i].id < out[j].id
+	})
+	return out, nil
+}
+
+// inServiceKeys returns the keys of role and algorithm that are not being
+// replaced: not revoked, removed or given a retire time.
+func inServiceKeys(keys []kaspKey, role, algorithm string) []kaspKey {
+	var out []kaspKey
+	for _, k := range keys {
+		if keyRole(&k.key) != role || k.key.Algorithm != algorithm {
+			continue
+		}
+		if k.key.Revoke || k.key.State == KeyStateRemoved || k.key.RetireAt != 0 {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// kaspAlgorithmConflict refuses a zone whose keys are all of another
+// algorithm: switching needs an algorithm rollover, not fresh keys.
+func kaspAlgorithmConflict(zone string, keys []kaspKey, algorithm string) error {
+	var others []string
+	for _, k := range keys {
+		if k.key.Revoke || k.key.State == KeyStateRemoved {
+			continue
+		}
+		if k.key.Algorithm == algorithm {
+			return nil
+		}
+		if !slices.Contains(others, k.key.Algorithm) {
+			others = append(others, k.key.Algorithm)
+		}
+	}
+	if len(others) == 0 {
+		return nil
+	}
+	sort.Strings(others)
+	return fmt.Errorf("zone %s is signed with %s, not the policy algorithm %s", zone, strings.Join(others, ", "), algorithm)
+}
+
+// KASPStep brings the keys of zone in line with policy p at now: it generates
+// the first KSK and ZSK, generates a successor once a key nears the end of
+// its lifetime, sets the retire and remove times of the key it replaces and
+// moves every key to the state its timestamps give. When the parent DS is
+// checked, a KSK is only replaced once the parent has published the DS of
+// its successor for the TTL of the DS RRset. An offline-KSK zone only
+// changes state: its ZSKs come from PregenerateZSKs. Only the zone's owner
+// should act on time; other nodes pass the delay after which they take over
+// overdue rollovers. The first keys of a zone are only generated without a
+// delay. During an algorithm rollover the keys are not rolled; the step takes
+// the algorithm rollover on instead.
+func KASPStep(zone string, p config.KASPPolicy, now, delay int64) (KASPResult, error) {
+	res := KASPResult{Changed: map[string]types.StoredKey{}}
+	p, err := NormalizeKASPPolicy(p)
+	if err != nil {
+		return res, err
+	}
+	zone = strings.TrimSuffix(dns.Fqdn(zone), ".")
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return res, err
+	}
+	algorithm, rollover, err := kaspAlgorithm(zone, p, now)
+	if err != nil {
+		return res, err
+	}
+	p.Algorithm = algorithm
+	if err := kaspAlgorithmConflict(zone, keys, p.Algorithm); err != nil {
+		return res, err
+	}
+
+	if rollover != nil {
+		if err := algorithmRolloverStep(zone, p, *rollover, now, delay, &res); err != nil {
+			return res, err
+		}
+	} else if !OfflineKSK(zone) {
+		// An offline-KSK zone rolls to the ZSKs generated for its KSR only.
+		for _, role := range []string{KeyRoleKSK, KeyRoleZSK} {
+			if err := kaspRoll(zone, role, p, keys, now, delay, &res); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	if keys, err = zoneKeys(zone); err != nil {
+		return res, err
+	}
+	for _, k := range keys {
+		if state := keyStateAt(&k.key, now); state != k.key.State {
+			k.key.State = state
+			if err := saveStoredKey(k.id, &k.key); err != nil {
+				return res, err
+			}
+			res.Changed[k.id] = k.key
+		}
+	}
+	return res, nil
+}
+
+func kaspRoll(zone, role string, p config.KASPPolicy, keys []kaspKey, now, delay int64, res *KASPResult) error {
+	live := inServiceKeys(keys, role, p.Algorithm)
+	generated := false
+	generate := func(publishAt, activateAt int64) (kaspKey, error) {
+		id, key, err := GenerateRolloverKey(zone, role, p.Algorithm, publishAt, activateAt)
+		if err != nil {
+			return kaspKey{}, fmt.Errorf("generate %s for %s: %w", role, zone, err)
+		}
+		res.Changed[id] = *key
+		res.Decisions = append(res.Decisions, fmt.Sprintf("generated %s %s (tag %d) publishing at %d, active at %d", role, id, key.KeyTag, publishAt, activateAt))
+		generated = true
+		return kaspKey{id: id, key: *key}, nil
+	}
+
+	if len(live) == 0 {
+		if delay > 0 {
+			return nil
+		}
+		_, err := generate(now, now)
+		return err
+	}
+	newest := live[len(live)-1]
+	if len(live) == 1 {
+		lifetime := roleLifetime(p, role)
+		if lifetime <= 0 {
+			return nil
+		}
+		lead, activateAfter := rolloverTiming(p, role)
+		if now < newest.key.ActivateAt+lifetime-lead+delay {
+			return nil
+		}
+		successor, err := generate(now, now+activateAfter)
+		if err != nil {
+			return err
+		}
+		live = append(live, successor)
+		newest = successor
+	}
+	var retireAt, removeAt int64
+	if role == KeyRoleKSK && ParentDSChecked() {
+		// The old KSK goes once validators can only hold a DS RRset with
+		// the new DS in it.
+		settled := ParentDSSettledAt(&newest.key, int64(p.ParentDSTTLSec))
+		if settled == 0 || now < settled+delay {
+			return nil
+		}
+		retireAt, removeAt = settled, settled
+	} else {
+		// A successor generated elsewhere retires its predecessors there too.
+		if !generated && now < newest.key.CreatedAt+delay {
+			return nil
+		}
+		retireAt, removeAt = predecessorTimes(p, role, newest.key.ActivateAt)
+	}
+	for _, old := range live[:len(live)-1] {
+		old.key.RetireAt = retireAt
+		old.key.RemoveAt = removeAt
+		old.key.State = keyStateAt(&old.key, now)
+		if err := saveStoredKey(old.id, &old.key); err != nil {
+			return err
+		}
+		res.Changed[old.id] = old.key
+		res.Decisions = append(res.Decisions, fmt.Sprintf("retiring %s %s at %d, removing at %d", role, old.id, retireAt, removeAt))
+	}
+	return nil
+}
+
+// ZoneKASPTimeline returns the keys of zone and the events ahead under policy
+// p: the stored times of its keys and, for a key due to be rolled, the
+// planned successor and retirement. When the keys are of another algorithm
+// than p nothing is planned and the timeline comes with the reason. An
+// offline-KSK zone plans nothing either: its rollovers are the keys
+// generated for its KSR. Neither does a zone during an algorithm rollover,
+// whose stored times are its plan.
+func ZoneKASPTimeline(zone string, p config.KASPPolicy, now int64) (KASPTimeline, error) {
+	out := KASPTimeline{Keys: []KeyStatus{}, Events: []KeyEvent{}}
+	p, err := NormalizeKASPPolicy(p)
+	if err != nil {
+		return out, err
+	}
+	zone = strings.TrimSuffix(dns.Fqdn(zone), ".")
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return out, err
+	}
+	algorithm, rollover, err := kaspAlgorithm(zone, p, now)
+	if err != nil {
+		return out, err
+	}
+	p.Algorithm = algorithm
+
+	add := func(at int64, event string, k *kaspKey, role string, planned bool) {
+		if at == 0 || (!planned && at <= now) {
+			return
+		}
+		ev := KeyEvent{At: at, Event: event, Role: role, Algorithm: p.Algorithm, Planned: planned}
+		if k != nil {
+			ev.Algorithm = k.key.Algorithm
+			ev.KeyID = k.id
+			ev.KeyTag = DNSKEYKeyTag(&k.key)
+		}
+		out.Events = append(out.Events, ev)
+	}
+	for i := range keys {
+		k := &keys[i]
+		role := keyRole(&k.key)
+		out.Keys = append(out.Keys, KeyStatus{
+			KeyID:      k.id,
+			Role:       role,
+			Algorithm:  k.key.Algorithm,
+			KeyTag:     DNSKEYKeyTag(&k.key),
+			State:      keyStateAt(&k.key, now),
+			PublishAt:  k.key.PublishAt,
+			ActivateAt: k.key.ActivateAt,
+			RetireAt:   k.key.RetireAt,
+			RemoveAt:   k.key.RemoveAt,
+		})
+		if k.key.State == KeyStateRemoved {
+			continue
+		}
+		add(k.key.PublishAt, KeyEventPublish, k, role, false)
+		add(k.key.ActivateAt, KeyEventActivate, k, role, false)
+		add(k.key.RetireAt, KeyEventRetire, k, role, false)
+		add(k.key.RemoveAt, KeyEventRemove, k, role, false)
+	}
+
+	conflict := kaspAlgorithmConflict(zone, keys, p.Algorithm)
+	if conflict == nil && rollover == nil && !OfflineKSK(zone) {
+		for _, role := range []string{KeyRoleKSK, KeyRoleZSK} {
+			live := inServiceKeys(keys, role, p.Algorithm)
+			lead, activateAfter := rolloverTiming(p, role)
+			// With the parent checked, the old KSK leaves when the new DS
+			// has settled there, which cannot be planned ahead.
+			waitsForParent := role == KeyRoleKSK && ParentDSChecked()
+			switch {
+			case len(live) == 0:
+				add(now, KeyEventGenerate, nil, role, true)
+				add(now, KeyEventActivate, nil, role, true)
+			case len(live) == 1 && roleLifetime(p, role) > 0:
+				current := live[0]
+				at := max(now, current.key.ActivateAt+roleLifetime(p, role)-lead)
+				add(at, KeyEventGenerate, nil, role, true)
+				add(at, KeyEventPublish, nil, role, true)
+				add(at+activateAfter, KeyEventActivate, nil, role, true)
+				if !waitsForParent {
+					retireAt, removeAt := predecessorTimes(p, role, at+activateAfter)
+					add(retireAt, KeyEventRetire, &current, role, true)
+					add(removeAt, KeyEventRemove, &current, role, true)
+				}
+			case len(live) > 1 && waitsForParent:
+				successor := live[len(live)-1]
+				if settled := ParentDSSettledAt(&successor.key, int64(p.ParentDSTTLSec)); settled != 0 {
+					for i := range live[:len(live)-1] {
+						add(max(now, settled), KeyEventRetire, &live[i], role, true)
+						add(max(now, settled), KeyEventRemove, &live[i], role, true)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(out.Events, func(i, j int) bool { return out.Events[i].At < out.Events[j].At })
+	return out, conflict
+}
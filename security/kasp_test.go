@@ -0,0 +1,254 @@
+package security
+
+import (
+	"testing"
+
+	"go53/config"
+	"go53/storage"
+)
+
+func setupKASPTest(t *testing.T) {
+	t.Helper()
+	storage.Backend = &storage.MockStorage{Zones: map[string][]byte{}, Tables: map[string]map[string][]byte{}}
+	if err := storage.Backend.Init(); err != nil {
+		t.Fatalf("storage init: %v", err)
+	}
+	if err := InitDNSSECKeyCache(); err != nil {
+		t.Fatalf("InitDNSSECKeyCache: %v", err)
+	}
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+}
+
+func testKASPPolicy() config.KASPPolicy {
+	return config.KASPPolicy{
+		Algorithm:                 "ECDSAP256SHA256",
+		ZSKLifetimeSec:            1000,
+		DNSKEYTTLSec:              20,
+		MaxZoneTTLSec:             30,
+		PropagationDelaySec:       10,
+		PublishSafetySec:          5,
+		RetireSafetySec:           5,
+		ParentDSTTLSec:            40,
+		ParentPropagationDelaySec: 10,
+	}
+}
+
+func kaspKeysByRole(t *testing.T, zone, role string) []kaspKey {
+	t.Helper()
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		t.Fatalf("zoneKeys: %v", err)
+	}
+	var out []kaspKey
+	for _, k := range keys {
+		if keyRole(&k.key) == role {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func TestKASPStepGeneratesInitialKeys(t *testing.T) {
+	setupKASPTest(t)
+	now := int64(100000)
+
+	res, err := KASPStep("kasp.test.", testKASPPolicy(), now, 0)
+	if err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	if len(res.Decisions) != 2 || len(res.Changed) != 2 {
+		t.Fatalf("result = %+v, want a generated KSK and ZSK", res)
+	}
+	for _, role := range []string{KeyRoleKSK, KeyRoleZSK} {
+		keys := kaspKeysByRole(t, "kasp.test", role)
+		if len(keys) != 1 || keys[0].key.ActivateAt != now || keys[0].key.State != KeyStateActive {
+			t.Fatalf("%s keys = %+v", role, keys)
+		}
+	}
+
+	res, err = KASPStep("kasp.test.", testKASPPolicy(), now+1, 0)
+	if err != nil || len(res.Changed) != 0 {
+		t.Fatalf("second step changed %+v, err %v", res.Changed, err)
+	}
+}
+
+func TestKASPStepRollsZSKWithPrePublish(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	now := int64(100000)
+	if _, err := KASPStep("roll.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+
+	// The successor is published 35s (propagation + DNSKEY TTL + safety)
+	// before the current ZSK reaches the end of its lifetime.
+	due := now + 1000 - 35
+	if res, _ := KASPStep("roll.test", p, due-1, 0); len(res.Decisions) != 0 {
+		t.Fatalf("rolled before due: %v", res.Decisions)
+	}
+	res, err := KASPStep("roll.test", p, due, 0)
+	if err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	if len(res.Decisions) != 2 {
+		t.Fatalf("decisions = %v", res.Decisions)
+	}
+	zsks := kaspKeysByRole(t, "roll.test", KeyRoleZSK)
+	if len(zsks) != 2 {
+		t.Fatalf("zsks = %+v", zsks)
+	}
+	old, successor := zsks[0].key, zsks[1].key
+	if successor.PublishAt != due || successor.ActivateAt != due+35 || successor.State != KeyStatePublished {
+		t.Fatalf("successor = %+v", successor)
+	}
+	if old.RetireAt != due+35 || old.RemoveAt != due+35+45 || old.State != KeyStateActive {
+		t.Fatalf("predecessor = %+v", old)
+	}
+
+	if _, err := KASPStep("roll.test", p, due+35+45, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	zsks = kaspKeysByRole(t, "roll.test", KeyRoleZSK)
+	if zsks[0].key.State != KeyStateRemoved || zsks[1].key.State != KeyStateActive {
+		t.Fatalf("states after removal = %s, %s", zsks[0].key.State, zsks[1].key.State)
+	}
+	if ksks := kaspKeysByRole(t, "roll.test", KeyRoleKSK); len(ksks) != 1 {
+		t.Fatalf("KSK without a lifetime rolled: %+v", ksks)
+	}
+}
+
+func TestKASPStepDoubleSignatureZSK(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	p.ZSKRollover = RolloverDoubleSignature
+	now := int64(100000)
+	if _, err := KASPStep("double.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+
+	// Both keys sign until the largest TTL has passed.
+	due := now + 1000 - 45
+	if _, err := KASPStep("double.test", p, due, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	zsks := kaspKeysByRole(t, "double.test", KeyRoleZSK)
+	if len(zsks) != 2 {
+		t.Fatalf("zsks = %+v", zsks)
+	}
+	if zsks[1].key.ActivateAt != due || zsks[1].key.State != KeyStateActive {
+		t.Fatalf("successor = %+v", zsks[1].key)
+	}
+	if zsks[0].key.RetireAt != due+45 || zsks[0].key.RemoveAt != due+45 {
+		t.Fatalf("predecessor = %+v", zsks[0].key)
+	}
+}
+
+func TestKASPStepDelayedTakeover(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	now := int64(100000)
+
+	if res, _ := KASPStep("delay.test", p, now, 60); len(res.Changed) != 0 {
+		t.Fatalf("non-owner generated first keys: %+v", res.Changed)
+	}
+	if _, err := KASPStep("delay.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+
+	due := now + 1000 - 35
+	if res, _ := KASPStep("delay.test", p, due+59, 60); len(res.Decisions) != 0 {
+		t.Fatalf("took over before the delay: %v", res.Decisions)
+	}
+	if res, _ := KASPStep("delay.test", p, due+60, 60); len(res.Decisions) != 2 {
+		t.Fatalf("did not take over an overdue rollover: %v", res.Decisions)
+	}
+}
+
+func TestKASPStepRefusesOtherAlgorithm(t *testing.T) {
+	setupKASPTest(t)
+	now := int64(100000)
+	if _, _, err := GenerateRolloverKey("algo.test", "zsk", "ED25519", now, now); err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	if _, err := KASPStep("algo.test", testKASPPolicy(), now, 0); err == nil {
+		t.Fatalf("KASPStep accepted keys of another algorithm")
+	}
+	timeline, err := ZoneKASPTimeline("algo.test", testKASPPolicy(), now)
+	if err == nil || len(timeline.Keys) != 1 {
+		t.Fatalf("timeline = %+v, err %v", timeline, err)
+	}
+	for _, ev := range timeline.Events {
+		if ev.Planned {
+			t.Fatalf("planned event despite conflict: %+v", ev)
+		}
+	}
+}
+
+func TestNormalizeKASPPolicy(t *testing.T) {
+	p, err := NormalizeKASPPolicy(config.KASPPolicy{})
+	if err != nil {
+		t.Fatalf("NormalizeKASPPolicy: %v", err)
+	}
+	if p.Algorithm != "ECDSAP256SHA256" || p.KSKRollover != RolloverDoubleSignature || p.ZSKRollover != RolloverPrePublish {
+		t.Fatalf("defaults = %+v", p)
+	}
+
+	bad := []config.KASPPolicy{
+		{Algorithm: "RSAMD5"},
+		{KSKRollover: RolloverPrePublish},
+		{ZSKRollover: "instant"},
+		{DNSKEYTTLSec: -1},
+		{ZSKLifetimeSec: 30, DNSKEYTTLSec: 20, PropagationDelaySec: 10},
+	}
+	for _, p := range bad {
+		if _, err := NormalizeKASPPolicy(p); err == nil {
+			t.Fatalf("NormalizeKASPPolicy(%+v) accepted", p)
+		}
+	}
+}
+
+func TestZoneKASPTimelinePlansRollover(t *testing.T) {
+	setupKASPTest(t)
+	p := testKASPPolicy()
+	now := int64(100000)
+
+	timeline, err := ZoneKASPTimeline("plan.test", p, now)
+	if err != nil {
+		t.Fatalf("ZoneKASPTimeline: %v", err)
+	}
+	if len(timeline.Keys) != 0 || len(timeline.Events) != 4 {
+		t.Fatalf("empty zone timeline = %+v", timeline)
+	}
+
+	if _, err := KASPStep("plan.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	timeline, err = ZoneKASPTimeline("plan.test", p, now+1)
+	if err != nil {
+		t.Fatalf("ZoneKASPTimeline: %v", err)
+	}
+	if len(timeline.Keys) != 2 {
+		t.Fatalf("keys = %+v", timeline.Keys)
+	}
+	due := now + 1000 - 35
+	want := []struct {
+		at    int64
+		event string
+	}{
+		{due, KeyEventGenerate},
+		{due, KeyEventPublish},
+		{due + 35, KeyEventActivate},
+		{due + 35, KeyEventRetire},
+		{due + 35 + 45, KeyEventRemove},
+	}
+	if len(timeline.Events) != len(want) {
+		t.Fatalf("events = %+v", timeline.Events)
+	}
+	for i, w := range want {
+		ev := timeline.Events[i]
+		if ev.At != w.at || ev.Event != w.event || ev.Role != KeyRoleZSK || !ev.Planned {
+			t.Fatalf("event %d = %+v, want %s at %d", i, ev, w.event, w.at)
+		}
+	}
+}
@@ -81,6 +81,8 @@ func InitDNSSECKeyCache() error {
 	dnssecKeyCache.backendID = currentStorageBackendID()
 	dnssecKeyCache.initialized = true
 	dnssecKeyCache.Unlock()
+	resetSKRCache()
+	resetAlgorithmRolloverCache()
 	return nil
 }
 
@@ -140,41 +142,28 @@ func uncacheStoredKey(keyID string) {
 
 func GenerateAndStoreAllKeys(zone string) error {
 	now := time.Now().Unix()
+	offline := OfflineKSK(zone)
 	for _, algo := range supportedAlgos {
 		for _, flag := range algo.Flags {
-			priv, pub, err := generateKeyPair(algo.Algorithm)
-			if err != nil {
-				return fmt.Errorf("generate %s key: %w", algo.Name, err)
+			if offline && flag&dns.SEP != 0 {
+				continue
 			}
-
 			log.Printf("Generating key for zone=%s, flag=%d, algorithm=%s", zone, flag, algo.Name)
 			log.Printf("algo=%s → algonum=%d", algo.Name, AlgorithmNumberFromName(algo.Name))
 
-			pubBytes, err := PublicKeyToDNS(pub, algo.Algorithm)
-			if err != nil {
-				return fmt.Errorf("convert pubkey: %w", err)
-			}
-
-			keyTag := ComputeKeyTag(flag, 3, algo.Algorithm, pubBytes)
 			keyID := fmt.Sprintf("%s_%s_%s", flagName(flag), zone, algo.Name)
-
-			pemPriv, err := EncodePrivateKeyPEM(priv)
-			if err != nil {
-				return fmt.Errorf("PEM encode failed: %w", err)
-			}
-
 			stored := types.StoredKey{
-				KeyTag:     keyTag,
 				Zone:       zone,
 				Algorithm:  algo.Name,
 				Flags:      flag,
-				PrivatePEM: pemPriv,
-				PublicKey:  base64.StdEncoding.EncodeToString(pubBytes),
 				State:      KeyStateActive,
 				CreatedAt:  now,
 				PublishAt:  now,
 				ActivateAt: now,
 			}
+			if err := newKeyMaterial(&stored, algo.Algorithm); err != nil {
+				return fmt.Errorf("generate %s key: %w", algo.Name, err)
+			}
 
 			if err := saveStoredKey(keyID, &stored); err != nil {
 				return fmt.Errorf("store key: %w", err)
@@ -215,7 +204,23 @@ func GetDSWithDigestTypes(zone string, digestTypes []uint8) ([]*dns.DS, error) {
 	return dsList, nil
 }
 
+// GetCDS returns the CDS RRset of zone, empty once the parent holds the DS
+// of every KSK. An offline-KSK zone has the CDS RRset of its SKR.
 func GetCDS(zone string) ([]*dns.CDS, error) {
+	if OfflineKSK(zone) {
+		rrs, err := SKRRRset(zone, dns.TypeCDS, time.Now().Unix())
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*dns.CDS, 0, len(rrs))
+		for _, rr := range rrs {
+			out = append(out, rr.(*dns.CDS))
+		}
+		return out, nil
+	}
+	if CDSWithdrawn(zone, time.Now().Unix()) {
+		return nil, nil
+	}
 	dsList, err := GetDSWithDigestTypes(zone, []uint8{dns.SHA256})
 	if err != nil {
 		return nil, err
@@ -227,7 +232,23 @@ func GetCDS(zone string) ([]*dns.CDS, error) {
 	return cdsList, nil
 }
 
+// GetCDNSKEY returns the CDNSKEY RRset of zone, empty once the parent holds
+// the DS of every KSK. An offline-KSK zone has the CDNSKEY RRset of its SKR.
 func GetCDNSKEY(zone string) ([]*dns.CDNSKEY, error) {
+	if OfflineKSK(zone) {
+		rrs, err := SKRRRset(zone, dns.TypeCDNSKEY, time.Now().Unix())
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*dns.CDNSKEY, 0, len(rrs))
+		for _, rr := range rrs {
+			out = append(out, rr.(*dns.CDNSKEY))
+		}
+		return out, nil
+	}
+	if CDSWithdrawn(zone, time.Now().Unix()) {
+		return nil, nil
+	}
 	dnskeys, err := ParentDSDNSKEYs(zone, time.Now().Unix())
 	if err != nil {
 		return nil, err
@@ -281,16 +302,29 @@ func ParentDSDNSKEYs(zone string, now int64) ([]*dns.DNSKEY, error) {
 		return nil, fmt.Errorf("FQDN sanitize check failed: %w", err)
 	}
 
-	keys, err := LoadPublishedKeysForZone(sz, now)
-	if err != nil {
-		return nil, err
-	}
 	var out []*dns.DNSKEY
-	for _, key := range keys {
-		if !isKSK(key) || !keySignsAt(key, now) {
-			continue
+	if OfflineKSK(sz) {
+		// The KSKs of an offline-KSK zone are those its SKR signs with.
+		b, err := currentSKRBundle(sz, now)
+		if err != nil {
+			return nil, err
+		}
+		ksks, _ := b.keys()
+		for _, key := range ksks {
+			out = append(out, dns.Copy(key).(*dns.DNSKEY))
+		}
+	} else {
+		keys, err := LoadPublishedKeysForZone(sz, now)
+		if err != nil {
+			return nil, err
+		}
+		excluded := dsExcludedAlgorithm(sz, now)
+		for _, key := range keys {
+			if !isKSK(key) || !keySignsAt(key, now) || key.Algorithm == excluded {
+				continue
+			}
+			out = append(out, storedKeyToDNSKEY(sz, key, 3600))
 		}
-		out = append(out, storedKeyToDNSKEY(sz, key, 3600))
 	}
 	if len(out) == 0 {
 		return nil, fmt.Errorf("no active KSK DNSKEY found for zone %s", sz)
@@ -442,6 +476,9 @@ func roleFlags(role string) (uint16, error) {
 }
 
 func SavePrivateKeyToStorage(zone, keyID, algorithmName string, priv crypto.PrivateKey, pub crypto.PublicKey, flags uint16) error {
+	if err := refuseOfflineKSK(zone, flags); err != nil {
+		return err
+	}
 	pemPriv, err := EncodePrivateKeyPEM(priv)
 	if err != nil {
 		return fmt.Errorf("encode private key: %w", err)
@@ -488,6 +525,9 @@ func GenerateRolloverKey(zone, role, algorithmName string, publishAt, activateAt
 	if err != nil {
 		return "", nil, err
 	}
+	if err := refuseOfflineKSK(zone, flags); err != nil {
+		return "", nil, err
+	}
 	algorithm := AlgorithmNumberFromName(algorithmName)
 	if algorithm == 0 {
 		return "", nil, fmt.Errorf("unsupported algorithm %q", algorithmName)
@@ -500,35 +540,22 @@ func GenerateRolloverKey(zone, role, algorithmName string, publishAt, activateAt
 		activateAt = publishAt
 	}
 
-	priv, pub, err := generateKeyPair(algorithm)
-	if err != nil {
-		return "", nil, err
-	}
-	pubBytes, err := PublicKeyToDNS(pub, algorithm)
-	if err != nil {
-		return "", nil, err
-	}
-	pemPriv, err := EncodePrivateKeyPEM(priv)
-	if err != nil {
-		return "", nil, err
-	}
-	keyTag := ComputeKeyTag(flags, 3, algorithm, pubBytes)
 	state := KeyStatePublished
 	if activateAt <= now {
 		state = KeyStateActive
 	}
 	stored := &types.StoredKey{
-		KeyTag:     keyTag,
 		Zone:       strings.TrimSuffix(dns.Fqdn(zone), "."),
 		Algorithm:  algorithmName,
 		Flags:      flags,
-		PrivatePEM: pemPriv,
-		PublicKey:  base64.StdEncoding.EncodeToString(pubBytes),
 		State:      state,
 		CreatedAt:  now,
 		PublishAt:  publishAt,
 		ActivateAt: activateAt,
 	}
+	if err := newKeyMaterial(stored, algorithm); err != nil {
+		return "", nil, err
+	}
 	keyID := keyIDForStored(stored)
 	data, err := json.Marshal(stored)
 	if err != nil {
@@ -648,6 +675,9 @@ func LoadPrivateKeyFromStorage(keyID string) (crypto.PrivateKey, *types.StoredKe
 		return nil, nil, err
 	}
 	normalizeStoredKey(stored)
+	if stored.Provider != "" {
+		return nil, nil, fmt.Errorf("key %q is held by the %s signer; its private key is not stored", keyID, stored.Provider)
+	}
 
 	block, _ := pem.Decode([]byte(stored.PrivatePEM))
 	if block == nil {
@@ -673,7 +703,11 @@ func LoadPrivateKeyFromStorage(keyID string) (crypto.PrivateKey, *types.StoredKe
 	return privKey, stored, nil
 }
 
+// DeleteStoredKey deletes the key keyID. A key held by a signer provider is
+// destroyed there too; a failure to do so is logged, since the key is gone
+// from go53 either way.
 func DeleteStoredKey(keyID string) error {
+	stored, _ := LoadStoredKey(keyID)
 	if err := storage.Backend.DeleteFromTable(dnssecKeyTable, keyID); err != nil {
 		return err
 	}
@@ -681,6 +715,11 @@ func DeleteStoredKey(keyID string) error {
 		return err
 	}
 	uncacheStoredKey(keyID)
+	if stored != nil {
+		if err := deleteProviderKey(stored); err != nil {
+			log.Printf("[dnssec] key %s deleted, but not its %s key %s: %v", keyID, stored.Provider, stored.KeyRef, err)
+		}
+	}
 	return nil
 }
 
@@ -826,7 +865,9 @@ func keySignsAt(stored *types.StoredKey, now int64) bool {
 func keyStateAt(stored *types.StoredKey, now int64) string {
 	normalizeStoredKey(stored)
 	switch {
-	case stored.RemoveAt != 0 && stored.RemoveAt <= now:
+	case stored.RemoveAt != 0 && stored.RemoveAt <= now && (stored.RetireAt == 0 || stored.RetireAt <= now):
+		// During an algorithm rollover a key signs on after its DNSKEY
+		// has gone.
 		return KeyStateRemoved
 	case stored.Revoke:
 		return KeyStateRevoked
@@ -16,6 +16,9 @@ import (
 	"github.com/miekg/dns"
 )
 
+// PrivateKeyImportFormat names the private-key file format.
+const PrivateKeyImportFormat = "go53-dnssec-private-keys"
+
 type PrivateKeyImportFile struct {
 	Format  string                  `json:"format"`
 	Version int                     `json:"version"`
@@ -45,7 +48,7 @@ func ImportPrivateKeys(data []byte) (PrivateKeyImportResult, error) {
 	if err := json.Unmarshal(data, &in); err != nil {
 		return PrivateKeyImportResult{}, fmt.Errorf("invalid key import JSON: %w", err)
 	}
-	if in.Format != "go53-dnssec-private-keys" || in.Version != 1 {
+	if in.Format != PrivateKeyImportFormat || in.Version != 1 {
 		return PrivateKeyImportResult{}, fmt.Errorf("unsupported key import format")
 	}
 	zone := dns.Fqdn(in.Zone)
@@ -64,20 +67,30 @@ func ImportPrivateKeys(data []byte) (PrivateKeyImportResult, error) {
 	return result, nil
 }
 
-func importPrivateKeyEntry(zone string, entry PrivateKeyImportEntry) (string, error) {
+// importedKey is a decoded entry of a private-key import file.
+type importedKey struct {
+	algorithm string
+	number    uint8
+	flags     uint16
+	keyTag    uint16
+	priv      crypto.PrivateKey
+	pubBytes  []byte
+}
+
+func decodeImportEntry(entry PrivateKeyImportEntry) (importedKey, error) {
 	algorithm := strings.ToUpper(strings.TrimSpace(entry.Algorithm))
 	if algorithm == "" && entry.AlgorithmNumber != 0 {
 		algorithm = algorithmNameFromNumber(entry.AlgorithmNumber)
 	}
 	if algorithm == "" {
-		return "", fmt.Errorf("missing algorithm for source key %q", entry.SourceKeyID)
+		return importedKey{}, fmt.Errorf("missing algorithm for source key %q", entry.SourceKeyID)
 	}
 	algorithmNumber, ok := algorithmNumberByName(algorithm)
 	if !ok {
-		return "", fmt.Errorf("unsupported algorithm %q for source key %q", algorithm, entry.SourceKeyID)
+		return importedKey{}, fmt.Errorf("unsupported algorithm %q for source key %q", algorithm, entry.SourceKeyID)
 	}
 	if entry.AlgorithmNumber != 0 && entry.AlgorithmNumber != algorithmNumber {
-		return "", fmt.Errorf("algorithm mismatch for source key %q", entry.SourceKeyID)
+		return importedKey{}, fmt.Errorf("algorithm mismatch for source key %q", entry.SourceKeyID)
 	}
 	flags := entry.Flags
 	if flags == 0 {
@@ -87,34 +100,45 @@ func importPrivateKeyEntry(zone string, entry PrivateKeyImportEntry) (string, er
 		case "zsk":
 			flags = 256
 		default:
-			return "", fmt.Errorf("missing flags for source key %q", entry.SourceKeyID)
+			return importedKey{}, fmt.Errorf("missing flags for source key %q", entry.SourceKeyID)
 		}
 	}
 
 	priv, pub, err := privateKeyFromImport(entry.PrivateKey, algorithmNumber)
 	if err != nil {
-		return "", fmt.Errorf("source key %q: %w", entry.SourceKeyID, err)
+		return importedKey{}, fmt.Errorf("source key %q: %w", entry.SourceKeyID, err)
 	}
 	pubBytes, err := importedPublicKeyToDNS(pub, algorithmNumber)
 	if err != nil {
-		return "", err
+		return importedKey{}, err
 	}
 	keyTag := ComputeKeyTag(flags, 3, algorithmNumber, pubBytes)
 	if entry.KeyTag != 0 && entry.KeyTag != keyTag {
-		return "", fmt.Errorf("source key %q keytag mismatch: import=%d computed=%d", entry.SourceKeyID, entry.KeyTag, keyTag)
+		return importedKey{}, fmt.Errorf("source key %q keytag mismatch: import=%d computed=%d", entry.SourceKeyID, entry.KeyTag, keyTag)
+	}
+	return importedKey{algorithm: algorithm, number: algorithmNumber, flags: flags, keyTag: keyTag, priv: priv, pubBytes: pubBytes}, nil
+}
+
+func importPrivateKeyEntry(zone string, entry PrivateKeyImportEntry) (string, error) {
+	key, err := decodeImportEntry(entry)
+	if err != nil {
+		return "", err
+	}
+	if err := refuseOfflineKSK(zone, key.flags); err != nil {
+		return "", err
 	}
-	pemPriv, err := EncodePrivateKeyPEM(priv)
+	pemPriv, err := EncodePrivateKeyPEM(key.priv)
 	if err != nil {
 		return "", err
 	}
 	now := time.Now().Unix()
 	stored := &types.StoredKey{
-		KeyTag:     keyTag,
+		KeyTag:     key.keyTag,
 		Zone:       strings.TrimSuffix(zone, "."),
-		Algorithm:  algorithm,
-		Flags:      flags,
+		Algorithm:  key.algorithm,
+		Flags:      key.flags,
 		PrivatePEM: pemPriv,
-		PublicKey:  base64.StdEncoding.EncodeToString(pubBytes),
+		PublicKey:  base64.StdEncoding.EncodeToString(key.pubBytes),
 		State:      KeyStateActive,
 		CreatedAt:  now,
 		PublishAt:  now,
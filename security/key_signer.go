@@ -0,0 +1,157 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: key_signer.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"go53/security/signer"
+	"go53/types"
+)
+
+// signerState holds the provider new keys are generated in and the signers
+// of its keys handed out so far, by key reference.
+var signerState = struct {
+	sync.RWMutex
+	provider signer.Provider
+	signers  map[string]crypto.Signer
+}{}
+
+// SetSignerProvider makes p generate and hold the private keys of new DNSSEC
+// keys; nil keeps them in storage. Keys generated earlier stay where they
+// are. It returns the provider p replaces.
+func SetSignerProvider(p signer.Provider) signer.Provider {
+	signerState.Lock()
+	defer signerState.Unlock()
+	old := signerState.provider
+	signerState.provider = p
+	signerState.signers = nil
+	return old
+}
+
+func currentSignerProvider() signer.Provider {
+	signerState.RLock()
+	defer signerState.RUnlock()
+	return signerState.provider
+}
+
+// newKeyMaterial generates a key pair of algorithm for key, whose zone and
+// flags are set, and fills in its public key and key tag. With a signer
+// provider the pair stays in the provider and key holds its reference;
+// otherwise the private key is stored PEM-encoded in key.
+func newKeyMaterial(key *types.StoredKey, algorithm uint8) error {
+	var pub crypto.PublicKey
+	if p := currentSignerProvider(); p != nil {
+		ref, public, err := p.GenerateKey(algorithm, fmt.Sprintf("%s %s", key.Zone, flagName(key.Flags)))
+		if err != nil {
+			return fmt.Errorf("%s signer: %w", p.Name(), err)
+		}
+		key.Provider, key.KeyRef, key.PrivatePEM = p.Name(), ref, ""
+		pub = public
+	} else {
+		priv, public, err := generateKeyPair(algorithm)
+		if err != nil {
+			return err
+		}
+		pemPriv, err := EncodePrivateKeyPEM(priv)
+		if err != nil {
+			return fmt.Errorf("PEM encode failed: %w", err)
+		}
+		key.Provider, key.KeyRef, key.PrivatePEM = "", "", pemPriv
+		pub = public
+	}
+	pubBytes, err := PublicKeyToDNS(pub, algorithm)
+	if err != nil {
+		return fmt.Errorf("convert pubkey: %w", err)
+	}
+	key.PublicKey = base64.StdEncoding.EncodeToString(pubBytes)
+	key.KeyTag = ComputeKeyTag(key.Flags, 3, algorithm, pubBytes)
+	return nil
+}
+
+// LoadSigner returns the signer of the stored key keyID: its stored private
+// key, or the key in the signer provider that holds it, once its public key
+// matches the stored one.
+func LoadSigner(keyID string) (crypto.Signer, *types.StoredKey, error) {
+	stored, err := LoadStoredKey(keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored.Provider == "" {
+		priv, stored, err := LoadPrivateKeyFromStorage(keyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("key %q does not implement crypto.Signer", keyID)
+		}
+		return s, stored, nil
+	}
+
+	signerState.RLock()
+	p, s := signerState.provider, signerState.signers[stored.KeyRef]
+	signerState.RUnlock()
+	if p == nil || p.Name() != stored.Provider {
+		return nil, nil, fmt.Errorf("key %q is held by the %s signer, which this node does not use", keyID, stored.Provider)
+	}
+	if s != nil {
+		return s, stored, nil
+	}
+	algorithm := AlgorithmNumberFromName(stored.Algorithm)
+	s, err = p.Signer(stored.KeyRef, algorithm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s signer: key %q: %w", p.Name(), keyID, err)
+	}
+	// The provider must hold the key the zone publishes; another key would
+	// make every signature fail to validate.
+	pubBytes, err := PublicKeyToDNS(s.Public(), algorithm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s signer: key %q: %w", p.Name(), keyID, err)
+	}
+	if base64.StdEncoding.EncodeToString(pubBytes) != stored.PublicKey {
+		return nil, nil, fmt.Errorf("%s signer: key %q: the provider's public key differs from the stored one", p.Name(), keyID)
+	}
+	signerState.Lock()
+	if signerState.provider == p {
+		if signerState.signers == nil {
+			signerState.signers = map[string]crypto.Signer{}
+		}
+		signerState.signers[stored.KeyRef] = s
+	}
+	signerState.Unlock()
+	return s, stored, nil
+}
+
+// deleteProviderKey destroys the private key of stored in its signer
+// provider. Keys of another provider than this node's are left alone.
+func deleteProviderKey(stored *types.StoredKey) error {
+	if stored.Provider == "" {
+		return nil
+	}
+	signerState.Lock()
+	p := signerState.provider
+	delete(signerState.signers, stored.KeyRef)
+	signerState.Unlock()
+	if p == nil || p.Name() != stored.Provider {
+		return fmt.Errorf("the %s signer holding the key is not in use on this node", stored.Provider)
+	}
+	return p.DeleteKey(stored.KeyRef)
+}
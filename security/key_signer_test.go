@@ -0,0 +1,132 @@
+package security
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/security/signer"
+)
+
+// testProvider keeps its keys in memory, standing in for an HSM.
+type testProvider struct {
+	keys map[string]crypto.Signer
+}
+
+var _ signer.Provider = (*testProvider)(nil)
+
+func (p *testProvider) Name() string { return "test" }
+func (p *testProvider) Close() error { return nil }
+
+func (p *testProvider) GenerateKey(algorithm uint8, label string) (string, crypto.PublicKey, error) {
+	priv, pub, err := generateKeyPair(algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+	ref := fmt.Sprintf("%s/%d", label, len(p.keys))
+	p.keys[ref] = priv.(crypto.Signer)
+	return ref, pub, nil
+}
+
+func (p *testProvider) Signer(ref string, algorithm uint8) (crypto.Signer, error) {
+	s, ok := p.keys[ref]
+	if !ok {
+		return nil, fmt.Errorf("no key %q", ref)
+	}
+	return s, nil
+}
+
+func (p *testProvider) DeleteKey(ref string) error {
+	delete(p.keys, ref)
+	return nil
+}
+
+func useTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	p := &testProvider{keys: map[string]crypto.Signer{}}
+	old := SetSignerProvider(p)
+	t.Cleanup(func() { SetSignerProvider(old) })
+	return p
+}
+
+func TestSignerProviderKeepsPrivateKeysOutOfStorage(t *testing.T) {
+	setupKASPTest(t)
+	p := useTestProvider(t)
+
+	keyID, stored, err := GenerateRolloverKey("hsm.test", KeyRoleZSK, "ECDSAP256SHA256", 0, 0)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	if stored.PrivatePEM != "" || stored.Provider != "test" || p.keys[stored.KeyRef] == nil {
+		t.Fatalf("stored key = %+v, want only a reference to the provider's key", stored)
+	}
+	if _, _, err := LoadPrivateKeyFromStorage(keyID); err == nil {
+		t.Fatal("LoadPrivateKeyFromStorage returned a private key the provider holds")
+	}
+
+	s, loaded, err := LoadSigner(keyID)
+	if err != nil {
+		t.Fatalf("LoadSigner: %v", err)
+	}
+	rrs := []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "www.hsm.test.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: []byte{192, 0, 2, 1}}}
+	sig, err := SignRRSet(rrs, s, loaded.KeyTag, "hsm.test.", dns.ECDSAP256SHA256)
+	if err != nil {
+		t.Fatalf("SignRRSet: %v", err)
+	}
+	if err := sig.Verify(storedKeyToDNSKEY("hsm.test.", loaded, 300), rrs); err != nil {
+		t.Fatalf("signature of the provider key does not verify: %v", err)
+	}
+
+	if err := DeleteStoredKey(keyID); err != nil {
+		t.Fatalf("DeleteStoredKey: %v", err)
+	}
+	if len(p.keys) != 0 {
+		t.Fatalf("provider still holds %d keys after the stored key was deleted", len(p.keys))
+	}
+}
+
+func TestLoadSignerNeedsTheProviderOfTheKey(t *testing.T) {
+	setupKASPTest(t)
+	useTestProvider(t)
+	keyID, _, err := GenerateRolloverKey("hsm.test", KeyRoleKSK, "ED25519", 0, 0)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+
+	SetSignerProvider(nil)
+	if _, _, err := LoadSigner(keyID); err == nil {
+		t.Fatal("LoadSigner succeeded without the provider holding the key")
+	}
+
+	localID, local, err := GenerateRolloverKey("hsm.test", KeyRoleZSK, "ED25519", time.Now().Unix(), 0)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	if local.PrivatePEM == "" || local.Provider != "" {
+		t.Fatalf("key generated without a provider = %+v, want a stored private key", local)
+	}
+	if _, _, err := LoadSigner(localID); err != nil {
+		t.Fatalf("LoadSigner of a stored private key: %v", err)
+	}
+}
+
+func TestLoadSignerRefusesAReplacedProviderKey(t *testing.T) {
+	setupKASPTest(t)
+	p := useTestProvider(t)
+	keyID, stored, err := GenerateRolloverKey("hsm.test", KeyRoleZSK, "ED25519", 0, 0)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	other, _, err := generateKeyPair(dns.ED25519)
+	if err != nil {
+		t.Fatalf("generateKeyPair: %v", err)
+	}
+	p.keys[stored.KeyRef] = other.(crypto.Signer)
+
+	if _, _, err := LoadSigner(keyID); err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Fatalf("LoadSigner with a replaced key = %v", err)
+	}
+}
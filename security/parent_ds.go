@@ -0,0 +1,132 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: parent_ds.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/types"
+)
+
+// ParentDSKey is a published KSK of a zone, one the parent may hold a DS for.
+type ParentDSKey struct {
+	KeyID  string
+	Key    types.StoredKey
+	DNSKEY *dns.DNSKEY
+}
+
+// ParentDSChecked reports whether the parent DS RRset is checked. KSK
+// rollovers then wait for the new DS instead of the policy's timing.
+func ParentDSChecked() bool {
+	return len(config.AppConfig.GetLive().DNSSEC.ParentDS.Resolvers) > 0
+}
+
+// ParentDSKeys returns the KSKs of zone published at now, sorted by key tag.
+func ParentDSKeys(zone string, now int64) ([]ParentDSKey, error) {
+	sz, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return nil, fmt.Errorf("FQDN sanitize check failed: %w", err)
+	}
+	keys, err := LoadPublishedKeysForZone(sz, now)
+	if err != nil {
+		return nil, err
+	}
+	var out []ParentDSKey
+	for id, key := range keys {
+		if !isKSK(key) {
+			continue
+		}
+		out = append(out, ParentDSKey{KeyID: id, Key: *key, DNSKEY: storedKeyToDNSKEY(sz, key, 3600)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := out[i].DNSKEY.KeyTag(), out[j].DNSKEY.KeyTag(); a != b {
+			return a < b
+		}
+		return out[i].KeyID < out[j].KeyID
+	})
+	return out, nil
+}
+
+// DSMatchesDNSKEY reports whether ds is a digest of key, in any digest type.
+func DSMatchesDNSKEY(ds *dns.DS, key *dns.DNSKEY) bool {
+	if ds == nil || key == nil || ds.KeyTag != key.KeyTag() || ds.Algorithm != key.Algorithm {
+		return false
+	}
+	want := key.ToDS(ds.DigestType)
+	return want != nil && strings.EqualFold(want.Digest, ds.Digest)
+}
+
+// SetParentDSSeen records that the parent publishes a DS for keyID since at,
+// in an RRset with ttl. at 0 clears it. The TTL is only taken at the first
+// sighting: a parent that lowers it later does not shorten the wait of
+// validators that cached the RRset before. The key is only saved, and
+// reported changed, when the parent gained or lost its DS.
+func SetParentDSSeen(keyID string, at int64, ttl uint32) (types.StoredKey, bool, error) {
+	stored, err := LoadStoredKey(keyID)
+	if err != nil {
+		return types.StoredKey{}, false, err
+	}
+	if (at == 0) == (stored.ParentDSAt == 0) {
+		return *stored, false, nil
+	}
+	stored.ParentDSAt = at
+	stored.ParentDSTTL = ttl
+	if at == 0 {
+		stored.ParentDSTTL = 0
+	}
+	if err := saveStoredKey(keyID, stored); err != nil {
+		return types.StoredKey{}, false, err
+	}
+	return *stored, true, nil
+}
+
+// ParentDSSettledAt returns when the DS of key has been visible at the
+// parent for the TTL of its RRset, so no validator still holds a DS RRset
+// without it; 0 while the parent has not been seen with it. The wait is the
+// longer of the recorded TTL and ttl, the TTL the policy expects.
+func ParentDSSettledAt(key *types.StoredKey, ttl int64) int64 {
+	if key == nil || key.ParentDSAt == 0 {
+		return 0
+	}
+	return key.ParentDSAt + max(ttl, int64(key.ParentDSTTL))
+}
+
+// CDSWithdrawn reports whether zone stops publishing CDS and CDNSKEY: the
+// parent has been seen with a DS for every published KSK, each of which
+// signs, so there is nothing left to ask of it (RFC 7344 section 4). During
+// an algorithm rollover only the KSKs CDS names count.
+func CDSWithdrawn(zone string, now int64) bool {
+	keys, err := ParentDSKeys(zone, now)
+	if err != nil || len(keys) == 0 {
+		return false
+	}
+	excluded := dsExcludedAlgorithm(zone, now)
+	for i := range keys {
+		if keys[i].Key.Algorithm == excluded {
+			continue
+		}
+		if keys[i].Key.ParentDSAt == 0 || !keySignsAt(&keys[i].Key, now) {
+			return false
+		}
+	}
+	return true
+}
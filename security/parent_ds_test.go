@@ -0,0 +1,124 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"go53/config"
+)
+
+func TestKASPKSKRolloverWaitsForParentDS(t *testing.T) {
+	setupKASPTest(t)
+	config.AppConfig.LiveForTest().DNSSEC.ParentDS.Resolvers = []string{"127.0.0.1:53"}
+	p := testKASPPolicy()
+	p.KSKLifetimeSec = 5000
+	p.ZSKLifetimeSec = 0
+	now := int64(100000)
+	if _, err := KASPStep("parent.test", p, now, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+
+	// A KSK is published 90s before it is due: publication, parent
+	// propagation, DS TTL and safety margins.
+	due := now + 5000 - 90
+	if _, err := KASPStep("parent.test", p, due, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	ksks := kaspKeysByRole(t, "parent.test", KeyRoleKSK)
+	if len(ksks) != 2 || ksks[0].key.RetireAt != 0 {
+		t.Fatalf("ksks after rollover start = %+v", ksks)
+	}
+	if res, _ := KASPStep("parent.test", p, due+1000, 0); len(res.Decisions) != 0 {
+		t.Fatalf("old KSK retired without the new DS at the parent: %v", res.Decisions)
+	}
+
+	seen := due + 1000
+	if _, changed, err := SetParentDSSeen(ksks[1].id, seen, 120); err != nil || !changed {
+		t.Fatalf("SetParentDSSeen changed=%v err=%v", changed, err)
+	}
+	timeline, err := ZoneKASPTimeline("parent.test", p, seen)
+	if err != nil {
+		t.Fatalf("ZoneKASPTimeline: %v", err)
+	}
+	planned := false
+	for _, ev := range timeline.Events {
+		if ev.Planned && ev.Role == KeyRoleKSK && ev.Event == KeyEventRetire && ev.At == seen+120 && ev.KeyID == ksks[0].id {
+			planned = true
+		}
+	}
+	if !planned {
+		t.Fatalf("no planned KSK retirement in %+v", timeline.Events)
+	}
+
+	if res, _ := KASPStep("parent.test", p, seen+119, 0); len(res.Decisions) != 0 {
+		t.Fatalf("old KSK retired before the DS TTL passed: %v", res.Decisions)
+	}
+	if _, err := KASPStep("parent.test", p, seen+120, 0); err != nil {
+		t.Fatalf("KASPStep: %v", err)
+	}
+	ksks = kaspKeysByRole(t, "parent.test", KeyRoleKSK)
+	if old := ksks[0].key; old.RetireAt != seen+120 || old.RemoveAt != seen+120 || old.State != KeyStateRemoved {
+		t.Fatalf("old KSK = %+v", old)
+	}
+}
+
+func TestCDSWithdrawnOnceParentHoldsEveryKSK(t *testing.T) {
+	setupKASPTest(t)
+	now := time.Now().Unix()
+	kskID, _, err := GenerateRolloverKey("cds.test", KeyRoleKSK, "ECDSAP256SHA256", now-10, now-10)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	if CDSWithdrawn("cds.test.", now) {
+		t.Fatalf("CDS withdrawn before the parent holds the DS")
+	}
+	if cds, err := GetCDS("cds.test."); err != nil || len(cds) != 1 {
+		t.Fatalf("GetCDS = %v, %v", cds, err)
+	}
+
+	key, changed, err := SetParentDSSeen(kskID, now-5, 3600)
+	if err != nil || !changed || key.ParentDSAt != now-5 || key.ParentDSTTL != 3600 {
+		t.Fatalf("SetParentDSSeen = %+v, %v, %v", key, changed, err)
+	}
+	if _, changed, _ := SetParentDSSeen(kskID, now, 3600); changed {
+		t.Fatalf("SetParentDSSeen moved the first sighting")
+	}
+	if stored, changed, _ := SetParentDSSeen(kskID, now, 60); changed || stored.ParentDSTTL != 3600 {
+		t.Fatalf("a lower TTL counted as a change: %+v, %v", stored, changed)
+	}
+	if ParentDSSettledAt(&key, 60) != now-5+3600 {
+		t.Fatalf("ParentDSSettledAt = %d", ParentDSSettledAt(&key, 60))
+	}
+	if ParentDSSettledAt(&key, 7200) != now-5+7200 {
+		t.Fatalf("ParentDSSettledAt below the policy TTL = %d", ParentDSSettledAt(&key, 7200))
+	}
+	if !CDSWithdrawn("cds.test.", now) {
+		t.Fatalf("CDS not withdrawn with the DS at the parent")
+	}
+	if cds, _ := GetCDS("cds.test."); len(cds) != 0 {
+		t.Fatalf("GetCDS after withdrawal = %v", cds)
+	}
+	if cdnskey, _ := GetCDNSKEY("cds.test."); len(cdnskey) != 0 {
+		t.Fatalf("GetCDNSKEY after withdrawal = %v", cdnskey)
+	}
+	if ds, err := GetDS("cds.test."); err != nil || len(ds) != 1 {
+		t.Fatalf("GetDS after withdrawal = %v, %v", ds, err)
+	}
+
+	// A new KSK needs the parent again.
+	if _, _, err := GenerateRolloverKey("cds.test", KeyRoleKSK, "ECDSAP256SHA256", now-1, now-1); err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	if CDSWithdrawn("cds.test.", now) {
+		t.Fatalf("CDS withdrawn during a KSK rollover")
+	}
+
+	keys, err := ParentDSKeys("cds.test.", now)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("ParentDSKeys = %d, %v", len(keys), err)
+	}
+	ds := keys[0].DNSKEY.ToDS(2)
+	if !DSMatchesDNSKEY(ds, keys[0].DNSKEY) || DSMatchesDNSKEY(ds, keys[1].DNSKEY) {
+		t.Fatalf("DSMatchesDNSKEY mismatched")
+	}
+}
@@ -1,6 +1,8 @@
 package security
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/base64"
 	"reflect"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"github.com/miekg/dns"
 
 	"go53/storage"
+	"go53/types"
 )
 
 func setupSecurityMockStorage(t *testing.T) {
@@ -231,3 +234,68 @@ func TestSortRRCanonicallyOrdersByPackedCanonicalWire(t *testing.T) {
 		}
 	}
 }
+
+func TestSignRRSetVerifiesServiceAndLocationRecords(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.test.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ED25519,
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+	}
+
+	sets := [][]string{
+		{
+			`naptr.example.test. 300 IN NAPTR 100 20 "S" "SIP+D2U" "" _SIP._UDP.Example.Test.`,
+			`naptr.example.test. 300 IN NAPTR 100 10 "S" "SIP+D2T" "" _sip._tcp.example.test.`,
+		},
+		{
+			`www.example.test. 300 IN HTTPS 1 . alpn="h3,h2" ipv4hint=192.0.2.10`,
+			`www.example.test. 300 IN HTTPS 2 alt.example.test. port=8443`,
+		},
+		{`loc.example.test. 300 IN LOC 59 19 45.480 N 18 4 6.960 E 28.00m`},
+		{`apl.example.test. 300 IN APL 1:192.0.2.0/24 !2:2001:db8::/32`},
+		{`opaque.example.test. 300 IN TYPE65534 \# 4 0a000001`},
+	}
+	for _, set := range sets {
+		var rrs []dns.RR
+		for _, text := range set {
+			rr, err := dns.NewRR(text)
+			if err != nil {
+				t.Fatalf("NewRR(%q): %v", text, err)
+			}
+			rrs = append(rrs, rr)
+		}
+		sig, err := SignRRSet(rrs, priv, dnskey.KeyTag(), "example.test.", dns.ED25519)
+		if err != nil {
+			t.Fatalf("SignRRSet(%s): %v", set[0], err)
+		}
+		if err := sig.Verify(dnskey, rrs); err != nil {
+			t.Fatalf("RRSIG over %s does not verify: %v", set[0], err)
+		}
+	}
+}
+
+func TestToRRSetBuildsOpaqueRecords(t *testing.T) {
+	rrs, err := ToRRSet("opaque.example.test.", "TYPE65534", []types.UnknownRecord{{Rdata: "0a000001", TTL: 300}})
+	if err != nil {
+		t.Fatalf("ToRRSet: %v", err)
+	}
+	if len(rrs) != 1 || rrs[0].Header().Rrtype != 65534 {
+		t.Fatalf("ToRRSet = %v", rrs)
+	}
+
+	// A known type stored opaquely comes back typed, so it is signed in its
+	// canonical form.
+	rrs, err = ToRRSet("host.example.test.", "HINFO", []types.UnknownRecord{{Rdata: "03414d440446726565", TTL: 300}})
+	if err != nil {
+		t.Fatalf("ToRRSet HINFO: %v", err)
+	}
+	if h, ok := rrs[0].(*dns.HINFO); !ok || h.Cpu != "AMD" || h.Os != "Free" {
+		t.Fatalf("ToRRSet HINFO = %v", rrs[0])
+	}
+}
@@ -0,0 +1,348 @@
+//go:build cgo
+
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: pkcs11.go is part of the go53 authoritative DNS server.
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS #11 3.0 identifiers for Ed25519, which the binding predates.
+const (
+	ckmECEdwardsKeyPairGen = 0x00001055
+	ckmEdDSA               = 0x00001057
+)
+
+// DER encoded curve OIDs for CKA_EC_PARAMS.
+var (
+	oidP256    = []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+	oidP384    = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x22}
+	oidEd25519 = []byte{0x06, 0x03, 0x2b, 0x65, 0x70}
+)
+
+// digestInfoPrefix is the DER DigestInfo header CKM_RSA_PKCS expects before
+// the digest.
+var digestInfoPrefix = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs11Provider keeps keys as token objects of one PKCS #11 token. Keys are
+// referenced by the hex CKA_ID of their key pair. A single logged-in session
+// serves every operation.
+type pkcs11Provider struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+}
+
+type pkcs11Signer struct {
+	p         *pkcs11Provider
+	id        []byte
+	algorithm uint8
+	pub       crypto.PublicKey
+}
+
+// NewPKCS11 loads the PKCS #11 library at module and logs in to the token
+// labelled tokenLabel, or the first token when it is empty.
+func NewPKCS11(module, tokenLabel, pin string) (Provider, error) {
+	if module == "" {
+		return nil, fmt.Errorf("PKCS #11 module path is required")
+	}
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("cannot load PKCS #11 module %s", module)
+	}
+	if err := ctx.Initialize(); err != nil && !isPKCS11Error(err, pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("initialize PKCS #11 module: %w", err)
+	}
+	fail := func(err error) (Provider, error) {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return fail(fmt.Errorf("list PKCS #11 slots: %w", err))
+	}
+	slot, found := uint(0), false
+	for _, s := range slots {
+		info, err := ctx.GetTokenInfo(s)
+		if err != nil {
+			continue
+		}
+		if tokenLabel == "" || info.Label == tokenLabel {
+			slot, found = s, true
+			break
+		}
+	}
+	if !found {
+		return fail(fmt.Errorf("no PKCS #11 token labelled %q", tokenLabel))
+	}
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return fail(fmt.Errorf("open PKCS #11 session: %w", err))
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil && !isPKCS11Error(err, pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		ctx.CloseSession(session)
+		return fail(fmt.Errorf("PKCS #11 login: %w", err))
+	}
+	return &pkcs11Provider{ctx: ctx, session: session}, nil
+}
+
+func isPKCS11Error(err error, code uint) bool {
+	var e pkcs11.Error
+	return errors.As(err, &e) && uint(e) == code
+}
+
+func (p *pkcs11Provider) Name() string { return PKCS11 }
+
+func (p *pkcs11Provider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ctx.Logout(p.session)
+	p.ctx.CloseSession(p.session)
+	err := p.ctx.Finalize()
+	p.ctx.Destroy()
+	return err
+}
+
+func (p *pkcs11Provider) GenerateKey(algorithm uint8, label string) (string, crypto.PublicKey, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	var mechanism uint
+	switch algorithm {
+	case dns.RSASHA256, dns.RSASHA512:
+		mechanism = pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN
+		public = append(public,
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, 2048),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}))
+	case dns.ECDSAP256SHA256:
+		mechanism = pkcs11.CKM_EC_KEY_PAIR_GEN
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidP256))
+	case dns.ECDSAP384SHA384:
+		mechanism = pkcs11.CKM_EC_KEY_PAIR_GEN
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidP384))
+	case dns.ED25519:
+		mechanism = ckmECEdwardsKeyPairGen
+		public = append(public, pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, oidEd25519))
+	default:
+		return "", nil, fmt.Errorf("PKCS #11 signer does not support DNSSEC algorithm %d", algorithm)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pubHandle, _, err := p.ctx.GenerateKeyPair(p.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, public, private)
+	if err != nil {
+		return "", nil, fmt.Errorf("PKCS #11 key generation: %w", err)
+	}
+	pub, err := p.publicKey(pubHandle, algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(id), pub, nil
+}
+
+// publicKey reads the public key object h of the DNSSEC algorithm.
+func (p *pkcs11Provider) publicKey(h pkcs11.ObjectHandle, algorithm uint8) (crypto.PublicKey, error) {
+	switch algorithm {
+	case dns.RSASHA256, dns.RSASHA512:
+		attrs, err := p.ctx.GetAttributeValue(p.session, h, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read PKCS #11 public key: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(attrs[1].Value)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("PKCS #11 RSA public exponent is too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(attrs[0].Value), E: int(exponent.Int64())}, nil
+	case dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		attrs, err := p.ctx.GetAttributeValue(p.session, h, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read PKCS #11 public key: %w", err)
+		}
+		point := attrs[0].Value
+		// Tokens return the point DER encoded as an OCTET STRING; some
+		// return it bare.
+		var inner []byte
+		if rest, err := asn1.Unmarshal(point, &inner); err == nil && len(rest) == 0 {
+			point = inner
+		}
+		if algorithm == dns.ED25519 {
+			if len(point) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("PKCS #11 Ed25519 public key has %d bytes", len(point))
+			}
+			return ed25519.PublicKey(append([]byte(nil), point...)), nil
+		}
+		curve := elliptic.P256()
+		if algorithm == dns.ECDSAP384SHA384 {
+			curve = elliptic.P384()
+		}
+		x, y := elliptic.Unmarshal(curve, point)
+		if x == nil {
+			return nil, fmt.Errorf("PKCS #11 EC public key is not an uncompressed %s point", curve.Params().Name)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("PKCS #11 signer does not support DNSSEC algorithm %d", algorithm)
+	}
+}
+
+// findKey returns the object of class with CKA_ID id.
+func (p *pkcs11Provider) findKey(class uint, id []byte) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+	}
+	if err := p.ctx.FindObjectsInit(p.session, template); err != nil {
+		return 0, err
+	}
+	handles, _, err := p.ctx.FindObjects(p.session, 1)
+	if finalErr := p.ctx.FindObjectsFinal(p.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(handles) == 0 {
+		return 0, fmt.Errorf("PKCS #11 key %x not found", id)
+	}
+	return handles[0], nil
+}
+
+func (p *pkcs11Provider) Signer(ref string, algorithm uint8) (crypto.Signer, error) {
+	id, err := hex.DecodeString(ref)
+	if err != nil || len(id) == 0 {
+		return nil, fmt.Errorf("invalid PKCS #11 key reference %q", ref)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, err := p.findKey(pkcs11.CKO_PUBLIC_KEY, id)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := p.publicKey(h, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.findKey(pkcs11.CKO_PRIVATE_KEY, id); err != nil {
+		return nil, err
+	}
+	return &pkcs11Signer{p: p, id: id, algorithm: algorithm, pub: pub}, nil
+}
+
+func (p *pkcs11Provider) DeleteKey(ref string) error {
+	id, err := hex.DecodeString(ref)
+	if err != nil || len(id) == 0 {
+		return fmt.Errorf("invalid PKCS #11 key reference %q", ref)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, class := range []uint{pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY} {
+		h, err := p.findKey(class, id)
+		if err != nil {
+			return err
+		}
+		if err := p.ctx.DestroyObject(p.session, h); err != nil {
+			return fmt.Errorf("destroy PKCS #11 key %s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey { return s.pub }
+
+func (s *pkcs11Signer) Sign(_ io.Reader, data []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var mechanism uint
+	input := data
+	switch s.algorithm {
+	case dns.RSASHA256, dns.RSASHA512:
+		prefix, ok := digestInfoPrefix[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("PKCS #11 signer cannot sign %s digests", opts.HashFunc())
+		}
+		mechanism = pkcs11.CKM_RSA_PKCS
+		input = append(append([]byte(nil), prefix...), data...)
+	case dns.ECDSAP256SHA256, dns.ECDSAP384SHA384:
+		mechanism = pkcs11.CKM_ECDSA
+	case dns.ED25519:
+		mechanism = ckmEdDSA
+	default:
+		return nil, fmt.Errorf("PKCS #11 signer does not support DNSSEC algorithm %d", s.algorithm)
+	}
+
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	h, err := s.p.findKey(pkcs11.CKO_PRIVATE_KEY, s.id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.p.ctx.SignInit(s.p.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, h); err != nil {
+		return nil, fmt.Errorf("PKCS #11 sign: %w", err)
+	}
+	sig, err := s.p.ctx.Sign(s.p.session, input)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS #11 sign: %w", err)
+	}
+	if mechanism == pkcs11.CKM_ECDSA {
+		// CKM_ECDSA returns r and s concatenated; crypto.Signer uses DER.
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			new(big.Int).SetBytes(sig[:half]),
+			new(big.Int).SetBytes(sig[half:]),
+		})
+	}
+	return sig, nil
+}
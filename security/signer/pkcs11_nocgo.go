@@ -0,0 +1,25 @@
+//go:build !cgo
+
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: pkcs11_nocgo.go is part of the go53 authoritative DNS server.
+package signer
+
+import "fmt"
+
+// NewPKCS11 is unavailable without cgo: PKCS #11 modules are C libraries.
+func NewPKCS11(module, tokenLabel, pin string) (Provider, error) {
+	return nil, fmt.Errorf("this go53 binary was built without cgo and cannot load PKCS #11 modules")
+}
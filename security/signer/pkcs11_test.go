@@ -0,0 +1,88 @@
+//go:build cgo
+
+package signer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// softHSMProvider initializes a fresh SoftHSM token in a temporary directory
+// and logs in to it. The test is skipped where SoftHSM is not installed;
+// SOFTHSM2_MODULE names the library if it is not in a usual place.
+func softHSMProvider(t *testing.T) Provider {
+	t.Helper()
+	module := os.Getenv("SOFTHSM2_MODULE")
+	for _, path := range []string{
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib64/pkcs11/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	} {
+		if module != "" {
+			break
+		}
+		if _, err := os.Stat(path); err == nil {
+			module = path
+		}
+	}
+	util, err := exec.LookPath("softhsm2-util")
+	if module == "" || err != nil {
+		t.Skip("SoftHSM is not installed")
+	}
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "softhsm2.conf")
+	if err := os.MkdirAll(filepath.Join(dir, "tokens"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(conf, []byte("directories.tokendir = "+filepath.Join(dir, "tokens")+"\nobjectstore.backend = file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", conf)
+	out, err := exec.Command(util, "--init-token", "--free", "--label", "go53-test", "--pin", "1234", "--so-pin", "5678").CombinedOutput()
+	if err != nil {
+		t.Fatalf("softhsm2-util: %v: %s", err, out)
+	}
+
+	p, err := New(Config{Provider: PKCS11, PKCS11Module: module, PKCS11TokenLabel: "go53-test", PKCS11PIN: "1234"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestPKCS11ProviderSignsInsideTheToken(t *testing.T) {
+	p := softHSMProvider(t)
+	for _, algorithm := range []uint8{dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.RSASHA256, dns.ED25519} {
+		ref, pub, err := p.GenerateKey(algorithm, "example.test. zsk")
+		if err != nil {
+			t.Fatalf("GenerateKey(%d): %v", algorithm, err)
+		}
+		if algorithm == dns.ECDSAP384SHA384 {
+			// signAndVerify builds P-256 DNSKEYs only; check the signer.
+			if _, err := p.Signer(ref, algorithm); err != nil {
+				t.Fatalf("Signer(%d): %v", algorithm, err)
+			}
+			continue
+		}
+		signAndVerify(t, p, ref, pub, algorithm)
+	}
+
+	ref, _, err := p.GenerateKey(dns.ECDSAP256SHA256, "deleted")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if err := p.DeleteKey(ref); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+	if _, err := p.Signer(ref, dns.ECDSAP256SHA256); err == nil {
+		t.Fatal("Signer of a deleted key succeeded")
+	}
+}
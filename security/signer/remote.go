@@ -0,0 +1,235 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: remote.go is part of the go53 authoritative DNS server.
+package signer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteKey is the body a remote signer answers key requests with: the key
+// reference and the public key as base64 DER SubjectPublicKeyInfo.
+type RemoteKey struct {
+	KeyRef    string `json:"key_ref"`
+	PublicKey string `json:"public_key"`
+}
+
+// RemoteGenerateRequest is the body of POST /keys.
+type RemoteGenerateRequest struct {
+	Algorithm uint8  `json:"algorithm"`
+	Label     string `json:"label,omitempty"`
+}
+
+// RemoteSignRequest is the body of POST /keys/{ref}/sign. Data is the
+// base64 digest, or the message itself when Hash is empty (Ed25519).
+type RemoteSignRequest struct {
+	Algorithm uint8  `json:"algorithm"`
+	Hash      string `json:"hash,omitempty"`
+	Data      string `json:"data"`
+}
+
+// RemoteSignResponse carries the base64 signature in crypto.Signer form.
+type RemoteSignResponse struct {
+	Signature string `json:"signature"`
+}
+
+// remoteProvider talks to a signing service over HTTP. Signatures are
+// verified with the key's public key before they are used:
+//
+//	POST   {url}/keys             RemoteGenerateRequest → RemoteKey
+//	GET    {url}/keys/{ref}       → RemoteKey
+//	POST   {url}/keys/{ref}/sign  RemoteSignRequest → RemoteSignResponse
+//	DELETE {url}/keys/{ref}
+type remoteProvider struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+type remoteSigner struct {
+	p         *remoteProvider
+	ref       string
+	algorithm uint8
+	pub       crypto.PublicKey
+}
+
+// NewRemote returns a provider that keeps keys in the signing service at
+// baseURL.
+func NewRemote(baseURL, token string, timeout time.Duration) (Provider, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("remote signer URL %q must be an http or https URL", baseURL)
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &remoteProvider{
+		base:   strings.TrimSuffix(u.String(), "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *remoteProvider) Name() string { return Remote }
+
+func (p *remoteProvider) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+func (p *remoteProvider) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, p.base+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote signer: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("remote signer: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("remote signer: %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("remote signer: invalid response to %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func keyPath(ref string) string {
+	return "/keys/" + url.PathEscape(ref)
+}
+
+func (k RemoteKey) publicKey() (crypto.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: public key of %q is not base64: %w", k.KeyRef, err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: public key of %q: %w", k.KeyRef, err)
+	}
+	return pub, nil
+}
+
+func (p *remoteProvider) GenerateKey(algorithm uint8, label string) (string, crypto.PublicKey, error) {
+	var key RemoteKey
+	if err := p.do(http.MethodPost, "/keys", RemoteGenerateRequest{Algorithm: algorithm, Label: label}, &key); err != nil {
+		return "", nil, err
+	}
+	if key.KeyRef == "" {
+		return "", nil, fmt.Errorf("remote signer: generated key has no key_ref")
+	}
+	pub, err := key.publicKey()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkPublicKey(pub, algorithm); err != nil {
+		return "", nil, err
+	}
+	return key.KeyRef, pub, nil
+}
+
+func (p *remoteProvider) Signer(ref string, algorithm uint8) (crypto.Signer, error) {
+	var key RemoteKey
+	if err := p.do(http.MethodGet, keyPath(ref), nil, &key); err != nil {
+		return nil, err
+	}
+	pub, err := key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublicKey(pub, algorithm); err != nil {
+		return nil, err
+	}
+	return &remoteSigner{p: p, ref: ref, algorithm: algorithm, pub: pub}, nil
+}
+
+func (p *remoteProvider) DeleteKey(ref string) error {
+	return p.do(http.MethodDelete, keyPath(ref), nil, nil)
+}
+
+func (s *remoteSigner) Public() crypto.PublicKey { return s.pub }
+
+func (s *remoteSigner) Sign(_ io.Reader, data []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := RemoteSignRequest{Algorithm: s.algorithm, Data: base64.StdEncoding.EncodeToString(data)}
+	if opts != nil && opts.HashFunc() != 0 {
+		req.Hash = opts.HashFunc().String()
+	}
+	var resp RemoteSignResponse
+	if err := s.p.do(http.MethodPost, keyPath(s.ref)+"/sign", req, &resp); err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil || len(sig) == 0 {
+		return nil, fmt.Errorf("remote signer: invalid signature for %q", s.ref)
+	}
+	if !verifySignature(s.pub, data, sig, opts) {
+		return nil, fmt.Errorf("remote signer: signature for %q does not verify with its public key", s.ref)
+	}
+	return sig, nil
+}
+
+// verifySignature reports whether sig, in crypto.Signer form, is a signature
+// of data by pub.
+func verifySignature(pub crypto.PublicKey, data, sig []byte, opts crypto.SignerOpts) bool {
+	var hash crypto.Hash
+	if opts != nil {
+		hash = opts.HashFunc()
+	}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, hash, data, sig) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, data, sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, data, sig)
+	}
+	return false
+}
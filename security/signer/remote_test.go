@@ -0,0 +1,244 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testRemoteSigner is a signing service speaking the remote protocol with
+// keys in memory.
+type testRemoteSigner struct {
+	mu    sync.Mutex
+	token string
+	keys  map[string]crypto.Signer
+	next  int
+}
+
+func (s *testRemoteSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeKey := func(ref string, key crypto.Signer) {
+		der, _ := x509.MarshalPKIXPublicKey(key.Public())
+		_ = json.NewEncoder(w).Encode(RemoteKey{KeyRef: ref, PublicKey: base64.StdEncoding.EncodeToString(der)})
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/keys")
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		var req RemoteGenerateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var key crypto.Signer
+		var err error
+		switch req.Algorithm {
+		case dns.RSASHA256:
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+		case dns.ECDSAP256SHA256:
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		case dns.ED25519:
+			_, key, err = ed25519.GenerateKey(rand.Reader)
+		default:
+			http.Error(w, "unsupported algorithm", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.next++
+		ref := fmt.Sprintf("key/%d", s.next)
+		s.keys[ref] = key
+		writeKey(ref, key)
+	case strings.HasSuffix(path, "/sign") && r.Method == http.MethodPost:
+		key, ok := s.keys[strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/sign")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var req RemoteSignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := base64.StdEncoding.DecodeString(req.Data)
+		var opts crypto.SignerOpts = crypto.Hash(0)
+		switch req.Hash {
+		case crypto.SHA256.String():
+			opts = crypto.SHA256
+		case crypto.SHA384.String():
+			opts = crypto.SHA384
+		}
+		sig, err := key.Sign(rand.Reader, data, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(RemoteSignResponse{Signature: base64.StdEncoding.EncodeToString(sig)})
+	default:
+		ref := strings.TrimPrefix(path, "/")
+		key, ok := s.keys[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(s.keys, ref)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeKey(ref, key)
+	}
+}
+
+func startTestRemoteSigner(t *testing.T, token string) (*testRemoteSigner, Provider) {
+	t.Helper()
+	service := &testRemoteSigner{token: token, keys: map[string]crypto.Signer{}}
+	server := httptest.NewServer(service)
+	t.Cleanup(server.Close)
+	p, err := New(Config{Provider: Remote, RemoteURL: server.URL + "/", RemoteToken: token, RemoteTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return service, p
+}
+
+// signAndVerify signs an RRset with the key ref and verifies the RRSIG with
+// the DNSKEY made from its public key.
+func signAndVerify(t *testing.T, p Provider, ref string, pub crypto.PublicKey, algorithm uint8) {
+	t.Helper()
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.test.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     256,
+		Protocol:  3,
+		Algorithm: algorithm,
+	}
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		key.PublicKey = base64.StdEncoding.EncodeToString(append(k.X.FillBytes(make([]byte, 32)), k.Y.FillBytes(make([]byte, 32))...))
+	case ed25519.PublicKey:
+		key.PublicKey = base64.StdEncoding.EncodeToString(k)
+	case *rsa.PublicKey:
+		e := []byte{1, 0, 1}
+		key.PublicKey = base64.StdEncoding.EncodeToString(append(append([]byte{byte(len(e))}, e...), k.N.Bytes()...))
+	}
+	s, err := p.Signer(ref, algorithm)
+	if err != nil {
+		t.Fatalf("Signer: %v", err)
+	}
+	rrs := []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "www.example.test.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: []byte{192, 0, 2, 1}}}
+	now := time.Now()
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: "www.example.test.", Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 300},
+		TypeCovered: dns.TypeA,
+		Algorithm:   algorithm,
+		Labels:      3,
+		OrigTtl:     300,
+		Inception:   uint32(now.Add(-time.Hour).Unix()),
+		Expiration:  uint32(now.Add(time.Hour).Unix()),
+		KeyTag:      key.KeyTag(),
+		SignerName:  "example.test.",
+	}
+	if err := sig.Sign(s, rrs); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := sig.Verify(key, rrs); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestRemoteProviderSignsInsideTheService(t *testing.T) {
+	service, p := startTestRemoteSigner(t, "secret")
+	for _, algorithm := range []uint8{dns.ECDSAP256SHA256, dns.ED25519, dns.RSASHA256} {
+		ref, pub, err := p.GenerateKey(algorithm, "example.test. zsk")
+		if err != nil {
+			t.Fatalf("GenerateKey(%d): %v", algorithm, err)
+		}
+		signAndVerify(t, p, ref, pub, algorithm)
+	}
+	if len(service.keys) != 3 {
+		t.Fatalf("service holds %d keys, want 3", len(service.keys))
+	}
+
+	ref, _, err := p.GenerateKey(dns.ED25519, "")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if err := p.DeleteKey(ref); err != nil {
+		t.Fatalf("DeleteKey: %v", err)
+	}
+	if _, err := p.Signer(ref, dns.ED25519); err == nil {
+		t.Fatal("Signer of a deleted key succeeded")
+	}
+}
+
+func TestRemoteProviderRejectsMismatchedKeys(t *testing.T) {
+	_, p := startTestRemoteSigner(t, "")
+	ref, _, err := p.GenerateKey(dns.ED25519, "")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if _, err := p.Signer(ref, dns.ECDSAP256SHA256); err == nil {
+		t.Fatal("Signer accepted an Ed25519 key for ECDSAP256SHA256")
+	}
+
+	// A service that signs with another key than it reports.
+	service, p := startTestRemoteSigner(t, "")
+	ref, _, err = p.GenerateKey(dns.ED25519, "")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := p.Signer(ref, dns.ED25519)
+	if err != nil {
+		t.Fatalf("Signer: %v", err)
+	}
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	service.mu.Lock()
+	service.keys[ref] = other
+	service.mu.Unlock()
+	if _, err := s.Sign(rand.Reader, []byte("data"), crypto.Hash(0)); err == nil {
+		t.Fatal("Sign accepted a signature by another key")
+	}
+
+	_, authed := startTestRemoteSigner(t, "secret")
+	unauthed, err := NewRemote(strings.TrimSuffix(authed.(*remoteProvider).base, "/"), "wrong", 0)
+	if err != nil {
+		t.Fatalf("NewRemote: %v", err)
+	}
+	if _, _, err := unauthed.GenerateKey(dns.ED25519, ""); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("GenerateKey with a wrong token = %v, want 401", err)
+	}
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+	if p, err := New(Config{}); p != nil || err != nil {
+		t.Fatalf("New(local) = %v, %v, want no provider", p, err)
+	}
+	if _, err := New(Config{Provider: "kms"}); err == nil {
+		t.Fatal("New accepted an unknown provider")
+	}
+	if _, err := New(Config{Provider: Remote, RemoteURL: "ftp://signer"}); err == nil {
+		t.Fatal("New accepted a remote signer URL that is not http")
+	}
+}
@@ -0,0 +1,110 @@
+// Package signer
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: signer.go is part of the go53 authoritative DNS server.
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Names of the signer providers. Keys of the local provider keep their
+// private key in storage and never reach a Provider.
+const (
+	Local  = "local"
+	PKCS11 = "pkcs11"
+	Remote = "remote"
+)
+
+// Provider generates DNSSEC keys and signs with them outside go53's storage.
+// A key is known by the reference the provider hands out; go53 stores only
+// that reference and the public key.
+//
+// Signers follow the crypto.Signer conventions: PKCS #1 v1.5 signatures over
+// the digest for RSA, ASN.1 DER for ECDSA and plain signatures over the
+// message for Ed25519.
+type Provider interface {
+	// Name identifies the provider in stored keys.
+	Name() string
+
+	// GenerateKey creates a key pair of the DNSSEC algorithm number, named
+	// label where the provider supports it, and returns its reference and
+	// public key.
+	GenerateKey(algorithm uint8, label string) (ref string, pub crypto.PublicKey, err error)
+
+	// Signer returns a signer for the key ref of the DNSSEC algorithm.
+	Signer(ref string, algorithm uint8) (crypto.Signer, error)
+
+	// DeleteKey destroys the key ref.
+	DeleteKey(ref string) error
+
+	// Close releases the provider's connections and sessions.
+	Close() error
+}
+
+// Config selects the provider new DNSSEC keys are generated in and the
+// settings it needs.
+type Config struct {
+	Provider string
+
+	PKCS11Module     string // path of the PKCS #11 library
+	PKCS11TokenLabel string // token to use; empty takes the first one
+	PKCS11PIN        string // user PIN of the token
+
+	RemoteURL     string        // base URL of the remote signer
+	RemoteToken   string        // sent as bearer token when set
+	RemoteTimeout time.Duration // per request; 0 uses 10 seconds
+}
+
+// New returns the provider cfg selects, nil for the local provider.
+func New(cfg Config) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case "", Local:
+		return nil, nil
+	case PKCS11:
+		return NewPKCS11(cfg.PKCS11Module, cfg.PKCS11TokenLabel, cfg.PKCS11PIN)
+	case Remote:
+		return NewRemote(cfg.RemoteURL, cfg.RemoteToken, cfg.RemoteTimeout)
+	default:
+		return nil, fmt.Errorf("unknown DNSSEC signer %q", cfg.Provider)
+	}
+}
+
+// checkPublicKey reports whether pub is a key of the DNSSEC algorithm.
+func checkPublicKey(pub crypto.PublicKey, algorithm uint8) error {
+	ok := false
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		ok = algorithm == dns.RSASHA256 || algorithm == dns.RSASHA512
+	case *ecdsa.PublicKey:
+		ok = (algorithm == dns.ECDSAP256SHA256 && k.Curve == elliptic.P256()) ||
+			(algorithm == dns.ECDSAP384SHA384 && k.Curve == elliptic.P384())
+	case ed25519.PublicKey:
+		ok = algorithm == dns.ED25519
+	}
+	if !ok {
+		return fmt.Errorf("%T is not a key of DNSSEC algorithm %d", pub, algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,884 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: skr.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/storage"
+	"go53/types"
+	"go53/wal"
+	"go53/zonemeta"
+)
+
+const dnssecSKRTable = "dnssec_skr"
+
+// Formats of the files exchanged with the holder of an offline KSK.
+const (
+	KSRFormat = "go53-ksr"
+	SKRFormat = "go53-skr"
+)
+
+// KeySigningRequest asks the holder of the offline KSK of a zone to sign the
+// DNSKEY RRsets the zone will publish, one per slot.
+type KeySigningRequest struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Zone    string    `json:"zone"`
+	TTL     uint32    `json:"ttl"`
+	Slots   []KSRSlot `json:"slots"`
+}
+
+// KSRSlot is a window with a fixed set of ZSKs, given as DNSKEY records in
+// presentation format, and the validity its signatures must have.
+type KSRSlot struct {
+	Inception     int64    `json:"inception"`
+	Expiration    int64    `json:"expiration"`
+	SigInception  int64    `json:"sig_inception"`
+	SigExpiration int64    `json:"sig_expiration"`
+	ZSKs          []string `json:"zsks"`
+}
+
+// SignedKeyResponse holds the signed key bundles made from a key signing
+// request, in order.
+type SignedKeyResponse struct {
+	Format  string      `json:"format"`
+	Version int         `json:"version"`
+	Zone    string      `json:"zone"`
+	Bundles []SKRBundle `json:"bundles"`
+}
+
+// SKRBundle is served from Inception until Expiration: the DNSKEY RRset with
+// the KSKs and the ZSKs of its window, the CDS and CDNSKEY RRsets if the
+// signer added them, and their RRSIGs, in presentation format.
+type SKRBundle struct {
+	Inception  int64    `json:"inception"`
+	Expiration int64    `json:"expiration"`
+	Records    []string `json:"records"`
+}
+
+// SKRBundleStatus describes an imported bundle.
+type SKRBundleStatus struct {
+	Inception  int64    `json:"inception"`
+	Expiration int64    `json:"expiration"`
+	KSKTags    []uint16 `json:"ksk_tags"`
+	ZSKTags    []uint16 `json:"zsk_tags"`
+	CDS        bool     `json:"cds"`
+	Current    bool     `json:"current,omitempty"`
+}
+
+// SKRStatus is the offline KSK state of a zone: its imported bundles, until
+// when they keep it signed without a gap and what needs attention.
+type SKRStatus struct {
+	Zone           string            `json:"zone"`
+	OfflineKSK     bool              `json:"offline_ksk"`
+	Bundles        []SKRBundleStatus `json:"bundles"`
+	CoveredUntil   int64             `json:"covered_until,omitempty"`
+	Remaining      int64             `json:"remaining_sec"`
+	KSKPrivateKeys []string          `json:"ksk_private_keys,omitempty"`
+	Warnings       []string          `json:"warnings,omitempty"`
+}
+
+// OfflineKey is a KSK in the hands of the offline signer.
+type OfflineKey struct {
+	DNSKEY *dns.DNSKEY
+	Signer crypto.Signer
+}
+
+// skrBundle is a parsed SKRBundle.
+type skrBundle struct {
+	inception  int64
+	expiration int64
+	rrsets     map[uint16][]dns.RR
+	sigs       map[uint16][]*dns.RRSIG
+}
+
+var skrCache = struct {
+	sync.RWMutex
+	backendID string
+	zones     map[string][]skrBundle
+}{}
+
+// OfflineKSK reports whether zone keeps its KSK offline: go53 holds only its
+// ZSKs and serves DNSKEY, CDS and CDNSKEY as signed in an imported SKR.
+func OfflineKSK(zone string) bool {
+	meta, err := zonemeta.Load(zone)
+	return err == nil && meta.Settings.OfflineKSK
+}
+
+// StoredKSKPrivateKeys returns the IDs of the KSKs of zone whose private key
+// is stored with the key record, removed ones included: their key records,
+// and the private keys with them, stay in storage.
+func StoredKSKPrivateKeys(zone string) ([]string, error) {
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, k := range keys {
+		if isKSK(&k.key) && k.key.PrivatePEM != "" {
+			ids = append(ids, k.id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// refuseOfflineKSK keeps the private material of a KSK out of storage when
+// its zone keeps the KSK offline.
+func refuseOfflineKSK(zone string, flags uint16) error {
+	if flags&dns.SEP != 0 && OfflineKSK(zone) {
+		return fmt.Errorf("zone %s keeps its KSK offline; sign its DNSKEY RRset with go53ctl skr sign", strings.TrimSuffix(dns.Fqdn(zone), "."))
+	}
+	return nil
+}
+
+func skrKey(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(dns.Fqdn(zone), "."))
+}
+
+func resetSKRCache() {
+	skrCache.Lock()
+	skrCache.zones = nil
+	skrCache.Unlock()
+}
+
+// zoneSKR returns the imported bundles of zone in order.
+func zoneSKR(zone string) ([]skrBundle, error) {
+	backendID := currentStorageBackendID()
+	skrCache.RLock()
+	if skrCache.zones != nil && skrCache.backendID == backendID {
+		bundles := skrCache.zones[skrKey(zone)]
+		skrCache.RUnlock()
+		return bundles, nil
+	}
+	skrCache.RUnlock()
+
+	if storage.Backend == nil {
+		return nil, fmt.Errorf("storage backend is not initialized")
+	}
+	table, err := storage.Backend.LoadTable(dnssecSKRTable)
+	if err != nil {
+		return nil, fmt.Errorf("load table: %w", err)
+	}
+	zones := make(map[string][]skrBundle, len(table))
+	for key, raw := range table {
+		var skr SignedKeyResponse
+		if err := json.Unmarshal(raw, &skr); err != nil {
+			return nil, fmt.Errorf("unmarshal SKR of %q: %w", key, err)
+		}
+		for _, b := range skr.Bundles {
+			parsed, err := parseSKRBundle(key+".", b)
+			if err != nil {
+				return nil, fmt.Errorf("SKR of %q: %w", key, err)
+			}
+			zones[key] = append(zones[key], parsed)
+		}
+	}
+
+	skrCache.Lock()
+	skrCache.zones = zones
+	skrCache.backendID = backendID
+	skrCache.Unlock()
+	return zones[skrKey(zone)], nil
+}
+
+func parseSKRBundle(zone string, b SKRBundle) (skrBundle, error) {
+	out := skrBundle{
+		inception:  b.Inception,
+		expiration: b.Expiration,
+		rrsets:     map[uint16][]dns.RR{},
+		sigs:       map[uint16][]*dns.RRSIG{},
+	}
+	if b.Expiration <= b.Inception {
+		return out, fmt.Errorf("bundle %d-%d ends before it starts", b.Inception, b.Expiration)
+	}
+	for _, line := range b.Records {
+		rr, err := dns.NewRR(line)
+		if err != nil || rr == nil {
+			return out, fmt.Errorf("bundle %d-%d: invalid record %q: %v", b.Inception, b.Expiration, line, err)
+		}
+		if !strings.EqualFold(rr.Header().Name, zone) {
+			return out, fmt.Errorf("bundle %d-%d: %s is not the apex of %s", b.Inception, b.Expiration, rr.Header().Name, zone)
+		}
+		switch v := rr.(type) {
+		case *dns.RRSIG:
+			if !skrType(v.TypeCovered) {
+				return out, fmt.Errorf("bundle %d-%d: unexpected RRSIG over %s", b.Inception, b.Expiration, dns.TypeToString[v.TypeCovered])
+			}
+			out.sigs[v.TypeCovered] = append(out.sigs[v.TypeCovered], v)
+		case *dns.DNSKEY, *dns.CDS, *dns.CDNSKEY:
+			out.rrsets[rr.Header().Rrtype] = append(out.rrsets[rr.Header().Rrtype], rr)
+		default:
+			return out, fmt.Errorf("bundle %d-%d: unexpected %s record", b.Inception, b.Expiration, dns.TypeToString[rr.Header().Rrtype])
+		}
+	}
+	if len(out.rrsets[dns.TypeDNSKEY]) == 0 {
+		return out, fmt.Errorf("bundle %d-%d has no DNSKEY RRset", b.Inception, b.Expiration)
+	}
+	return out, nil
+}
+
+func skrType(rrtype uint16) bool {
+	return rrtype == dns.TypeDNSKEY || rrtype == dns.TypeCDS || rrtype == dns.TypeCDNSKEY
+}
+
+// keys returns the KSKs and ZSKs of the bundle's DNSKEY RRset.
+func (b *skrBundle) keys() (ksks, zsks []*dns.DNSKEY) {
+	for _, rr := range b.rrsets[dns.TypeDNSKEY] {
+		key := rr.(*dns.DNSKEY)
+		switch {
+		case key.Flags&dns.SEP == 0:
+			zsks = append(zsks, key)
+		case key.Flags&dns.REVOKE == 0:
+			ksks = append(ksks, key)
+		}
+	}
+	return ksks, zsks
+}
+
+// verify checks that every RRset of the bundle is signed by a KSK in its
+// DNSKEY RRset, with signatures valid for the whole window.
+func (b *skrBundle) verify() error {
+	ksks, _ := b.keys()
+	if len(ksks) == 0 {
+		return fmt.Errorf("bundle %d-%d: no KSK in the DNSKEY RRset", b.inception, b.expiration)
+	}
+	for rrtype := range b.sigs {
+		if len(b.rrsets[rrtype]) == 0 {
+			return fmt.Errorf("bundle %d-%d: RRSIG over a missing %s RRset", b.inception, b.expiration, dns.TypeToString[rrtype])
+		}
+	}
+	for rrtype, rrs := range b.rrsets {
+		name := dns.TypeToString[rrtype]
+		if len(b.sigs[rrtype]) == 0 {
+			return fmt.Errorf("bundle %d-%d: %s RRset is not signed", b.inception, b.expiration, name)
+		}
+		for _, sig := range b.sigs[rrtype] {
+			var key *dns.DNSKEY
+			for _, ksk := range ksks {
+				if ksk.KeyTag() == sig.KeyTag && ksk.Algorithm == sig.Algorithm && strings.EqualFold(ksk.Hdr.Name, sig.SignerName) {
+					key = ksk
+					break
+				}
+			}
+			if key == nil {
+				return fmt.Errorf("bundle %d-%d: %s signed by unknown key %d", b.inception, b.expiration, name, sig.KeyTag)
+			}
+			if err := sig.Verify(key, rrs); err != nil {
+				return fmt.Errorf("bundle %d-%d: %s signature of key %d: %w", b.inception, b.expiration, name, sig.KeyTag, err)
+			}
+			if int64(sig.Inception) > b.inception || int64(sig.Expiration) < b.expiration {
+				return fmt.Errorf("bundle %d-%d: %s signature of key %d is only valid %d-%d", b.inception, b.expiration, name, sig.KeyTag, sig.Inception, sig.Expiration)
+			}
+		}
+	}
+	return nil
+}
+
+func dnskeyIdentity(key *dns.DNSKEY) string {
+	return fmt.Sprintf("%d/%d/%s", key.Flags, key.Algorithm, key.PublicKey)
+}
+
+// localZSKsAt returns the ZSKs of zone published at now, by identity.
+func localZSKsAt(zone string, now int64) (map[string]uint16, error) {
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]uint16{}
+	for i := range keys {
+		key := &keys[i].key
+		if isZSK(key) && keyPublishedAt(key, now) {
+			dnskey := storedKeyToDNSKEY(zone, key, 0)
+			out[dnskeyIdentity(dnskey)] = dnskey.KeyTag()
+		}
+	}
+	return out, nil
+}
+
+// matchesLocalZSKs checks that the bundle publishes the ZSKs zone has during
+// its window.
+func (b *skrBundle) matchesLocalZSKs(zone string) error {
+	_, zsks := b.keys()
+	for _, at := range []int64{b.inception, b.expiration - 1} {
+		local, err := localZSKsAt(zone, at)
+		if err != nil {
+			return err
+		}
+		var missing, unexpected []string
+		inBundle := map[string]bool{}
+		for _, zsk := range zsks {
+			id := dnskeyIdentity(zsk)
+			inBundle[id] = true
+			if _, ok := local[id]; !ok {
+				unexpected = append(unexpected, fmt.Sprint(zsk.KeyTag()))
+			}
+		}
+		for id, tag := range local {
+			if !inBundle[id] {
+				missing = append(missing, fmt.Sprint(tag))
+			}
+		}
+		if len(missing) > 0 || len(unexpected) > 0 {
+			sort.Strings(missing)
+			sort.Strings(unexpected)
+			return fmt.Errorf("bundle %d-%d does not match the ZSKs published at %d: missing [%s], unknown [%s]", b.inception, b.expiration, at, strings.Join(missing, " "), strings.Join(unexpected, " "))
+		}
+	}
+	return nil
+}
+
+// ImportSKR checks the signed key response in data against zone and its
+// ZSKs and stores its bundles. They replace the stored bundles they overlap;
+// bundles that have run out are dropped. It returns the stored SKR.
+func ImportSKR(zone string, data []byte, now int64) (SignedKeyResponse, error) {
+	fqdn, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return SignedKeyResponse{}, fmt.Errorf("FQDN sanitize check failed: %w", err)
+	}
+	fqdn = strings.ToLower(fqdn)
+	var in SignedKeyResponse
+	if err := json.Unmarshal(data, &in); err != nil {
+		return SignedKeyResponse{}, fmt.Errorf("invalid SKR JSON: %w", err)
+	}
+	if in.Format != SKRFormat || in.Version != 1 {
+		return SignedKeyResponse{}, fmt.Errorf("unsupported SKR format")
+	}
+	if in.Zone != "" && !strings.EqualFold(dns.Fqdn(in.Zone), fqdn) {
+		return SignedKeyResponse{}, fmt.Errorf("SKR is for zone %s, not %s", in.Zone, fqdn)
+	}
+	sort.SliceStable(in.Bundles, func(i, j int) bool { return in.Bundles[i].Inception < in.Bundles[j].Inception })
+
+	var fresh []SKRBundle
+	for i, b := range in.Bundles {
+		if i > 0 && b.Inception < in.Bundles[i-1].Expiration {
+			return SignedKeyResponse{}, fmt.Errorf("bundle %d-%d overlaps the one before", b.Inception, b.Expiration)
+		}
+		if b.Expiration <= now {
+			continue
+		}
+		parsed, err := parseSKRBundle(fqdn, b)
+		if err != nil {
+			return SignedKeyResponse{}, err
+		}
+		if err := parsed.verify(); err != nil {
+			return SignedKeyResponse{}, err
+		}
+		if err := parsed.matchesLocalZSKs(fqdn); err != nil {
+			return SignedKeyResponse{}, err
+		}
+		fresh = append(fresh, b)
+	}
+	if len(fresh) == 0 {
+		return SignedKeyResponse{}, fmt.Errorf("SKR has no bundle that has not run out")
+	}
+
+	stored, err := storedSKR(fqdn)
+	if err != nil {
+		return SignedKeyResponse{}, err
+	}
+	out := SignedKeyResponse{Format: SKRFormat, Version: 1, Zone: fqdn}
+	for _, b := range stored.Bundles {
+		overlaps := slices.ContainsFunc(fresh, func(n SKRBundle) bool {
+			return b.Inception < n.Expiration && n.Inception < b.Expiration
+		})
+		if b.Expiration > now && !overlaps {
+			out.Bundles = append(out.Bundles, b)
+		}
+	}
+	out.Bundles = append(out.Bundles, fresh...)
+	sort.SliceStable(out.Bundles, func(i, j int) bool { return out.Bundles[i].Inception < out.Bundles[j].Inception })
+	if err := saveSKR(fqdn, out); err != nil {
+		return SignedKeyResponse{}, err
+	}
+	return out, nil
+}
+
+func storedSKR(zone string) (SignedKeyResponse, error) {
+	table, err := storage.Backend.LoadTable(dnssecSKRTable)
+	if err != nil {
+		return SignedKeyResponse{}, fmt.Errorf("load table: %w", err)
+	}
+	var skr SignedKeyResponse
+	if raw, ok := table[skrKey(zone)]; ok {
+		if err := json.Unmarshal(raw, &skr); err != nil {
+			return SignedKeyResponse{}, err
+		}
+	}
+	return skr, nil
+}
+
+func saveSKR(zone string, skr SignedKeyResponse) error {
+	data, err := json.Marshal(skr)
+	if err != nil {
+		return err
+	}
+	key := skrKey(zone)
+	if err := storage.Backend.SaveTable(dnssecSKRTable, key, data); err != nil {
+		return err
+	}
+	if _, err := wal.Append(wal.KindDNSSECKey, wal.OpUpsert, "", "", "", dnssecSKRTable, key, data); err != nil {
+		return err
+	}
+	resetSKRCache()
+	return nil
+}
+
+// SaveReplicatedSKR stores the SKR of a zone received from another node.
+func SaveReplicatedSKR(zone string, data []byte) error {
+	if err := storage.Backend.SaveTable(dnssecSKRTable, skrKey(zone), data); err != nil {
+		return err
+	}
+	resetSKRCache()
+	return nil
+}
+
+func currentSKRBundle(zone string, now int64) (*skrBundle, error) {
+	bundles, err := zoneSKR(zone)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bundles {
+		if bundles[i].inception <= now && now < bundles[i].expiration {
+			return &bundles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no SKR bundle of zone %s covers %s", zone, time.Unix(now, 0).UTC().Format(time.RFC3339))
+}
+
+// SKRRRset returns the rrtype RRset of the SKR bundle of zone that covers
+// now: DNSKEY, CDS or CDNSKEY. It is empty when the bundle has none.
+func SKRRRset(zone string, rrtype uint16, now int64) ([]dns.RR, error) {
+	b, err := currentSKRBundle(zone, now)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]dns.RR, 0, len(b.rrsets[rrtype]))
+	for _, rr := range b.rrsets[rrtype] {
+		out = append(out, dns.Copy(rr))
+	}
+	return out, nil
+}
+
+// SKRSignatures returns the signatures over the rrtype RRset of the SKR
+// bundle of zone that covers now.
+func SKRSignatures(zone string, rrtype uint16, now int64) ([]*dns.RRSIG, error) {
+	b, err := currentSKRBundle(zone, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.sigs[rrtype]) == 0 {
+		return nil, fmt.Errorf("SKR bundle of zone %s has no %s signature", zone, dns.TypeToString[rrtype])
+	}
+	out := make([]*dns.RRSIG, 0, len(b.sigs[rrtype]))
+	for _, sig := range b.sigs[rrtype] {
+		out = append(out, dns.Copy(sig).(*dns.RRSIG))
+	}
+	return out, nil
+}
+
+// SKRStatusOf reports the imported bundles of zone at now. An offline-KSK
+// zone gets warnings when no bundle covers now, when the bundles run out
+// within warn seconds and while KSK private keys are still stored.
+func SKRStatusOf(zone string, now, warn int64) (SKRStatus, error) {
+	fqdn, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return SKRStatus{}, fmt.Errorf("FQDN sanitize check failed: %w", err)
+	}
+	out := SKRStatus{Zone: fqdn, OfflineKSK: OfflineKSK(fqdn), Bundles: []SKRBundleStatus{}}
+	bundles, err := zoneSKR(fqdn)
+	if err != nil {
+		return out, err
+	}
+	current := -1
+	for i := range bundles {
+		b := &bundles[i]
+		status := SKRBundleStatus{
+			Inception:  b.inception,
+			Expiration: b.expiration,
+			KSKTags:    []uint16{},
+			ZSKTags:    []uint16{},
+			CDS:        len(b.rrsets[dns.TypeCDS]) > 0,
+			Current:    b.inception <= now && now < b.expiration,
+		}
+		ksks, zsks := b.keys()
+		for _, key := range ksks {
+			status.KSKTags = append(status.KSKTags, key.KeyTag())
+		}
+		for _, key := range zsks {
+			status.ZSKTags = append(status.ZSKTags, key.KeyTag())
+		}
+		slices.Sort(status.KSKTags)
+		slices.Sort(status.ZSKTags)
+		if status.Current {
+			current = i
+		}
+		out.Bundles = append(out.Bundles, status)
+	}
+	if current >= 0 {
+		end := bundles[current].expiration
+		for _, b := range bundles[current+1:] {
+			if b.inception > end {
+				break
+			}
+			end = b.expiration
+		}
+		out.CoveredUntil = end
+		out.Remaining = end - now
+	}
+
+	if out.KSKPrivateKeys, err = StoredKSKPrivateKeys(fqdn); err != nil {
+		return out, err
+	}
+
+	if out.OfflineKSK {
+		switch {
+		case current < 0:
+			out.Warnings = append(out.Warnings, "no SKR bundle covers the current time; DNSKEY, CDS and CDNSKEY go unsigned")
+		case out.Remaining < warn:
+			out.Warnings = append(out.Warnings, fmt.Sprintf("the SKR runs out at %s, in %s", time.Unix(out.CoveredUntil, 0).UTC().Format(time.RFC3339), time.Duration(out.Remaining)*time.Second))
+		}
+		if len(out.KSKPrivateKeys) > 0 {
+			out.Warnings = append(out.Warnings, "KSK private keys are still stored: "+strings.Join(out.KSKPrivateKeys, ", "))
+		}
+	}
+	return out, nil
+}
+
+// PregenerateZSKs generates the ZSKs zone rolls to under policy p from now
+// until the given time, with the retire and remove times of the keys they
+// replace, so a key signing request can cover their DNSKEY RRsets. An
+// offline-KSK zone only rolls keys generated this way.
+func PregenerateZSKs(zone string, p config.KASPPolicy, now, until int64) (KASPResult, error) {
+	res := KASPResult{Changed: map[string]types.StoredKey{}}
+	p, err := NormalizeKASPPolicy(p)
+	if err != nil {
+		return res, err
+	}
+	zone = strings.TrimSuffix(dns.Fqdn(zone), ".")
+	keys, err := zoneKeys(zone)
+	if err != nil {
+		return res, err
+	}
+	if err := kaspAlgorithmConflict(zone, keys, p.Algorithm); err != nil {
+		return res, err
+	}
+
+	generate := func(publishAt, activateAt int64) (kaspKey, error) {
+		id, key, err := GenerateRolloverKey(zone, KeyRoleZSK, p.Algorithm, publishAt, activateAt)
+		if err != nil {
+			return kaspKey{}, fmt.Errorf("generate zsk for %s: %w", zone, err)
+		}
+		if state := keyStateAt(key, now); state != key.State {
+			key.State = state
+			if err := saveStoredKey(id, key); err != nil {
+				return kaspKey{}, err
+			}
+		}
+		res.Changed[id] = *key
+		res.Decisions = append(res.Decisions, fmt.Sprintf("generated zsk %s (tag %d) publishing at %d, active at %d", id, key.KeyTag, publishAt, activateAt))
+		return kaspKey{id: id, key: *key}, nil
+	}
+
+	live := inServiceKeys(keys, KeyRoleZSK, p.Algorithm)
+	if len(live) == 0 {
+		first, err := generate(now, now)
+		if err != nil {
+			return res, err
+		}
+		live = []kaspKey{first}
+	}
+	lifetime := roleLifetime(p, KeyRoleZSK)
+	lead, activateAfter := rolloverTiming(p, KeyRoleZSK)
+	for lifetime > 0 {
+		newest := live[len(live)-1]
+		due := max(now, newest.key.ActivateAt+lifetime-lead)
+		if due > until {
+			break
+		}
+		successor, err := generate(due, due+activateAfter)
+		if err != nil {
+			return res, err
+		}
+		retireAt, removeAt := predecessorTimes(p, KeyRoleZSK, successor.key.ActivateAt)
+		for _, old := range live {
+			old.key.RetireAt = retireAt
+			old.key.RemoveAt = removeAt
+			old.key.State = keyStateAt(&old.key, now)
+			if err := saveStoredKey(old.id, &old.key); err != nil {
+				return res, err
+			}
+			res.Changed[old.id] = old.key
+			res.Decisions = append(res.Decisions, fmt.Sprintf("retiring zsk %s at %d, removing at %d", old.id, retireAt, removeAt))
+		}
+		live = []kaspKey{successor}
+	}
+	return res, nil
+}
+
+// BuildKSR returns the key signing request for the DNSKEY RRsets of zone
+// from from until to. Slots start wherever a ZSK is published or removed and
+// last at most the DNSKEY signature validity less its refresh margin. Their
+// signatures are valid from the inception skew before the slot until the
+// refresh margin after it, so an RRset cached at a switch stays valid.
+func BuildKSR(zone string, from, to int64, ttl uint32) (KeySigningRequest, error) {
+	fqdn, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return KeySigningRequest{}, fmt.Errorf("FQDN sanitize check failed: %w", err)
+	}
+	if to <= from {
+		return KeySigningRequest{}, fmt.Errorf("KSR period ends before it starts")
+	}
+	if ttl == 0 {
+		ttl = 3600
+	}
+	keys, err := zoneKeys(fqdn)
+	if err != nil {
+		return KeySigningRequest{}, err
+	}
+	var zsks []kaspKey
+	bounds := []int64{from, to}
+	for _, k := range keys {
+		if !isZSK(&k.key) || k.key.State == KeyStateRemoved {
+			continue
+		}
+		zsks = append(zsks, k)
+		for _, at := range []int64{k.key.PublishAt, k.key.RemoveAt} {
+			if at > from && at < to {
+				bounds = append(bounds, at)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	policy := PolicyForRRType(dns.TypeDNSKEY)
+	refresh := int64(policy.RefreshBefore / time.Second)
+	skew := int64(policy.InceptionSkew / time.Second)
+	step := int64(policy.Validity/time.Second) - refresh
+
+	req := KeySigningRequest{Format: KSRFormat, Version: 1, Zone: fqdn, TTL: ttl, Slots: []KSRSlot{}}
+	for i := 0; i+1 < len(bounds); i++ {
+		for start := bounds[i]; start < bounds[i+1]; start += step {
+			end := min(start+step, bounds[i+1])
+			slot := KSRSlot{Inception: start, Expiration: end, SigInception: start - skew, SigExpiration: end + refresh, ZSKs: []string{}}
+			for j := range zsks {
+				if keyPublishedAt(&zsks[j].key, start) {
+					slot.ZSKs = append(slot.ZSKs, storedKeyToDNSKEY(fqdn, &zsks[j].key, ttl).String())
+				}
+			}
+			if len(slot.ZSKs) == 0 {
+				return KeySigningRequest{}, fmt.Errorf("zone %s has no ZSK published at %d", fqdn, start)
+			}
+			sort.Strings(slot.ZSKs)
+			req.Slots = append(req.Slots, slot)
+		}
+	}
+	return req, nil
+}
+
+// GenerateOfflineKSK generates a KSK for zone outside any server. It comes in
+// the private-key file format, to be kept offline and used by SignKSR.
+func GenerateOfflineKSK(zone, algorithmName string) (PrivateKeyImportFile, error) {
+	fqdn, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return PrivateKeyImportFile{}, fmt.Errorf("FQDN sanitize check failed: %w", err)
+	}
+	algorithm, ok := algorithmNumberByName(algorithmName)
+	if !ok {
+		return PrivateKeyImportFile{}, fmt.Errorf("unsupported algorithm %q", algorithmName)
+	}
+	priv, pub, err := generateKeyPair(algorithm)
+	if err != nil {
+		return PrivateKeyImportFile{}, err
+	}
+	var secret []byte
+	switch k := priv.(type) {
+	case *ecdsa.PrivateKey:
+		secret = k.D.FillBytes(make([]byte, (k.Curve.Params().BitSize+7)/8))
+	case ed25519.PrivateKey:
+		secret = k.Seed()
+	default:
+		return PrivateKeyImportFile{}, fmt.Errorf("offline KSKs of algorithm %s are not supported", algorithmName)
+	}
+	pubBytes, err := importedPublicKeyToDNS(pub, algorithm)
+	if err != nil {
+		return PrivateKeyImportFile{}, err
+	}
+	name := algorithmNameFromNumber(algorithm)
+	keyTag := ComputeKeyTag(257, 3, algorithm, pubBytes)
+	return PrivateKeyImportFile{
+		Format:  PrivateKeyImportFormat,
+		Version: 1,
+		Source:  "go53ctl",
+		Zone:    fqdn,
+		Keys: []PrivateKeyImportEntry{{
+			SourceKeyID:      fmt.Sprintf("ksk_%s_%s_%d", strings.TrimSuffix(fqdn, "."), name, keyTag),
+			Role:             KeyRoleKSK,
+			Flags:            257,
+			Algorithm:        name,
+			AlgorithmNumber:  algorithm,
+			KeyTag:           keyTag,
+			PrivateKeyFormat: "v1.2",
+			PrivateAlgorithm: fmt.Sprintf("%d (%s)", algorithm, name),
+			PrivateKey:       base64.StdEncoding.EncodeToString(secret),
+		}},
+	}, nil
+}
+
+// LoadOfflineKeys reads the KSKs of a private-key file for SignKSR.
+func LoadOfflineKeys(data []byte) ([]OfflineKey, error) {
+	var in PrivateKeyImportFile
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid key file JSON: %w", err)
+	}
+	if in.Format != PrivateKeyImportFormat || in.Version != 1 {
+		return nil, fmt.Errorf("unsupported key file format")
+	}
+	zone := dns.Fqdn(strings.ToLower(in.Zone))
+	if zone == "." || len(in.Keys) == 0 {
+		return nil, fmt.Errorf("key file requires zone and at least one key")
+	}
+	var out []OfflineKey
+	for _, entry := range in.Keys {
+		key, err := decodeImportEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if key.flags&dns.SEP == 0 {
+			return nil, fmt.Errorf("source key %q is not a KSK", entry.SourceKeyID)
+		}
+		signer, ok := key.priv.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("source key %q cannot sign", entry.SourceKeyID)
+		}
+		out = append(out, OfflineKey{
+			DNSKEY: &dns.DNSKEY{
+				Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+				Flags:     key.flags,
+				Protocol:  3,
+				Algorithm: key.number,
+				PublicKey: base64.StdEncoding.EncodeToString(key.pubBytes),
+			},
+			Signer: signer,
+		})
+	}
+	return out, nil
+}
+
+// SignKSR signs the DNSKEY RRset of every slot of req with the offline KSKs,
+// and with withCDS also CDS and CDNSKEY RRsets for them.
+func SignKSR(req KeySigningRequest, ksks []OfflineKey, withCDS bool) (SignedKeyResponse, error) {
+	if req.Format != KSRFormat || req.Version != 1 {
+		return SignedKeyResponse{}, fmt.Errorf("unsupported KSR format")
+	}
+	zone := dns.Fqdn(strings.ToLower(req.Zone))
+	if zone == "." || len(req.Slots) == 0 {
+		return SignedKeyResponse{}, fmt.Errorf("KSR requires zone and at least one slot")
+	}
+	if len(ksks) == 0 {
+		return SignedKeyResponse{}, fmt.Errorf("no KSK to sign with")
+	}
+	for _, k := range ksks {
+		if !strings.EqualFold(k.DNSKEY.Hdr.Name, zone) {
+			return SignedKeyResponse{}, fmt.Errorf("KSK %d is for %s, not %s", k.DNSKEY.KeyTag(), k.DNSKEY.Hdr.Name, zone)
+		}
+	}
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = 3600
+	}
+
+	out := SignedKeyResponse{Format: SKRFormat, Version: 1, Zone: zone}
+	for i, slot := range req.Slots {
+		if slot.Expiration <= slot.Inception || slot.SigInception > slot.Inception || slot.SigExpiration < slot.Expiration {
+			return SignedKeyResponse{}, fmt.Errorf("slot %d-%d has an invalid validity", slot.Inception, slot.Expiration)
+		}
+		if i > 0 && slot.Inception < req.Slots[i-1].Expiration {
+			return SignedKeyResponse{}, fmt.Errorf("slot %d-%d overlaps the one before", slot.Inception, slot.Expiration)
+		}
+		var dnskeys, cds, cdnskeys []dns.RR
+		for _, k := range ksks {
+			key := *k.DNSKEY
+			key.Hdr.Ttl = ttl
+			dnskeys = append(dnskeys, &key)
+			if withCDS {
+				ds := key.ToDS(dns.SHA256)
+				if ds == nil {
+					return SignedKeyResponse{}, fmt.Errorf("cannot build the DS of KSK %d", key.KeyTag())
+				}
+				cds = append(cds, ds.ToCDS())
+				cdnskeys = append(cdnskeys, key.ToCDNSKEY())
+			}
+		}
+		for _, line := range slot.ZSKs {
+			rr, err := dns.NewRR(line)
+			zsk, ok := rr.(*dns.DNSKEY)
+			if err != nil || !ok {
+				return SignedKeyResponse{}, fmt.Errorf("slot %d-%d: %q is not a DNSKEY record", slot.Inception, slot.Expiration, line)
+			}
+			if !strings.EqualFold(zsk.Hdr.Name, zone) || zsk.Flags&dns.SEP != 0 {
+				return SignedKeyResponse{}, fmt.Errorf("slot %d-%d: %q is not a ZSK of %s", slot.Inception, slot.Expiration, line, zone)
+			}
+			zsk.Hdr.Ttl = ttl
+			dnskeys = append(dnskeys, zsk)
+		}
+
+		bundle := SKRBundle{Inception: slot.Inception, Expiration: slot.Expiration}
+		for _, rrs := range [][]dns.RR{dnskeys, cds, cdnskeys} {
+			if len(rrs) == 0 {
+				continue
+			}
+			for _, rr := range rrs {
+				bundle.Records = append(bundle.Records, rr.String())
+			}
+			for _, k := range ksks {
+				sig := &dns.RRSIG{
+					Hdr:         dns.RR_Header{Name: zone, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: ttl},
+					TypeCovered: rrs[0].Header().Rrtype,
+					Algorithm:   k.DNSKEY.Algorithm,
+					Labels:      rrsigLabelCount(zone),
+					OrigTtl:     ttl,
+					Expiration:  uint32(slot.SigExpiration),
+					Inception:   uint32(slot.SigInception),
+					KeyTag:      k.DNSKEY.KeyTag(),
+					SignerName:  zone,
+				}
+				if err := sig.Sign(k.Signer, rrs); err != nil {
+					return SignedKeyResponse{}, fmt.Errorf("slot %d-%d: sign with KSK %d: %w", slot.Inception, slot.Expiration, sig.KeyTag, err)
+				}
+				bundle.Records = append(bundle.Records, sig.String())
+			}
+		}
+		out.Bundles = append(out.Bundles, bundle)
+	}
+	return out, nil
+}
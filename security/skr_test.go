@@ -0,0 +1,220 @@
+package security
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/zonemeta"
+)
+
+// setupOfflineKSKZone marks zone offline-KSK, pregenerates its ZSKs for
+// period seconds from now and returns the KSR together with a fresh offline
+// KSK file.
+func setupOfflineKSKZone(t *testing.T, zone string, now, period int64) (KeySigningRequest, []byte) {
+	t.Helper()
+	setupKASPTest(t)
+	if err := zonemeta.SaveSettings(zone, zonemeta.Settings{OfflineKSK: true}); err != nil {
+		t.Fatalf("SaveSettings: %v", err)
+	}
+	if _, err := PregenerateZSKs(zone, testKASPPolicy(), now, now+period); err != nil {
+		t.Fatalf("PregenerateZSKs: %v", err)
+	}
+	req, err := BuildKSR(zone, now, now+period, 20)
+	if err != nil {
+		t.Fatalf("BuildKSR: %v", err)
+	}
+	file, err := GenerateOfflineKSK(zone, "ECDSAP256SHA256")
+	if err != nil {
+		t.Fatalf("GenerateOfflineKSK: %v", err)
+	}
+	keyFile, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("marshal key file: %v", err)
+	}
+	return req, keyFile
+}
+
+func signTestKSR(t *testing.T, req KeySigningRequest, keyFile []byte) []byte {
+	t.Helper()
+	ksks, err := LoadOfflineKeys(keyFile)
+	if err != nil {
+		t.Fatalf("LoadOfflineKeys: %v", err)
+	}
+	skr, err := SignKSR(req, ksks, true)
+	if err != nil {
+		t.Fatalf("SignKSR: %v", err)
+	}
+	data, err := json.Marshal(skr)
+	if err != nil {
+		t.Fatalf("marshal SKR: %v", err)
+	}
+	return data
+}
+
+func TestSKRRoundTrip(t *testing.T) {
+	zone := "offline.test."
+	now := time.Now().Unix()
+	req, keyFile := setupOfflineKSKZone(t, zone, now, 3000)
+
+	if len(req.Slots) < 3 {
+		t.Fatalf("KSR has %d slots, want one per ZSK set over three rollovers", len(req.Slots))
+	}
+	for i, slot := range req.Slots {
+		if len(slot.ZSKs) == 0 {
+			t.Fatalf("slot %d has no ZSK", i)
+		}
+		if i > 0 && slot.Inception != req.Slots[i-1].Expiration {
+			t.Fatalf("slot %d starts at %d, want %d", i, slot.Inception, req.Slots[i-1].Expiration)
+		}
+	}
+
+	if _, err := ImportSKR(zone, signTestKSR(t, req, keyFile), now); err != nil {
+		t.Fatalf("ImportSKR: %v", err)
+	}
+
+	dnskeys, err := SKRRRset(zone, dns.TypeDNSKEY, now)
+	if err != nil {
+		t.Fatalf("SKRRRset: %v", err)
+	}
+	var ksk, zsk int
+	for _, rr := range dnskeys {
+		if rr.(*dns.DNSKEY).Flags&dns.SEP != 0 {
+			ksk++
+		} else {
+			zsk++
+		}
+	}
+	if ksk != 1 || zsk != len(req.Slots[0].ZSKs) {
+		t.Fatalf("DNSKEY RRset has %d KSKs and %d ZSKs, want 1 and %d", ksk, zsk, len(req.Slots[0].ZSKs))
+	}
+	sigs, err := SKRSignatures(zone, dns.TypeDNSKEY, now)
+	if err != nil || len(sigs) != 1 {
+		t.Fatalf("SKRSignatures = %v, %v, want one signature", sigs, err)
+	}
+	for _, rr := range dnskeys {
+		if key := rr.(*dns.DNSKEY); key.Flags&dns.SEP != 0 {
+			if err := sigs[0].Verify(key, dnskeys); err != nil {
+				t.Fatalf("served DNSKEY signature does not verify: %v", err)
+			}
+		}
+	}
+	if cds, err := GetCDS(zone); err != nil || len(cds) != 1 {
+		t.Fatalf("GetCDS = %v, %v, want the CDS of the SKR", cds, err)
+	}
+
+	status, err := SKRStatusOf(zone, now, 3600)
+	if err != nil {
+		t.Fatalf("SKRStatusOf: %v", err)
+	}
+	if status.CoveredUntil != now+3000 || len(status.Bundles) != len(req.Slots) {
+		t.Fatalf("status = %+v, want %d bundles covering until %d", status, len(req.Slots), now+3000)
+	}
+	if len(status.Warnings) != 1 || !strings.Contains(status.Warnings[0], "runs out") {
+		t.Fatalf("warnings = %v, want the SKR running out", status.Warnings)
+	}
+	if status, _ = SKRStatusOf(zone, now, 60); len(status.Warnings) != 0 {
+		t.Fatalf("warnings = %v, want none", status.Warnings)
+	}
+	if status, _ = SKRStatusOf(zone, now+3000, 60); len(status.Warnings) != 1 || !strings.Contains(status.Warnings[0], "no SKR bundle") {
+		t.Fatalf("warnings after the SKR = %v, want no bundle covering", status.Warnings)
+	}
+}
+
+func TestImportSKRReplacesOverlappingBundles(t *testing.T) {
+	zone := "offline.test."
+	now := time.Now().Unix()
+	req, keyFile := setupOfflineKSKZone(t, zone, now, 3000)
+	if _, err := ImportSKR(zone, signTestKSR(t, req, keyFile), now); err != nil {
+		t.Fatalf("ImportSKR: %v", err)
+	}
+
+	tail := req
+	tail.Slots = req.Slots[1:]
+	skr, err := ImportSKR(zone, signTestKSR(t, tail, keyFile), now)
+	if err != nil {
+		t.Fatalf("ImportSKR tail: %v", err)
+	}
+	if len(skr.Bundles) != len(req.Slots) {
+		t.Fatalf("stored %d bundles, want %d", len(skr.Bundles), len(req.Slots))
+	}
+
+	later := req.Slots[1].Inception
+	if skr, err = ImportSKR(zone, signTestKSR(t, tail, keyFile), later); err != nil {
+		t.Fatalf("ImportSKR later: %v", err)
+	}
+	if len(skr.Bundles) != len(tail.Slots) {
+		t.Fatalf("stored %d bundles, want the run-out bundle dropped", len(skr.Bundles))
+	}
+}
+
+func TestImportSKRRejectsBadBundles(t *testing.T) {
+	zone := "offline.test."
+	now := time.Now().Unix()
+	req, keyFile := setupOfflineKSKZone(t, zone, now, 3000)
+
+	noZSK := req
+	noZSK.Slots = append([]KSRSlot{}, req.Slots...)
+	noZSK.Slots[0].ZSKs = nil
+	if _, err := ImportSKR(zone, signTestKSR(t, noZSK, keyFile), now); err == nil || !strings.Contains(err.Error(), "does not match the ZSKs") {
+		t.Fatalf("ImportSKR without ZSKs = %v, want a ZSK mismatch", err)
+	}
+
+	var skr SignedKeyResponse
+	if err := json.Unmarshal(signTestKSR(t, req, keyFile), &skr); err != nil {
+		t.Fatalf("unmarshal SKR: %v", err)
+	}
+	records := skr.Bundles[0].Records
+	skr.Bundles[0].Records = append(records[:1:1], records[2:]...)
+	data, _ := json.Marshal(skr)
+	if _, err := ImportSKR(zone, data, now); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Fatalf("ImportSKR of a tampered bundle = %v, want a signature error", err)
+	}
+
+	if _, err := ImportSKR("other.test.", signTestKSR(t, req, keyFile), now); err == nil {
+		t.Fatal("ImportSKR accepted the SKR of another zone")
+	}
+}
+
+func TestOfflineKSKKeepsKSKMaterialOut(t *testing.T) {
+	zone := "offline.test."
+	now := time.Now().Unix()
+	_, keyFile := setupOfflineKSKZone(t, zone, now, 3000)
+
+	if _, _, err := GenerateRolloverKey(zone, KeyRoleKSK, "ECDSAP256SHA256", now, now); err == nil {
+		t.Fatal("GenerateRolloverKey stored a KSK of an offline-KSK zone")
+	}
+	if _, err := ImportPrivateKeys(keyFile); err == nil {
+		t.Fatal("ImportPrivateKeys stored a KSK of an offline-KSK zone")
+	}
+	if err := GenerateAndStoreAllKeys(zone); err != nil {
+		t.Fatalf("GenerateAndStoreAllKeys: %v", err)
+	}
+	if ksks := kaspKeysByRole(t, zone, KeyRoleKSK); len(ksks) != 0 {
+		t.Fatalf("offline-KSK zone has %d stored KSKs", len(ksks))
+	}
+}
+
+func TestStoredKSKPrivateKeysListsRemovedKSKs(t *testing.T) {
+	setupKASPTest(t)
+	now := time.Now().Unix()
+	kskID, key, err := GenerateRolloverKey("retired.test", KeyRoleKSK, "ECDSAP256SHA256", now-20, now-20)
+	if err != nil {
+		t.Fatalf("GenerateRolloverKey: %v", err)
+	}
+	key.State = KeyStateRemoved
+	key.RetireAt, key.RemoveAt = now-10, now-10
+	if err := saveStoredKey(kskID, key); err != nil {
+		t.Fatalf("saveStoredKey: %v", err)
+	}
+	if key.PrivatePEM == "" {
+		t.Fatal("generated KSK has no stored private key")
+	}
+
+	ids, err := StoredKSKPrivateKeys("retired.test.")
+	if err != nil || len(ids) != 1 || ids[0] != kskID {
+		t.Fatalf("StoredKSKPrivateKeys = %v, %v, want the removed KSK %s", ids, err, kskID)
+	}
+}
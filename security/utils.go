@@ -4,19 +4,53 @@ import (
 	"bytes"
 	"github.com/miekg/dns"
 	"sort"
+	"strings"
 )
 
 func rrCanonicalLess(a, b dns.RR) bool {
-	msgA := make([]byte, 4096)
-	msgB := make([]byte, 4096)
-
-	offA, errA := dns.PackRR(a, msgA, 0, nil, true)
-	offB, errB := dns.PackRR(b, msgB, 0, nil, true)
+	wireA, errA := canonicalWire(a)
+	wireB, errB := canonicalWire(b)
 	if errA != nil || errB != nil {
 		return false // or decide how to handle packing errors
 	}
 
-	return bytes.Compare(msgA[:offA], msgB[:offB]) < 0
+	return bytes.Compare(wireA, wireB) < 0
+}
+
+// canonicalWire packs rr in the canonical form of RFC 4034 section 6.2: owner
+// and the domain names embedded in the rdata of the listed types (as amended by
+// RFC 6840 section 5.1) are lowercased and nothing is compressed.
+func canonicalWire(rr dns.RR) ([]byte, error) {
+	c := dns.Copy(rr)
+	c.Header().Name = strings.ToLower(c.Header().Name)
+	switch v := c.(type) {
+	case *dns.NS:
+		v.Ns = strings.ToLower(v.Ns)
+	case *dns.CNAME:
+		v.Target = strings.ToLower(v.Target)
+	case *dns.SOA:
+		v.Ns = strings.ToLower(v.Ns)
+		v.Mbox = strings.ToLower(v.Mbox)
+	case *dns.PTR:
+		v.Ptr = strings.ToLower(v.Ptr)
+	case *dns.MX:
+		v.Mx = strings.ToLower(v.Mx)
+	case *dns.SRV:
+		v.Target = strings.ToLower(v.Target)
+	case *dns.DNAME:
+		v.Target = strings.ToLower(v.Target)
+	case *dns.NAPTR:
+		v.Replacement = strings.ToLower(v.Replacement)
+	case *dns.RRSIG:
+		v.SignerName = strings.ToLower(v.SignerName)
+	}
+
+	msg := make([]byte, dns.Len(c)+1)
+	off, err := dns.PackRR(c, msg, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return msg[:off], nil
 }
 
 func SortRRCanonically(rrs []dns.RR) {
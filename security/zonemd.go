@@ -0,0 +1,177 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: zonemd.go is part of the go53 authoritative DNS server.
+
+package security
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+)
+
+var (
+	// ErrZONEMDMismatch means no ZONEMD record of a supported scheme and hash
+	// matched the digest and serial of the zone.
+	ErrZONEMDMismatch = errors.New("ZONEMD digest does not match zone")
+	// ErrZONEMDUnsupported means the zone carries ZONEMD records, but none
+	// with a scheme and hash go53 can compute.
+	ErrZONEMDUnsupported = errors.New("no ZONEMD record with a supported scheme and hash")
+	// ErrZONEMDMissing means the zone has no apex ZONEMD record.
+	ErrZONEMDMissing = errors.New("no ZONEMD record for the SOA serial")
+)
+
+func zonemdHash(alg uint8) (hash.Hash, bool) {
+	switch alg {
+	case dns.ZoneMDHashAlgSHA384:
+		return sha512.New384(), true
+	case dns.ZoneMDHashAlgSHA512:
+		return sha512.New(), true
+	}
+	return nil, false
+}
+
+// ZONEMDDigest computes the SIMPLE scheme digest of RFC 8976 section 3.3 over
+// the zone with apex apex. rrs is the whole zone; the apex ZONEMD RRset and
+// the RRSIGs covering it are left out, duplicates are dropped and the rest is
+// hashed in canonical form and order. The digest is returned as uppercase hex.
+func ZONEMDDigest(apex string, rrs []dns.RR, alg uint8) (string, error) {
+	h, ok := zonemdHash(alg)
+	if !ok {
+		return "", fmt.Errorf("unsupported ZONEMD hash algorithm %d", alg)
+	}
+	apex = dns.CanonicalName(apex)
+
+	type entry struct {
+		name   string
+		rrtype uint16
+		rdata  []byte
+		wire   []byte
+	}
+	seen := make(map[string]bool, len(rrs))
+	entries := make([]entry, 0, len(rrs))
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if dns.CanonicalName(hdr.Name) == apex {
+			if hdr.Rrtype == dns.TypeZONEMD {
+				continue
+			}
+			if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == dns.TypeZONEMD {
+				continue
+			}
+		}
+		wire, err := canonicalWire(rr)
+		if err != nil {
+			return "", fmt.Errorf("pack %s: %w", rr.String(), err)
+		}
+		if seen[string(wire)] {
+			continue
+		}
+		seen[string(wire)] = true
+		entries = append(entries, entry{
+			name:   hdr.Name,
+			rrtype: hdr.Rrtype,
+			rdata:  rdataOf(wire),
+			wire:   wire,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if c := internal.CanonicalDNSSECNameCompare(a.name, b.name); c != 0 {
+			return c < 0
+		}
+		if a.rrtype != b.rrtype {
+			return a.rrtype < b.rrtype
+		}
+		return bytes.Compare(a.rdata, b.rdata) < 0
+	})
+	for _, e := range entries {
+		h.Write(e.wire)
+	}
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// rdataOf returns the rdata of an uncompressed wire RR: what follows the
+// owner name and the ten bytes of type, class, TTL and rdlength.
+func rdataOf(wire []byte) []byte {
+	off := 0
+	for off < len(wire) && wire[off] != 0 {
+		off += int(wire[off]) + 1
+	}
+	off += 1 + 10
+	if off > len(wire) {
+		return nil
+	}
+	return wire[off:]
+}
+
+// VerifyZONEMD checks the zone rrs against its apex ZONEMD records as a
+// recipient would (RFC 8976 section 4): a record only matches when it
+// carries the serial of the SOA, and one match of a supported scheme and hash
+// is enough.
+func VerifyZONEMD(apex string, rrs []dns.RR) error {
+	apex = dns.CanonicalName(apex)
+	var soa *dns.SOA
+	var zonemds []*dns.ZONEMD
+	for _, rr := range rrs {
+		if dns.CanonicalName(rr.Header().Name) != apex {
+			continue
+		}
+		switch v := rr.(type) {
+		case *dns.SOA:
+			soa = v
+		case *dns.ZONEMD:
+			zonemds = append(zonemds, v)
+		}
+	}
+	if soa == nil {
+		return errors.New("zone has no SOA")
+	}
+
+	supported := false
+	for _, z := range zonemds {
+		if _, ok := zonemdHash(z.Hash); !ok || z.Scheme != dns.ZoneMDSchemeSimple {
+			continue
+		}
+		supported = true
+		if z.Serial != soa.Serial {
+			continue
+		}
+		digest, err := ZONEMDDigest(apex, rrs, z.Hash)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(digest, z.Digest) {
+			return nil
+		}
+	}
+	switch {
+	case supported:
+		return ErrZONEMDMismatch
+	case len(zonemds) > 0:
+		return ErrZONEMDUnsupported
+	default:
+		return ErrZONEMDMissing
+	}
+}
@@ -0,0 +1,82 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// rfc8976SimpleZone is the example of RFC 8976 appendix A.1.
+const rfc8976SimpleZone = `example.      86400  IN  SOA     ns1 admin 2018031900 1800 900 604800 86400
+example.      86400  IN  NS      ns1
+example.      86400  IN  NS      ns2
+example.      86400  IN  ZONEMD  2018031900 1 1 c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c
+ns1           3600   IN  A       203.0.113.63
+ns2           3600   IN  AAAA    2001:db8::63
+`
+
+func parseTestZone(t *testing.T, origin, text string) []dns.RR {
+	t.Helper()
+	var rrs []dns.RR
+	zp := dns.NewZoneParser(strings.NewReader(text), origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("parse zone: %v", err)
+	}
+	return rrs
+}
+
+func TestZONEMDDigestRFC8976Example(t *testing.T) {
+	rrs := parseTestZone(t, "example.", rfc8976SimpleZone)
+	digest, err := ZONEMDDigest("example.", rrs, dns.ZoneMDHashAlgSHA384)
+	if err != nil {
+		t.Fatalf("ZONEMDDigest: %v", err)
+	}
+	if want := strings.ToUpper("c68090d90a7aed716bc459f9340e3d7c1370d4d24b7e2fc3a1ddc0b9a87153b9a9713b3c9ae5cc27777f98b8e730044c"); digest != want {
+		t.Fatalf("digest = %s, want %s", digest, want)
+	}
+	if err := VerifyZONEMD("example.", rrs); err != nil {
+		t.Fatalf("VerifyZONEMD: %v", err)
+	}
+
+	// Order and duplicates, such as the closing SOA of an AXFR, do not matter.
+	shuffled := append([]dns.RR{rrs[len(rrs)-1]}, rrs...)
+	shuffled = append(shuffled, rrs[0])
+	if err := VerifyZONEMD("example.", shuffled); err != nil {
+		t.Fatalf("VerifyZONEMD with reordered records: %v", err)
+	}
+}
+
+func TestVerifyZONEMDFailures(t *testing.T) {
+	rrs := parseTestZone(t, "example.", rfc8976SimpleZone)
+
+	tampered := append([]dns.RR{}, rrs...)
+	tampered[4] = parseTestZone(t, "example.", "ns1 3600 IN A 203.0.113.64\n")[0]
+	if err := VerifyZONEMD("example.", tampered); !errors.Is(err, ErrZONEMDMismatch) {
+		t.Fatalf("tampered zone: err = %v, want mismatch", err)
+	}
+
+	stale := parseTestZone(t, "example.", strings.Replace(rfc8976SimpleZone, "admin 2018031900", "admin 2018031901", 1))
+	if err := VerifyZONEMD("example.", stale); !errors.Is(err, ErrZONEMDMismatch) {
+		t.Fatalf("serial mismatch: err = %v, want mismatch", err)
+	}
+
+	private := parseTestZone(t, "example.", strings.Replace(rfc8976SimpleZone, "2018031900 1 1 ", "2018031900 1 240 ", 1))
+	if err := VerifyZONEMD("example.", private); !errors.Is(err, ErrZONEMDUnsupported) {
+		t.Fatalf("private hash: err = %v, want unsupported", err)
+	}
+
+	var without []dns.RR
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeZONEMD {
+			without = append(without, rr)
+		}
+	}
+	if err := VerifyZONEMD("example.", without); !errors.Is(err, ErrZONEMDMissing) {
+		t.Fatalf("no ZONEMD: err = %v, want missing", err)
+	}
+}
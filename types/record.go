@@ -3,34 +3,39 @@ package types
 type RecordType string
 
 const (
-	TypeA         RecordType = "A"
-	TypeAAAA      RecordType = "AAAA"
-	TypeMX        RecordType = "MX"
-	TypeNS        RecordType = "NS"
-	TypeSOA       RecordType = "SOA"
-	TypeCNAME     RecordType = "CNAME"
-	TypeTXT       RecordType = "TXT"
-	TypeSRV       RecordType = "SRV"
-	TypePTR       RecordType = "PTR"
-	TypeCAA       RecordType = "CAA"
-	TypeDNSKEY    RecordType = "DNSKEY"
-	TypeCDNSKEY   RecordType = "CDNSKEY"
-	TypeRRSIG     RecordType = "RRSIG"
-	TypeNSEC      RecordType = "NSEC"
-	TypeNSEC3     RecordType = "NSEC3"
-	TypeNSECPARAM RecordType = "NSECPARAM"
-	TypeDS        RecordType = "DS"
-	TypeCDS       RecordType = "CDS"
-	TypeNAPTR     RecordType = "NAPTR"
-	TypeSPF       RecordType = "SPF"
-	TypeHTTPS     RecordType = "HTTPS"
-	TypeSVCB      RecordType = "SVCB"
-	TypeLOC       RecordType = "LOC"
-	TypeCERT      RecordType = "CERT"
-	TypeSSHFP     RecordType = "SSHFP"
-	TypeURI       RecordType = "URI"
-	TypeAPL       RecordType = "APL"
-	TypeDNAME     RecordType = "DNAME"
+	TypeA          RecordType = "A"
+	TypeAAAA       RecordType = "AAAA"
+	TypeMX         RecordType = "MX"
+	TypeNS         RecordType = "NS"
+	TypeSOA        RecordType = "SOA"
+	TypeCNAME      RecordType = "CNAME"
+	TypeTXT        RecordType = "TXT"
+	TypeSRV        RecordType = "SRV"
+	TypePTR        RecordType = "PTR"
+	TypeCAA        RecordType = "CAA"
+	TypeDNSKEY     RecordType = "DNSKEY"
+	TypeCDNSKEY    RecordType = "CDNSKEY"
+	TypeRRSIG      RecordType = "RRSIG"
+	TypeNSEC       RecordType = "NSEC"
+	TypeNSEC3      RecordType = "NSEC3"
+	TypeNSECPARAM  RecordType = "NSECPARAM"
+	TypeDS         RecordType = "DS"
+	TypeCDS        RecordType = "CDS"
+	TypeNAPTR      RecordType = "NAPTR"
+	TypeSPF        RecordType = "SPF"
+	TypeHTTPS      RecordType = "HTTPS"
+	TypeSVCB       RecordType = "SVCB"
+	TypeLOC        RecordType = "LOC"
+	TypeCERT       RecordType = "CERT"
+	TypeSSHFP      RecordType = "SSHFP"
+	TypeURI        RecordType = "URI"
+	TypeAPL        RecordType = "APL"
+	TypeDNAME      RecordType = "DNAME"
+	TypeTLSA       RecordType = "TLSA"
+	TypeSMIMEA     RecordType = "SMIMEA"
+	TypeOPENPGPKEY RecordType = "OPENPGPKEY"
+	TypeZONEMD     RecordType = "ZONEMD"
+	TypeALIAS      RecordType = "ALIAS"
 )
 
 type ARecord struct {
@@ -203,10 +208,12 @@ type SVCBRecord struct {
 }
 
 type LOCRecord struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Altitude  float64 `json:"altitude"`
-	Size      float64 `json:"size"`
+	Latitude  float64 `json:"latitude"`  // degrees, north positive
+	Longitude float64 `json:"longitude"` // degrees, east positive
+	Altitude  float64 `json:"altitude"`  // meters
+	Size      float64 `json:"size"`      // meters
+	HorizPre  float64 `json:"horiz_pre"` // meters
+	VertPre   float64 `json:"vert_pre"`  // meters
 	TTL       uint32  `json:"ttl"`
 }
 
@@ -239,35 +246,85 @@ type APLRecord struct {
 	TTL           uint32 `json:"ttl"`
 }
 
+type TLSARecord struct {
+	Usage        uint8  `json:"usage"`         // 0=PKIX-TA, 1=PKIX-EE, 2=DANE-TA, 3=DANE-EE
+	Selector     uint8  `json:"selector"`      // 0=Cert, 1=SPKI
+	MatchingType uint8  `json:"matching_type"` // 0=Full, 1=SHA2-256, 2=SHA2-512
+	Certificate  string `json:"certificate"`   // hex
+	TTL          uint32 `json:"ttl"`
+}
+
+type SMIMEARecord struct {
+	Usage        uint8  `json:"usage"`
+	Selector     uint8  `json:"selector"`
+	MatchingType uint8  `json:"matching_type"`
+	Certificate  string `json:"certificate"` // hex
+	TTL          uint32 `json:"ttl"`
+}
+
+type OPENPGPKEYRecord struct {
+	PublicKey string `json:"public_key"` // base64 transferable public key
+	TTL       uint32 `json:"ttl"`
+}
+
+type ZONEMDRecord struct {
+	Serial uint32 `json:"serial"` // SOA serial the digest was computed for
+	Scheme uint8  `json:"scheme"` // 1=SIMPLE
+	Hash   uint8  `json:"hash"`   // 1=SHA384, 2=SHA512
+	Digest string `json:"digest"` // hex
+	TTL    uint32 `json:"ttl"`
+}
+
+// ALIASRecord points the A and AAAA RRsets of its owner at another name. It is
+// never served itself: queries and transfers get the target's addresses.
+type ALIASRecord struct {
+	Target string `json:"target"`
+	TTL    uint32 `json:"ttl"`
+}
+
+// UnknownRecord holds the rdata of a record type go53 has no dedicated handler
+// for, in the opaque form of RFC 3597.
+type UnknownRecord struct {
+	Rdata string `json:"rdata"` // hex
+	TTL   uint32 `json:"ttl"`
+}
+
 type ZoneData struct {
-	A          map[string][]ARecord       `json:"a,omitempty"`
-	AAAA       map[string][]AAAARecord    `json:"aaaa,omitempty"`
-	MX         map[string][]MXRecord      `json:"mx,omitempty"`
-	SOA        *SOARecord                 `json:"soa,omitempty"` // Only one per zone
-	CNAME      map[string]CNAMERecord     `json:"cname,omitempty"`
-	NS         map[string][]NSRecord      `json:"ns,omitempty"`
-	SRV        map[string][]SRVRecord     `json:"srv,omitempty"`
-	TXT        map[string][]TXTRecord     `json:"txt,omitempty"`
-	PTR        map[string][]PTRRecord     `json:"ptr,omitempty"`
-	CAA        map[string][]CAARecord     `json:"caa,omitempty"`
-	DNSKEY     map[string][]DNSKEYRecord  `json:"dnskey,omitempty"`
-	CDNSKEY    map[string][]CDNSKEYRecord `json:"cdnskey,omitempty"`
-	RRSIG      map[string][]*RRSIGRecord  `json:"rrsig,omitempty"`
-	NSEC       map[string]NSECRecord      `json:"nsec,omitempty"`  // one per name
-	NSEC3      map[string]NSEC3Record     `json:"nsec3,omitempty"` // one per name
-	NSEC3PARAM *NSEC3ParamRecord          `json:"nsec3param,omitempty"`
-	DS         map[string][]DSRecord      `json:"ds,omitempty"`
-	CDS        map[string][]CDSRecord     `json:"cds,omitempty"`
-	NAPTR      map[string][]NAPTRRecord   `json:"naptr,omitempty"`
-	SPF        map[string]SPFRecord       `json:"spf,omitempty"`
-	HTTPS      map[string][]HTTPSRecord   `json:"https,omitempty"`
-	SVCB       map[string][]SVCBRecord    `json:"svcb,omitempty"`
-	LOC        map[string][]LOCRecord     `json:"loc,omitempty"`
-	CERT       map[string][]CERTRecord    `json:"cert,omitempty"`
-	SSHFP      map[string][]SSHFPRecord   `json:"sshfp,omitempty"`
-	URI        map[string][]URIRecord     `json:"uri,omitempty"`
-	APL        map[string][]APLRecord     `json:"apl,omitempty"`
-	DNAME      map[string]DNAMERecord     `json:"dname,omitempty"`
+	A          map[string][]ARecord          `json:"a,omitempty"`
+	AAAA       map[string][]AAAARecord       `json:"aaaa,omitempty"`
+	MX         map[string][]MXRecord         `json:"mx,omitempty"`
+	SOA        *SOARecord                    `json:"soa,omitempty"` // Only one per zone
+	CNAME      map[string]CNAMERecord        `json:"cname,omitempty"`
+	NS         map[string][]NSRecord         `json:"ns,omitempty"`
+	SRV        map[string][]SRVRecord        `json:"srv,omitempty"`
+	TXT        map[string][]TXTRecord        `json:"txt,omitempty"`
+	PTR        map[string][]PTRRecord        `json:"ptr,omitempty"`
+	CAA        map[string][]CAARecord        `json:"caa,omitempty"`
+	DNSKEY     map[string][]DNSKEYRecord     `json:"dnskey,omitempty"`
+	CDNSKEY    map[string][]CDNSKEYRecord    `json:"cdnskey,omitempty"`
+	RRSIG      map[string][]*RRSIGRecord     `json:"rrsig,omitempty"`
+	NSEC       map[string]NSECRecord         `json:"nsec,omitempty"`  // one per name
+	NSEC3      map[string]NSEC3Record        `json:"nsec3,omitempty"` // one per name
+	NSEC3PARAM *NSEC3ParamRecord             `json:"nsec3param,omitempty"`
+	DS         map[string][]DSRecord         `json:"ds,omitempty"`
+	CDS        map[string][]CDSRecord        `json:"cds,omitempty"`
+	NAPTR      map[string][]NAPTRRecord      `json:"naptr,omitempty"`
+	SPF        map[string]SPFRecord          `json:"spf,omitempty"`
+	HTTPS      map[string][]HTTPSRecord      `json:"https,omitempty"`
+	SVCB       map[string][]SVCBRecord       `json:"svcb,omitempty"`
+	LOC        map[string][]LOCRecord        `json:"loc,omitempty"`
+	CERT       map[string][]CERTRecord       `json:"cert,omitempty"`
+	SSHFP      map[string][]SSHFPRecord      `json:"sshfp,omitempty"`
+	URI        map[string][]URIRecord        `json:"uri,omitempty"`
+	APL        map[string][]APLRecord        `json:"apl,omitempty"`
+	DNAME      map[string]DNAMERecord        `json:"dname,omitempty"`
+	TLSA       map[string][]TLSARecord       `json:"tlsa,omitempty"`
+	SMIMEA     map[string][]SMIMEARecord     `json:"smimea,omitempty"`
+	OPENPGPKEY map[string][]OPENPGPKEYRecord `json:"openpgpkey,omitempty"`
+	ZONEMD     map[string][]ZONEMDRecord     `json:"zonemd,omitempty"`
+	// Unknown holds the records of every other type, keyed by type name
+	// ("HINFO", "TYPE65534") and then by owner.
+	Unknown map[string]map[string][]UnknownRecord `json:"unknown,omitempty"`
 }
 
 type StoredKey struct {
@@ -278,6 +335,9 @@ type StoredKey struct {
 	PrivatePEM string `json:"private_pem"` // PEM-encoded EC/RSA key
 	PublicKey  string `json:"public_key"`  // Optional: base64 DNSKEY string
 
+	Provider string `json:"provider,omitempty"` // signer holding the private key; "" = PrivatePEM
+	KeyRef   string `json:"key_ref,omitempty"`  // the key in that signer
+
 	State      string `json:"state,omitempty"`       // generated, published, active, retired, revoked, removed
 	CreatedAt  int64  `json:"created_at,omitempty"`  // Unix timestamp
 	PublishAt  int64  `json:"publish_at,omitempty"`  // DNSKEY may be published from this time
@@ -286,4 +346,7 @@ type StoredKey struct {
 	RemoveAt   int64  `json:"remove_at,omitempty"`   // DNSKEY may be removed from this time
 	RevokedAt  int64  `json:"revoked_at,omitempty"`  // RFC 5011 revoke publication time
 	Revoke     bool   `json:"revoke,omitempty"`      // publish DNSKEY with revoke bit set
+
+	ParentDSAt  int64  `json:"parent_ds_at,omitempty"`  // KSK: first seen in the parent's DS RRset
+	ParentDSTTL uint32 `json:"parent_ds_ttl,omitempty"` // KSK: TTL of that DS RRset
 }
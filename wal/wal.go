@@ -30,6 +30,10 @@ const (
 	KindConfig     = "config"
 	KindTSIGKey    = "tsig_key"
 	KindDNSSECKey  = "dnssec_key"
+	KindZoneMeta   = "zone_meta"
+	// View events carry the name of the split-horizon view in Key.
+	KindViewRecord = "view_record"
+	KindViewZone   = "view_zone"
 
 	OpUpsert = "upsert"
 	OpDelete = "delete"
@@ -9,9 +9,9 @@ import (
 	"net"
 )
 
-type ARecord struct{}
+type ARecord struct{ scope }
 
-func (ARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt ARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -42,13 +42,13 @@ func (ARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypeA), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypeA), key)
 
 	var currentList []types.ARecord
 	if found {
@@ -97,21 +97,21 @@ func (ARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		})
 	}
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeA), key, listToStore)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeA), key, listToStore)
 }
 
-func (ARecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt ARecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeA), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeA), name)
 	if !ok {
 		return nil, false
 	}
@@ -174,8 +174,8 @@ func (ARecord) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (ARecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt ARecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -184,12 +184,12 @@ func (ARecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeA), name)
 	}
 
 	targetIP, ok := value.(string)
@@ -197,7 +197,7 @@ func (ARecord) Delete(host string, value interface{}) error {
 		return fmt.Errorf("ARecord Delete: expected string IP, got %T", value)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeA), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeA), name)
 	if !found {
 		return nil
 	}
@@ -225,9 +225,9 @@ func (ARecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeA), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeA), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeA), name, filtered)
 }
 
 func (ARecord) Type() uint16 {
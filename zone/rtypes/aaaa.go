@@ -9,9 +9,9 @@ import (
 	"net"
 )
 
-type AAAARecord struct{}
+type AAAARecord struct{ scope }
 
-func (AAAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt AAAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -42,13 +42,13 @@ func (AAAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypeAAAA), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypeAAAA), key)
 
 	var currentList []types.AAAARecord
 	if found {
@@ -77,11 +77,11 @@ func (AAAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	currentList = append(currentList, types.AAAARecord{IP: ip, TTL: TTL})
-	return memStore.AddRecord(sanitizedZone, string(types.TypeAAAA), key, currentList)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeAAAA), key, currentList)
 }
 
-func (AAAARecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt AAAARecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -90,11 +90,11 @@ func (AAAARecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeAAAA), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeAAAA), name)
 	if !ok {
 		return nil, false
 	}
@@ -139,8 +139,8 @@ func (AAAARecord) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (AAAARecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt AAAARecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -148,12 +148,12 @@ func (AAAARecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeAAAA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeAAAA), name)
 	}
 
 	targetIP, ok := value.(string)
@@ -161,7 +161,7 @@ func (AAAARecord) Delete(host string, value interface{}) error {
 		return fmt.Errorf("AAAARecord Delete: expected string IP, got %T", value)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeAAAA), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeAAAA), name)
 	if !found {
 		return nil
 	}
@@ -192,9 +192,9 @@ func (AAAARecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeAAAA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeAAAA), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeAAAA), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeAAAA), name, filtered)
 }
 
 func (AAAARecord) Type() uint16 {
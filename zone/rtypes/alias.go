@@ -0,0 +1,73 @@
+package rtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+type ALIASRecord struct{ scope }
+
+// parseALIAS validates an ALIAS value. The target may be any name, in a local
+// zone or not, but not the owner itself.
+func parseALIAS(value interface{}, ttl *uint32) (types.ALIASRecord, error) {
+	m, err := recordObject("ALIASRecord", value)
+	if err != nil {
+		return types.ALIASRecord{}, err
+	}
+	target, err := domainField(m, "ALIASRecord", "target", true, "")
+	if err != nil {
+		return types.ALIASRecord{}, err
+	}
+	if target == "." {
+		return types.ALIASRecord{}, fmt.Errorf("ALIASRecord: field 'target' must not be the root")
+	}
+	return types.ALIASRecord{
+		Target: target,
+		TTL:    recordTTL(m, ttl),
+	}, nil
+}
+
+// Add sets the ALIAS of name, replacing an earlier target: like CNAME, an
+// owner has at most one.
+func (rt ALIASRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseALIAS(value, ttl)
+	if err != nil {
+		return err
+	}
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return errors.New("FQDN sanitize check failed")
+	}
+	if rt.store() == nil {
+		return errors.New("memory store not initialized")
+	}
+
+	key := normalizeRecordKey(sanitizedZone, name)
+	if strings.EqualFold(rec.Target, zoneOwner(sanitizedZone, key)) {
+		return fmt.Errorf("ALIASRecord: %s cannot point at itself", rec.Target)
+	}
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeALIAS), key, []types.ALIASRecord{rec})
+}
+
+// Lookup never answers: ALIAS records are not served, queries for A and AAAA
+// at the owner get the target's addresses instead (see ResolveALIAS).
+func (rt ALIASRecord) Lookup(host string) ([]dns.RR, bool) {
+	return nil, false
+}
+
+func (rt ALIASRecord) Delete(host string, value interface{}) error {
+	return deleteFromRRset[types.ALIASRecord](rt.scope, string(types.TypeALIAS), host, nil)
+}
+
+func (ALIASRecord) Type() uint16 {
+	return internal.TypeALIAS
+}
+
+func init() {
+	Register(ALIASRecord{})
+}
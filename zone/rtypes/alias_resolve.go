@@ -0,0 +1,362 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: alias_resolve.go is part of the go53 authoritative DNS server.
+
+package rtypes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TenforwardAB/slog"
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/memory"
+	"go53/types"
+)
+
+// maxALIASChain bounds the ALIAS and CNAME hops followed through local zones.
+const maxALIASChain = 8
+
+// aliasFailureTTL is how long a failed upstream lookup is remembered, so a
+// target whose resolvers are down does not hold up every query for it
+// (RFC 9520).
+const aliasFailureTTL = 5 * time.Second
+
+// ResolveALIAS answers an A or AAAA query for host from the ALIAS record of
+// host in mem (nil for the default zones). found reports whether host has an
+// ALIAS at all. Targets in locally hosted zones are read from the stores,
+// following further ALIAS and CNAME records; other targets are asked of the
+// alias.resolvers. The answer carries the owner host and the ALIAS TTL,
+// capped by the TTLs met on the way, which stays the same for as long as the
+// answer is cached. Whether the target had any qtype records is recorded in
+// the store for the NSEC type bitmaps.
+func ResolveALIAS(mem *memory.InMemoryZoneStore, host string, qtype uint16) (rrs []dns.RR, found bool, err error) {
+	sc := scope{mem: mem}
+	rec, ok := sc.aliasRecord(host)
+	if !ok {
+		return nil, false, nil
+	}
+	if qtype != dns.TypeA && qtype != dns.TypeAAAA {
+		return nil, true, nil
+	}
+	addrs, ttl, err := sc.resolveALIASTarget(rec.Target, qtype)
+	if err != nil {
+		return nil, true, err
+	}
+	if rec.TTL < ttl {
+		ttl = rec.TTL
+	}
+	for _, rr := range addrs {
+		cp := dns.Copy(rr)
+		cp.Header().Name = dns.Fqdn(host)
+		if cp.Header().Ttl > ttl {
+			cp.Header().Ttl = ttl
+		}
+		rrs = append(rrs, cp)
+	}
+	if zone, name, ok := sc.splitName(host); ok {
+		if sanitizedZone, err := internal.SanitizeFQDN(zone); err == nil {
+			sc.store().SetALIASFamily(sanitizedZone, name, qtype, len(rrs) > 0)
+		}
+	}
+	return rrs, true, nil
+}
+
+// TransferZone returns what a zone transfer of zone carries: the stored
+// records plus the A and AAAA records its ALIAS records resolve to at this
+// moment. An ALIAS whose target cannot be resolved is logged and left out.
+func TransferZone(mem *memory.InMemoryZoneStore, zone string) ([]dns.RR, error) {
+	if mem == nil {
+		return nil, errors.New("memory store not initialized")
+	}
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve first: what the targets resolve to decides the NSEC type
+	// bitmaps of the ALIAS owners.
+	var resolved []dns.RR
+	for name := range mem.ZoneRecordsSnapshot(sanitizedZone)[string(types.TypeALIAS)] {
+		host := zoneOwner(sanitizedZone, name)
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			addrs, _, err := ResolveALIAS(mem, host, qtype)
+			if err != nil {
+				slog.Warn("ALIAS %s left out of transfer of %s: %v", host, sanitizedZone, err)
+				continue
+			}
+			resolved = append(resolved, addrs...)
+		}
+	}
+	rrs, err := mem.GetZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	return append(rrs, resolved...), nil
+}
+
+// zoneOwner turns a storage key of zone back into an owner name.
+func zoneOwner(zone, name string) string {
+	if name == "@" {
+		return dns.Fqdn(zone)
+	}
+	return dns.Fqdn(name + "." + strings.TrimSuffix(zone, "."))
+}
+
+func (s scope) aliasRecord(host string) (types.ALIASRecord, bool) {
+	if s.store() == nil {
+		return types.ALIASRecord{}, false
+	}
+	zone, name, ok := s.splitName(host)
+	if !ok {
+		return types.ALIASRecord{}, false
+	}
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return types.ALIASRecord{}, false
+	}
+	_, _, raw, ok := s.store().GetRecord(sanitizedZone, string(types.TypeALIAS), name)
+	if !ok {
+		return types.ALIASRecord{}, false
+	}
+	recs := internal.DecodeRecords[types.ALIASRecord](raw)
+	if len(recs) == 0 {
+		return types.ALIASRecord{}, false
+	}
+	return recs[0], true
+}
+
+// localScopeFor returns the scope of the locally hosted zone name is served
+// from: s itself, or the default zones for a view store that does not hold
+// it. Names below a delegation are not local.
+func (s scope) localScopeFor(name string) (scope, bool) {
+	for _, candidate := range []scope{s, {}} {
+		mem := candidate.store()
+		if mem == nil {
+			continue
+		}
+		if _, _, ok := mem.AuthoritativeNameParts(name); !ok {
+			continue
+		}
+		if _, _, delegated := mem.DelegationFor(name); delegated {
+			return scope{}, false
+		}
+		return candidate, true
+	}
+	return scope{}, false
+}
+
+// resolveALIASTarget looks up the qtype addresses of target and returns them
+// with the smallest TTL of the records followed to reach them.
+func (s scope) resolveALIASTarget(target string, qtype uint16) ([]dns.RR, uint32, error) {
+	ttl := uint32(math.MaxUint32)
+	name := target
+	for depth := 0; depth < maxALIASChain; depth++ {
+		local, ok := s.localScopeFor(name)
+		if !ok {
+			rrs, err := resolveUpstream(name, qtype)
+			return rrs, ttl, err
+		}
+		if handler, ok := GetFor(local.mem, qtype); ok {
+			if rrs, ok := handler.Lookup(name); ok {
+				return rrs, ttl, nil
+			}
+		}
+		if rec, ok := local.aliasRecord(name); ok {
+			ttl = min(ttl, rec.TTL)
+			name = rec.Target
+			continue
+		}
+		if handler, ok := GetFor(local.mem, dns.TypeCNAME); ok {
+			if rrs, ok := handler.Lookup(name); ok {
+				if cname, ok := rrs[0].(*dns.CNAME); ok {
+					ttl = min(ttl, cname.Hdr.Ttl)
+					name = cname.Target
+					continue
+				}
+			}
+		}
+		// A local name without addresses: an empty, authoritative answer.
+		return nil, ttl, nil
+	}
+	return nil, ttl, fmt.Errorf("ALIAS chain from %s is longer than %d hops", target, maxALIASChain)
+}
+
+type aliasCacheEntry struct {
+	rrs     []dns.RR
+	err     error
+	expires time.Time
+}
+
+// aliasCache holds upstream answers for external ALIAS targets, positive and
+// negative, until their TTL runs out, and failed lookups for aliasFailureTTL.
+var aliasCache = struct {
+	sync.Mutex
+	entries map[string]aliasCacheEntry
+}{entries: map[string]aliasCacheEntry{}}
+
+// aliasExchange sends one query to an upstream resolver.
+var aliasExchange = func(m *dns.Msg, server string, timeout time.Duration) (*dns.Msg, error) {
+	c := &dns.Client{Net: "udp", Timeout: timeout}
+	resp, _, err := c.Exchange(m, server)
+	if err == nil && resp.Truncated {
+		c.Net = "tcp"
+		resp, _, err = c.Exchange(m, server)
+	}
+	return resp, err
+}
+
+// resolveUpstream answers name/qtype through the configured alias.resolvers,
+// trying them in order, and caches the answer for its TTL. Cached records
+// come back with the TTL they were cached for, so the answer and its
+// signature stay the same until it expires. When every resolver fails the
+// failure is cached for aliasFailureTTL.
+func resolveUpstream(name string, qtype uint16) ([]dns.RR, error) {
+	cfg := config.AppConfig.GetLive().ALIAS
+	name = dns.Fqdn(name)
+	key := strings.ToLower(name) + "|" + internal.TypeName(qtype)
+	now := time.Now()
+	if entry, ok := aliasCacheGet(key, now); ok {
+		return entry.rrs, entry.err
+	}
+	if len(cfg.Resolvers) == 0 {
+		return nil, fmt.Errorf("%s is not in a local zone and alias.resolvers is empty", name)
+	}
+
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = true
+	m.SetEdns0(1232, false)
+
+	var lastErr error
+	for _, server := range cfg.Resolvers {
+		resp, err := aliasExchange(m, server, timeout)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", server, err)
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+			lastErr = fmt.Errorf("%s answered %s", server, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		rrs, ttl := upstreamAddresses(resp, name, qtype)
+		aliasCachePut(key, aliasCacheEntry{rrs: rrs, expires: now.Add(time.Duration(ttl) * time.Second)}, cfg.CacheSize, now)
+		return rrs, nil
+	}
+	err := fmt.Errorf("resolve %s %s: %w", name, internal.TypeName(qtype), lastErr)
+	aliasCachePut(key, aliasCacheEntry{err: err, expires: now.Add(aliasFailureTTL)}, cfg.CacheSize, now)
+	return nil, err
+}
+
+// upstreamAddresses picks the qtype records of name from a resolver answer,
+// following CNAMEs in it, and the TTL to cache them for, which the returned
+// copies carry as well. An answer without
+// addresses is cached for its SOA negative TTL (RFC 2308 section 5), or not
+// at all when it carries no SOA.
+func upstreamAddresses(resp *dns.Msg, name string, qtype uint16) ([]dns.RR, uint32) {
+	ttl := uint32(math.MaxUint32)
+	current := name
+	for hop := 0; hop <= maxALIASChain; hop++ {
+		var rrs []dns.RR
+		next := ""
+		for _, rr := range resp.Answer {
+			hdr := rr.Header()
+			if !strings.EqualFold(hdr.Name, current) {
+				continue
+			}
+			switch {
+			case hdr.Rrtype == qtype:
+				rrs = append(rrs, rr)
+			case hdr.Rrtype == dns.TypeCNAME:
+				next = rr.(*dns.CNAME).Target
+				ttl = min(ttl, hdr.Ttl)
+			}
+		}
+		if len(rrs) > 0 {
+			for _, rr := range rrs {
+				ttl = min(ttl, rr.Header().Ttl)
+			}
+			out := make([]dns.RR, 0, len(rrs))
+			for _, rr := range rrs {
+				cp := dns.Copy(rr)
+				cp.Header().Ttl = ttl
+				out = append(out, cp)
+			}
+			return out, ttl
+		}
+		if next == "" {
+			break
+		}
+		current = next
+	}
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return nil, min(ttl, soa.Hdr.Ttl, soa.Minttl)
+		}
+	}
+	return nil, 0
+}
+
+func aliasCacheGet(key string, now time.Time) (aliasCacheEntry, bool) {
+	aliasCache.Lock()
+	defer aliasCache.Unlock()
+	entry, ok := aliasCache.entries[key]
+	if !ok {
+		return aliasCacheEntry{}, false
+	}
+	if !now.Before(entry.expires) {
+		delete(aliasCache.entries, key)
+		return aliasCacheEntry{}, false
+	}
+	out := make([]dns.RR, 0, len(entry.rrs))
+	for _, rr := range entry.rrs {
+		out = append(out, dns.Copy(rr))
+	}
+	entry.rrs = out
+	return entry, true
+}
+
+// aliasCachePut stores entry until it expires. A full cache first drops
+// expired entries, then an arbitrary one.
+func aliasCachePut(key string, entry aliasCacheEntry, size int, now time.Time) {
+	if !now.Before(entry.expires) || size <= 0 {
+		return
+	}
+	aliasCache.Lock()
+	defer aliasCache.Unlock()
+	if len(aliasCache.entries) >= size {
+		for k, entry := range aliasCache.entries {
+			if !now.Before(entry.expires) {
+				delete(aliasCache.entries, k)
+			}
+		}
+	}
+	for k := range aliasCache.entries {
+		if len(aliasCache.entries) < size {
+			break
+		}
+		delete(aliasCache.entries, k)
+	}
+	aliasCache.entries[key] = entry
+}
@@ -0,0 +1,341 @@
+package rtypes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go53/config"
+	"go53/internal"
+	"go53/security"
+)
+
+// stubALIASUpstream answers upstream ALIAS queries with answer and counts
+// them, until the test ends.
+func stubALIASUpstream(t *testing.T, answer func(q dns.Question) (*dns.Msg, error)) *int {
+	t.Helper()
+	calls := 0
+	prev := aliasExchange
+	aliasExchange = func(m *dns.Msg, server string, timeout time.Duration) (*dns.Msg, error) {
+		calls++
+		return answer(m.Question[0])
+	}
+	aliasCache.Lock()
+	aliasCache.entries = map[string]aliasCacheEntry{}
+	aliasCache.Unlock()
+	live := config.AppConfig.LiveForTest()
+	prevCfg := live.ALIAS
+	live.ALIAS = config.ALIASConfig{Resolvers: []string{"192.0.2.53:53"}, TimeoutMs: 100, CacheSize: 16}
+	t.Cleanup(func() {
+		aliasExchange = prev
+		config.AppConfig.LiveForTest().ALIAS = prevCfg
+	})
+	return &calls
+}
+
+func TestALIASRecordValidationAndCoexistence(t *testing.T) {
+	alias := mustRR(t, internal.TypeALIAS)
+	ttl := uint32(300)
+	if err := alias.Add("alias-val.test", "@", map[string]interface{}{"target": "lb.example.net."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	if err := alias.Add("alias-val.test", "@", map[string]interface{}{"target": "lb2.example.net."}, &ttl); err != nil {
+		t.Fatalf("replace ALIAS: %v", err)
+	}
+	if rec, ok := (scope{}).aliasRecord("alias-val.test."); !ok || rec.Target != "lb2.example.net." {
+		t.Fatalf("ALIAS = %+v, %v; want the replacement target", rec, ok)
+	}
+	if rrs, ok := alias.Lookup("alias-val.test."); ok || len(rrs) != 0 {
+		t.Fatalf("ALIAS must not be served, got %v", rrs)
+	}
+
+	for name, bad := range map[string]map[string]interface{}{
+		"missing": {},
+		"self":    {"target": "www.alias-val.test."},
+		"root":    {"target": "."},
+		"invalid": {"target": "bad..name"},
+	} {
+		if err := alias.Add("alias-val.test", "www", bad, &ttl); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if err := mustRR(t, dns.TypeA).Add("alias-val.test", "@", map[string]interface{}{"ip": "192.0.2.1"}, &ttl); err == nil {
+		t.Fatalf("A next to ALIAS was accepted")
+	}
+	if err := mustRR(t, dns.TypeAAAA).Add("alias-val.test", "v6", map[string]interface{}{"ip": "2001:db8::1"}, &ttl); err != nil {
+		t.Fatalf("add AAAA: %v", err)
+	}
+	if err := alias.Add("alias-val.test", "v6", map[string]interface{}{"target": "lb.example.net."}, &ttl); err == nil {
+		t.Fatalf("ALIAS next to AAAA was accepted")
+	}
+	if err := mustRR(t, dns.TypeCNAME).Add("alias-val.test", "c", map[string]interface{}{"target": "x.example.net."}, &ttl); err != nil {
+		t.Fatalf("add CNAME: %v", err)
+	}
+	if err := alias.Add("alias-val.test", "c", map[string]interface{}{"target": "lb.example.net."}, &ttl); err == nil {
+		t.Fatalf("ALIAS next to CNAME was accepted")
+	}
+
+	if err := alias.Delete("alias-val.test.", nil); err != nil {
+		t.Fatalf("delete ALIAS: %v", err)
+	}
+	if _, found, _ := ResolveALIAS(nil, "alias-val.test.", dns.TypeA); found {
+		t.Fatalf("ALIAS still present after delete")
+	}
+}
+
+func TestResolveALIASFromLocalZones(t *testing.T) {
+	ttl := uint32(600)
+	short := uint32(60)
+	if err := mustRR(t, dns.TypeA).Add("alias-local.test", "web", map[string]interface{}{"ip": "192.0.2.10"}, &ttl); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	if err := mustRR(t, dns.TypeA).Add("alias-other.test", "lb", map[string]interface{}{"ip": "192.0.2.20"}, &ttl); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	if err := mustRR(t, dns.TypeCNAME).Add("alias-other.test", "front", map[string]interface{}{"target": "lb.alias-other.test."}, &short); err != nil {
+		t.Fatalf("add CNAME: %v", err)
+	}
+	alias := mustRR(t, internal.TypeALIAS)
+	if err := alias.Add("alias-local.test", "@", map[string]interface{}{"target": "web.alias-local.test."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	if err := alias.Add("alias-local.test", "shop", map[string]interface{}{"target": "front.alias-other.test."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+
+	rrs, found, err := ResolveALIAS(nil, "alias-local.test.", dns.TypeA)
+	if err != nil || !found || len(rrs) != 1 {
+		t.Fatalf("apex ALIAS = %v, %v, %v", rrs, found, err)
+	}
+	if a := rrs[0].(*dns.A); a.Hdr.Name != "alias-local.test." || a.A.String() != "192.0.2.10" || a.Hdr.Ttl != 600 {
+		t.Fatalf("apex ALIAS answer = %s", a)
+	}
+
+	rrs, _, err = ResolveALIAS(nil, "shop.alias-local.test.", dns.TypeA)
+	if err != nil || len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "192.0.2.20" {
+		t.Fatalf("ALIAS through CNAME = %v, %v", rrs, err)
+	}
+	if rrs[0].Header().Ttl != short {
+		t.Fatalf("TTL = %d, want the CNAME TTL %d", rrs[0].Header().Ttl, short)
+	}
+
+	rrs, found, err = ResolveALIAS(nil, "alias-local.test.", dns.TypeAAAA)
+	if err != nil || !found || len(rrs) != 0 {
+		t.Fatalf("AAAA of a target without AAAA = %v, %v, %v; want an empty answer", rrs, found, err)
+	}
+
+	if err := alias.Add("alias-local.test", "loop1", map[string]interface{}{"target": "loop2.alias-local.test."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	if err := alias.Add("alias-local.test", "loop2", map[string]interface{}{"target": "loop1.alias-local.test."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	if _, _, err := ResolveALIAS(nil, "loop1.alias-local.test.", dns.TypeA); err == nil {
+		t.Fatalf("ALIAS loop resolved without error")
+	}
+}
+
+func TestResolveALIASUpstreamHonorsTTL(t *testing.T) {
+	calls := stubALIASUpstream(t, func(q dns.Question) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetQuestion(q.Name, q.Qtype)
+		switch {
+		case q.Name == "down.example.net.":
+			return nil, errors.New("timeout")
+		case q.Qtype == dns.TypeA:
+			cname, _ := dns.NewRR("lb.example.net. 30 IN CNAME edge.example.net.")
+			a, _ := dns.NewRR("edge.example.net. 120 IN A 198.51.100.7")
+			resp.Answer = []dns.RR{cname, a}
+		default:
+			soa, _ := dns.NewRR("example.net. 300 IN SOA ns.example.net. h.example.net. 1 2 3 4 45")
+			resp.Ns = []dns.RR{soa}
+		}
+		return resp, nil
+	})
+
+	ttl := uint32(3600)
+	alias := mustRR(t, internal.TypeALIAS)
+	if err := alias.Add("alias-ext.test", "@", map[string]interface{}{"target": "lb.example.net."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		rrs, _, err := ResolveALIAS(nil, "alias-ext.test.", dns.TypeA)
+		if err != nil || len(rrs) != 1 {
+			t.Fatalf("round %d: upstream ALIAS = %v, %v", i, rrs, err)
+		}
+		if a := rrs[0].(*dns.A); a.Hdr.Name != "alias-ext.test." || a.A.String() != "198.51.100.7" || a.Hdr.Ttl != 30 {
+			t.Fatalf("round %d: answer = %s, want owner renamed and the CNAME TTL", i, a)
+		}
+		if rrs, _, err := ResolveALIAS(nil, "alias-ext.test.", dns.TypeAAAA); err != nil || len(rrs) != 0 {
+			t.Fatalf("round %d: negative upstream answer = %v, %v", i, rrs, err)
+		}
+	}
+	if *calls != 2 {
+		t.Fatalf("upstream queried %d times, want one per type", *calls)
+	}
+
+	key := "lb.example.net.|A"
+	if _, ok := aliasCacheGet(key, time.Now().Add(29*time.Second)); !ok {
+		t.Fatalf("answer expired before its TTL")
+	}
+	if _, ok := aliasCacheGet(key, time.Now().Add(31*time.Second)); ok {
+		t.Fatalf("answer outlived its TTL")
+	}
+	if _, ok := aliasCacheGet("lb.example.net.|AAAA", time.Now().Add(46*time.Second)); ok {
+		t.Fatalf("negative answer outlived the SOA minimum")
+	}
+
+	if err := alias.Add("alias-ext.test", "down", map[string]interface{}{"target": "down.example.net."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, found, err := ResolveALIAS(nil, "down.alias-ext.test.", dns.TypeA); !found || err == nil {
+			t.Fatalf("failing upstream: found=%v err=%v", found, err)
+		}
+	}
+	if *calls != 3 {
+		t.Fatalf("upstream queried %d times, want the failure cached", *calls)
+	}
+	if _, ok := aliasCacheGet("down.example.net.|A", time.Now().Add(aliasFailureTTL+time.Second)); ok {
+		t.Fatalf("failure outlived aliasFailureTTL")
+	}
+}
+
+func TestALIASCacheIsBounded(t *testing.T) {
+	stubALIASUpstream(t, nil)
+	now := time.Now()
+	for i := 0; i < 40; i++ {
+		aliasCachePut(string(rune('a'+i)), aliasCacheEntry{expires: now.Add(time.Minute)}, 16, now)
+	}
+	aliasCache.Lock()
+	n := len(aliasCache.entries)
+	aliasCache.Unlock()
+	if n > 16 {
+		t.Fatalf("cache holds %d entries, limit 16", n)
+	}
+}
+
+func TestALIASTransferAndDNSSEC(t *testing.T) {
+	config.AppConfig.LiveForTest().DNSSECEnabled = true
+	config.AppConfig.LiveForTest().Mode = "primary"
+	t.Cleanup(func() { config.AppConfig.LiveForTest().DNSSECEnabled = false })
+
+	zone := "alias-sec.test"
+	ttl := uint32(3600)
+	if err := mustRR(t, dns.TypeSOA).Add(zone, zone, map[string]interface{}{
+		"ns": "ns1." + zone, "mbox": "hostmaster." + zone,
+		"refresh": float64(3600), "retry": float64(900), "expire": float64(1209600), "minimum": float64(300),
+	}, &ttl); err != nil {
+		t.Fatalf("add SOA: %v", err)
+	}
+	now := time.Now().Unix()
+	if _, _, err := security.GenerateRolloverKey(zone, "ksk", "ED25519", now-10, now-10); err != nil {
+		t.Fatalf("generate KSK: %v", err)
+	}
+	if _, _, err := security.GenerateRolloverKey(zone, "zsk", "ED25519", now-10, now-10); err != nil {
+		t.Fatalf("generate ZSK: %v", err)
+	}
+	if err := memStore.RefreshDNSSECKeyMaterial(zone); err != nil {
+		t.Fatalf("refresh DNSSEC key material: %v", err)
+	}
+	if err := mustRR(t, dns.TypeA).Add(zone, "web", map[string]interface{}{"ip": "192.0.2.80"}, &ttl); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	if err := mustRR(t, internal.TypeALIAS).Add(zone, "@", map[string]interface{}{"target": "web." + zone + "."}, &ttl); err != nil {
+		t.Fatalf("add ALIAS: %v", err)
+	}
+	memStore.WaitForSigning()
+
+	nsec, ok := mustRR(t, dns.TypeNSEC).Lookup(zone + ".")
+	if !ok {
+		t.Fatalf("apex NSEC missing")
+	}
+	bitmap := nsec[0].(*dns.NSEC).TypeBitMap
+	hasType := func(want uint16) bool {
+		for _, t := range bitmap {
+			if t == want {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasType(dns.TypeA) || !hasType(dns.TypeAAAA) || hasType(internal.TypeALIAS) {
+		t.Fatalf("apex NSEC types = %v, want A and AAAA instead of ALIAS", bitmap)
+	}
+
+	rrs, ok := mustRR(t, dns.TypeAXFR).Lookup(zone + ".")
+	if !ok {
+		t.Fatalf("AXFR lookup failed")
+	}
+	var apexA *dns.A
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == internal.TypeALIAS {
+			t.Fatalf("AXFR carries the ALIAS record: %s", rr)
+		}
+		if a, ok := rr.(*dns.A); ok && a.Hdr.Name == zone+"." {
+			apexA = a
+		}
+	}
+	if apexA == nil || apexA.A.String() != "192.0.2.80" {
+		t.Fatalf("AXFR apex A = %v, want the ALIAS target address", apexA)
+	}
+
+	// The transfer resolved the target, which has no AAAA: the apex NSEC
+	// stops listing it, so NODATA for AAAA validates.
+	memStore.WaitForSigning()
+	nsec, ok = mustRR(t, dns.TypeNSEC).Lookup(zone + ".")
+	if !ok {
+		t.Fatalf("apex NSEC missing after transfer")
+	}
+	bitmap = nsec[0].(*dns.NSEC).TypeBitMap
+	if !hasType(dns.TypeA) || hasType(dns.TypeAAAA) {
+		t.Fatalf("apex NSEC types = %v, want A without AAAA", bitmap)
+	}
+	nsecSigs, err := memStore.EnsureSignedRRSet(nsec)
+	if err != nil || len(nsecSigs) == 0 {
+		t.Fatalf("rebuilt apex NSEC not signed: %v, %v", nsecSigs, err)
+	}
+	nsecSig := nsecSigs[0].(*dns.RRSIG)
+	keys, _ := mustRR(t, dns.TypeDNSKEY).Lookup(zone + ".")
+	verified := false
+	for _, rr := range keys {
+		if key, ok := rr.(*dns.DNSKEY); ok && key.KeyTag() == nsecSig.KeyTag {
+			verified = nsecSig.Verify(key, nsec) == nil
+		}
+	}
+	if !verified {
+		t.Fatalf("rebuilt apex NSEC signature does not verify: %s", nsecSig)
+	}
+
+	answer, _, err := ResolveALIAS(nil, zone+".", dns.TypeA)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	sigs, err := memStore.EnsureSignedRRSet(answer)
+	if err != nil || len(sigs) == 0 {
+		t.Fatalf("sign ALIAS answer: %v, %v", sigs, err)
+	}
+	again, err := memStore.EnsureSignedRRSet(answer)
+	if err != nil || len(again) != len(sigs) || again[0].String() != sigs[0].String() {
+		t.Fatalf("ALIAS answer signatures were not reused")
+	}
+	if _, _, stored, ok := memStore.GetRecord(zone+".", "RRSIG", "A"); ok {
+		if byName, _ := stored.(map[string]any); byName["@"] != nil {
+			t.Fatalf("ALIAS answer signature was stored in the zone")
+		}
+	}
+
+	if err := mustRR(t, dns.TypeA).Delete("web."+zone+".", nil); err != nil {
+		t.Fatalf("delete A: %v", err)
+	}
+	if err := mustRR(t, dns.TypeA).Add(zone, "web", map[string]interface{}{"ip": "192.0.2.81"}, &ttl); err != nil {
+		t.Fatalf("add A: %v", err)
+	}
+	changed, _, _ := ResolveALIAS(nil, zone+".", dns.TypeA)
+	resigned, err := memStore.EnsureSignedRRSet(changed)
+	if err != nil || len(resigned) == 0 || resigned[0].String() == sigs[0].String() {
+		t.Fatalf("changed ALIAS answer kept its old signature")
+	}
+}
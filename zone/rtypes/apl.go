@@ -0,0 +1,84 @@
+package rtypes
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+// APLRecord stores one address prefix item per record (RFC 3123). All items of
+// an owner are served as a single APL RR.
+type APLRecord struct{ scope }
+
+func parseAPL(value interface{}, ttl *uint32) (types.APLRecord, error) {
+	m, err := recordObject("APLRecord", value)
+	if err != nil {
+		return types.APLRecord{}, err
+	}
+
+	family, err := uintField(m, "APLRecord", "address_family", 2, 0)
+	if err != nil {
+		return types.APLRecord{}, err
+	}
+	prefix, err := stringField(m, "APLRecord", "prefix", true, "")
+	if err != nil {
+		return types.APLRecord{}, err
+	}
+	negation := false
+	if raw, ok := m["negation"]; ok && raw != nil {
+		negation, ok = raw.(bool)
+		if !ok {
+			return types.APLRecord{}, fmt.Errorf("APLRecord: field 'negation' must be a boolean, got %T", raw)
+		}
+	}
+	p, err := internal.APLPrefix(uint16(family), prefix, negation)
+	if err != nil {
+		return types.APLRecord{}, fmt.Errorf("APLRecord: %w", err)
+	}
+
+	return types.APLRecord{
+		AddressFamily: internal.APLFamily(p),
+		Prefix:        p.Network.String(),
+		Negation:      negation,
+		TTL:           recordTTL(m, ttl),
+	}, nil
+}
+
+func sameAPL(a, b types.APLRecord) bool {
+	return a.AddressFamily == b.AddressFamily && a.Prefix == b.Prefix && a.Negation == b.Negation
+}
+
+func (rt APLRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseAPL(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeAPL), rec, sameAPL)
+}
+
+func (rt APLRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeAPL), host)
+}
+
+func (rt APLRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.APLRecord](rt.scope, string(types.TypeAPL), host, nil)
+	}
+	rec, err := parseAPL(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeAPL), host, func(r types.APLRecord) bool {
+		return sameAPL(r, rec)
+	})
+}
+
+func (APLRecord) Type() uint16 {
+	return dns.TypeAPL
+}
+
+func init() {
+	Register(APLRecord{})
+}
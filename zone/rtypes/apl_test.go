@@ -0,0 +1,78 @@
+package rtypes
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestAPLRecordServesOneRR(t *testing.T) {
+	rr, ok := Get(dns.TypeAPL)
+	if !ok {
+		t.Fatalf("APL record type not found")
+	}
+
+	for _, v := range []map[string]interface{}{
+		{"prefix": "192.0.2.10/24"},
+		{"prefix": "2001:db8::/32", "negation": true},
+	} {
+		if err := rr.Add("go53.test", "apl", v, nil); err != nil {
+			t.Fatalf("failed to add APL item %v: %v", v, err)
+		}
+	}
+	if err := rr.Add("go53.test", "apl", map[string]interface{}{"prefix": "192.0.2.0/24", "address_family": float64(2)}, nil); err == nil {
+		t.Fatalf("expected family mismatch error")
+	}
+
+	results, ok := rr.Lookup("apl.go53.test.")
+	if !ok || len(results) != 1 {
+		t.Fatalf("expected a single APL RR, got %v", results)
+	}
+	apl := results[0].(*dns.APL)
+	if len(apl.Prefixes) != 2 || apl.Prefixes[0].Network.String() != "192.0.2.0/24" || !apl.Prefixes[1].Negation {
+		t.Fatalf("unexpected APL record: %s", apl)
+	}
+
+	if err := rr.Delete("apl.go53.test.", map[string]interface{}{"prefix": "192.0.2.0/24"}); err != nil {
+		t.Fatalf("failed to delete APL item: %v", err)
+	}
+	results, _ = rr.Lookup("apl.go53.test.")
+	if len(results) != 1 || len(results[0].(*dns.APL).Prefixes) != 1 {
+		t.Fatalf("expected one remaining APL item, got %v", results)
+	}
+}
+
+func TestCERTSSHFPAndURIValidation(t *testing.T) {
+	cert, _ := Get(dns.TypeCERT)
+	if err := cert.Add("go53.test", "cert", map[string]interface{}{"type": "PKIX", "key_tag": float64(0), "algorithm": "RSASHA256", "cert": "MIIB"}, nil); err != nil {
+		t.Fatalf("valid CERT rejected: %v", err)
+	}
+	if results, ok := cert.Lookup("cert.go53.test."); !ok || results[0].(*dns.CERT).Type != dns.CertPKIX {
+		t.Fatalf("unexpected CERT lookup: %v", results)
+	}
+	if err := cert.Add("go53.test", "cert", map[string]interface{}{"type": "NOPE", "algorithm": float64(8), "cert": "MIIB"}, nil); err == nil {
+		t.Fatalf("expected unknown CERT type error")
+	}
+	if err := cert.Add("go53.test", "cert", map[string]interface{}{"type": float64(1), "algorithm": float64(8), "cert": "%%%"}, nil); err == nil {
+		t.Fatalf("expected CERT base64 error")
+	}
+
+	sshfp, _ := Get(dns.TypeSSHFP)
+	if err := sshfp.Add("go53.test", "host", map[string]interface{}{"algorithm": float64(4), "fingerprint_type": float64(2), "fingerprint": "a87f1b687ac0e57d2a081a2f282672334d90ed316d2b818ca9580ea384d92401"}, nil); err != nil {
+		t.Fatalf("valid SSHFP rejected: %v", err)
+	}
+	if err := sshfp.Add("go53.test", "host", map[string]interface{}{"algorithm": float64(4), "fingerprint_type": float64(1), "fingerprint": "a87f1b687ac0e57d2a081a2f282672334d90ed316d2b818ca9580ea384d92401"}, nil); err == nil {
+		t.Fatalf("expected SSHFP length error")
+	}
+	if err := sshfp.Add("go53.test", "host", map[string]interface{}{"algorithm": float64(5), "fingerprint_type": float64(2), "fingerprint": "00"}, nil); err == nil {
+		t.Fatalf("expected SSHFP algorithm error")
+	}
+
+	uri, _ := Get(dns.TypeURI)
+	if err := uri.Add("go53.test", "_http._tcp", map[string]interface{}{"priority": float64(10), "weight": float64(1), "target": "https://www.go53.test/"}, nil); err != nil {
+		t.Fatalf("valid URI rejected: %v", err)
+	}
+	if err := uri.Add("go53.test", "_http._tcp", map[string]interface{}{"priority": float64(10), "weight": float64(1), "target": "www.go53.test"}, nil); err == nil {
+		t.Fatalf("expected URI scheme error")
+	}
+}
@@ -2,14 +2,13 @@ package rtypes
 
 import (
 	"github.com/miekg/dns"
-	"go53/internal"
 	"go53/internal/errors"
 	"log"
 	"sort"
 	"strings"
 )
 
-type AXFRRecord struct{}
+type AXFRRecord struct{ scope }
 
 func (AXFRRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	return errors.NotImplemented("AXFRRecord.Add")
@@ -19,20 +18,20 @@ func (AXFRRecord) Delete(host string, value interface{}) error {
 	return errors.NotImplemented("AXFRRecord.Delete")
 }
 
-func (AXFRRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, _, ok := internal.SplitName(host)
+func (rt AXFRRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, _, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 
 	log.Println("AXFRRecord.Lookup", zone)
-	recs, err := memStore.GetZone(zone)
+	recs, err := TransferZone(rt.store(), zone)
 	log.Println("We have the recs: ", recs)
 	if err != nil || len(recs) == 0 {
 		return nil, false
 	}
 
-	recs = memStore.SignZoneTransferRRsets(recs)
+	recs = rt.store().SignZoneTransferRRsets(recs)
 
 	var soa dns.RR
 	var result []dns.RR
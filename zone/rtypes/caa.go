@@ -11,7 +11,7 @@ import (
 	"github.com/miekg/dns"
 )
 
-type CAARecord struct{}
+type CAARecord struct{ scope }
 
 // caaFromAny normalizes a stored CAA value (typed slice or JSON-decoded
 // freshly added records and records reloaded from disk behave identically.
@@ -44,7 +44,7 @@ func caaFromAny(val interface{}) []types.CAARecord {
 	return recs
 }
 
-func (CAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt CAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -91,13 +91,13 @@ func (CAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, existing, found := memStore.GetRecord(sanitizedZone, string(types.TypeCAA), key)
+	_, _, existing, found := rt.store().GetRecord(sanitizedZone, string(types.TypeCAA), key)
 
 	var currentList []types.CAARecord
 	if found {
@@ -117,12 +117,12 @@ func (CAARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL:   TTL,
 	})
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeCAA), key, currentList)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeCAA), key, currentList)
 }
 
-func (CAARecord) Lookup(name string) ([]dns.RR, bool) {
+func (rt CAARecord) Lookup(name string) ([]dns.RR, bool) {
 	slog.Crazy("[caa.go:Lookup] name: %s", name)
-	zone, label, ok := internal.SplitName(name)
+	zone, label, ok := rt.splitName(name)
 	if !ok {
 		return nil, false
 	}
@@ -130,11 +130,11 @@ func (CAARecord) Lookup(name string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeCAA), label)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeCAA), label)
 	if !ok {
 		return nil, false
 	}
@@ -159,8 +159,8 @@ func (CAARecord) Lookup(name string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (CAARecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt CAARecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -168,12 +168,12 @@ func (CAARecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeCAA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeCAA), name)
 	}
 
 	obj, ok := value.(map[string]interface{})
@@ -188,7 +188,7 @@ func (CAARecord) Delete(host string, value interface{}) error {
 	tag, _ := obj["tag"].(string)
 	val, _ := obj["value"].(string)
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeCAA), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeCAA), name)
 	if !found {
 		return nil
 	}
@@ -203,9 +203,9 @@ func (CAARecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeCAA), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeCAA), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeCAA), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeCAA), name, filtered)
 }
 
 func (CAARecord) Type() uint16 {
@@ -11,9 +11,9 @@ import (
 	"go53/types"
 )
 
-type CDNSKEYRecord struct{}
+type CDNSKEYRecord struct{ scope }
 
-func (CDNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt CDNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sz, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return fmt.Errorf("FQDN sanitize check failed: %w", err)
@@ -38,12 +38,12 @@ func (CDNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) erro
 		return err
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	var current []types.CDNSKEYRecord
-	_, _, existing, found := memStore.GetRecord(sz, string(types.TypeCDNSKEY), key)
+	_, _, existing, found := rt.store().GetRecord(sz, string(types.TypeCDNSKEY), key)
 	if found {
 		current = cdnskeyRecordsFromRaw(existing)
 	}
@@ -55,23 +55,27 @@ func (CDNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) erro
 	}
 
 	current = append(current, types.CDNSKEYRecord(rec))
-	return memStore.AddRecord(sz, string(types.TypeCDNSKEY), key, current)
+	return rt.store().AddRecord(sz, string(types.TypeCDNSKEY), key, current)
 }
 
-func (CDNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt CDNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sz, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
 	var records []types.CDNSKEYRecord
-	if _, _, val, ok := memStore.GetRecord(sz, string(types.TypeCDNSKEY), name); ok {
+	if _, _, val, ok := rt.store().GetRecord(sz, string(types.TypeCDNSKEY), name); ok {
 		records = append(records, cdnskeyRecordsFromRaw(val)...)
 	}
+	if name == "@" && security.OfflineKSK(sz) {
+		// Only the RRset the SKR signed can be served at the apex.
+		records = nil
+	}
 
 	var out []dns.RR
 	if name == "@" {
@@ -100,8 +104,8 @@ func (CDNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
 	return dedupeDNSKEYLike(out), len(out) > 0
 }
 
-func (CDNSKEYRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt CDNSKEYRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -109,11 +113,11 @@ func (CDNSKEYRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 	if value == nil {
-		return memStore.DeleteRecord(sz, string(types.TypeCDNSKEY), name)
+		return rt.store().DeleteRecord(sz, string(types.TypeCDNSKEY), name)
 	}
 
 	obj, ok := value.(map[string]interface{})
@@ -125,7 +129,7 @@ func (CDNSKEYRecord) Delete(host string, value interface{}) error {
 		return errors.New("CDNSKEYRecord Delete: invalid CDNSKEY structure")
 	}
 
-	_, _, existing, found := memStore.GetRecord(sz, string(types.TypeCDNSKEY), name)
+	_, _, existing, found := rt.store().GetRecord(sz, string(types.TypeCDNSKEY), name)
 	if !found {
 		return nil
 	}
@@ -138,9 +142,9 @@ func (CDNSKEYRecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(remaining) == 0 {
-		return memStore.DeleteRecord(sz, string(types.TypeCDNSKEY), name)
+		return rt.store().DeleteRecord(sz, string(types.TypeCDNSKEY), name)
 	}
-	return memStore.AddRecord(sz, string(types.TypeCDNSKEY), name, remaining)
+	return rt.store().AddRecord(sz, string(types.TypeCDNSKEY), name, remaining)
 }
 
 func (CDNSKEYRecord) Type() uint16 {
@@ -11,14 +11,14 @@ import (
 	"go53/types"
 )
 
-type CDSRecord struct{}
+type CDSRecord struct{ scope }
 
-func (CDSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt CDSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -54,7 +54,7 @@ func (CDSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	key := normalizeRecordKey(sanitizedZone, name)
 
 	var current []types.CDSRecord
-	_, _, existing, found := memStore.GetRecord(sanitizedZone, string(types.TypeCDS), key)
+	_, _, existing, found := rt.store().GetRecord(sanitizedZone, string(types.TypeCDS), key)
 	if found {
 		current = cdsRecordsFromRaw(existing)
 	}
@@ -73,21 +73,21 @@ func (CDSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	current = append(current, rec)
-	return memStore.AddRecord(sanitizedZone, string(types.TypeCDS), key, current)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeCDS), key, current)
 }
 
-func (CDSRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt CDSRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
 	var out []dns.RR
-	if _, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeCDS), name); found {
+	if _, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeCDS), name); found {
 		for _, rec := range cdsRecordsFromRaw(raw) {
 			out = append(out, &dns.CDS{DS: dns.DS{
 				Hdr: dns.RR_Header{
@@ -105,6 +105,10 @@ func (CDSRecord) Lookup(host string) ([]dns.RR, bool) {
 	}
 
 	if name == "@" {
+		if security.OfflineKSK(sanitizedZone) {
+			// Only the RRset the SKR signed can be served at the apex.
+			out = nil
+		}
 		if cdsList, err := security.GetCDS(sanitizedZone); err == nil {
 			for _, cds := range cdsList {
 				out = append(out, cds)
@@ -115,8 +119,8 @@ func (CDSRecord) Lookup(host string) ([]dns.RR, bool) {
 	return dedupeDSLike(out), len(out) > 0
 }
 
-func (CDSRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt CDSRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -124,11 +128,11 @@ func (CDSRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeCDS), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeCDS), name)
 	}
 	return errors.New("CDSRecord Delete only supports deleting the full RRSet")
 }
@@ -0,0 +1,106 @@
+package rtypes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+type CERTRecord struct{ scope }
+
+// parseCERT validates a CERT value (RFC 4398). Type and algorithm accept either
+// the number or the mnemonic ("PKIX", "PGP", "RSASHA256", ...), and cert must
+// be base64.
+func parseCERT(value interface{}, ttl *uint32) (types.CERTRecord, error) {
+	m, err := recordObject("CERTRecord", value)
+	if err != nil {
+		return types.CERTRecord{}, err
+	}
+
+	certType, err := mnemonicField(m, "type", 65535, func(s string) (uint64, bool) {
+		v, ok := dns.StringToCertType[s]
+		return uint64(v), ok
+	})
+	if err != nil {
+		return types.CERTRecord{}, err
+	}
+	keyTag, err := uintField(m, "CERTRecord", "key_tag", 65535, 0)
+	if err != nil {
+		return types.CERTRecord{}, err
+	}
+	algorithm, err := mnemonicField(m, "algorithm", 255, func(s string) (uint64, bool) {
+		v, ok := dns.StringToAlgorithm[s]
+		return uint64(v), ok
+	})
+	if err != nil {
+		return types.CERTRecord{}, err
+	}
+	cert, err := stringField(m, "CERTRecord", "cert", true, "")
+	if err != nil {
+		return types.CERTRecord{}, err
+	}
+	cert = strings.Join(strings.Fields(cert), "")
+	if decoded, err := base64.StdEncoding.DecodeString(cert); err != nil || len(decoded) == 0 {
+		return types.CERTRecord{}, fmt.Errorf("CERTRecord: field 'cert' must be non-empty base64")
+	}
+
+	return types.CERTRecord{
+		Type:      uint16(certType),
+		KeyTag:    uint16(keyTag),
+		Algorithm: uint8(algorithm),
+		Cert:      cert,
+		TTL:       recordTTL(m, ttl),
+	}, nil
+}
+
+// mnemonicField reads a CERT field given as a number or a mnemonic.
+func mnemonicField(m map[string]interface{}, field string, max uint64, lookup func(string) (uint64, bool)) (uint64, error) {
+	if s, ok := m[field].(string); ok {
+		v, ok := lookup(strings.ToUpper(strings.TrimSpace(s)))
+		if !ok {
+			return 0, fmt.Errorf("CERTRecord: unknown %s %q", field, s)
+		}
+		return v, nil
+	}
+	return uintField(m, "CERTRecord", field, max, -1)
+}
+
+func sameCERT(a, b types.CERTRecord) bool {
+	return a.Type == b.Type && a.KeyTag == b.KeyTag && a.Algorithm == b.Algorithm && a.Cert == b.Cert
+}
+
+func (rt CERTRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseCERT(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeCERT), rec, sameCERT)
+}
+
+func (rt CERTRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeCERT), host)
+}
+
+func (rt CERTRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.CERTRecord](rt.scope, string(types.TypeCERT), host, nil)
+	}
+	rec, err := parseCERT(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeCERT), host, func(r types.CERTRecord) bool {
+		return sameCERT(r, rec)
+	})
+}
+
+func (CERTRecord) Type() uint16 {
+	return dns.TypeCERT
+}
+
+func init() {
+	Register(CERTRecord{})
+}
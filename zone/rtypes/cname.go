@@ -26,9 +26,9 @@ import (
 	"go53/types"
 )
 
-type CNAMERecord struct{}
+type CNAMERecord struct{ scope }
 
-func (CNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt CNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -58,11 +58,11 @@ func (CNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
-	if exists, other := memory.HasOtherRecords(memStore, sanitizedZone, name, dns.TypeCNAME, GetRegistry()); exists {
+	if exists, other := memory.HasOtherRecords(rt.store(), sanitizedZone, name, dns.TypeCNAME, GetRegistry()); exists {
 		return fmt.Errorf("CNAME: other record of type %d exists", other)
 	}
 
@@ -70,11 +70,11 @@ func (CNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error
 		Target: sanitizedTarget,
 		TTL:    TTL,
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeCNAME), name, rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeCNAME), name, rec)
 }
 
-func (CNAMERecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt CNAMERecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -82,11 +82,11 @@ func (CNAMERecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeCNAME), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeCNAME), name)
 	if !ok {
 		return nil, false
 	}
@@ -124,8 +124,8 @@ func (CNAMERecord) Lookup(host string) ([]dns.RR, bool) {
 	}, true
 }
 
-func (CNAMERecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt CNAMERecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -133,12 +133,12 @@ func (CNAMERecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	// oavsett value, det finns bara en möjlig CNAME-post per namn
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeCNAME), name)
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeCNAME), name)
 }
 
 func (CNAMERecord) Type() uint16 {
@@ -9,14 +9,14 @@ import (
 	"go53/types"
 )
 
-type DNAMERecord struct{}
+type DNAMERecord struct{ scope }
 
-func (DNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt DNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -43,7 +43,7 @@ func (DNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
-	if _, _, _, found := memStore.GetRecord(sanitizedZone, string(types.TypeCNAME), key); found {
+	if _, _, _, found := rt.store().GetRecord(sanitizedZone, string(types.TypeCNAME), key); found {
 		return errors.New("DNAME cannot coexist with CNAME at the same owner")
 	}
 
@@ -51,20 +51,20 @@ func (DNAMERecord) Add(zone, name string, value interface{}, ttl *uint32) error
 		Target: sanitizedTarget,
 		TTL:    ttlVal,
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeDNAME), key, rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeDNAME), key, rec)
 }
 
-func (DNAMERecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt DNAMERecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeDNAME), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeDNAME), name)
 	if !ok {
 		return nil, false
 	}
@@ -98,8 +98,8 @@ func (DNAMERecord) Lookup(host string) ([]dns.RR, bool) {
 	}}, true
 }
 
-func (DNAMERecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt DNAMERecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -107,10 +107,10 @@ func (DNAMERecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeDNAME), name)
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeDNAME), name)
 }
 
 func (DNAMERecord) Type() uint16 {
@@ -12,9 +12,9 @@ import (
 	"time"
 )
 
-type DNSKEYRecord struct{}
+type DNSKEYRecord struct{ scope }
 
-func (DNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt DNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sz, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return fmt.Errorf("FQDN sanitize check failed: %w", err)
@@ -41,11 +41,11 @@ func (DNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error
 		return err
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
-	_, _, existing, found := memStore.GetRecord(sz, string(types.TypeDNSKEY), key)
+	_, _, existing, found := rt.store().GetRecord(sz, string(types.TypeDNSKEY), key)
 
 	var current []types.DNSKEYRecord
 	if found {
@@ -71,11 +71,11 @@ func (DNSKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error
 	}
 
 	current = append(current, rec)
-	return memStore.AddRecord(sz, string(types.TypeDNSKEY), key, current)
+	return rt.store().AddRecord(sz, string(types.TypeDNSKEY), key, current)
 }
 
-func (DNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt DNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -85,12 +85,26 @@ func (DNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
 		return nil, false
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
+	if name == "@" && security.OfflineKSK(sz) {
+		// The apex DNSKEY RRset of an offline-KSK zone is the one its SKR
+		// signed, exactly.
+		rrs, err := security.SKRRRset(sz, dns.TypeDNSKEY, time.Now().Unix())
+		if err != nil {
+			slog.Error("[dnskey.go:Lookup] %v", err)
+			return nil, false
+		}
+		for _, rr := range rrs {
+			rr.Header().Name = host
+		}
+		return rrs, len(rrs) > 0
+	}
+
 	var records []types.DNSKEYRecord
-	if _, _, val, ok := memStore.GetRecord(sz, string(types.TypeDNSKEY), name); ok {
+	if _, _, val, ok := rt.store().GetRecord(sz, string(types.TypeDNSKEY), name); ok {
 		records = dnskeyRecordsFromRaw(val)
 	}
 
@@ -134,8 +148,8 @@ func (DNSKEYRecord) Lookup(host string) ([]dns.RR, bool) {
 	return out, len(out) > 0
 }
 
-func (DNSKEYRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt DNSKEYRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -145,12 +159,12 @@ func (DNSKEYRecord) Delete(host string, value interface{}) error {
 		return errors.New("FQDN sanitize check failed")
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sz, string(types.TypeDNSKEY), name)
+		return rt.store().DeleteRecord(sz, string(types.TypeDNSKEY), name)
 	}
 
 	obj, ok := value.(map[string]interface{})
@@ -163,7 +177,7 @@ func (DNSKEYRecord) Delete(host string, value interface{}) error {
 		return errors.New("DNSKEYRecord Delete: invalid DNSKEY structure")
 	}
 
-	_, _, existing, found := memStore.GetRecord(sz, string(types.TypeDNSKEY), name)
+	_, _, existing, found := rt.store().GetRecord(sz, string(types.TypeDNSKEY), name)
 	if !found {
 		return nil
 	}
@@ -188,9 +202,9 @@ func (DNSKEYRecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(remaining) == 0 {
-		return memStore.DeleteRecord(sz, string(types.TypeDNSKEY), name)
+		return rt.store().DeleteRecord(sz, string(types.TypeDNSKEY), name)
 	}
-	return memStore.AddRecord(sz, string(types.TypeDNSKEY), name, remaining)
+	return rt.store().AddRecord(sz, string(types.TypeDNSKEY), name, remaining)
 }
 
 func (DNSKEYRecord) Type() uint16 {
@@ -10,14 +10,14 @@ import (
 	"go53/types"
 )
 
-type DSRecord struct{}
+type DSRecord struct{ scope }
 
-func (DSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt DSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -54,7 +54,7 @@ func (DSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	key := normalizeRecordKey(sanitizedZone, name)
 
 	var current []types.DSRecord
-	_, _, existing, found := memStore.GetRecord(sanitizedZone, string(types.TypeDS), key)
+	_, _, existing, found := rt.store().GetRecord(sanitizedZone, string(types.TypeDS), key)
 	if found {
 		current = dsRecordsFromRaw(existing)
 	}
@@ -73,20 +73,20 @@ func (DSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	current = append(current, rec)
-	return memStore.AddRecord(sanitizedZone, string(types.TypeDS), key, current)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeDS), key, current)
 }
 
-func (DSRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt DSRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeDS), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeDS), name)
 	if !found {
 		return nil, false
 	}
@@ -114,8 +114,8 @@ func (DSRecord) Lookup(host string) ([]dns.RR, bool) {
 	return out, true
 }
 
-func (DSRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt DSRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -123,11 +123,11 @@ func (DSRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeDS), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeDS), name)
 	}
 	return errors.New("DSRecord Delete only supports deleting the full RRSet")
 }
@@ -0,0 +1,45 @@
+package rtypes
+
+import (
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+// HTTPSRecord is the HTTP-specific form of SVCB (RFC 9460 section 9) and
+// shares its rdata and validation.
+type HTTPSRecord struct{ scope }
+
+func (rt HTTPSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	sb, err := parseServiceBinding("HTTPSRecord", value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeHTTPS), types.HTTPSRecord(sb), func(a, b types.HTTPSRecord) bool {
+		return sameServiceBinding(serviceBinding(a), serviceBinding(b))
+	})
+}
+
+func (rt HTTPSRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeHTTPS), host)
+}
+
+func (rt HTTPSRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.HTTPSRecord](rt.scope, string(types.TypeHTTPS), host, nil)
+	}
+	sb, err := parseServiceBinding("HTTPSRecord", value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeHTTPS), host, func(r types.HTTPSRecord) bool {
+		return sameServiceBinding(serviceBinding(r), sb)
+	})
+}
+
+func (HTTPSRecord) Type() uint16 {
+	return dns.TypeHTTPS
+}
+
+func init() {
+	Register(HTTPSRecord{})
+}
@@ -0,0 +1,98 @@
+package rtypes
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+type LOCRecord struct{ scope }
+
+// parseLOC validates a LOC value (RFC 1876). Latitude and longitude are
+// required decimal degrees; altitude defaults to 0 and the size and precision
+// fields to the RFC 1876 defaults of 1 m, 10 km and 10 m.
+func parseLOC(value interface{}, ttl *uint32) (types.LOCRecord, error) {
+	m, err := recordObject("LOCRecord", value)
+	if err != nil {
+		return types.LOCRecord{}, err
+	}
+
+	rec := types.LOCRecord{
+		Size:     internal.LOCDefaultSize,
+		HorizPre: internal.LOCDefaultHorizPre,
+		VertPre:  internal.LOCDefaultVertPre,
+		TTL:      recordTTL(m, ttl),
+	}
+	fields := []struct {
+		name     string
+		dst      *float64
+		required bool
+	}{
+		{"latitude", &rec.Latitude, true},
+		{"longitude", &rec.Longitude, true},
+		{"altitude", &rec.Altitude, false},
+		{"size", &rec.Size, false},
+		{"horiz_pre", &rec.HorizPre, false},
+		{"vert_pre", &rec.VertPre, false},
+	}
+	for _, f := range fields {
+		raw, ok := m[f.name]
+		if !ok || raw == nil {
+			if f.required {
+				return types.LOCRecord{}, fmt.Errorf("LOCRecord expects field '%s'", f.name)
+			}
+			continue
+		}
+		v, ok := raw.(float64)
+		if !ok {
+			return types.LOCRecord{}, fmt.Errorf("LOCRecord: field '%s' must be a number, got %T", f.name, raw)
+		}
+		*f.dst = v
+	}
+	if err := internal.ValidateLOC(rec); err != nil {
+		return types.LOCRecord{}, fmt.Errorf("LOCRecord: %w", err)
+	}
+	return rec, nil
+}
+
+// sameLOC compares the wire form, since several decimal inputs round to the
+// same thousandth of an arc second.
+func sameLOC(a, b types.LOCRecord) bool {
+	a.TTL, b.TTL = 0, 0
+	return dns.IsDuplicate(internal.LOCToRR(".", a), internal.LOCToRR(".", b))
+}
+
+func (rt LOCRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseLOC(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeLOC), rec, sameLOC)
+}
+
+func (rt LOCRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeLOC), host)
+}
+
+func (rt LOCRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.LOCRecord](rt.scope, string(types.TypeLOC), host, nil)
+	}
+	rec, err := parseLOC(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeLOC), host, func(r types.LOCRecord) bool {
+		return sameLOC(r, rec)
+	})
+}
+
+func (LOCRecord) Type() uint16 {
+	return dns.TypeLOC
+}
+
+func init() {
+	Register(LOCRecord{})
+}
@@ -0,0 +1,44 @@
+package rtypes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+)
+
+func TestLOCRecordDefaultsAndValidation(t *testing.T) {
+	rr, ok := Get(dns.TypeLOC)
+	if !ok {
+		t.Fatalf("LOC record type not found")
+	}
+
+	value := map[string]interface{}{"latitude": 59.3293, "longitude": 18.0686, "altitude": float64(28)}
+	if err := rr.Add("go53.test", "loc", value, nil); err != nil {
+		t.Fatalf("failed to add LOC record: %v", err)
+	}
+	results, ok := rr.Lookup("loc.go53.test.")
+	if !ok || len(results) != 1 {
+		t.Fatalf("expected one LOC record, got %v", results)
+	}
+	got := internal.LOCFromRR(results[0].(*dns.LOC))
+	if math.Abs(got.Latitude-59.3293) > 1e-6 || math.Abs(got.Longitude-18.0686) > 1e-6 || got.Altitude != 28 {
+		t.Fatalf("unexpected LOC coordinates: %+v", got)
+	}
+	if got.Size != internal.LOCDefaultSize || got.HorizPre != internal.LOCDefaultHorizPre || got.VertPre != internal.LOCDefaultVertPre {
+		t.Fatalf("LOC precision defaults not applied: %+v", got)
+	}
+
+	for name, bad := range map[string]map[string]interface{}{
+		"latitude":  {"latitude": float64(91), "longitude": float64(0)},
+		"longitude": {"latitude": float64(0), "longitude": float64(-181)},
+		"altitude":  {"latitude": float64(0), "longitude": float64(0), "altitude": float64(-100001)},
+		"size":      {"latitude": float64(0), "longitude": float64(0), "size": float64(1e9)},
+		"missing":   {"latitude": float64(0)},
+	} {
+		if err := rr.Add("go53.test", "loc-bad", bad, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
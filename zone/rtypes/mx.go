@@ -9,9 +9,9 @@ import (
 	"go53/types"
 )
 
-type MXRecord struct{}
+type MXRecord struct{ scope }
 
-func (MXRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt MXRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -50,13 +50,13 @@ func (MXRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypeMX), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypeMX), key)
 
 	var currentList []types.MXRecord
 	if found {
@@ -94,12 +94,12 @@ func (MXRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL:      TTL,
 	})
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeMX), key, currentList)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeMX), key, currentList)
 }
 
-func (MXRecord) Lookup(host string) ([]dns.RR, bool) {
+func (rt MXRecord) Lookup(host string) ([]dns.RR, bool) {
 	slog.Crazy("[mx.go:Lookup] host: %s", host)
-	zone, name, ok := internal.SplitName(host)
+	zone, name, ok := rt.splitName(host)
 	slog.Crazy("[mx.go:Lookup] Name: %s", name)
 	if !ok {
 		return nil, false
@@ -108,11 +108,11 @@ func (MXRecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeMX), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeMX), name)
 	if !ok {
 		return nil, false
 	}
@@ -158,8 +158,8 @@ func (MXRecord) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (MXRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt MXRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -167,12 +167,12 @@ func (MXRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeMX), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeMX), name)
 	}
 
 	obj, ok := value.(map[string]interface{})
@@ -194,7 +194,7 @@ func (MXRecord) Delete(host string, value interface{}) error {
 		priority = uint16(p)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeMX), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeMX), name)
 	if !found {
 		return nil
 	}
@@ -229,9 +229,9 @@ func (MXRecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeMX), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeMX), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeMX), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeMX), name, filtered)
 }
 
 func (MXRecord) Type() uint16 {
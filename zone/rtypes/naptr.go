@@ -0,0 +1,103 @@
+package rtypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+type NAPTRRecord struct{ scope }
+
+// parseNAPTR validates a NAPTR value (RFC 3403 section 4.1). Flags are limited
+// to A-Z and 0-9, and regexp and replacement are mutually exclusive.
+func parseNAPTR(value interface{}, ttl *uint32) (types.NAPTRRecord, error) {
+	m, err := recordObject("NAPTRRecord", value)
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+
+	order, err := uintField(m, "NAPTRRecord", "order", 65535, -1)
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	preference, err := uintField(m, "NAPTRRecord", "preference", 65535, -1)
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	flags, err := stringField(m, "NAPTRRecord", "flags", false, "")
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	for _, c := range flags {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return types.NAPTRRecord{}, fmt.Errorf("NAPTRRecord: field 'flags' must be alphanumeric, got %q", flags)
+		}
+	}
+	service, err := stringField(m, "NAPTRRecord", "service", false, "")
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	regexp, err := stringField(m, "NAPTRRecord", "regexp", false, "")
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	replacement, err := domainField(m, "NAPTRRecord", "replacement", false, ".")
+	if err != nil {
+		return types.NAPTRRecord{}, err
+	}
+	if regexp != "" && replacement != "." {
+		return types.NAPTRRecord{}, errors.New("NAPTRRecord: 'regexp' and 'replacement' are mutually exclusive")
+	}
+
+	return types.NAPTRRecord{
+		Order:       uint16(order),
+		Preference:  uint16(preference),
+		Flags:       strings.ToUpper(flags),
+		Service:     service,
+		Regexp:      regexp,
+		Replacement: replacement,
+		TTL:         recordTTL(m, ttl),
+	}, nil
+}
+
+func sameNAPTR(a, b types.NAPTRRecord) bool {
+	return a.Order == b.Order && a.Preference == b.Preference &&
+		strings.EqualFold(a.Flags, b.Flags) && strings.EqualFold(a.Service, b.Service) &&
+		a.Regexp == b.Regexp && strings.EqualFold(dns.Fqdn(a.Replacement), dns.Fqdn(b.Replacement))
+}
+
+func (rt NAPTRRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseNAPTR(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeNAPTR), rec, sameNAPTR)
+}
+
+func (rt NAPTRRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeNAPTR), host)
+}
+
+func (rt NAPTRRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.NAPTRRecord](rt.scope, string(types.TypeNAPTR), host, nil)
+	}
+	rec, err := parseNAPTR(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeNAPTR), host, func(r types.NAPTRRecord) bool {
+		return sameNAPTR(r, rec)
+	})
+}
+
+func (NAPTRRecord) Type() uint16 {
+	return dns.TypeNAPTR
+}
+
+func init() {
+	Register(NAPTRRecord{})
+}
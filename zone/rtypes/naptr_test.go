@@ -0,0 +1,64 @@
+package rtypes
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNAPTRRecordLifecycle(t *testing.T) {
+	rr, ok := Get(dns.TypeNAPTR)
+	if !ok {
+		t.Fatalf("NAPTR record type not found")
+	}
+
+	value := map[string]interface{}{
+		"order":       float64(100),
+		"preference":  float64(10),
+		"flags":       "u",
+		"service":     "E2U+sip",
+		"regexp":      "!^.*$!sip:info@go53.test!",
+		"replacement": ".",
+	}
+	if err := rr.Add("go53.test", "naptr", value, nil); err != nil {
+		t.Fatalf("failed to add NAPTR record: %v", err)
+	}
+	if err := rr.Add("go53.test", "naptr", value, nil); err != nil {
+		t.Fatalf("re-adding NAPTR record: %v", err)
+	}
+
+	fqdn := "naptr.go53.test."
+	results, ok := rr.Lookup(fqdn)
+	if !ok || len(results) != 1 {
+		t.Fatalf("expected one NAPTR record for %s, got %v", fqdn, results)
+	}
+	naptr := results[0].(*dns.NAPTR)
+	if naptr.Order != 100 || naptr.Preference != 10 || naptr.Flags != "U" || naptr.Service != "E2U+sip" || naptr.Replacement != "." {
+		t.Fatalf("unexpected NAPTR record: %s", naptr)
+	}
+
+	if err := rr.Delete(fqdn, value); err != nil {
+		t.Fatalf("failed to delete NAPTR record: %v", err)
+	}
+	if results, _ := rr.Lookup(fqdn); len(results) != 0 {
+		t.Fatalf("expected no NAPTR record after delete, got %v", results)
+	}
+}
+
+func TestNAPTRRecordValidation(t *testing.T) {
+	rr, _ := Get(dns.TypeNAPTR)
+	cases := map[string]map[string]interface{}{
+		"missing order": {"preference": float64(10)},
+		"bad flags":     {"order": float64(1), "preference": float64(1), "flags": "s!"},
+		"regexp and replacement": {
+			"order": float64(1), "preference": float64(1),
+			"regexp": "!^.*$!sip:a@b!", "replacement": "_sip._udp.go53.test.",
+		},
+		"bad replacement": {"order": float64(1), "preference": float64(1), "replacement": "a..b"},
+	}
+	for name, value := range cases {
+		if err := rr.Add("go53.test", "naptr-bad", value, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -9,9 +9,9 @@ import (
 	"github.com/miekg/dns"
 )
 
-type NSRecord struct{}
+type NSRecord struct{ scope }
 
-func (NSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt NSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -40,14 +40,14 @@ func (NSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
 	var current []types.NSRecord
-	_, _, existing, found := memStore.GetRecord(sanitizedZone, string(types.TypeNS), key)
+	_, _, existing, found := rt.store().GetRecord(sanitizedZone, string(types.TypeNS), key)
 	if found {
 		switch list := existing.(type) {
 		case []types.NSRecord:
@@ -82,11 +82,11 @@ func (NSRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL: TTL,
 	})
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeNS), key, current)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeNS), key, current)
 }
 
-func (NSRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -94,7 +94,7 @@ func (NSRecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
@@ -103,7 +103,7 @@ func (NSRecord) Lookup(host string) ([]dns.RR, bool) {
 		key = "@"
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeNS), key)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeNS), key)
 	if !ok {
 		return nil, false
 	}
@@ -147,8 +147,8 @@ func (NSRecord) Lookup(host string) ([]dns.RR, bool) {
 	return result, true
 }
 
-func (NSRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -156,7 +156,7 @@ func (NSRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -167,7 +167,7 @@ func (NSRecord) Delete(host string, value interface{}) error {
 
 	if value == nil {
 		// Ta bort hela NS-listan
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeNS), key)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeNS), key)
 	}
 
 	nsToRemove, ok := value.(string)
@@ -179,7 +179,7 @@ func (NSRecord) Delete(host string, value interface{}) error {
 		return fmt.Errorf("NSRecord Delete: invalid FQDN %q", nsToRemove)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeNS), key)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeNS), key)
 	if !found {
 		return nil
 	}
@@ -210,9 +210,9 @@ func (NSRecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeNS), key)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeNS), key)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeNS), key, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeNS), key, filtered)
 }
 
 func (NSRecord) Type() uint16 {
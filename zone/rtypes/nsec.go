@@ -9,9 +9,9 @@ import (
 	"sort"
 )
 
-type NSEC struct{}
+type NSEC struct{ scope }
 
-func (NSEC) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt NSEC) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -55,26 +55,26 @@ func (NSEC) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL:        ttlVal,
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeNSEC), key, rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeNSEC), key, rec)
 }
 
-func (NSEC) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSEC) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeNSEC), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeNSEC), name)
 	if !found {
 		return nil, false
 	}
@@ -102,7 +102,7 @@ func (NSEC) Lookup(host string) ([]dns.RR, bool) {
 
 	var bitmap []uint16
 	for _, t := range rec.Types {
-		if code, ok := dns.StringToType[t]; ok {
+		if code, ok := internal.TypeCode(t); ok {
 			bitmap = append(bitmap, code)
 		}
 	}
@@ -124,8 +124,8 @@ func (NSEC) Lookup(host string) ([]dns.RR, bool) {
 	}, true
 }
 
-func (NSEC) Delete(host string, _ interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSEC) Delete(host string, _ interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -133,11 +133,11 @@ func (NSEC) Delete(host string, _ interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeNSEC), name)
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeNSEC), name)
 }
 
 func (NSEC) Type() uint16 {
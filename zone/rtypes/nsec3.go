@@ -12,9 +12,9 @@ import (
 	"strings"
 )
 
-type NSEC3 struct{}
+type NSEC3 struct{ scope }
 
-func (NSEC3) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt NSEC3) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -105,26 +105,26 @@ func (NSEC3) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL:        ttlVal,
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	return memStore.AddRecord(sanitizedZone, string(types.TypeNSEC3), key, rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeNSEC3), key, rec)
 }
 
-func (NSEC3) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSEC3) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeNSEC3), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeNSEC3), name)
 	if !found {
 		return nil, false
 	}
@@ -169,7 +169,7 @@ func (NSEC3) Lookup(host string) ([]dns.RR, bool) {
 
 	var bitmap []uint16
 	for _, t := range rec.Types {
-		if code, ok := dns.StringToType[t]; ok {
+		if code, ok := internal.TypeCode(t); ok {
 			bitmap = append(bitmap, code)
 		}
 	}
@@ -197,8 +197,8 @@ func (NSEC3) Lookup(host string) ([]dns.RR, bool) {
 	}, true
 }
 
-func (NSEC3) Delete(host string, _ interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt NSEC3) Delete(host string, _ interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -206,11 +206,11 @@ func (NSEC3) Delete(host string, _ interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeNSEC3), name)
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeNSEC3), name)
 }
 
 func (NSEC3) Type() uint16 {
@@ -8,14 +8,14 @@ import (
 	"go53/types"
 )
 
-type NSEC3PARAM struct{}
+type NSEC3PARAM struct{ scope }
 
-func (NSEC3PARAM) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt NSEC3PARAM) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -81,21 +81,21 @@ func (NSEC3PARAM) Add(zone, name string, value interface{}, ttl *uint32) error {
 		name = "@"
 	}
 
-	return memStore.AddRecord(sanitizedZone, "NSEC3PARAM", name, rec)
+	return rt.store().AddRecord(sanitizedZone, "NSEC3PARAM", name, rec)
 }
 
-func (NSEC3PARAM) Lookup(host string) ([]dns.RR, bool) {
-	zone, _, ok := internal.SplitName(host)
+func (rt NSEC3PARAM) Lookup(host string) ([]dns.RR, bool) {
+	zone, _, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
 	// name always "@"
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, "NSEC3PARAM", "@")
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, "NSEC3PARAM", "@")
 	if !found {
 		return nil, false
 	}
@@ -141,8 +141,8 @@ func (NSEC3PARAM) Lookup(host string) ([]dns.RR, bool) {
 	}, true
 }
 
-func (NSEC3PARAM) Delete(host string, _ interface{}) error {
-	zone, _, ok := internal.SplitName(host)
+func (rt NSEC3PARAM) Delete(host string, _ interface{}) error {
+	zone, _, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -150,10 +150,10 @@ func (NSEC3PARAM) Delete(host string, _ interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
-	return memStore.DeleteRecord(sanitizedZone, "NSEC3PARAM", "@")
+	return rt.store().DeleteRecord(sanitizedZone, "NSEC3PARAM", "@")
 }
 
 func (NSEC3PARAM) Type() uint16 {
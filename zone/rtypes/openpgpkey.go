@@ -0,0 +1,67 @@
+package rtypes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+type OPENPGPKEYRecord struct{ scope }
+
+// parseOPENPGPKEY validates an OPENPGPKEY value (RFC 7929): a base64 encoded
+// transferable public key.
+func parseOPENPGPKEY(value interface{}, ttl *uint32) (types.OPENPGPKEYRecord, error) {
+	m, err := recordObject("OPENPGPKEYRecord", value)
+	if err != nil {
+		return types.OPENPGPKEYRecord{}, err
+	}
+	key, err := stringField(m, "OPENPGPKEYRecord", "public_key", true, "")
+	if err != nil {
+		return types.OPENPGPKEYRecord{}, err
+	}
+	key = strings.Join(strings.Fields(key), "")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) == 0 {
+		return types.OPENPGPKEYRecord{}, fmt.Errorf("OPENPGPKEYRecord: field 'public_key' must be non-empty base64")
+	}
+	return types.OPENPGPKEYRecord{PublicKey: key, TTL: recordTTL(m, ttl)}, nil
+}
+
+func sameOPENPGPKEY(a, b types.OPENPGPKEYRecord) bool {
+	return a.PublicKey == b.PublicKey
+}
+
+func (rt OPENPGPKEYRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseOPENPGPKEY(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeOPENPGPKEY), rec, sameOPENPGPKEY)
+}
+
+func (rt OPENPGPKEYRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeOPENPGPKEY), host)
+}
+
+func (rt OPENPGPKEYRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.OPENPGPKEYRecord](rt.scope, string(types.TypeOPENPGPKEY), host, nil)
+	}
+	rec, err := parseOPENPGPKEY(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeOPENPGPKEY), host, func(r types.OPENPGPKEYRecord) bool {
+		return sameOPENPGPKEY(r, rec)
+	})
+}
+
+func (OPENPGPKEYRecord) Type() uint16 {
+	return dns.TypeOPENPGPKEY
+}
+
+func init() {
+	Register(OPENPGPKEYRecord{})
+}
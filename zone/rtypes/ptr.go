@@ -9,9 +9,9 @@ import (
 	"go53/types"
 )
 
-type PTR struct{}
+type PTR struct{ scope }
 
-func (PTR) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt PTR) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -37,13 +37,13 @@ func (PTR) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypePTR), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypePTR), key)
 
 	var currentList []types.PTRRecord
 	if found {
@@ -72,21 +72,21 @@ func (PTR) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	currentList = append(currentList, types.PTRRecord{Ptr: ptr, TTL: TTL})
-	return memStore.AddRecord(sanitizedZone, string(types.TypePTR), key, currentList)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypePTR), key, currentList)
 }
 
-func (PTR) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt PTR) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
 
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypePTR), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypePTR), name)
 	if !ok {
 		return nil, false
 	}
@@ -127,8 +127,8 @@ func (PTR) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (PTR) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt PTR) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -136,12 +136,12 @@ func (PTR) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypePTR), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypePTR), name)
 	}
 
 	ptrToRemove, ok := value.(string)
@@ -149,7 +149,7 @@ func (PTR) Delete(host string, value interface{}) error {
 		return fmt.Errorf("PTRRecord Delete: expected string ptr, got %T", value)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypePTR), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypePTR), name)
 	if !found {
 		return nil
 	}
@@ -180,9 +180,9 @@ func (PTR) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypePTR), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypePTR), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypePTR), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypePTR), name, filtered)
 }
 
 func (PTR) Type() uint16 {
@@ -0,0 +1,184 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: rrset.go is part of the go53 authoritative DNS server.
+
+package rtypes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+)
+
+// addToRRset appends rec to the typeName RRset of name unless a record with
+// the same rdata is already present. same compares rdata and ignores the TTL.
+func addToRRset[T any](rt scope, zone, name, typeName string, rec T, same func(a, b T) bool) error {
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return errors.New("FQDN sanitize check failed")
+	}
+	if rt.store() == nil {
+		return errors.New("memory store not initialized")
+	}
+
+	key := normalizeRecordKey(sanitizedZone, name)
+	var current []T
+	if _, _, existing, found := rt.store().GetRecord(sanitizedZone, typeName, key); found {
+		current = append(current, internal.DecodeRecords[T](existing)...)
+	}
+	for _, r := range current {
+		if same(r, rec) {
+			return nil
+		}
+	}
+	return rt.store().AddRecord(sanitizedZone, typeName, key, append(current, rec))
+}
+
+// lookupRRset builds the typeName RRset of host with the shared RR builder, so
+// queries, zone transfers and signing all see the same records.
+func lookupRRset(rt scope, typeName, host string) ([]dns.RR, bool) {
+	zone, label, ok := rt.splitName(host)
+	if !ok {
+		return nil, false
+	}
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return nil, false
+	}
+	if rt.store() == nil {
+		return nil, false
+	}
+
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, typeName, label)
+	if !ok {
+		return nil, false
+	}
+	builder, ok := internal.BuilderFor(typeName)
+	if !ok {
+		return nil, false
+	}
+	rrs := builder(host, val)
+	return rrs, len(rrs) > 0
+}
+
+// deleteFromRRset removes the records of the typeName RRset of host for which
+// match reports true, or the whole RRset when match is nil.
+func deleteFromRRset[T any](rt scope, typeName, host string, match func(T) bool) error {
+	zone, name, ok := rt.splitName(host)
+	if !ok {
+		return errors.New("invalid host format")
+	}
+	sanitizedZone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return errors.New("FQDN sanitize check failed")
+	}
+	if rt.store() == nil {
+		return errors.New("memory store not initialized")
+	}
+	if match == nil {
+		return rt.store().DeleteRecord(sanitizedZone, typeName, name)
+	}
+
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, typeName, name)
+	if !found {
+		return nil
+	}
+	var filtered []T
+	for _, r := range internal.DecodeRecords[T](raw) {
+		if !match(r) {
+			filtered = append(filtered, r)
+		}
+	}
+	if len(filtered) == 0 {
+		return rt.store().DeleteRecord(sanitizedZone, typeName, name)
+	}
+	return rt.store().AddRecord(sanitizedZone, typeName, name, filtered)
+}
+
+// recordObject returns value as the JSON object record handlers expect.
+func recordObject(typ string, value interface{}) (map[string]interface{}, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s expects value to be a JSON object, got %T", typ, value)
+	}
+	return m, nil
+}
+
+// uintField reads an unsigned integer field no larger than max. A missing
+// field yields def, or an error when def is negative.
+func uintField(m map[string]interface{}, typ, field string, max uint64, def int64) (uint64, error) {
+	raw, ok := m[field]
+	if !ok || raw == nil {
+		if def < 0 {
+			return 0, fmt.Errorf("%s expects field '%s'", typ, field)
+		}
+		return uint64(def), nil
+	}
+	f, ok := raw.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s: field '%s' must be a number, got %T", typ, field, raw)
+	}
+	if f < 0 || f > float64(max) || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%s: field '%s' must be an integer between 0 and %d", typ, field, max)
+	}
+	return uint64(f), nil
+}
+
+// stringField reads a string field. A missing field yields def, or an error
+// when required is set.
+func stringField(m map[string]interface{}, typ, field string, required bool, def string) (string, error) {
+	raw, ok := m[field]
+	if !ok || raw == nil {
+		if required {
+			return "", fmt.Errorf("%s expects field '%s'", typ, field)
+		}
+		return def, nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: field '%s' must be a string, got %T", typ, field, raw)
+	}
+	return s, nil
+}
+
+// domainField reads a domain name field and returns it fully qualified.
+func domainField(m map[string]interface{}, typ, field string, required bool, def string) (string, error) {
+	s, err := stringField(m, typ, field, required, def)
+	if err != nil {
+		return "", err
+	}
+	if s == "" {
+		s = "."
+	}
+	if _, ok := dns.IsDomainName(s); !ok {
+		return "", fmt.Errorf("%s: field '%s' is not a valid domain name: %q", typ, field, s)
+	}
+	return dns.Fqdn(s), nil
+}
+
+// recordTTL picks the record TTL: a "ttl" field in the value wins over the
+// request TTL, which wins over the 3600 second default.
+func recordTTL(m map[string]interface{}, ttl *uint32) uint32 {
+	if t, ok := m["ttl"].(float64); ok && t >= 0 {
+		return uint32(t)
+	}
+	if ttl != nil {
+		return *ttl
+	}
+	return 3600
+}
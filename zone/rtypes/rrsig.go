@@ -12,9 +12,9 @@ import (
 	"github.com/miekg/dns"
 )
 
-type RRSIGRecord struct{}
+type RRSIGRecord struct{ scope }
 
-func (RRSIGRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt RRSIGRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	valMap, ok := value.(map[string]interface{})
 	if !ok {
 		return fmt.Errorf("RRSIGRecord.Add: value is not a map[string]interface{}")
@@ -47,7 +47,7 @@ func (RRSIGRecord) Add(zone, name string, value interface{}, ttl *uint32) error
 	}
 
 	// Now: fetch existing map for the covered type ("DNSKEY", etc)
-	_, _, current, ok := memStore.GetRecord(zone, "RRSIG", rec.TypeCovered)
+	_, _, current, ok := rt.store().GetRecord(zone, "RRSIG", rec.TypeCovered)
 	var nameMap map[string][]map[string]interface{}
 	if ok {
 		// Defensive: attempt to type-assert and use if map[string][]map[string]interface{}
@@ -84,10 +84,10 @@ func (RRSIGRecord) Add(zone, name string, value interface{}, ttl *uint32) error
 
 	nameMap[name] = append(nameMap[name], recMap)
 
-	return memStore.AddRecord(zone, "RRSIG", rec.TypeCovered, nameMap)
+	return rt.store().AddRecord(zone, "RRSIG", rec.TypeCovered, nameMap)
 }
 
-func (RRSIGRecord) Lookup(host string) ([]dns.RR, bool) {
+func (rt RRSIGRecord) Lookup(host string) ([]dns.RR, bool) {
 	parts := strings.SplitN(host, "___", 2)
 	if len(parts) != 2 {
 		return nil, false
@@ -97,7 +97,7 @@ func (RRSIGRecord) Lookup(host string) ([]dns.RR, bool) {
 	rtypeStr := parts[1]
 	slog.Crazy("[handleRequest] rtype is: %s", rtypeStr)
 
-	zone, shortName, ok := internal.SplitName(name)
+	zone, shortName, ok := rt.splitName(name)
 	//shortName, _ = internal.SanitizeFQDN(shortName)
 	if !ok {
 		return nil, false
@@ -106,11 +106,11 @@ func (RRSIGRecord) Lookup(host string) ([]dns.RR, bool) {
 	slog.Crazy("[handleRequest] short name is: %s", shortName)
 
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
-	if err != nil || memStore == nil {
+	if err != nil || rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, "RRSIG", rtypeStr)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, "RRSIG", rtypeStr)
 	slog.Crazy("[handleRequest] val is: %#v", val)
 	if !ok {
 		return nil, false
@@ -174,7 +174,7 @@ func (RRSIGRecord) Lookup(host string) ([]dns.RR, bool) {
 			continue
 		}
 
-		covered, ok := dns.StringToType[sig.TypeCovered]
+		covered, ok := internal.TypeCode(sig.TypeCovered)
 		if !ok {
 			slog.Crazy("[handleRequest] unknown TypeCovered: %q", sig.TypeCovered)
 			continue
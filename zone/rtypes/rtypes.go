@@ -26,6 +26,7 @@
 package rtypes
 
 import (
+	"reflect"
 	"strings"
 
 	"github.com/miekg/dns"
@@ -72,15 +73,70 @@ func GetMemStore() *memory.InMemoryZoneStore {
 	return memStore
 }
 
+// scope selects the zone store a record type handler works on. Registered
+// handlers carry the zero scope and use the package-level store; GetFor binds
+// a copy to another store, such as the zones of a split-horizon view.
+type scope struct {
+	mem *memory.InMemoryZoneStore
+}
+
+func (s scope) store() *memory.InMemoryZoneStore {
+	if s.mem != nil {
+		return s.mem
+	}
+	return memStore
+}
+
+func (s *scope) bind(mem *memory.InMemoryZoneStore) {
+	s.mem = mem
+}
+
+// splitName splits host into zone and owner against the zones of the scoped
+// store. The package-level store keeps using the shared resolver.
+func (s scope) splitName(host string) (string, string, bool) {
+	if s.mem == nil {
+		return internal.SplitName(host)
+	}
+	zone, name, ok := s.mem.AuthoritativeNameParts(host)
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSuffix(zone, "."), name, true
+}
+
 func Register(rr RRType) {
 	registry[rr.Type()] = rr
 }
 
+// Get returns the handler for rrtype. Types without a registered handler are
+// served in RFC 3597 opaque form, except those internal.OpaqueType rules out.
 func Get(rrtype uint16) (RRType, bool) {
 	rr, ok := registry[rrtype]
+	if !ok && internal.OpaqueType(rrtype) {
+		return UnknownRecord{rrtype: rrtype}, true
+	}
 	return rr, ok
 }
 
+// GetFor returns the handler for rrtype working on mem instead of the
+// package-level store. A nil mem returns the registered handler unchanged.
+func GetFor(mem *memory.InMemoryZoneStore, rrtype uint16) (RRType, bool) {
+	rr, ok := Get(rrtype)
+	if !ok || mem == nil {
+		return rr, ok
+	}
+	bound := reflect.New(reflect.TypeOf(rr))
+	bound.Elem().Set(reflect.ValueOf(rr))
+	b, ok := bound.Interface().(interface {
+		bind(*memory.InMemoryZoneStore)
+	})
+	if !ok {
+		return rr, true
+	}
+	b.bind(mem)
+	return bound.Elem().Interface().(RRType), true
+}
+
 func GetRegistry() map[uint16]RRType {
 	return registry
 }
@@ -0,0 +1,45 @@
+package rtypes
+
+import (
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+// SMIMEARecord publishes S/MIME certificate associations (RFC 8162). The rdata
+// is the same as TLSA.
+type SMIMEARecord struct{ scope }
+
+func (rt SMIMEARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseDANE("SMIMEARecord", value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeSMIMEA), types.SMIMEARecord(rec), func(a, b types.SMIMEARecord) bool {
+		return sameDANE(types.TLSARecord(a), types.TLSARecord(b))
+	})
+}
+
+func (rt SMIMEARecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeSMIMEA), host)
+}
+
+func (rt SMIMEARecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.SMIMEARecord](rt.scope, string(types.TypeSMIMEA), host, nil)
+	}
+	rec, err := parseDANE("SMIMEARecord", value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeSMIMEA), host, func(r types.SMIMEARecord) bool {
+		return sameDANE(types.TLSARecord(r), rec)
+	})
+}
+
+func (SMIMEARecord) Type() uint16 {
+	return dns.TypeSMIMEA
+}
+
+func init() {
+	Register(SMIMEARecord{})
+}
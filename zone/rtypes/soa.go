@@ -5,26 +5,26 @@ import (
 	"fmt"
 	"github.com/TenforwardAB/slog"
 	"github.com/miekg/dns"
-	"go53/config"
 	"go53/internal"
 	"go53/types"
+	"go53/zonemeta"
 	"strings"
 )
 
-type SOARecord struct{}
+type SOARecord struct{ scope }
 
-func (SOARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt SOARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	slog.Crazy("[soa.go:Add] Adding SOA record for zone: ", zone, "with value: ", value)
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	var existing types.SOARecord
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeSOA), "@")
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeSOA), "@")
 	if found {
 		var ok bool
 		existing, ok = soaRecordFromRaw(raw)
@@ -82,7 +82,7 @@ func (SOARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 
 	slog.Crazy("[soa.go:Add] SOA record from cfg: ", rec)
 
-	if config.AppConfig.GetLive().Mode == "secondary" {
+	if zonemeta.IsSecondary(sanitizedZone) {
 		if v, ok := soaUint32(cfg, "serial"); ok {
 			rec.Serial = v
 		}
@@ -91,11 +91,11 @@ func (SOARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	slog.Crazy("[soa.go:Add] Actually Adding SOA record: ", rec)
-	return memStore.AddRecord(sanitizedZone, string(types.TypeSOA), "@", rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeSOA), "@", rec)
 }
 
-func (SOARecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt SOARecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -106,10 +106,10 @@ func (SOARecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeSOA), "@")
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeSOA), "@")
 	if !ok {
 		return nil, false
 	}
@@ -138,8 +138,8 @@ func (SOARecord) Lookup(host string) ([]dns.RR, bool) {
 	return []dns.RR{rr}, true
 }
 
-func (SOARecord) Delete(host string, value interface{}) error {
-	zone, _, ok := internal.SplitName(host)
+func (rt SOARecord) Delete(host string, value interface{}) error {
+	zone, _, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -147,12 +147,12 @@ func (SOARecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	// SOA only supports one record, delete unconditionally
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeSOA), "@")
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeSOA), "@")
 }
 
 func (SOARecord) Type() uint16 {
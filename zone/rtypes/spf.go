@@ -27,9 +27,9 @@ import (
 	"github.com/miekg/dns"
 )
 
-type SPFRecord struct{}
+type SPFRecord struct{ scope }
 
-func (SPFRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt SPFRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
@@ -54,7 +54,7 @@ func (SPFRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
@@ -63,11 +63,11 @@ func (SPFRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL:    TTL,
 		Chunks: internal.ChunkTXT(text),
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeSPF), name, rec)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeSPF), name, rec)
 }
 
-func (SPFRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt SPFRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -75,11 +75,11 @@ func (SPFRecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeSPF), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeSPF), name)
 	if !ok {
 		return nil, false
 	}
@@ -122,8 +122,8 @@ func (SPFRecord) Lookup(host string) ([]dns.RR, bool) {
 	}, true
 }
 
-func (SPFRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt SPFRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -131,12 +131,12 @@ func (SPFRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	// Only one SPF per name allowed
-	return memStore.DeleteRecord(sanitizedZone, string(types.TypeSPF), name)
+	return rt.store().DeleteRecord(sanitizedZone, string(types.TypeSPF), name)
 }
 
 func (SPFRecord) Type() uint16 {
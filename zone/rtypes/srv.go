@@ -8,9 +8,9 @@ import (
 	"go53/types"
 )
 
-type SRV struct{}
+type SRV struct{ scope }
 
-func (SRV) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt SRV) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -52,13 +52,13 @@ func (SRV) Add(zone, name string, value interface{}, ttl *uint32) error {
 		r.TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypeSRV), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypeSRV), key)
 
 	var current []types.SRVRecord
 	if found {
@@ -105,11 +105,11 @@ func (SRV) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	current = append(current, r)
-	return memStore.AddRecord(sanitizedZone, string(types.TypeSRV), key, current)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeSRV), key, current)
 }
 
-func (SRV) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt SRV) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -118,11 +118,11 @@ func (SRV) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeSRV), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeSRV), name)
 	if !ok {
 		return nil, false
 	}
@@ -177,8 +177,8 @@ func (SRV) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (SRV) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt SRV) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -186,12 +186,12 @@ func (SRV) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeSRV), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeSRV), name)
 	}
 
 	m, ok := value.(map[string]interface{})
@@ -202,7 +202,7 @@ func (SRV) Delete(host string, value interface{}) error {
 	target, _ := m["target"].(string)
 	port, _ := m["port"].(float64)
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeSRV), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeSRV), name)
 	if !found {
 		return nil
 	}
@@ -244,9 +244,9 @@ func (SRV) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeSRV), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeSRV), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeSRV), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeSRV), name, filtered)
 }
 
 func (SRV) Type() uint16 {
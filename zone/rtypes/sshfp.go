@@ -0,0 +1,99 @@
+package rtypes
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+type SSHFPRecord struct{ scope }
+
+// sshfpAlgorithms are the SSHFP key algorithms from the IANA registry:
+// RSA, DSA, ECDSA, Ed25519 and Ed448 (RFC 4255, 6594, 7479, 8709).
+var sshfpAlgorithms = map[uint64]bool{1: true, 2: true, 3: true, 4: true, 6: true}
+
+// sshfpDigestLen maps the fingerprint type to its digest length in bytes:
+// SHA-1 and SHA-256 (RFC 4255, 6594).
+var sshfpDigestLen = map[uint64]int{1: 20, 2: 32}
+
+func parseSSHFP(value interface{}, ttl *uint32) (types.SSHFPRecord, error) {
+	m, err := recordObject("SSHFPRecord", value)
+	if err != nil {
+		return types.SSHFPRecord{}, err
+	}
+
+	algorithm, err := uintField(m, "SSHFPRecord", "algorithm", 255, -1)
+	if err != nil {
+		return types.SSHFPRecord{}, err
+	}
+	if !sshfpAlgorithms[algorithm] {
+		return types.SSHFPRecord{}, fmt.Errorf("SSHFPRecord: unsupported algorithm %d", algorithm)
+	}
+	fpType, err := uintField(m, "SSHFPRecord", "fingerprint_type", 255, -1)
+	if err != nil {
+		return types.SSHFPRecord{}, err
+	}
+	wantLen, ok := sshfpDigestLen[fpType]
+	if !ok {
+		return types.SSHFPRecord{}, fmt.Errorf("SSHFPRecord: unsupported fingerprint_type %d", fpType)
+	}
+	fp, err := stringField(m, "SSHFPRecord", "fingerprint", true, "")
+	if err != nil {
+		return types.SSHFPRecord{}, err
+	}
+	fp = strings.ToUpper(strings.Join(strings.Fields(fp), ""))
+	decoded, err := hex.DecodeString(fp)
+	if err != nil {
+		return types.SSHFPRecord{}, fmt.Errorf("SSHFPRecord: field 'fingerprint' must be hex")
+	}
+	if len(decoded) != wantLen {
+		return types.SSHFPRecord{}, fmt.Errorf("SSHFPRecord: fingerprint_type %d expects a %d byte fingerprint, got %d", fpType, wantLen, len(decoded))
+	}
+
+	return types.SSHFPRecord{
+		Algorithm:       uint8(algorithm),
+		FingerprintType: uint8(fpType),
+		Fingerprint:     fp,
+		TTL:             recordTTL(m, ttl),
+	}, nil
+}
+
+func sameSSHFP(a, b types.SSHFPRecord) bool {
+	return a.Algorithm == b.Algorithm && a.FingerprintType == b.FingerprintType && strings.EqualFold(a.Fingerprint, b.Fingerprint)
+}
+
+func (rt SSHFPRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseSSHFP(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeSSHFP), rec, sameSSHFP)
+}
+
+func (rt SSHFPRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeSSHFP), host)
+}
+
+func (rt SSHFPRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.SSHFPRecord](rt.scope, string(types.TypeSSHFP), host, nil)
+	}
+	rec, err := parseSSHFP(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeSSHFP), host, func(r types.SSHFPRecord) bool {
+		return sameSSHFP(r, rec)
+	})
+}
+
+func (SSHFPRecord) Type() uint16 {
+	return dns.TypeSSHFP
+}
+
+func init() {
+	Register(SSHFPRecord{})
+}
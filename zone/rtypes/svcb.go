@@ -0,0 +1,138 @@
+package rtypes
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+type SVCBRecord struct{ scope }
+
+// serviceBinding is the rdata shared by SVCB and HTTPS records (RFC 9460).
+type serviceBinding struct {
+	Priority uint16
+	Target   string
+	Params   map[string]string
+	TTL      uint32
+}
+
+// parseServiceBinding validates an SVCB or HTTPS value. Params is an object of
+// SvcParamKey to value, where a value may be a string, a number or a list that
+// is joined with commas (alpn, ipv4hint, ipv6hint, mandatory). The params are
+// stored in their normalized presentation form so later reads need no parsing
+// beyond ParseSVCBParams.
+func parseServiceBinding(typ string, value interface{}, ttl *uint32) (serviceBinding, error) {
+	m, err := recordObject(typ, value)
+	if err != nil {
+		return serviceBinding{}, err
+	}
+
+	priority, err := uintField(m, typ, "priority", 65535, -1)
+	if err != nil {
+		return serviceBinding{}, err
+	}
+	target, err := domainField(m, typ, "target", false, ".")
+	if err != nil {
+		return serviceBinding{}, err
+	}
+
+	params := map[string]string{}
+	if raw, ok := m["params"]; ok && raw != nil {
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return serviceBinding{}, fmt.Errorf("%s: field 'params' must be an object, got %T", typ, raw)
+		}
+		for k, v := range obj {
+			s, err := svcParamString(v)
+			if err != nil {
+				return serviceBinding{}, fmt.Errorf("%s: param %q: %w", typ, k, err)
+			}
+			params[strings.ToLower(k)] = s
+		}
+	}
+	kvs, err := internal.ParseSVCBParams(params)
+	if err != nil {
+		return serviceBinding{}, fmt.Errorf("%s: %w", typ, err)
+	}
+	if priority == 0 && len(kvs) > 0 {
+		return serviceBinding{}, fmt.Errorf("%s: AliasMode (priority 0) must not carry SvcParams", typ)
+	}
+
+	return serviceBinding{
+		Priority: uint16(priority),
+		Target:   target,
+		Params:   internal.SVCBParamsToMap(kvs),
+		TTL:      recordTTL(m, ttl),
+	}, nil
+}
+
+func svcParamString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case bool:
+		if !val {
+			return "", errors.New("a flag parameter can only be true")
+		}
+		return "", nil
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("list items must be strings, got %T", item)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func sameServiceBinding(a, b serviceBinding) bool {
+	return a.Priority == b.Priority && strings.EqualFold(dns.Fqdn(a.Target), dns.Fqdn(b.Target)) && maps.Equal(a.Params, b.Params)
+}
+
+func (rt SVCBRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	sb, err := parseServiceBinding("SVCBRecord", value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeSVCB), types.SVCBRecord(sb), func(a, b types.SVCBRecord) bool {
+		return sameServiceBinding(serviceBinding(a), serviceBinding(b))
+	})
+}
+
+func (rt SVCBRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeSVCB), host)
+}
+
+func (rt SVCBRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.SVCBRecord](rt.scope, string(types.TypeSVCB), host, nil)
+	}
+	sb, err := parseServiceBinding("SVCBRecord", value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeSVCB), host, func(r types.SVCBRecord) bool {
+		return sameServiceBinding(serviceBinding(r), sb)
+	})
+}
+
+func (SVCBRecord) Type() uint16 {
+	return dns.TypeSVCB
+}
+
+func init() {
+	Register(SVCBRecord{})
+}
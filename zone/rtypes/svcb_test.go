@@ -0,0 +1,99 @@
+package rtypes
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSVCBAndHTTPSRecordLifecycle(t *testing.T) {
+	for _, rrtype := range []uint16{dns.TypeSVCB, dns.TypeHTTPS} {
+		rr, ok := Get(rrtype)
+		if !ok {
+			t.Fatalf("%s record type not found", dns.TypeToString[rrtype])
+		}
+
+		service := map[string]interface{}{
+			"priority": float64(1),
+			"target":   "svc.go53.test",
+			"params": map[string]interface{}{
+				"alpn":     []interface{}{"h2", "h3"},
+				"port":     float64(8443),
+				"ipv4hint": "192.0.2.1,192.0.2.2",
+				"ipv6hint": []interface{}{"2001:db8::1"},
+				"ech":      "AEn+DQBFKwAgACABWIHUGj4u+PIggYXcR5JF0gYk3dCRioBW8uJq9H4mKAAIAAEAAQABAANAEnB1YmxpYy50bHMtZWNoLmRldgAA",
+			},
+		}
+		alias := map[string]interface{}{"priority": float64(0), "target": "alias.go53.test."}
+		if err := rr.Add("go53.test", "svc", service, nil); err != nil {
+			t.Fatalf("%s: add ServiceMode: %v", dns.TypeToString[rrtype], err)
+		}
+		if err := rr.Add("go53.test", "svc", alias, nil); err != nil {
+			t.Fatalf("%s: add AliasMode: %v", dns.TypeToString[rrtype], err)
+		}
+
+		fqdn := "svc.go53.test."
+		results, ok := rr.Lookup(fqdn)
+		if !ok || len(results) != 2 {
+			t.Fatalf("%s: expected two records, got %v", dns.TypeToString[rrtype], results)
+		}
+		var svcb *dns.SVCB
+		switch v := results[0].(type) {
+		case *dns.SVCB:
+			svcb = v
+		case *dns.HTTPS:
+			svcb = &v.SVCB
+		}
+		if svcb == nil || svcb.Hdr.Rrtype != rrtype || svcb.Target != "svc.go53.test." || len(svcb.Value) != 5 {
+			t.Fatalf("%s: unexpected record %s", dns.TypeToString[rrtype], results[0])
+		}
+		for i := 1; i < len(svcb.Value); i++ {
+			if svcb.Value[i-1].Key() >= svcb.Value[i].Key() {
+				t.Fatalf("%s: SvcParams not in key order: %s", dns.TypeToString[rrtype], svcb)
+			}
+		}
+		if _, err := dns.NewRR(results[0].String()); err != nil {
+			t.Fatalf("%s: record does not reparse: %v", dns.TypeToString[rrtype], err)
+		}
+
+		if err := rr.Delete(fqdn, alias); err != nil {
+			t.Fatalf("%s: delete AliasMode: %v", dns.TypeToString[rrtype], err)
+		}
+		if results, _ := rr.Lookup(fqdn); len(results) != 1 {
+			t.Fatalf("%s: expected one record after delete, got %v", dns.TypeToString[rrtype], results)
+		}
+		if err := rr.Delete(fqdn, nil); err != nil {
+			t.Fatalf("%s: delete RRset: %v", dns.TypeToString[rrtype], err)
+		}
+	}
+}
+
+func TestSVCBRecordValidation(t *testing.T) {
+	rr, _ := Get(dns.TypeSVCB)
+	cases := map[string]map[string]interface{}{
+		"alias with params": {"priority": float64(0), "target": "a.go53.test.", "params": map[string]interface{}{"port": float64(443)}},
+		"bad port":          {"priority": float64(1), "params": map[string]interface{}{"port": float64(70000)}},
+		"v6 in ipv4hint":    {"priority": float64(1), "params": map[string]interface{}{"ipv4hint": "2001:db8::1"}},
+		"mandatory missing": {"priority": float64(1), "params": map[string]interface{}{"mandatory": "port", "alpn": "h2"}},
+		"mandatory self":    {"priority": float64(1), "params": map[string]interface{}{"mandatory": "mandatory"}},
+		"no-default-alpn":   {"priority": float64(1), "params": map[string]interface{}{"no-default-alpn": true}},
+		"bad ech":           {"priority": float64(1), "params": map[string]interface{}{"ech": "not base64!"}},
+		"unknown key":       {"priority": float64(1), "params": map[string]interface{}{"bogus": "x"}},
+		"missing priority":  {"target": "a.go53.test."},
+	}
+	for name, value := range cases {
+		if err := rr.Add("go53.test", "svc-bad", value, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	ok := map[string]interface{}{"priority": float64(1), "params": map[string]interface{}{
+		"mandatory":       []interface{}{"alpn"},
+		"alpn":            "h2",
+		"no-default-alpn": true,
+		"key65000":        "opaque",
+	}}
+	if err := rr.Add("go53.test", "svc-ok", ok, nil); err != nil {
+		t.Fatalf("valid SVCB rejected: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package rtypes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+type TLSARecord struct{ scope }
+
+// parseDANE validates the rdata shared by TLSA (RFC 6698) and SMIMEA
+// (RFC 8162). Usage, selector and matching_type accept a number or an RFC 7218
+// mnemonic such as "DANE-EE", "SPKI" or "SHA2-256".
+func parseDANE(typ string, value interface{}, ttl *uint32) (types.TLSARecord, error) {
+	m, err := recordObject(typ, value)
+	if err != nil {
+		return types.TLSARecord{}, err
+	}
+
+	var params [3]uint8
+	for i, f := range []struct {
+		name  string
+		codes map[string]uint8
+	}{
+		{"usage", internal.DANEUsages},
+		{"selector", internal.DANESelectors},
+		{"matching_type", internal.DANEMatchingTypes},
+	} {
+		raw, ok := m[f.name]
+		if !ok || raw == nil {
+			return types.TLSARecord{}, fmt.Errorf("%s expects field '%s'", typ, f.name)
+		}
+		params[i], err = internal.DANECode(f.codes, raw)
+		if err != nil {
+			return types.TLSARecord{}, fmt.Errorf("%s: field '%s': %w", typ, f.name, err)
+		}
+	}
+	data, err := stringField(m, typ, "certificate", true, "")
+	if err != nil {
+		return types.TLSARecord{}, err
+	}
+	data, err = internal.ValidateDANEData(params[2], data)
+	if err != nil {
+		return types.TLSARecord{}, fmt.Errorf("%s: %w", typ, err)
+	}
+
+	return types.TLSARecord{
+		Usage:        params[0],
+		Selector:     params[1],
+		MatchingType: params[2],
+		Certificate:  data,
+		TTL:          recordTTL(m, ttl),
+	}, nil
+}
+
+func sameDANE(a, b types.TLSARecord) bool {
+	return a.Usage == b.Usage && a.Selector == b.Selector && a.MatchingType == b.MatchingType && strings.EqualFold(a.Certificate, b.Certificate)
+}
+
+func (rt TLSARecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseDANE("TLSARecord", value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeTLSA), rec, sameDANE)
+}
+
+func (rt TLSARecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeTLSA), host)
+}
+
+func (rt TLSARecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.TLSARecord](rt.scope, string(types.TypeTLSA), host, nil)
+	}
+	rec, err := parseDANE("TLSARecord", value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeTLSA), host, func(r types.TLSARecord) bool {
+		return sameDANE(r, rec)
+	})
+}
+
+func (TLSARecord) Type() uint16 {
+	return dns.TypeTLSA
+}
+
+func init() {
+	Register(TLSARecord{})
+}
@@ -0,0 +1,56 @@
+package rtypes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDANERecordTypes(t *testing.T) {
+	digest := strings.Repeat("0c", 32)
+	for _, rrtype := range []uint16{dns.TypeTLSA, dns.TypeSMIMEA} {
+		rr, ok := Get(rrtype)
+		if !ok {
+			t.Fatalf("%s record type not found", dns.TypeToString[rrtype])
+		}
+		value := map[string]interface{}{"usage": "DANE-EE", "selector": "SPKI", "matching_type": "SHA2-256", "certificate": digest}
+		if err := rr.Add("go53.test", "_25._tcp.mail", value, nil); err != nil {
+			t.Fatalf("%s: add: %v", dns.TypeToString[rrtype], err)
+		}
+		results, ok := rr.Lookup("_25._tcp.mail.go53.test.")
+		if !ok || len(results) != 1 {
+			t.Fatalf("%s: expected one record, got %v", dns.TypeToString[rrtype], results)
+		}
+		want := "3 1 1 " + strings.ToUpper(digest)
+		if !strings.HasSuffix(results[0].String(), want) {
+			t.Fatalf("%s: record = %q, want rdata %q", dns.TypeToString[rrtype], results[0], want)
+		}
+
+		for name, bad := range map[string]map[string]interface{}{
+			"usage":    {"usage": float64(4), "selector": float64(1), "matching_type": float64(1), "certificate": digest},
+			"selector": {"usage": float64(3), "selector": float64(2), "matching_type": float64(1), "certificate": digest},
+			"length":   {"usage": float64(3), "selector": float64(1), "matching_type": float64(2), "certificate": digest},
+			"hex":      {"usage": float64(3), "selector": float64(1), "matching_type": float64(0), "certificate": "xyz"},
+		} {
+			if err := rr.Add("go53.test", "_25._tcp.mail", bad, nil); err == nil {
+				t.Errorf("%s: %s: expected error", dns.TypeToString[rrtype], name)
+			}
+		}
+
+		if err := rr.Delete("_25._tcp.mail.go53.test.", value); err != nil {
+			t.Fatalf("%s: delete: %v", dns.TypeToString[rrtype], err)
+		}
+		if results, _ := rr.Lookup("_25._tcp.mail.go53.test."); len(results) != 0 {
+			t.Fatalf("%s: expected no record after delete, got %v", dns.TypeToString[rrtype], results)
+		}
+	}
+
+	pgp, _ := Get(dns.TypeOPENPGPKEY)
+	if err := pgp.Add("go53.test", "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey", map[string]interface{}{"public_key": "mQENBFVHm5sBCADR"}, nil); err != nil {
+		t.Fatalf("valid OPENPGPKEY rejected: %v", err)
+	}
+	if err := pgp.Add("go53.test", "x._openpgpkey", map[string]interface{}{"public_key": "not base64"}, nil); err == nil {
+		t.Fatalf("expected OPENPGPKEY base64 error")
+	}
+}
@@ -9,9 +9,9 @@ import (
 	"github.com/miekg/dns"
 )
 
-type TXTRecord struct{}
+type TXTRecord struct{ scope }
 
-func (TXTRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+func (rt TXTRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	sanitizedZone, err := internal.SanitizeFQDN(zone)
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
@@ -37,13 +37,13 @@ func (TXTRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 		TTL = *ttl
 	}
 
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	key := normalizeRecordKey(sanitizedZone, name)
 
-	_, _, val, found := memStore.GetRecord(sanitizedZone, string(types.TypeTXT), key)
+	_, _, val, found := rt.store().GetRecord(sanitizedZone, string(types.TypeTXT), key)
 
 	var currentList []types.TXTRecord
 	if found {
@@ -72,11 +72,11 @@ func (TXTRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
 	}
 
 	currentList = append(currentList, types.TXTRecord{Text: text, TTL: TTL, Chunks: internal.ChunkTXT(text)})
-	return memStore.AddRecord(sanitizedZone, string(types.TypeTXT), key, currentList)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeTXT), key, currentList)
 }
 
-func (TXTRecord) Lookup(host string) ([]dns.RR, bool) {
-	zone, name, ok := internal.SplitName(host)
+func (rt TXTRecord) Lookup(host string) ([]dns.RR, bool) {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return nil, false
 	}
@@ -85,11 +85,11 @@ func (TXTRecord) Lookup(host string) ([]dns.RR, bool) {
 	if err != nil {
 		return nil, false
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return nil, false
 	}
 
-	_, _, val, ok := memStore.GetRecord(sanitizedZone, string(types.TypeTXT), name)
+	_, _, val, ok := rt.store().GetRecord(sanitizedZone, string(types.TypeTXT), name)
 	if !ok {
 		return nil, false
 	}
@@ -134,8 +134,8 @@ func (TXTRecord) Lookup(host string) ([]dns.RR, bool) {
 	return results, len(results) > 0
 }
 
-func (TXTRecord) Delete(host string, value interface{}) error {
-	zone, name, ok := internal.SplitName(host)
+func (rt TXTRecord) Delete(host string, value interface{}) error {
+	zone, name, ok := rt.splitName(host)
 	if !ok {
 		return errors.New("invalid host format")
 	}
@@ -143,12 +143,12 @@ func (TXTRecord) Delete(host string, value interface{}) error {
 	if err != nil {
 		return errors.New("FQDN Sanitize check failed")
 	}
-	if memStore == nil {
+	if rt.store() == nil {
 		return errors.New("memory store not initialized")
 	}
 
 	if value == nil {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeTXT), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeTXT), name)
 	}
 
 	textToRemove, ok := value.(string)
@@ -156,7 +156,7 @@ func (TXTRecord) Delete(host string, value interface{}) error {
 		return fmt.Errorf("TXTRecord Delete: expected string text, got %T", value)
 	}
 
-	_, _, raw, found := memStore.GetRecord(sanitizedZone, string(types.TypeTXT), name)
+	_, _, raw, found := rt.store().GetRecord(sanitizedZone, string(types.TypeTXT), name)
 	if !found {
 		return nil
 	}
@@ -187,9 +187,9 @@ func (TXTRecord) Delete(host string, value interface{}) error {
 	}
 
 	if len(filtered) == 0 {
-		return memStore.DeleteRecord(sanitizedZone, string(types.TypeTXT), name)
+		return rt.store().DeleteRecord(sanitizedZone, string(types.TypeTXT), name)
 	}
-	return memStore.AddRecord(sanitizedZone, string(types.TypeTXT), name, filtered)
+	return rt.store().AddRecord(sanitizedZone, string(types.TypeTXT), name, filtered)
 }
 
 func (TXTRecord) Type() uint16 {
@@ -0,0 +1,71 @@
+package rtypes
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+	"go53/internal"
+	"go53/types"
+)
+
+// UnknownRecord serves record types without a dedicated handler in the opaque
+// form of RFC 3597. It is not registered; Get hands out one per type.
+type UnknownRecord struct {
+	scope
+	rrtype uint16
+}
+
+// parse accepts {"rdata": "\\# 4 0a000001"} or bare hex rdata and checks
+// that the rdata is valid for the type when miekg/dns knows it.
+func (rt UnknownRecord) parse(value interface{}, ttl *uint32) (types.UnknownRecord, error) {
+	typ := internal.TypeName(rt.rrtype)
+	m, err := recordObject(typ, value)
+	if err != nil {
+		return types.UnknownRecord{}, err
+	}
+	raw, err := stringField(m, typ, "rdata", true, "")
+	if err != nil {
+		return types.UnknownRecord{}, err
+	}
+	rdata, err := internal.ParseOpaqueRdata(raw)
+	if err != nil {
+		return types.UnknownRecord{}, fmt.Errorf("%s: %w", typ, err)
+	}
+	if _, err := internal.OpaqueRR(".", rt.rrtype, rdata, 0); err != nil {
+		return types.UnknownRecord{}, fmt.Errorf("%s: %w", typ, err)
+	}
+	return types.UnknownRecord{Rdata: rdata, TTL: recordTTL(m, ttl)}, nil
+}
+
+func sameUnknown(a, b types.UnknownRecord) bool {
+	return a.Rdata == b.Rdata
+}
+
+func (rt UnknownRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := rt.parse(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, internal.TypeName(rt.rrtype), rec, sameUnknown)
+}
+
+func (rt UnknownRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, internal.TypeName(rt.rrtype), host)
+}
+
+func (rt UnknownRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.UnknownRecord](rt.scope, internal.TypeName(rt.rrtype), host, nil)
+	}
+	rec, err := rt.parse(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, internal.TypeName(rt.rrtype), host, func(r types.UnknownRecord) bool {
+		return sameUnknown(r, rec)
+	})
+}
+
+func (rt UnknownRecord) Type() uint16 {
+	return rt.rrtype
+}
@@ -0,0 +1,81 @@
+package rtypes
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/miekg/dns"
+	"go53/types"
+)
+
+type URIRecord struct{ scope }
+
+// parseURI validates a URI value (RFC 7553). The target must be an absolute
+// URI, i.e. carry a scheme.
+func parseURI(value interface{}, ttl *uint32) (types.URIRecord, error) {
+	m, err := recordObject("URIRecord", value)
+	if err != nil {
+		return types.URIRecord{}, err
+	}
+
+	priority, err := uintField(m, "URIRecord", "priority", 65535, -1)
+	if err != nil {
+		return types.URIRecord{}, err
+	}
+	weight, err := uintField(m, "URIRecord", "weight", 65535, -1)
+	if err != nil {
+		return types.URIRecord{}, err
+	}
+	target, err := stringField(m, "URIRecord", "target", true, "")
+	if err != nil {
+		return types.URIRecord{}, err
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" {
+		return types.URIRecord{}, fmt.Errorf("URIRecord: field 'target' must be an absolute URI, got %q", target)
+	}
+
+	return types.URIRecord{
+		Priority: uint16(priority),
+		Weight:   uint16(weight),
+		Target:   target,
+		TTL:      recordTTL(m, ttl),
+	}, nil
+}
+
+func sameURI(a, b types.URIRecord) bool {
+	return a.Priority == b.Priority && a.Weight == b.Weight && a.Target == b.Target
+}
+
+func (rt URIRecord) Add(zone, name string, value interface{}, ttl *uint32) error {
+	rec, err := parseURI(value, ttl)
+	if err != nil {
+		return err
+	}
+	return addToRRset(rt.scope, zone, name, string(types.TypeURI), rec, sameURI)
+}
+
+func (rt URIRecord) Lookup(host string) ([]dns.RR, bool) {
+	return lookupRRset(rt.scope, string(types.TypeURI), host)
+}
+
+func (rt URIRecord) Delete(host string, value interface{}) error {
+	if value == nil {
+		return deleteFromRRset[types.URIRecord](rt.scope, string(types.TypeURI), host, nil)
+	}
+	rec, err := parseURI(value, nil)
+	if err != nil {
+		return err
+	}
+	return deleteFromRRset(rt.scope, string(types.TypeURI), host, func(r types.URIRecord) bool {
+		return sameURI(r, rec)
+	})
+}
+
+func (URIRecord) Type() uint16 {
+	return dns.TypeURI
+}
+
+func init() {
+	Register(URIRecord{})
+}
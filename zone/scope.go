@@ -0,0 +1,407 @@
+// This file is part of the go53 project.
+//
+// This file is licensed under the European Union Public License (EUPL) v1.2.
+// You may only use this work in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	https://joinup.ec.europa.eu/collection/eupl/eupl-text-eupl-12
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed "as is",
+// without any warranty or conditions of any kind.
+//
+// Copyleft (c) 2025 - Tenforward AB. All rights reserved.
+//
+// This file: scope.go is part of the go53 authoritative DNS server.
+package zone
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/TenforwardAB/slog"
+	"github.com/miekg/dns"
+
+	"go53/config"
+	"go53/internal"
+	"go53/memory"
+	"go53/storage"
+	"go53/zone/rtypes"
+)
+
+// Scope is the set of zones a query is answered from: the default zones, or
+// the zones of one split-horizon view. The zero Scope is the default zones.
+type Scope struct {
+	view    *view
+	overlay bool
+}
+
+// Default is the scope of clients that match no view.
+var Default Scope
+
+// view holds the zones stored for one split-horizon view. Overlay views keep
+// only the RRsets they override; the zones they answer from are merged over
+// the default zones. Merges are rebuilt when either side changes: in the
+// background when a query finds one out of date, which keeps answering from
+// the previous merge until the new one is ready, and in line when the view
+// itself is written.
+type view struct {
+	name   string
+	store  *memory.InMemoryZoneStore
+	mu     sync.Mutex
+	merged map[string]*mergedZone
+}
+
+type mergedZone struct {
+	baseGen    uint64
+	viewGen    uint64
+	store      *memory.InMemoryZoneStore
+	rebuilding bool
+}
+
+var views = struct {
+	sync.Mutex
+	byName map[string]*view
+}{byName: map[string]*view{}}
+
+// ForView returns the scope of the named view, loading its zones from storage
+// on first use. An overlay view answers for the zones it holds from those
+// zones merged over the default ones, and from the default zones otherwise.
+func ForView(name string, overlay bool) (Scope, error) {
+	if !config.ValidViewName(name) {
+		return Scope{}, fmt.Errorf("invalid view name %q", name)
+	}
+	views.Lock()
+	defer views.Unlock()
+	v, ok := views.byName[name]
+	if !ok {
+		if storage.Backend == nil {
+			return Scope{}, fmt.Errorf("storage backend is not initialized")
+		}
+		mem, err := memory.NewViewStore(storage.Backend, name)
+		if err != nil {
+			return Scope{}, fmt.Errorf("load view %s: %w", name, err)
+		}
+		v = &view{name: name, store: mem, merged: map[string]*mergedZone{}}
+		views.byName[name] = v
+		if base := rtypes.GetMemStore(); overlay && base != nil {
+			go func() {
+				for _, zone := range mem.ZoneNamesSnapshot() {
+					v.rebuildMerged(base, zone)
+				}
+			}()
+		}
+	}
+	return Scope{view: v, overlay: overlay}, nil
+}
+
+// ForConfiguredView returns the scope of the view of that name in the live
+// config. ok is false when no such view is configured.
+func ForConfiguredView(name string) (s Scope, ok bool, err error) {
+	for _, v := range config.AppConfig.GetLive().Views {
+		if v.Name == name {
+			s, err = ForView(v.Name, v.Overlay)
+			return s, true, err
+		}
+	}
+	return Scope{}, false, nil
+}
+
+// ResetViews forgets the loaded view zones so they are read from storage
+// again, e.g. after a restore replaced them.
+func ResetViews() {
+	views.Lock()
+	defer views.Unlock()
+	views.byName = map[string]*view{}
+}
+
+// View returns the name of the view, or "" for the default zones.
+func (s Scope) View() string {
+	if s.view == nil {
+		return ""
+	}
+	return s.view.name
+}
+
+// Overlay reports whether the view only overrides RRsets of the default zones.
+func (s Scope) Overlay() bool {
+	return s.view != nil && s.overlay
+}
+
+// Store returns the store records of this scope are written to.
+func (s Scope) Store() *memory.InMemoryZoneStore {
+	if s.view == nil {
+		return rtypes.GetMemStore()
+	}
+	return s.view.store
+}
+
+// storeFor returns the store that answers for name.
+func (s Scope) storeFor(name string) *memory.InMemoryZoneStore {
+	base := rtypes.GetMemStore()
+	if s.view == nil {
+		return base
+	}
+	if !s.overlay {
+		return s.view.store
+	}
+	own, _, ok := s.view.store.AuthoritativeNameParts(name)
+	if !ok {
+		return base
+	}
+	if base == nil {
+		return s.view.store
+	}
+	if baseZone, _, ok := base.AuthoritativeNameParts(name); ok && len(baseZone) > len(own) {
+		return base
+	}
+	return s.view.mergedStore(base, own)
+}
+
+// stores returns every store holding zones of this scope.
+func (s Scope) stores() []*memory.InMemoryZoneStore {
+	base := rtypes.GetMemStore()
+	var out []*memory.InMemoryZoneStore
+	if s.view != nil {
+		out = append(out, s.view.store)
+		if !s.overlay {
+			return out
+		}
+	}
+	if base != nil {
+		out = append(out, base)
+	}
+	return out
+}
+
+func (s Scope) handler(mem *memory.InMemoryZoneStore, rrtype uint16) (rtypes.RRType, bool) {
+	if s.view == nil {
+		return rtypes.Get(rrtype)
+	}
+	return rtypes.GetFor(mem, rrtype)
+}
+
+// mergedStore returns the merge of zone the view answers from. An out of date
+// merge is still returned while a newer one is built in the background; only
+// a zone that was never merged is built before answering.
+func (v *view) mergedStore(base *memory.InMemoryZoneStore, zone string) *memory.InMemoryZoneStore {
+	baseGen, viewGen := base.Generation(zone), v.store.Generation(zone)
+	v.mu.Lock()
+	m, ok := v.merged[zone]
+	if !ok {
+		v.mu.Unlock()
+		return v.rebuildMerged(base, zone)
+	}
+	if (m.baseGen != baseGen || m.viewGen != viewGen) && !m.rebuilding {
+		m.rebuilding = true
+		go v.rebuildMerged(base, zone)
+	}
+	store := m.store
+	v.mu.Unlock()
+	return store
+}
+
+// rebuildMerged merges zone over the default zone and signs the result without
+// holding v.mu, then swaps the new merge in.
+func (v *view) rebuildMerged(base *memory.InMemoryZoneStore, zone string) *memory.InMemoryZoneStore {
+	baseGen, viewGen := base.Generation(zone), v.store.Generation(zone)
+	merged, err := memory.NewOverlayStore(base, v.store, zone)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m, ok := v.merged[zone]
+	if err != nil {
+		slog.Warn("view %s: failed to merge %s over the default zone: %v", v.name, zone, err)
+		if ok {
+			m.rebuilding = false
+			return m.store
+		}
+		return v.store
+	}
+	v.merged[zone] = &mergedZone{baseGen: baseGen, viewGen: viewGen, store: merged}
+	return merged
+}
+
+// viewWritten brings the merge of the zone holding name up to date after a
+// write to an overlay view, so the write is visible as soon as it returns.
+func (s Scope) viewWritten(name string) {
+	if !s.Overlay() {
+		return
+	}
+	base := rtypes.GetMemStore()
+	zone, _, ok := s.view.store.AuthoritativeNameParts(name)
+	if base == nil || !ok {
+		return
+	}
+	s.view.rebuildMerged(base, zone)
+}
+
+func (s Scope) AddRecord(rrtype uint16, zone, name string, value interface{}, ttl *uint32) error {
+	mem := s.Store()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	rr, ok := s.handler(mem, rrtype)
+	if !ok {
+		return fmt.Errorf("unknown rrtype: %d", rrtype)
+	}
+	if err := rr.Add(zone, name, value, ttl); err != nil {
+		return err
+	}
+	s.viewWritten(zone)
+	return nil
+}
+
+func (s Scope) LookupRecord(rrtype uint16, name string) ([]dns.RR, bool) {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return nil, false
+	}
+	rr, ok := s.handler(mem, rrtype)
+	if !ok {
+		return nil, false
+	}
+	return rr.Lookup(name)
+}
+
+func (s Scope) DeleteRecord(rrtype uint16, name string, value interface{}) error {
+	mem := s.Store()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	rr, ok := s.handler(mem, rrtype)
+	if !ok {
+		return fmt.Errorf("unknown rrtype: %d", rrtype)
+	}
+	if err := rr.Delete(name, value); err != nil {
+		return err
+	}
+	s.viewWritten(name)
+	return nil
+}
+
+func (s Scope) DeleteZone(zone string) error {
+	mem := s.Store()
+	if mem == nil {
+		return fmt.Errorf("memory store is not initialized")
+	}
+	if err := mem.DeleteZone(zone); err != nil {
+		return err
+	}
+	if s.view != nil {
+		s.view.mu.Lock()
+		delete(s.view.merged, zone)
+		s.view.mu.Unlock()
+	}
+	return nil
+}
+
+func (s Scope) AuthoritativeZoneForName(name string) (string, bool) {
+	qname := strings.ToLower(dns.Fqdn(name))
+	var best string
+	for _, mem := range s.stores() {
+		for _, zoneName := range mem.ZoneNamesSnapshot() {
+			z := strings.ToLower(dns.Fqdn(zoneName))
+			if qname == z || strings.HasSuffix(qname, "."+z) {
+				if len(z) > len(best) {
+					best = z
+				}
+			}
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return best, true
+}
+
+func (s Scope) EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
+	var mem *memory.InMemoryZoneStore
+	if len(rrs) > 0 {
+		mem = s.storeFor(rrs[0].Header().Name)
+	} else {
+		mem = s.Store()
+	}
+	if mem == nil {
+		return nil, fmt.Errorf("memory store is not initialized")
+	}
+	return mem.EnsureSignedRRSet(rrs)
+}
+
+// ResolveALIAS answers an A or AAAA query for name from its ALIAS record,
+// see rtypes.ResolveALIAS. found is false when name has no ALIAS.
+func (s Scope) ResolveALIAS(name string, qtype uint16) ([]dns.RR, bool, error) {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return nil, false, nil
+	}
+	return rtypes.ResolveALIAS(mem, name, qtype)
+}
+
+func (s Scope) DenialProofs(name string, qtype uint16, nxdomain bool) []dns.RR {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return nil
+	}
+	return mem.DenialProofs(name, qtype, nxdomain)
+}
+
+func (s Scope) NameExists(name string) bool {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return false
+	}
+	return mem.NameExists(name)
+}
+
+func (s Scope) WildcardExists(name string) bool {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return false
+	}
+	return mem.WildcardExists(name)
+}
+
+func (s Scope) WildcardName(name string) (string, bool) {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return "", false
+	}
+	return mem.WildcardName(name)
+}
+
+func (s Scope) DelegationFor(name string) (string, []dns.RR, bool) {
+	mem := s.storeFor(name)
+	if mem == nil {
+		return "", nil, false
+	}
+	return mem.DelegationFor(name)
+}
+
+// refreshViewKeyMaterial re-signs zone in every loaded view holding it after
+// its DNSSEC keys changed. Views share the zone's keys, so one DS set at the
+// parent covers every view.
+func refreshViewKeyMaterial(zone string) {
+	zone, err := internal.SanitizeFQDN(zone)
+	if err != nil {
+		return
+	}
+	views.Lock()
+	loaded := make([]*view, 0, len(views.byName))
+	for _, v := range views.byName {
+		loaded = append(loaded, v)
+	}
+	views.Unlock()
+	for _, v := range loaded {
+		v.mu.Lock()
+		delete(v.merged, zone)
+		v.mu.Unlock()
+		if !v.store.HasZone(zone) {
+			continue
+		}
+		if err := v.store.RefreshDNSSECKeyMaterial(zone); err != nil {
+			slog.Warn("view %s: failed to refresh DNSSEC key material for %s: %v", v.name, zone, err)
+		}
+	}
+}
@@ -20,7 +20,6 @@ package zone
 import (
 	"fmt"
 	"github.com/miekg/dns"
-	"strings"
 
 	"go53/zone/rtypes"
 	"go53/zonereader"
@@ -41,11 +40,7 @@ import (
 // Returns:
 //   - error: An error if the RR type is unknown or the handler fails to add the record.
 func AddRecord(rrtype uint16, zone, name string, value interface{}, ttl *uint32) error {
-	rr, ok := rtypes.Get(rrtype)
-	if !ok {
-		return fmt.Errorf("unknown rrtype: %d", rrtype)
-	}
-	return rr.Add(zone, name, value, ttl)
+	return Default.AddRecord(rrtype, zone, name, value, ttl)
 }
 
 // LookupRecord retrieves DNS records of the specified type for a given name.
@@ -61,11 +56,7 @@ func AddRecord(rrtype uint16, zone, name string, value interface{}, ttl *uint32)
 //   - []dns.RR: A slice of matching DNS resource records.
 //   - bool:     True if records were found; false otherwise or if the RR type is unknown.
 func LookupRecord(rrtype uint16, name string) ([]dns.RR, bool) {
-	rr, ok := rtypes.Get(rrtype)
-	if !ok {
-		return nil, false
-	}
-	return rr.Lookup(name)
+	return Default.LookupRecord(rrtype, name)
 }
 
 // DeleteRecord deletes a specific DNS record of the given type and name.
@@ -81,11 +72,7 @@ func LookupRecord(rrtype uint16, name string) ([]dns.RR, bool) {
 // Returns:
 //   - error: An error if the RR type is unknown or the deletion fails.
 func DeleteRecord(rrtype uint16, name string, value interface{}) error {
-	rr, ok := rtypes.Get(rrtype)
-	if !ok {
-		return fmt.Errorf("unknown rrtype: %d", rrtype)
-	}
-	return rr.Delete(name, value)
+	return Default.DeleteRecord(rrtype, name, value)
 }
 
 // DeleteZone removes all records for a specific DNS zone from the in-memory store.
@@ -100,40 +87,15 @@ func DeleteRecord(rrtype uint16, name string, value interface{}) error {
 // Returns:
 //   - error: An error if the memory store is not initialized or the deletion fails.
 func DeleteZone(zone string) error {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return fmt.Errorf("memory store is not initialized")
-	}
-	return mem.DeleteZone(zone)
+	return Default.DeleteZone(zone)
 }
 
 func AuthoritativeZoneForName(name string) (string, bool) {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return "", false
-	}
-	qname := strings.ToLower(dns.Fqdn(name))
-	var best string
-	for _, zoneName := range mem.ZoneNamesSnapshot() {
-		z := strings.ToLower(dns.Fqdn(zoneName))
-		if qname == z || strings.HasSuffix(qname, "."+z) {
-			if len(z) > len(best) {
-				best = z
-			}
-		}
-	}
-	if best == "" {
-		return "", false
-	}
-	return best, true
+	return Default.AuthoritativeZoneForName(name)
 }
 
 func EnsureSignedRRSet(rrs []dns.RR) ([]dns.RR, error) {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return nil, fmt.Errorf("memory store is not initialized")
-	}
-	return mem.EnsureSignedRRSet(rrs)
+	return Default.EnsureSignedRRSet(rrs)
 }
 
 func RefreshDNSSECKeyMaterial(zone string) error {
@@ -141,7 +103,11 @@ func RefreshDNSSECKeyMaterial(zone string) error {
 	if mem == nil {
 		return fmt.Errorf("memory store is not initialized")
 	}
-	return mem.RefreshDNSSECKeyMaterial(zone)
+	if err := mem.RefreshDNSSECKeyMaterial(zone); err != nil {
+		return err
+	}
+	refreshViewKeyMaterial(zone)
+	return nil
 }
 
 func FindNSECProof(name string) ([]dns.RR, bool) {
@@ -161,43 +127,23 @@ func FindNSEC3Proof(name string) ([]dns.RR, bool) {
 }
 
 func DenialProofs(name string, qtype uint16, nxdomain bool) []dns.RR {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return nil
-	}
-	return mem.DenialProofs(name, qtype, nxdomain)
+	return Default.DenialProofs(name, qtype, nxdomain)
 }
 
 func NameExists(name string) bool {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return false
-	}
-	return mem.NameExists(name)
+	return Default.NameExists(name)
 }
 
 func WildcardExists(name string) bool {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return false
-	}
-	return mem.WildcardExists(name)
+	return Default.WildcardExists(name)
 }
 
 func WildcardName(name string) (string, bool) {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return "", false
-	}
-	return mem.WildcardName(name)
+	return Default.WildcardName(name)
 }
 
 func DelegationFor(name string) (string, []dns.RR, bool) {
-	mem := rtypes.GetMemStore()
-	if mem == nil {
-		return "", nil, false
-	}
-	return mem.DelegationFor(name)
+	return Default.DelegationFor(name)
 }
 
 func init() {
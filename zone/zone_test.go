@@ -87,13 +87,27 @@ func TestZoneFacadeUnknownRRType(t *testing.T) {
 	setupZoneFacadeTestStore(t)
 
 	if err := AddRecord(65534, "unknown.test.", "www", map[string]interface{}{}, nil); err == nil {
-		t.Fatalf("AddRecord unknown type succeeded")
+		t.Fatalf("AddRecord without rdata succeeded")
+	}
+	if err := AddRecord(65534, "unknown.test.", "www", map[string]interface{}{"rdata": `\# 4 0A000001`}, nil); err != nil {
+		t.Fatalf("AddRecord TYPE65534: %v", err)
+	}
+	rrs, ok := LookupRecord(65534, "www.unknown.test.")
+	if !ok || len(rrs) != 1 {
+		t.Fatalf("LookupRecord TYPE65534 = %v, %v", rrs, ok)
+	}
+	if got, ok := rrs[0].(*dns.RFC3597); !ok || got.Rdata != "0a000001" {
+		t.Fatalf("LookupRecord TYPE65534 returned %v", rrs[0])
+	}
+	if err := DeleteRecord(65534, "www.unknown.test.", map[string]interface{}{"rdata": "0a000001"}); err != nil {
+		t.Fatalf("DeleteRecord TYPE65534: %v", err)
 	}
 	if _, ok := LookupRecord(65534, "www.unknown.test."); ok {
-		t.Fatalf("LookupRecord unknown type succeeded")
+		t.Fatalf("TYPE65534 still present after delete")
 	}
-	if err := DeleteRecord(65534, "www.unknown.test.", nil); err == nil {
-		t.Fatalf("DeleteRecord unknown type succeeded")
+
+	if err := AddRecord(dns.TypeTSIG, "unknown.test.", "www", map[string]interface{}{"rdata": "00"}, nil); err == nil {
+		t.Fatalf("AddRecord of meta type TSIG succeeded")
 	}
 }
 
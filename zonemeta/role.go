@@ -0,0 +1,86 @@
+package zonemeta
+
+import (
+	"fmt"
+	"go53/config"
+	"strings"
+)
+
+// Zone roles. A zone without a role of its own takes the server's mode.
+const (
+	RolePrimary     = "primary"
+	RoleSecondary   = "secondary"
+	RoleDistributed = "distributed"
+)
+
+// NormalizeRole validates role against the server mode. Distributed zones
+// need a node running in distributed mode to replicate them.
+func NormalizeRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case "", RolePrimary, RoleSecondary:
+		return role, nil
+	case RoleDistributed:
+		if config.AppConfig.GetLive().Mode != RoleDistributed {
+			return "", fmt.Errorf("role %q requires mode %q", role, RoleDistributed)
+		}
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown role %q", role)
+	}
+}
+
+// RoleOf returns the effective role of zoneName: its own role when set,
+// otherwise the server mode.
+func RoleOf(zoneName string) string {
+	meta, err := Load(zoneName)
+	if err != nil {
+		return config.AppConfig.GetLive().Mode
+	}
+	return meta.EffectiveRole()
+}
+
+// EffectiveRole returns the role of the zone m describes, see RoleOf.
+func (m ZoneMeta) EffectiveRole() string {
+	mode := config.AppConfig.GetLive().Mode
+	if m.Role == "" {
+		return mode
+	}
+	if m.Role == RoleDistributed && mode != RoleDistributed {
+		// Replication is off on this node; the zone is edited locally.
+		return RolePrimary
+	}
+	return m.Role
+}
+
+// IsSecondary reports whether zoneName is transferred from a primary rather
+// than edited here.
+func IsSecondary(zoneName string) bool {
+	return RoleOf(zoneName) == RoleSecondary
+}
+
+// SetRole stores the role of zoneName, keeping its other metadata. An empty
+// role makes the zone follow the server mode again.
+func SetRole(zoneName, role string) error {
+	meta, err := Load(zoneName)
+	if err != nil {
+		return err
+	}
+	meta.Role = role
+	return Save(meta)
+}
+
+// ZonesWithRole returns the zones whose own role is role.
+func ZonesWithRole(role string) []string {
+	metas, err := List()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, meta := range metas {
+		if meta.Role == role {
+			out = append(out, meta.Zone)
+		}
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package zonemeta
+
+import (
+	"fmt"
+	"go53/internal"
+	"go53/storage"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Settings are the per-zone transfer and signing settings. Zones without them
+// use the global primary, allow_transfer, TSIG and DNSSEC policy
+// configuration.
+type Settings struct {
+	// Primaries are asked for the zone, in order, when it is a secondary.
+	Primaries []Server `json:"primaries,omitempty"`
+	// AllowTransfer replaces the global allow_transfer list for the zone.
+	AllowTransfer []TransferACLEntry `json:"allow_transfer,omitempty"`
+	// AlsoNotify are sent NOTIFY on every change, next to the single
+	// addresses in AllowTransfer.
+	AlsoNotify []Server `json:"also_notify,omitempty"`
+	// NotifySource is the local address NOTIFY is sent from, as IP or
+	// IP:port.
+	NotifySource string `json:"notify_source,omitempty"`
+	// DNSSECPolicy names the key and signing policy of the zone, overriding
+	// dnssec.default_policy. PolicyNone opts the zone out.
+	DNSSECPolicy string `json:"dnssec_policy,omitempty"`
+	// OfflineKSK keeps the KSK of the zone off this server: only its ZSKs
+	// are stored, and DNSKEY, CDS and CDNSKEY are served with the signatures
+	// of an imported SKR.
+	OfflineKSK bool `json:"offline_ksk,omitempty"`
+}
+
+// PolicyNone as dnssec_policy keeps a zone out of the default policy.
+const PolicyNone = "none"
+
+// Server is a peer of a zone, a primary to transfer from or a server to
+// notify, with the TSIG key used towards it.
+type Server struct {
+	IP      string `json:"ip"`
+	Port    int    `json:"port,omitempty"`
+	TSIGKey string `json:"tsig_key,omitempty"`
+}
+
+// TransferACLEntry allows transfers to clients in Address, an IP address or
+// CIDR prefix. With TSIGKey set the request must also be signed with that key.
+type TransferACLEntry struct {
+	Address string `json:"address"`
+	TSIGKey string `json:"tsig_key,omitempty"`
+}
+
+// Addr returns the host:port of s, port 53 unless set.
+func (s Server) Addr() string {
+	port := s.Port
+	if port == 0 {
+		port = 53
+	}
+	return net.JoinHostPort(s.IP, strconv.Itoa(port))
+}
+
+// Matches reports whether ip is covered by the entry.
+func (e TransferACLEntry) Matches(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if _, prefix, err := net.ParseCIDR(e.Address); err == nil {
+		return prefix.Contains(ip)
+	}
+	addr := net.ParseIP(e.Address)
+	return addr != nil && addr.Equal(ip)
+}
+
+// Host returns the address of an entry naming a single host, for NOTIFY.
+func (e TransferACLEntry) Host() (net.IP, bool) {
+	ip := net.ParseIP(e.Address)
+	return ip, ip != nil
+}
+
+// TSIGKeys returns the key names the settings refer to.
+func (s Settings) TSIGKeys() []string {
+	var keys []string
+	for _, server := range append(append([]Server{}, s.Primaries...), s.AlsoNotify...) {
+		if server.TSIGKey != "" {
+			keys = append(keys, server.TSIGKey)
+		}
+	}
+	for _, entry := range s.AllowTransfer {
+		if entry.TSIGKey != "" {
+			keys = append(keys, entry.TSIGKey)
+		}
+	}
+	return keys
+}
+
+// Normalize validates s and brings addresses and key names into canonical
+// form.
+func (s *Settings) Normalize() error {
+	for i := range s.Primaries {
+		if err := s.Primaries[i].normalize("primaries"); err != nil {
+			return err
+		}
+	}
+	for i := range s.AlsoNotify {
+		if err := s.AlsoNotify[i].normalize("also_notify"); err != nil {
+			return err
+		}
+	}
+	for i := range s.AllowTransfer {
+		entry := &s.AllowTransfer[i]
+		entry.Address = strings.TrimSpace(entry.Address)
+		if _, prefix, err := net.ParseCIDR(entry.Address); err == nil {
+			entry.Address = prefix.String()
+		} else if ip := net.ParseIP(entry.Address); ip != nil {
+			entry.Address = ip.String()
+		} else {
+			return fmt.Errorf("allow_transfer: %q is not an IP address or CIDR prefix", entry.Address)
+		}
+		key, err := normalizeKeyName(entry.TSIGKey)
+		if err != nil {
+			return fmt.Errorf("allow_transfer: %w", err)
+		}
+		entry.TSIGKey = key
+	}
+	s.NotifySource = strings.TrimSpace(s.NotifySource)
+	if s.NotifySource != "" {
+		host, port, err := net.SplitHostPort(s.NotifySource)
+		if err != nil {
+			host, port = s.NotifySource, ""
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("notify_source: %q is not an IP address", s.NotifySource)
+		}
+		s.NotifySource = ip.String()
+		if port != "" {
+			if _, err := parsePort(port); err != nil {
+				return fmt.Errorf("notify_source: %w", err)
+			}
+			s.NotifySource = net.JoinHostPort(ip.String(), port)
+		}
+	}
+	s.DNSSECPolicy = strings.TrimSpace(s.DNSSECPolicy)
+	return nil
+}
+
+func (s *Server) normalize(field string) error {
+	ip := net.ParseIP(strings.TrimSpace(s.IP))
+	if ip == nil {
+		return fmt.Errorf("%s: %q is not an IP address", field, s.IP)
+	}
+	s.IP = ip.String()
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("%s: port %d out of range", field, s.Port)
+	}
+	key, err := normalizeKeyName(s.TSIGKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	s.TSIGKey = key
+	return nil
+}
+
+func normalizeKeyName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	key, err := internal.SanitizeFQDN(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid TSIG key name %q: %w", name, err)
+	}
+	return strings.ToLower(key), nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// SaveSettings replaces the settings of zoneName, keeping its other metadata.
+func SaveSettings(zoneName string, settings Settings) error {
+	meta, err := Load(zoneName)
+	if err != nil {
+		return err
+	}
+	meta.Settings = settings
+	return Save(meta)
+}
+
+// List returns the metadata of every zone that has any.
+func List() ([]ZoneMeta, error) {
+	if storage.Backend == nil {
+		return nil, fmt.Errorf("storage backend is not initialized")
+	}
+	if err := ensureCache(); err != nil {
+		return nil, err
+	}
+	cache.RLock()
+	defer cache.RUnlock()
+	out := make([]ZoneMeta, 0, len(cache.zones))
+	for _, entry := range cache.zones {
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		out = append(out, entry.meta.clone())
+	}
+	return out, nil
+}
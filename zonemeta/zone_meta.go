@@ -5,19 +5,40 @@ import (
 	"fmt"
 	"go53/internal"
 	"go53/storage"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
-const tableName = "zone_meta"
+// TableName is the storage table zone metadata is kept in, keyed by zone
+// name without the trailing dot.
+const TableName = "zone_meta"
+
+// cache holds the zone_meta table of storage.Backend decoded, so the role
+// and settings checks of the query and signing paths do not read storage. It
+// is filled on first use and whenever the backend changes, kept current by
+// Save and refilled by Reload.
+var cache = struct {
+	sync.RWMutex
+	backendID string
+	zones     map[string]cacheEntry // by zone name without the trailing dot
+}{}
+
+type cacheEntry struct {
+	meta ZoneMeta
+	err  error
+}
 
 type ZoneMeta struct {
 	Zone            string `json:"zone"`
+	Role            string `json:"role,omitempty"`
 	DNSSECMode      string `json:"dnssec_mode,omitempty"`
 	ReadOnly        bool   `json:"read_only,omitempty"`
 	ReadOnlyReason  string `json:"read_only_reason,omitempty"`
 	ImportedAtUnix  int64  `json:"imported_at_unix,omitempty"`
 	ImportedRecords int    `json:"imported_records,omitempty"`
+	Settings
 }
 
 func SetPreserveReadOnly(zoneName string, recordCount int) error {
@@ -25,14 +46,16 @@ func SetPreserveReadOnly(zoneName string, recordCount int) error {
 	if err != nil {
 		return err
 	}
-	return Save(ZoneMeta{
-		Zone:            zoneName,
-		DNSSECMode:      "preserve",
-		ReadOnly:        true,
-		ReadOnlyReason:  "dnssec-preserve-import",
-		ImportedAtUnix:  time.Now().Unix(),
-		ImportedRecords: recordCount,
-	})
+	meta, err := Load(zoneName)
+	if err != nil {
+		return err
+	}
+	meta.DNSSECMode = "preserve"
+	meta.ReadOnly = true
+	meta.ReadOnlyReason = "dnssec-preserve-import"
+	meta.ImportedAtUnix = time.Now().Unix()
+	meta.ImportedRecords = recordCount
+	return Save(meta)
 }
 
 func Save(meta ZoneMeta) error {
@@ -48,7 +71,19 @@ func Save(meta ZoneMeta) error {
 	if err != nil {
 		return err
 	}
-	return storage.Backend.SaveTable(tableName, strings.TrimSuffix(zoneName, "."), data)
+	if err := ensureCache(); err != nil {
+		return err
+	}
+	key := strings.TrimSuffix(zoneName, ".")
+	if err := storage.Backend.SaveTable(TableName, key, data); err != nil {
+		return err
+	}
+	cache.Lock()
+	if cache.zones != nil {
+		cache.zones[key] = cacheEntry{meta: meta.clone()}
+	}
+	cache.Unlock()
+	return nil
 }
 
 func Load(zoneName string) (ZoneMeta, error) {
@@ -59,22 +94,72 @@ func Load(zoneName string) (ZoneMeta, error) {
 	if err != nil {
 		return ZoneMeta{}, err
 	}
-	table, err := storage.Backend.LoadTable(tableName)
-	if err != nil {
+	if err := ensureCache(); err != nil {
 		return ZoneMeta{}, err
 	}
-	raw, ok := table[strings.TrimSuffix(zoneName, ".")]
+	cache.RLock()
+	entry, ok := cache.zones[strings.TrimSuffix(zoneName, ".")]
+	cache.RUnlock()
 	if !ok {
 		return ZoneMeta{Zone: zoneName}, nil
 	}
-	var meta ZoneMeta
-	if err := json.Unmarshal(raw, &meta); err != nil {
-		return ZoneMeta{}, err
+	if entry.err != nil {
+		return ZoneMeta{}, entry.err
 	}
-	if meta.Zone == "" {
-		meta.Zone = zoneName
+	return entry.meta.clone(), nil
+}
+
+// Reload refills the cache from storage. Call it after writing the zone_meta
+// table other than through Save, as a WAL replay does.
+func Reload() error {
+	if storage.Backend == nil {
+		return fmt.Errorf("storage backend is not initialized")
+	}
+	backendID := currentBackendID()
+	table, err := storage.Backend.LoadTable(TableName)
+	if err != nil {
+		return err
+	}
+	zones := make(map[string]cacheEntry, len(table))
+	for key, raw := range table {
+		var meta ZoneMeta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			zones[key] = cacheEntry{err: fmt.Errorf("zone meta %s: %w", key, err)}
+			continue
+		}
+		if meta.Zone == "" {
+			meta.Zone = key + "."
+		}
+		zones[key] = cacheEntry{meta: meta}
 	}
-	return meta, nil
+	cache.Lock()
+	cache.zones = zones
+	cache.backendID = backendID
+	cache.Unlock()
+	return nil
+}
+
+func ensureCache() error {
+	cache.RLock()
+	ready := cache.zones != nil && cache.backendID == currentBackendID()
+	cache.RUnlock()
+	if ready {
+		return nil
+	}
+	return Reload()
+}
+
+func currentBackendID() string {
+	return fmt.Sprintf("%T:%p", storage.Backend, storage.Backend)
+}
+
+// clone copies m so callers can change the settings it returns without
+// touching the cache.
+func (m ZoneMeta) clone() ZoneMeta {
+	m.Primaries = slices.Clone(m.Primaries)
+	m.AllowTransfer = slices.Clone(m.AllowTransfer)
+	m.AlsoNotify = slices.Clone(m.AlsoNotify)
+	return m
 }
 
 func ReadOnly(zoneName string) (ZoneMeta, bool) {
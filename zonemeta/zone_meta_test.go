@@ -0,0 +1,79 @@
+package zonemeta
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go53/config"
+	"go53/storage"
+)
+
+// countingStorage counts the reads of the zone_meta table.
+type countingStorage struct {
+	storage.MockStorage
+	loads int
+}
+
+func (c *countingStorage) LoadTable(table string) (map[string][]byte, error) {
+	if table == TableName {
+		c.loads++
+	}
+	return c.MockStorage.LoadTable(table)
+}
+
+func setupMetaTest(t *testing.T) *countingStorage {
+	t.Helper()
+	backend := &countingStorage{}
+	if err := backend.Init(); err != nil {
+		t.Fatalf("init mock storage: %v", err)
+	}
+	storage.Backend = backend
+	config.AppConfig = &config.ConfigManager{}
+	config.AppConfig.SetLive(config.DefaultLiveConfig)
+	config.AppConfig.LiveForTest().Mode = RolePrimary
+	return backend
+}
+
+func TestRoleOfReadsStorageOnce(t *testing.T) {
+	backend := setupMetaTest(t)
+	if err := SetRole("cached.test.", RoleSecondary); err != nil {
+		t.Fatalf("SetRole: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if !IsSecondary("cached.test.") || IsSecondary("other.test.") {
+			t.Fatalf("roles = %s, %s", RoleOf("cached.test."), RoleOf("other.test."))
+		}
+	}
+	if backend.loads != 1 {
+		t.Fatalf("zone_meta read %d times, want 1", backend.loads)
+	}
+
+	meta, _ := Load("cached.test.")
+	meta.Primaries = append(meta.Primaries, Server{IP: "192.0.2.1"})
+	if again, _ := Load("cached.test."); len(again.Primaries) != 0 {
+		t.Fatalf("changing a loaded copy changed the cache: %+v", again)
+	}
+}
+
+func TestReloadPicksUpDirectTableWrites(t *testing.T) {
+	backend := setupMetaTest(t)
+	if RoleOf("replayed.test.") != RolePrimary {
+		t.Fatalf("role before the write = %s", RoleOf("replayed.test."))
+	}
+	raw, _ := json.Marshal(ZoneMeta{Zone: "replayed.test.", Role: RoleSecondary})
+	if err := backend.SaveTable(TableName, "replayed.test", raw); err != nil {
+		t.Fatalf("SaveTable: %v", err)
+	}
+	if err := Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !IsSecondary("replayed.test.") {
+		t.Fatalf("role after Reload = %s", RoleOf("replayed.test."))
+	}
+
+	// A new backend starts from its own table.
+	setupMetaTest(t)
+	if IsSecondary("replayed.test.") {
+		t.Fatal("role survived the backend change")
+	}
+}